@@ -0,0 +1,42 @@
+package main
+
+import "time"
+
+// adaptivePollingQuietCycles is the number of consecutive reconciliations
+// without changes after which the polling interval is doubled.
+const adaptivePollingQuietCycles = 3
+
+// adaptivePolling lengthens the polling interval of quiet clusters, whose
+// reconciliations change nothing, from --polling-interval up to
+// --max-polling-interval, and goes back to --polling-interval as soon as a
+// reconciliation changes something or fails.
+type adaptivePolling struct {
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+	quiet   int
+}
+
+func newAdaptivePolling(min, max time.Duration) *adaptivePolling {
+	return &adaptivePolling{min: min, max: max, current: min}
+}
+
+// next returns the interval until the next reconciliation given the result
+// of the last one.
+func (p *adaptivePolling) next(changed bool, err error) time.Duration {
+	if changed || err != nil || p.max <= p.min {
+		p.current = p.min
+		p.quiet = 0
+		return p.current
+	}
+
+	p.quiet++
+	if p.quiet >= adaptivePollingQuietCycles && p.current < p.max {
+		p.current *= 2
+		if p.current > p.max {
+			p.current = p.max
+		}
+		p.quiet = 0
+	}
+	return p.current
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptivePolling(t *testing.T) {
+	p := newAdaptivePolling(30*time.Second, 100*time.Second)
+
+	var intervals []time.Duration
+	for i := 0; i < 10; i++ {
+		intervals = append(intervals, p.next(false, nil))
+	}
+	require.Equal(t, []time.Duration{
+		30 * time.Second, 30 * time.Second, 60 * time.Second,
+		60 * time.Second, 60 * time.Second, 100 * time.Second,
+		100 * time.Second, 100 * time.Second, 100 * time.Second,
+		100 * time.Second,
+	}, intervals)
+
+	require.Equal(t, 30*time.Second, p.next(true, nil))
+	require.Equal(t, 30*time.Second, p.next(false, nil))
+	require.Equal(t, 30*time.Second, p.next(false, nil))
+	require.Equal(t, 60*time.Second, p.next(false, nil))
+	require.Equal(t, 30*time.Second, p.next(false, errors.New("failed")))
+
+	// without a longer maximum the interval never changes
+	p = newAdaptivePolling(30*time.Second, 0)
+	for i := 0; i < 10; i++ {
+		require.Equal(t, 30*time.Second, p.next(false, nil))
+	}
+}
@@ -3,11 +3,14 @@ package aws
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/acm"
@@ -22,6 +25,18 @@ import (
 	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/aws/aws-sdk-go/service/wafregional"
+	"github.com/aws/aws-sdk-go/service/wafregional/wafregionaliface"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/aws/aws-sdk-go/service/wafv2/wafv2iface"
 	"github.com/linki/instrumented_http"
 	log "github.com/sirupsen/logrus"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/certs"
@@ -36,8 +51,18 @@ type Adapter struct {
 	acm            acmiface.ACMAPI
 	iam            iamiface.IAMAPI
 	cloudformation cloudformationiface.CloudFormationAPI
+	route53        route53iface.Route53API
+	servicequotas  servicequotasiface.ServiceQuotasAPI
+	wafv2          wafv2iface.WAFV2API
+	wafregional    wafregionaliface.WAFRegionalAPI
+	s3             s3iface.S3API
+	sqs            sqsiface.SQSAPI
 
 	manifest                    *manifest
+	session                     *sessionConfig
+	roles                       *roleAdapters
+	roleARN                     string
+	faults                      *FaultInjection
 	healthCheckPath             string
 	healthCheckPort             uint
 	healthCheckInterval         time.Duration
@@ -52,13 +77,17 @@ type Adapter struct {
 	ec2Details                  map[string]*instanceDetails
 	singleInstances             map[string]*instanceDetails
 	obsoleteInstances           []string
+	drainingInstances           map[string]*drainingInstance
 	stackTerminationProtection  bool
 	stackTags                   map[string]string
 	controllerID                string
+	previousOwner               *stackOwner
 	sslPolicy                   string
 	ipAddressType               string
 	albLogsS3Bucket             string
 	albLogsS3Prefix             string
+	templateBucket              string
+	changeSets                  string
 	httpRedirectToHTTPS         bool
 	nlbCrossZone                bool
 	nlbHTTPEnabled              bool
@@ -68,6 +97,11 @@ type Adapter struct {
 	denyInternalRespBody        string
 	denyInternalRespContentType string
 	denyInternalRespStatusCode  int
+	dnsOwnerID                  string
+	validatedResources          map[string]time.Time
+	hostedZones                 []*hostedZone
+	hostedZonesListed           time.Time
+	clock                       Clock
 }
 
 type manifest struct {
@@ -115,6 +149,24 @@ const (
 	DefaultNLBCrossZone   = false
 	DefaultNLBHTTPEnabled = false
 
+	// MinSlowStartDuration and MaxSlowStartDuration define the range of
+	// the slow start duration supported by target groups.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-target-groups.html#slow-start-mode
+	MinSlowStartDuration = 30 * time.Second
+	MaxSlowStartDuration = 900 * time.Second
+
+	// MinClientKeepAlive and MaxClientKeepAlive define the range of the
+	// client keep alive duration supported by application load balancers.
+	// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/application-load-balancers.html#http-client-keep-alive-duration
+	MinClientKeepAlive = 60 * time.Second
+	MaxClientKeepAlive = 604800 * time.Second
+
+	// MinHealthCheckThresholdCount and MaxHealthCheckThresholdCount define
+	// the range of the healthy and unhealthy threshold counts supported by
+	// target groups.
+	MinHealthCheckThresholdCount = 2
+	MaxHealthCheckThresholdCount = 10
+
 	nameTag                     = "Name"
 	LoadBalancerTypeApplication = "application"
 	LoadBalancerTypeNetwork     = "network"
@@ -122,6 +174,17 @@ const (
 	IPAddressTypeDualstack      = "dualstack"
 )
 
+// IPAddressTypeDualstackWithoutPublicIPV4 is the IP address type of
+// internet-facing application load balancers with public IPv6 addresses and
+// private IPv4 addresses only, saving the cost of public IPv4 addresses.
+const IPAddressTypeDualstackWithoutPublicIPV4 = "dualstack-without-public-ipv4"
+
+// IsDualstack returns true if load balancers of the IP address type have
+// IPv6 addresses.
+func IsDualstack(ipAddressType string) bool {
+	return ipAddressType == IPAddressTypeDualstack || ipAddressType == IPAddressTypeDualstackWithoutPublicIPV4
+}
+
 var (
 	// ErrLoadBalancerStackNotFound is used to signal that a given load balancer CF stack was not found.
 	ErrLoadBalancerStackNotFound = errors.New("load balancer stack not found")
@@ -140,16 +203,23 @@ var (
 	// SSLPolicies is a map of valid ALB SSL Policies
 	// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/create-https-listener.html#describe-ssl-policies
 	SSLPolicies = map[string]bool{
-		"ELBSecurityPolicy-2016-08":             true,
-		"ELBSecurityPolicy-FS-2018-06":          true,
-		"ELBSecurityPolicy-TLS-1-2-2017-01":     true,
-		"ELBSecurityPolicy-TLS-1-2-Ext-2018-06": true,
-		"ELBSecurityPolicy-TLS-1-1-2017-01":     true,
-		"ELBSecurityPolicy-2015-05":             true,
-		"ELBSecurityPolicy-TLS-1-0-2015-04":     true,
-		"ELBSecurityPolicy-FS-1-1-2019-08":      true,
-		"ELBSecurityPolicy-FS-1-2-2019-08":      true,
-		"ELBSecurityPolicy-FS-1-2-Res-2019-08":  true,
+		"ELBSecurityPolicy-2016-08":                true,
+		"ELBSecurityPolicy-FS-2018-06":             true,
+		"ELBSecurityPolicy-TLS-1-2-2017-01":        true,
+		"ELBSecurityPolicy-TLS-1-2-Ext-2018-06":    true,
+		"ELBSecurityPolicy-TLS-1-1-2017-01":        true,
+		"ELBSecurityPolicy-2015-05":                true,
+		"ELBSecurityPolicy-TLS-1-0-2015-04":        true,
+		"ELBSecurityPolicy-FS-1-1-2019-08":         true,
+		"ELBSecurityPolicy-FS-1-2-2019-08":         true,
+		"ELBSecurityPolicy-FS-1-2-Res-2019-08":     true,
+		"ELBSecurityPolicy-TLS13-1-0-2021-06":      true,
+		"ELBSecurityPolicy-TLS13-1-1-2021-06":      true,
+		"ELBSecurityPolicy-TLS13-1-2-2021-06":      true,
+		"ELBSecurityPolicy-TLS13-1-2-Res-2021-06":  true,
+		"ELBSecurityPolicy-TLS13-1-2-Ext1-2021-06": true,
+		"ELBSecurityPolicy-TLS13-1-2-Ext2-2021-06": true,
+		"ELBSecurityPolicy-TLS13-1-3-2021-06":      true,
 	}
 	SSLPoliciesList = []string{
 		"ELBSecurityPolicy-2016-08",
@@ -162,9 +232,51 @@ var (
 		"ELBSecurityPolicy-FS-1-1-2019-08",
 		"ELBSecurityPolicy-FS-1-2-2019-08",
 		"ELBSecurityPolicy-FS-1-2-Res-2019-08",
+		"ELBSecurityPolicy-TLS13-1-0-2021-06",
+		"ELBSecurityPolicy-TLS13-1-1-2021-06",
+		"ELBSecurityPolicy-TLS13-1-2-2021-06",
+		"ELBSecurityPolicy-TLS13-1-2-Res-2021-06",
+		"ELBSecurityPolicy-TLS13-1-2-Ext1-2021-06",
+		"ELBSecurityPolicy-TLS13-1-2-Ext2-2021-06",
+		"ELBSecurityPolicy-TLS13-1-3-2021-06",
 	}
 )
 
+// sslPolicyTLSVersions maps the SSL policies to the lowest and highest TLS
+// version they support, e.g. 12 for TLS 1.2.
+var sslPolicyTLSVersions = map[string][2]int{
+	"ELBSecurityPolicy-2016-08":                {10, 12},
+	"ELBSecurityPolicy-FS-2018-06":             {10, 12},
+	"ELBSecurityPolicy-TLS-1-2-2017-01":        {12, 12},
+	"ELBSecurityPolicy-TLS-1-2-Ext-2018-06":    {12, 12},
+	"ELBSecurityPolicy-TLS-1-1-2017-01":        {11, 12},
+	"ELBSecurityPolicy-2015-05":                {10, 12},
+	"ELBSecurityPolicy-TLS-1-0-2015-04":        {10, 12},
+	"ELBSecurityPolicy-FS-1-1-2019-08":         {11, 12},
+	"ELBSecurityPolicy-FS-1-2-2019-08":         {12, 12},
+	"ELBSecurityPolicy-FS-1-2-Res-2019-08":     {12, 12},
+	"ELBSecurityPolicy-TLS13-1-0-2021-06":      {10, 13},
+	"ELBSecurityPolicy-TLS13-1-1-2021-06":      {11, 13},
+	"ELBSecurityPolicy-TLS13-1-2-2021-06":      {12, 13},
+	"ELBSecurityPolicy-TLS13-1-2-Res-2021-06":  {12, 13},
+	"ELBSecurityPolicy-TLS13-1-2-Ext1-2021-06": {12, 13},
+	"ELBSecurityPolicy-TLS13-1-2-Ext2-2021-06": {12, 13},
+	"ELBSecurityPolicy-TLS13-1-3-2021-06":      {13, 13},
+}
+
+// IsWeakerSSLPolicy returns true if the SSL policy allows a lower TLS
+// version than the minimum policy, or the same lowest version without
+// supporting TLS 1.3 while the minimum policy does. Unknown policies are
+// always considered weaker.
+func IsWeakerSSLPolicy(policy, minimum string) bool {
+	p, ok := sslPolicyTLSVersions[policy]
+	if !ok {
+		return true
+	}
+	m := sslPolicyTLSVersions[minimum]
+	return p[0] < m[0] || p[0] == m[0] && p[1] < m[1]
+}
+
 func newConfigProvider(debug, disableInstrumentedHttpClient bool) client.ConfigProvider {
 	cfg := aws.NewConfig().WithMaxRetries(3)
 	if debug {
@@ -185,15 +297,143 @@ func newConfigProvider(debug, disableInstrumentedHttpClient bool) client.ConfigP
 // Security Group that should be used for newly created Load Balancers. If any of those critical steps fail
 // an appropriate error is returned.
 func NewAdapter(clusterID, newControllerID, vpcID string, debug, disableInstrumentedHttpClient bool) (adapter *Adapter, err error) {
-	p := newConfigProvider(debug, disableInstrumentedHttpClient)
-	adapter = &Adapter{
-		ec2:                 ec2.New(p),
-		elbv2:               elbv2.New(p),
-		ec2metadata:         ec2metadata.New(p),
-		autoscaling:         autoscaling.New(p),
-		acm:                 acm.New(p),
-		iam:                 iam.New(p),
-		cloudformation:      cloudformation.New(p),
+	return NewAdapterWithEndpoint(clusterID, newControllerID, vpcID, "", debug, disableInstrumentedHttpClient)
+}
+
+// NewAdapterWithEndpoint returns a new Adapter like NewAdapter, but sends the
+// requests of all services to the given endpoint if it's not empty, e.g. to
+// LocalStack for end-to-end tests. The EC2 instance metadata isn't used with a
+// custom endpoint, so the clusterID and vpcID must be given.
+func NewAdapterWithEndpoint(clusterID, newControllerID, vpcID, endpoint string, debug, disableInstrumentedHttpClient bool) (adapter *Adapter, err error) {
+	return NewAdapterInRegion(clusterID, newControllerID, vpcID, "", endpoint, debug, disableInstrumentedHttpClient)
+}
+
+// NewAdapterInRegion returns a new Adapter like NewAdapterWithEndpoint, but
+// sends the requests of all services to the given region if it's not empty
+// instead of the region of the session, e.g. to provision load balancers for
+// the cluster in additional regions. The EC2 instance metadata is the one of
+// the region the controller runs in, so the clusterID and the vpcID of the
+// region must be given.
+func NewAdapterInRegion(clusterID, newControllerID, vpcID, region, endpoint string, debug, disableInstrumentedHttpClient bool) (adapter *Adapter, err error) {
+	cfg := aws.NewConfig()
+	s3Config := aws.NewConfig()
+	if region != "" {
+		if clusterID == "" || vpcID == "" {
+			return nil, fmt.Errorf("clusterID and vpcID are required in region %s", region)
+		}
+		cfg = cfg.WithRegion(region)
+		s3Config = s3Config.WithRegion(region)
+	}
+	if endpoint != "" {
+		if clusterID == "" || vpcID == "" {
+			return nil, errors.New("clusterID and vpcID are required with a custom endpoint")
+		}
+		cfg = cfg.WithEndpoint(endpoint)
+		// custom endpoints don't resolve the virtual hosts of buckets
+		s3Config = s3Config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sc := &sessionConfig{
+		provider: newConfigProvider(debug, disableInstrumentedHttpClient),
+		config:   cfg,
+		s3Config: s3Config,
+	}
+	adapter = newAdapter(newControllerID, sc.clients(nil))
+	adapter.ec2metadata = ec2metadata.New(sc.provider)
+	adapter.session = sc
+
+	adapter.manifest, err = buildManifest(adapter, clusterID, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// sessionConfig is the configuration of the clients of an Adapter created
+// from an AWS session, which is used again for the clients of assumed roles.
+type sessionConfig struct {
+	provider client.ConfigProvider
+	config   *aws.Config
+	s3Config *aws.Config
+}
+
+// clients returns the clients of the AWS services, with the credentials if
+// they're not nil instead of the ones of the session.
+func (s *sessionConfig) clients(creds *credentials.Credentials) Clients {
+	cfg, s3Config := s.config, s.s3Config
+	if creds != nil {
+		cfg = cfg.Copy().WithCredentials(creds)
+		s3Config = s3Config.Copy().WithCredentials(creds)
+	}
+	p := s.provider
+	return Clients{
+		EC2:            ec2.New(p, cfg),
+		ELBV2:          elbv2.New(p, cfg),
+		AutoScaling:    autoscaling.New(p, cfg),
+		ACM:            acm.New(p, cfg),
+		IAM:            iam.New(p, cfg),
+		CloudFormation: cloudformation.New(p, cfg),
+		Route53:        route53.New(p, cfg),
+		ServiceQuotas:  servicequotas.New(p, cfg),
+		WAFV2:          wafv2.New(p, cfg),
+		WAFRegional:    wafregional.New(p, cfg),
+		S3:             s3.New(p, s3Config),
+		SQS:            sqs.New(p, cfg),
+	}
+}
+
+// Clients holds the clients of the AWS services used by an Adapter.
+type Clients struct {
+	EC2            ec2iface.EC2API
+	ELBV2          elbv2iface.ELBV2API
+	AutoScaling    autoscalingiface.AutoScalingAPI
+	ACM            acmiface.ACMAPI
+	IAM            iamiface.IAMAPI
+	CloudFormation cloudformationiface.CloudFormationAPI
+	Route53        route53iface.Route53API
+	ServiceQuotas  servicequotasiface.ServiceQuotasAPI
+	WAFV2          wafv2iface.WAFV2API
+	WAFRegional    wafregionaliface.WAFRegionalAPI
+	S3             s3iface.S3API
+	SQS            sqsiface.SQSAPI
+}
+
+// NewAdapterWithClients returns a new Adapter which uses the given clients,
+// e.g. the fakes of the aws/fake package, instead of the ones of an AWS
+// session. The EC2 instance metadata isn't available, so the clusterID and
+// vpcID must be given. The discovery of the Security Group and subnets is the
+// same as for NewAdapter.
+func NewAdapterWithClients(clusterID, newControllerID, vpcID string, clients Clients) (*Adapter, error) {
+	if clusterID == "" || vpcID == "" {
+		return nil, errors.New("clusterID and vpcID are required without EC2 instance metadata")
+	}
+
+	adapter := newAdapter(newControllerID, clients)
+
+	var err error
+	adapter.manifest, err = buildManifest(adapter, clusterID, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	return adapter, nil
+}
+
+func newAdapter(newControllerID string, clients Clients) *Adapter {
+	return &Adapter{
+		ec2:                 clients.EC2,
+		elbv2:               clients.ELBV2,
+		autoscaling:         clients.AutoScaling,
+		acm:                 clients.ACM,
+		iam:                 clients.IAM,
+		cloudformation:      clients.CloudFormation,
+		route53:             clients.Route53,
+		servicequotas:       clients.ServiceQuotas,
+		wafv2:               clients.WAFV2,
+		wafregional:         clients.WAFRegional,
+		s3:                  clients.S3,
+		sqs:                 clients.SQS,
 		healthCheckPath:     DefaultHealthCheckPath,
 		healthCheckPort:     DefaultHealthCheckPort,
 		targetPort:          DefaultTargetPort,
@@ -211,14 +451,11 @@ func NewAdapter(clusterID, newControllerID, vpcID string, debug, disableInstrume
 		nlbCrossZone:        DefaultNLBCrossZone,
 		nlbHTTPEnabled:      DefaultNLBHTTPEnabled,
 		customFilter:        DefaultCustomFilter,
+		dnsOwnerID:          newControllerID,
+		validatedResources:  make(map[string]time.Time),
+		roles:               &roleAdapters{adapters: make(map[string]*Adapter)},
+		clock:               SystemClock,
 	}
-
-	adapter.manifest, err = buildManifest(adapter, clusterID, vpcID)
-	if err != nil {
-		return nil, err
-	}
-
-	return
 }
 
 func (a *Adapter) NewACMCertificateProvider() certs.CertificatesProvider {
@@ -348,6 +585,14 @@ func (a *Adapter) WithAlbLogsS3Prefix(prefix string) *Adapter {
 	return a
 }
 
+// WithTemplateBucket returns the receiver adapter after changing the S3
+// bucket the templates exceeding the size limit of CloudFormation requests
+// are uploaded to.
+func (a *Adapter) WithTemplateBucket(bucket string) *Adapter {
+	a.templateBucket = bucket
+	return a
+}
+
 // WithHTTPRedirectToHTTPS returns the receiver adapter after changing the flag to effect HTTP->HTTPS redirection
 func (a *Adapter) WithHTTPRedirectToHTTPS(httpRedirectToHTTPS bool) *Adapter {
 	a.httpRedirectToHTTPS = httpRedirectToHTTPS
@@ -419,6 +664,21 @@ func (a *Adapter) WithInternalDomainsDenyResponseContenType(contentType string)
 	return a
 }
 
+// WithDNSOwnerID returns the receiver adapter after changing the owner ID
+// written to the external-dns compatible TXT records of the DNS records
+// managed by the adapter.
+func (a *Adapter) WithDNSOwnerID(ownerID string) *Adapter {
+	a.dnsOwnerID = ownerID
+	return a
+}
+
+// WithClock returns the receiver adapter after changing the clock used by
+// the decisions depending on the current time.
+func (a *Adapter) WithClock(clock Clock) *Adapter {
+	a.clock = clock
+	return a
+}
+
 // ClusterID returns the ClusterID tag that all resources from the same Kubernetes cluster share.
 // It's taken from the current ec2 instance.
 func (a *Adapter) ClusterID() string {
@@ -458,6 +718,25 @@ func (a *Adapter) SingleInstances() []string {
 	return instances
 }
 
+// targetIPs returns the sorted IP addresses of the running instances of the
+// targeted auto scaling groups and of the running single instances.
+func (a *Adapter) targetIPs() []string {
+	ips := make([]string, 0, len(a.ec2Details))
+	for _, details := range a.ec2Details {
+		if !details.running || details.ip == "" {
+			continue
+		}
+		if name, err := getAutoScalingGroupName(details.tags); err == nil {
+			if _, ok := a.TargetedAutoScalingGroups[name]; !ok {
+				continue
+			}
+		}
+		ips = append(ips, details.ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
 // RunningSingleInstances returns list of IDs of running instances that do
 // not belong to any Auto Scaling Group and should be managed manually.
 func (a Adapter) RunningSingleInstances() []string {
@@ -497,14 +776,29 @@ func (a *Adapter) SecurityGroupID() string {
 
 // FindManagedStacks returns all CloudFormation stacks containing the controller management tags
 // that match the current cluster and are ready to be used. The stack status is used to filter.
+// Stacks of the previous owner, if set, are returned too, marked with PreviousOwner.
 func (a *Adapter) FindManagedStacks() ([]*Stack, error) {
-	stacks, err := findManagedStacks(a.cloudformation, a.ClusterID(), a.controllerID)
+	current, previous := a.owners()
+	stacks, err := findManagedStacks(a.cloudformation, current.clusterID, current.controllerID, previous)
 	if err != nil {
 		return nil, err
 	}
 	return stacks, nil
 }
 
+// LoadBalancerQuotas returns the Service Quotas of the account which limit
+// the load balancers and the certificates, listeners and rules of
+// application load balancers.
+func (a *Adapter) LoadBalancerQuotas() (*LoadBalancerQuotas, error) {
+	return getLoadBalancerQuotas(a.servicequotas)
+}
+
+// CountLoadBalancers returns the number of load balancers of the account in
+// the region by their type, e.g. to compare them with the quotas.
+func (a *Adapter) CountLoadBalancers() (map[string]int, error) {
+	return countLoadBalancers(a.elbv2)
+}
+
 // UpdateTargetGroupsAndAutoScalingGroups updates Auto Scaling Groups
 // config to have relevant Target Groups and registers/deregisters single
 // instances (that do not belong to ASG) in relevant Target Groups.
@@ -521,6 +815,17 @@ func (a *Adapter) UpdateTargetGroupsAndAutoScalingGroups(stacks []*Stack) {
 		return
 	}
 
+	// the auto scaling groups of the cluster can't attach the target
+	// groups of the account of a role, the instances are registered by IP
+	// address instead
+	if a.roleARN != "" {
+		if err := updateTargetIPs(a.elbv2, targetGroupARNs, a.targetIPs()); err != nil {
+			log.Errorf("UpdateTargetGroupsAndAutoScalingGroups() failed to register the instances by IP address: %v", err)
+		}
+		return
+	}
+	defer a.updateDrainingInstances()
+
 	ownerTags := map[string]string{
 		clusterIDTagPrefix + a.ClusterID(): resourceLifecycleOwned,
 		kubernetesCreatorTag:               a.controllerID,
@@ -554,126 +859,245 @@ func (a *Adapter) UpdateTargetGroupsAndAutoScalingGroups(stacks []*Stack) {
 		if err := deregisterTargetsOnTargetGroups(a.elbv2, targetGroupARNs, a.obsoleteInstances); err != nil {
 			log.Errorf("UpdateTargetGroupsAndAutoScalingGroups() failed to deregister instances %q in target groups: %v", a.obsoleteInstances, err)
 		} else {
+			a.startDraining(a.obsoleteInstances, targetGroupARNs)
 			a.obsoleteInstances = make([]string, 0)
 		}
 	}
 }
 
+// StackOptions holds the load balancer specific configuration used when
+// creating or updating a stack. Everything else is taken from the adapter
+// configuration.
+type StackOptions struct {
+	CertificateARNs map[string]time.Time
+	Scheme          string
+	SecurityGroup   string
+	Owner           string
+	SSLPolicy       string
+	IPAddressType   string
+	WAFWebACLID     string
+	CWAlarms        CloudWatchAlarmList
+	// TemplateFragments are merged into the template of the stack, e.g.
+	// to add custom resources.
+	TemplateFragments TemplateFragments
+	LoadBalancerType  string
+	HTTP2             bool
+	// WAFRateLimit makes the controller create a WebACL for an
+	// application load balancer, blocking clients exceeding the number of
+	// requests within 5 minutes. The requests are counted by the
+	// WAFRateLimitKey, WAFRateLimitKeyIP if empty. It's ignored if
+	// WAFWebACLID is set.
+	WAFRateLimit    int64
+	WAFRateLimitKey string
+	// WAFManagedRuleGroups are the names of AWS managed rule groups added
+	// to the WebACL created by the controller, like the rate limit.
+	WAFManagedRuleGroups []string
+	// PreserveHostHeader makes application load balancers keep the Host
+	// header of the requests when forwarding them to the targets.
+	PreserveHostHeader bool
+	// HTTPDisabled removes the HTTP listener of the load balancer, only
+	// HTTPS is served.
+	HTTPDisabled bool
+	// FrontingNLB makes an application load balancer internal and puts a
+	// network load balancer with the scheme of the stack in front of it,
+	// giving it static IPs and PrivateLink compatibility.
+	FrontingNLB bool
+	// ElasticIPs are the allocation IDs of the Elastic IPs of an
+	// internet-facing network load balancer, one per subnet, or
+	// AutoAllocateElasticIPs to allocate them with the stack.
+	ElasticIPs []string
+	// IPv4Pool is the IPAM pool the addresses of an internet-facing
+	// application load balancer are taken from, or the public IPv4 pool of
+	// the Elastic IPs allocated for an internet-facing network load
+	// balancer.
+	IPv4Pool string
+	// EndpointServicePrincipals are the principals allowed to connect to
+	// the VPC endpoint service of an internal network load balancer, which
+	// is only created if there are any.
+	EndpointServicePrincipals []string
+	// NLBSecurityGroup attaches the security group to a network load
+	// balancer. It only takes effect when the load balancer is created.
+	NLBSecurityGroup bool
+	// LambdaTarget is the ARN of a Lambda function the listeners of an
+	// application load balancer forward all requests to instead of the
+	// cluster, e.g. to serve a maintenance page.
+	LambdaTarget string
+	SlowStart    time.Duration
+	// ClientKeepAlive is the client keep alive duration of application
+	// load balancers. The AWS default is used if zero.
+	ClientKeepAlive time.Duration
+	// PreserveClientIP enables or disables client IP preservation of the
+	// target group of network load balancers, "true" or "false". The AWS
+	// default is used if empty.
+	PreserveClientIP string
+	// ProxyProtocolV2 enables the PROXY protocol version 2 for the target
+	// group of network load balancers, which sends the client IPs to the
+	// targets in a header of each TCP connection.
+	ProxyProtocolV2 bool
+	// ClientRoutingPolicy is the client routing policy of the DNS records
+	// of network load balancers, one of the ClientRoutingPolicy constants.
+	// The AWS default, any availability zone, is used if empty.
+	ClientRoutingPolicy string
+	// CapacityUnits are the load balancer capacity units reserved for
+	// application load balancers, e.g. ahead of an expected peak of
+	// traffic. Nothing is reserved if zero.
+	CapacityUnits int64
+	// DefaultCertificateARN is the certificate the HTTPS listener presents
+	// to clients without SNI, if it's one of the certificates of the load
+	// balancer. The first of the sorted certificates is used otherwise.
+	DefaultCertificateARN string
+	// ExternalTargetGroupARNs are target groups not created by the
+	// controller, which the listeners of an application load balancer
+	// forward requests to evenly with its own target group. Their targets
+	// aren't registered by the controller.
+	ExternalTargetGroupARNs []string
+	// HealthCheckMatcher are the HTTP codes of a successful health check.
+	// The AWS default is used if empty.
+	HealthCheckMatcher string
+	// HealthyThresholdCount and UnhealthyThresholdCount are the number of
+	// consecutive health check results before a target changes its state.
+	// The AWS defaults are used if zero.
+	HealthyThresholdCount   uint
+	UnhealthyThresholdCount uint
+	// ListenerRules are additional rules of the listeners of application
+	// load balancers.
+	ListenerRules ListenerRuleList
+	// Authentication authenticates the requests of the HTTPS listener of
+	// application load balancers.
+	Authentication *ListenerAuthentication
+	// ResourceTags are set on the load balancers and target groups in
+	// addition to the tags of the stack.
+	ResourceTags ResourceTags
+	// TemplateResourceTags are rendered from the metadata of the ingresses
+	// and set like ResourceTags, which take precedence.
+	TemplateResourceTags ResourceTags
+	// LoadBalancerAttributes and TargetGroupAttributes are set on the load
+	// balancer and the target group of the cluster, overriding the ones
+	// set by the controller.
+	LoadBalancerAttributes Attributes
+	TargetGroupAttributes  Attributes
+	// DenyInternalDomains overrides the adapter setting denying requests
+	// to internal domains, "true" or "false". The adapter setting is used
+	// if empty.
+	DenyInternalDomains string
+	// DenyInternalDomainsResponse, DenyInternalDomainsResponseContentType
+	// and DenyInternalDomainsResponseStatusCode override the response for
+	// requests to internal domains if set.
+	DenyInternalDomainsResponse            string
+	DenyInternalDomainsResponseContentType string
+	DenyInternalDomainsResponseStatusCode  int
+	// Namespaces are the namespaces of the ingresses served by the load
+	// balancer. They are added as a tag for cost allocation if not empty.
+	Namespaces []string
+	// DNSHostnames are the hostnames for which weighted DNS records
+	// pointing to the load balancer should be managed.
+	DNSHostnames []string
+	// InternalDomains override the internal domains of the adapter if not
+	// empty, e.g. when read from a ConfigMap.
+	InternalDomains []string
+}
+
 // CreateStack creates a new Application Load Balancer using CloudFormation.
 // The stack name is derived from the Cluster ID and a has of the certificate
 // ARNs (when available).
 // All the required resources (listeners and target group) are created in a
 // transactional fashion.
 // Failure to create the stack causes it to be deleted automatically.
-func (a *Adapter) CreateStack(certificateARNs []string, scheme, securityGroup, owner, sslPolicy, ipAddressType, wafWebACLID string, cwAlarms CloudWatchAlarmList, loadBalancerType string, http2 bool) (string, error) {
-	certARNs := make(map[string]time.Time, len(certificateARNs))
-	for _, arn := range certificateARNs {
-		certARNs[arn] = time.Time{}
-	}
-
-	if sslPolicy == "" {
-		sslPolicy = a.sslPolicy
-	}
-
-	if _, ok := SSLPolicies[sslPolicy]; !ok {
-		return "", fmt.Errorf("invalid SSLPolicy '%s' defined", sslPolicy)
-	}
-
-	spec := &stackSpec{
-		name:            a.stackName(),
-		scheme:          scheme,
-		ownerIngress:    owner,
-		certificateARNs: certARNs,
-		securityGroupID: securityGroup,
-		subnets:         a.FindLBSubnets(scheme),
-		vpcID:           a.VpcID(),
-		clusterID:       a.ClusterID(),
-		healthCheck: &healthCheck{
-			path:     a.healthCheckPath,
-			port:     a.healthCheckPort,
-			interval: a.healthCheckInterval,
-			timeout:  a.healthCheckTimeout,
-		},
-		targetPort:                        a.targetPort,
-		targetHTTPS:                       a.targetHTTPS,
-		timeoutInMinutes:                  uint(a.creationTimeout.Minutes()),
-		stackTerminationProtection:        a.stackTerminationProtection,
-		idleConnectionTimeoutSeconds:      uint(a.idleConnectionTimeout.Seconds()),
-		deregistrationDelayTimeoutSeconds: uint(a.deregistrationDelayTimeout.Seconds()),
-		controllerID:                      a.controllerID,
-		sslPolicy:                         sslPolicy,
-		ipAddressType:                     ipAddressType,
-		loadbalancerType:                  loadBalancerType,
-		albLogsS3Bucket:                   a.albLogsS3Bucket,
-		albLogsS3Prefix:                   a.albLogsS3Prefix,
-		wafWebAclId:                       wafWebACLID,
-		cwAlarms:                          cwAlarms,
-		httpRedirectToHTTPS:               a.httpRedirectToHTTPS,
-		nlbCrossZone:                      a.nlbCrossZone,
-		nlbHTTPEnabled:                    a.nlbHTTPEnabled,
-		http2:                             http2,
-		tags:                              a.stackTags,
-		internalDomains:                   a.internalDomains,
-		denyInternalDomains:               a.denyInternalDomains,
-		denyInternalDomainsResponse: denyResp{
-			body:        a.denyInternalRespBody,
-			statusCode:  a.denyInternalRespStatusCode,
-			contentType: a.denyInternalRespContentType,
-		},
+func (a *Adapter) CreateStack(opts *StackOptions) (string, error) {
+	if opts.SSLPolicy == "" {
+		opts.SSLPolicy = a.sslPolicy
 	}
 
-	return createStack(a.cloudformation, spec)
+	spec, err := a.newStackSpec(a.stackName(), opts)
+	if err != nil {
+		return "", err
+	}
+
+	return createStack(a.cloudformation, spec, a.templateUploader())
 }
 
-func (a *Adapter) UpdateStack(stackName string, certificateARNs map[string]time.Time, scheme, securityGroup, owner, sslPolicy, ipAddressType, wafWebACLID string, cwAlarms CloudWatchAlarmList, loadBalancerType string, http2 bool) (string, error) {
-	if _, ok := SSLPolicies[sslPolicy]; !ok {
-		return "", fmt.Errorf("invalid SSLPolicy '%s' defined", sslPolicy)
+// UpdateStack updates the CloudFormation stack with the given name using the
+// specified options. The update is made with a change set if enabled, see
+// WithChangeSets.
+func (a *Adapter) UpdateStack(stackName string, opts *StackOptions) (string, error) {
+	spec, err := a.newStackSpec(stackName, opts)
+	if err != nil {
+		return "", err
 	}
 
-	spec := &stackSpec{
-		name:            stackName,
-		scheme:          scheme,
-		ownerIngress:    owner,
-		certificateARNs: certificateARNs,
-		securityGroupID: securityGroup,
-		subnets:         a.FindLBSubnets(scheme),
-		vpcID:           a.VpcID(),
-		clusterID:       a.ClusterID(),
-		healthCheck: &healthCheck{
-			path:     a.healthCheckPath,
-			port:     a.healthCheckPort,
-			interval: a.healthCheckInterval,
-			timeout:  a.healthCheckTimeout,
-		},
-		targetPort:                        a.targetPort,
-		targetHTTPS:                       a.targetHTTPS,
-		timeoutInMinutes:                  uint(a.creationTimeout.Minutes()),
-		stackTerminationProtection:        a.stackTerminationProtection,
-		idleConnectionTimeoutSeconds:      uint(a.idleConnectionTimeout.Seconds()),
-		deregistrationDelayTimeoutSeconds: uint(a.deregistrationDelayTimeout.Seconds()),
-		controllerID:                      a.controllerID,
-		sslPolicy:                         sslPolicy,
-		ipAddressType:                     ipAddressType,
-		loadbalancerType:                  loadBalancerType,
-		albLogsS3Bucket:                   a.albLogsS3Bucket,
-		albLogsS3Prefix:                   a.albLogsS3Prefix,
-		wafWebAclId:                       wafWebACLID,
-		cwAlarms:                          cwAlarms,
-		httpRedirectToHTTPS:               a.httpRedirectToHTTPS,
-		nlbCrossZone:                      a.nlbCrossZone,
-		nlbHTTPEnabled:                    a.nlbHTTPEnabled,
-		http2:                             http2,
-		tags:                              a.stackTags,
-		internalDomains:                   a.internalDomains,
-		denyInternalDomains:               a.denyInternalDomains,
-		denyInternalDomainsResponse: denyResp{
-			body:        a.denyInternalRespBody,
-			statusCode:  a.denyInternalRespStatusCode,
-			contentType: a.denyInternalRespContentType,
-		},
+	if a.changeSets == ChangeSetsNonDestructive || a.changeSets == ChangeSetsAlways {
+		return updateStackWithChangeSet(a.cloudformation, spec, a.templateUploader(), a.changeSets)
+	}
+	return updateStack(a.cloudformation, spec, a.templateUploader())
+}
+
+// UpdateStackTags updates the tags of the stack, e.g. the TTLs of its
+// certificates, without changing its template.
+func (a *Adapter) UpdateStackTags(stackName string, opts *StackOptions) (string, error) {
+	spec, err := a.newStackSpec(stackName, opts)
+	if err != nil {
+		return "", err
 	}
 
-	return updateStack(a.cloudformation, spec)
+	return updateStackTags(a.cloudformation, spec)
+}
+
+func (a *Adapter) newStackSpec(stackName string, opts *StackOptions) (*stackSpec, error) {
+	settings, err := a.templateSettings(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newStackSpec(stackName, settings, opts)
+}
+
+// templateSettings returns the settings of the adapter for the stack of a
+// load balancer with the given options.
+func (a *Adapter) templateSettings(opts *StackOptions) (*TemplateSettings, error) {
+	settings := &TemplateSettings{
+		ClusterID:                              a.ClusterID(),
+		VPCID:                                  a.VpcID(),
+		ControllerID:                           a.controllerID,
+		Subnets:                                a.FindLBSubnets(opts.Scheme),
+		HealthCheckPath:                        a.healthCheckPath,
+		HealthCheckPort:                        a.healthCheckPort,
+		HealthCheckInterval:                    a.healthCheckInterval,
+		HealthCheckTimeout:                     a.healthCheckTimeout,
+		TargetPort:                             a.targetPort,
+		TargetHTTPS:                            a.targetHTTPS,
+		TargetIPs:                              a.roleARN != "",
+		CreationTimeout:                        a.creationTimeout,
+		StackTerminationProtection:             a.stackTerminationProtection,
+		IdleConnectionTimeout:                  a.idleConnectionTimeout,
+		DeregistrationDelayTimeout:             a.deregistrationDelayTimeout,
+		ALBLogsS3Bucket:                        a.albLogsS3Bucket,
+		ALBLogsS3Prefix:                        a.albLogsS3Prefix,
+		HTTPRedirectToHTTPS:                    a.httpRedirectToHTTPS,
+		NLBCrossZone:                           a.nlbCrossZone,
+		NLBHTTPEnabled:                         a.nlbHTTPEnabled,
+		InternalDomains:                        a.internalDomains,
+		DenyInternalDomains:                    a.denyInternalDomains,
+		DenyInternalDomainsResponse:            a.denyInternalRespBody,
+		DenyInternalDomainsResponseContentType: a.denyInternalRespContentType,
+		DenyInternalDomainsResponseStatusCode:  a.denyInternalRespStatusCode,
+		StackTags:                              a.stackTags,
+		DNSOwnerID:                             a.dnsOwnerID,
+	}
+
+	if opts.LoadBalancerType == LoadBalancerTypeNetwork && IsDualstack(opts.IPAddressType) {
+		if subnets := a.subnetsWithoutIPv6(settings.Subnets); len(subnets) > 0 {
+			return nil, fmt.Errorf("dualstack network load balancer requires IPv6 CIDR blocks in subnets %s", strings.Join(subnets, ", "))
+		}
+	}
+
+	if len(opts.DNSHostnames) > 0 {
+		zoneIDs, err := a.hostedZoneIDs(opts.DNSHostnames, opts.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		settings.HostedZoneIDs = zoneIDs
+	}
+
+	return settings, nil
 }
 
 func (a *Adapter) stackName() string {
@@ -687,6 +1111,10 @@ func (a *Adapter) GetStack(stackID string) (*Stack, error) {
 
 // DeleteStack deletes the CloudFormation stack with the given name
 func (a *Adapter) DeleteStack(stack *Stack) error {
+	if a.roleARN != "" {
+		return deleteStack(a.cloudformation, stack.Name)
+	}
+
 	for _, asg := range a.TargetedAutoScalingGroups {
 		if err := detachTargetGroupsFromAutoScalingGroup(a.autoscaling, []string{stack.TargetGroupARN}, asg.name); err != nil {
 			return fmt.Errorf("DeleteStack failed to detach: %v", err)
@@ -696,6 +1124,55 @@ func (a *Adapter) DeleteStack(stack *Stack) error {
 	return deleteStack(a.cloudformation, stack.Name)
 }
 
+// DetachStack detaches the target group of the stack from the targeted auto
+// scaling groups and deregisters the single instances from it, so the load
+// balancer drains the connections to the targets before the stack is
+// deleted.
+func (a *Adapter) DetachStack(stack *Stack) error {
+	if stack.TargetGroupARN == "" {
+		return nil
+	}
+
+	if a.roleARN != "" {
+		if err := updateTargetIPs(a.elbv2, []string{stack.TargetGroupARN}, nil); err != nil {
+			return fmt.Errorf("DetachStack failed to deregister: %v", err)
+		}
+		return nil
+	}
+
+	for _, asg := range a.TargetedAutoScalingGroups {
+		if err := detachTargetGroupsFromAutoScalingGroup(a.autoscaling, []string{stack.TargetGroupARN}, asg.name); err != nil {
+			return fmt.Errorf("DetachStack failed to detach: %v", err)
+		}
+	}
+
+	if instances := a.RunningSingleInstances(); len(instances) > 0 {
+		if err := deregisterTargetsOnTargetGroups(a.elbv2, []string{stack.TargetGroupARN}, instances); err != nil {
+			return fmt.Errorf("DetachStack failed to deregister: %v", err)
+		}
+	}
+	return nil
+}
+
+// StackTargetsDrained returns true if the target group of the stack has no
+// targets left, neither registered nor draining.
+func (a *Adapter) StackTargetsDrained(stack *Stack) (bool, error) {
+	if stack.TargetGroupARN == "" {
+		return true, nil
+	}
+
+	resp, err := a.elbv2.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(stack.TargetGroupARN),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == elbv2.ErrCodeTargetGroupNotFoundException {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to describe the targets of %s: %v", stack.TargetGroupARN, err)
+	}
+	return len(resp.TargetHealthDescriptions) == 0, nil
+}
+
 func buildManifest(awsAdapter *Adapter, clusterID, vpcID string) (*manifest, error) {
 	var err error
 	var instanceDetails *instanceDetails
@@ -799,6 +1276,24 @@ func (a *Adapter) FindLBSubnets(scheme string) []string {
 	return subnetIDs
 }
 
+// subnetsWithoutIPv6 returns the IDs of the subnets without an IPv6 CIDR
+// block.
+func (a *Adapter) subnetsWithoutIPv6(subnetIDs []string) []string {
+	ipv6 := make(map[string]bool, len(a.manifest.subnets))
+	for _, subnet := range a.manifest.subnets {
+		ipv6[subnet.id] = subnet.ipv6
+	}
+
+	var missing []string
+	for _, id := range subnetIDs {
+		if !ipv6[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
 func getNameTag(tags map[string]string) (string, error) {
 	if name, err := getTag(tags, nameTag); err == nil {
 		return name, nil
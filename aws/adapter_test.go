@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -778,6 +779,40 @@ func TestFindLBSubnets(tt *testing.T) {
 	}
 }
 
+func TestTemplateSettingsDualstackNLB(t *testing.T) {
+	a := &Adapter{
+		manifest: &manifest{
+			subnets: []*subnetDetails{
+				{availabilityZone: "a", public: true, id: "1", ipv6: true},
+				{availabilityZone: "b", public: true, id: "2"},
+			},
+		},
+	}
+
+	_, err := a.templateSettings(&StackOptions{
+		LoadBalancerType: LoadBalancerTypeNetwork,
+		IPAddressType:    IPAddressTypeDualstack,
+		Scheme:           elbv2.LoadBalancerSchemeEnumInternetFacing,
+	})
+	assert.EqualError(t, err, "dualstack network load balancer requires IPv6 CIDR blocks in subnets 2")
+
+	_, err = a.templateSettings(&StackOptions{
+		LoadBalancerType: LoadBalancerTypeNetwork,
+		IPAddressType:    IPAddressTypeIPV4,
+		Scheme:           elbv2.LoadBalancerSchemeEnumInternetFacing,
+	})
+	assert.NoError(t, err)
+
+	a.manifest.subnets[1].ipv6 = true
+	settings, err := a.templateSettings(&StackOptions{
+		LoadBalancerType: LoadBalancerTypeNetwork,
+		IPAddressType:    IPAddressTypeDualstack,
+		Scheme:           elbv2.LoadBalancerSchemeEnumInternetFacing,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, settings.Subnets)
+}
+
 func TestParseFilterTagsDefault(t *testing.T) {
 	for _, test := range []struct {
 		name         string
@@ -927,3 +962,53 @@ func TestWithTargetHTTPS(t *testing.T) {
 		require.Equal(t, true, b.targetHTTPS)
 	})
 }
+
+func TestNewAdapterInRegion(t *testing.T) {
+	t.Run("NewAdapterInRegion requires the cluster and the VPC", func(t *testing.T) {
+		_, err := NewAdapterInRegion("", "controller", "vpc-1", "eu-west-1", "", false, true)
+		require.Error(t, err)
+		_, err = NewAdapterInRegion("cluster", "controller", "", "eu-west-1", "", false, true)
+		require.Error(t, err)
+	})
+}
+
+func TestIsWeakerSSLPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy  string
+		minimum string
+		weaker  bool
+	}{
+		{"ELBSecurityPolicy-2016-08", "ELBSecurityPolicy-TLS-1-2-2017-01", true},
+		{"ELBSecurityPolicy-TLS-1-2-2017-01", "ELBSecurityPolicy-TLS13-1-2-2021-06", true},
+		{"ELBSecurityPolicy-TLS13-1-0-2021-06", "ELBSecurityPolicy-TLS-1-2-2017-01", true},
+		{"ELBSecurityPolicy-TLS13-1-2-2021-06", "ELBSecurityPolicy-TLS13-1-2-2021-06", false},
+		{"ELBSecurityPolicy-TLS13-1-2-Res-2021-06", "ELBSecurityPolicy-TLS-1-2-2017-01", false},
+		{"ELBSecurityPolicy-TLS13-1-3-2021-06", "ELBSecurityPolicy-TLS13-1-2-2021-06", false},
+		{"unknown", "ELBSecurityPolicy-2016-08", true},
+	} {
+		t.Run(test.policy+"/"+test.minimum, func(t *testing.T) {
+			require.Equal(t, test.weaker, IsWeakerSSLPolicy(test.policy, test.minimum))
+		})
+	}
+}
+
+func TestSSLPolicyTLSVersions(t *testing.T) {
+	for _, policy := range SSLPoliciesList {
+		require.Contains(t, sslPolicyTLSVersions, policy)
+		require.True(t, SSLPolicies[policy])
+	}
+}
+
+func TestTargetIPs(t *testing.T) {
+	a := &Adapter{
+		TargetedAutoScalingGroups: map[string]*autoScalingGroupDetails{"asg1": {name: "asg1"}},
+		ec2Details: map[string]*instanceDetails{
+			"i-1": {id: "i-1", ip: "10.0.0.2", running: true, tags: map[string]string{autoScalingGroupNameTag: "asg1"}},
+			"i-2": {id: "i-2", ip: "10.0.0.1", running: true, tags: map[string]string{autoScalingGroupNameTag: "asg1"}},
+			"i-3": {id: "i-3", ip: "10.0.0.3", running: false, tags: map[string]string{autoScalingGroupNameTag: "asg1"}},
+			"i-4": {id: "i-4", ip: "10.0.0.4", running: true, tags: map[string]string{autoScalingGroupNameTag: "asg2"}},
+			"i-5": {id: "i-5", ip: "10.0.0.5", running: true},
+		},
+	}
+	require.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.5"}, a.targetIPs())
+}
@@ -155,12 +155,33 @@ func getOwnedAndTargetedAutoScalingGroups(service autoscalingiface.AutoScalingAP
 	return targetedASGs, ownedASGs, nil
 }
 
-func updateTargetGroupsForAutoScalingGroup(svc autoscalingiface.AutoScalingAPI, elbv2svc elbv2iface.ELBV2API, targetGroupARNs []string, autoScalingGroupName string, ownerTags map[string]string) error {
+// maxTargetGroupsPerCall is the maximum number of target groups attached to
+// or detached from an auto scaling group in one call of the API.
+const maxTargetGroupsPerCall = 10
+
+// describeLoadBalancerTargetGroups returns the target groups attached to the
+// auto scaling group, from all pages of the results.
+func describeLoadBalancerTargetGroups(svc autoscalingiface.AutoScalingAPI, autoScalingGroupName string) ([]*autoscaling.LoadBalancerTargetGroupState, error) {
 	params := &autoscaling.DescribeLoadBalancerTargetGroupsInput{
 		AutoScalingGroupName: aws.String(autoScalingGroupName),
 	}
 
-	resp, err := svc.DescribeLoadBalancerTargetGroups(params)
+	var targetGroups []*autoscaling.LoadBalancerTargetGroupState
+	for {
+		resp, err := svc.DescribeLoadBalancerTargetGroups(params)
+		if err != nil {
+			return nil, err
+		}
+		targetGroups = append(targetGroups, resp.LoadBalancerTargetGroups...)
+		if aws.StringValue(resp.NextToken) == "" {
+			return targetGroups, nil
+		}
+		params.NextToken = resp.NextToken
+	}
+}
+
+func updateTargetGroupsForAutoScalingGroup(svc autoscalingiface.AutoScalingAPI, elbv2svc elbv2iface.ELBV2API, targetGroupARNs []string, autoScalingGroupName string, ownerTags map[string]string) error {
+	attached, err := describeLoadBalancerTargetGroups(svc, autoScalingGroupName)
 	if err != nil {
 		return err
 	}
@@ -168,7 +189,7 @@ func updateTargetGroupsForAutoScalingGroup(svc autoscalingiface.AutoScalingAPI,
 	// get all target groups to ensure we are only working with target
 	// groups that still exists.
 	tgParams := &elbv2.DescribeTargetGroupsInput{}
-	allTGs := make(map[string]struct{}, len(resp.LoadBalancerTargetGroups))
+	allTGs := make(map[string]struct{}, len(attached))
 	err = elbv2svc.DescribeTargetGroupsPagesWithContext(context.TODO(), tgParams, func(resp *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
 		for _, tg := range resp.TargetGroups {
 			allTGs[aws.StringValue(tg.TargetGroupArn)] = struct{}{}
@@ -179,11 +200,12 @@ func updateTargetGroupsForAutoScalingGroup(svc autoscalingiface.AutoScalingAPI,
 		return err
 	}
 
-	if len(resp.LoadBalancerTargetGroups) > 0 {
+	var detachErr error
+	if len(attached) > 0 {
 		// find non-existing target groups which should be detached
 		detachARNs := make([]string, 0, len(targetGroupARNs))
 		validARNs := make([]string, 0, len(targetGroupARNs))
-		for _, tg := range resp.LoadBalancerTargetGroups {
+		for _, tg := range attached {
 			tgARN := aws.StringValue(tg.LoadBalancerTargetGroupARN)
 
 			// check that TG exists at all, otherwise detach it
@@ -212,39 +234,47 @@ func updateTargetGroupsForAutoScalingGroup(svc autoscalingiface.AutoScalingAPI,
 			}
 		}
 
-		if len(detachARNs) > 0 {
-			err = detachTargetGroupsFromAutoScalingGroup(svc, detachARNs, autoScalingGroupName)
-			if err != nil {
-				return err
-			}
-		}
+		// the target groups are still attached if detaching fails, they're
+		// detached again on the next update
+		detachErr = detachTargetGroupsFromAutoScalingGroup(svc, detachARNs, autoScalingGroupName)
 	}
 
-	// limit target group updates to 10 at a time since this is the limit
-	// in AWS API.
-	chunkSize := 10
+	// a failed batch doesn't keep the others from being attached, they're
+	// all attached again on the next update
+	var failed []string
+	var attachErr error
+	for _, groups := range chunkTargetGroups(targetGroupARNs) {
+		attachParams := &autoscaling.AttachLoadBalancerTargetGroupsInput{
+			AutoScalingGroupName: aws.String(autoScalingGroupName),
+			TargetGroupARNs:      aws.StringSlice(groups),
+		}
+		if _, err := svc.AttachLoadBalancerTargetGroups(attachParams); err != nil {
+			failed = append(failed, groups...)
+			attachErr = err
+		}
+	}
 
-	for i := 0; i < len(targetGroupARNs); i += chunkSize {
-		end := i + chunkSize
+	switch {
+	case attachErr != nil && detachErr != nil:
+		return fmt.Errorf("failed to detach target groups: %v, failed to attach target groups %v: %v", detachErr, failed, attachErr)
+	case attachErr != nil:
+		return fmt.Errorf("failed to attach target groups %v: %v", failed, attachErr)
+	}
+	return detachErr
+}
 
+// chunkTargetGroups splits the target groups into chunks of at most
+// maxTargetGroupsPerCall.
+func chunkTargetGroups(targetGroupARNs []string) [][]string {
+	var chunks [][]string
+	for i := 0; i < len(targetGroupARNs); i += maxTargetGroupsPerCall {
+		end := i + maxTargetGroupsPerCall
 		if end > len(targetGroupARNs) {
 			end = len(targetGroupARNs)
 		}
-
-		groups := targetGroupARNs[i:end]
-		if len(groups) > 0 {
-			attachParams := &autoscaling.AttachLoadBalancerTargetGroupsInput{
-				AutoScalingGroupName: aws.String(autoScalingGroupName),
-				TargetGroupARNs:      aws.StringSlice(groups),
-			}
-			_, err = svc.AttachLoadBalancerTargetGroups(attachParams)
-			if err != nil {
-				return err
-			}
-		}
+		chunks = append(chunks, targetGroupARNs[i:end])
 	}
-
-	return nil
+	return chunks
 }
 
 func describeTagsChunked(svc elbv2iface.ELBV2API, arns []string) ([]*elbv2.TagDescription, error) {
@@ -322,31 +352,26 @@ func hasTagsASG(tags []*autoscaling.TagDescription, expectedTags map[string]stri
 	return true
 }
 
+// detachTargetGroupsFromAutoScalingGroup detaches the target groups in
+// chunks. All chunks are detached even if one fails, the error names the
+// target groups which are still attached.
 func detachTargetGroupsFromAutoScalingGroup(svc autoscalingiface.AutoScalingAPI, targetGroupARNs []string, autoScalingGroupName string) error {
-	// limit target group updates to 10 at a time since this is the limit
-	// in AWS API.
-	chunkSize := 10
-
-	for i := 0; i < len(targetGroupARNs); i += chunkSize {
-		end := i + chunkSize
-
-		if end > len(targetGroupARNs) {
-			end = len(targetGroupARNs)
+	var failed []string
+	var lastErr error
+	for _, groups := range chunkTargetGroups(targetGroupARNs) {
+		params := &autoscaling.DetachLoadBalancerTargetGroupsInput{
+			AutoScalingGroupName: aws.String(autoScalingGroupName),
+			TargetGroupARNs:      aws.StringSlice(groups),
 		}
-
-		targetGroupARNsChunked := targetGroupARNs[i:end]
-		if len(targetGroupARNsChunked) > 0 {
-			params := &autoscaling.DetachLoadBalancerTargetGroupsInput{
-				AutoScalingGroupName: aws.String(autoScalingGroupName),
-				TargetGroupARNs:      aws.StringSlice(targetGroupARNsChunked),
-			}
-			_, err := svc.DetachLoadBalancerTargetGroups(params)
-			if err != nil {
-				return err
-			}
+		if _, err := svc.DetachLoadBalancerTargetGroups(params); err != nil {
+			failed = append(failed, groups...)
+			lastErr = err
 		}
 	}
 
+	if lastErr != nil {
+		return fmt.Errorf("failed to detach target groups %v: %v", failed, lastErr)
+	}
 	return nil
 }
 
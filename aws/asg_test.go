@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/require"
 )
 
 type asgtags map[string]string
@@ -535,3 +536,68 @@ func TestTestFilterTags(t *testing.T) {
 		})
 	}
 }
+
+func TestAttachManyTargetGroups(t *testing.T) {
+	targetGroupARNs := make([]string, 25)
+	for i := range targetGroupARNs {
+		targetGroupARNs[i] = fmt.Sprintf("tg-%02d", i)
+	}
+	ownerTags := []*elbv2.Tag{{Key: aws.String("owner"), Value: aws.String("true")}}
+
+	for _, test := range []struct {
+		name      string
+		attachErr error
+		wantError bool
+	}{
+		{name: "success"},
+		{name: "failed-attach", attachErr: errDummy, wantError: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mockSvc := &mockAutoScalingClient{
+				outputs: autoscalingMockOutputs{
+					attachLoadBalancerTargetGroups: R(nil, test.attachErr),
+					detachLoadBalancerTargetGroups: R(nil, nil),
+				},
+				targetGroupPages: []*autoscaling.DescribeLoadBalancerTargetGroupsOutput{
+					{
+						LoadBalancerTargetGroups: []*autoscaling.LoadBalancerTargetGroupState{
+							{LoadBalancerTargetGroupARN: aws.String("obsolete-1")},
+						},
+						NextToken: aws.String("1"),
+					},
+					{
+						LoadBalancerTargetGroups: []*autoscaling.LoadBalancerTargetGroupState{
+							{LoadBalancerTargetGroupARN: aws.String("obsolete-2")},
+						},
+					},
+				},
+			}
+			mockElbv2Svc := &mockElbv2Client{outputs: elbv2MockOutputs{
+				describeTargetGroups: R(&elbv2.DescribeTargetGroupsOutput{
+					TargetGroups: []*elbv2.TargetGroup{
+						{TargetGroupArn: aws.String("obsolete-1")},
+						{TargetGroupArn: aws.String("obsolete-2")},
+					},
+				}, nil),
+				describeTags: R(&elbv2.DescribeTagsOutput{
+					TagDescriptions: []*elbv2.TagDescription{
+						{ResourceArn: aws.String("obsolete-1"), Tags: ownerTags},
+						{ResourceArn: aws.String("obsolete-2"), Tags: ownerTags},
+					},
+				}, nil),
+			}}
+
+			err := updateTargetGroupsForAutoScalingGroup(mockSvc, mockElbv2Svc, targetGroupARNs, "asg", map[string]string{"owner": "true"})
+			if test.wantError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// the target groups of all pages are detached
+			require.Equal(t, [][]string{{"obsolete-1", "obsolete-2"}}, mockSvc.detached)
+			// all batches are attached even if one fails
+			require.Equal(t, [][]string{targetGroupARNs[:10], targetGroupARNs[10:20], targetGroupARNs[20:]}, mockSvc.attached)
+		})
+	}
+}
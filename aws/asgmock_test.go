@@ -1,6 +1,8 @@
 package aws
 
 import (
+	"strconv"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
@@ -16,6 +18,11 @@ type autoscalingMockOutputs struct {
 type mockAutoScalingClient struct {
 	autoscalingiface.AutoScalingAPI
 	outputs autoscalingMockOutputs
+	// targetGroupPages are the pages of the target groups of the auto
+	// scaling group, the NextToken of a page is the index of the next one.
+	targetGroupPages []*autoscaling.DescribeLoadBalancerTargetGroupsOutput
+	attached         [][]string
+	detached         [][]string
 }
 
 func (m *mockAutoScalingClient) DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
@@ -32,18 +39,24 @@ func (m *mockAutoScalingClient) DescribeAutoScalingGroupsPages(_ *autoscaling.De
 	return m.outputs.describeAutoScalingGroups.err
 }
 
-func (m *mockAutoScalingClient) DescribeLoadBalancerTargetGroups(*autoscaling.DescribeLoadBalancerTargetGroupsInput) (*autoscaling.DescribeLoadBalancerTargetGroupsOutput, error) {
+func (m *mockAutoScalingClient) DescribeLoadBalancerTargetGroups(in *autoscaling.DescribeLoadBalancerTargetGroupsInput) (*autoscaling.DescribeLoadBalancerTargetGroupsOutput, error) {
+	if len(m.targetGroupPages) > 0 {
+		page, _ := strconv.Atoi(aws.StringValue(in.NextToken))
+		return m.targetGroupPages[page], nil
+	}
 	if out, ok := m.outputs.describeLoadBalancerTargetGroups.response.(*autoscaling.DescribeLoadBalancerTargetGroupsOutput); ok {
 		return out, m.outputs.describeLoadBalancerTargetGroups.err
 	}
 	return nil, m.outputs.describeLoadBalancerTargetGroups.err
 }
 
-func (m *mockAutoScalingClient) AttachLoadBalancerTargetGroups(*autoscaling.AttachLoadBalancerTargetGroupsInput) (*autoscaling.AttachLoadBalancerTargetGroupsOutput, error) {
+func (m *mockAutoScalingClient) AttachLoadBalancerTargetGroups(in *autoscaling.AttachLoadBalancerTargetGroupsInput) (*autoscaling.AttachLoadBalancerTargetGroupsOutput, error) {
+	m.attached = append(m.attached, aws.StringValueSlice(in.TargetGroupARNs))
 	return nil, m.outputs.attachLoadBalancerTargetGroups.err
 }
 
-func (m *mockAutoScalingClient) DetachLoadBalancerTargetGroups(*autoscaling.DetachLoadBalancerTargetGroupsInput) (*autoscaling.DetachLoadBalancerTargetGroupsOutput, error) {
+func (m *mockAutoScalingClient) DetachLoadBalancerTargetGroups(in *autoscaling.DetachLoadBalancerTargetGroupsInput) (*autoscaling.DetachLoadBalancerTargetGroupsOutput, error) {
+	m.detached = append(m.detached, aws.StringValueSlice(in.TargetGroupARNs))
 	return nil, m.outputs.detachLoadBalancerTargetGroups.err
 }
 
@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// assumeRoleSessionName is the name of the sessions of the assumed roles,
+// which identifies the controller in CloudTrail.
+const assumeRoleSessionName = "kube-ingress-aws-controller"
+
+// roleAdapters caches the adapters of the roles assumed by an Adapter, so
+// the credentials of a role are only refreshed when they expire.
+type roleAdapters struct {
+	mu       sync.Mutex
+	adapters map[string]*Adapter
+}
+
+// IsRoleARN returns true if the ARN is the one of an IAM role.
+func IsRoleARN(s string) bool {
+	a, err := arn.Parse(s)
+	return err == nil && a.Service == "iam" && strings.HasPrefix(a.Resource, "role/") && len(a.Resource) > len("role/")
+}
+
+// AssumeRole returns an Adapter which creates the stacks and the load
+// balancers in the account of the role, with credentials of the role
+// obtained from STS. The instances and auto scaling groups of the cluster
+// are still the ones of the account of the Adapter, as are its subnets and
+// security group. The adapters are cached by role ARN.
+func (a *Adapter) AssumeRole(roleARN string) (*Adapter, error) {
+	if !IsRoleARN(roleARN) {
+		return nil, fmt.Errorf("invalid role ARN %q", roleARN)
+	}
+	if a.session == nil || a.roles == nil {
+		return nil, errors.New("assuming roles requires the clients of an AWS session")
+	}
+
+	a.roles.mu.Lock()
+	defer a.roles.mu.Unlock()
+
+	if adapter, ok := a.roles.adapters[roleARN]; ok {
+		return adapter, nil
+	}
+
+	stsClient := sts.New(a.session.provider, a.session.config)
+	creds := stscreds.NewCredentialsWithClient(stsClient, roleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = assumeRoleSessionName
+	})
+	clients := a.session.clients(creds)
+	// the clients of the account of the cluster already inject the faults
+	a.faults.inject(stsClient, clients.ELBV2, clients.ACM, clients.IAM, clients.CloudFormation, clients.Route53,
+		clients.ServiceQuotas, clients.WAFV2, clients.WAFRegional, clients.S3)
+
+	adapter := *a
+	adapter.elbv2 = clients.ELBV2
+	adapter.acm = clients.ACM
+	adapter.iam = clients.IAM
+	adapter.cloudformation = clients.CloudFormation
+	adapter.route53 = clients.Route53
+	adapter.servicequotas = clients.ServiceQuotas
+	adapter.wafv2 = clients.WAFV2
+	adapter.wafregional = clients.WAFRegional
+	adapter.s3 = clients.S3
+	adapter.roleARN = roleARN
+	// the state of the instances and of the validations is kept per
+	// account, roles can't be assumed again from the account of a role
+	adapter.TargetedAutoScalingGroups = nil
+	adapter.OwnedAutoScalingGroups = nil
+	adapter.ec2Details = make(map[string]*instanceDetails)
+	adapter.singleInstances = make(map[string]*instanceDetails)
+	adapter.obsoleteInstances = make([]string, 0)
+	adapter.drainingInstances = nil
+	adapter.validatedResources = make(map[string]time.Time)
+	adapter.hostedZones = nil
+	adapter.roles = nil
+
+	a.roles.adapters[roleARN] = &adapter
+	return &adapter, nil
+}
+
+// RoleARN returns the ARN of the role assumed by the Adapter, or an empty
+// string if it uses the credentials of the controller.
+func (a *Adapter) RoleARN() string {
+	return a.roleARN
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRoleARN(t *testing.T) {
+	require.True(t, IsRoleARN("arn:aws:iam::123456789012:role/load-balancers"))
+	require.True(t, IsRoleARN("arn:aws:iam::123456789012:role/path/load-balancers"))
+	require.False(t, IsRoleARN("arn:aws:iam::123456789012:role/"))
+	require.False(t, IsRoleARN("arn:aws:iam::123456789012:user/load-balancers"))
+	require.False(t, IsRoleARN("arn:aws:lambda:eu-central-1:123456789012:function:maintenance"))
+	require.False(t, IsRoleARN("load-balancers"))
+}
+
+func TestAssumeRole(t *testing.T) {
+	cfg := aws.NewConfig().WithRegion("eu-central-1")
+	sc := &sessionConfig{
+		provider: session.Must(session.NewSession()),
+		config:   cfg,
+		s3Config: cfg,
+	}
+	a := newAdapter("controller", sc.clients(nil))
+	a.session = sc
+	a.manifest = &manifest{clusterID: "cluster", vpcID: "vpc-1"}
+
+	const roleARN = "arn:aws:iam::123456789012:role/load-balancers"
+	roleAdapter, err := a.AssumeRole(roleARN)
+	require.NoError(t, err)
+	require.Equal(t, roleARN, roleAdapter.RoleARN())
+	require.Empty(t, a.RoleARN())
+	require.Equal(t, "cluster", roleAdapter.ClusterID())
+
+	// the instances are the ones of the account of the cluster
+	require.True(t, a.ec2 == roleAdapter.ec2)
+	require.True(t, a.autoscaling == roleAdapter.autoscaling)
+	require.False(t, a.cloudformation == roleAdapter.cloudformation)
+	require.False(t, a.elbv2 == roleAdapter.elbv2)
+
+	// the adapters are cached by role
+	cached, err := a.AssumeRole(roleARN)
+	require.NoError(t, err)
+	require.True(t, roleAdapter == cached)
+
+	// roles can't be assumed from the account of a role
+	_, err = roleAdapter.AssumeRole("arn:aws:iam::123456789012:role/other")
+	require.Error(t, err)
+
+	_, err = a.AssumeRole("load-balancers")
+	require.Error(t, err)
+
+	// adapters with fake clients can't assume roles
+	_, err = newAdapter("controller", Clients{}).AssumeRole(roleARN)
+	require.Error(t, err)
+}
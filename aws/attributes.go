@@ -0,0 +1,161 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	cloudformation "github.com/mweagle/go-cloudformation"
+	log "github.com/sirupsen/logrus"
+)
+
+var attributeKeyRegex = regexp.MustCompile(`^[a-z0-9_.]{1,256}$`)
+
+// Attributes are ELBv2 attributes of the load balancer or the target group
+// of a stack, in addition to the ones set by the controller, which they
+// override. They allow using attributes the controller has no dedicated
+// setting for.
+type Attributes map[string]string
+
+// NewAttributes parses a comma separated list of attributes of the form
+// key=value.
+func NewAttributes(value string) (Attributes, error) {
+	attributes := Attributes{}
+	for _, attribute := range strings.Split(value, ",") {
+		attribute = strings.TrimSpace(attribute)
+		if attribute == "" {
+			continue
+		}
+
+		kv := strings.SplitN(attribute, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("attribute %q is not of the form key=value", attribute)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if !attributeKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid attribute key %q", key)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("attribute %q has no value", key)
+		}
+		attributes[key] = value
+	}
+	return attributes, nil
+}
+
+// Filter returns the attributes whose keys match any of the patterns of
+// path.Match, and the sorted keys of the others.
+func (a Attributes) Filter(patterns []string) (Attributes, []string) {
+	allowed := make(Attributes, len(a))
+	var denied []string
+	for key, value := range a {
+		if matchesAny(key, patterns) {
+			allowed[key] = value
+		} else {
+			denied = append(denied, key)
+		}
+	}
+	sort.Strings(denied)
+	return allowed, denied
+}
+
+func matchesAny(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAttributePatterns checks the syntax of the patterns of allowed
+// attributes.
+func ValidateAttributePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid attribute pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// HashAttributes returns a stable hash of the attributes of the load
+// balancer and the target group. No attributes result in an empty hash.
+func HashAttributes(loadBalancer, targetGroup Attributes) string {
+	if len(loadBalancer) == 0 && len(targetGroup) == 0 {
+		return ""
+	}
+
+	// maps are marshalled with sorted keys
+	buf, err := json.Marshal(struct {
+		LoadBalancer Attributes `json:"loadBalancer,omitempty"`
+		TargetGroup  Attributes `json:"targetGroup,omitempty"`
+	}{loadBalancer, targetGroup})
+	if err != nil {
+		log.Errorf("failed to marshal attributes: %v", err)
+		return ""
+	}
+
+	hash := sha256.Sum256(buf)
+	return hex.EncodeToString(hash[:])
+}
+
+// sortedKeys returns the keys of the attributes which aren't in the list,
+// sorted.
+func (a Attributes) sortedKeys(exclude map[string]bool) []string {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		if !exclude[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// loadBalancerAttributes returns the list with the values of the attributes
+// of the same keys replaced and the other attributes appended.
+func (a Attributes) loadBalancerAttributes(list cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttributeList) cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttributeList {
+	set := make(map[string]bool, len(list))
+	for i, attribute := range list {
+		key := attribute.Key.Literal
+		if value, ok := a[key]; ok {
+			list[i].Value = cloudformation.String(value)
+		}
+		set[key] = true
+	}
+
+	for _, key := range a.sortedKeys(set) {
+		list = append(list, cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+			Key:   cloudformation.String(key),
+			Value: cloudformation.String(a[key]),
+		})
+	}
+	return list
+}
+
+// targetGroupAttributes returns the list with the values of the attributes
+// of the same keys replaced and the other attributes appended.
+func (a Attributes) targetGroupAttributes(list cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttributeList) cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttributeList {
+	set := make(map[string]bool, len(list))
+	for i, attribute := range list {
+		key := attribute.Key.Literal
+		if value, ok := a[key]; ok {
+			list[i].Value = cloudformation.String(value)
+		}
+		set[key] = true
+	}
+
+	for _, key := range a.sortedKeys(set) {
+		list = append(list, cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttribute{
+			Key:   cloudformation.String(key),
+			Value: cloudformation.String(a[key]),
+		})
+	}
+	return list
+}
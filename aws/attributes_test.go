@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"testing"
+
+	cloudformation "github.com/mweagle/go-cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAttributes(t *testing.T) {
+	for _, test := range []struct {
+		msg       string
+		given     string
+		want      Attributes
+		wantError bool
+	}{
+		{
+			msg:   "attributes",
+			given: "routing.http.drop_invalid_header_fields.enabled=true, waf.fail_open.enabled = true",
+			want: Attributes{
+				"routing.http.drop_invalid_header_fields.enabled": "true",
+				"waf.fail_open.enabled":                           "true",
+			},
+		},
+		{
+			msg:   "value with equal sign",
+			given: "routing.http.xff_header_processing.mode=append=1",
+			want:  Attributes{"routing.http.xff_header_processing.mode": "append=1"},
+		},
+		{
+			msg:   "empty",
+			given: ",",
+			want:  Attributes{},
+		},
+		{
+			msg:       "missing value",
+			given:     "waf.fail_open.enabled",
+			wantError: true,
+		},
+		{
+			msg:       "empty value",
+			given:     "waf.fail_open.enabled=",
+			wantError: true,
+		},
+		{
+			msg:       "invalid key",
+			given:     "WAF Fail Open=true",
+			wantError: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			got, err := NewAttributes(test.given)
+			if test.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestAttributesFilter(t *testing.T) {
+	attributes := Attributes{
+		"routing.http.drop_invalid_header_fields.enabled": "true",
+		"routing.http.desync_mitigation_mode":             "strictest",
+		"deletion_protection.enabled":                     "true",
+		"waf.fail_open.enabled":                           "true",
+	}
+
+	allowed, denied := attributes.Filter([]string{"routing.http.*", "waf.fail_open.enabled"})
+	assert.Equal(t, Attributes{
+		"routing.http.drop_invalid_header_fields.enabled": "true",
+		"routing.http.desync_mitigation_mode":             "strictest",
+		"waf.fail_open.enabled":                           "true",
+	}, allowed)
+	assert.Equal(t, []string{"deletion_protection.enabled"}, denied)
+
+	allowed, denied = attributes.Filter(nil)
+	assert.Empty(t, allowed)
+	assert.Len(t, denied, 4)
+}
+
+func TestValidateAttributePatterns(t *testing.T) {
+	assert.NoError(t, ValidateAttributePatterns([]string{"routing.http.*", "waf.fail_open.enabled"}))
+	assert.Error(t, ValidateAttributePatterns([]string{"routing.[http"}))
+}
+
+func TestHashAttributes(t *testing.T) {
+	assert.Empty(t, HashAttributes(nil, Attributes{}))
+
+	lb := HashAttributes(Attributes{"a": "1"}, nil)
+	tg := HashAttributes(nil, Attributes{"a": "1"})
+	assert.NotEmpty(t, lb)
+	assert.NotEqual(t, lb, tg)
+	assert.Equal(t, lb, HashAttributes(Attributes{"a": "1"}, Attributes{}))
+}
+
+func TestLoadBalancerAttributesOverride(t *testing.T) {
+	list := cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttributeList{
+		{Key: cloudformation.String("idle_timeout.timeout_seconds"), Value: cloudformation.String("60")},
+	}
+	got := Attributes{
+		"idle_timeout.timeout_seconds": "120",
+		"waf.fail_open.enabled":        "true",
+		"deletion_protection.enabled":  "true",
+	}.loadBalancerAttributes(list)
+
+	assert.Equal(t, cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttributeList{
+		{Key: cloudformation.String("idle_timeout.timeout_seconds"), Value: cloudformation.String("120")},
+		{Key: cloudformation.String("deletion_protection.enabled"), Value: cloudformation.String("true")},
+		{Key: cloudformation.String("waf.fail_open.enabled"), Value: cloudformation.String("true")},
+	}, got)
+}
@@ -0,0 +1,56 @@
+package aws
+
+import (
+	cloudformation "github.com/mweagle/go-cloudformation"
+)
+
+const (
+	// MinCapacityUnits is the minimum capacity reservation of application
+	// load balancers in load balancer capacity units (LCU).
+	MinCapacityUnits = 100
+)
+
+// IsValidCapacityUnits returns true if the number of capacity units can be
+// reserved for an application load balancer.
+func IsValidCapacityUnits(units int64) bool {
+	return units >= MinCapacityUnits
+}
+
+// hasCapacityReservation returns true if capacity is reserved for the load
+// balancer of the stack. Only application load balancers are supported.
+func (spec *stackSpec) hasCapacityReservation() bool {
+	return spec.loadbalancerType == LoadBalancerTypeApplication && spec.capacityUnits > 0
+}
+
+// loadBalancerProperties adds the capacity reservation and the IPAM pool to
+// the properties of a load balancer, as the CloudFormation library doesn't
+// support them yet.
+type loadBalancerProperties struct {
+	*cloudformation.ElasticLoadBalancingV2LoadBalancer
+	MinimumLoadBalancerCapacity *minimumLoadBalancerCapacity `json:"MinimumLoadBalancerCapacity,omitempty"`
+	Ipv4IpamPoolID              *cloudformation.StringExpr   `json:"Ipv4IpamPoolId,omitempty"`
+}
+
+type minimumLoadBalancerCapacity struct {
+	CapacityUnits *cloudformation.IntegerExpr `json:"CapacityUnits"`
+}
+
+// loadBalancerResource returns the properties of the load balancer of the
+// stack, with the capacity units parameter reserved and the addresses taken
+// from the IPv4 pool parameter if configured.
+func loadBalancerResource(spec *stackSpec, lb *cloudformation.ElasticLoadBalancingV2LoadBalancer) cloudformation.ResourceProperties {
+	if !spec.hasCapacityReservation() && !spec.hasIPAMPool() {
+		return lb
+	}
+
+	properties := &loadBalancerProperties{ElasticLoadBalancingV2LoadBalancer: lb}
+	if spec.hasCapacityReservation() {
+		properties.MinimumLoadBalancerCapacity = &minimumLoadBalancerCapacity{
+			CapacityUnits: cloudformation.Ref(parameterCapacityUnitsParameter).Integer(),
+		}
+	}
+	if spec.hasIPAMPool() {
+		properties.Ipv4IpamPoolID = cloudformation.Ref(parameterIPv4PoolParameter).String()
+	}
+	return properties
+}
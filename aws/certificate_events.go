@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	log "github.com/sirupsen/logrus"
+)
+
+// The detail types of the events of AWS Certificate Manager, see
+// https://docs.aws.amazon.com/acm/latest/userguide/supported-events.html
+const (
+	CertificateEventAvailable             = "ACM Certificate Available"
+	CertificateEventApproachingExpiration = "ACM Certificate Approaching Expiration"
+	CertificateEventExpired               = "ACM Certificate Expired"
+	CertificateEventRenewalActionRequired = "ACM Certificate Renewal Action Required"
+)
+
+// certificateEventsWaitTime is the time receiving certificate events waits
+// for messages, using long polling of the queue.
+const certificateEventsWaitTime = 20
+
+// CertificateEvent is an event of AWS Certificate Manager, delivered by an
+// EventBridge rule to an SQS queue.
+type CertificateEvent struct {
+	// Type is the detail type of the event, one of the CertificateEvent
+	// constants.
+	Type            string
+	CertificateARNs []string
+	// DaysToExpiry is the number of days until the certificates expire,
+	// for events approaching expiration.
+	DaysToExpiry int
+}
+
+// Expiring returns true if the event warns about an expiring certificate,
+// which isn't renewed by ACM on its own.
+func (e *CertificateEvent) Expiring() bool {
+	return e.Type != CertificateEventAvailable
+}
+
+type eventBridgeEvent struct {
+	DetailType string   `json:"detail-type"`
+	Source     string   `json:"source"`
+	Resources  []string `json:"resources"`
+	Detail     struct {
+		DaysToExpiry int `json:"DaysToExpiry"`
+	} `json:"detail"`
+}
+
+func parseCertificateEvent(body string) (*CertificateEvent, error) {
+	var event eventBridgeEvent
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return nil, err
+	}
+	if event.Source != "aws.acm" {
+		return nil, fmt.Errorf("unexpected source %q", event.Source)
+	}
+
+	switch event.DetailType {
+	case CertificateEventAvailable, CertificateEventApproachingExpiration, CertificateEventExpired, CertificateEventRenewalActionRequired:
+	default:
+		return nil, fmt.Errorf("unexpected detail type %q", event.DetailType)
+	}
+
+	return &CertificateEvent{
+		Type:            event.DetailType,
+		CertificateARNs: event.Resources,
+		DaysToExpiry:    event.Detail.DaysToExpiry,
+	}, nil
+}
+
+// ReceiveCertificateEvents waits for the events of AWS Certificate Manager in
+// the SQS queue and deletes them from the queue. Messages which aren't
+// certificate events are logged and deleted as well.
+func (a *Adapter) ReceiveCertificateEvents(queueURL string) ([]*CertificateEvent, error) {
+	resp, err := a.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueURL),
+		MaxNumberOfMessages: aws.Int64(10),
+		WaitTimeSeconds:     aws.Int64(certificateEventsWaitTime),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive the certificate events of %s: %v", queueURL, err)
+	}
+
+	events := make([]*CertificateEvent, 0, len(resp.Messages))
+	for _, msg := range resp.Messages {
+		event, err := parseCertificateEvent(aws.StringValue(msg.Body))
+		if err != nil {
+			log.Warnf("Ignoring invalid certificate event %s: %v", aws.StringValue(msg.MessageId), err)
+		} else {
+			events = append(events, event)
+		}
+
+		_, err = a.sqs.DeleteMessage(&sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(queueURL),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+		if err != nil {
+			// the event is received again, handling it twice is harmless
+			log.Warnf("Failed to delete certificate event %s: %v", aws.StringValue(msg.MessageId), err)
+		}
+	}
+	return events, nil
+}
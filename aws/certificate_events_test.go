@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockedSQSClient struct {
+	sqsiface.SQSAPI
+	messages []*sqs.Message
+	deleted  []string
+}
+
+func (m *mockedSQSClient) ReceiveMessage(*sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	return &sqs.ReceiveMessageOutput{Messages: m.messages}, nil
+}
+
+func (m *mockedSQSClient) DeleteMessage(in *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	m.deleted = append(m.deleted, aws.StringValue(in.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func TestReceiveCertificateEvents(t *testing.T) {
+	svc := &mockedSQSClient{
+		messages: []*sqs.Message{
+			{
+				ReceiptHandle: aws.String("expiring"),
+				Body: aws.String(`{
+					"detail-type": "ACM Certificate Approaching Expiration",
+					"source": "aws.acm",
+					"resources": ["arn:aws:acm:eu-central-1:123456789012:certificate/a"],
+					"detail": {"DaysToExpiry": 31, "CommonName": "example.org"}
+				}`),
+			},
+			{
+				ReceiptHandle: aws.String("renewed"),
+				Body: aws.String(`{
+					"detail-type": "ACM Certificate Available",
+					"source": "aws.acm",
+					"resources": ["arn:aws:acm:eu-central-1:123456789012:certificate/b"],
+					"detail": {"Action": "RENEWAL"}
+				}`),
+			},
+			{
+				ReceiptHandle: aws.String("other"),
+				Body:          aws.String(`{"detail-type": "EC2 Instance State-change Notification", "source": "aws.ec2"}`),
+			},
+			{
+				ReceiptHandle: aws.String("invalid"),
+				Body:          aws.String(`not json`),
+			},
+		},
+	}
+	a := &Adapter{sqs: svc}
+
+	events, err := a.ReceiveCertificateEvents("queue")
+	require.NoError(t, err)
+	assert.Equal(t, []*CertificateEvent{
+		{
+			Type:            CertificateEventApproachingExpiration,
+			CertificateARNs: []string{"arn:aws:acm:eu-central-1:123456789012:certificate/a"},
+			DaysToExpiry:    31,
+		},
+		{
+			Type:            CertificateEventAvailable,
+			CertificateARNs: []string{"arn:aws:acm:eu-central-1:123456789012:certificate/b"},
+		},
+	}, events)
+	assert.True(t, events[0].Expiring())
+	assert.False(t, events[1].Expiring())
+	assert.Equal(t, []string{"expiring", "renewed", "other", "invalid"}, svc.deleted)
+}
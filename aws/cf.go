@@ -1,39 +1,106 @@
 package aws
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
-	certificateARNTagLegacy = "ingress:certificate-arn"
-	certificateARNTagPrefix = "ingress:certificate-arn/"
-	ingressOwnerTag         = "ingress:owner"
-	cwAlarmConfigHashTag    = "cloudwatch:alarm-config-hash"
+	certificateARNTagLegacy  = "ingress:certificate-arn"
+	certificateARNTagPrefix  = "ingress:certificate-arn/"
+	ingressOwnerTag          = "ingress:owner"
+	cwAlarmConfigHashTag     = "cloudwatch:alarm-config-hash"
+	templateFragmentsHashTag = "ingress:template-fragments-hash"
+	listenerRulesHashTag     = "ingress:listener-rules-hash"
+	authenticationHashTag    = "ingress:authentication-hash"
+	resourceTagsHashTag      = "ingress:resource-tags-hash"
+	templateTagsHashTag      = "ingress:template-tags-hash"
+	attributesHashTag        = "ingress:attributes-hash"
+	namespacesTag            = "ingress:namespaces"
+	// maxTagValueLength is the maximum length of CloudFormation stack tag
+	// values.
+	maxTagValueLength      = 256
+	dnsHostnamesHashTag    = "ingress:dns-hostnames-hash"
+	dnsHostnamesTag        = "ingress:dns-hostnames"
+	internalDomainsHashTag = "ingress:internal-domains-hash"
+	pausedTag              = "ingress:paused"
 )
 
 // Stack is a simple wrapper around a CloudFormation Stack.
 type Stack struct {
-	Name              string
-	status            string
-	DNSName           string
-	Scheme            string
-	SecurityGroup     string
-	SSLPolicy         string
-	IpAddressType     string
-	LoadBalancerType  string
-	HTTP2             bool
-	OwnerIngress      string
-	CWAlarmConfigHash string
-	TargetGroupARN    string
-	WAFWebACLID       string
-	CertificateARNs   map[string]time.Time
-	tags              map[string]string
+	Name                      string
+	status                    string
+	statusReason              string
+	quotaExceeded             bool
+	creationTime              time.Time
+	DNSName                   string
+	Scheme                    string
+	SecurityGroup             string
+	SSLPolicy                 string
+	IpAddressType             string
+	LoadBalancerType          string
+	HTTP2                     bool
+	PreserveHostHeader        bool
+	HTTPDisabled              bool
+	FrontingNLB               bool
+	NLBSecurityGroup          bool
+	LambdaTarget              string
+	SlowStart                 time.Duration
+	ClientKeepAlive           time.Duration
+	HealthCheckMatcher        string
+	PreserveClientIP          string
+	ProxyProtocolV2           bool
+	ClientRoutingPolicy       string
+	CapacityUnits             int64
+	DefaultCertificateARN     string
+	ExternalTargetGroupARNs   []string
+	ElasticIPs                []string
+	IPv4Pool                  string
+	EndpointServicePrincipals []string
+	// EndpointServiceName is the name of the VPC endpoint service of an
+	// internal network load balancer, which other VPCs connect to.
+	EndpointServiceName                    string
+	DenyInternalDomains                    string
+	DenyInternalDomainsResponse            string
+	DenyInternalDomainsResponseContentType string
+	DenyInternalDomainsResponseStatusCode  int
+	HealthyThresholdCount                  uint
+	UnhealthyThresholdCount                uint
+	OwnerIngress                           string
+	CWAlarmConfigHash                      string
+	TemplateFragmentsHash                  string
+	DNSHostnamesHash                       string
+	DNSHostnames                           []string
+	NamespacesTag                          string
+	InternalDomainsHash                    string
+	ListenerRulesHash                      string
+	AuthenticationHash                     string
+	ResourceTagsHash                       string
+	TemplateResourceTagsHash               string
+	AttributesHash                         string
+	TargetGroupARN                         string
+	LoadBalancerARN                        string
+	WAFWebACLID                            string
+	WAFRateLimit                           int64
+	WAFRateLimitKey                        string
+	WAFManagedRuleGroups                   []string
+	Paused                                 bool
+	// PreviousOwner is true if the stack is tagged by the previous cluster
+	// ID or controller ID, and its tags must be migrated.
+	PreviousOwner   bool
+	CertificateARNs map[string]time.Time
+	tags            map[string]string
 }
 
 // IsComplete returns true if the stack status is a complete state.
@@ -52,14 +119,80 @@ func (s *Stack) IsComplete() bool {
 	return false
 }
 
+// IsStuck returns true if the stack is in a state it won't leave on its
+// own, i.e. DELETE_FAILED, or REVIEW_IN_PROGRESS or CREATE_IN_PROGRESS for
+// longer than the timeout at the given time.
+func (s *Stack) IsStuck(timeout time.Duration, now time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	switch s.status {
+	case cloudformation.StackStatusDeleteFailed:
+		return true
+	case cloudformation.StackStatusReviewInProgress,
+		cloudformation.StackStatusCreateInProgress:
+		return !s.creationTime.IsZero() && now.Sub(s.creationTime) > timeout
+	}
+	return false
+}
+
+// IsQuotaExceeded returns true if the creation of the stack was rolled back
+// because the load balancer quota of the account is exhausted.
+func (s *Stack) IsQuotaExceeded() bool {
+	return s != nil && s.quotaExceeded
+}
+
+// quotaExceededReasons are substrings of the errors of resources which
+// failed because of an exhausted quota.
+var quotaExceededReasons = []string{
+	elbv2.ErrCodeTooManyLoadBalancersException,
+	"reached the limit",
+	"LimitExceeded",
+}
+
+func isQuotaExceededReason(reason string) bool {
+	for _, r := range quotaExceededReasons {
+		if strings.Contains(reason, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsQuotaExceededError returns true if the error was caused by an exhausted
+// quota of the account, e.g. of load balancers or stacks.
+func IsQuotaExceededError(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case cloudformation.ErrCodeLimitExceededException,
+			elbv2.ErrCodeTooManyLoadBalancersException:
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error describing the status of the stack, including the
+// reason reported by CloudFormation if any.
+func (s *Stack) Err() error {
+	if s == nil {
+		return nil
+	}
+
+	if s.statusReason != "" {
+		return fmt.Errorf("stack %q is in status %s: %s", s.Name, s.status, s.statusReason)
+	}
+	return fmt.Errorf("stack %q is in status %s", s.Name, s.status)
+}
+
 // ShouldDelete returns true if stack is to be deleted because there are no
-// valid certificates attached anymore.
-func (s *Stack) ShouldDelete() bool {
+// valid certificates attached anymore at the given time.
+func (s *Stack) ShouldDelete(now time.Time) bool {
 	if s == nil {
 		return false
 	}
 
-	now := time.Now().UTC()
 	for _, t := range s.CertificateARNs {
 		if t.IsZero() || t.After(now) {
 			return false
@@ -69,6 +202,20 @@ func (s *Stack) ShouldDelete() bool {
 	return true
 }
 
+// ListenerTargetGroupARNs returns the target groups the listeners of the
+// load balancer of the stack forward requests to: the target group of the
+// stack and the external target groups, or none for a Lambda target.
+func (s *Stack) ListenerTargetGroupARNs() []string {
+	if s == nil || s.LambdaTarget != "" || s.TargetGroupARN == "" {
+		return nil
+	}
+	arns := []string{s.TargetGroupARN}
+	if s.LoadBalancerType == LoadBalancerTypeApplication {
+		arns = append(arns, s.ExternalTargetGroupARNs...)
+	}
+	return arns
+}
+
 type stackOutput map[string]string
 
 func newStackOutput(outputs []*cloudformation.Output) stackOutput {
@@ -87,6 +234,14 @@ func (o stackOutput) targetGroupARN() string {
 	return o[outputTargetGroupARN]
 }
 
+func (o stackOutput) loadBalancerARN() string {
+	return o[outputLoadBalancerARN]
+}
+
+func (o stackOutput) endpointServiceName() string {
+	return o[outputEndpointServiceName]
+}
+
 // convertStackParameters converts a list of cloudformation stack parameters to
 // a map.
 func convertStackParameters(parameters []*cloudformation.Parameter) map[string]string {
@@ -101,57 +256,121 @@ const (
 	// The following constants should be part of the Output section of the CloudFormation template
 	outputLoadBalancerDNSName = "LoadBalancerDNSName"
 	outputTargetGroupARN      = "TargetGroupARN"
-
-	parameterLoadBalancerSchemeParameter             = "LoadBalancerSchemeParameter"
-	parameterLoadBalancerSecurityGroupParameter      = "LoadBalancerSecurityGroupParameter"
-	parameterLoadBalancerSubnetsParameter            = "LoadBalancerSubnetsParameter"
-	parameterTargetGroupHealthCheckPathParameter     = "TargetGroupHealthCheckPathParameter"
-	parameterTargetGroupHealthCheckPortParameter     = "TargetGroupHealthCheckPortParameter"
-	parameterTargetGroupHealthCheckIntervalParameter = "TargetGroupHealthCheckIntervalParameter"
-	parameterTargetGroupHealthCheckTimeoutParameter  = "TargetGroupHealthCheckTimeoutParameter"
-	parameterTargetTargetPortParameter               = "TargetGroupTargetPortParameter"
-	parameterTargetGroupVPCIDParameter               = "TargetGroupVPCIDParameter"
-	parameterListenerCertificatesParameter           = "ListenerCertificatesParameter"
-	parameterListenerSslPolicyParameter              = "ListenerSslPolicyParameter"
-	parameterIpAddressTypeParameter                  = "IpAddressType"
-	parameterLoadBalancerTypeParameter               = "Type"
-	parameterLoadBalancerWAFWebACLIDParameter        = "LoadBalancerWAFWebACLIDParameter"
-	parameterHTTP2Parameter                          = "HTTP2"
+	outputLoadBalancerARN     = "LoadBalancerARN"
+
+	parameterLoadBalancerSchemeParameter                     = "LoadBalancerSchemeParameter"
+	parameterLoadBalancerSecurityGroupParameter              = "LoadBalancerSecurityGroupParameter"
+	parameterLoadBalancerSubnetsParameter                    = "LoadBalancerSubnetsParameter"
+	parameterTargetGroupHealthCheckPathParameter             = "TargetGroupHealthCheckPathParameter"
+	parameterTargetGroupHealthCheckPortParameter             = "TargetGroupHealthCheckPortParameter"
+	parameterTargetGroupHealthCheckIntervalParameter         = "TargetGroupHealthCheckIntervalParameter"
+	parameterTargetGroupHealthCheckTimeoutParameter          = "TargetGroupHealthCheckTimeoutParameter"
+	parameterTargetTargetPortParameter                       = "TargetGroupTargetPortParameter"
+	parameterTargetGroupVPCIDParameter                       = "TargetGroupVPCIDParameter"
+	parameterListenerCertificatesParameter                   = "ListenerCertificatesParameter"
+	parameterListenerSslPolicyParameter                      = "ListenerSslPolicyParameter"
+	parameterIpAddressTypeParameter                          = "IpAddressType"
+	parameterLoadBalancerTypeParameter                       = "Type"
+	parameterLoadBalancerWAFWebACLIDParameter                = "LoadBalancerWAFWebACLIDParameter"
+	parameterHTTP2Parameter                                  = "HTTP2"
+	parameterPreserveHostHeaderParameter                     = "PreserveHostHeader"
+	parameterHTTPDisabledParameter                           = "HTTPDisabled"
+	parameterFrontingNLBParameter                            = "FrontingNLB"
+	parameterNLBSecurityGroupParameter                       = "NLBSecurityGroup"
+	parameterLambdaTargetParameter                           = "LambdaTargetParameter"
+	parameterTargetGroupSlowStartParameter                   = "TargetGroupSlowStartDurationParameter"
+	parameterClientKeepAliveParameter                        = "ClientKeepAliveParameter"
+	parameterTargetGroupHealthCheckMatcherParameter          = "TargetGroupHealthCheckMatcherParameter"
+	parameterTargetGroupPreserveClientIPParameter            = "TargetGroupPreserveClientIPParameter"
+	parameterTargetGroupProxyProtocolV2Parameter             = "TargetGroupProxyProtocolV2Parameter"
+	parameterClientRoutingPolicyParameter                    = "LoadBalancerClientRoutingPolicyParameter"
+	parameterCapacityUnitsParameter                          = "LoadBalancerCapacityUnitsParameter"
+	parameterDefaultCertificateARNParameter                  = "DefaultCertificateARNParameter"
+	parameterExternalTargetGroupARNsParameter                = "ExternalTargetGroupARNsParameter"
+	parameterElasticIPsParameter                             = "ElasticIPsParameter"
+	parameterIPv4PoolParameter                               = "IPv4PoolParameter"
+	parameterEndpointServicePrincipalsParameter              = "EndpointServicePrincipalsParameter"
+	parameterDenyInternalDomainsParameter                    = "DenyInternalDomainsParameter"
+	parameterDenyInternalDomainsResponseParameter            = "DenyInternalDomainsResponseParameter"
+	parameterDenyInternalDomainsResponseContentTypeParameter = "DenyInternalDomainsResponseContentTypeParameter"
+	parameterDenyInternalDomainsResponseStatusCodeParameter  = "DenyInternalDomainsResponseStatusCodeParameter"
+	parameterTargetGroupHealthyThresholdParameter            = "TargetGroupHealthyThresholdCountParameter"
+	parameterTargetGroupUnhealthyThresholdParameter          = "TargetGroupUnhealthyThresholdCountParameter"
+	parameterWAFRateLimitParameter                           = "WAFRateLimitParameter"
+	parameterWAFRateLimitKeyParameter                        = "WAFRateLimitKeyParameter"
+	parameterWAFManagedRuleGroupsParameter                   = "WAFManagedRuleGroupsParameter"
 )
 
 type stackSpec struct {
-	name                              string
-	scheme                            string
-	ownerIngress                      string
-	subnets                           []string
-	certificateARNs                   map[string]time.Time
-	securityGroupID                   string
-	clusterID                         string
-	vpcID                             string
-	healthCheck                       *healthCheck
-	targetPort                        uint
-	targetHTTPS                       bool
-	timeoutInMinutes                  uint
-	customTemplate                    string
-	stackTerminationProtection        bool
-	idleConnectionTimeoutSeconds      uint
-	deregistrationDelayTimeoutSeconds uint
-	controllerID                      string
-	sslPolicy                         string
-	ipAddressType                     string
-	loadbalancerType                  string
-	albLogsS3Bucket                   string
-	albLogsS3Prefix                   string
-	wafWebAclId                       string
-	cwAlarms                          CloudWatchAlarmList
-	httpRedirectToHTTPS               bool
-	nlbCrossZone                      bool
-	nlbHTTPEnabled                    bool
-	http2                             bool
-	denyInternalDomains               bool
-	denyInternalDomainsResponse       denyResp
-	internalDomains                   []string
-	tags                              map[string]string
+	name                                string
+	scheme                              string
+	ownerIngress                        string
+	subnets                             []string
+	certificateARNs                     map[string]time.Time
+	securityGroupID                     string
+	clusterID                           string
+	vpcID                               string
+	healthCheck                         *healthCheck
+	targetPort                          uint
+	targetHTTPS                         bool
+	targetIPs                           bool
+	timeoutInMinutes                    uint
+	customTemplate                      string
+	stackTerminationProtection          bool
+	idleConnectionTimeoutSeconds        uint
+	deregistrationDelayTimeoutSeconds   uint
+	controllerID                        string
+	sslPolicy                           string
+	ipAddressType                       string
+	loadbalancerType                    string
+	albLogsS3Bucket                     string
+	albLogsS3Prefix                     string
+	wafWebAclId                         string
+	wafRateLimit                        int64
+	wafRateLimitKey                     string
+	wafManagedRuleGroups                []string
+	cwAlarms                            CloudWatchAlarmList
+	templateFragments                   TemplateFragments
+	httpRedirectToHTTPS                 bool
+	nlbCrossZone                        bool
+	nlbHTTPEnabled                      bool
+	http2                               bool
+	preserveHostHeader                  bool
+	httpDisabled                        bool
+	frontingNLB                         bool
+	nlbSecurityGroup                    bool
+	lambdaTarget                        string
+	slowStartDurationSeconds            uint
+	clientKeepAliveSeconds              uint
+	healthCheckMatcher                  string
+	preserveClientIP                    string
+	proxyProtocolV2                     bool
+	clientRoutingPolicy                 string
+	capacityUnits                       int64
+	defaultCertificateARN               string
+	externalTargetGroupARNs             []string
+	elasticIPs                          []string
+	ipv4Pool                            string
+	endpointServicePrincipals           []string
+	healthyThresholdCount               uint
+	unhealthyThresholdCount             uint
+	listenerRules                       ListenerRuleList
+	authentication                      *ListenerAuthentication
+	resourceTags                        ResourceTags
+	templateResourceTags                ResourceTags
+	loadBalancerAttributes              Attributes
+	targetGroupAttributes               Attributes
+	denyInternalDomains                 bool
+	denyInternalDomainsOverride         string
+	denyInternalDomainsResponse         denyResp
+	denyInternalDomainsResponseOverride denyResp
+	internalDomains                     []string
+	tags                                map[string]string
+	dnsRecords                          []*dnsRecord
+	dnsHostnamesHash                    string
+	internalDomainsHash                 string
+	dnsOwnerID                          string
+	namespacesTag                       string
 }
 
 type healthCheck struct {
@@ -161,74 +380,165 @@ type healthCheck struct {
 	timeout  time.Duration
 }
 
+// IsValidHealthCheckMatcher returns true if the matcher is a valid list of
+// HTTP codes for application load balancer health checks, e.g. "200",
+// "200,302" or "200-399". Codes must be between 200 and 499.
+func IsValidHealthCheckMatcher(matcher string) bool {
+	if matcher == "" {
+		return false
+	}
+
+	for _, codes := range strings.Split(matcher, ",") {
+		bounds := strings.SplitN(codes, "-", 2)
+		prev := 0
+		for _, bound := range bounds {
+			code, err := strconv.Atoi(bound)
+			if err != nil || len(bound) != 3 || code < 200 || code > 499 || code < prev {
+				return false
+			}
+			prev = code
+		}
+	}
+	return true
+}
+
+// The client routing policies of the DNS records of network load balancers:
+// the records resolve to the IPs of the zone of the client only, unless no
+// IP of the zone is healthy, or to 85% of the time only, or to the IPs of
+// all zones.
+const (
+	ClientRoutingPolicyAZAffinity        = "availability_zone_affinity"
+	ClientRoutingPolicyPartialAZAffinity = "partial_availability_zone_affinity"
+	ClientRoutingPolicyAnyAZ             = "any_availability_zone"
+)
+
+// IsValidClientRoutingPolicy returns true if the policy is a client routing
+// policy of network load balancers.
+func IsValidClientRoutingPolicy(policy string) bool {
+	switch policy {
+	case ClientRoutingPolicyAZAffinity, ClientRoutingPolicyPartialAZAffinity, ClientRoutingPolicyAnyAZ:
+		return true
+	}
+	return false
+}
+
+// IsValidDenyResponseContentType returns true if the content type is
+// supported by fixed responses of application load balancers.
+func IsValidDenyResponseContentType(contentType string) bool {
+	switch contentType {
+	case "text/plain", "text/css", "text/html", "application/javascript", "application/json":
+		return true
+	}
+	return false
+}
+
+// IsValidDenyResponseStatusCode returns true if the status code is
+// supported by fixed responses of application load balancers, i.e. a 2XX,
+// 4XX or 5XX code.
+func IsValidDenyResponseStatusCode(code int) bool {
+	return (code >= 200 && code <= 299) || (code >= 400 && code <= 599)
+}
+
 type denyResp struct {
 	statusCode  int
 	contentType string
 	body        string
 }
 
-func createStack(svc cloudformationiface.CloudFormationAPI, spec *stackSpec) (string, error) {
+func createStack(svc cloudformationiface.CloudFormationAPI, spec *stackSpec, upload templateUploader) (string, error) {
 	template, err := generateTemplate(spec)
 	if err != nil {
 		return "", err
 	}
-
-	stackTags := map[string]string{
-		kubernetesCreatorTag:                spec.controllerID,
-		clusterIDTagPrefix + spec.clusterID: resourceLifecycleOwned,
+	body, url, err := templateSource(spec.name, template, upload)
+	if err != nil {
+		return spec.name, err
 	}
 
-	tags := mergeTags(spec.tags, stackTags)
-
 	params := &cloudformation.CreateStackInput{
-		StackName: aws.String(spec.name),
-		OnFailure: aws.String(cloudformation.OnFailureDelete),
-		Parameters: []*cloudformation.Parameter{
-			cfParam(parameterLoadBalancerSchemeParameter, spec.scheme),
-			cfParam(parameterLoadBalancerSecurityGroupParameter, spec.securityGroupID),
-			cfParam(parameterLoadBalancerSubnetsParameter, strings.Join(spec.subnets, ",")),
-			cfParam(parameterTargetGroupVPCIDParameter, spec.vpcID),
-			cfParam(parameterTargetTargetPortParameter, fmt.Sprintf("%d", spec.targetPort)),
-			cfParam(parameterListenerSslPolicyParameter, spec.sslPolicy),
-			cfParam(parameterIpAddressTypeParameter, spec.ipAddressType),
-			cfParam(parameterLoadBalancerTypeParameter, spec.loadbalancerType),
-			cfParam(parameterHTTP2Parameter, fmt.Sprintf("%t", spec.http2)),
-		},
-		Tags:                        tagMapToCloudformationTags(tags),
-		TemplateBody:                aws.String(template),
+		StackName:                   aws.String(spec.name),
+		OnFailure:                   aws.String(cloudformation.OnFailureDelete),
+		Parameters:                  stackParameters(spec),
+		Tags:                        stackTags(spec),
+		TemplateBody:                body,
+		TemplateURL:                 url,
 		TimeoutInMinutes:            aws.Int64(int64(spec.timeoutInMinutes)),
 		EnableTerminationProtection: aws.Bool(spec.stackTerminationProtection),
 	}
 
-	if spec.wafWebAclId != "" {
-		params.Parameters = append(
-			params.Parameters,
-			cfParam(parameterLoadBalancerWAFWebACLIDParameter, spec.wafWebAclId),
-		)
+	resp, err := svc.CreateStack(params)
+	if err != nil {
+		return spec.name, err
 	}
 
-	for certARN, ttl := range spec.certificateARNs {
-		params.Tags = append(params.Tags, cfTag(certificateARNTagPrefix+certARN, ttl.Format(time.RFC3339)))
+	return aws.StringValue(resp.StackId), nil
+}
+
+func updateStack(svc cloudformationiface.CloudFormationAPI, spec *stackSpec, upload templateUploader) (string, error) {
+	params, err := prepareStackUpdate(svc, spec, upload)
+	if err != nil {
+		return spec.name, err
 	}
 
-	if spec.healthCheck != nil {
-		params.Parameters = append(params.Parameters,
-			cfParam(parameterTargetGroupHealthCheckPathParameter, spec.healthCheck.path),
-			cfParam(parameterTargetGroupHealthCheckPortParameter, fmt.Sprintf("%d", spec.healthCheck.port)),
-			cfParam(parameterTargetGroupHealthCheckIntervalParameter, fmt.Sprintf("%.0f", spec.healthCheck.interval.Seconds())),
-			cfParam(parameterTargetGroupHealthCheckTimeoutParameter, fmt.Sprintf("%.0f", spec.healthCheck.timeout.Seconds())),
-		)
+	resp, err := svc.UpdateStack(params)
+	if err != nil {
+		return spec.name, err
 	}
 
-	if spec.ownerIngress != "" {
-		params.Tags = append(params.Tags, cfTag(ingressOwnerTag, spec.ownerIngress))
+	return aws.StringValue(resp.StackId), nil
+}
+
+// prepareStackUpdate returns the input of the update of the stack to the
+// spec. The termination protection of the stack is enabled first, if set.
+func prepareStackUpdate(svc cloudformationiface.CloudFormationAPI, spec *stackSpec, upload templateUploader) (*cloudformation.UpdateStackInput, error) {
+	template, err := generateTemplate(spec)
+	if err != nil {
+		return nil, err
+	}
+	body, url, err := templateSource(spec.name, template, upload)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(spec.cwAlarms) > 0 {
-		params.Tags = append(params.Tags, cfTag(cwAlarmConfigHashTag, spec.cwAlarms.Hash()))
+	params := &cloudformation.UpdateStackInput{
+		StackName:    aws.String(spec.name),
+		Parameters:   stackParameters(spec),
+		Tags:         stackTags(spec),
+		TemplateBody: body,
+		TemplateURL:  url,
 	}
 
-	resp, err := svc.CreateStack(params)
+	if spec.stackTerminationProtection {
+		params := &cloudformation.UpdateTerminationProtectionInput{
+			StackName:                   aws.String(spec.name),
+			EnableTerminationProtection: aws.Bool(spec.stackTerminationProtection),
+		}
+
+		_, err := svc.UpdateTerminationProtection(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return params, nil
+}
+
+// updateStackTags updates only the tags of the stack. The previous template
+// and parameter values are kept, so CloudFormation just propagates the tags
+// to the resources of the stack instead of replacing any of them.
+func updateStackTags(svc cloudformationiface.CloudFormationAPI, spec *stackSpec) (string, error) {
+	params := stackParameters(spec)
+	for _, param := range params {
+		param.ParameterValue = nil
+		param.UsePreviousValue = aws.Bool(true)
+	}
+
+	resp, err := svc.UpdateStack(&cloudformation.UpdateStackInput{
+		StackName:           aws.String(spec.name),
+		UsePreviousTemplate: aws.Bool(true),
+		Parameters:          params,
+		Tags:                stackTags(spec),
+	})
 	if err != nil {
 		return spec.name, err
 	}
@@ -236,49 +546,118 @@ func createStack(svc cloudformationiface.CloudFormationAPI, spec *stackSpec) (st
 	return aws.StringValue(resp.StackId), nil
 }
 
-func updateStack(svc cloudformationiface.CloudFormationAPI, spec *stackSpec) (string, error) {
-	template, err := generateTemplate(spec)
-	if err != nil {
-		return "", err
+// stackParameters returns the parameters passed to the stack template when
+// creating or updating the stack.
+func stackParameters(spec *stackSpec) []*cloudformation.Parameter {
+	params := []*cloudformation.Parameter{
+		cfParam(parameterLoadBalancerSchemeParameter, spec.scheme),
+		cfParam(parameterLoadBalancerSecurityGroupParameter, spec.securityGroupID),
+		cfParam(parameterLoadBalancerSubnetsParameter, strings.Join(spec.subnets, ",")),
+		cfParam(parameterTargetGroupVPCIDParameter, spec.vpcID),
+		cfParam(parameterTargetTargetPortParameter, fmt.Sprintf("%d", spec.targetPort)),
+		cfParam(parameterListenerSslPolicyParameter, spec.sslPolicy),
+		cfParam(parameterIpAddressTypeParameter, spec.ipAddressType),
+		cfParam(parameterLoadBalancerTypeParameter, spec.loadbalancerType),
+		cfParam(parameterHTTP2Parameter, fmt.Sprintf("%t", spec.http2)),
+		cfParam(parameterPreserveHostHeaderParameter, fmt.Sprintf("%t", spec.preserveHostHeader)),
+		cfParam(parameterHTTPDisabledParameter, fmt.Sprintf("%t", spec.httpDisabled)),
+		cfParam(parameterTargetGroupSlowStartParameter, fmt.Sprintf("%d", spec.slowStartDurationSeconds)),
+		cfParam(parameterClientKeepAliveParameter, fmt.Sprintf("%d", spec.clientKeepAliveSeconds)),
 	}
 
-	stackTags := map[string]string{
-		kubernetesCreatorTag:                spec.controllerID,
-		clusterIDTagPrefix + spec.clusterID: resourceLifecycleOwned,
+	if spec.wafWebAclId != "" {
+		params = append(params, cfParam(parameterLoadBalancerWAFWebACLIDParameter, spec.wafWebAclId))
 	}
 
-	tags := mergeTags(spec.tags, stackTags)
+	if spec.hasWebACL() && spec.wafRateLimit > 0 {
+		params = append(params,
+			cfParam(parameterWAFRateLimitParameter, fmt.Sprintf("%d", spec.wafRateLimit)),
+			cfParam(parameterWAFRateLimitKeyParameter, spec.wafRateLimitKey),
+		)
+	}
 
-	params := &cloudformation.UpdateStackInput{
-		StackName: aws.String(spec.name),
-		Parameters: []*cloudformation.Parameter{
-			cfParam(parameterLoadBalancerSchemeParameter, spec.scheme),
-			cfParam(parameterLoadBalancerSecurityGroupParameter, spec.securityGroupID),
-			cfParam(parameterLoadBalancerSubnetsParameter, strings.Join(spec.subnets, ",")),
-			cfParam(parameterTargetGroupVPCIDParameter, spec.vpcID),
-			cfParam(parameterTargetTargetPortParameter, fmt.Sprintf("%d", spec.targetPort)),
-			cfParam(parameterListenerSslPolicyParameter, spec.sslPolicy),
-			cfParam(parameterIpAddressTypeParameter, spec.ipAddressType),
-			cfParam(parameterLoadBalancerTypeParameter, spec.loadbalancerType),
-			cfParam(parameterHTTP2Parameter, fmt.Sprintf("%t", spec.http2)),
-		},
-		Tags:         tagMapToCloudformationTags(tags),
-		TemplateBody: aws.String(template),
+	if spec.hasWebACL() && len(spec.wafManagedRuleGroups) > 0 {
+		params = append(params, cfParam(parameterWAFManagedRuleGroupsParameter, strings.Join(spec.wafManagedRuleGroups, ",")))
 	}
 
-	if spec.wafWebAclId != "" {
-		params.Parameters = append(
-			params.Parameters,
-			cfParam(parameterLoadBalancerWAFWebACLIDParameter, spec.wafWebAclId),
-		)
+	if spec.frontingNLB {
+		params = append(params, cfParam(parameterFrontingNLBParameter, "true"))
 	}
 
-	for certARN, ttl := range spec.certificateARNs {
-		params.Tags = append(params.Tags, cfTag(certificateARNTagPrefix+certARN, ttl.Format(time.RFC3339)))
+	if spec.nlbSecurityGroup {
+		params = append(params, cfParam(parameterNLBSecurityGroupParameter, "true"))
+	}
+
+	if spec.lambdaTarget != "" {
+		params = append(params, cfParam(parameterLambdaTargetParameter, spec.lambdaTarget))
+	}
+
+	if spec.healthCheckMatcher != "" {
+		params = append(params, cfParam(parameterTargetGroupHealthCheckMatcherParameter, spec.healthCheckMatcher))
+	}
+
+	if spec.denyInternalDomainsOverride != "" {
+		params = append(params, cfParam(parameterDenyInternalDomainsParameter, spec.denyInternalDomainsOverride))
+	}
+
+	if spec.denyInternalDomainsResponseOverride.body != "" {
+		params = append(params, cfParam(parameterDenyInternalDomainsResponseParameter, spec.denyInternalDomainsResponseOverride.body))
+	}
+
+	if spec.denyInternalDomainsResponseOverride.contentType != "" {
+		params = append(params, cfParam(parameterDenyInternalDomainsResponseContentTypeParameter, spec.denyInternalDomainsResponseOverride.contentType))
+	}
+
+	if spec.denyInternalDomainsResponseOverride.statusCode > 0 {
+		params = append(params, cfParam(parameterDenyInternalDomainsResponseStatusCodeParameter, fmt.Sprintf("%d", spec.denyInternalDomainsResponseOverride.statusCode)))
+	}
+
+	if spec.preserveClientIP != "" {
+		params = append(params, cfParam(parameterTargetGroupPreserveClientIPParameter, spec.preserveClientIP))
+	}
+
+	if spec.proxyProtocolV2 {
+		params = append(params, cfParam(parameterTargetGroupProxyProtocolV2Parameter, "true"))
+	}
+
+	if spec.clientRoutingPolicy != "" {
+		params = append(params, cfParam(parameterClientRoutingPolicyParameter, spec.clientRoutingPolicy))
+	}
+
+	if spec.hasCapacityReservation() {
+		params = append(params, cfParam(parameterCapacityUnitsParameter, fmt.Sprintf("%d", spec.capacityUnits)))
+	}
+
+	if spec.defaultCertificateARN != "" {
+		params = append(params, cfParam(parameterDefaultCertificateARNParameter, spec.defaultCertificateARN))
+	}
+
+	if len(spec.externalTargetGroupARNs) > 0 {
+		params = append(params, cfParam(parameterExternalTargetGroupARNsParameter, strings.Join(spec.externalTargetGroupARNs, ",")))
+	}
+
+	if len(spec.elasticIPs) > 0 {
+		params = append(params, cfParam(parameterElasticIPsParameter, strings.Join(spec.elasticIPs, ",")))
+	}
+
+	if spec.ipv4Pool != "" {
+		params = append(params, cfParam(parameterIPv4PoolParameter, spec.ipv4Pool))
+	}
+
+	if len(spec.endpointServicePrincipals) > 0 {
+		params = append(params, cfParam(parameterEndpointServicePrincipalsParameter, strings.Join(spec.endpointServicePrincipals, ",")))
+	}
+
+	if spec.healthyThresholdCount > 0 {
+		params = append(params, cfParam(parameterTargetGroupHealthyThresholdParameter, fmt.Sprintf("%d", spec.healthyThresholdCount)))
+	}
+
+	if spec.unhealthyThresholdCount > 0 {
+		params = append(params, cfParam(parameterTargetGroupUnhealthyThresholdParameter, fmt.Sprintf("%d", spec.unhealthyThresholdCount)))
 	}
 
 	if spec.healthCheck != nil {
-		params.Parameters = append(params.Parameters,
+		params = append(params,
 			cfParam(parameterTargetGroupHealthCheckPathParameter, spec.healthCheck.path),
 			cfParam(parameterTargetGroupHealthCheckPortParameter, fmt.Sprintf("%d", spec.healthCheck.port)),
 			cfParam(parameterTargetGroupHealthCheckIntervalParameter, fmt.Sprintf("%.0f", spec.healthCheck.interval.Seconds())),
@@ -286,32 +665,75 @@ func updateStack(svc cloudformationiface.CloudFormationAPI, spec *stackSpec) (st
 		)
 	}
 
+	return params
+}
+
+// stackTags returns the tags set on the stack when creating or updating it.
+func stackTags(spec *stackSpec) []*cloudformation.Tag {
+	stackTags := map[string]string{
+		kubernetesCreatorTag:                spec.controllerID,
+		clusterIDTagPrefix + spec.clusterID: resourceLifecycleOwned,
+	}
+
+	tags := tagMapToCloudformationTags(mergeTags(spec.tags, stackTags))
+
+	for certARN, ttl := range spec.certificateARNs {
+		tags = append(tags, cfTag(certificateARNTagPrefix+certARN, ttl.Format(time.RFC3339)))
+	}
+
 	if spec.ownerIngress != "" {
-		params.Tags = append(params.Tags, cfTag(ingressOwnerTag, spec.ownerIngress))
+		tags = append(tags, cfTag(ingressOwnerTag, spec.ownerIngress))
 	}
 
 	if len(spec.cwAlarms) > 0 {
-		params.Tags = append(params.Tags, cfTag(cwAlarmConfigHashTag, spec.cwAlarms.Hash()))
+		tags = append(tags, cfTag(cwAlarmConfigHashTag, spec.cwAlarms.Hash()))
 	}
 
-	if spec.stackTerminationProtection {
-		params := &cloudformation.UpdateTerminationProtectionInput{
-			StackName:                   aws.String(spec.name),
-			EnableTerminationProtection: aws.Bool(spec.stackTerminationProtection),
-		}
+	if len(spec.templateFragments) > 0 {
+		tags = append(tags, cfTag(templateFragmentsHashTag, spec.templateFragments.Hash()))
+	}
 
-		_, err := svc.UpdateTerminationProtection(params)
-		if err != nil {
-			return spec.name, err
+	if spec.dnsHostnamesHash != "" {
+		tags = append(tags, cfTag(dnsHostnamesHashTag, spec.dnsHostnamesHash))
+	}
+
+	if len(spec.dnsRecords) > 0 {
+		hostnames := make([]string, 0, len(spec.dnsRecords))
+		for _, record := range spec.dnsRecords {
+			hostnames = append(hostnames, record.hostname)
 		}
+		tags = append(tags, cfTag(dnsHostnamesTag, JoinTagValues(hostnames)))
 	}
 
-	resp, err := svc.UpdateStack(params)
-	if err != nil {
-		return spec.name, err
+	if len(spec.listenerRules) > 0 {
+		tags = append(tags, cfTag(listenerRulesHashTag, spec.listenerRules.Hash()))
 	}
 
-	return aws.StringValue(resp.StackId), nil
+	if spec.authentication != nil {
+		tags = append(tags, cfTag(authenticationHashTag, spec.authentication.Hash()))
+	}
+
+	if len(spec.resourceTags) > 0 {
+		tags = append(tags, cfTag(resourceTagsHashTag, spec.resourceTags.Hash()))
+	}
+
+	if len(spec.templateResourceTags) > 0 {
+		tags = append(tags, cfTag(templateTagsHashTag, spec.templateResourceTags.Hash()))
+	}
+
+	if hash := HashAttributes(spec.loadBalancerAttributes, spec.targetGroupAttributes); hash != "" {
+		tags = append(tags, cfTag(attributesHashTag, hash))
+	}
+
+	if spec.namespacesTag != "" {
+		tags = append(tags, cfTag(namespacesTag, spec.namespacesTag))
+	}
+
+	if spec.internalDomainsHash != "" {
+		tags = append(tags, cfTag(internalDomainsHashTag, spec.internalDomainsHash))
+	}
+
+	return tags
 }
 
 func mergeTags(tags ...map[string]string) map[string]string {
@@ -431,32 +853,174 @@ func mapToManagedStack(stack *cloudformation.Stack) *Stack {
 		http2 = false
 	}
 
+	var slowStart time.Duration
+	if seconds, err := strconv.Atoi(parameters[parameterTargetGroupSlowStartParameter]); err == nil {
+		slowStart = time.Duration(seconds) * time.Second
+	}
+
+	var clientKeepAlive time.Duration
+	if seconds, err := strconv.Atoi(parameters[parameterClientKeepAliveParameter]); err == nil {
+		clientKeepAlive = time.Duration(seconds) * time.Second
+	}
+
+	var healthyThresholdCount, unhealthyThresholdCount uint
+	if count, err := strconv.ParseUint(parameters[parameterTargetGroupHealthyThresholdParameter], 10, 32); err == nil {
+		healthyThresholdCount = uint(count)
+	}
+	if count, err := strconv.ParseUint(parameters[parameterTargetGroupUnhealthyThresholdParameter], 10, 32); err == nil {
+		unhealthyThresholdCount = uint(count)
+	}
+
+	var wafRateLimit int64
+	if limit, err := strconv.ParseInt(parameters[parameterWAFRateLimitParameter], 10, 64); err == nil {
+		wafRateLimit = limit
+	}
+
+	var capacityUnits int64
+	if units, err := strconv.ParseInt(parameters[parameterCapacityUnitsParameter], 10, 64); err == nil {
+		capacityUnits = units
+	}
+
+	var wafManagedRuleGroups []string
+	if groups := parameters[parameterWAFManagedRuleGroupsParameter]; groups != "" {
+		wafManagedRuleGroups = strings.Split(groups, ",")
+	}
+
+	var externalTargetGroupARNs []string
+	if arns := parameters[parameterExternalTargetGroupARNsParameter]; arns != "" {
+		externalTargetGroupARNs = strings.Split(arns, ",")
+	}
+
+	var elasticIPs []string
+	if ids := parameters[parameterElasticIPsParameter]; ids != "" {
+		elasticIPs = strings.Split(ids, ",")
+	}
+
+	var endpointServicePrincipals []string
+	if principals := parameters[parameterEndpointServicePrincipalsParameter]; principals != "" {
+		endpointServicePrincipals = strings.Split(principals, ",")
+	}
+
+	var denyRespStatusCode int
+	if code, err := strconv.Atoi(parameters[parameterDenyInternalDomainsResponseStatusCodeParameter]); err == nil {
+		denyRespStatusCode = code
+	}
+
 	return &Stack{
-		Name:              aws.StringValue(stack.StackName),
-		DNSName:           outputs.dnsName(),
-		TargetGroupARN:    outputs.targetGroupARN(),
-		Scheme:            parameters[parameterLoadBalancerSchemeParameter],
-		SecurityGroup:     parameters[parameterLoadBalancerSecurityGroupParameter],
-		SSLPolicy:         parameters[parameterListenerSslPolicyParameter],
-		IpAddressType:     parameters[parameterIpAddressTypeParameter],
-		LoadBalancerType:  parameters[parameterLoadBalancerTypeParameter],
-		HTTP2:             http2,
-		CertificateARNs:   certificateARNs,
-		tags:              tags,
-		OwnerIngress:      ownerIngress,
-		status:            aws.StringValue(stack.StackStatus),
-		CWAlarmConfigHash: tags[cwAlarmConfigHashTag],
-		WAFWebACLID:       parameters[parameterLoadBalancerWAFWebACLIDParameter],
-	}
-}
-
-func findManagedStacks(svc cloudformationiface.CloudFormationAPI, clusterID, controllerID string) ([]*Stack, error) {
+		Name:                                   aws.StringValue(stack.StackName),
+		DNSName:                                outputs.dnsName(),
+		TargetGroupARN:                         outputs.targetGroupARN(),
+		LoadBalancerARN:                        outputs.loadBalancerARN(),
+		Scheme:                                 parameters[parameterLoadBalancerSchemeParameter],
+		SecurityGroup:                          parameters[parameterLoadBalancerSecurityGroupParameter],
+		SSLPolicy:                              parameters[parameterListenerSslPolicyParameter],
+		IpAddressType:                          parameters[parameterIpAddressTypeParameter],
+		LoadBalancerType:                       parameters[parameterLoadBalancerTypeParameter],
+		HTTP2:                                  http2,
+		PreserveHostHeader:                     parameters[parameterPreserveHostHeaderParameter] == "true",
+		HTTPDisabled:                           parameters[parameterHTTPDisabledParameter] == "true",
+		FrontingNLB:                            parameters[parameterFrontingNLBParameter] == "true",
+		NLBSecurityGroup:                       parameters[parameterNLBSecurityGroupParameter] == "true",
+		LambdaTarget:                           parameters[parameterLambdaTargetParameter],
+		SlowStart:                              slowStart,
+		ClientKeepAlive:                        clientKeepAlive,
+		CertificateARNs:                        certificateARNs,
+		tags:                                   tags,
+		OwnerIngress:                           ownerIngress,
+		status:                                 aws.StringValue(stack.StackStatus),
+		statusReason:                           aws.StringValue(stack.StackStatusReason),
+		creationTime:                           aws.TimeValue(stack.CreationTime),
+		CWAlarmConfigHash:                      tags[cwAlarmConfigHashTag],
+		TemplateFragmentsHash:                  tags[templateFragmentsHashTag],
+		ListenerRulesHash:                      tags[listenerRulesHashTag],
+		AuthenticationHash:                     tags[authenticationHashTag],
+		ResourceTagsHash:                       tags[resourceTagsHashTag],
+		TemplateResourceTagsHash:               tags[templateTagsHashTag],
+		AttributesHash:                         tags[attributesHashTag],
+		DNSHostnamesHash:                       tags[dnsHostnamesHashTag],
+		DNSHostnames:                           splitTagValues(tags[dnsHostnamesTag]),
+		NamespacesTag:                          tags[namespacesTag],
+		Paused:                                 tags[pausedTag] == "true",
+		InternalDomainsHash:                    tags[internalDomainsHashTag],
+		WAFWebACLID:                            parameters[parameterLoadBalancerWAFWebACLIDParameter],
+		WAFRateLimit:                           wafRateLimit,
+		WAFRateLimitKey:                        parameters[parameterWAFRateLimitKeyParameter],
+		WAFManagedRuleGroups:                   wafManagedRuleGroups,
+		HealthCheckMatcher:                     parameters[parameterTargetGroupHealthCheckMatcherParameter],
+		PreserveClientIP:                       parameters[parameterTargetGroupPreserveClientIPParameter],
+		ProxyProtocolV2:                        parameters[parameterTargetGroupProxyProtocolV2Parameter] == "true",
+		ClientRoutingPolicy:                    parameters[parameterClientRoutingPolicyParameter],
+		CapacityUnits:                          capacityUnits,
+		DefaultCertificateARN:                  parameters[parameterDefaultCertificateARNParameter],
+		ExternalTargetGroupARNs:                externalTargetGroupARNs,
+		ElasticIPs:                             elasticIPs,
+		IPv4Pool:                               parameters[parameterIPv4PoolParameter],
+		EndpointServicePrincipals:              endpointServicePrincipals,
+		EndpointServiceName:                    outputs.endpointServiceName(),
+		DenyInternalDomains:                    parameters[parameterDenyInternalDomainsParameter],
+		DenyInternalDomainsResponse:            parameters[parameterDenyInternalDomainsResponseParameter],
+		DenyInternalDomainsResponseContentType: parameters[parameterDenyInternalDomainsResponseContentTypeParameter],
+		DenyInternalDomainsResponseStatusCode:  denyRespStatusCode,
+		HealthyThresholdCount:                  healthyThresholdCount,
+		UnhealthyThresholdCount:                unhealthyThresholdCount,
+	}
+}
+
+// NamespacesTagValue returns the value of the tag listing the namespaces of
+// the ingresses served by a load balancer, sorted and separated by spaces.
+// Lists exceeding the maximum length of tag values are replaced by their
+// hash. An empty list results in an empty value.
+func NamespacesTagValue(namespaces []string) string {
+	return JoinTagValues(namespaces)
+}
+
+// JoinTagValues returns the value of a tag listing the values, sorted and
+// separated by spaces. Lists exceeding the maximum length of tag values are
+// replaced by their hash. An empty list results in an empty value.
+func JoinTagValues(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	sorted := make([]string, len(values))
+	copy(sorted, values)
+	sort.Strings(sorted)
+
+	value := strings.Join(sorted, " ")
+	if len(value) > maxTagValueLength {
+		return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(value)))
+	}
+	return value
+}
+
+// splitTagValues returns the values listed by a tag value of JoinTagValues.
+// Empty values and lists replaced by their hash result in no values.
+func splitTagValues(value string) []string {
+	if value == "" || strings.HasPrefix(value, "sha256:") {
+		return nil
+	}
+	return strings.Fields(value)
+}
+
+// findManagedStacks returns the stacks of the controller in the cluster.
+// Stacks tagged by the previous owners are returned too, with PreviousOwner
+// set, so that their tags are migrated by the next update.
+func findManagedStacks(svc cloudformationiface.CloudFormationAPI, clusterID, controllerID string, previous ...*stackOwner) ([]*Stack, error) {
 	stacks := make([]*Stack, 0)
 	err := svc.DescribeStacksPages(&cloudformation.DescribeStacksInput{},
 		func(page *cloudformation.DescribeStacksOutput, lastPage bool) bool {
 			for _, s := range page.Stacks {
 				if isManagedStack(s.Tags, clusterID, controllerID) {
 					stacks = append(stacks, mapToManagedStack(s))
+					continue
+				}
+				for _, owner := range previous {
+					if owner != nil && owner.owns(s.Tags) {
+						stack := mapToManagedStack(s)
+						stack.PreviousOwner = true
+						stacks = append(stacks, stack)
+						break
+					}
 				}
 			}
 			return true
@@ -464,9 +1028,38 @@ func findManagedStacks(svc cloudformationiface.CloudFormationAPI, clusterID, con
 	if err != nil {
 		return nil, fmt.Errorf("findManagedStacks failed to list stacks: %v", err)
 	}
+
+	for _, stack := range stacks {
+		if stack.status != cloudformation.StackStatusRollbackComplete {
+			continue
+		}
+		reason, err := stackCreationFailureReason(svc, stack.Name)
+		if err != nil {
+			log.Warnf("findManagedStacks failed to get the failure reason of stack %q: %v", stack.Name, err)
+			continue
+		}
+		stack.quotaExceeded = isQuotaExceededReason(reason)
+	}
 	return stacks, nil
 }
 
+// stackCreationFailureReason returns the reason of the first resource of
+// the stack which failed to be created, the root cause of the rollback.
+func stackCreationFailureReason(svc cloudformationiface.CloudFormationAPI, stackName string) (string, error) {
+	var reason string
+	err := svc.DescribeStackEventsPages(&cloudformation.DescribeStackEventsInput{StackName: aws.String(stackName)},
+		func(page *cloudformation.DescribeStackEventsOutput, lastPage bool) bool {
+			// events are sorted from the newest to the oldest
+			for _, event := range page.StackEvents {
+				if aws.StringValue(event.ResourceStatus) == cloudformation.ResourceStatusCreateFailed {
+					reason = aws.StringValue(event.ResourceStatusReason)
+				}
+			}
+			return true
+		})
+	return reason, err
+}
+
 func isManagedStack(cfTags []*cloudformation.Tag, clusterID string, controllerID string) bool {
 	tags := convertCloudFormationTags(cfTags)
 
@@ -485,3 +1078,25 @@ func convertCloudFormationTags(tags []*cloudformation.Tag) map[string]string {
 	}
 	return ret
 }
+
+// stackLoadBalancingResources returns the ARNs of the load balancers and
+// target groups of the stack.
+func stackLoadBalancingResources(svc cloudformationiface.CloudFormationAPI, stackName string) ([]string, error) {
+	resp, err := svc.DescribeStackResources(&cloudformation.DescribeStackResourcesInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the resources of stack %q: %v", stackName, err)
+	}
+
+	var arns []string
+	for _, resource := range resp.StackResources {
+		switch aws.StringValue(resource.ResourceType) {
+		case "AWS::ElasticLoadBalancingV2::LoadBalancer", "AWS::ElasticLoadBalancingV2::TargetGroup":
+			if arn := aws.StringValue(resource.PhysicalResourceId); arn != "" {
+				arns = append(arns, arn)
+			}
+		}
+	}
+	return arns, nil
+}
@@ -3,11 +3,11 @@ package aws
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
 
 	"crypto/sha256"
 	"sort"
 
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	cloudformation "github.com/mweagle/go-cloudformation"
 )
 
@@ -25,6 +25,10 @@ const (
 	// [0]: https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-elasticloadbalancingv2-listenerrule.html#cfn-elasticloadbalancingv2-listenerrule-priority
 	internalTrafficDenyRulePriority int64 = 1
 
+	// dnsRecordWeight is the weight of the DNS records pointing to a load
+	// balancer. All load balancers serving a hostname get the same weight.
+	dnsRecordWeight int64 = 100
+
 	httpProtocol  = "HTTP"
 	httpsProtocol = "HTTPS"
 )
@@ -40,6 +44,18 @@ func hashARNs(certARNs []string) []byte {
 	return hash.Sum(nil)
 }
 
+// defaultCertificateARN returns the default certificate of the HTTPS
+// listener, the preferred one if it's among the sorted certificates or the
+// first one otherwise.
+func defaultCertificateARN(certARNs []string, preferred string) string {
+	for _, arn := range certARNs {
+		if arn == preferred {
+			return arn
+		}
+	}
+	return certARNs[0]
+}
+
 func generateTemplate(spec *stackSpec) (string, error) {
 	template := cloudformation.NewTemplate()
 	template.Description = "Load Balancer for Kubernetes Ingress"
@@ -93,7 +109,7 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		},
 		parameterIpAddressTypeParameter: &cloudformation.Parameter{
 			Type:        "String",
-			Description: "IP Address Type, 'ipv4' or 'dualstack'",
+			Description: "IP Address Type, 'ipv4', 'dualstack' or 'dualstack-without-public-ipv4'",
 			Default:     IPAddressTypeIPV4,
 		},
 		parameterLoadBalancerTypeParameter: &cloudformation.Parameter{
@@ -106,6 +122,26 @@ func generateTemplate(spec *stackSpec) (string, error) {
 			Description: "H2 Enabled",
 			Default:     "true",
 		},
+		parameterPreserveHostHeaderParameter: &cloudformation.Parameter{
+			Type:        "String",
+			Description: "Preserve Host Header Enabled",
+			Default:     "false",
+		},
+		parameterHTTPDisabledParameter: &cloudformation.Parameter{
+			Type:        "String",
+			Description: "HTTP Listener Disabled",
+			Default:     "false",
+		},
+		parameterTargetGroupSlowStartParameter: &cloudformation.Parameter{
+			Type:        "Number",
+			Description: "The slow start duration of the targets in seconds, 0 to disable",
+			Default:     "0",
+		},
+		parameterClientKeepAliveParameter: &cloudformation.Parameter{
+			Type:        "Number",
+			Description: "The client keep alive duration in seconds, 0 for the default",
+			Default:     "0",
+		},
 	}
 
 	if spec.wafWebAclId != "" {
@@ -115,6 +151,165 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		}
 	}
 
+	if spec.hasWebACL() && spec.wafRateLimit > 0 {
+		template.Parameters[parameterWAFRateLimitParameter] = &cloudformation.Parameter{
+			Type:        "Number",
+			Description: "The maximum number of requests of a client within 5 minutes",
+		}
+		template.Parameters[parameterWAFRateLimitKeyParameter] = &cloudformation.Parameter{
+			Type:          "String",
+			Description:   "What the requests of a client are identified by",
+			AllowedValues: []string{WAFRateLimitKeyIP, WAFRateLimitKeyForwardedIP},
+		}
+	}
+
+	if spec.hasWebACL() && len(spec.wafManagedRuleGroups) > 0 {
+		template.Parameters[parameterWAFManagedRuleGroupsParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The AWS managed rule groups of the WebACL",
+		}
+	}
+
+	if spec.frontingNLB {
+		template.Parameters[parameterFrontingNLBParameter] = &cloudformation.Parameter{
+			Type:          "String",
+			Description:   "Whether a network load balancer fronts the internal application load balancer",
+			AllowedValues: []string{"true", "false"},
+		}
+	}
+
+	if spec.nlbSecurityGroup {
+		template.Parameters[parameterNLBSecurityGroupParameter] = &cloudformation.Parameter{
+			Type:          "String",
+			Description:   "Whether the security group is attached to the network load balancer",
+			AllowedValues: []string{"true", "false"},
+		}
+	}
+
+	if spec.lambdaTarget != "" {
+		template.Parameters[parameterLambdaTargetParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The ARN of the Lambda function requests are forwarded to",
+		}
+	}
+
+	if spec.healthCheckMatcher != "" {
+		template.Parameters[parameterTargetGroupHealthCheckMatcherParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The HTTP codes of a successful healthcheck",
+		}
+	}
+
+	if spec.denyInternalDomainsOverride != "" {
+		template.Parameters[parameterDenyInternalDomainsParameter] = &cloudformation.Parameter{
+			Type:          "String",
+			Description:   "Whether requests to internal domains are denied",
+			AllowedValues: []string{"true", "false"},
+		}
+	}
+
+	if spec.denyInternalDomainsResponseOverride.body != "" {
+		template.Parameters[parameterDenyInternalDomainsResponseParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The response body for requests to internal domains",
+		}
+	}
+
+	if spec.denyInternalDomainsResponseOverride.contentType != "" {
+		template.Parameters[parameterDenyInternalDomainsResponseContentTypeParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The response content type for requests to internal domains",
+		}
+	}
+
+	if spec.denyInternalDomainsResponseOverride.statusCode > 0 {
+		template.Parameters[parameterDenyInternalDomainsResponseStatusCodeParameter] = &cloudformation.Parameter{
+			Type:        "Number",
+			Description: "The response status code for requests to internal domains",
+		}
+	}
+
+	if spec.preserveClientIP != "" {
+		template.Parameters[parameterTargetGroupPreserveClientIPParameter] = &cloudformation.Parameter{
+			Type:          "String",
+			Description:   "Whether the client IP is preserved for the targets",
+			AllowedValues: []string{"true", "false"},
+		}
+	}
+
+	if spec.proxyProtocolV2 {
+		template.Parameters[parameterTargetGroupProxyProtocolV2Parameter] = &cloudformation.Parameter{
+			Type:          "String",
+			Description:   "Whether the PROXY protocol version 2 is enabled for the targets",
+			AllowedValues: []string{"true", "false"},
+		}
+	}
+
+	if spec.hasCapacityReservation() {
+		template.Parameters[parameterCapacityUnitsParameter] = &cloudformation.Parameter{
+			Type:        "Number",
+			Description: "The load balancer capacity units reserved for the load balancer",
+			MinValue:    cloudformation.Integer(MinCapacityUnits),
+		}
+	}
+
+	if spec.defaultCertificateARN != "" {
+		template.Parameters[parameterDefaultCertificateARNParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The certificate presented to clients without SNI",
+		}
+	}
+
+	if spec.ipv4Pool != "" {
+		template.Parameters[parameterIPv4PoolParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The IPAM pool or public IPv4 pool the addresses of the Load Balancer are taken from",
+		}
+	}
+
+	if len(spec.endpointServicePrincipals) > 0 {
+		template.Parameters[parameterEndpointServicePrincipalsParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The principals allowed to connect to the VPC endpoint service of the Load Balancer",
+		}
+	}
+
+	if len(spec.elasticIPs) > 0 {
+		template.Parameters[parameterElasticIPsParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The allocation IDs of the Elastic IPs of the Load Balancer",
+		}
+	}
+
+	if len(spec.externalTargetGroupARNs) > 0 {
+		template.Parameters[parameterExternalTargetGroupARNsParameter] = &cloudformation.Parameter{
+			Type:        "String",
+			Description: "The target groups not created by the controller which the listeners forward requests to",
+		}
+	}
+
+	if spec.clientRoutingPolicy != "" {
+		template.Parameters[parameterClientRoutingPolicyParameter] = &cloudformation.Parameter{
+			Type:          "String",
+			Description:   "The client routing policy of the DNS records of the load balancer",
+			AllowedValues: []string{ClientRoutingPolicyAZAffinity, ClientRoutingPolicyPartialAZAffinity, ClientRoutingPolicyAnyAZ},
+		}
+	}
+
+	if spec.healthyThresholdCount > 0 {
+		template.Parameters[parameterTargetGroupHealthyThresholdParameter] = &cloudformation.Parameter{
+			Type:        "Number",
+			Description: "The number of consecutive successful healthchecks before a target is healthy",
+		}
+	}
+
+	if spec.unhealthyThresholdCount > 0 {
+		template.Parameters[parameterTargetGroupUnhealthyThresholdParameter] = &cloudformation.Parameter{
+			Type:        "Number",
+			Description: "The number of consecutive failed healthchecks before a target is unhealthy",
+		}
+	}
+
 	protocol := httpProtocol
 	tlsProtocol := httpsProtocol
 	healthCheckProtocol := httpProtocol
@@ -126,7 +321,28 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		healthCheckProtocol = httpsProtocol
 	}
 
-	if spec.loadbalancerType == LoadBalancerTypeApplication && spec.httpRedirectToHTTPS {
+	// the listeners forward to the cluster unless a Lambda function serves
+	// all requests instead
+	defaultTargetGroup := "TG"
+	if spec.lambdaTarget != "" {
+		defaultTargetGroup = lambdaTargetGroupName(spec.lambdaTarget)
+	}
+
+	// only application load balancers split requests between target groups
+	var externalTargetGroupARNs []string
+	if spec.loadbalancerType == LoadBalancerTypeApplication && spec.lambdaTarget == "" {
+		externalTargetGroupARNs = spec.externalTargetGroupARNs
+	}
+
+	// no HTTP listener at all if disabled, neither redirecting nor forwarding
+	httpEnabled := !spec.httpDisabled
+	// authentication is only supported by HTTPS listeners, so HTTP
+	// requests are always redirected when it's configured
+	var authentication *ListenerAuthentication
+	if spec.loadbalancerType == LoadBalancerTypeApplication {
+		authentication = spec.authentication
+	}
+	if httpEnabled && spec.loadbalancerType == LoadBalancerTypeApplication && (spec.httpRedirectToHTTPS || authentication != nil) {
 		template.AddResource("HTTPListener", &cloudformation.ElasticLoadBalancingV2Listener{
 			DefaultActions: &cloudformation.ElasticLoadBalancingV2ListenerActionList{
 				{
@@ -145,14 +361,11 @@ func generateTemplate(spec *stackSpec) (string, error) {
 			Port:            cloudformation.Integer(80),
 			Protocol:        cloudformation.String(httpProtocol),
 		})
-	} else if spec.loadbalancerType == LoadBalancerTypeApplication || spec.nlbHTTPEnabled {
+	} else if httpEnabled && (spec.loadbalancerType == LoadBalancerTypeApplication || spec.nlbHTTPEnabled) {
 		listenerName := "HTTPListener"
 		template.AddResource(listenerName, &cloudformation.ElasticLoadBalancingV2Listener{
 			DefaultActions: &cloudformation.ElasticLoadBalancingV2ListenerActionList{
-				{
-					Type:           cloudformation.String("forward"),
-					TargetGroupArn: cloudformation.Ref("TG").String(),
-				},
+				forwardAction(defaultTargetGroup, externalTargetGroupARNs),
 			},
 			LoadBalancerArn: cloudformation.Ref("LB").String(),
 			Port:            cloudformation.Integer(80),
@@ -170,6 +383,9 @@ func generateTemplate(spec *stackSpec) (string, error) {
 				),
 			)
 		}
+		if spec.loadbalancerType == LoadBalancerTypeApplication {
+			addListenerRules(template, listenerName, spec.listenerRules, nil)
+		}
 	}
 
 	if len(spec.certificateARNs) > 0 {
@@ -182,18 +398,20 @@ func generateTemplate(spec *stackSpec) (string, error) {
 			return certificateARNs[i] < certificateARNs[j]
 		})
 
-		// Add an HTTPS Listener resource with the first certificate as the default one
+		// Add an HTTPS Listener resource with the default certificate
 		listenerName := "HTTPSListener"
+		defaultActions := cloudformation.ElasticLoadBalancingV2ListenerActionList{
+			forwardAction(defaultTargetGroup, externalTargetGroupARNs),
+		}
+		if authentication != nil {
+			defaultActions[0].Order = cloudformation.Integer(2)
+			defaultActions = append(cloudformation.ElasticLoadBalancingV2ListenerActionList{authentication.listenerAction()}, defaultActions...)
+		}
 		template.AddResource(listenerName, &cloudformation.ElasticLoadBalancingV2Listener{
-			DefaultActions: &cloudformation.ElasticLoadBalancingV2ListenerActionList{
-				{
-					Type:           cloudformation.String("forward"),
-					TargetGroupArn: cloudformation.Ref("TG").String(),
-				},
-			},
+			DefaultActions: &defaultActions,
 			Certificates: &cloudformation.ElasticLoadBalancingV2ListenerCertificatePropertyList{
 				{
-					CertificateArn: cloudformation.String(certificateARNs[0]),
+					CertificateArn: cloudformation.String(defaultCertificateARN(certificateARNs, spec.defaultCertificateARN)),
 				},
 			},
 			LoadBalancerArn: cloudformation.Ref("LB").String(),
@@ -213,6 +431,9 @@ func generateTemplate(spec *stackSpec) (string, error) {
 				),
 			)
 		}
+		if spec.loadbalancerType == LoadBalancerTypeApplication {
+			addListenerRules(template, listenerName, spec.listenerRules, authentication)
+		}
 
 		// Add a ListenerCertificate resource with all of the certificates, including the default one
 		certificateList := make(cloudformation.ElasticLoadBalancingV2ListenerCertificateCertificateList, 0, len(certificateARNs))
@@ -249,6 +470,24 @@ func generateTemplate(spec *stackSpec) (string, error) {
 				Value: cloudformation.String(fmt.Sprintf("%t", spec.http2)),
 			},
 		)
+
+		if spec.preserveHostHeader {
+			lbAttrList = append(lbAttrList,
+				cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+					Key:   cloudformation.String("routing.http.preserve_host_header.enabled"),
+					Value: cloudformation.String("true"),
+				},
+			)
+		}
+
+		if spec.clientKeepAliveSeconds > 0 {
+			lbAttrList = append(lbAttrList,
+				cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+					Key:   cloudformation.String("client_keep_alive.seconds"),
+					Value: cloudformation.String(fmt.Sprintf("%d", spec.clientKeepAliveSeconds)),
+				},
+			)
+		}
 	}
 
 	if spec.nlbCrossZone && spec.loadbalancerType == LoadBalancerTypeNetwork {
@@ -260,6 +499,15 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		)
 	}
 
+	if spec.clientRoutingPolicy != "" && spec.loadbalancerType == LoadBalancerTypeNetwork {
+		lbAttrList = append(lbAttrList,
+			cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+				Key:   cloudformation.String("dns_record.client_routing_policy"),
+				Value: cloudformation.Ref(parameterClientRoutingPolicyParameter).String(),
+			},
+		)
+	}
+
 	if spec.albLogsS3Bucket != "" {
 		lbAttrList = append(lbAttrList,
 			cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
@@ -290,25 +538,40 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		)
 	}
 
+	lbAttrList = spec.loadBalancerAttributes.loadBalancerAttributes(lbAttrList)
+
 	lb := &cloudformation.ElasticLoadBalancingV2LoadBalancer{
 		LoadBalancerAttributes: &lbAttrList,
 
 		IPAddressType: cloudformation.Ref(parameterIpAddressTypeParameter).String(),
 		Scheme:        cloudformation.Ref(parameterLoadBalancerSchemeParameter).String(),
 		Subnets:       cloudformation.Ref(parameterLoadBalancerSubnetsParameter).StringList(),
-		Tags: &cloudformation.TagList{
-			{
-				Key:   cloudformation.String("StackName"),
-				Value: cloudformation.Ref("AWS::StackName").String(),
-			},
-		},
+		Tags: spec.allResourceTags().templateTags(cloudformation.Tag{
+			Key:   cloudformation.String(stackNameResourceTag),
+			Value: cloudformation.Ref("AWS::StackName").String(),
+		}),
 	}
 
-	// Security groups can't be set for 'network' load balancers
-	if spec.loadbalancerType != LoadBalancerTypeNetwork {
+	// the application load balancer is only reachable through the network
+	// load balancer in front of it, which gets the scheme of the stack
+	if spec.frontingNLB {
+		lb.Scheme = cloudformation.String(elbv2.LoadBalancerSchemeEnumInternal)
+	}
+
+	// Security groups can only be set for 'network' load balancers when
+	// they are created, so only the ones requesting it get one
+	if spec.loadbalancerType != LoadBalancerTypeNetwork || spec.nlbSecurityGroup {
 		lb.SecurityGroups = cloudformation.Ref(parameterLoadBalancerSecurityGroupParameter).StringList()
 	}
 
+	// static IPs are only supported by internet-facing network load
+	// balancers
+	if len(spec.elasticIPs) > 0 && spec.loadbalancerType == LoadBalancerTypeNetwork {
+		if err := addSubnetMappings(template, spec, lb); err != nil {
+			return "", err
+		}
+	}
+
 	// TODO(mlarsen): hack to only set type on "new" stacks where this
 	// features was enabled. Adding the Type value for existing Load
 	// Balancers will cause them to be recreated which is disruptive (and
@@ -318,7 +581,7 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		lb.Type = cloudformation.Ref(parameterLoadBalancerTypeParameter).String()
 	}
 
-	template.AddResource("LB", lb)
+	template.AddResource("LB", loadBalancerResource(spec, lb))
 
 	targetGroupAttributes := cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttributeList{
 		{
@@ -327,6 +590,35 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		},
 	}
 
+	if spec.slowStartDurationSeconds > 0 && spec.loadbalancerType == LoadBalancerTypeApplication {
+		targetGroupAttributes = append(targetGroupAttributes,
+			cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttribute{
+				Key:   cloudformation.String("slow_start.duration_seconds"),
+				Value: cloudformation.String(fmt.Sprintf("%d", spec.slowStartDurationSeconds)),
+			},
+		)
+	}
+
+	if spec.preserveClientIP != "" && spec.loadbalancerType == LoadBalancerTypeNetwork {
+		targetGroupAttributes = append(targetGroupAttributes,
+			cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttribute{
+				Key:   cloudformation.String("preserve_client_ip.enabled"),
+				Value: cloudformation.Ref(parameterTargetGroupPreserveClientIPParameter).String(),
+			},
+		)
+	}
+
+	if spec.proxyProtocolV2 && spec.loadbalancerType == LoadBalancerTypeNetwork {
+		targetGroupAttributes = append(targetGroupAttributes,
+			cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttribute{
+				Key:   cloudformation.String("proxy_protocol_v2.enabled"),
+				Value: cloudformation.Ref(parameterTargetGroupProxyProtocolV2Parameter).String(),
+			},
+		)
+	}
+
+	targetGroupAttributes = spec.targetGroupAttributes.targetGroupAttributes(targetGroupAttributes)
+
 	targetGroup := &cloudformation.ElasticLoadBalancingV2TargetGroup{
 		TargetGroupAttributes: &targetGroupAttributes,
 
@@ -337,16 +629,49 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		Port:                       cloudformation.Ref(parameterTargetTargetPortParameter).Integer(),
 		Protocol:                   cloudformation.String(protocol),
 		VPCID:                      cloudformation.Ref(parameterTargetGroupVPCIDParameter).String(),
+		Tags:                       spec.allResourceTags().templateTags(),
+	}
+
+	// the instances of the cluster can't be registered by ID in the target
+	// group of another account
+	if spec.targetIPs {
+		targetGroup.TargetType = cloudformation.String("ip")
 	}
 
 	// custom target group healthcheck only supported when the target group protocol is != TCP
 	if protocol != "TCP" {
 		targetGroup.HealthCheckTimeoutSeconds = cloudformation.Ref(parameterTargetGroupHealthCheckTimeoutParameter).Integer()
 	}
+
+	if spec.healthyThresholdCount > 0 {
+		targetGroup.HealthyThresholdCount = cloudformation.Ref(parameterTargetGroupHealthyThresholdParameter).Integer()
+	}
+
+	if spec.unhealthyThresholdCount > 0 {
+		targetGroup.UnhealthyThresholdCount = cloudformation.Ref(parameterTargetGroupUnhealthyThresholdParameter).Integer()
+	}
+
+	if spec.loadbalancerType == LoadBalancerTypeApplication && spec.healthCheckMatcher != "" {
+		targetGroup.Matcher = &cloudformation.ElasticLoadBalancingV2TargetGroupMatcher{
+			HTTPCode: cloudformation.Ref(parameterTargetGroupHealthCheckMatcherParameter).String(),
+		}
+	}
 	template.AddResource("TG", targetGroup)
 
+	if spec.loadbalancerType == LoadBalancerTypeApplication {
+		addLambdaTargetGroups(template, spec.name, lambdaFunctionARNs(spec), spec.allResourceTags())
+	}
+
+	// the DNS name of the stack is the one of the load balancer receiving
+	// the traffic of the clients
+	dnsLB := "LB"
+	if spec.frontingNLB {
+		addFrontingNLB(template, spec, httpEnabled, len(spec.certificateARNs) > 0)
+		dnsLB = "FrontingLB"
+	}
+
 	if spec.loadbalancerType == LoadBalancerTypeApplication && spec.wafWebAclId != "" {
-		if strings.HasPrefix(spec.wafWebAclId, "arn:aws:wafv2:") {
+		if isWAFv2WebACLARN(spec.wafWebAclId) {
 			template.AddResource("WAFAssociation", &cloudformation.WAFv2WebACLAssociation{
 				ResourceArn: cloudformation.Ref("LB").String(),
 				WebACLArn:   cloudformation.Ref(parameterLoadBalancerWAFWebACLIDParameter).String(),
@@ -359,6 +684,14 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		}
 	}
 
+	if spec.hasWebACL() {
+		addWebACL(template, spec)
+	}
+
+	for _, record := range spec.dnsRecords {
+		addDNSRecords(template, dnsLB, record, spec.ipAddressType, spec.dnsOwnerID)
+	}
+
 	for idx, alarm := range spec.cwAlarms {
 		resourceName := fmt.Sprintf("CloudWatchAlarm%d", idx)
 		template.AddResource(resourceName, &cloudformation.CloudWatchAlarm{
@@ -384,14 +717,23 @@ func generateTemplate(spec *stackSpec) (string, error) {
 	}
 
 	template.Outputs = map[string]*cloudformation.Output{
-		"LoadBalancerDNSName": &cloudformation.Output{
+		outputLoadBalancerDNSName: &cloudformation.Output{
 			Description: "DNS name for the LoadBalancer",
-			Value:       cloudformation.GetAtt("LB", "DNSName").String(),
+			Value:       cloudformation.GetAtt(dnsLB, "DNSName").String(),
 		},
-		"TargetGroupARN": &cloudformation.Output{
+		outputTargetGroupARN: &cloudformation.Output{
 			Description: "The ARN of the TargetGroup",
 			Value:       cloudformation.Ref("TG").String(),
 		},
+		outputLoadBalancerARN: &cloudformation.Output{
+			Description: "The ARN of the LoadBalancer",
+			Value:       cloudformation.Ref("LB").String(),
+		},
+	}
+
+	// PrivateLink is only supported by internal network load balancers
+	if len(spec.endpointServicePrincipals) > 0 && spec.loadbalancerType == LoadBalancerTypeNetwork && spec.scheme == elbv2.LoadBalancerSchemeEnumInternal {
+		addEndpointService(template, spec)
 	}
 
 	stackTemplate, err := json.MarshalIndent(template, "", "    ")
@@ -399,9 +741,132 @@ func generateTemplate(spec *stackSpec) (string, error) {
 		return "", err
 	}
 
+	if len(spec.templateFragments) > 0 {
+		stackTemplate, err = mergeTemplateFragments(stackTemplate, spec.templateFragments)
+		if err != nil {
+			return "", fmt.Errorf("failed to merge the template fragments: %v", err)
+		}
+	}
+
 	return string(stackTemplate), nil
 }
 
+// addDNSRecords adds weighted alias records pointing to the load balancer for
+// the hostname of the record. Route53 only answers with the records of load
+// balancers considered healthy, which spreads traffic across all the load
+// balancers serving the hostname. A TXT record in the external-dns format
+// marks the records as owned by the controller.
+func addDNSRecords(template *cloudformation.Template, lbName string, record *dnsRecord, ipAddressType, ownerID string) {
+	hash := sha256.Sum256([]byte(record.hostname))
+	resourceName := fmt.Sprintf("DNSRecord%x", hash[:8])
+
+	recordTypes := []string{"A"}
+	if IsDualstack(ipAddressType) {
+		recordTypes = append(recordTypes, "AAAA")
+	}
+
+	for _, recordType := range recordTypes {
+		template.AddResource(resourceName+recordType, &cloudformation.Route53RecordSet{
+			HostedZoneID:  cloudformation.String(record.hostedZoneID),
+			Name:          cloudformation.String(record.hostname),
+			Type:          cloudformation.String(recordType),
+			SetIDentifier: cloudformation.Ref("AWS::StackName").String(),
+			Weight:        cloudformation.Integer(dnsRecordWeight),
+			AliasTarget: &cloudformation.Route53RecordSetAliasTarget{
+				DNSName:              cloudformation.GetAtt(lbName, "DNSName").String(),
+				HostedZoneID:         cloudformation.GetAtt(lbName, "CanonicalHostedZoneID").String(),
+				EvaluateTargetHealth: cloudformation.Bool(true),
+			},
+		})
+	}
+
+	template.AddResource(resourceName+"TXT", &cloudformation.Route53RecordSet{
+		HostedZoneID:    cloudformation.String(record.hostedZoneID),
+		Name:            cloudformation.String(record.hostname),
+		Type:            cloudformation.String("TXT"),
+		SetIDentifier:   cloudformation.Ref("AWS::StackName").String(),
+		Weight:          cloudformation.Integer(dnsRecordWeight),
+		TTL:             cloudformation.String("300"),
+		ResourceRecords: cloudformation.StringList(cloudformation.String(externalDNSOwnershipRecord(ownerID))),
+	})
+}
+
+// addFrontingNLB adds a network load balancer forwarding the TCP traffic of
+// the listeners of the application load balancer to it, through target
+// groups of type alb. TLS is still terminated by the application load
+// balancer.
+func addFrontingNLB(template *cloudformation.Template, spec *stackSpec, http, https bool) {
+	lbAttrList := cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttributeList{}
+	if spec.nlbCrossZone {
+		lbAttrList = append(lbAttrList,
+			cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+				Key:   cloudformation.String("load_balancing.cross_zone.enabled"),
+				Value: cloudformation.String("true"),
+			},
+		)
+	}
+
+	template.AddResource("FrontingLB", &cloudformation.ElasticLoadBalancingV2LoadBalancer{
+		LoadBalancerAttributes: &lbAttrList,
+
+		IPAddressType: cloudformation.String(IPAddressTypeIPV4),
+		Scheme:        cloudformation.Ref(parameterLoadBalancerSchemeParameter).String(),
+		Subnets:       cloudformation.Ref(parameterLoadBalancerSubnetsParameter).StringList(),
+		Type:          cloudformation.String(LoadBalancerTypeNetwork),
+		Tags: spec.allResourceTags().templateTags(cloudformation.Tag{
+			Key:   cloudformation.String(stackNameResourceTag),
+			Value: cloudformation.Ref("AWS::StackName").String(),
+		}),
+	})
+
+	listeners := []struct {
+		name     string
+		port     int64
+		protocol string
+		enabled  bool
+	}{
+		{name: "HTTPListener", port: 80, protocol: httpProtocol, enabled: http},
+		{name: "HTTPSListener", port: 443, protocol: httpsProtocol, enabled: https},
+	}
+	for _, l := range listeners {
+		if !l.enabled {
+			continue
+		}
+
+		targetGroupName := "Fronting" + l.protocol + "TG"
+		// targets of type alb can only be registered once the listener of
+		// the application load balancer exists
+		tg := template.AddResource(targetGroupName, &cloudformation.ElasticLoadBalancingV2TargetGroup{
+			TargetType: cloudformation.String("alb"),
+			Port:       cloudformation.Integer(l.port),
+			Protocol:   cloudformation.String("TCP"),
+			VPCID:      cloudformation.Ref(parameterTargetGroupVPCIDParameter).String(),
+			Targets: &cloudformation.ElasticLoadBalancingV2TargetGroupTargetDescriptionList{
+				{
+					ID:   cloudformation.Ref("LB").String(),
+					Port: cloudformation.Integer(l.port),
+				},
+			},
+			HealthCheckProtocol: cloudformation.String(l.protocol),
+			HealthCheckPath:     cloudformation.Ref(parameterTargetGroupHealthCheckPathParameter).String(),
+			Tags:                spec.allResourceTags().templateTags(),
+		})
+		tg.DependsOn = []string{l.name}
+
+		template.AddResource("Fronting"+l.name, &cloudformation.ElasticLoadBalancingV2Listener{
+			DefaultActions: &cloudformation.ElasticLoadBalancingV2ListenerActionList{
+				{
+					Type:           cloudformation.String("forward"),
+					TargetGroupArn: cloudformation.Ref(targetGroupName).String(),
+				},
+			},
+			LoadBalancerArn: cloudformation.Ref("FrontingLB").String(),
+			Port:            cloudformation.Integer(l.port),
+			Protocol:        cloudformation.String("TCP"),
+		})
+	}
+}
+
 func generateDenyInternalTrafficRule(listenerName string, rulePriority int64, internalDomains []string, resp denyResp) cloudformation.ElasticLoadBalancingV2ListenerRule {
 	values := cloudformation.StringList()
 	for _, domain := range internalDomains {
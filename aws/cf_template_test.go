@@ -231,6 +231,107 @@ func TestGenerateTemplate(t *testing.T) {
 				require.NotNil(t, props.WebACLID)
 			},
 		},
+		{
+			name: "stack has WAF rate limit",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+				wafRateLimit:     2000,
+				wafRateLimitKey:  WAFRateLimitKeyForwardedIP,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterWAFRateLimitParameter])
+				require.NotNil(t, template.Parameters[parameterWAFRateLimitKeyParameter])
+				require.NotNil(t, template.Resources["WebACL"])
+				props := template.Resources["WebACL"].Properties.(*cloudformation.WAFv2WebACL)
+				require.Equal(t, "REGIONAL", props.Scope.Literal)
+				require.NotNil(t, props.DefaultAction.Allow)
+				require.Len(t, *props.Rules, 1)
+				rule := (*props.Rules)[0]
+				require.NotNil(t, rule.Action.Block)
+				statement := rule.Statement.RateBasedStatement
+				require.Equal(t, cloudformation.Ref(parameterWAFRateLimitParameter).Integer(), statement.Limit)
+				require.Equal(t, cloudformation.Ref(parameterWAFRateLimitKeyParameter).String(), statement.AggregateKeyType)
+				require.Equal(t, "X-Forwarded-For", statement.ForwardedIPConfig.HeaderName.Literal)
+
+				require.NotNil(t, template.Resources["WebACLAssociation"])
+				association := template.Resources["WebACLAssociation"].Properties.(*cloudformation.WAFv2WebACLAssociation)
+				require.Equal(t, cloudformation.GetAtt("WebACL", "Arn").String(), association.WebACLArn)
+				require.Nil(t, template.Resources["WAFAssociation"])
+			},
+		},
+		{
+			name: "stack has WAF managed rule groups",
+			spec: &stackSpec{
+				loadbalancerType:     LoadBalancerTypeApplication,
+				wafRateLimit:         2000,
+				wafRateLimitKey:      WAFRateLimitKeyIP,
+				wafManagedRuleGroups: []string{"AWSManagedRulesCommonRuleSet", "AWSManagedRulesKnownBadInputsRuleSet"},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterWAFManagedRuleGroupsParameter])
+				require.NotNil(t, template.Resources["WebACL"])
+				props := template.Resources["WebACL"].Properties.(*cloudformation.WAFv2WebACL)
+				rules := *props.Rules
+				require.Len(t, rules, 3)
+				require.NotNil(t, rules[0].Statement.RateBasedStatement)
+				require.Nil(t, rules[0].Statement.RateBasedStatement.ForwardedIPConfig)
+				for i, name := range []string{"AWSManagedRulesCommonRuleSet", "AWSManagedRulesKnownBadInputsRuleSet"} {
+					rule := rules[i+1]
+					require.Equal(t, int64(i+1), rule.Priority.Literal)
+					require.NotNil(t, rule.OverrideAction.None)
+					require.Nil(t, rule.Action)
+					require.Equal(t, "AWS", rule.Statement.ManagedRuleGroupStatement.VendorName.Literal)
+					require.Equal(t, name, rule.Statement.ManagedRuleGroupStatement.Name.Literal)
+				}
+				require.NotNil(t, template.Resources["WebACLAssociation"])
+			},
+		},
+		{
+			name: "stack with WAF Web ACL has no WAF rate limit",
+			spec: &stackSpec{
+				loadbalancerType:     LoadBalancerTypeApplication,
+				wafWebAclId:          "foo-bar-baz",
+				wafRateLimit:         2000,
+				wafRateLimitKey:      WAFRateLimitKeyIP,
+				wafManagedRuleGroups: []string{"AWSManagedRulesCommonRuleSet"},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Resources["WAFAssociation"])
+				require.Nil(t, template.Resources["WebACL"])
+				require.Nil(t, template.Parameters[parameterWAFRateLimitParameter])
+				require.Nil(t, template.Parameters[parameterWAFManagedRuleGroupsParameter])
+			},
+		},
+		{
+			name: "attributes override the ones of the controller",
+			spec: &stackSpec{
+				loadbalancerType:                  LoadBalancerTypeApplication,
+				deregistrationDelayTimeoutSeconds: 1234,
+				loadBalancerAttributes:            Attributes{"routing.http2.enabled": "false"},
+				targetGroupAttributes:             Attributes{"load_balancing.algorithm.type": "least_outstanding_requests"},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				for _, attribute := range *lb.LoadBalancerAttributes {
+					if attribute.Key.Literal == "routing.http2.enabled" {
+						require.Equal(t, "false", attribute.Value.Literal)
+					}
+				}
+
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				expected := cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttributeList{
+					{
+						Key:   cloudformation.String("deregistration_delay.timeout_seconds"),
+						Value: cloudformation.String("1234"),
+					},
+					{
+						Key:   cloudformation.String("load_balancing.algorithm.type"),
+						Value: cloudformation.String("least_outstanding_requests"),
+					},
+				}
+				require.Equal(t, &expected, tg.TargetGroupAttributes)
+			},
+		},
 		{
 			name: "deregistration timeout is set correctly",
 			spec: &stackSpec{
@@ -341,6 +442,39 @@ func TestGenerateTemplate(t *testing.T) {
 				require.NotContains(t, template.Resources, "HTTPRuleBlockInternalTraffic")
 			},
 		},
+		{
+			name: "Override of deny internal traffic response is passed as parameters",
+			spec: &stackSpec{
+				loadbalancerType:                    LoadBalancerTypeApplication,
+				certificateARNs:                     map[string]time.Time{"domain.company.com": time.Now()},
+				denyInternalDomains:                 true,
+				internalDomains:                     internalDomains,
+				denyInternalDomainsResponse:         denyResp,
+				denyInternalDomainsResponseOverride: denyResp,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.Contains(t, template.Parameters, parameterDenyInternalDomainsResponseParameter)
+				require.Contains(t, template.Parameters, parameterDenyInternalDomainsResponseContentTypeParameter)
+				require.Contains(t, template.Parameters, parameterDenyInternalDomainsResponseStatusCodeParameter)
+				validateDenyRule(t, template.Resources["HTTPSRuleBlockInternalTraffic"])
+			},
+		},
+		{
+			name: "Override of deny internal traffic is passed as parameter",
+			spec: &stackSpec{
+				loadbalancerType:    LoadBalancerTypeApplication,
+				certificateARNs:     map[string]time.Time{"domain.company.com": time.Now()},
+				httpRedirectToHTTPS: false,
+
+				denyInternalDomains:         false,
+				denyInternalDomainsOverride: "false",
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.Contains(t, template.Parameters, parameterDenyInternalDomainsParameter)
+				require.NotContains(t, template.Resources, "HTTPSRuleBlockInternalTraffic")
+				require.NotContains(t, template.Resources, "HTTPRuleBlockInternalTraffic")
+			},
+		},
 		{
 			name: "Does not create deny internal traffic rule on NLBs",
 			spec: &stackSpec{
@@ -402,6 +536,507 @@ func TestGenerateTemplate(t *testing.T) {
 				require.Equal(t, cloudformation.String("HTTP"), tg.HealthCheckProtocol)
 			},
 		},
+		{
+			name: "DNS records are weighted aliases with an ownership TXT record",
+			spec: &stackSpec{
+				ipAddressType: IPAddressTypeDualstack,
+				dnsOwnerID:    "my-owner",
+				dnsRecords: []*dnsRecord{
+					{hostname: "foo.example.org", hostedZoneID: "Z123"},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				var records []*cloudformation.Route53RecordSet
+				for _, resource := range template.Resources {
+					if record, ok := resource.Properties.(*cloudformation.Route53RecordSet); ok {
+						records = append(records, record)
+					}
+				}
+				require.Len(t, records, 3)
+
+				types := make([]string, 0, len(records))
+				for _, record := range records {
+					types = append(types, record.Type.Literal)
+					require.Equal(t, "foo.example.org", record.Name.Literal)
+					require.Equal(t, "Z123", record.HostedZoneID.Literal)
+					require.Equal(t, dnsRecordWeight, record.Weight.Literal)
+					if record.Type.Literal == "TXT" {
+						require.Equal(t, `"heritage=external-dns,external-dns/owner=my-owner"`, record.ResourceRecords.Literal[0].Literal)
+					} else {
+						require.True(t, record.AliasTarget.EvaluateTargetHealth.Literal)
+					}
+				}
+				sort.Strings(types)
+				require.Equal(t, []string{"A", "AAAA", "TXT"}, types)
+			},
+		},
+		{
+			name: "DNS records of a load balancer without public IPv4 include AAAA records",
+			spec: &stackSpec{
+				ipAddressType: IPAddressTypeDualstackWithoutPublicIPV4,
+				dnsRecords: []*dnsRecord{
+					{hostname: "foo.example.org", hostedZoneID: "Z123"},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				var types []string
+				for _, resource := range template.Resources {
+					if record, ok := resource.Properties.(*cloudformation.Route53RecordSet); ok {
+						types = append(types, record.Type.Literal)
+					}
+				}
+				sort.Strings(types)
+				require.Equal(t, []string{"A", "AAAA", "TXT"}, types)
+			},
+		},
+		{
+			name: "DNS records of a dualstack NLB include AAAA records",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeNetwork,
+				ipAddressType:    IPAddressTypeDualstack,
+				dnsRecords: []*dnsRecord{
+					{hostname: "foo.example.org", hostedZoneID: "Z123"},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Equal(t, cloudformation.Ref(parameterIpAddressTypeParameter).String(), lb.IPAddressType)
+
+				var types []string
+				for _, resource := range template.Resources {
+					if record, ok := resource.Properties.(*cloudformation.Route53RecordSet); ok {
+						types = append(types, record.Type.Literal)
+					}
+				}
+				sort.Strings(types)
+				require.Equal(t, []string{"A", "AAAA", "TXT"}, types)
+			},
+		},
+		{
+			name: "ALB target group has slow start attribute",
+			spec: &stackSpec{
+				loadbalancerType:         LoadBalancerTypeApplication,
+				slowStartDurationSeconds: 60,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Contains(t, *tg.TargetGroupAttributes, cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttribute{
+					Key:   cloudformation.String("slow_start.duration_seconds"),
+					Value: cloudformation.String("60"),
+				})
+			},
+		},
+		{
+			name: "NLB target group has no slow start attribute",
+			spec: &stackSpec{
+				loadbalancerType:         LoadBalancerTypeNetwork,
+				slowStartDurationSeconds: 60,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				for _, attr := range *tg.TargetGroupAttributes {
+					require.NotEqual(t, "slow_start.duration_seconds", attr.Key.Literal)
+				}
+			},
+		},
+		{
+			name: "ALB target group has health check matcher",
+			spec: &stackSpec{
+				loadbalancerType:   LoadBalancerTypeApplication,
+				healthCheckMatcher: "200-399",
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterTargetGroupHealthCheckMatcherParameter])
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Equal(t, &cloudformation.ElasticLoadBalancingV2TargetGroupMatcher{
+					HTTPCode: cloudformation.Ref(parameterTargetGroupHealthCheckMatcherParameter).String(),
+				}, tg.Matcher)
+			},
+		},
+		{
+			name: "target group has no health check matcher by default",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.Nil(t, template.Parameters[parameterTargetGroupHealthCheckMatcherParameter])
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Nil(t, tg.Matcher)
+			},
+		},
+		{
+			name: "target group has health check threshold counts",
+			spec: &stackSpec{
+				loadbalancerType:        LoadBalancerTypeApplication,
+				healthyThresholdCount:   3,
+				unhealthyThresholdCount: 4,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterTargetGroupHealthyThresholdParameter])
+				require.NotNil(t, template.Parameters[parameterTargetGroupUnhealthyThresholdParameter])
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Equal(t, cloudformation.Ref(parameterTargetGroupHealthyThresholdParameter).Integer(), tg.HealthyThresholdCount)
+				require.Equal(t, cloudformation.Ref(parameterTargetGroupUnhealthyThresholdParameter).Integer(), tg.UnhealthyThresholdCount)
+			},
+		},
+		{
+			name: "target group uses default health check threshold counts",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.Nil(t, template.Parameters[parameterTargetGroupHealthyThresholdParameter])
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Nil(t, tg.HealthyThresholdCount)
+				require.Nil(t, tg.UnhealthyThresholdCount)
+			},
+		},
+		{
+			name: "ALB listeners have listener rules",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+				certificateARNs:  map[string]time.Time{"foo": time.Now()},
+				listenerRules: ListenerRuleList{
+					{SourceIPs: []string{"10.0.0.0/8"}, PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionForward},
+					{PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionDeny},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				for _, listener := range []string{"HTTPListener", "HTTPSListener"} {
+					for i := 0; i < 2; i++ {
+						name := fmt.Sprintf("%sRule%d", listener, i)
+						require.Contains(t, template.Resources, name)
+						rule := template.Resources[name].Properties.(*cloudformation.ElasticLoadBalancingV2ListenerRule)
+						require.Equal(t, cloudformation.Integer(listenerRulesBasePriority+int64(i)), rule.Priority)
+						require.Equal(t, cloudformation.Ref(listener).String(), rule.ListenerArn)
+					}
+				}
+			},
+		},
+		{
+			name: "ALB authenticates the requests of the HTTPS listener",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+				certificateARNs:  map[string]time.Time{"foo": time.Now()},
+				authentication: &ListenerAuthentication{
+					Type:             ListenerAuthenticationCognito,
+					UserPoolARN:      "arn:aws:cognito-idp:eu-central-1:123456789012:userpool/eu-central-1_abc",
+					UserPoolClientID: "client",
+					UserPoolDomain:   "internal",
+				},
+				listenerRules: ListenerRuleList{
+					{PathPatterns: []string{"/api/*"}, Action: ListenerRuleActionForward},
+					{PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionDeny},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				https := template.Resources["HTTPSListener"].Properties.(*cloudformation.ElasticLoadBalancingV2Listener)
+				require.Len(t, *https.DefaultActions, 2)
+				require.Equal(t, cloudformation.String("authenticate-cognito"), (*https.DefaultActions)[0].Type)
+				require.Equal(t, cloudformation.Integer(1), (*https.DefaultActions)[0].Order)
+				require.Equal(t, cloudformation.String("forward"), (*https.DefaultActions)[1].Type)
+				require.Equal(t, cloudformation.Integer(2), (*https.DefaultActions)[1].Order)
+
+				forward := template.Resources["HTTPSListenerRule0"].Properties.(*cloudformation.ElasticLoadBalancingV2ListenerRule)
+				require.Len(t, *forward.Actions, 2)
+				require.Equal(t, cloudformation.String("authenticate-cognito"), (*forward.Actions)[0].Type)
+				deny := template.Resources["HTTPSListenerRule1"].Properties.(*cloudformation.ElasticLoadBalancingV2ListenerRule)
+				require.Len(t, *deny.Actions, 1)
+
+				http := template.Resources["HTTPListener"].Properties.(*cloudformation.ElasticLoadBalancingV2Listener)
+				require.Equal(t, cloudformation.String("redirect"), (*http.DefaultActions)[0].Type)
+				require.NotContains(t, template.Resources, "HTTPListenerRule0")
+			},
+		},
+		{
+			name: "NLB has no security group by default",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeNetwork,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Nil(t, lb.SecurityGroups)
+			},
+		},
+		{
+			name: "NLB has the security group if requested",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeNetwork,
+				nlbSecurityGroup: true,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterNLBSecurityGroupParameter])
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Equal(t, cloudformation.Ref(parameterLoadBalancerSecurityGroupParameter).StringList(), lb.SecurityGroups)
+			},
+		},
+		{
+			name: "NLB listeners have no listener rules",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeNetwork,
+				certificateARNs:  map[string]time.Time{"foo": time.Now()},
+				listenerRules: ListenerRuleList{
+					{PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionDeny},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotContains(t, template.Resources, "HTTPSListenerRule0")
+			},
+		},
+		{
+			name: "ALB has client keep alive attribute",
+			spec: &stackSpec{
+				loadbalancerType:       LoadBalancerTypeApplication,
+				clientKeepAliveSeconds: 7200,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Contains(t, *lb.LoadBalancerAttributes, cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+					Key:   cloudformation.String("client_keep_alive.seconds"),
+					Value: cloudformation.String("7200"),
+				})
+			},
+		},
+		{
+			name: "NLB target group has preserve client IP attribute",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeNetwork,
+				preserveClientIP: "false",
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterTargetGroupPreserveClientIPParameter])
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Contains(t, *tg.TargetGroupAttributes, cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttribute{
+					Key:   cloudformation.String("preserve_client_ip.enabled"),
+					Value: cloudformation.Ref(parameterTargetGroupPreserveClientIPParameter).String(),
+				})
+			},
+		},
+		{
+			name: "NLB target group has PROXY protocol v2 attribute",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeNetwork,
+				proxyProtocolV2:  true,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterTargetGroupProxyProtocolV2Parameter])
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Contains(t, *tg.TargetGroupAttributes, cloudformation.ElasticLoadBalancingV2TargetGroupTargetGroupAttribute{
+					Key:   cloudformation.String("proxy_protocol_v2.enabled"),
+					Value: cloudformation.Ref(parameterTargetGroupProxyProtocolV2Parameter).String(),
+				})
+			},
+		},
+		{
+			name: "NLB has client routing policy attribute",
+			spec: &stackSpec{
+				loadbalancerType:    LoadBalancerTypeNetwork,
+				clientRoutingPolicy: ClientRoutingPolicyPartialAZAffinity,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotNil(t, template.Parameters[parameterClientRoutingPolicyParameter])
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Contains(t, *lb.LoadBalancerAttributes, cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+					Key:   cloudformation.String("dns_record.client_routing_policy"),
+					Value: cloudformation.Ref(parameterClientRoutingPolicyParameter).String(),
+				})
+			},
+		},
+		{
+			name: "ALB has preserve host header attribute",
+			spec: &stackSpec{
+				loadbalancerType:   LoadBalancerTypeApplication,
+				preserveHostHeader: true,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Contains(t, *lb.LoadBalancerAttributes, cloudformation.ElasticLoadBalancingV2LoadBalancerLoadBalancerAttribute{
+					Key:   cloudformation.String("routing.http.preserve_host_header.enabled"),
+					Value: cloudformation.String("true"),
+				})
+			},
+		},
+		{
+			name: "HTTP listener can be disabled",
+			spec: &stackSpec{
+				loadbalancerType:    LoadBalancerTypeApplication,
+				httpRedirectToHTTPS: true,
+				httpDisabled:        true,
+				certificateARNs:     map[string]time.Time{"foo": time.Now()},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotContains(t, template.Resources, "HTTPListener")
+				require.Contains(t, template.Resources, "HTTPSListener")
+			},
+		},
+		{
+			name: "ALB can be fronted by an NLB",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+				frontingNLB:      true,
+				certificateARNs:  map[string]time.Time{"foo": time.Now()},
+				dnsRecords: []*dnsRecord{
+					{hostname: "foo.example.org", hostedZoneID: "Z123"},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				lb := template.Resources["LB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Equal(t, cloudformation.String("internal"), lb.Scheme)
+
+				nlb := template.Resources["FrontingLB"].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+				require.Equal(t, cloudformation.String(LoadBalancerTypeNetwork), nlb.Type)
+				require.Equal(t, cloudformation.Ref(parameterLoadBalancerSchemeParameter).String(), nlb.Scheme)
+
+				for _, port := range []struct {
+					protocol string
+					port     int64
+				}{{"HTTP", 80}, {"HTTPS", 443}} {
+					tg := template.Resources["Fronting"+port.protocol+"TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+					require.Equal(t, cloudformation.String("alb"), tg.TargetType)
+					require.Equal(t, cloudformation.String("TCP"), tg.Protocol)
+					require.Equal(t, cloudformation.Ref("LB").String(), (*tg.Targets)[0].ID)
+					require.Equal(t, cloudformation.Integer(port.port), (*tg.Targets)[0].Port)
+
+					listener := template.Resources["Fronting"+port.protocol+"Listener"].Properties.(*cloudformation.ElasticLoadBalancingV2Listener)
+					require.Equal(t, cloudformation.Ref("FrontingLB").String(), listener.LoadBalancerArn)
+					require.Equal(t, cloudformation.Ref("Fronting"+port.protocol+"TG").String(), (*listener.DefaultActions)[0].TargetGroupArn)
+				}
+
+				require.Equal(t, map[string]interface{}{"Fn::GetAtt": []interface{}{"FrontingLB", "DNSName"}}, template.Outputs["LoadBalancerDNSName"].Value)
+				for _, resource := range template.Resources {
+					if record, ok := resource.Properties.(*cloudformation.Route53RecordSet); ok && record.AliasTarget != nil {
+						require.Equal(t, cloudformation.GetAtt("FrontingLB", "DNSName").String(), record.AliasTarget.DNSName)
+					}
+				}
+			},
+		},
+		{
+			name: "NLB in front of an ALB only forwards the enabled listeners",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+				frontingNLB:      true,
+				httpDisabled:     true,
+				certificateARNs:  map[string]time.Time{"foo": time.Now()},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotContains(t, template.Resources, "FrontingHTTPListener")
+				require.NotContains(t, template.Resources, "FrontingHTTPTG")
+				require.Contains(t, template.Resources, "FrontingHTTPSListener")
+			},
+		},
+		{
+			name: "target group registers the instances by IP address",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+				targetIPs:        true,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Equal(t, cloudformation.String("ip"), tg.TargetType)
+			},
+		},
+		{
+			name: "ALB listeners can forward to a Lambda function",
+			spec: &stackSpec{
+				name:             "stack",
+				loadbalancerType: LoadBalancerTypeApplication,
+				lambdaTarget:     "arn:aws:lambda:eu-central-1:123456789012:function:maintenance",
+				certificateARNs:  map[string]time.Time{"foo": time.Now()},
+				listenerRules: ListenerRuleList{
+					{PathPatterns: []string{"/maintenance/*"}, Action: ListenerRuleActionLambda, FunctionARN: "arn:aws:lambda:eu-central-1:123456789012:function:maintenance-rules"},
+				},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				tgName := lambdaTargetGroupName("arn:aws:lambda:eu-central-1:123456789012:function:maintenance")
+				tg := template.Resources[tgName].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Equal(t, cloudformation.String("lambda"), tg.TargetType)
+				require.Equal(t, cloudformation.String("arn:aws:lambda:eu-central-1:123456789012:function:maintenance"), (*tg.Targets)[0].ID)
+				awsName := lambdaTargetGroupAWSName("stack", "arn:aws:lambda:eu-central-1:123456789012:function:maintenance")
+				require.Equal(t, cloudformation.String(awsName), tg.Name)
+				require.Len(t, awsName, 31)
+
+				permission := template.Resources[tgName+"Permission"].Properties.(*cloudformation.LambdaPermission)
+				require.Equal(t, cloudformation.String("arn:aws:lambda:eu-central-1:123456789012:function:maintenance"), permission.FunctionName)
+				require.Equal(t, cloudformation.String("elasticloadbalancing.amazonaws.com"), permission.Principal)
+				sourceARN, err := json.Marshal(permission.SourceArn)
+				require.NoError(t, err)
+				require.Contains(t, string(sourceARN), `":targetgroup/`+awsName+`/*"`)
+
+				for _, listenerName := range []string{"HTTPListener", "HTTPSListener"} {
+					listener := template.Resources[listenerName].Properties.(*cloudformation.ElasticLoadBalancingV2Listener)
+					require.Equal(t, cloudformation.Ref(tgName).String(), (*listener.DefaultActions)[0].TargetGroupArn)
+				}
+
+				rule := template.Resources["HTTPSListenerRule0"].Properties.(*cloudformation.ElasticLoadBalancingV2ListenerRule)
+				require.Equal(t, cloudformation.Ref(lambdaTargetGroupName("arn:aws:lambda:eu-central-1:123456789012:function:maintenance-rules")).String(), (*rule.Actions)[0].TargetGroupArn)
+			},
+		},
+		{
+			name: "resource tags are set on the load balancers and target groups",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+				frontingNLB:      true,
+				lambdaTarget:     "arn:aws:lambda:eu-central-1:123456789012:function:maintenance",
+				certificateARNs:  map[string]time.Time{"foo": time.Now()},
+				resourceTags:     ResourceTags{"team": "foo", "cost-center": "1234"},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				tags := &cloudformation.TagList{
+					{Key: cloudformation.String("cost-center"), Value: cloudformation.String("1234")},
+					{Key: cloudformation.String("team"), Value: cloudformation.String("foo")},
+				}
+				lbTags := &cloudformation.TagList{
+					{Key: cloudformation.String("StackName"), Value: cloudformation.Ref("AWS::StackName").String()},
+					(*tags)[0],
+					(*tags)[1],
+				}
+
+				for _, name := range []string{"LB", "FrontingLB"} {
+					lb := template.Resources[name].Properties.(*cloudformation.ElasticLoadBalancingV2LoadBalancer)
+					require.Equal(t, lbTags, lb.Tags)
+				}
+
+				for _, name := range []string{"TG", "FrontingHTTPTG", "FrontingHTTPSTG", lambdaTargetGroupName("arn:aws:lambda:eu-central-1:123456789012:function:maintenance")} {
+					tg := template.Resources[name].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+					require.Equal(t, tags, tg.Tags)
+				}
+			},
+		},
+		{
+			name: "resource tags override the ones of the template",
+			spec: &stackSpec{
+				loadbalancerType:     LoadBalancerTypeApplication,
+				resourceTags:         ResourceTags{"team": "foo"},
+				templateResourceTags: ResourceTags{"team": "bar", "namespace": "baz"},
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Equal(t, &cloudformation.TagList{
+					{Key: cloudformation.String("namespace"), Value: cloudformation.String("baz")},
+					{Key: cloudformation.String("team"), Value: cloudformation.String("foo")},
+				}, tg.Tags)
+			},
+		},
+		{
+			name: "target groups have no tags by default",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				tg := template.Resources["TG"].Properties.(*cloudformation.ElasticLoadBalancingV2TargetGroup)
+				require.Nil(t, tg.Tags)
+			},
+		},
+		{
+			name: "ALB has no fronting NLB by default",
+			spec: &stackSpec{
+				loadbalancerType: LoadBalancerTypeApplication,
+			},
+			validate: func(t *testing.T, template *cloudformation.Template) {
+				require.NotContains(t, template.Resources, "FrontingLB")
+				require.Equal(t, map[string]interface{}{"Fn::GetAtt": []interface{}{"LB", "DNSName"}}, template.Outputs["LoadBalancerDNSName"].Value)
+			},
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			generated, err := generateTemplate(test.spec)
@@ -418,3 +1053,53 @@ func TestGenerateTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateTemplateCapacityReservation(t *testing.T) {
+	capacity := func(spec *stackSpec) interface{} {
+		generated, err := generateTemplate(spec)
+		require.NoError(t, err)
+
+		var template struct {
+			Parameters map[string]interface{}
+			Resources  map[string]struct {
+				Properties map[string]interface{}
+			}
+		}
+		require.NoError(t, json.Unmarshal([]byte(generated), &template))
+		if spec.hasCapacityReservation() {
+			require.Contains(t, template.Parameters, parameterCapacityUnitsParameter)
+			require.Contains(t, template.Resources["LB"].Properties, "LoadBalancerAttributes")
+		} else {
+			require.NotContains(t, template.Parameters, parameterCapacityUnitsParameter)
+		}
+		return template.Resources["LB"].Properties["MinimumLoadBalancerCapacity"]
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"CapacityUnits": map[string]interface{}{"Ref": parameterCapacityUnitsParameter},
+	}, capacity(&stackSpec{loadbalancerType: LoadBalancerTypeApplication, capacityUnits: 200}))
+	assert.Nil(t, capacity(&stackSpec{loadbalancerType: LoadBalancerTypeApplication}))
+	assert.Nil(t, capacity(&stackSpec{loadbalancerType: LoadBalancerTypeNetwork, capacityUnits: 200}))
+}
+
+func TestGenerateTemplateDefaultCertificate(t *testing.T) {
+	defaultCertificate := func(spec *stackSpec) interface{} {
+		generated, err := generateTemplate(spec)
+		require.NoError(t, err)
+
+		var template struct {
+			Resources map[string]struct {
+				Properties struct {
+					Certificates []map[string]interface{}
+				}
+			}
+		}
+		require.NoError(t, json.Unmarshal([]byte(generated), &template))
+		return template.Resources["HTTPSListener"].Properties.Certificates[0]["CertificateArn"]
+	}
+
+	certificateARNs := map[string]time.Time{"arn:cert-a": {}, "arn:cert-b": {}}
+	assert.Equal(t, "arn:cert-a", defaultCertificate(&stackSpec{certificateARNs: certificateARNs}))
+	assert.Equal(t, "arn:cert-b", defaultCertificate(&stackSpec{certificateARNs: certificateARNs, defaultCertificateARN: "arn:cert-b"}))
+	assert.Equal(t, "arn:cert-a", defaultCertificate(&stackSpec{certificateARNs: certificateARNs, defaultCertificateARN: "arn:cert-c"}))
+}
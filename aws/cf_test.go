@@ -1,11 +1,14 @@
 package aws
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 )
 
@@ -61,7 +64,7 @@ func TestCreatingStack(t *testing.T) {
 	} {
 		t.Run(ti.name, func(t *testing.T) {
 			c := &mockCloudFormationClient{outputs: ti.givenOutputs}
-			got, err := createStack(c, &ti.givenSpec)
+			got, err := createStack(c, &ti.givenSpec, nil)
 			if ti.wantErr {
 				if !ti.wantErr {
 					t.Error("unexpected error", err)
@@ -127,7 +130,7 @@ func TestUpdatingStack(t *testing.T) {
 	} {
 		t.Run(ti.name, func(t *testing.T) {
 			c := &mockCloudFormationClient{outputs: ti.givenOutputs}
-			got, err := updateStack(c, &ti.givenSpec)
+			got, err := updateStack(c, &ti.givenSpec, nil)
 			if ti.wantErr {
 				if !ti.wantErr {
 					t.Error("unexpected error", err)
@@ -141,6 +144,62 @@ func TestUpdatingStack(t *testing.T) {
 	}
 }
 
+func TestUpdatingStackTags(t *testing.T) {
+	expiry := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	spec := &stackSpec{
+		name:            "foo",
+		securityGroupID: "bar",
+		vpcID:           "baz",
+		certificateARNs: map[string]time.Time{
+			"arn-default": {},
+			"arn-old":     expiry,
+		},
+		namespacesTag: "default",
+		dnsRecords: []*dnsRecord{
+			{hostname: "foo.example.org", hostedZoneID: "zone-1"},
+			{hostname: "bar.example.org", hostedZoneID: "zone-1"},
+		},
+	}
+
+	c := &mockCloudFormationClient{outputs: cfMockOutputs{updateStack: R(mockUSOutput("fake-stack-id"), nil)}}
+	got, err := updateStackTags(c, spec)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got != "fake-stack-id" {
+		t.Errorf("unexpected result. wanted fake-stack-id, got %s", got)
+	}
+
+	in := c.updateStackInput
+	if aws.StringValue(in.StackName) != "foo" || !aws.BoolValue(in.UsePreviousTemplate) || in.TemplateBody != nil {
+		t.Errorf("expected update of stack foo with previous template, got %v", in)
+	}
+	if len(in.Parameters) == 0 {
+		t.Error("expected parameters to keep their previous values")
+	}
+	for _, param := range in.Parameters {
+		if !aws.BoolValue(param.UsePreviousValue) || param.ParameterValue != nil {
+			t.Errorf("expected previous value of parameter %s, got %v", aws.StringValue(param.ParameterKey), param)
+		}
+	}
+
+	tags := convertCloudFormationTags(in.Tags)
+	if tags[certificateARNTagPrefix+"arn-old"] != expiry.Format(time.RFC3339) {
+		t.Errorf("unexpected certificate TTL tag %q", tags[certificateARNTagPrefix+"arn-old"])
+	}
+	if tags[namespacesTag] != "default" {
+		t.Errorf("unexpected namespaces tag %q", tags[namespacesTag])
+	}
+	if hostnames := splitTagValues(tags[dnsHostnamesTag]); !reflect.DeepEqual(hostnames, []string{"bar.example.org", "foo.example.org"}) {
+		t.Errorf("unexpected DNS hostnames tag %q", tags[dnsHostnamesTag])
+	}
+
+	c = &mockCloudFormationClient{outputs: cfMockOutputs{updateStack: R(nil, errDummy)}}
+	if _, err := updateStackTags(c, spec); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestDeleteStack(t *testing.T) {
 	for _, ti := range []struct {
 		msg          string
@@ -208,6 +267,83 @@ func TestIsComplete(t *testing.T) {
 
 }
 
+func TestIsStuck(t *testing.T) {
+	timeout := 10 * time.Minute
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, ti := range []struct {
+		name  string
+		given *Stack
+		want  bool
+	}{
+		{"nil stack", nil, false},
+		{"delete failed", &Stack{status: cloudformation.StackStatusDeleteFailed}, true},
+		{"creating", &Stack{status: cloudformation.StackStatusCreateInProgress, creationTime: now}, false},
+		{"creating beyond timeout", &Stack{status: cloudformation.StackStatusCreateInProgress, creationTime: now.Add(-time.Hour)}, true},
+		{"in review beyond timeout", &Stack{status: cloudformation.StackStatusReviewInProgress, creationTime: now.Add(-time.Hour)}, true},
+		{"updating", &Stack{status: cloudformation.StackStatusUpdateInProgress, creationTime: now.Add(-time.Hour)}, false},
+		{"complete", &Stack{status: cloudformation.StackStatusCreateComplete, creationTime: now.Add(-time.Hour)}, false},
+	} {
+		t.Run(ti.name, func(t *testing.T) {
+			got := ti.given.IsStuck(timeout, now)
+			if ti.want != got {
+				t.Errorf("unexpected result. wanted %+v, got %+v", ti.want, got)
+			}
+		})
+	}
+}
+
+func TestFindManagedStacksQuotaExceeded(t *testing.T) {
+	svc := &mockCloudFormationClient{outputs: cfMockOutputs{
+		describeStackPages: R(nil, nil),
+		describeStacks: R(&cloudformation.DescribeStacksOutput{
+			Stacks: []*cloudformation.Stack{
+				{
+					StackName:   aws.String("rolled-back"),
+					StackStatus: aws.String(cloudformation.StackStatusRollbackComplete),
+					Tags: []*cloudformation.Tag{
+						cfTag(kubernetesCreatorTag, DefaultControllerID),
+						cfTag(clusterIDTagPrefix+"test-cluster", resourceLifecycleOwned),
+					},
+				},
+			},
+		}, nil),
+		describeStackEvents: R(&cloudformation.DescribeStackEventsOutput{
+			StackEvents: []*cloudformation.StackEvent{
+				{ResourceStatus: aws.String(cloudformation.ResourceStatusCreateFailed), ResourceStatusReason: aws.String("Resource creation cancelled")},
+				{ResourceStatus: aws.String(cloudformation.ResourceStatusCreateFailed), ResourceStatusReason: aws.String("You've reached the limit on the number of load balancers (Service: ElasticLoadBalancingV2; Error Code: TooManyLoadBalancers)")},
+			},
+		}, nil),
+	}}
+
+	stacks, err := findManagedStacks(svc, "test-cluster", DefaultControllerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stacks) != 1 || !stacks[0].IsQuotaExceeded() {
+		t.Errorf("expected a stack with exceeded quota, got %+v", stacks)
+	}
+}
+
+func TestIsQuotaExceededError(t *testing.T) {
+	if !IsQuotaExceededError(awserr.New(cloudformation.ErrCodeLimitExceededException, "limit exceeded", nil)) {
+		t.Error("expected a quota error")
+	}
+	if IsQuotaExceededError(awserr.New(cloudformation.ErrCodeAlreadyExistsException, "already exists", nil)) {
+		t.Error("unexpected quota error")
+	}
+	if IsQuotaExceededError(nil) {
+		t.Error("unexpected quota error")
+	}
+}
+
+func TestStackErr(t *testing.T) {
+	stack := &Stack{Name: "foo", status: cloudformation.StackStatusDeleteFailed, statusReason: "resource in use"}
+	want := `stack "foo" is in status DELETE_FAILED: resource in use`
+	if err := stack.Err(); err == nil || err.Error() != want {
+		t.Errorf("unexpected error. wanted %q, got %v", want, err)
+	}
+}
+
 func TestManagementAssertion(t *testing.T) {
 	for _, ti := range []struct {
 		name  string
@@ -593,6 +729,7 @@ func TestGetStack(t *testing.T) {
 }
 
 func TestShouldDelete(t *testing.T) {
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
 	for _, ti := range []struct {
 		msg   string
 		given *Stack
@@ -600,22 +737,22 @@ func TestShouldDelete(t *testing.T) {
 	}{
 		{
 			"DeleteInProgress",
-			&Stack{CertificateARNs: map[string]time.Time{"test-arn": time.Now().UTC().Add(1 * time.Minute)}},
+			&Stack{CertificateARNs: map[string]time.Time{"test-arn": now.Add(1 * time.Minute)}},
 			false,
 		},
 		{
 			"DeleteInProgressSecond",
-			&Stack{CertificateARNs: map[string]time.Time{"test-arn": time.Now().UTC().Add(1 * time.Second)}},
+			&Stack{CertificateARNs: map[string]time.Time{"test-arn": now.Add(1 * time.Second)}},
 			false,
 		},
 		{
 			"ShouldDelete",
-			&Stack{CertificateARNs: map[string]time.Time{"test-arn": time.Now().UTC().Add(-1 * time.Second)}},
+			&Stack{CertificateARNs: map[string]time.Time{"test-arn": now.Add(-1 * time.Second)}},
 			true,
 		},
 		{
 			"ShouldDeleteMinute",
-			&Stack{CertificateARNs: map[string]time.Time{"test-arn": time.Now().UTC().Add(-1 * time.Minute)}},
+			&Stack{CertificateARNs: map[string]time.Time{"test-arn": now.Add(-1 * time.Minute)}},
 			true,
 		},
 		{
@@ -630,7 +767,7 @@ func TestShouldDelete(t *testing.T) {
 		},
 	} {
 		t.Run(ti.msg, func(t *testing.T) {
-			got := ti.given.ShouldDelete()
+			got := ti.given.ShouldDelete(now)
 			if ti.want != got {
 				t.Errorf("unexpected result for %s. wanted %+v, got %+v", ti.msg, ti.want, got)
 			}
@@ -638,3 +775,115 @@ func TestShouldDelete(t *testing.T) {
 	}
 
 }
+
+func TestIsValidHealthCheckMatcher(t *testing.T) {
+	for _, ti := range []struct {
+		given string
+		want  bool
+	}{
+		{"200", true},
+		{"200,302", true},
+		{"200-399", true},
+		{"200-299,401", true},
+		{"", false},
+		{"100", false},
+		{"500", false},
+		{"399-200", false},
+		{"200,", false},
+		{"2000", false},
+		{"ok", false},
+	} {
+		t.Run(ti.given, func(t *testing.T) {
+			got := IsValidHealthCheckMatcher(ti.given)
+			if ti.want != got {
+				t.Errorf("unexpected result for %q. wanted %+v, got %+v", ti.given, ti.want, got)
+			}
+		})
+	}
+}
+
+func TestIsValidDenyResponse(t *testing.T) {
+	for _, ti := range []struct {
+		given string
+		want  bool
+	}{
+		{"text/plain", true},
+		{"text/html", true},
+		{"application/json", true},
+		{"", false},
+		{"image/png", false},
+	} {
+		t.Run(ti.given, func(t *testing.T) {
+			if got := IsValidDenyResponseContentType(ti.given); ti.want != got {
+				t.Errorf("unexpected result for %q. wanted %+v, got %+v", ti.given, ti.want, got)
+			}
+		})
+	}
+
+	for _, ti := range []struct {
+		given int
+		want  bool
+	}{
+		{200, true},
+		{403, true},
+		{503, true},
+		{100, false},
+		{302, false},
+		{600, false},
+	} {
+		t.Run(fmt.Sprintf("%d", ti.given), func(t *testing.T) {
+			if got := IsValidDenyResponseStatusCode(ti.given); ti.want != got {
+				t.Errorf("unexpected result for %d. wanted %+v, got %+v", ti.given, ti.want, got)
+			}
+		})
+	}
+}
+
+func TestNamespacesTagValue(t *testing.T) {
+	if got := NamespacesTagValue(nil); got != "" {
+		t.Errorf("unexpected value for no namespaces: %q", got)
+	}
+
+	if got := NamespacesTagValue([]string{"team-b", "team-a"}); got != "team-a team-b" {
+		t.Errorf("unexpected value: %q", got)
+	}
+
+	namespaces := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		namespaces = append(namespaces, strings.Repeat(string(rune('a'+i)), 63))
+	}
+	got := NamespacesTagValue(namespaces)
+	if len(got) > maxTagValueLength || !strings.HasPrefix(got, "sha256:") {
+		t.Errorf("unexpected value for long namespace list: %q", got)
+	}
+	if values := splitTagValues(got); values != nil {
+		t.Errorf("unexpected values of a hashed list: %v", values)
+	}
+}
+
+func TestListenerTargetGroupARNs(t *testing.T) {
+	var stack *Stack
+	if got := stack.ListenerTargetGroupARNs(); got != nil {
+		t.Errorf("unexpected target groups of a nil stack: %v", got)
+	}
+
+	stack = &Stack{
+		LoadBalancerType:        LoadBalancerTypeApplication,
+		TargetGroupARN:          "arn:tg",
+		ExternalTargetGroupARNs: []string{"arn:external-tg"},
+	}
+	if got, want := stack.ListenerTargetGroupARNs(), []string{"arn:tg", "arn:external-tg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected target groups, wanted %v, got %v", want, got)
+	}
+
+	stack.LambdaTarget = "arn:function"
+	if got := stack.ListenerTargetGroupARNs(); got != nil {
+		t.Errorf("unexpected target groups with a Lambda target: %v", got)
+	}
+
+	stack.LambdaTarget = ""
+	stack.LoadBalancerType = LoadBalancerTypeNetwork
+	if got, want := stack.ListenerTargetGroupARNs(), []string{"arn:tg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected target groups of a network load balancer, wanted %v, got %v", want, got)
+	}
+}
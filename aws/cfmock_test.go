@@ -13,11 +13,14 @@ type cfMockOutputs struct {
 	updateStack                 *apiResponse
 	deleteStack                 *apiResponse
 	updateTerminationProtection *apiResponse
+	describeStackEvents         *apiResponse
+	describeStackResources      *apiResponse
 }
 
 type mockCloudFormationClient struct {
 	cloudformationiface.CloudFormationAPI
-	outputs cfMockOutputs
+	outputs          cfMockOutputs
+	updateStackInput *cloudformation.UpdateStackInput
 }
 
 func (m *mockCloudFormationClient) DescribeStacksPages(in *cloudformation.DescribeStacksInput, fn func(*cloudformation.DescribeStacksOutput, bool) bool) (err error) {
@@ -45,6 +48,23 @@ func (m *mockCloudFormationClient) DescribeStacks(in *cloudformation.DescribeSta
 	return nil, m.outputs.describeStacks.err
 }
 
+func (m *mockCloudFormationClient) DescribeStackEventsPages(in *cloudformation.DescribeStackEventsInput, fn func(*cloudformation.DescribeStackEventsOutput, bool) bool) error {
+	if m.outputs.describeStackEvents == nil {
+		return nil
+	}
+	if out, ok := m.outputs.describeStackEvents.response.(*cloudformation.DescribeStackEventsOutput); ok {
+		fn(out, true)
+	}
+	return m.outputs.describeStackEvents.err
+}
+
+func (m *mockCloudFormationClient) DescribeStackResources(in *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	if out, ok := m.outputs.describeStackResources.response.(*cloudformation.DescribeStackResourcesOutput); ok {
+		return out, m.outputs.describeStackResources.err
+	}
+	return nil, m.outputs.describeStackResources.err
+}
+
 func (m *mockCloudFormationClient) CreateStack(params *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
 	if out, ok := m.outputs.createStack.response.(*cloudformation.CreateStackOutput); ok {
 		return out, m.outputs.createStack.err
@@ -59,6 +79,7 @@ func mockCSOutput(stackId string) *cloudformation.CreateStackOutput {
 }
 
 func (m *mockCloudFormationClient) UpdateStack(params *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	m.updateStackInput = params
 	if out, ok := m.outputs.updateStack.response.(*cloudformation.UpdateStackOutput); ok {
 		return out, m.outputs.updateStack.err
 	}
@@ -0,0 +1,291 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ChangeSetsDisabled updates stacks directly.
+	ChangeSetsDisabled = "disabled"
+	// ChangeSetsNonDestructive updates stacks with change sets, which are
+	// only executed if they don't replace or remove any resource.
+	ChangeSetsNonDestructive = "non-destructive"
+	// ChangeSetsAlways updates stacks with change sets, which are always
+	// executed.
+	ChangeSetsAlways = "always"
+
+	changeSetNamePrefix       = "kube-ingress-aws-controller-"
+	changeSetNoChangesMessage = "didn't contain changes"
+
+	listenerCertificateResourceType = "AWS::ElasticLoadBalancingV2::ListenerCertificate"
+)
+
+// DestructiveChangeSetError is returned when the change set of a stack
+// update replaces or removes resources and isn't executed. The change set is
+// kept, so it can be reviewed and executed manually.
+type DestructiveChangeSetError struct {
+	StackName     string
+	ChangeSetName string
+	// Changes are the destructive changes of the change set.
+	Changes []string
+}
+
+func (e *DestructiveChangeSetError) Error() string {
+	return fmt.Sprintf("change set %s of stack %s isn't executed because of destructive changes: %s",
+		e.ChangeSetName, e.StackName, strings.Join(e.Changes, ", "))
+}
+
+// ChangeSetPendingError is returned when the change set of a stack update
+// is still being created by CloudFormation. The update continues with the
+// same change set when it's repeated, e.g. in the next reconciliation.
+type ChangeSetPendingError struct {
+	StackName     string
+	ChangeSetName string
+}
+
+func (e *ChangeSetPendingError) Error() string {
+	return fmt.Sprintf("change set %s of stack %s is still being created", e.ChangeSetName, e.StackName)
+}
+
+// WithChangeSets returns the receiver adapter after setting how stacks are
+// updated, one of ChangeSetsDisabled, ChangeSetsNonDestructive or
+// ChangeSetsAlways.
+func (a *Adapter) WithChangeSets(mode string) *Adapter {
+	a.changeSets = mode
+	return a
+}
+
+// updateStackWithChangeSet updates the stack by creating a change set, which
+// is executed unless it's destructive and the mode is
+// ChangeSetsNonDestructive. The planned changes are logged. A change set
+// without changes fails like a stack update without changes.
+//
+// It doesn't wait for CloudFormation to create the change set, which takes
+// a few seconds. A ChangeSetPendingError is returned instead, and the update
+// continues with the change set when it's repeated.
+func updateStackWithChangeSet(svc cloudformationiface.CloudFormationAPI, spec *stackSpec, upload templateUploader, mode string) (string, error) {
+	params, err := prepareStackUpdate(svc, spec, upload)
+	if err != nil {
+		return spec.name, err
+	}
+	name := changeSetName(params)
+
+	// a change set kept because of destructive changes is reused until the
+	// update changes
+	changeSet, err := describeChangeSet(svc, spec.name, name)
+	if err != nil {
+		return spec.name, err
+	}
+	if changeSet == nil {
+		if err := deleteChangeSets(svc, spec.name); err != nil {
+			return spec.name, err
+		}
+		changeSet, err = createChangeSet(svc, params, name)
+		if err != nil {
+			return spec.name, err
+		}
+	}
+
+	switch aws.StringValue(changeSet.Status) {
+	case cloudformation.ChangeSetStatusCreateComplete:
+	case cloudformation.ChangeSetStatusCreatePending, cloudformation.ChangeSetStatusCreateInProgress:
+		return spec.name, &ChangeSetPendingError{StackName: spec.name, ChangeSetName: name}
+	case cloudformation.ChangeSetStatusFailed:
+		reason := aws.StringValue(changeSet.StatusReason)
+		deleteChangeSet(svc, spec.name, name)
+		if strings.Contains(reason, changeSetNoChangesMessage) {
+			// the same error as a stack update without changes
+			return spec.name, awserr.New("ValidationError", "No updates are to be performed.", nil)
+		}
+		return spec.name, fmt.Errorf("change set %s of stack %s failed: %s", name, spec.name, reason)
+	default:
+		return spec.name, fmt.Errorf("change set %s of stack %s isn't complete: %s", name, spec.name, aws.StringValue(changeSet.Status))
+	}
+
+	// the destructive changes of a change set which isn't executed are
+	// reported by the error in every reconciliation, the other changes
+	// only when debugging
+	destructive := destructiveChanges(changeSet.Changes)
+	if len(destructive) > 0 && mode != ChangeSetsAlways {
+		for _, change := range changeSet.Changes {
+			log.Debugf("stack %s change set %s: %s", spec.name, name, describeChange(change))
+		}
+		return spec.name, &DestructiveChangeSetError{
+			StackName:     spec.name,
+			ChangeSetName: name,
+			Changes:       destructive,
+		}
+	}
+
+	for _, change := range changeSet.Changes {
+		log.Infof("stack %s change set %s: %s", spec.name, name, describeChange(change))
+	}
+
+	_, err = svc.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{
+		StackName:     aws.String(spec.name),
+		ChangeSetName: aws.String(name),
+	})
+	if err != nil {
+		return spec.name, fmt.Errorf("failed to execute change set %s of stack %s: %v", name, spec.name, err)
+	}
+
+	return aws.StringValue(changeSet.StackId), nil
+}
+
+// changeSetName returns the name of the change set of the update, derived
+// from the hash of the update so that the same update gets the same name.
+func changeSetName(params *cloudformation.UpdateStackInput) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", aws.StringValue(params.TemplateBody), aws.StringValue(params.TemplateURL))
+
+	parameters := make([]string, 0, len(params.Parameters))
+	for _, p := range params.Parameters {
+		parameters = append(parameters, aws.StringValue(p.ParameterKey)+"="+aws.StringValue(p.ParameterValue))
+	}
+	sort.Strings(parameters)
+
+	tags := make([]string, 0, len(params.Tags))
+	for _, t := range params.Tags {
+		tags = append(tags, aws.StringValue(t.Key)+"="+aws.StringValue(t.Value))
+	}
+	sort.Strings(tags)
+
+	fmt.Fprintf(h, "%s\n%s\n", strings.Join(parameters, "\n"), strings.Join(tags, "\n"))
+	return fmt.Sprintf("%s%x", changeSetNamePrefix, h.Sum(nil)[:8])
+}
+
+func createChangeSet(svc cloudformationiface.CloudFormationAPI, params *cloudformation.UpdateStackInput, name string) (*cloudformation.DescribeChangeSetOutput, error) {
+	stackName := aws.StringValue(params.StackName)
+	_, err := svc.CreateChangeSet(&cloudformation.CreateChangeSetInput{
+		StackName:     params.StackName,
+		ChangeSetName: aws.String(name),
+		ChangeSetType: aws.String(cloudformation.ChangeSetTypeUpdate),
+		Parameters:    params.Parameters,
+		Tags:          params.Tags,
+		TemplateBody:  params.TemplateBody,
+		TemplateURL:   params.TemplateURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create change set %s of stack %s: %v", name, stackName, err)
+	}
+
+	changeSet, err := describeChangeSet(svc, stackName, name)
+	if err != nil {
+		return nil, err
+	}
+	if changeSet == nil {
+		return nil, fmt.Errorf("change set %s of stack %s not found", name, stackName)
+	}
+	return changeSet, nil
+}
+
+// describeChangeSet returns the change set with all of its changes, or nil
+// if it doesn't exist.
+func describeChangeSet(svc cloudformationiface.CloudFormationAPI, stackName, name string) (*cloudformation.DescribeChangeSetOutput, error) {
+	input := &cloudformation.DescribeChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(name),
+	}
+
+	var changeSet *cloudformation.DescribeChangeSetOutput
+	for {
+		page, err := svc.DescribeChangeSet(input)
+		if err != nil {
+			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == cloudformation.ErrCodeChangeSetNotFoundException {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to describe change set %s of stack %s: %v", name, stackName, err)
+		}
+		if changeSet == nil {
+			changeSet = page
+		} else {
+			changeSet.Changes = append(changeSet.Changes, page.Changes...)
+		}
+		if page.NextToken == nil {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+	return changeSet, nil
+}
+
+// deleteChangeSets deletes the change sets of the controller which were kept
+// for previous updates of the stack.
+func deleteChangeSets(svc cloudformationiface.CloudFormationAPI, stackName string) error {
+	input := &cloudformation.ListChangeSetsInput{
+		StackName: aws.String(stackName),
+	}
+	var names []string
+	for {
+		page, err := svc.ListChangeSets(input)
+		if err != nil {
+			return fmt.Errorf("failed to list the change sets of stack %s: %v", stackName, err)
+		}
+		for _, summary := range page.Summaries {
+			if name := aws.StringValue(summary.ChangeSetName); strings.HasPrefix(name, changeSetNamePrefix) {
+				names = append(names, name)
+			}
+		}
+		if page.NextToken == nil {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	for _, name := range names {
+		deleteChangeSet(svc, stackName, name)
+	}
+	return nil
+}
+
+func deleteChangeSet(svc cloudformationiface.CloudFormationAPI, stackName, name string) {
+	_, err := svc.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(name),
+	})
+	if err != nil {
+		log.Warnf("failed to delete change set %s of stack %s: %v", name, stackName, err)
+	}
+}
+
+// destructiveChanges returns the descriptions of the changes which replace
+// or remove resources. A conditional replacement is considered destructive.
+// The listener certificates are exempt, their resource is renamed whenever
+// the certificates change, which removes the previous one.
+func destructiveChanges(changes []*cloudformation.Change) []string {
+	var destructive []string
+	for _, change := range changes {
+		rc := change.ResourceChange
+		if rc == nil || aws.StringValue(rc.ResourceType) == listenerCertificateResourceType {
+			continue
+		}
+		switch {
+		case aws.StringValue(rc.Action) == cloudformation.ChangeActionRemove,
+			aws.StringValue(rc.Replacement) == cloudformation.ReplacementTrue,
+			aws.StringValue(rc.Replacement) == cloudformation.ReplacementConditional:
+			destructive = append(destructive, describeChange(change))
+		}
+	}
+	return destructive
+}
+
+func describeChange(change *cloudformation.Change) string {
+	rc := change.ResourceChange
+	if rc == nil {
+		return aws.StringValue(change.Type)
+	}
+	description := fmt.Sprintf("%s %s %s", aws.StringValue(rc.Action), aws.StringValue(rc.ResourceType), aws.StringValue(rc.LogicalResourceId))
+	if rc.Replacement != nil {
+		description += fmt.Sprintf(" (replacement: %s)", aws.StringValue(rc.Replacement))
+	}
+	return description
+}
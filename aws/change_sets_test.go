@@ -0,0 +1,248 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type changeSetClient struct {
+	cloudformationiface.CloudFormationAPI
+	// changeSets are the existing change sets by name.
+	changeSets map[string]*cloudformation.DescribeChangeSetOutput
+	// created is the change set returned for the created one.
+	created  *cloudformation.DescribeChangeSetOutput
+	creates  []string
+	executes []string
+	deletes  []string
+}
+
+func (c *changeSetClient) DescribeChangeSet(in *cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error) {
+	if cs, ok := c.changeSets[aws.StringValue(in.ChangeSetName)]; ok {
+		return cs, nil
+	}
+	return nil, awserr.New(cloudformation.ErrCodeChangeSetNotFoundException, "not found", nil)
+}
+
+func (c *changeSetClient) CreateChangeSet(in *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error) {
+	name := aws.StringValue(in.ChangeSetName)
+	c.creates = append(c.creates, name)
+	c.changeSets[name] = c.created
+	return &cloudformation.CreateChangeSetOutput{}, nil
+}
+
+func (c *changeSetClient) ExecuteChangeSet(in *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error) {
+	c.executes = append(c.executes, aws.StringValue(in.ChangeSetName))
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+func (c *changeSetClient) ListChangeSets(in *cloudformation.ListChangeSetsInput) (*cloudformation.ListChangeSetsOutput, error) {
+	out := &cloudformation.ListChangeSetsOutput{}
+	for name := range c.changeSets {
+		out.Summaries = append(out.Summaries, &cloudformation.ChangeSetSummary{ChangeSetName: aws.String(name)})
+	}
+	out.Summaries = append(out.Summaries, &cloudformation.ChangeSetSummary{ChangeSetName: aws.String("manual")})
+	return out, nil
+}
+
+func (c *changeSetClient) DeleteChangeSet(in *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error) {
+	c.deletes = append(c.deletes, aws.StringValue(in.ChangeSetName))
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
+
+func resourceChange(action, logicalID, replacement string) *cloudformation.Change {
+	rc := &cloudformation.ResourceChange{
+		Action:            aws.String(action),
+		ResourceType:      aws.String("AWS::ElasticLoadBalancingV2::TargetGroup"),
+		LogicalResourceId: aws.String(logicalID),
+	}
+	if replacement != "" {
+		rc.Replacement = aws.String(replacement)
+	}
+	return &cloudformation.Change{Type: aws.String(cloudformation.ChangeTypeResource), ResourceChange: rc}
+}
+
+func changeSet(status, reason string, changes ...*cloudformation.Change) *cloudformation.DescribeChangeSetOutput {
+	return &cloudformation.DescribeChangeSetOutput{
+		StackId:      aws.String("stack-id"),
+		Status:       aws.String(status),
+		StatusReason: aws.String(reason),
+		Changes:      changes,
+	}
+}
+
+func TestUpdateStackWithChangeSet(t *testing.T) {
+	spec := &stackSpec{name: "stack", securityGroupID: "sg", vpcID: "vpc"}
+	params, err := prepareStackUpdate(&changeSetClient{}, spec, nil)
+	require.NoError(t, err)
+	name := changeSetName(params)
+
+	modify := resourceChange(cloudformation.ChangeActionModify, "TG", cloudformation.ReplacementFalse)
+	replace := resourceChange(cloudformation.ChangeActionModify, "TG", cloudformation.ReplacementTrue)
+	remove := resourceChange(cloudformation.ChangeActionRemove, "Listener", "")
+
+	for _, ti := range []struct {
+		name         string
+		mode         string
+		existing     *cloudformation.DescribeChangeSetOutput
+		created      *cloudformation.DescribeChangeSetOutput
+		wantCreated  bool
+		wantExecuted bool
+		wantDeleted  []string
+		wantErr      func(error) bool
+	}{
+		{
+			name:         "non-destructive change set executed",
+			mode:         ChangeSetsNonDestructive,
+			created:      changeSet(cloudformation.ChangeSetStatusCreateComplete, "", modify),
+			wantCreated:  true,
+			wantExecuted: true,
+		},
+		{
+			name:        "destructive change set kept",
+			mode:        ChangeSetsNonDestructive,
+			created:     changeSet(cloudformation.ChangeSetStatusCreateComplete, "", modify, replace, remove),
+			wantCreated: true,
+			wantErr: func(err error) bool {
+				var destructive *DestructiveChangeSetError
+				return errors.As(err, &destructive) &&
+					assert.Equal(t, []string{
+						"Modify AWS::ElasticLoadBalancingV2::TargetGroup TG (replacement: True)",
+						"Remove AWS::ElasticLoadBalancingV2::TargetGroup Listener",
+					}, destructive.Changes)
+			},
+		},
+		{
+			name:     "kept change set reused",
+			mode:     ChangeSetsNonDestructive,
+			existing: changeSet(cloudformation.ChangeSetStatusCreateComplete, "", replace),
+			wantErr: func(err error) bool {
+				var destructive *DestructiveChangeSetError
+				return errors.As(err, &destructive)
+			},
+		},
+		{
+			name:        "pending change set",
+			mode:        ChangeSetsNonDestructive,
+			created:     changeSet(cloudformation.ChangeSetStatusCreatePending, ""),
+			wantCreated: true,
+			wantErr: func(err error) bool {
+				var pending *ChangeSetPendingError
+				return errors.As(err, &pending)
+			},
+		},
+		{
+			name:         "created change set executed",
+			mode:         ChangeSetsNonDestructive,
+			existing:     changeSet(cloudformation.ChangeSetStatusCreateComplete, "", modify),
+			wantExecuted: true,
+		},
+		{
+			name:         "destructive change set executed always",
+			mode:         ChangeSetsAlways,
+			created:      changeSet(cloudformation.ChangeSetStatusCreateComplete, "", replace),
+			wantCreated:  true,
+			wantExecuted: true,
+		},
+		{
+			name:        "no changes",
+			mode:        ChangeSetsNonDestructive,
+			created:     changeSet(cloudformation.ChangeSetStatusFailed, "The submitted information didn't contain changes. Submit different information to create a change set."),
+			wantCreated: true,
+			wantDeleted: []string{name},
+			wantErr: func(err error) bool {
+				return assert.Contains(t, err.Error(), "No updates are to be performed")
+			},
+		},
+		{
+			name:        "failed change set",
+			mode:        ChangeSetsNonDestructive,
+			created:     changeSet(cloudformation.ChangeSetStatusFailed, "invalid template"),
+			wantCreated: true,
+			wantDeleted: []string{name},
+			wantErr: func(err error) bool {
+				return assert.Contains(t, err.Error(), "invalid template")
+			},
+		},
+	} {
+		t.Run(ti.name, func(t *testing.T) {
+			c := &changeSetClient{
+				changeSets: map[string]*cloudformation.DescribeChangeSetOutput{
+					changeSetNamePrefix + "previous": changeSet(cloudformation.ChangeSetStatusCreateComplete, ""),
+				},
+				created: ti.created,
+			}
+			if ti.existing != nil {
+				c.changeSets[name] = ti.existing
+			}
+
+			id, err := updateStackWithChangeSet(c, spec, nil, ti.mode)
+			if ti.wantErr != nil {
+				require.Error(t, err)
+				assert.True(t, ti.wantErr(err), "unexpected error: %v", err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "stack-id", id)
+			}
+
+			if ti.wantCreated {
+				assert.Equal(t, []string{name}, c.creates)
+				// the previous change set of the controller is deleted,
+				// others are kept
+				assert.Equal(t, append([]string{changeSetNamePrefix + "previous"}, ti.wantDeleted...), c.deletes)
+			} else {
+				assert.Empty(t, c.creates)
+				assert.Empty(t, c.deletes)
+			}
+			if ti.wantExecuted {
+				assert.Equal(t, []string{name}, c.executes)
+			} else {
+				assert.Empty(t, c.executes)
+			}
+		})
+	}
+}
+
+func TestDestructiveChanges(t *testing.T) {
+	certificate := func(action, logicalID string) *cloudformation.Change {
+		change := resourceChange(action, logicalID, "")
+		change.ResourceChange.ResourceType = aws.String(listenerCertificateResourceType)
+		return change
+	}
+
+	assert.Empty(t, destructiveChanges([]*cloudformation.Change{
+		certificate(cloudformation.ChangeActionRemove, "HTTPSListenerCertificate1a2b"),
+		certificate(cloudformation.ChangeActionAdd, "HTTPSListenerCertificate3c4d"),
+	}))
+	assert.Equal(t, []string{"Remove AWS::ElasticLoadBalancingV2::TargetGroup TG"}, destructiveChanges([]*cloudformation.Change{
+		certificate(cloudformation.ChangeActionRemove, "HTTPSListenerCertificate1a2b"),
+		resourceChange(cloudformation.ChangeActionRemove, "TG", ""),
+	}))
+}
+
+func TestChangeSetName(t *testing.T) {
+	params := &cloudformation.UpdateStackInput{
+		TemplateBody: aws.String("{}"),
+		Parameters:   []*cloudformation.Parameter{cfParam("a", "1"), cfParam("b", "2")},
+		Tags:         []*cloudformation.Tag{cfTag("x", "1"), cfTag("y", "2")},
+	}
+	name := changeSetName(params)
+	assert.Regexp(t, "^"+changeSetNamePrefix+"[0-9a-f]{16}$", name)
+
+	// the order of the parameters and tags doesn't matter
+	reordered := &cloudformation.UpdateStackInput{
+		TemplateBody: aws.String("{}"),
+		Parameters:   []*cloudformation.Parameter{cfParam("b", "2"), cfParam("a", "1")},
+		Tags:         []*cloudformation.Tag{cfTag("y", "2"), cfTag("x", "1")},
+	}
+	assert.Equal(t, name, changeSetName(reordered))
+
+	params.Tags = []*cloudformation.Tag{cfTag("x", "1"), cfTag("y", "3")}
+	assert.NotEqual(t, name, changeSetName(params))
+}
@@ -0,0 +1,24 @@
+package aws
+
+import "time"
+
+// Clock provides the current time to the decisions depending on it, e.g.
+// the expiry of certificate TTLs, and the timers of the resyncs, so tests
+// and simulations can control time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock is the Clock of the system.
+var SystemClock Clock = systemClock{}
@@ -57,6 +57,7 @@ type subnetDetails struct {
 	availabilityZone string
 	tags             map[string]string
 	public           bool
+	ipv6             bool
 }
 
 func (sd *subnetDetails) String() string {
@@ -184,11 +185,13 @@ func getSubnets(svc ec2iface.EC2API, vpcID, clusterID string) ([]*subnetDetails,
 			return nil, err
 		}
 		tags := convertEc2Tags(sn.Tags)
+		ipv6 := hasIPv6CIDRBlock(sn)
 		retAll[i] = &subnetDetails{
 			id:               subnetID,
 			availabilityZone: az,
 			public:           isPublic,
 			tags:             tags,
+			ipv6:             ipv6,
 		}
 		if _, ok := tags[clusterIDTagPrefix+clusterID]; ok {
 			retFiltered = append(retFiltered, &subnetDetails{
@@ -196,6 +199,7 @@ func getSubnets(svc ec2iface.EC2API, vpcID, clusterID string) ([]*subnetDetails,
 				availabilityZone: az,
 				public:           isPublic,
 				tags:             tags,
+				ipv6:             ipv6,
 			})
 		}
 	}
@@ -208,6 +212,17 @@ func getSubnets(svc ec2iface.EC2API, vpcID, clusterID string) ([]*subnetDetails,
 	return retFiltered, nil
 }
 
+// hasIPv6CIDRBlock returns true if an IPv6 CIDR block is associated with
+// the subnet, which dualstack load balancers require.
+func hasIPv6CIDRBlock(subnet *ec2.Subnet) bool {
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil && aws.StringValue(association.Ipv6CidrBlockState.State) == ec2.SubnetCidrBlockStateCodeAssociated {
+			return true
+		}
+	}
+	return false
+}
+
 func convertEc2Tags(instanceTags []*ec2.Tag) map[string]string {
 	tags := make(map[string]string, len(instanceTags))
 	for _, tagDescription := range instanceTags {
@@ -175,7 +175,7 @@ func TestGetSubnets(t *testing.T) {
 			"success-call-nofilter",
 			ec2MockOutputs{
 				describeSubnets: R(mockDSOutput(
-					testSubnet{id: "foo1", name: "bar1", az: "baz1", tags: map[string]string{elbRoleTagName: ""}},
+					testSubnet{id: "foo1", name: "bar1", az: "baz1", tags: map[string]string{elbRoleTagName: ""}, ipv6: true},
 					testSubnet{id: "foo2", name: "bar2", az: "baz2"},
 				), nil),
 				describeRouteTables: R(mockDRTOutput(
@@ -184,7 +184,7 @@ func TestGetSubnets(t *testing.T) {
 				), nil),
 			},
 			[]*subnetDetails{
-				{id: "foo1", availabilityZone: "baz1", public: true, tags: map[string]string{nameTag: "bar1", elbRoleTagName: ""}},
+				{id: "foo1", availabilityZone: "baz1", public: true, tags: map[string]string{nameTag: "bar1", elbRoleTagName: ""}, ipv6: true},
 				{id: "foo2", availabilityZone: "baz2", public: true, tags: map[string]string{nameTag: "bar2"}},
 			},
 			false,
@@ -117,6 +117,7 @@ type testSubnet struct {
 	az   string
 	name string
 	tags map[string]string
+	ipv6 bool
 }
 
 func mockDSOutput(mockedSubnets ...testSubnet) *ec2.DescribeSubnetsOutput {
@@ -132,6 +133,12 @@ func mockDSOutput(mockedSubnets ...testSubnet) *ec2.DescribeSubnetsOutput {
 		for k, v := range subnet.tags {
 			s.Tags = append(s.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
 		}
+		if subnet.ipv6 {
+			s.Ipv6CidrBlockAssociationSet = []*ec2.SubnetIpv6CidrBlockAssociation{{
+				Ipv6CidrBlock:      aws.String("2001:db8::/64"),
+				Ipv6CidrBlockState: &ec2.SubnetCidrBlockState{State: aws.String(ec2.SubnetCidrBlockStateCodeAssociated)},
+			}}
+		}
 		subnets = append(subnets, s)
 	}
 	return &ec2.DescribeSubnetsOutput{Subnets: subnets}
@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cloudformation "github.com/mweagle/go-cloudformation"
+)
+
+// AutoAllocateElasticIPs makes the stack of an internet-facing network load
+// balancer allocate an Elastic IP for each of its subnets, instead of using
+// existing ones. They are released with the stack.
+const AutoAllocateElasticIPs = "auto-allocate"
+
+// NewElasticIPs parses a comma separated list of allocation IDs of Elastic
+// IPs, which are returned sorted and without duplicates, or the value
+// AutoAllocateElasticIPs, which is returned as the only element.
+func NewElasticIPs(value string) ([]string, error) {
+	if strings.TrimSpace(value) == AutoAllocateElasticIPs {
+		return []string{AutoAllocateElasticIPs}, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.HasPrefix(s, "eipalloc-") {
+			return nil, fmt.Errorf("invalid Elastic IP allocation ID %q", s)
+		}
+		seen[s] = true
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no Elastic IP allocation IDs")
+	}
+
+	var ids []string
+	for s := range seen {
+		ids = append(ids, s)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func autoAllocateElasticIPs(elasticIPs []string) bool {
+	return len(elasticIPs) == 1 && elasticIPs[0] == AutoAllocateElasticIPs
+}
+
+// addSubnetMappings maps the Elastic IPs of the spec to the subnets of the
+// load balancer, one per subnet. Elastic IPs aren't bound to an availability
+// zone, so they are mapped to the subnets in the order of their IDs.
+func addSubnetMappings(template *cloudformation.Template, spec *stackSpec, lb *cloudformation.ElasticLoadBalancingV2LoadBalancer) error {
+	subnets := make([]string, len(spec.subnets))
+	copy(subnets, spec.subnets)
+	sort.Strings(subnets)
+
+	auto := autoAllocateElasticIPs(spec.elasticIPs)
+	if !auto && len(spec.elasticIPs) != len(subnets) {
+		return fmt.Errorf("%d Elastic IPs for %d subnets, one per subnet is required", len(spec.elasticIPs), len(subnets))
+	}
+
+	mappings := make(cloudformation.ElasticLoadBalancingV2LoadBalancerSubnetMappingList, 0, len(subnets))
+	for i, subnet := range subnets {
+		var allocationID *cloudformation.StringExpr
+		if auto {
+			name := fmt.Sprintf("EIP%d", i)
+			eip := &cloudformation.EC2EIP{
+				Domain: cloudformation.String("vpc"),
+				Tags: spec.allResourceTags().templateTags(cloudformation.Tag{
+					Key:   cloudformation.String(stackNameResourceTag),
+					Value: cloudformation.Ref("AWS::StackName").String(),
+				}),
+			}
+			if spec.hasPublicIPv4Pool() {
+				eip.PublicIPv4Pool = cloudformation.Ref(parameterIPv4PoolParameter).String()
+			}
+			template.AddResource(name, eip)
+			allocationID = cloudformation.GetAtt(name, "AllocationId")
+		} else {
+			allocationID = cloudformation.String(spec.elasticIPs[i])
+		}
+		mappings = append(mappings, cloudformation.ElasticLoadBalancingV2LoadBalancerSubnetMapping{
+			SubnetID:     cloudformation.String(subnet),
+			AllocationID: allocationID,
+		})
+	}
+
+	lb.Subnets = nil
+	lb.SubnetMappings = &mappings
+	return nil
+}
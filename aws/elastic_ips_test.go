@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewElasticIPs(t *testing.T) {
+	for _, test := range []struct {
+		msg       string
+		given     string
+		want      []string
+		wantError bool
+	}{
+		{
+			msg:   "allocation IDs are sorted without duplicates",
+			given: "eipalloc-2, eipalloc-1,eipalloc-2,",
+			want:  []string{"eipalloc-1", "eipalloc-2"},
+		},
+		{
+			msg:   "auto allocation",
+			given: " auto-allocate ",
+			want:  []string{AutoAllocateElasticIPs},
+		},
+		{
+			msg:       "empty list",
+			given:     " , ",
+			wantError: true,
+		},
+		{
+			msg:       "not an allocation ID",
+			given:     "eipalloc-1,203.0.113.1",
+			wantError: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			got, err := NewElasticIPs(test.given)
+			if test.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestGenerateTemplateElasticIPs(t *testing.T) {
+	type resource struct {
+		Type       string
+		Properties map[string]interface{}
+	}
+	resources := func(spec *stackSpec) map[string]resource {
+		generated, err := generateTemplate(spec)
+		require.NoError(t, err)
+
+		var template struct {
+			Parameters map[string]interface{}
+			Resources  map[string]resource
+		}
+		require.NoError(t, json.Unmarshal([]byte(generated), &template))
+		assert.Contains(t, template.Parameters, parameterElasticIPsParameter)
+		return template.Resources
+	}
+
+	spec := &stackSpec{
+		loadbalancerType: LoadBalancerTypeNetwork,
+		subnets:          []string{"subnet-b", "subnet-a"},
+		elasticIPs:       []string{"eipalloc-1", "eipalloc-2"},
+	}
+	lb := resources(spec)["LB"]
+	assert.NotContains(t, lb.Properties, "Subnets")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"SubnetId": "subnet-a", "AllocationId": "eipalloc-1"},
+		map[string]interface{}{"SubnetId": "subnet-b", "AllocationId": "eipalloc-2"},
+	}, lb.Properties["SubnetMappings"])
+
+	spec.elasticIPs = []string{AutoAllocateElasticIPs}
+	template := resources(spec)
+	assert.Equal(t, "AWS::EC2::EIP", template["EIP0"].Type)
+	assert.Equal(t, "AWS::EC2::EIP", template["EIP1"].Type)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"SubnetId": "subnet-a", "AllocationId": map[string]interface{}{"Fn::GetAtt": []interface{}{"EIP0", "AllocationId"}}},
+		map[string]interface{}{"SubnetId": "subnet-b", "AllocationId": map[string]interface{}{"Fn::GetAtt": []interface{}{"EIP1", "AllocationId"}}},
+	}, template["LB"].Properties["SubnetMappings"])
+
+	spec.elasticIPs = []string{"eipalloc-1"}
+	_, err := generateTemplate(spec)
+	assert.Error(t, err)
+}
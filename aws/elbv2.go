@@ -51,3 +51,65 @@ func deregisterTargetsOnTargetGroups(svc elbv2iface.ELBV2API, targetGroupARNs []
 	}
 	return nil
 }
+
+// updateTargetIPs registers the IP addresses in the target groups of type
+// ip and deregisters the other targets, e.g. of terminated instances.
+func updateTargetIPs(svc elbv2iface.ELBV2API, targetGroupARNs []string, ips []string) error {
+	for _, targetGroupARN := range targetGroupARNs {
+		resp, err := svc.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(targetGroupARN),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to describe the targets of target group %s: %v", targetGroupARN, err)
+		}
+
+		registered := make(map[string]bool)
+		var obsolete []string
+		for _, health := range resp.TargetHealthDescriptions {
+			// draining targets are registered again if needed
+			if aws.StringValue(health.TargetHealth.State) == elbv2.TargetHealthStateEnumDraining {
+				continue
+			}
+			ip := aws.StringValue(health.Target.Id)
+			registered[ip] = true
+			if !inStrSlice(ip, ips) {
+				obsolete = append(obsolete, ip)
+			}
+		}
+
+		var missing []string
+		for _, ip := range ips {
+			if !registered[ip] {
+				missing = append(missing, ip)
+			}
+		}
+
+		if len(missing) > 0 {
+			if err := registerTargetsOnTargetGroups(svc, []string{targetGroupARN}, missing); err != nil {
+				return err
+			}
+		}
+		if len(obsolete) > 0 {
+			if err := deregisterTargetsOnTargetGroups(svc, []string{targetGroupARN}, obsolete); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// countLoadBalancers returns the number of load balancers of the account in
+// the region by their type.
+func countLoadBalancers(svc elbv2iface.ELBV2API) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := svc.DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{}, func(page *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range page.LoadBalancers {
+			counts[aws.StringValue(lb.Type)]++
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe load balancers: %v", err)
+	}
+	return counts, nil
+}
@@ -1,12 +1,15 @@
 package aws
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/require"
 )
 
 type registerTargetsOnTargetGroupsInputTest struct {
@@ -204,3 +207,62 @@ func TestDeregisterTargetsOnTargetGroups(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateTargetIPs(t *testing.T) {
+	target := func(ip, state string) *elbv2.TargetHealthDescription {
+		return &elbv2.TargetHealthDescription{
+			Target:       &elbv2.TargetDescription{Id: aws.String(ip)},
+			TargetHealth: &elbv2.TargetHealth{State: aws.String(state)},
+		}
+	}
+	targetIDs := func(targets []*elbv2.TargetDescription) []string {
+		ids := make([]string, 0, len(targets))
+		for _, target := range targets {
+			ids = append(ids, aws.StringValue(target.Id))
+		}
+		return ids
+	}
+
+	svc := &mockElbv2Client{outputs: elbv2MockOutputs{
+		describeTargetHealth: R(&elbv2.DescribeTargetHealthOutput{
+			TargetHealthDescriptions: []*elbv2.TargetHealthDescription{
+				target("10.0.0.1", elbv2.TargetHealthStateEnumHealthy),
+				target("10.0.0.2", elbv2.TargetHealthStateEnumDraining),
+				target("10.0.0.4", elbv2.TargetHealthStateEnumUnhealthy),
+			},
+		}, nil),
+		registerTargets:   R(mockRTOutput(), nil),
+		deregisterTargets: R(mockDTOutput(), nil),
+	}}
+	require.NoError(t, updateTargetIPs(svc, []string{"tg1"}, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}))
+	require.Len(t, svc.rtinputs, 1)
+	require.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, targetIDs(svc.rtinputs[0].Targets))
+	require.Len(t, svc.dtinputs, 1)
+	require.Equal(t, []string{"10.0.0.4"}, targetIDs(svc.dtinputs[0].Targets))
+
+	svc = &mockElbv2Client{outputs: elbv2MockOutputs{
+		describeTargetHealth: R(nil, errDummy),
+	}}
+	require.Error(t, updateTargetIPs(svc, []string{"tg1"}, []string{"10.0.0.1"}))
+	require.Empty(t, svc.rtinputs)
+}
+
+func TestCountLoadBalancers(t *testing.T) {
+	client := &mockElbv2Client{outputs: elbv2MockOutputs{
+		describeLoadBalancers: R(&elbv2.DescribeLoadBalancersOutput{
+			LoadBalancers: []*elbv2.LoadBalancer{
+				{Type: aws.String(elbv2.LoadBalancerTypeEnumApplication)},
+				{Type: aws.String(elbv2.LoadBalancerTypeEnumApplication)},
+				{Type: aws.String(elbv2.LoadBalancerTypeEnumNetwork)},
+			},
+		}, nil),
+	}}
+
+	counts, err := countLoadBalancers(client)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{LoadBalancerTypeApplication: 2, LoadBalancerTypeNetwork: 1}, counts)
+
+	client.outputs.describeLoadBalancers = R(nil, errors.New("access denied"))
+	_, err = countLoadBalancers(client)
+	require.Error(t, err)
+}
@@ -8,17 +8,40 @@ import (
 )
 
 type elbv2MockOutputs struct {
-	registerTargets      *apiResponse
-	deregisterTargets    *apiResponse
-	describeTags         *apiResponse
-	describeTargetGroups *apiResponse
+	registerTargets       *apiResponse
+	deregisterTargets     *apiResponse
+	describeTags          *apiResponse
+	describeTargetGroups  *apiResponse
+	describeLoadBalancers *apiResponse
+	addTags               *apiResponse
+	removeTags            *apiResponse
+	describeTargetHealth  *apiResponse
 }
 
 type mockElbv2Client struct {
 	elbv2iface.ELBV2API
-	outputs  elbv2MockOutputs
-	rtinputs []*elbv2.RegisterTargetsInput
-	dtinputs []*elbv2.DeregisterTargetsInput
+	outputs          elbv2MockOutputs
+	rtinputs         []*elbv2.RegisterTargetsInput
+	dtinputs         []*elbv2.DeregisterTargetsInput
+	addTagsInputs    []*elbv2.AddTagsInput
+	removeTagsInputs []*elbv2.RemoveTagsInput
+	dthinputs        []*elbv2.DescribeTargetHealthInput
+}
+
+func (m *mockElbv2Client) AddTags(in *elbv2.AddTagsInput) (*elbv2.AddTagsOutput, error) {
+	m.addTagsInputs = append(m.addTagsInputs, in)
+	if m.outputs.addTags == nil {
+		return &elbv2.AddTagsOutput{}, nil
+	}
+	return &elbv2.AddTagsOutput{}, m.outputs.addTags.err
+}
+
+func (m *mockElbv2Client) RemoveTags(in *elbv2.RemoveTagsInput) (*elbv2.RemoveTagsOutput, error) {
+	m.removeTagsInputs = append(m.removeTagsInputs, in)
+	if m.outputs.removeTags == nil {
+		return &elbv2.RemoveTagsOutput{}, nil
+	}
+	return &elbv2.RemoveTagsOutput{}, m.outputs.removeTags.err
 }
 
 func (m *mockElbv2Client) RegisterTargets(in *elbv2.RegisterTargetsInput) (*elbv2.RegisterTargetsOutput, error) {
@@ -41,6 +64,14 @@ func (m *mockElbv2Client) DeregisterTargets(in *elbv2.DeregisterTargetsInput) (*
 	return nil, m.outputs.deregisterTargets.err
 }
 
+func (m *mockElbv2Client) DescribeTargetHealth(in *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	m.dthinputs = append(m.dthinputs, in)
+	if out, ok := m.outputs.describeTargetHealth.response.(*elbv2.DescribeTargetHealthOutput); ok {
+		return out, m.outputs.describeTargetHealth.err
+	}
+	return nil, m.outputs.describeTargetHealth.err
+}
+
 func (m *mockElbv2Client) DescribeTags(tags *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
 	if out, ok := m.outputs.describeTags.response.(*elbv2.DescribeTagsOutput); ok {
 		return out, m.outputs.describeTags.err
@@ -55,6 +86,13 @@ func (m *mockElbv2Client) DescribeTargetGroupsPagesWithContext(ctx aws.Context,
 	return m.outputs.describeTargetGroups.err
 }
 
+func (m *mockElbv2Client) DescribeLoadBalancersPages(in *elbv2.DescribeLoadBalancersInput, f func(resp *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool) error {
+	if out, ok := m.outputs.describeLoadBalancers.response.(*elbv2.DescribeLoadBalancersOutput); ok {
+		f(out, true)
+	}
+	return m.outputs.describeLoadBalancers.err
+}
+
 func mockDTOutput() *elbv2.DeregisterTargetsOutput {
 	return &elbv2.DeregisterTargetsOutput{}
 }
@@ -0,0 +1,72 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	cloudformation "github.com/mweagle/go-cloudformation"
+)
+
+const (
+	outputEndpointServiceName = "EndpointServiceName"
+
+	endpointServiceResource            = "EndpointService"
+	endpointServicePermissionsResource = "EndpointServicePermissions"
+)
+
+// NewEndpointServicePrincipals parses a comma separated list of the IAM
+// principals allowed to connect to the VPC endpoint service of an internal
+// network load balancer, e.g. arn:aws:iam::123456789012:root for a whole
+// account, or * for everyone. The principals are returned sorted, without
+// duplicates.
+func NewEndpointServicePrincipals(value string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if s != "*" {
+			a, err := arn.Parse(s)
+			if err != nil || a.Service != "iam" {
+				return nil, fmt.Errorf("invalid principal %q", s)
+			}
+		}
+		seen[s] = true
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no principals")
+	}
+
+	var principals []string
+	for s := range seen {
+		principals = append(principals, s)
+	}
+	sort.Strings(principals)
+	return principals, nil
+}
+
+// addEndpointService makes the network load balancer of the stack available
+// to other VPCs with PrivateLink. Connections of the allowed principals are
+// accepted without approval.
+func addEndpointService(template *cloudformation.Template, spec *stackSpec) {
+	template.AddResource(endpointServiceResource, &cloudformation.EC2VPCEndpointService{
+		AcceptanceRequired:      cloudformation.Bool(false),
+		NetworkLoadBalancerArns: cloudformation.StringList(cloudformation.Ref("LB").String()),
+	})
+	template.AddResource(endpointServicePermissionsResource, &cloudformation.EC2VPCEndpointServicePermissions{
+		AllowedPrincipals: stringList(spec.endpointServicePrincipals),
+		ServiceID:         cloudformation.Ref(endpointServiceResource).String(),
+	})
+	template.Outputs[outputEndpointServiceName] = &cloudformation.Output{
+		Description: "The name of the VPC endpoint service of the LoadBalancer",
+		Value: cloudformation.Join("",
+			cloudformation.String("com.amazonaws.vpce."),
+			cloudformation.Ref("AWS::Region"),
+			cloudformation.String("."),
+			cloudformation.Ref(endpointServiceResource),
+		),
+	}
+}
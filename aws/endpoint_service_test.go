@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEndpointServicePrincipals(t *testing.T) {
+	for _, test := range []struct {
+		msg       string
+		given     string
+		want      []string
+		wantError bool
+	}{
+		{
+			msg:   "principals are sorted without duplicates",
+			given: "arn:aws:iam::210987654321:root, arn:aws:iam::123456789012:role/consumer,arn:aws:iam::210987654321:root",
+			want:  []string{"arn:aws:iam::123456789012:role/consumer", "arn:aws:iam::210987654321:root"},
+		},
+		{
+			msg:   "everyone",
+			given: "*",
+			want:  []string{"*"},
+		},
+		{
+			msg:       "empty list",
+			given:     ",",
+			wantError: true,
+		},
+		{
+			msg:       "not an IAM principal",
+			given:     "arn:aws:s3:::bucket",
+			wantError: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			got, err := NewEndpointServicePrincipals(test.given)
+			if test.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestGenerateTemplateEndpointService(t *testing.T) {
+	type template struct {
+		Parameters map[string]interface{}
+		Resources  map[string]struct {
+			Type       string
+			Properties map[string]interface{}
+		}
+		Outputs map[string]interface{}
+	}
+	generate := func(spec *stackSpec) template {
+		generated, err := generateTemplate(spec)
+		require.NoError(t, err)
+
+		var tmpl template
+		require.NoError(t, json.Unmarshal([]byte(generated), &tmpl))
+		return tmpl
+	}
+
+	spec := &stackSpec{
+		loadbalancerType:          LoadBalancerTypeNetwork,
+		scheme:                    elbv2.LoadBalancerSchemeEnumInternal,
+		endpointServicePrincipals: []string{"arn:aws:iam::123456789012:root"},
+	}
+	tmpl := generate(spec)
+	assert.Contains(t, tmpl.Parameters, parameterEndpointServicePrincipalsParameter)
+	assert.Equal(t, "AWS::EC2::VPCEndpointService", tmpl.Resources[endpointServiceResource].Type)
+	assert.Equal(t, []interface{}{map[string]interface{}{"Ref": "LB"}}, tmpl.Resources[endpointServiceResource].Properties["NetworkLoadBalancerArns"])
+	assert.Equal(t, []interface{}{"arn:aws:iam::123456789012:root"}, tmpl.Resources[endpointServicePermissionsResource].Properties["AllowedPrincipals"])
+	assert.Contains(t, tmpl.Outputs, outputEndpointServiceName)
+
+	spec.scheme = elbv2.LoadBalancerSchemeEnumInternetFacing
+	tmpl = generate(spec)
+	assert.NotContains(t, tmpl.Resources, endpointServiceResource)
+	assert.NotContains(t, tmpl.Outputs, outputEndpointServiceName)
+}
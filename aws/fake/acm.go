@@ -0,0 +1,86 @@
+package fake
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/acm/acmiface"
+)
+
+// ACM is a fake of the ACM API holding issued certificates.
+type ACM struct {
+	acmiface.ACMAPI
+
+	mu           sync.Mutex
+	certificates map[string]*acm.GetCertificateOutput
+	domainNames  map[string]string
+}
+
+// NewACM returns a fake without any certificates.
+func NewACM() *ACM {
+	return &ACM{
+		certificates: make(map[string]*acm.GetCertificateOutput),
+		domainNames:  make(map[string]string),
+	}
+}
+
+// AddCertificate seeds an issued certificate with its chain of
+// intermediate certificates.
+func (a *ACM) AddCertificate(arn string, cert *x509.Certificate, chain ...*x509.Certificate) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := &acm.GetCertificateOutput{Certificate: aws.String(encodePEM(cert))}
+	if len(chain) > 0 {
+		out.CertificateChain = aws.String(encodePEM(chain...))
+	}
+	a.certificates[arn] = out
+	a.domainNames[arn] = cert.Subject.CommonName
+}
+
+func (a *ACM) ListCertificatesPages(in *acm.ListCertificatesInput, fn func(*acm.ListCertificatesOutput, bool) bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	summaries := make([]*acm.CertificateSummary, 0, len(a.certificates))
+	for arn := range a.certificates {
+		summaries = append(summaries, &acm.CertificateSummary{
+			CertificateArn: aws.String(arn),
+			DomainName:     aws.String(a.domainNames[arn]),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return aws.StringValue(summaries[i].CertificateArn) < aws.StringValue(summaries[j].CertificateArn)
+	})
+
+	fn(&acm.ListCertificatesOutput{CertificateSummaryList: summaries}, true)
+	return nil
+}
+
+func (a *ACM) GetCertificate(in *acm.GetCertificateInput) (*acm.GetCertificateOutput, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	arn := aws.StringValue(in.CertificateArn)
+	out, ok := a.certificates[arn]
+	if !ok {
+		return nil, awserr.New(acm.ErrCodeResourceNotFoundException, fmt.Sprintf("Could not find certificate %s", arn), nil)
+	}
+	return out, nil
+}
+
+func encodePEM(certs ...*x509.Certificate) string {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		// writing to a buffer never fails
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.String()
+}
@@ -0,0 +1,69 @@
+package fake
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake of aws.Clock whose time only changes when it's set or
+// advanced.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	until time.Time
+	c     chan time.Time
+}
+
+// NewClock returns a fake clock at the given time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns a channel receiving the time of the clock once it's
+// advanced by the duration.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &waiter{until: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- c.now
+		return w.c
+	}
+	c.waiters = append(c.waiters, w)
+	return w.c
+}
+
+// Advance moves the clock forward by the duration.
+func (c *Clock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set sets the time of the clock and fires the timers which expired.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+	waiters := make([]*waiter, 0, len(c.waiters))
+	for _, w := range c.waiters {
+		if now.Before(w.until) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.c <- now
+	}
+	c.waiters = waiters
+}
@@ -0,0 +1,299 @@
+package fake
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+)
+
+const (
+	outputLoadBalancerDNSName = "LoadBalancerDNSName"
+	outputTargetGroupARN      = "TargetGroupARN"
+)
+
+// CloudFormation is a fake of the CloudFormation API. Stacks are created,
+// updated and deleted immediately.
+type CloudFormation struct {
+	cloudformationiface.CloudFormationAPI
+
+	mu        sync.Mutex
+	stacks    map[string]*cloudformation.Stack
+	templates map[string]string
+	events    map[string][]*cloudformation.StackEvent
+}
+
+// NewCloudFormation returns a fake without any stacks.
+func NewCloudFormation() *CloudFormation {
+	return &CloudFormation{
+		stacks:    make(map[string]*cloudformation.Stack),
+		templates: make(map[string]string),
+		events:    make(map[string][]*cloudformation.StackEvent),
+	}
+}
+
+// AddStack seeds a stack, e.g. one created by a previous run of the
+// controller.
+func (c *CloudFormation) AddStack(stack *cloudformation.Stack) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stack.StackId == nil {
+		stack.StackId = aws.String(stackID(aws.StringValue(stack.StackName)))
+	}
+	c.stacks[aws.StringValue(stack.StackName)] = stack
+}
+
+// AddStackEvent seeds an event of the stack with the given name, e.g. the
+// failure of a resource which rolled back its creation. Events are returned
+// from the newest to the oldest.
+func (c *CloudFormation) AddStackEvent(name string, event *cloudformation.StackEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events[name] = append([]*cloudformation.StackEvent{event}, c.events[name]...)
+}
+
+// Stack returns the stack with the given name or nil.
+func (c *CloudFormation) Stack(name string) *cloudformation.Stack {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stacks[name]
+}
+
+// Template returns the template body of the stack with the given name.
+func (c *CloudFormation) Template(name string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.templates[name]
+}
+
+// Stacks returns the names of all stacks.
+func (c *CloudFormation) Stacks() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.stacks))
+	for name := range c.stacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *CloudFormation) CreateStack(in *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(in.StackName)
+	if _, ok := c.stacks[name]; ok {
+		return nil, awserr.New(cloudformation.ErrCodeAlreadyExistsException, fmt.Sprintf("Stack [%s] already exists", name), nil)
+	}
+
+	c.stacks[name] = &cloudformation.Stack{
+		StackId:                     aws.String(stackID(name)),
+		StackName:                   aws.String(name),
+		StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+		CreationTime:                aws.Time(time.Now()),
+		Parameters:                  in.Parameters,
+		Tags:                        in.Tags,
+		EnableTerminationProtection: in.EnableTerminationProtection,
+		Outputs: []*cloudformation.Output{
+			{
+				OutputKey:   aws.String(outputLoadBalancerDNSName),
+				OutputValue: aws.String(name + ".elb.amazonaws.com"),
+			},
+			{
+				OutputKey:   aws.String(outputTargetGroupARN),
+				OutputValue: aws.String("arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/" + name),
+			},
+		},
+	}
+	c.templates[name] = aws.StringValue(in.TemplateBody)
+
+	return &cloudformation.CreateStackOutput{StackId: c.stacks[name].StackId}, nil
+}
+
+func (c *CloudFormation) UpdateStack(in *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(in.StackName)
+	stack, ok := c.stacks[name]
+	if !ok {
+		return nil, stackNotFoundError(name)
+	}
+
+	template := aws.StringValue(in.TemplateBody)
+	if aws.BoolValue(in.UsePreviousTemplate) {
+		template = c.templates[name]
+	}
+
+	previous := make(map[string]*string, len(stack.Parameters))
+	for _, param := range stack.Parameters {
+		previous[aws.StringValue(param.ParameterKey)] = param.ParameterValue
+	}
+	params := make([]*cloudformation.Parameter, 0, len(in.Parameters))
+	for _, param := range in.Parameters {
+		value := param.ParameterValue
+		if aws.BoolValue(param.UsePreviousValue) {
+			value = previous[aws.StringValue(param.ParameterKey)]
+		}
+		params = append(params, &cloudformation.Parameter{
+			ParameterKey:   param.ParameterKey,
+			ParameterValue: value,
+		})
+	}
+
+	if template == c.templates[name] &&
+		reflect.DeepEqual(parameterMap(params), parameterMap(stack.Parameters)) &&
+		reflect.DeepEqual(tagMap(in.Tags), tagMap(stack.Tags)) {
+		return nil, awserr.New("ValidationError", "No updates are to be performed.", nil)
+	}
+
+	stack.Parameters = params
+	stack.Tags = in.Tags
+	stack.StackStatus = aws.String(cloudformation.StackStatusUpdateComplete)
+	stack.LastUpdatedTime = aws.Time(time.Now())
+	c.templates[name] = template
+
+	return &cloudformation.UpdateStackOutput{StackId: stack.StackId}, nil
+}
+
+func (c *CloudFormation) UpdateTerminationProtection(in *cloudformation.UpdateTerminationProtectionInput) (*cloudformation.UpdateTerminationProtectionOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(in.StackName)
+	stack, ok := c.stacks[name]
+	if !ok {
+		return nil, stackNotFoundError(name)
+	}
+	stack.EnableTerminationProtection = in.EnableTerminationProtection
+
+	return &cloudformation.UpdateTerminationProtectionOutput{StackId: stack.StackId}, nil
+}
+
+func (c *CloudFormation) DeleteStack(in *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(in.StackName)
+	if stack, ok := c.stacks[name]; ok && aws.BoolValue(stack.EnableTerminationProtection) {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("Stack [%s] cannot be deleted while TerminationProtection is enabled", name), nil)
+	}
+
+	stacks := make(map[string]*cloudformation.Stack, len(c.stacks))
+	for n, stack := range c.stacks {
+		if n != name {
+			stacks[n] = stack
+		}
+	}
+	c.stacks = stacks
+
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func (c *CloudFormation) DescribeStacks(in *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if in.StackName == nil {
+		return &cloudformation.DescribeStacksOutput{Stacks: c.sortedStacks()}, nil
+	}
+
+	name := aws.StringValue(in.StackName)
+	for _, stack := range c.stacks {
+		if aws.StringValue(stack.StackName) == name || aws.StringValue(stack.StackId) == name {
+			return &cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{stack}}, nil
+		}
+	}
+	return nil, stackNotFoundError(name)
+}
+
+func (c *CloudFormation) DescribeStacksPages(in *cloudformation.DescribeStacksInput, fn func(*cloudformation.DescribeStacksOutput, bool) bool) error {
+	resp, err := c.DescribeStacks(in)
+	if err != nil {
+		return err
+	}
+	fn(resp, true)
+	return nil
+}
+
+func (c *CloudFormation) DescribeStackEventsPages(in *cloudformation.DescribeStackEventsInput, fn func(*cloudformation.DescribeStackEventsOutput, bool) bool) error {
+	c.mu.Lock()
+	events := c.events[aws.StringValue(in.StackName)]
+	c.mu.Unlock()
+
+	fn(&cloudformation.DescribeStackEventsOutput{StackEvents: events}, true)
+	return nil
+}
+
+// DescribeStackResources returns the target group of the stack, the only
+// resource whose ID is known to the fake.
+func (c *CloudFormation) DescribeStackResources(in *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(in.StackName)
+	stack, ok := c.stacks[name]
+	if !ok {
+		return nil, stackNotFoundError(name)
+	}
+
+	resp := &cloudformation.DescribeStackResourcesOutput{}
+	for _, output := range stack.Outputs {
+		if aws.StringValue(output.OutputKey) == outputTargetGroupARN {
+			resp.StackResources = append(resp.StackResources, &cloudformation.StackResource{
+				StackName:          stack.StackName,
+				LogicalResourceId:  aws.String("TG"),
+				PhysicalResourceId: output.OutputValue,
+				ResourceType:       aws.String("AWS::ElasticLoadBalancingV2::TargetGroup"),
+			})
+		}
+	}
+	return resp, nil
+}
+
+func (c *CloudFormation) sortedStacks() []*cloudformation.Stack {
+	stacks := make([]*cloudformation.Stack, 0, len(c.stacks))
+	for _, stack := range c.stacks {
+		stacks = append(stacks, stack)
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		return aws.StringValue(stacks[i].StackName) < aws.StringValue(stacks[j].StackName)
+	})
+	return stacks
+}
+
+func stackID(name string) string {
+	return "arn:aws:cloudformation:eu-central-1:123456789012:stack/" + name
+}
+
+func stackNotFoundError(name string) error {
+	return awserr.New("ValidationError", fmt.Sprintf("Stack with id %s does not exist", name), nil)
+}
+
+func parameterMap(params []*cloudformation.Parameter) map[string]string {
+	result := make(map[string]string, len(params))
+	for _, param := range params {
+		result[aws.StringValue(param.ParameterKey)] = aws.StringValue(param.ParameterValue)
+	}
+	return result
+}
+
+func tagMap(tags []*cloudformation.Tag) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return result
+}
@@ -0,0 +1,245 @@
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+const (
+	instanceStateRunning = 16
+	instanceStateStopped = 80
+)
+
+// EC2 is a fake of the EC2 API holding instances, subnets and security
+// groups.
+type EC2 struct {
+	ec2iface.EC2API
+
+	mu             sync.Mutex
+	instances      map[string]*ec2.Instance
+	subnets        map[string]*ec2.Subnet
+	publicSubnets  map[string]bool
+	securityGroups map[string]*ec2.SecurityGroup
+}
+
+// NewEC2 returns a fake without any resources.
+func NewEC2() *EC2 {
+	return &EC2{
+		instances:      make(map[string]*ec2.Instance),
+		subnets:        make(map[string]*ec2.Subnet),
+		publicSubnets:  make(map[string]bool),
+		securityGroups: make(map[string]*ec2.SecurityGroup),
+	}
+}
+
+// AddInstance seeds a running instance.
+func (e *EC2) AddInstance(id, ip, vpcID string, tags map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.instances[id] = &ec2.Instance{
+		InstanceId:       aws.String(id),
+		PrivateIpAddress: aws.String(ip),
+		VpcId:            aws.String(vpcID),
+		Tags:             ec2Tags(tags),
+		State: &ec2.InstanceState{
+			Code: aws.Int64(instanceStateRunning),
+			Name: aws.String(ec2.InstanceStateNameRunning),
+		},
+	}
+}
+
+// StopInstance stops the instance with the given ID.
+func (e *EC2) StopInstance(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if instance, ok := e.instances[id]; ok {
+		instance.State = &ec2.InstanceState{
+			Code: aws.Int64(instanceStateStopped),
+			Name: aws.String(ec2.InstanceStateNameStopped),
+		}
+	}
+}
+
+// TerminateInstance removes the instance with the given ID.
+func (e *EC2) TerminateInstance(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	instances := make(map[string]*ec2.Instance, len(e.instances))
+	for i, instance := range e.instances {
+		if i != id {
+			instances[i] = instance
+		}
+	}
+	e.instances = instances
+}
+
+// AddSubnet seeds a subnet. Public subnets are routed to an internet
+// gateway.
+func (e *EC2) AddSubnet(id, vpcID, availabilityZone string, public bool, tags map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.subnets[id] = &ec2.Subnet{
+		SubnetId:         aws.String(id),
+		VpcId:            aws.String(vpcID),
+		AvailabilityZone: aws.String(availabilityZone),
+		Tags:             ec2Tags(tags),
+	}
+	e.publicSubnets[id] = public
+}
+
+// AddSecurityGroup seeds a security group.
+func (e *EC2) AddSecurityGroup(id, name, vpcID string, tags map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.securityGroups[id] = &ec2.SecurityGroup{
+		GroupId:   aws.String(id),
+		GroupName: aws.String(name),
+		VpcId:     aws.String(vpcID),
+		Tags:      ec2Tags(tags),
+	}
+}
+
+func (e *EC2) DescribeInstances(in *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids := aws.StringValueSlice(in.InstanceIds)
+	instances := make([]*ec2.Instance, 0)
+	for id, instance := range e.instances {
+		if len(ids) > 0 && !contains(ids, id) {
+			continue
+		}
+		fields := map[string]string{
+			"instance-id":         id,
+			"vpc-id":              aws.StringValue(instance.VpcId),
+			"private-ip-address":  aws.StringValue(instance.PrivateIpAddress),
+			"instance-state-name": aws.StringValue(instance.State.Name),
+		}
+		if matchFilters(in.Filters, convertTags(instance.Tags), fields) {
+			instances = append(instances, instance)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return aws.StringValue(instances[i].InstanceId) < aws.StringValue(instances[j].InstanceId)
+	})
+
+	resp := &ec2.DescribeInstancesOutput{}
+	if len(instances) > 0 {
+		resp.Reservations = []*ec2.Reservation{{Instances: instances}}
+	}
+	return resp, nil
+}
+
+func (e *EC2) DescribeInstancesPages(in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	resp, err := e.DescribeInstances(in)
+	if err != nil {
+		return err
+	}
+	fn(resp, true)
+	return nil
+}
+
+func (e *EC2) DescribeSubnets(in *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids := aws.StringValueSlice(in.SubnetIds)
+	subnets := make([]*ec2.Subnet, 0)
+	for id, subnet := range e.subnets {
+		if len(ids) > 0 && !contains(ids, id) {
+			continue
+		}
+		fields := map[string]string{
+			"subnet-id":         id,
+			"vpc-id":            aws.StringValue(subnet.VpcId),
+			"availability-zone": aws.StringValue(subnet.AvailabilityZone),
+		}
+		if matchFilters(in.Filters, convertTags(subnet.Tags), fields) {
+			subnets = append(subnets, subnet)
+		}
+	}
+	sort.Slice(subnets, func(i, j int) bool {
+		return aws.StringValue(subnets[i].SubnetId) < aws.StringValue(subnets[j].SubnetId)
+	})
+	return &ec2.DescribeSubnetsOutput{Subnets: subnets}, nil
+}
+
+// DescribeRouteTables returns a route table per subnet. The tables of public
+// subnets have a route to an internet gateway.
+func (e *EC2) DescribeRouteTables(in *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tables := make([]*ec2.RouteTable, 0)
+	for id, subnet := range e.subnets {
+		vpcID := aws.StringValue(subnet.VpcId)
+		if !matchFilters(in.Filters, nil, map[string]string{"vpc-id": vpcID}) {
+			continue
+		}
+
+		routes := []*ec2.Route{{GatewayId: aws.String("local")}}
+		if e.publicSubnets[id] {
+			routes = append(routes, &ec2.Route{GatewayId: aws.String("igw-" + vpcID)})
+		}
+		tables = append(tables, &ec2.RouteTable{
+			RouteTableId: aws.String("rtb-" + id),
+			VpcId:        aws.String(vpcID),
+			Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String(id)}},
+			Routes:       routes,
+		})
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return aws.StringValue(tables[i].RouteTableId) < aws.StringValue(tables[j].RouteTableId)
+	})
+	return &ec2.DescribeRouteTablesOutput{RouteTables: tables}, nil
+}
+
+func (e *EC2) DescribeSecurityGroups(in *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids := aws.StringValueSlice(in.GroupIds)
+	for _, id := range ids {
+		if _, ok := e.securityGroups[id]; !ok {
+			return nil, awserr.New("InvalidGroup.NotFound", fmt.Sprintf("The security group '%s' does not exist", id), nil)
+		}
+	}
+
+	groups := make([]*ec2.SecurityGroup, 0)
+	for id, group := range e.securityGroups {
+		if len(ids) > 0 && !contains(ids, id) {
+			continue
+		}
+		fields := map[string]string{
+			"group-id":   id,
+			"group-name": aws.StringValue(group.GroupName),
+			"vpc-id":     aws.StringValue(group.VpcId),
+		}
+		if matchFilters(in.Filters, convertTags(group.Tags), fields) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return aws.StringValue(groups[i].GroupId) < aws.StringValue(groups[j].GroupId)
+	})
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: groups}, nil
+}
+
+func convertTags(tags []*ec2.Tag) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return result
+}
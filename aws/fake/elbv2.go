@@ -0,0 +1,152 @@
+package fake
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+)
+
+// ELBV2 is a fake of the ELBv2 API recording the targets registered on
+// target groups and the tags of resources.
+type ELBV2 struct {
+	elbv2iface.ELBV2API
+
+	mu      sync.Mutex
+	targets map[string]map[string]bool
+	tags    map[string]map[string]string
+}
+
+// NewELBV2 returns a fake without any targets.
+func NewELBV2() *ELBV2 {
+	return &ELBV2{
+		targets: make(map[string]map[string]bool),
+		tags:    make(map[string]map[string]string),
+	}
+}
+
+// Tags returns the tags set on the resource with the ELBv2 API.
+func (e *ELBV2) Tags(arn string) map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tags := make(map[string]string, len(e.tags[arn]))
+	for key, value := range e.tags[arn] {
+		tags[key] = value
+	}
+	return tags
+}
+
+func (e *ELBV2) DescribeTags(in *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	resp := &elbv2.DescribeTagsOutput{}
+	for _, arn := range in.ResourceArns {
+		description := &elbv2.TagDescription{ResourceArn: arn}
+		for key, value := range e.tags[aws.StringValue(arn)] {
+			description.Tags = append(description.Tags, &elbv2.Tag{
+				Key:   aws.String(key),
+				Value: aws.String(value),
+			})
+		}
+		resp.TagDescriptions = append(resp.TagDescriptions, description)
+	}
+	return resp, nil
+}
+
+func (e *ELBV2) AddTags(in *elbv2.AddTagsInput) (*elbv2.AddTagsOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, arn := range aws.StringValueSlice(in.ResourceArns) {
+		if e.tags[arn] == nil {
+			e.tags[arn] = make(map[string]string)
+		}
+		for _, tag := range in.Tags {
+			e.tags[arn][aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+	return &elbv2.AddTagsOutput{}, nil
+}
+
+func (e *ELBV2) RemoveTags(in *elbv2.RemoveTagsInput) (*elbv2.RemoveTagsOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, arn := range aws.StringValueSlice(in.ResourceArns) {
+		for _, key := range aws.StringValueSlice(in.TagKeys) {
+			delete(e.tags[arn], key)
+		}
+	}
+	return &elbv2.RemoveTagsOutput{}, nil
+}
+
+// Targets returns the sorted IDs of the targets registered on the target
+// group.
+func (e *ELBV2) Targets(targetGroupARN string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	targets := make([]string, 0, len(e.targets[targetGroupARN]))
+	for id, registered := range e.targets[targetGroupARN] {
+		if registered {
+			targets = append(targets, id)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func (e *ELBV2) RegisterTargets(in *elbv2.RegisterTargetsInput) (*elbv2.RegisterTargetsOutput, error) {
+	e.setTargets(aws.StringValue(in.TargetGroupArn), in.Targets, true)
+	return &elbv2.RegisterTargetsOutput{}, nil
+}
+
+func (e *ELBV2) DeregisterTargets(in *elbv2.DeregisterTargetsInput) (*elbv2.DeregisterTargetsOutput, error) {
+	e.setTargets(aws.StringValue(in.TargetGroupArn), in.Targets, false)
+	return &elbv2.DeregisterTargetsOutput{}, nil
+}
+
+func (e *ELBV2) DescribeTargetHealth(in *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	resp := &elbv2.DescribeTargetHealthOutput{}
+	for _, id := range e.Targets(aws.StringValue(in.TargetGroupArn)) {
+		resp.TargetHealthDescriptions = append(resp.TargetHealthDescriptions, &elbv2.TargetHealthDescription{
+			Target:       &elbv2.TargetDescription{Id: aws.String(id)},
+			TargetHealth: &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumHealthy)},
+		})
+	}
+	return resp, nil
+}
+
+func (e *ELBV2) setTargets(targetGroupARN string, targets []*elbv2.TargetDescription, registered bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.targets[targetGroupARN] == nil {
+		e.targets[targetGroupARN] = make(map[string]bool)
+	}
+	for _, target := range targets {
+		e.targets[targetGroupARN][aws.StringValue(target.Id)] = registered
+	}
+}
+
+// AutoScaling is a fake of the Auto Scaling API without any auto scaling
+// groups, so the adapter registers all instances as single instances.
+type AutoScaling struct {
+	autoscalingiface.AutoScalingAPI
+}
+
+// NewAutoScaling returns a fake without any auto scaling groups.
+func NewAutoScaling() *AutoScaling {
+	return &AutoScaling{}
+}
+
+func (a *AutoScaling) DescribeAutoScalingGroupsPages(in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+	fn(&autoscaling.DescribeAutoScalingGroupsOutput{}, true)
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package fake provides in-memory fakes of the Amazon Web Services used by
+// the aws.Adapter, so tools embedding the controller packages can test
+// against it without real AWS accounts.
+//
+// The fakes keep their state in memory and apply changes immediately, e.g.
+// created CloudFormation stacks are complete right away. Only the API calls
+// made by the adapter are implemented, any other call panics.
+package fake
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	kubeaws "github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+const (
+	clusterIDTagPrefix     = "kubernetes.io/cluster/"
+	resourceLifecycleOwned = "owned"
+	kubernetesCreatorTag   = "kubernetes:application"
+	kubernetesNodeRoleTag  = "k8s.io/role/node"
+)
+
+// AWS bundles the fakes of all the services used by the adapter.
+type AWS struct {
+	CloudFormation *CloudFormation
+	EC2            *EC2
+	ELBV2          *ELBV2
+	AutoScaling    *AutoScaling
+	ACM            *ACM
+	Route53        *Route53
+}
+
+// New returns fakes without any resources.
+func New() *AWS {
+	return &AWS{
+		CloudFormation: NewCloudFormation(),
+		EC2:            NewEC2(),
+		ELBV2:          NewELBV2(),
+		AutoScaling:    NewAutoScaling(),
+		ACM:            NewACM(),
+		Route53:        NewRoute53(),
+	}
+}
+
+// Clients returns the fakes as clients of an adapter.
+func (f *AWS) Clients() kubeaws.Clients {
+	return kubeaws.Clients{
+		CloudFormation: f.CloudFormation,
+		EC2:            f.EC2,
+		ELBV2:          f.ELBV2,
+		AutoScaling:    f.AutoScaling,
+		ACM:            f.ACM,
+		Route53:        f.Route53,
+	}
+}
+
+// NewAdapter returns an adapter using the fakes. The cluster must have been
+// seeded with AddCluster before.
+func (f *AWS) NewAdapter(clusterID, controllerID, vpcID string) (*kubeaws.Adapter, error) {
+	return kubeaws.NewAdapterWithClients(clusterID, controllerID, vpcID, f.Clients())
+}
+
+// AddCluster seeds the resources the adapter discovers on start for the
+// cluster: the security group of its load balancers and a public and a
+// private subnet.
+func (f *AWS) AddCluster(clusterID, controllerID, vpcID string) {
+	f.EC2.AddSecurityGroup("sg-"+clusterID, clusterID+"-lb", vpcID, map[string]string{
+		clusterIDTagPrefix + clusterID: resourceLifecycleOwned,
+		kubernetesCreatorTag:           controllerID,
+	})
+	tags := map[string]string{clusterIDTagPrefix + clusterID: resourceLifecycleOwned}
+	f.EC2.AddSubnet("subnet-public-"+clusterID, vpcID, "eu-central-1a", true, tags)
+	f.EC2.AddSubnet("subnet-private-"+clusterID, vpcID, "eu-central-1a", false, tags)
+}
+
+// AddNode seeds a running instance which is a node of the cluster and
+// isn't part of an auto scaling group.
+func (f *AWS) AddNode(clusterID, instanceID, ip, vpcID string) {
+	f.EC2.AddInstance(instanceID, ip, vpcID, map[string]string{
+		clusterIDTagPrefix + clusterID: resourceLifecycleOwned,
+		kubernetesNodeRoleTag:          "",
+	})
+}
+
+// matchFilters returns true if the resource with the given tags and fields,
+// e.g. vpc-id, matches all the filters. Filters on tags ("tag:<key>",
+// "tag-key" and "tag-value") and on the given fields are supported, any
+// other filter doesn't match.
+func matchFilters(filters []*ec2.Filter, tags map[string]string, fields map[string]string) bool {
+	for _, filter := range filters {
+		name := aws.StringValue(filter.Name)
+		values := aws.StringValueSlice(filter.Values)
+
+		var match bool
+		switch {
+		case strings.HasPrefix(name, "tag:"):
+			value, ok := tags[strings.TrimPrefix(name, "tag:")]
+			match = ok && contains(values, value)
+		case name == "tag-key":
+			for key := range tags {
+				match = match || contains(values, key)
+			}
+		case name == "tag-value":
+			for _, value := range tags {
+				match = match || contains(values, value)
+			}
+		default:
+			value, ok := fields[name]
+			match = ok && contains(values, value)
+		}
+
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func ec2Tags(tags map[string]string) []*ec2.Tag {
+	result := make([]*ec2.Tag, 0, len(tags))
+	for key, value := range tags {
+		result = append(result, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return result
+}
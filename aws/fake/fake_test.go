@@ -0,0 +1,126 @@
+package fake
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kubeaws "github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+func TestAdapter(t *testing.T) {
+	f := New()
+	f.AddCluster("cluster", "controller", "vpc")
+	f.AddNode("cluster", "i-1", "10.0.0.1", "vpc")
+
+	a, err := f.NewAdapter("cluster", "controller", "vpc")
+	require.NoError(t, err)
+	assert.Equal(t, "sg-cluster", a.SecurityGroupID())
+	assert.Equal(t, []string{"subnet-public-cluster"}, a.FindLBSubnets("internet-facing"))
+
+	require.NoError(t, a.UpdateAutoScalingGroupsAndInstances())
+	assert.Equal(t, []string{"i-1"}, a.SingleInstances())
+
+	opts := &kubeaws.StackOptions{
+		Scheme:          "internet-facing",
+		SecurityGroup:   a.SecurityGroupID(),
+		SSLPolicy:       kubeaws.DefaultSslPolicy,
+		IPAddressType:   kubeaws.DefaultIpAddressType,
+		CertificateARNs: map[string]time.Time{"cert-arn": {}},
+	}
+	_, err = a.CreateStack(opts)
+	require.NoError(t, err)
+
+	stacks, err := a.FindManagedStacks()
+	require.NoError(t, err)
+	require.Len(t, stacks, 1)
+	stack := stacks[0]
+	assert.True(t, stack.IsComplete())
+	assert.NotEmpty(t, stack.DNSName)
+	assert.Contains(t, stack.CertificateARNs, "cert-arn")
+	assert.NotEmpty(t, f.CloudFormation.Template(stack.Name))
+
+	a.UpdateTargetGroupsAndAutoScalingGroups(stacks)
+	assert.Equal(t, []string{"i-1"}, f.ELBV2.Targets(stack.TargetGroupARN))
+
+	_, err = a.UpdateStack(stack.Name, opts)
+	assert.Error(t, err, "expected no updates to be performed")
+
+	drained, err := a.StackTargetsDrained(stack)
+	require.NoError(t, err)
+	assert.False(t, drained)
+
+	require.NoError(t, a.DetachStack(stack))
+	drained, err = a.StackTargetsDrained(stack)
+	require.NoError(t, err)
+	assert.True(t, drained)
+
+	require.NoError(t, a.DeleteStack(stack))
+	assert.Empty(t, f.CloudFormation.Stacks())
+}
+
+func TestNewAdapterWithoutCluster(t *testing.T) {
+	_, err := New().NewAdapter("cluster", "controller", "vpc")
+	assert.Error(t, err)
+}
+
+func TestACM(t *testing.T) {
+	cert := selfSignedCertificate(t, "foo.example.org")
+
+	f := New()
+	f.ACM.AddCertificate("cert-arn", cert)
+	f.AddCluster("cluster", "controller", "vpc")
+
+	a, err := f.NewAdapter("cluster", "controller", "vpc")
+	require.NoError(t, err)
+
+	summaries, err := a.NewACMCertificateProvider().GetCertificates()
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "cert-arn", summaries[0].ID())
+	assert.Contains(t, summaries[0].DomainNames(), "foo.example.org")
+}
+
+func selfSignedCertificate(t *testing.T, hostname string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hostname},
+		DNSNames:     []string{hostname},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestClock(t *testing.T) {
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	c := NewClock(now)
+	var _ kubeaws.Clock = c
+
+	after := c.After(time.Minute)
+	c.Advance(30 * time.Second)
+	select {
+	case <-after:
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	assert.Equal(t, now.Add(time.Minute), <-after)
+	assert.Equal(t, now.Add(time.Minute), c.Now())
+}
@@ -0,0 +1,80 @@
+package fake
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+// Route53 is a fake of the Route53 API listing the seeded hosted zones and
+// their records.
+type Route53 struct {
+	route53iface.Route53API
+
+	mu      sync.Mutex
+	zones   []*route53.HostedZone
+	records map[string][]*route53.ResourceRecordSet
+}
+
+// NewRoute53 returns a fake without any hosted zones.
+func NewRoute53() *Route53 {
+	return &Route53{records: make(map[string][]*route53.ResourceRecordSet)}
+}
+
+// AddHostedZone seeds a hosted zone with the given ID and domain name.
+func (r *Route53) AddHostedZone(id, name string, private bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.zones = append(r.zones, &route53.HostedZone{
+		Id:     aws.String("/hostedzone/" + id),
+		Name:   aws.String(name + "."),
+		Config: &route53.HostedZoneConfig{PrivateZone: aws.Bool(private)},
+	})
+}
+
+// AddRecord seeds a record of the hosted zone with the given ID, e.g. one
+// managed by external-dns. Records without set identifier are plain ones.
+func (r *Route53) AddRecord(zoneID, name, recordType, setIdentifier string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record := &route53.ResourceRecordSet{Name: aws.String(name + "."), Type: aws.String(recordType)}
+	if setIdentifier != "" {
+		record.SetIdentifier = aws.String(setIdentifier)
+	}
+	records := append(r.records[zoneID], record)
+	sort.SliceStable(records, func(i, j int) bool {
+		return aws.StringValue(records[i].Name) < aws.StringValue(records[j].Name)
+	})
+	r.records[zoneID] = records
+}
+
+func (r *Route53) ListHostedZonesPages(in *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool) error {
+	r.mu.Lock()
+	zones := append([]*route53.HostedZone(nil), r.zones...)
+	r.mu.Unlock()
+
+	fn(&route53.ListHostedZonesOutput{HostedZones: zones}, true)
+	return nil
+}
+
+// ListResourceRecordSets returns the records of the hosted zone sorted by
+// name, starting with the requested one. Unlike Route53, the fake doesn't
+// limit the number of records returned.
+func (r *Route53) ListResourceRecordSets(in *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resp := &route53.ListResourceRecordSetsOutput{}
+	start := aws.StringValue(in.StartRecordName) + "."
+	for _, record := range r.records[aws.StringValue(in.HostedZoneId)] {
+		if aws.StringValue(record.Name) >= start {
+			resp.ResourceRecordSets = append(resp.ResourceRecordSets, record)
+		}
+	}
+	return resp, nil
+}
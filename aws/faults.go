@@ -0,0 +1,149 @@
+package aws
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/corehandlers"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/wafregional"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// InjectedErrorCode is the code of the errors injected into requests.
+	InjectedErrorCode = "InternalFailure"
+	// InjectedThrottleCode is the code of the throttling errors injected
+	// into requests.
+	InjectedThrottleCode = "Throttling"
+)
+
+// FaultInjection configures the faults injected into the requests to AWS,
+// to test the resilience and the alerting of the controller. It must never
+// be used in production.
+type FaultInjection struct {
+	// ErrorRate is the share of requests failing with an internal error,
+	// between 0 and 1.
+	ErrorRate float64
+	// ThrottleRate is the share of requests failing with a throttling
+	// error, between 0 and 1.
+	ThrottleRate float64
+	// Latency is added to every request.
+	Latency time.Duration
+}
+
+// Enabled returns true if any faults are injected.
+func (f *FaultInjection) Enabled() bool {
+	return f != nil && (f.ErrorRate > 0 || f.ThrottleRate > 0 || f.Latency > 0)
+}
+
+// WithFaultInjection injects the faults into the requests of the AWS clients
+// of the adapter. The faults are injected instead of sending the request,
+// so they're retried by the AWS SDK like real ones. Clients which weren't
+// created from an AWS session, e.g. fakes, are left untouched. The faults
+// are injected into the clients of the roles assumed later as well.
+func (a *Adapter) WithFaultInjection(faults *FaultInjection) *Adapter {
+	if !faults.Enabled() {
+		return a
+	}
+
+	a.faults = faults
+	faults.inject(a.ec2, a.elbv2, a.autoscaling, a.acm, a.iam, a.cloudformation, a.route53, a.servicequotas, a.wafv2, a.wafregional, a.s3, a.sqs)
+	return a
+}
+
+// inject replaces the send handler of the clients by the one injecting the
+// faults.
+func (f *FaultInjection) inject(clients ...interface{}) {
+	if !f.Enabled() {
+		return
+	}
+	for _, c := range clients {
+		if cl := sdkClient(c); cl != nil {
+			cl.Handlers.Send.Swap(corehandlers.SendHandler.Name, f.sendHandler())
+		}
+	}
+}
+
+func (f *FaultInjection) sendHandler() request.NamedHandler {
+	return request.NamedHandler{
+		Name: "kube-ingress-aws-controller.FaultInjectionSendHandler",
+		Fn: func(r *request.Request) {
+			if f.Latency > 0 {
+				time.Sleep(f.Latency)
+			}
+
+			n := rand.Float64()
+			switch {
+			case n < f.ThrottleRate:
+				injectFault(r, InjectedThrottleCode, http.StatusBadRequest)
+			case n < f.ThrottleRate+f.ErrorRate:
+				injectFault(r, InjectedErrorCode, http.StatusInternalServerError)
+			default:
+				corehandlers.SendHandler.Fn(r)
+			}
+		},
+	}
+}
+
+func injectFault(r *request.Request, code string, statusCode int) {
+	log.Debugf("Injecting %s into %s.%s", code, r.ClientInfo.ServiceName, r.Operation.Name)
+	r.HTTPResponse = &http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+	r.Error = awserr.NewRequestFailure(awserr.New(code, "fault injected by the controller", nil), statusCode, "")
+}
+
+// sdkClient returns the client of the AWS SDK service, or nil if it's not
+// one.
+func sdkClient(c interface{}) *client.Client {
+	switch s := c.(type) {
+	case *ec2.EC2:
+		return s.Client
+	case *elbv2.ELBV2:
+		return s.Client
+	case *autoscaling.AutoScaling:
+		return s.Client
+	case *acm.ACM:
+		return s.Client
+	case *iam.IAM:
+		return s.Client
+	case *cloudformation.CloudFormation:
+		return s.Client
+	case *route53.Route53:
+		return s.Client
+	case *servicequotas.ServiceQuotas:
+		return s.Client
+	case *wafv2.WAFV2:
+		return s.Client
+	case *wafregional.WAFRegional:
+		return s.Client
+	case *s3.S3:
+		return s.Client
+	case *sqs.SQS:
+		return s.Client
+	case *sts.STS:
+		return s.Client
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/corehandlers"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjection(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"></DescribeInstancesResponse>`))
+	}))
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(aws.NewConfig().
+		WithEndpoint(server.URL).
+		WithRegion("eu-central-1").
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")).
+		WithMaxRetries(0)))
+
+	for _, test := range []struct {
+		msg      string
+		faults   *FaultInjection
+		wantCode string
+	}{
+		{
+			msg: "no faults",
+		},
+		{
+			msg:    "latency",
+			faults: &FaultInjection{Latency: time.Millisecond},
+		},
+		{
+			msg:      "errors",
+			faults:   &FaultInjection{ErrorRate: 1},
+			wantCode: InjectedErrorCode,
+		},
+		{
+			msg:      "throttling",
+			faults:   &FaultInjection{ThrottleRate: 1},
+			wantCode: InjectedThrottleCode,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			requests = 0
+			a := newAdapter(DefaultControllerID, Clients{EC2: ec2.New(sess)}).WithFaultInjection(test.faults)
+
+			_, err := a.ec2.DescribeInstances(&ec2.DescribeInstancesInput{})
+			if test.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, 1, requests)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Equal(t, test.wantCode, err.(awserr.Error).Code())
+			assert.Equal(t, 0, requests)
+		})
+	}
+}
+
+func TestFaultInjectionAssumedRole(t *testing.T) {
+	cfg := aws.NewConfig().
+		WithRegion("eu-central-1").
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")).
+		WithMaxRetries(0)
+	sc := &sessionConfig{
+		provider: session.Must(session.NewSession()),
+		config:   cfg,
+		s3Config: cfg,
+	}
+	a := newAdapter("controller", sc.clients(nil))
+	a.session = sc
+	a.manifest = &manifest{clusterID: "cluster", vpcID: "vpc-1"}
+	a = a.WithFaultInjection(&FaultInjection{ErrorRate: 1})
+
+	roleAdapter, err := a.AssumeRole("arn:aws:iam::123456789012:role/load-balancers")
+	require.NoError(t, err)
+
+	// the credentials of the role are requested with the first request,
+	// which fails with the injected error before reaching STS
+	_, err = roleAdapter.cloudformation.DescribeStacks(&cloudformation.DescribeStacksInput{})
+	require.Error(t, err)
+	assert.Equal(t, InjectedErrorCode, err.(awserr.Error).Code())
+
+	// the clients of the role don't send requests anymore
+	for _, c := range []interface{}{roleAdapter.cloudformation, roleAdapter.elbv2, roleAdapter.route53} {
+		assert.False(t, sdkClient(c).Handlers.Send.Swap(corehandlers.SendHandler.Name, corehandlers.SendHandler))
+	}
+}
+
+func TestFaultInjectionEnabled(t *testing.T) {
+	var faults *FaultInjection
+	assert.False(t, faults.Enabled())
+	assert.False(t, (&FaultInjection{}).Enabled())
+	assert.True(t, (&FaultInjection{ThrottleRate: 0.1}).Enabled())
+}
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxInstanceDraining is the longest time instances deregistered from
+// target groups are considered draining, the maximum deregistration delay.
+const maxInstanceDraining = time.Hour
+
+// drainingInstance is an instance deregistered from target groups whose
+// connections may still be draining.
+type drainingInstance struct {
+	since           time.Time
+	targetGroupARNs []string
+}
+
+// DrainingInstances returns the sorted IDs of the instances deregistered from
+// the target groups, e.g. after they were terminated, whose connections are
+// still draining.
+func (a *Adapter) DrainingInstances() []string {
+	instances := make([]string, 0, len(a.drainingInstances))
+	for id := range a.drainingInstances {
+		instances = append(instances, id)
+	}
+	sort.Strings(instances)
+	return instances
+}
+
+// startDraining records the instances deregistered from the target groups.
+func (a *Adapter) startDraining(instances []string, targetGroupARNs []string) {
+	if a.drainingInstances == nil {
+		a.drainingInstances = make(map[string]*drainingInstance)
+	}
+	now := a.clock.Now()
+	for _, id := range instances {
+		a.drainingInstances[id] = &drainingInstance{since: now, targetGroupARNs: targetGroupARNs}
+	}
+}
+
+// updateDrainingInstances checks the health of the draining instances in
+// their target groups and forgets the ones which aren't draining in any of
+// them anymore, or which exceeded maxInstanceDraining. Instances whose health
+// can't be described are kept until the next check.
+func (a *Adapter) updateDrainingInstances() {
+	if len(a.drainingInstances) == 0 {
+		return
+	}
+
+	targets := make(map[string][]string)
+	for id, instance := range a.drainingInstances {
+		for _, arn := range instance.targetGroupARNs {
+			targets[arn] = append(targets[arn], id)
+		}
+	}
+
+	draining := make(map[string]bool)
+	for arn, ids := range targets {
+		descriptions := make([]*elbv2.TargetDescription, 0, len(ids))
+		for _, id := range ids {
+			descriptions = append(descriptions, &elbv2.TargetDescription{Id: aws.String(id)})
+		}
+		resp, err := a.elbv2.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(arn),
+			Targets:        descriptions,
+		})
+		if err != nil {
+			log.Warnf("Failed to describe the draining instances %q in target group %s: %v", ids, arn, err)
+			for _, id := range ids {
+				draining[id] = true
+			}
+			continue
+		}
+		for _, health := range resp.TargetHealthDescriptions {
+			if aws.StringValue(health.TargetHealth.State) == elbv2.TargetHealthStateEnumDraining {
+				draining[aws.StringValue(health.Target.Id)] = true
+			}
+		}
+	}
+
+	now := a.clock.Now()
+	pruned := make(map[string]*drainingInstance, len(a.drainingInstances))
+	for id, instance := range a.drainingInstances {
+		switch {
+		case !draining[id]:
+			log.Infof("Instance %s drained from the target groups after %s", id, now.Sub(instance.since))
+		case now.Sub(instance.since) >= maxInstanceDraining:
+			log.Warnf("Instance %s still draining from the target groups after %s, not waiting anymore", id, maxInstanceDraining)
+		default:
+			pruned[id] = instance
+		}
+	}
+	a.drainingInstances = pruned
+}
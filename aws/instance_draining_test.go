@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/require"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	return make(chan time.Time)
+}
+
+func targetHealth(states map[string]string) *elbv2.DescribeTargetHealthOutput {
+	out := &elbv2.DescribeTargetHealthOutput{}
+	for id, state := range states {
+		out.TargetHealthDescriptions = append(out.TargetHealthDescriptions, &elbv2.TargetHealthDescription{
+			Target:       &elbv2.TargetDescription{Id: aws.String(id)},
+			TargetHealth: &elbv2.TargetHealth{State: aws.String(state)},
+		})
+	}
+	return out
+}
+
+func TestUpdateDrainingInstances(t *testing.T) {
+	clock := &testClock{now: time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)}
+	elbv2Client := &mockElbv2Client{}
+	a := &Adapter{elbv2: elbv2Client, clock: clock}
+
+	a.startDraining([]string{"i-1", "i-2"}, []string{"arn:tg"})
+	require.Equal(t, []string{"i-1", "i-2"}, a.DrainingInstances())
+
+	// i-2 drained
+	elbv2Client.outputs.describeTargetHealth = R(targetHealth(map[string]string{
+		"i-1": elbv2.TargetHealthStateEnumDraining,
+		"i-2": elbv2.TargetHealthStateEnumUnused,
+	}), nil)
+	a.updateDrainingInstances()
+	require.Equal(t, []string{"i-1"}, a.DrainingInstances())
+	require.Len(t, elbv2Client.dthinputs, 1)
+	require.Equal(t, "arn:tg", aws.StringValue(elbv2Client.dthinputs[0].TargetGroupArn))
+
+	// instances are kept while their health can't be described
+	elbv2Client.outputs.describeTargetHealth = R(nil, errors.New("failed"))
+	a.updateDrainingInstances()
+	require.Equal(t, []string{"i-1"}, a.DrainingInstances())
+
+	// instances draining for too long are given up on
+	elbv2Client.outputs.describeTargetHealth = R(targetHealth(map[string]string{
+		"i-1": elbv2.TargetHealthStateEnumDraining,
+	}), nil)
+	a.updateDrainingInstances()
+	require.Equal(t, []string{"i-1"}, a.DrainingInstances())
+
+	clock.now = clock.now.Add(maxInstanceDraining)
+	a.updateDrainingInstances()
+	require.Empty(t, a.DrainingInstances())
+}
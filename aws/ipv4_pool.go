@@ -0,0 +1,43 @@
+package aws
+
+import (
+	"regexp"
+)
+
+var (
+	ipamPoolIDPattern       = regexp.MustCompile(`^ipam-pool-[0-9a-f]+$`)
+	publicIPv4PoolIDPattern = regexp.MustCompile(`^ipv4pool-ec2-[0-9a-f]+$`)
+)
+
+// IsIPAMPoolID returns true if id is the ID of an IPv4 IPAM pool, e.g.
+// ipam-pool-0123456789abcdef0, which internet-facing application load
+// balancers can take their addresses from.
+func IsIPAMPoolID(id string) bool {
+	return ipamPoolIDPattern.MatchString(id)
+}
+
+// IsPublicIPv4PoolID returns true if id is the ID of a public IPv4 pool of
+// addresses brought to AWS (BYOIP), e.g. ipv4pool-ec2-0123456789abcdef0,
+// which the Elastic IPs allocated for internet-facing network load balancers
+// can be taken from.
+func IsPublicIPv4PoolID(id string) bool {
+	return publicIPv4PoolIDPattern.MatchString(id)
+}
+
+// IsValidIPv4Pool returns true if pool is the ID of an IPAM pool or of a
+// public IPv4 pool.
+func IsValidIPv4Pool(pool string) bool {
+	return IsIPAMPoolID(pool) || IsPublicIPv4PoolID(pool)
+}
+
+// hasIPAMPool returns true if the application load balancer of the stack
+// takes its addresses from an IPAM pool.
+func (spec *stackSpec) hasIPAMPool() bool {
+	return spec.loadbalancerType == LoadBalancerTypeApplication && IsIPAMPoolID(spec.ipv4Pool)
+}
+
+// hasPublicIPv4Pool returns true if the Elastic IPs allocated for the
+// network load balancer of the stack are taken from a public IPv4 pool.
+func (spec *stackSpec) hasPublicIPv4Pool() bool {
+	return spec.loadbalancerType == LoadBalancerTypeNetwork && IsPublicIPv4PoolID(spec.ipv4Pool) && autoAllocateElasticIPs(spec.elasticIPs)
+}
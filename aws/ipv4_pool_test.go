@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidIPv4Pool(t *testing.T) {
+	assert.True(t, IsIPAMPoolID("ipam-pool-0123456789abcdef0"))
+	assert.False(t, IsIPAMPoolID("ipv4pool-ec2-0123456789abcdef0"))
+	assert.True(t, IsPublicIPv4PoolID("ipv4pool-ec2-0123456789abcdef0"))
+	assert.False(t, IsPublicIPv4PoolID("ipam-pool-0123456789abcdef0"))
+	assert.True(t, IsValidIPv4Pool("ipam-pool-0123456789abcdef0"))
+	assert.True(t, IsValidIPv4Pool("ipv4pool-ec2-0123456789abcdef0"))
+	assert.False(t, IsValidIPv4Pool("ipam-pool-"))
+	assert.False(t, IsValidIPv4Pool("ipv4pool-coip-0123456789abcdef0"))
+}
+
+func TestGenerateTemplateIPv4Pool(t *testing.T) {
+	type resource struct {
+		Properties map[string]interface{}
+	}
+	resources := func(spec *stackSpec) map[string]resource {
+		generated, err := generateTemplate(spec)
+		require.NoError(t, err)
+
+		var template struct {
+			Parameters map[string]interface{}
+			Resources  map[string]resource
+		}
+		require.NoError(t, json.Unmarshal([]byte(generated), &template))
+		assert.Contains(t, template.Parameters, parameterIPv4PoolParameter)
+		return template.Resources
+	}
+	ref := map[string]interface{}{"Ref": parameterIPv4PoolParameter}
+
+	alb := resources(&stackSpec{
+		loadbalancerType: LoadBalancerTypeApplication,
+		ipv4Pool:         "ipam-pool-0123456789abcdef0",
+	})["LB"]
+	assert.Equal(t, ref, alb.Properties["Ipv4IpamPoolId"])
+	assert.Contains(t, alb.Properties, "LoadBalancerAttributes")
+
+	nlb := resources(&stackSpec{
+		loadbalancerType: LoadBalancerTypeNetwork,
+		subnets:          []string{"subnet-a"},
+		elasticIPs:       []string{AutoAllocateElasticIPs},
+		ipv4Pool:         "ipv4pool-ec2-0123456789abcdef0",
+	})
+	assert.NotContains(t, nlb["LB"].Properties, "Ipv4IpamPoolId")
+	assert.Equal(t, ref, nlb["EIP0"].Properties["PublicIpv4Pool"])
+}
@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	cloudformation "github.com/mweagle/go-cloudformation"
+)
+
+// IsLambdaFunctionARN returns true if the ARN is the one of a Lambda
+// function, optionally with a version or alias.
+func IsLambdaFunctionARN(s string) bool {
+	a, err := arn.Parse(s)
+	return err == nil && a.Service == "lambda" && len(a.Resource) > len("function:") && a.Resource[:len("function:")] == "function:"
+}
+
+// lambdaTargetGroupName returns the name of the resource of the target group
+// of the Lambda function.
+func lambdaTargetGroupName(functionARN string) string {
+	hash := sha256.Sum256([]byte(functionARN))
+	return fmt.Sprintf("LambdaTG%x", hash[:8])
+}
+
+// lambdaFunctionARNs returns the sorted ARNs of the Lambda functions
+// requests are forwarded to, by the listeners or their rules.
+func lambdaFunctionARNs(spec *stackSpec) []string {
+	functions := make(map[string]bool)
+	if spec.lambdaTarget != "" {
+		functions[spec.lambdaTarget] = true
+	}
+	for _, rule := range spec.listenerRules {
+		if rule.Action == ListenerRuleActionLambda {
+			functions[rule.FunctionARN] = true
+		}
+	}
+
+	result := make([]string, 0, len(functions))
+	for function := range functions {
+		result = append(result, function)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// lambdaTargetGroupAWSName returns the name of the target group of the
+// Lambda function in AWS. It's derived from the stack name, so it's unique
+// in the account, and fits the 32 characters allowed for target groups.
+func lambdaTargetGroupAWSName(stackName, functionARN string) string {
+	hash := sha256.Sum256([]byte(stackName + "/" + lambdaTargetGroupName(functionARN)))
+	return fmt.Sprintf("lambda-%x", hash[:12])
+}
+
+// addLambdaTargetGroups adds a target group for each of the Lambda functions
+// and the permission of the load balancer to invoke it. The permission is
+// required before the function is registered as target of the target group,
+// so it's restricted to the name of the target group known in advance.
+func addLambdaTargetGroups(template *cloudformation.Template, stackName string, functionARNs []string, tags ResourceTags) {
+	for _, functionARN := range functionARNs {
+		targetGroupName := lambdaTargetGroupName(functionARN)
+		permissionName := targetGroupName + "Permission"
+		awsName := lambdaTargetGroupAWSName(stackName, functionARN)
+
+		template.AddResource(permissionName, &cloudformation.LambdaPermission{
+			Action:       cloudformation.String("lambda:InvokeFunction"),
+			FunctionName: cloudformation.String(functionARN),
+			Principal:    cloudformation.String("elasticloadbalancing.amazonaws.com"),
+			SourceArn: cloudformation.Join("",
+				cloudformation.String("arn:"),
+				cloudformation.Ref("AWS::Partition"),
+				cloudformation.String(":elasticloadbalancing:"),
+				cloudformation.Ref("AWS::Region"),
+				cloudformation.String(":"),
+				cloudformation.Ref("AWS::AccountId"),
+				cloudformation.String(":targetgroup/"+awsName+"/*"),
+			),
+		})
+
+		tg := template.AddResource(targetGroupName, &cloudformation.ElasticLoadBalancingV2TargetGroup{
+			Name:       cloudformation.String(awsName),
+			TargetType: cloudformation.String("lambda"),
+			Targets: &cloudformation.ElasticLoadBalancingV2TargetGroupTargetDescriptionList{
+				{ID: cloudformation.String(functionARN)},
+			},
+			Tags: tags.templateTags(),
+		})
+		tg.DependsOn = []string{permissionName}
+	}
+}
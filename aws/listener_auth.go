@@ -0,0 +1,230 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	cloudformation "github.com/mweagle/go-cloudformation"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ListenerAuthenticationOIDC    = "oidc"
+	ListenerAuthenticationCognito = "cognito"
+
+	MinAuthenticationSessionTimeout = 1
+	MaxAuthenticationSessionTimeout = 7 * 24 * 60 * 60
+
+	authenticateOIDCActionType    = "authenticate-oidc"
+	authenticateCognitoActionType = "authenticate-cognito"
+)
+
+// ListenerAuthentication configures the HTTPS listener of an application
+// load balancer to authenticate the users with an OIDC identity provider or
+// a Cognito user pool before forwarding their requests.
+//
+// The client secret of an OIDC identity provider isn't part of the
+// configuration. ClientSecretRef is the name or the ARN of the Secrets
+// Manager secret storing it, which is resolved by CloudFormation, with
+// ClientSecretKey the JSON key of the secret value if it's a JSON object.
+type ListenerAuthentication struct {
+	Type string `json:"type"`
+
+	Issuer                string `json:"issuer,omitempty"`
+	AuthorizationEndpoint string `json:"authorizationEndpoint,omitempty"`
+	TokenEndpoint         string `json:"tokenEndpoint,omitempty"`
+	UserInfoEndpoint      string `json:"userInfoEndpoint,omitempty"`
+	ClientID              string `json:"clientID,omitempty"`
+	ClientSecretRef       string `json:"clientSecretRef,omitempty"`
+	ClientSecretKey       string `json:"clientSecretKey,omitempty"`
+
+	UserPoolARN      string `json:"userPoolARN,omitempty"`
+	UserPoolClientID string `json:"userPoolClientID,omitempty"`
+	UserPoolDomain   string `json:"userPoolDomain,omitempty"`
+
+	Scope                    string `json:"scope,omitempty"`
+	SessionTimeout           int64  `json:"sessionTimeout,omitempty"`
+	OnUnauthenticatedRequest string `json:"onUnauthenticatedRequest,omitempty"`
+}
+
+// NewListenerAuthenticationFromJSON parses and validates the JSON
+// configuration of the listener authentication.
+func NewListenerAuthenticationFromJSON(b []byte) (*ListenerAuthentication, error) {
+	auth := &ListenerAuthentication{}
+
+	if err := json.Unmarshal(b, auth); err != nil {
+		return nil, err
+	}
+
+	if err := auth.validate(); err != nil {
+		return nil, fmt.Errorf("invalid authentication: %v", err)
+	}
+
+	return auth, nil
+}
+
+func (a *ListenerAuthentication) validate() error {
+	switch a.Type {
+	case ListenerAuthenticationOIDC:
+		for name, endpoint := range map[string]string{
+			"issuer":                a.Issuer,
+			"authorizationEndpoint": a.AuthorizationEndpoint,
+			"tokenEndpoint":         a.TokenEndpoint,
+			"userInfoEndpoint":      a.UserInfoEndpoint,
+		} {
+			u, err := url.Parse(endpoint)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("%s must be an HTTPS URL", name)
+			}
+		}
+		if a.ClientID == "" {
+			return fmt.Errorf("clientID is required")
+		}
+		if a.ClientSecretRef == "" {
+			return fmt.Errorf("clientSecretRef is required")
+		}
+	case ListenerAuthenticationCognito:
+		if userPool, err := arn.Parse(a.UserPoolARN); err != nil || userPool.Service != "cognito-idp" {
+			return fmt.Errorf("invalid user pool ARN %q", a.UserPoolARN)
+		}
+		if a.UserPoolClientID == "" {
+			return fmt.Errorf("userPoolClientID is required")
+		}
+		if a.UserPoolDomain == "" {
+			return fmt.Errorf("userPoolDomain is required")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", a.Type)
+	}
+
+	if a.SessionTimeout != 0 && (a.SessionTimeout < MinAuthenticationSessionTimeout || a.SessionTimeout > MaxAuthenticationSessionTimeout) {
+		return fmt.Errorf("sessionTimeout must be between %d and %d seconds", MinAuthenticationSessionTimeout, MaxAuthenticationSessionTimeout)
+	}
+
+	switch a.OnUnauthenticatedRequest {
+	case "", "deny", "allow", "authenticate":
+	default:
+		return fmt.Errorf("onUnauthenticatedRequest must be one of deny, allow, authenticate")
+	}
+
+	return nil
+}
+
+// Hash computes a hash of the ListenerAuthentication which can be used to
+// detect changes between two versions. The hash string will be empty if a
+// is nil or there was an error while encoding.
+func (a *ListenerAuthentication) Hash() string {
+	if a == nil {
+		return ""
+	}
+
+	buf, err := json.Marshal(a)
+	if err != nil {
+		log.Errorf("failed to marshal listener authentication: %v", err)
+		return ""
+	}
+
+	hash := sha256.New()
+	hash.Write(buf)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// clientSecret returns a dynamic reference to the client secret, which
+// CloudFormation resolves without the secret being part of the template.
+func (a *ListenerAuthentication) clientSecret() string {
+	if a.ClientSecretKey == "" {
+		return fmt.Sprintf("{{resolve:secretsmanager:%s}}", a.ClientSecretRef)
+	}
+	return fmt.Sprintf("{{resolve:secretsmanager:%s:SecretString:%s}}", a.ClientSecretRef, a.ClientSecretKey)
+}
+
+func optionalString(s string) *cloudformation.StringExpr {
+	if s == "" {
+		return nil
+	}
+	return cloudformation.String(s)
+}
+
+// listenerAction returns the authentication action of the default actions of
+// the listener.
+func (a *ListenerAuthentication) listenerAction() cloudformation.ElasticLoadBalancingV2ListenerAction {
+	var sessionTimeout *cloudformation.StringExpr
+	if a.SessionTimeout != 0 {
+		sessionTimeout = cloudformation.String(strconv.FormatInt(a.SessionTimeout, 10))
+	}
+
+	action := cloudformation.ElasticLoadBalancingV2ListenerAction{
+		Order: cloudformation.Integer(1),
+	}
+	switch a.Type {
+	case ListenerAuthenticationOIDC:
+		action.Type = cloudformation.String(authenticateOIDCActionType)
+		action.AuthenticateOidcConfig = &cloudformation.ElasticLoadBalancingV2ListenerAuthenticateOidcConfig{
+			Issuer:                   cloudformation.String(a.Issuer),
+			AuthorizationEndpoint:    cloudformation.String(a.AuthorizationEndpoint),
+			TokenEndpoint:            cloudformation.String(a.TokenEndpoint),
+			UserInfoEndpoint:         cloudformation.String(a.UserInfoEndpoint),
+			ClientID:                 cloudformation.String(a.ClientID),
+			ClientSecret:             cloudformation.String(a.clientSecret()),
+			Scope:                    optionalString(a.Scope),
+			SessionTimeout:           sessionTimeout,
+			OnUnauthenticatedRequest: optionalString(a.OnUnauthenticatedRequest),
+		}
+	case ListenerAuthenticationCognito:
+		action.Type = cloudformation.String(authenticateCognitoActionType)
+		action.AuthenticateCognitoConfig = &cloudformation.ElasticLoadBalancingV2ListenerAuthenticateCognitoConfig{
+			UserPoolArn:              cloudformation.String(a.UserPoolARN),
+			UserPoolClientID:         cloudformation.String(a.UserPoolClientID),
+			UserPoolDomain:           cloudformation.String(a.UserPoolDomain),
+			Scope:                    optionalString(a.Scope),
+			SessionTimeout:           sessionTimeout,
+			OnUnauthenticatedRequest: optionalString(a.OnUnauthenticatedRequest),
+		}
+	}
+	return action
+}
+
+// listenerRuleAction returns the authentication action of the listener rules
+// forwarding requests.
+func (a *ListenerAuthentication) listenerRuleAction() cloudformation.ElasticLoadBalancingV2ListenerRuleAction {
+	var sessionTimeout *cloudformation.IntegerExpr
+	if a.SessionTimeout != 0 {
+		sessionTimeout = cloudformation.Integer(a.SessionTimeout)
+	}
+
+	action := cloudformation.ElasticLoadBalancingV2ListenerRuleAction{
+		Order: cloudformation.Integer(1),
+	}
+	switch a.Type {
+	case ListenerAuthenticationOIDC:
+		action.Type = cloudformation.String(authenticateOIDCActionType)
+		action.AuthenticateOidcConfig = &cloudformation.ElasticLoadBalancingV2ListenerRuleAuthenticateOidcConfig{
+			Issuer:                   cloudformation.String(a.Issuer),
+			AuthorizationEndpoint:    cloudformation.String(a.AuthorizationEndpoint),
+			TokenEndpoint:            cloudformation.String(a.TokenEndpoint),
+			UserInfoEndpoint:         cloudformation.String(a.UserInfoEndpoint),
+			ClientID:                 cloudformation.String(a.ClientID),
+			ClientSecret:             cloudformation.String(a.clientSecret()),
+			Scope:                    optionalString(a.Scope),
+			SessionTimeout:           sessionTimeout,
+			OnUnauthenticatedRequest: optionalString(a.OnUnauthenticatedRequest),
+		}
+	case ListenerAuthenticationCognito:
+		action.Type = cloudformation.String(authenticateCognitoActionType)
+		action.AuthenticateCognitoConfig = &cloudformation.ElasticLoadBalancingV2ListenerRuleAuthenticateCognitoConfig{
+			UserPoolArn:              cloudformation.String(a.UserPoolARN),
+			UserPoolClientID:         cloudformation.String(a.UserPoolClientID),
+			UserPoolDomain:           cloudformation.String(a.UserPoolDomain),
+			Scope:                    optionalString(a.Scope),
+			SessionTimeout:           sessionTimeout,
+			OnUnauthenticatedRequest: optionalString(a.OnUnauthenticatedRequest),
+		}
+	}
+	return action
+}
@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"testing"
+
+	cloudformation "github.com/mweagle/go-cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOIDCAuthentication = `{
+	"type": "oidc",
+	"issuer": "https://idp.example.org",
+	"authorizationEndpoint": "https://idp.example.org/authorize",
+	"tokenEndpoint": "https://idp.example.org/token",
+	"userInfoEndpoint": "https://idp.example.org/userinfo",
+	"clientID": "internal-tools",
+	"clientSecretRef": "internal-tools/oidc",
+	"clientSecretKey": "clientSecret"
+}`
+
+func TestNewListenerAuthenticationFromJSON(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		auth    string
+		want    *ListenerAuthentication
+		wantErr bool
+	}{
+		{
+			name: "OIDC",
+			auth: testOIDCAuthentication,
+			want: &ListenerAuthentication{
+				Type:                  ListenerAuthenticationOIDC,
+				Issuer:                "https://idp.example.org",
+				AuthorizationEndpoint: "https://idp.example.org/authorize",
+				TokenEndpoint:         "https://idp.example.org/token",
+				UserInfoEndpoint:      "https://idp.example.org/userinfo",
+				ClientID:              "internal-tools",
+				ClientSecretRef:       "internal-tools/oidc",
+				ClientSecretKey:       "clientSecret",
+			},
+		},
+		{
+			name: "Cognito",
+			auth: `{"type": "cognito", "userPoolARN": "arn:aws:cognito-idp:eu-central-1:123456789012:userpool/eu-central-1_abc", "userPoolClientID": "client", "userPoolDomain": "internal", "sessionTimeout": 3600, "onUnauthenticatedRequest": "deny"}`,
+			want: &ListenerAuthentication{
+				Type:                     ListenerAuthenticationCognito,
+				UserPoolARN:              "arn:aws:cognito-idp:eu-central-1:123456789012:userpool/eu-central-1_abc",
+				UserPoolClientID:         "client",
+				UserPoolDomain:           "internal",
+				SessionTimeout:           3600,
+				OnUnauthenticatedRequest: "deny",
+			},
+		},
+		{
+			name:    "invalid JSON",
+			auth:    `{`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			auth:    `{"type": "saml"}`,
+			wantErr: true,
+		},
+		{
+			name:    "OIDC endpoint without HTTPS",
+			auth:    `{"type": "oidc", "issuer": "http://idp.example.org", "authorizationEndpoint": "https://idp.example.org/authorize", "tokenEndpoint": "https://idp.example.org/token", "userInfoEndpoint": "https://idp.example.org/userinfo", "clientID": "id", "clientSecretRef": "secret"}`,
+			wantErr: true,
+		},
+		{
+			name:    "OIDC without client secret",
+			auth:    `{"type": "oidc", "issuer": "https://idp.example.org", "authorizationEndpoint": "https://idp.example.org/authorize", "tokenEndpoint": "https://idp.example.org/token", "userInfoEndpoint": "https://idp.example.org/userinfo", "clientID": "id"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid user pool ARN",
+			auth:    `{"type": "cognito", "userPoolARN": "arn:aws:s3:::bucket", "userPoolClientID": "client", "userPoolDomain": "internal"}`,
+			wantErr: true,
+		},
+		{
+			name:    "session timeout out of range",
+			auth:    `{"type": "cognito", "userPoolARN": "arn:aws:cognito-idp:eu-central-1:123456789012:userpool/eu-central-1_abc", "userPoolClientID": "client", "userPoolDomain": "internal", "sessionTimeout": 604801}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown unauthenticated request behavior",
+			auth:    `{"type": "cognito", "userPoolARN": "arn:aws:cognito-idp:eu-central-1:123456789012:userpool/eu-central-1_abc", "userPoolClientID": "client", "userPoolDomain": "internal", "onUnauthenticatedRequest": "redirect"}`,
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			auth, err := NewListenerAuthenticationFromJSON([]byte(test.auth))
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, auth)
+		})
+	}
+}
+
+func TestListenerAuthenticationHash(t *testing.T) {
+	assert.Equal(t, "", (*ListenerAuthentication)(nil).Hash())
+
+	auth, err := NewListenerAuthenticationFromJSON([]byte(testOIDCAuthentication))
+	require.NoError(t, err)
+	assert.NotEmpty(t, auth.Hash())
+
+	changed := *auth
+	changed.ClientID = "other"
+	assert.NotEqual(t, auth.Hash(), changed.Hash())
+}
+
+func TestListenerAuthenticationListenerAction(t *testing.T) {
+	auth, err := NewListenerAuthenticationFromJSON([]byte(testOIDCAuthentication))
+	require.NoError(t, err)
+
+	action := auth.listenerAction()
+	assert.Equal(t, cloudformation.String("authenticate-oidc"), action.Type)
+	assert.Equal(t, cloudformation.Integer(1), action.Order)
+	require.NotNil(t, action.AuthenticateOidcConfig)
+	assert.Equal(t, cloudformation.String("{{resolve:secretsmanager:internal-tools/oidc:SecretString:clientSecret}}"), action.AuthenticateOidcConfig.ClientSecret)
+	assert.Nil(t, action.AuthenticateOidcConfig.SessionTimeout)
+
+	auth.ClientSecretKey = ""
+	assert.Equal(t, "{{resolve:secretsmanager:internal-tools/oidc}}", auth.clientSecret())
+}
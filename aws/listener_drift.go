@@ -0,0 +1,265 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+)
+
+// ListenerDrift lists the differences between the HTTPS listener and the
+// attributes of the load balancer of a stack and the stack, e.g. after
+// changes in the AWS console. CloudFormation doesn't revert them until the
+// resources are changed by a stack update.
+type ListenerDrift struct {
+	ListenerARN string
+	// MissingListener is true if the load balancer has no HTTPS listener.
+	MissingListener bool
+	// SSLPolicy is the policy of the listener if it's not the one of the
+	// stack.
+	SSLPolicy string
+	// DefaultCertificate is the default certificate of the listener if
+	// it's not the one of the stack.
+	DefaultCertificate  string
+	MissingCertificates []string
+	UnknownCertificates []string
+	// Attributes are the values of the attributes of the load balancer
+	// which differ from the ones of the stack.
+	Attributes Attributes
+
+	defaultCertificate string
+	attributes         Attributes
+}
+
+// Empty returns true if the load balancer doesn't differ from the stack.
+func (d *ListenerDrift) Empty() bool {
+	return !d.MissingListener &&
+		d.SSLPolicy == "" &&
+		d.DefaultCertificate == "" &&
+		len(d.MissingCertificates) == 0 &&
+		len(d.UnknownCertificates) == 0 &&
+		len(d.Attributes) == 0
+}
+
+func (d *ListenerDrift) String() string {
+	if d.MissingListener {
+		return "HTTPS listener missing"
+	}
+
+	var diffs []string
+	if d.SSLPolicy != "" {
+		diffs = append(diffs, "SSL policy "+d.SSLPolicy)
+	}
+	if d.DefaultCertificate != "" {
+		diffs = append(diffs, "default certificate "+d.DefaultCertificate)
+	}
+	if len(d.MissingCertificates) > 0 {
+		diffs = append(diffs, fmt.Sprintf("missing certificates %v", d.MissingCertificates))
+	}
+	if len(d.UnknownCertificates) > 0 {
+		diffs = append(diffs, fmt.Sprintf("unknown certificates %v", d.UnknownCertificates))
+	}
+	for _, key := range d.Attributes.sortedKeys(nil) {
+		diffs = append(diffs, fmt.Sprintf("attribute %s=%s", key, d.Attributes[key]))
+	}
+	return strings.Join(diffs, ", ")
+}
+
+// DetectListenerDrift compares the HTTPS listener, its certificates and the
+// attributes of the load balancer of the stack with the stack. Attributes
+// aren't compared if the stack sets attributes from annotations.
+func (a *Adapter) DetectListenerDrift(stack *Stack) (*ListenerDrift, error) {
+	return detectListenerDrift(a.elbv2, stack, a.declaredAttributes(stack))
+}
+
+// RevertListenerDrift changes the listener and the load balancer back to
+// the state of the stack. A missing listener can't be reverted.
+func (a *Adapter) RevertListenerDrift(stack *Stack, drift *ListenerDrift) error {
+	return revertListenerDrift(a.elbv2, stack, drift)
+}
+
+// declaredAttributes returns the attributes of the load balancer set by the
+// template of the stack which are compared with the actual ones.
+func (a *Adapter) declaredAttributes(stack *Stack) Attributes {
+	if stack.LoadBalancerType != LoadBalancerTypeApplication || stack.AttributesHash != "" {
+		return nil
+	}
+
+	attributes := Attributes{
+		"idle_timeout.timeout_seconds": fmt.Sprintf("%d", uint(a.idleConnectionTimeout.Seconds())),
+		"routing.http2.enabled":        fmt.Sprintf("%t", stack.HTTP2),
+	}
+	if stack.ClientKeepAlive > 0 {
+		attributes["client_keep_alive.seconds"] = fmt.Sprintf("%d", int64(stack.ClientKeepAlive.Seconds()))
+	}
+	return attributes
+}
+
+func detectListenerDrift(svc elbv2iface.ELBV2API, stack *Stack, attributes Attributes) (*ListenerDrift, error) {
+	drift := &ListenerDrift{}
+
+	listeners, err := svc.DescribeListeners(&elbv2.DescribeListenersInput{
+		LoadBalancerArn: aws.String(stack.LoadBalancerARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the listeners of %s: %v", stack.LoadBalancerARN, err)
+	}
+	var listener *elbv2.Listener
+	for _, l := range listeners.Listeners {
+		if aws.Int64Value(l.Port) == 443 {
+			listener = l
+			break
+		}
+	}
+	if listener == nil {
+		drift.MissingListener = true
+		return drift, nil
+	}
+	drift.ListenerARN = aws.StringValue(listener.ListenerArn)
+
+	if policy := aws.StringValue(listener.SslPolicy); policy != stack.SSLPolicy {
+		drift.SSLPolicy = policy
+	}
+
+	declared := make([]string, 0, len(stack.CertificateARNs))
+	for arn := range stack.CertificateARNs {
+		declared = append(declared, arn)
+	}
+	sort.Strings(declared)
+
+	if len(declared) > 0 {
+		drift.defaultCertificate = defaultCertificateARN(declared, stack.DefaultCertificateARN)
+	}
+
+	actual := make(map[string]bool)
+	input := &elbv2.DescribeListenerCertificatesInput{
+		ListenerArn: listener.ListenerArn,
+	}
+	for {
+		page, err := svc.DescribeListenerCertificates(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe the certificates of listener %s: %v", drift.ListenerARN, err)
+		}
+		for _, cert := range page.Certificates {
+			arn := aws.StringValue(cert.CertificateArn)
+			if aws.BoolValue(cert.IsDefault) {
+				if arn != drift.defaultCertificate {
+					drift.DefaultCertificate = arn
+				}
+				continue
+			}
+			actual[arn] = true
+		}
+		if page.NextMarker == nil {
+			break
+		}
+		input.Marker = page.NextMarker
+	}
+
+	for _, arn := range declared {
+		// the default certificate is listed again by the template, but
+		// not necessarily by the API
+		if !actual[arn] && arn != drift.defaultCertificate {
+			drift.MissingCertificates = append(drift.MissingCertificates, arn)
+		}
+	}
+	for arn := range actual {
+		if _, ok := stack.CertificateARNs[arn]; !ok {
+			drift.UnknownCertificates = append(drift.UnknownCertificates, arn)
+		}
+	}
+	sort.Strings(drift.UnknownCertificates)
+
+	if len(attributes) == 0 {
+		return drift, nil
+	}
+	resp, err := svc.DescribeLoadBalancerAttributes(&elbv2.DescribeLoadBalancerAttributesInput{
+		LoadBalancerArn: aws.String(stack.LoadBalancerARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the attributes of %s: %v", stack.LoadBalancerARN, err)
+	}
+	for _, attribute := range resp.Attributes {
+		key, value := aws.StringValue(attribute.Key), aws.StringValue(attribute.Value)
+		if declared, ok := attributes[key]; ok && value != declared {
+			if drift.Attributes == nil {
+				drift.Attributes = make(Attributes)
+			}
+			drift.Attributes[key] = value
+		}
+	}
+	drift.attributes = attributes
+
+	return drift, nil
+}
+
+func revertListenerDrift(svc elbv2iface.ELBV2API, stack *Stack, drift *ListenerDrift) error {
+	if drift.MissingListener {
+		return fmt.Errorf("the HTTPS listener of %s is missing", stack.LoadBalancerARN)
+	}
+
+	if drift.SSLPolicy != "" || drift.DefaultCertificate != "" {
+		input := &elbv2.ModifyListenerInput{
+			ListenerArn: aws.String(drift.ListenerARN),
+		}
+		if drift.SSLPolicy != "" {
+			input.SslPolicy = aws.String(stack.SSLPolicy)
+		}
+		if drift.DefaultCertificate != "" {
+			input.Certificates = []*elbv2.Certificate{{CertificateArn: aws.String(drift.defaultCertificate)}}
+		}
+		if _, err := svc.ModifyListener(input); err != nil {
+			return fmt.Errorf("failed to modify listener %s: %v", drift.ListenerARN, err)
+		}
+	}
+
+	if len(drift.MissingCertificates) > 0 {
+		_, err := svc.AddListenerCertificates(&elbv2.AddListenerCertificatesInput{
+			ListenerArn:  aws.String(drift.ListenerARN),
+			Certificates: listenerCertificates(drift.MissingCertificates),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add certificates to listener %s: %v", drift.ListenerARN, err)
+		}
+	}
+
+	if len(drift.UnknownCertificates) > 0 {
+		_, err := svc.RemoveListenerCertificates(&elbv2.RemoveListenerCertificatesInput{
+			ListenerArn:  aws.String(drift.ListenerARN),
+			Certificates: listenerCertificates(drift.UnknownCertificates),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove certificates from listener %s: %v", drift.ListenerARN, err)
+		}
+	}
+
+	if len(drift.Attributes) > 0 {
+		attributes := make([]*elbv2.LoadBalancerAttribute, 0, len(drift.Attributes))
+		for _, key := range drift.Attributes.sortedKeys(nil) {
+			attributes = append(attributes, &elbv2.LoadBalancerAttribute{
+				Key:   aws.String(key),
+				Value: aws.String(drift.attributes[key]),
+			})
+		}
+		_, err := svc.ModifyLoadBalancerAttributes(&elbv2.ModifyLoadBalancerAttributesInput{
+			LoadBalancerArn: aws.String(stack.LoadBalancerARN),
+			Attributes:      attributes,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to modify the attributes of %s: %v", stack.LoadBalancerARN, err)
+		}
+	}
+
+	return nil
+}
+
+func listenerCertificates(arns []string) []*elbv2.Certificate {
+	certificates := make([]*elbv2.Certificate, 0, len(arns))
+	for _, arn := range arns {
+		certificates = append(certificates, &elbv2.Certificate{CertificateArn: aws.String(arn)})
+	}
+	return certificates
+}
@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockListenerELBV2 struct {
+	elbv2iface.ELBV2API
+	listeners    []*elbv2.Listener
+	certificates []*elbv2.Certificate
+	attributes   []*elbv2.LoadBalancerAttribute
+
+	modifyListener   *elbv2.ModifyListenerInput
+	addCerts         *elbv2.AddListenerCertificatesInput
+	removeCerts      *elbv2.RemoveListenerCertificatesInput
+	modifyAttributes *elbv2.ModifyLoadBalancerAttributesInput
+}
+
+func (m *mockListenerELBV2) DescribeListeners(*elbv2.DescribeListenersInput) (*elbv2.DescribeListenersOutput, error) {
+	return &elbv2.DescribeListenersOutput{Listeners: m.listeners}, nil
+}
+
+func (m *mockListenerELBV2) DescribeListenerCertificates(*elbv2.DescribeListenerCertificatesInput) (*elbv2.DescribeListenerCertificatesOutput, error) {
+	return &elbv2.DescribeListenerCertificatesOutput{Certificates: m.certificates}, nil
+}
+
+func (m *mockListenerELBV2) DescribeLoadBalancerAttributes(*elbv2.DescribeLoadBalancerAttributesInput) (*elbv2.DescribeLoadBalancerAttributesOutput, error) {
+	return &elbv2.DescribeLoadBalancerAttributesOutput{Attributes: m.attributes}, nil
+}
+
+func (m *mockListenerELBV2) ModifyListener(in *elbv2.ModifyListenerInput) (*elbv2.ModifyListenerOutput, error) {
+	m.modifyListener = in
+	return &elbv2.ModifyListenerOutput{}, nil
+}
+
+func (m *mockListenerELBV2) AddListenerCertificates(in *elbv2.AddListenerCertificatesInput) (*elbv2.AddListenerCertificatesOutput, error) {
+	m.addCerts = in
+	return &elbv2.AddListenerCertificatesOutput{}, nil
+}
+
+func (m *mockListenerELBV2) RemoveListenerCertificates(in *elbv2.RemoveListenerCertificatesInput) (*elbv2.RemoveListenerCertificatesOutput, error) {
+	m.removeCerts = in
+	return &elbv2.RemoveListenerCertificatesOutput{}, nil
+}
+
+func (m *mockListenerELBV2) ModifyLoadBalancerAttributes(in *elbv2.ModifyLoadBalancerAttributesInput) (*elbv2.ModifyLoadBalancerAttributesOutput, error) {
+	m.modifyAttributes = in
+	return &elbv2.ModifyLoadBalancerAttributesOutput{}, nil
+}
+
+func TestListenerDrift(t *testing.T) {
+	stack := &Stack{
+		LoadBalancerARN: "lb",
+		SSLPolicy:       "ELBSecurityPolicy-TLS-1-2-2017-01",
+		CertificateARNs: map[string]time.Time{"cert-a": {}, "cert-b": {}},
+	}
+	attributes := Attributes{"routing.http2.enabled": "true"}
+	svc := &mockListenerELBV2{
+		listeners: []*elbv2.Listener{
+			{ListenerArn: aws.String("http"), Port: aws.Int64(80)},
+			{ListenerArn: aws.String("https"), Port: aws.Int64(443), SslPolicy: aws.String(stack.SSLPolicy)},
+		},
+		certificates: []*elbv2.Certificate{
+			{CertificateArn: aws.String("cert-a"), IsDefault: aws.Bool(true)},
+			{CertificateArn: aws.String("cert-a")},
+			{CertificateArn: aws.String("cert-b")},
+		},
+		attributes: []*elbv2.LoadBalancerAttribute{
+			{Key: aws.String("routing.http2.enabled"), Value: aws.String("true")},
+			{Key: aws.String("deletion_protection.enabled"), Value: aws.String("true")},
+		},
+	}
+
+	drift, err := detectListenerDrift(svc, stack, attributes)
+	require.NoError(t, err)
+	assert.True(t, drift.Empty())
+
+	svc.listeners[1].SslPolicy = aws.String("ELBSecurityPolicy-2016-08")
+	svc.certificates = []*elbv2.Certificate{
+		{CertificateArn: aws.String("cert-c"), IsDefault: aws.Bool(true)},
+		{CertificateArn: aws.String("cert-a")},
+		{CertificateArn: aws.String("cert-d")},
+	}
+	svc.attributes[0].Value = aws.String("false")
+
+	drift, err = detectListenerDrift(svc, stack, attributes)
+	require.NoError(t, err)
+	require.False(t, drift.Empty())
+	assert.Equal(t, "https", drift.ListenerARN)
+	assert.Equal(t, "ELBSecurityPolicy-2016-08", drift.SSLPolicy)
+	assert.Equal(t, "cert-c", drift.DefaultCertificate)
+	assert.Equal(t, []string{"cert-b"}, drift.MissingCertificates)
+	assert.Equal(t, []string{"cert-d"}, drift.UnknownCertificates)
+	assert.Equal(t, Attributes{"routing.http2.enabled": "false"}, drift.Attributes)
+	assert.Equal(t, "SSL policy ELBSecurityPolicy-2016-08, default certificate cert-c, missing certificates [cert-b], unknown certificates [cert-d], attribute routing.http2.enabled=false", drift.String())
+
+	require.NoError(t, revertListenerDrift(svc, stack, drift))
+	assert.Equal(t, stack.SSLPolicy, aws.StringValue(svc.modifyListener.SslPolicy))
+	assert.Equal(t, "cert-a", aws.StringValue(svc.modifyListener.Certificates[0].CertificateArn))
+	assert.Equal(t, "cert-b", aws.StringValue(svc.addCerts.Certificates[0].CertificateArn))
+	assert.Equal(t, "cert-d", aws.StringValue(svc.removeCerts.Certificates[0].CertificateArn))
+	assert.Equal(t, []*elbv2.LoadBalancerAttribute{
+		{Key: aws.String("routing.http2.enabled"), Value: aws.String("true")},
+	}, svc.modifyAttributes.Attributes)
+
+	svc.listeners = svc.listeners[:1]
+	drift, err = detectListenerDrift(svc, stack, attributes)
+	require.NoError(t, err)
+	assert.True(t, drift.MissingListener)
+	assert.Equal(t, "HTTPS listener missing", drift.String())
+	assert.Error(t, revertListenerDrift(svc, stack, drift))
+}
+
+func TestListenerDriftDefaultCertificate(t *testing.T) {
+	stack := &Stack{
+		LoadBalancerARN:       "lb",
+		CertificateARNs:       map[string]time.Time{"cert-a": {}, "cert-b": {}},
+		DefaultCertificateARN: "cert-b",
+	}
+	svc := &mockListenerELBV2{
+		listeners: []*elbv2.Listener{
+			{ListenerArn: aws.String("https"), Port: aws.Int64(443)},
+		},
+		certificates: []*elbv2.Certificate{
+			{CertificateArn: aws.String("cert-a"), IsDefault: aws.Bool(true)},
+			{CertificateArn: aws.String("cert-a")},
+			{CertificateArn: aws.String("cert-b")},
+		},
+	}
+
+	drift, err := detectListenerDrift(svc, stack, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "cert-a", drift.DefaultCertificate)
+
+	require.NoError(t, revertListenerDrift(svc, stack, drift))
+	assert.Equal(t, "cert-b", aws.StringValue(svc.modifyListener.Certificates[0].CertificateArn))
+}
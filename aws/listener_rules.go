@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	cloudformation "github.com/mweagle/go-cloudformation"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ListenerRuleActionForward  = "forward"
+	ListenerRuleActionDeny     = "deny"
+	ListenerRuleActionRedirect = "redirect"
+	ListenerRuleActionLambda   = "lambda"
+
+	// listenerRulesBasePriority is the priority of the first listener rule.
+	// It leaves room for the rules created by the controller itself, e.g.
+	// the one denying traffic to internal domains.
+	listenerRulesBasePriority int64 = 10
+
+	defaultListenerRuleDenyStatusCode     = 403
+	defaultListenerRuleRedirectStatusCode = 302
+)
+
+// ListenerRule is an additional rule of the listeners of an application load
+// balancer. It matches requests by the source IP and the path and forwards
+// them to the target group or a Lambda function, denies them with a fixed
+// response or redirects them. Rules are evaluated in order before the
+// default action of the listener.
+type ListenerRule struct {
+	SourceIPs    []string `json:"sourceIPs,omitempty"`
+	PathPatterns []string `json:"pathPatterns,omitempty"`
+	Action       string   `json:"action"`
+	StatusCode   int      `json:"statusCode,omitempty"`
+	RedirectURL  string   `json:"redirectURL,omitempty"`
+	FunctionARN  string   `json:"functionARN,omitempty"`
+}
+
+// ListenerRuleList represents a list of listener rules.
+type ListenerRuleList []ListenerRule
+
+// NewListenerRuleListFromJSON parses and validates a JSON list of listener
+// rules.
+func NewListenerRuleListFromJSON(b []byte) (ListenerRuleList, error) {
+	rules := ListenerRuleList{}
+
+	err := json.Unmarshal(b, &rules)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid listener rule %d: %v", i, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r *ListenerRule) validate() error {
+	if len(r.SourceIPs) == 0 && len(r.PathPatterns) == 0 {
+		return fmt.Errorf("at least one source IP or path pattern is required")
+	}
+
+	for _, cidr := range r.SourceIPs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid source IP %q: %v", cidr, err)
+		}
+	}
+
+	switch r.Action {
+	case ListenerRuleActionForward:
+	case ListenerRuleActionDeny:
+		if r.StatusCode != 0 && (r.StatusCode < 200 || r.StatusCode > 599) {
+			return fmt.Errorf("invalid status code %d for action %q", r.StatusCode, r.Action)
+		}
+	case ListenerRuleActionRedirect:
+		if r.StatusCode != 0 && r.StatusCode != 301 && r.StatusCode != 302 {
+			return fmt.Errorf("invalid status code %d for action %q", r.StatusCode, r.Action)
+		}
+		u, err := url.Parse(r.RedirectURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid redirect URL %q", r.RedirectURL)
+		}
+	case ListenerRuleActionLambda:
+		if !IsLambdaFunctionARN(r.FunctionARN) {
+			return fmt.Errorf("invalid Lambda function ARN %q", r.FunctionARN)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+
+	return nil
+}
+
+// Hash computes a hash of the ListenerRuleList which can be used to detect
+// changes between two versions. The hash string will be empty if r is empty
+// or there was an error while encoding.
+func (r ListenerRuleList) Hash() string {
+	if len(r) == 0 {
+		return ""
+	}
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		log.Errorf("failed to marshal listener rule list: %v", err)
+		return ""
+	}
+
+	hash := sha256.New()
+	hash.Write(buf)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func generateListenerRule(listenerName string, rulePriority int64, rule ListenerRule, authentication *ListenerAuthentication) cloudformation.ElasticLoadBalancingV2ListenerRule {
+	conditions := cloudformation.ElasticLoadBalancingV2ListenerRuleRuleConditionList{}
+	if len(rule.SourceIPs) > 0 {
+		conditions = append(conditions, cloudformation.ElasticLoadBalancingV2ListenerRuleRuleCondition{
+			Field: cloudformation.String("source-ip"),
+			SourceIPConfig: &cloudformation.ElasticLoadBalancingV2ListenerRuleSourceIPConfig{
+				Values: stringList(rule.SourceIPs),
+			},
+		})
+	}
+	if len(rule.PathPatterns) > 0 {
+		conditions = append(conditions, cloudformation.ElasticLoadBalancingV2ListenerRuleRuleCondition{
+			Field: cloudformation.String("path-pattern"),
+			PathPatternConfig: &cloudformation.ElasticLoadBalancingV2ListenerRulePathPatternConfig{
+				Values: stringList(rule.PathPatterns),
+			},
+		})
+	}
+
+	action := cloudformation.ElasticLoadBalancingV2ListenerRuleAction{}
+	switch rule.Action {
+	case ListenerRuleActionForward:
+		action.Type = cloudformation.String("forward")
+		action.TargetGroupArn = cloudformation.Ref("TG").String()
+	case ListenerRuleActionDeny:
+		statusCode := rule.StatusCode
+		if statusCode == 0 {
+			statusCode = defaultListenerRuleDenyStatusCode
+		}
+		action.Type = cloudformation.String(listenerRuleActionTypeFixedRes)
+		action.FixedResponseConfig = &cloudformation.ElasticLoadBalancingV2ListenerRuleFixedResponseConfig{
+			ContentType: cloudformation.String("text/plain"),
+			StatusCode:  cloudformation.String(fmt.Sprintf("%d", statusCode)),
+		}
+	case ListenerRuleActionRedirect:
+		statusCode := rule.StatusCode
+		if statusCode == 0 {
+			statusCode = defaultListenerRuleRedirectStatusCode
+		}
+		// the URL was validated when parsing the rules
+		u, _ := url.Parse(rule.RedirectURL)
+		redirect := &cloudformation.ElasticLoadBalancingV2ListenerRuleRedirectConfig{
+			Protocol:   cloudformation.String(strings.ToUpper(u.Scheme)),
+			Host:       cloudformation.String(u.Hostname()),
+			StatusCode: cloudformation.String(fmt.Sprintf("HTTP_%d", statusCode)),
+		}
+		if u.Port() != "" {
+			redirect.Port = cloudformation.String(u.Port())
+		}
+		if u.Path != "" {
+			redirect.Path = cloudformation.String(u.Path)
+		}
+		if u.RawQuery != "" {
+			redirect.Query = cloudformation.String(u.RawQuery)
+		}
+		action.Type = cloudformation.String("redirect")
+		action.RedirectConfig = redirect
+	case ListenerRuleActionLambda:
+		action.Type = cloudformation.String("forward")
+		action.TargetGroupArn = cloudformation.Ref(lambdaTargetGroupName(rule.FunctionARN)).String()
+	}
+
+	actions := cloudformation.ElasticLoadBalancingV2ListenerRuleActionList{action}
+	// forwarded requests are authenticated like the ones of the default
+	// action of the listener
+	if authentication != nil && (rule.Action == ListenerRuleActionForward || rule.Action == ListenerRuleActionLambda) {
+		action.Order = cloudformation.Integer(2)
+		actions = cloudformation.ElasticLoadBalancingV2ListenerRuleActionList{authentication.listenerRuleAction(), action}
+	}
+
+	return cloudformation.ElasticLoadBalancingV2ListenerRule{
+		Conditions:  &conditions,
+		Actions:     &actions,
+		Priority:    cloudformation.Integer(rulePriority),
+		ListenerArn: cloudformation.Ref(listenerName).String(),
+	}
+}
+
+func addListenerRules(template *cloudformation.Template, listenerName string, rules ListenerRuleList, authentication *ListenerAuthentication) {
+	for i, rule := range rules {
+		template.AddResource(
+			fmt.Sprintf("%sRule%d", listenerName, i),
+			generateListenerRule(listenerName, listenerRulesBasePriority+int64(i), rule, authentication),
+		)
+	}
+}
+
+func stringList(values []string) *cloudformation.StringListExpr {
+	list := cloudformation.StringList()
+	for _, value := range values {
+		list.Literal = append(list.Literal, cloudformation.String(value))
+	}
+	return list
+}
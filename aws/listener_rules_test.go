@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"testing"
+
+	cloudformation "github.com/mweagle/go-cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewListenerRuleListFromJSON(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		rules   string
+		want    ListenerRuleList
+		wantErr bool
+	}{
+		{
+			name: "valid rules",
+			rules: `[
+				{"sourceIPs": ["10.0.0.0/8"], "pathPatterns": ["/admin/*"], "action": "forward"},
+				{"pathPatterns": ["/admin/*"], "action": "deny"},
+				{"pathPatterns": ["/old/*"], "action": "redirect", "redirectURL": "https://example.org/new", "statusCode": 301},
+				{"pathPatterns": ["/maintenance/*"], "action": "lambda", "functionARN": "arn:aws:lambda:eu-central-1:123456789012:function:maintenance"}
+			]`,
+			want: ListenerRuleList{
+				{SourceIPs: []string{"10.0.0.0/8"}, PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionForward},
+				{PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionDeny},
+				{PathPatterns: []string{"/old/*"}, Action: ListenerRuleActionRedirect, RedirectURL: "https://example.org/new", StatusCode: 301},
+				{PathPatterns: []string{"/maintenance/*"}, Action: ListenerRuleActionLambda, FunctionARN: "arn:aws:lambda:eu-central-1:123456789012:function:maintenance"},
+			},
+		},
+		{
+			name:    "invalid JSON",
+			rules:   `[{`,
+			wantErr: true,
+		},
+		{
+			name:    "missing conditions",
+			rules:   `[{"action": "deny"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid source IP",
+			rules:   `[{"sourceIPs": ["10.0.0.1"], "action": "deny"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			rules:   `[{"pathPatterns": ["/"], "action": "drop"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid redirect URL",
+			rules:   `[{"pathPatterns": ["/"], "action": "redirect", "redirectURL": "/new"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid Lambda function ARN",
+			rules:   `[{"pathPatterns": ["/"], "action": "lambda", "functionARN": "arn:aws:s3:::bucket"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid redirect status code",
+			rules:   `[{"pathPatterns": ["/"], "action": "redirect", "redirectURL": "https://example.org", "statusCode": 307}]`,
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := NewListenerRuleListFromJSON([]byte(test.rules))
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, rules)
+		})
+	}
+}
+
+func TestListenerRuleListHash(t *testing.T) {
+	assert.Equal(t, "", ListenerRuleList(nil).Hash())
+
+	rules := ListenerRuleList{{PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionDeny}}
+	assert.NotEmpty(t, rules.Hash())
+	assert.Equal(t, rules.Hash(), ListenerRuleList{{PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionDeny}}.Hash())
+	assert.NotEqual(t, rules.Hash(), ListenerRuleList{{PathPatterns: []string{"/admin/*"}, Action: ListenerRuleActionForward}}.Hash())
+}
+
+func TestGenerateListenerRule(t *testing.T) {
+	rule := generateListenerRule("HTTPSListener", 11, ListenerRule{
+		SourceIPs:   []string{"10.0.0.0/8"},
+		Action:      ListenerRuleActionRedirect,
+		RedirectURL: "https://example.org:8443/new?foo=bar",
+	}, nil)
+
+	assert.Equal(t, cloudformation.Integer(11), rule.Priority)
+	require.Len(t, *rule.Conditions, 1)
+	assert.Equal(t, cloudformation.String("source-ip"), (*rule.Conditions)[0].Field)
+	require.Len(t, *rule.Actions, 1)
+	assert.Equal(t, &cloudformation.ElasticLoadBalancingV2ListenerRuleRedirectConfig{
+		Protocol:   cloudformation.String("HTTPS"),
+		Host:       cloudformation.String("example.org"),
+		Port:       cloudformation.String("8443"),
+		Path:       cloudformation.String("/new"),
+		Query:      cloudformation.String("foo=bar"),
+		StatusCode: cloudformation.String("HTTP_302"),
+	}, (*rule.Actions)[0].RedirectConfig)
+}
+
+func TestIsLambdaFunctionARN(t *testing.T) {
+	assert.True(t, IsLambdaFunctionARN("arn:aws:lambda:eu-central-1:123456789012:function:maintenance"))
+	assert.True(t, IsLambdaFunctionARN("arn:aws:lambda:eu-central-1:123456789012:function:maintenance:live"))
+	assert.False(t, IsLambdaFunctionARN("arn:aws:lambda:eu-central-1:123456789012:layer:name"))
+	assert.False(t, IsLambdaFunctionARN("arn:aws:s3:::bucket"))
+	assert.False(t, IsLambdaFunctionARN("maintenance"))
+}
@@ -0,0 +1,111 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+)
+
+// stackOwner is the pair of the cluster ID and the controller ID which tag
+// the stacks managed by a controller.
+type stackOwner struct {
+	clusterID    string
+	controllerID string
+}
+
+func (o stackOwner) owns(cfTags []*cloudformation.Tag) bool {
+	return isManagedStack(cfTags, o.clusterID, o.controllerID)
+}
+
+// WithPreviousOwner returns the receiver adapter after setting the cluster
+// ID and the controller ID which tagged the stacks before they were changed.
+// The stacks tagged by them are managed too, and their tags are migrated to
+// the current IDs. Empty values default to the current ones.
+func (a *Adapter) WithPreviousOwner(clusterID, controllerID string) *Adapter {
+	if clusterID == "" && controllerID == "" {
+		a.previousOwner = nil
+		return a
+	}
+	a.previousOwner = &stackOwner{clusterID: clusterID, controllerID: controllerID}
+	return a
+}
+
+// owners returns the current owner of the stacks and the previous one, if
+// it's set and differs from the current one.
+func (a *Adapter) owners() (stackOwner, *stackOwner) {
+	current := stackOwner{clusterID: a.ClusterID(), controllerID: a.controllerID}
+	if a.previousOwner == nil {
+		return current, nil
+	}
+
+	previous := *a.previousOwner
+	if previous.clusterID == "" {
+		previous.clusterID = current.clusterID
+	}
+	if previous.controllerID == "" {
+		previous.controllerID = current.controllerID
+	}
+	if previous == current {
+		return current, nil
+	}
+	return current, &previous
+}
+
+// MigrateStackOwnership replaces the controller and the cluster tags of a
+// stack of the previous owner by the ones of the current owner. The template
+// and the parameters of the stack are kept, so CloudFormation just
+// propagates the tags to its resources instead of replacing them.
+func (a *Adapter) MigrateStackOwnership(stack *Stack) (string, error) {
+	current, previous := a.owners()
+	if previous == nil {
+		return "", fmt.Errorf("no previous owner of stack %q", stack.Name)
+	}
+	return migrateStackOwnership(a.cloudformation, stack.Name, *previous, current)
+}
+
+func migrateStackOwnership(svc cloudformationiface.CloudFormationAPI, stackName string, previous, current stackOwner) (string, error) {
+	resp, err := svc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe stack %q: %v", stackName, err)
+	}
+	if len(resp.Stacks) != 1 {
+		return "", fmt.Errorf("stack %q not found", stackName)
+	}
+	stack := resp.Stacks[0]
+	if !previous.owns(stack.Tags) {
+		return "", fmt.Errorf("stack %q isn't owned by controller %q of cluster %q", stackName, previous.controllerID, previous.clusterID)
+	}
+
+	tags := convertCloudFormationTags(stack.Tags)
+	if tags[clusterIDTagPrefix+previous.clusterID] == resourceLifecycleOwned {
+		delete(tags, clusterIDTagPrefix+previous.clusterID)
+	}
+	if tags[clusterIDTag] == previous.clusterID {
+		delete(tags, clusterIDTag)
+	}
+	tags[kubernetesCreatorTag] = current.controllerID
+	tags[clusterIDTagPrefix+current.clusterID] = resourceLifecycleOwned
+
+	params := make([]*cloudformation.Parameter, 0, len(stack.Parameters))
+	for _, param := range stack.Parameters {
+		params = append(params, &cloudformation.Parameter{
+			ParameterKey:     param.ParameterKey,
+			UsePreviousValue: aws.Bool(true),
+		})
+	}
+
+	out, err := svc.UpdateStack(&cloudformation.UpdateStackInput{
+		StackName:           aws.String(stackName),
+		UsePreviousTemplate: aws.Bool(true),
+		Parameters:          params,
+		Tags:                tagMapToCloudformationTags(tags),
+	})
+	if err != nil {
+		return stackName, err
+	}
+	return aws.StringValue(out.StackId), nil
+}
@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func TestFindManagedStacksOfPreviousOwner(t *testing.T) {
+	c := &mockCloudFormationClient{outputs: cfMockOutputs{
+		describeStacks: R(&cloudformation.DescribeStacksOutput{
+			Stacks: []*cloudformation.Stack{
+				{
+					StackName: aws.String("current"),
+					Tags: []*cloudformation.Tag{
+						cfTag(kubernetesCreatorTag, DefaultControllerID),
+						cfTag(clusterIDTagPrefix+"new-cluster", resourceLifecycleOwned),
+					},
+				},
+				{
+					StackName: aws.String("previous"),
+					Tags: []*cloudformation.Tag{
+						cfTag(kubernetesCreatorTag, DefaultControllerID),
+						cfTag(clusterIDTagPrefix+"old-cluster", resourceLifecycleOwned),
+					},
+				},
+				{
+					StackName: aws.String("other"),
+					Tags: []*cloudformation.Tag{
+						cfTag(kubernetesCreatorTag, "other-controller"),
+						cfTag(clusterIDTagPrefix+"old-cluster", resourceLifecycleOwned),
+					},
+				},
+			},
+		}, nil),
+	}}
+
+	previous := &stackOwner{clusterID: "old-cluster", controllerID: DefaultControllerID}
+	stacks, err := findManagedStacks(c, "new-cluster", DefaultControllerID, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]bool)
+	for _, s := range stacks {
+		got[s.Name] = s.PreviousOwner
+	}
+	want := map[string]bool{"current": false, "previous": true}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected stacks. wanted %v, got %v", want, got)
+	}
+}
+
+func TestMigrateStackOwnership(t *testing.T) {
+	previous := stackOwner{clusterID: "old-cluster", controllerID: "old-controller"}
+	current := stackOwner{clusterID: "new-cluster", controllerID: DefaultControllerID}
+
+	for _, ti := range []struct {
+		name     string
+		tags     []*cloudformation.Tag
+		wantTags map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "migrated",
+			tags: []*cloudformation.Tag{
+				cfTag(kubernetesCreatorTag, "old-controller"),
+				cfTag(clusterIDTagPrefix+"old-cluster", resourceLifecycleOwned),
+				cfTag(ingressOwnerTag, "default/foo"),
+			},
+			wantTags: map[string]string{
+				kubernetesCreatorTag:               DefaultControllerID,
+				clusterIDTagPrefix + "new-cluster": resourceLifecycleOwned,
+				ingressOwnerTag:                    "default/foo",
+			},
+		},
+		{
+			name: "legacy cluster tag",
+			tags: []*cloudformation.Tag{
+				cfTag(kubernetesCreatorTag, "old-controller"),
+				cfTag(clusterIDTag, "old-cluster"),
+			},
+			wantTags: map[string]string{
+				kubernetesCreatorTag:               DefaultControllerID,
+				clusterIDTagPrefix + "new-cluster": resourceLifecycleOwned,
+			},
+		},
+		{
+			name: "not owned",
+			tags: []*cloudformation.Tag{
+				cfTag(kubernetesCreatorTag, "other-controller"),
+				cfTag(clusterIDTagPrefix+"old-cluster", resourceLifecycleOwned),
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(ti.name, func(t *testing.T) {
+			c := &mockCloudFormationClient{outputs: cfMockOutputs{
+				describeStacks: R(&cloudformation.DescribeStacksOutput{
+					Stacks: []*cloudformation.Stack{{
+						StackName: aws.String("stack"),
+						Parameters: []*cloudformation.Parameter{
+							cfParam(parameterLoadBalancerSchemeParameter, "internet-facing"),
+						},
+						Tags: ti.tags,
+					}},
+				}, nil),
+				updateStack: R(mockUSOutput("stack-id"), nil),
+			}}
+
+			id, err := migrateStackOwnership(c, "stack", previous, current)
+			if ti.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				if c.updateStackInput != nil {
+					t.Error("unexpected stack update")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != "stack-id" {
+				t.Errorf("unexpected stack ID %q", id)
+			}
+
+			in := c.updateStackInput
+			if !aws.BoolValue(in.UsePreviousTemplate) {
+				t.Error("the previous template isn't kept")
+			}
+			for _, param := range in.Parameters {
+				if param.ParameterValue != nil || !aws.BoolValue(param.UsePreviousValue) {
+					t.Errorf("the previous value of parameter %q isn't kept", aws.StringValue(param.ParameterKey))
+				}
+			}
+			if got := convertCloudFormationTags(in.Tags); !reflect.DeepEqual(ti.wantTags, got) {
+				t.Errorf("unexpected tags. wanted %v, got %v", ti.wantTags, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/waf"
+	"github.com/aws/aws-sdk-go/service/wafregional"
+	"github.com/aws/aws-sdk-go/service/wafregional/wafregionaliface"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/aws/aws-sdk-go/service/wafv2/wafv2iface"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// validatedResourceTTL is how long a referenced resource found to be valid
+// isn't checked again.
+const validatedResourceTTL = time.Hour
+
+// ValidateResources checks that the security group and the WAF web ACL
+// referenced by a load balancer exist, and that the security group belongs
+// to the VPC of the cluster. This reports invalid references right away
+// instead of through a slow stack rollback. Resources which can't be
+// checked for lack of permissions are considered valid.
+func (a *Adapter) ValidateResources(securityGroup, wafWebACLID string) error {
+	if securityGroup != "" {
+		if err := a.validateResource("security-group/"+securityGroup, func() error {
+			return validateSecurityGroup(a.ec2, securityGroup, a.VpcID())
+		}); err != nil {
+			return err
+		}
+	}
+
+	if wafWebACLID != "" {
+		if err := a.validateResource("waf/"+wafWebACLID, func() error {
+			return validateWebACL(a.wafv2, a.wafregional, wafWebACLID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Adapter) validateResource(key string, validate func() error) error {
+	if t, ok := a.validatedResources[key]; ok && a.clock.Now().Sub(t) < validatedResourceTTL {
+		return nil
+	}
+
+	err := validate()
+	if isAccessDeniedError(err) {
+		log.Warnf("Skipping validation of %s: %v", key, err)
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	a.validatedResources[key] = a.clock.Now()
+	return nil
+}
+
+func validateSecurityGroup(svc ec2iface.EC2API, securityGroup, vpcID string) error {
+	resp, err := svc.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: []*string{aws.String(securityGroup)},
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvalidGroup.NotFound" {
+			return fmt.Errorf("security group %s not found", securityGroup)
+		}
+		return err
+	}
+
+	if len(resp.SecurityGroups) < 1 {
+		return fmt.Errorf("security group %s not found", securityGroup)
+	}
+
+	if sgVPC := aws.StringValue(resp.SecurityGroups[0].VpcId); sgVPC != vpcID {
+		return fmt.Errorf("security group %s belongs to VPC %s instead of %s", securityGroup, sgVPC, vpcID)
+	}
+	return nil
+}
+
+func validateWebACL(wafv2Svc wafv2iface.WAFV2API, wafRegionalSvc wafregionaliface.WAFRegionalAPI, id string) error {
+	if !isWAFv2WebACLARN(id) {
+		_, err := wafRegionalSvc.GetWebACL(&waf.GetWebACLInput{WebACLId: aws.String(id)})
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == wafregional.ErrCodeWAFNonexistentItemException {
+			return fmt.Errorf("WAF web ACL %s not found", id)
+		}
+		return err
+	}
+
+	name, webACLID, err := parseWAFv2WebACLARN(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = wafv2Svc.GetWebACL(&wafv2.GetWebACLInput{
+		Name:  aws.String(name),
+		Id:    aws.String(webACLID),
+		Scope: aws.String(wafv2.ScopeRegional),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == wafv2.ErrCodeWAFNonexistentItemException {
+		return fmt.Errorf("WAF web ACL %s not found", id)
+	}
+	return err
+}
+
+// isWAFv2WebACLARN returns true if the WAF web ACL ID is the ARN of a WAFv2
+// web ACL. Any partition is accepted, e.g. arn:aws-cn:wafv2:..., as well as
+// the ARNs of emulators like LocalStack.
+func isWAFv2WebACLARN(id string) bool {
+	parsed, err := arn.Parse(id)
+	return err == nil && parsed.Service == "wafv2"
+}
+
+// parseWAFv2WebACLARN returns the name and the ID of the regional WAFv2 web
+// ACL of the ARN.
+func parseWAFv2WebACLARN(id string) (string, string, error) {
+	// arn:aws:wafv2:<region>:<account>:regional/webacl/<name>/<id>
+	parsed, err := arn.Parse(id)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid WAF web ACL ARN %s: %v", id, err)
+	}
+	parts := strings.Split(parsed.Resource, "/")
+	if len(parts) != 4 || parts[0] != "regional" || parts[1] != "webacl" {
+		return "", "", fmt.Errorf("invalid WAF web ACL ARN %s: not a regional web ACL", id)
+	}
+	return parts[2], parts[3], nil
+}
+
+// IsValidWAFWebACLID returns true if the ID is the ARN of a regional WAFv2
+// web ACL or the ID of a WAF Classic regional web ACL.
+func IsValidWAFWebACLID(id string) bool {
+	if isWAFv2WebACLARN(id) {
+		_, _, err := parseWAFv2WebACLARN(id)
+		return err == nil
+	}
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+func isAccessDeniedError(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/waf"
+	"github.com/aws/aws-sdk-go/service/wafregional"
+	"github.com/aws/aws-sdk-go/service/wafregional/wafregionaliface"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/aws/aws-sdk-go/service/wafv2/wafv2iface"
+	"github.com/stretchr/testify/require"
+)
+
+type mockedWAFv2Client struct {
+	wafv2iface.WAFV2API
+	err    error
+	webACL *wafv2.WebACL
+}
+
+func (m mockedWAFv2Client) GetWebACL(*wafv2.GetWebACLInput) (*wafv2.GetWebACLOutput, error) {
+	return &wafv2.GetWebACLOutput{}, m.err
+}
+
+func (m mockedWAFv2Client) GetWebACLForResource(*wafv2.GetWebACLForResourceInput) (*wafv2.GetWebACLForResourceOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &wafv2.GetWebACLForResourceOutput{WebACL: m.webACL}, nil
+}
+
+type mockedWAFRegionalClient struct {
+	wafregionaliface.WAFRegionalAPI
+	err error
+}
+
+func (m mockedWAFRegionalClient) GetWebACL(*waf.GetWebACLInput) (*waf.GetWebACLOutput, error) {
+	return &waf.GetWebACLOutput{}, m.err
+}
+
+func TestValidateSecurityGroup(t *testing.T) {
+	for _, test := range []struct {
+		msg     string
+		given   ec2MockOutputs
+		wantErr bool
+	}{
+		{
+			msg: "security group in the VPC",
+			given: ec2MockOutputs{describeSecurityGroups: R(&ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-1"), VpcId: aws.String("vpc-1")}},
+			}, nil)},
+		},
+		{
+			msg: "security group in another VPC",
+			given: ec2MockOutputs{describeSecurityGroups: R(&ec2.DescribeSecurityGroupsOutput{
+				SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-1"), VpcId: aws.String("vpc-2")}},
+			}, nil)},
+			wantErr: true,
+		},
+		{
+			msg:     "security group not found",
+			given:   ec2MockOutputs{describeSecurityGroups: R(nil, awserr.New("InvalidGroup.NotFound", "not found", nil))},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			err := validateSecurityGroup(&mockEc2Client{outputs: test.given}, "sg-1", "vpc-1")
+			if test.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateWebACL(t *testing.T) {
+	notFound := awserr.New(wafv2.ErrCodeWAFNonexistentItemException, "not found", nil)
+	arn := "arn:aws:wafv2:eu-central-1:123456789012:regional/webacl/name/id"
+
+	require.NoError(t, validateWebACL(mockedWAFv2Client{}, mockedWAFRegionalClient{}, arn))
+	require.Error(t, validateWebACL(mockedWAFv2Client{err: notFound}, mockedWAFRegionalClient{}, arn))
+	require.Error(t, validateWebACL(mockedWAFv2Client{}, mockedWAFRegionalClient{}, "arn:aws:wafv2:eu-central-1:123456789012:global/webacl/name/id"))
+
+	require.NoError(t, validateWebACL(mockedWAFv2Client{}, mockedWAFRegionalClient{}, "waf-id"))
+	require.Error(t, validateWebACL(mockedWAFv2Client{}, mockedWAFRegionalClient{err: awserr.New(wafregional.ErrCodeWAFNonexistentItemException, "not found", nil)}, "waf-id"))
+}
+
+func TestIsWAFv2WebACLARN(t *testing.T) {
+	require.True(t, isWAFv2WebACLARN("arn:aws:wafv2:eu-central-1:123456789012:regional/webacl/name/id"))
+	require.True(t, isWAFv2WebACLARN("arn:aws-cn:wafv2:cn-north-1:123456789012:regional/webacl/name/id"))
+	require.True(t, isWAFv2WebACLARN("arn:aws:wafv2:us-east-1:000000000000:regional/webacl/name/id"))
+	require.False(t, isWAFv2WebACLARN("arn:aws:waf-regional:eu-central-1:123456789012:webacl/id"))
+	require.False(t, isWAFv2WebACLARN("waf-id"))
+}
+
+func TestIsValidWAFWebACLID(t *testing.T) {
+	require.True(t, IsValidWAFWebACLID("arn:aws:wafv2:eu-central-1:123456789012:regional/webacl/name/id"))
+	require.True(t, IsValidWAFWebACLID("a1b2c3d4-5678-90ab-cdef-0123456789ab"))
+	require.False(t, IsValidWAFWebACLID("arn:aws:wafv2:us-east-1:123456789012:global/webacl/name/id"))
+	require.False(t, IsValidWAFWebACLID("waf-id"))
+}
+
+func TestValidateResourcesAccessDenied(t *testing.T) {
+	a := &Adapter{
+		ec2:                &mockEc2Client{outputs: ec2MockOutputs{describeSecurityGroups: R(nil, awserr.New("UnauthorizedOperation", "denied", nil))}},
+		manifest:           &manifest{vpcID: "vpc-1"},
+		validatedResources: make(map[string]time.Time),
+		clock:              SystemClock,
+	}
+	require.NoError(t, a.ValidateResources("sg-1", ""))
+}
@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+)
+
+const (
+	elbServiceCode = "elasticloadbalancing"
+
+	certificatesPerALBQuotaName = "Certificates per Application Load Balancer"
+	listenersPerALBQuotaName    = "Listeners per Application Load Balancer"
+	rulesPerALBQuotaName        = "Rules per Application Load Balancer"
+	albsPerRegionQuotaName      = "Application Load Balancers per Region"
+	nlbsPerRegionQuotaName      = "Network Load Balancers per Region"
+)
+
+// LoadBalancerQuotas are the Service Quotas of the account limiting how
+// ingresses are grouped into load balancers. Quotas which couldn't be
+// found are zero.
+type LoadBalancerQuotas struct {
+	// CertificatesPerALB is the number of certificates of an application
+	// load balancer, not including the default certificate.
+	CertificatesPerALB int
+	// ListenersPerALB is the number of listeners of an application load
+	// balancer.
+	ListenersPerALB int
+	// RulesPerALB is the number of listener rules of an application load
+	// balancer, not including the default rules.
+	RulesPerALB int
+	// ALBsPerRegion and NLBsPerRegion are the number of application and
+	// network load balancers of the account in the region, including the
+	// ones not managed by the controller.
+	ALBsPerRegion int
+	NLBsPerRegion int
+}
+
+func getLoadBalancerQuotas(svc servicequotasiface.ServiceQuotasAPI) (*LoadBalancerQuotas, error) {
+	values := make(map[string]float64)
+	err := svc.ListServiceQuotasPages(&servicequotas.ListServiceQuotasInput{
+		ServiceCode: aws.String(elbServiceCode),
+	}, func(page *servicequotas.ListServiceQuotasOutput, lastPage bool) bool {
+		for _, quota := range page.Quotas {
+			values[aws.StringValue(quota.QuotaName)] = aws.Float64Value(quota.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service quotas: %v", err)
+	}
+
+	return &LoadBalancerQuotas{
+		CertificatesPerALB: int(values[certificatesPerALBQuotaName]),
+		ListenersPerALB:    int(values[listenersPerALBQuotaName]),
+		RulesPerALB:        int(values[rulesPerALBQuotaName]),
+		ALBsPerRegion:      int(values[albsPerRegionQuotaName]),
+		NLBsPerRegion:      int(values[nlbsPerRegionQuotaName]),
+	}, nil
+}
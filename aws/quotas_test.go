@@ -0,0 +1,42 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/stretchr/testify/require"
+)
+
+type mockedServiceQuotasClient struct {
+	servicequotasiface.ServiceQuotasAPI
+	quotas []*servicequotas.ServiceQuota
+	err    error
+}
+
+func (m mockedServiceQuotasClient) ListServiceQuotasPages(input *servicequotas.ListServiceQuotasInput, fn func(*servicequotas.ListServiceQuotasOutput, bool) bool) error {
+	if m.err != nil {
+		return m.err
+	}
+	fn(&servicequotas.ListServiceQuotasOutput{Quotas: m.quotas}, true)
+	return nil
+}
+
+func TestGetLoadBalancerQuotas(t *testing.T) {
+	client := mockedServiceQuotasClient{
+		quotas: []*servicequotas.ServiceQuota{
+			{QuotaName: aws.String(certificatesPerALBQuotaName), Value: aws.Float64(50)},
+			{QuotaName: aws.String(rulesPerALBQuotaName), Value: aws.Float64(200)},
+			{QuotaName: aws.String("Application Load Balancers per Region"), Value: aws.Float64(50)},
+		},
+	}
+
+	quotas, err := getLoadBalancerQuotas(client)
+	require.NoError(t, err)
+	require.Equal(t, &LoadBalancerQuotas{CertificatesPerALB: 50, RulesPerALB: 200, ALBsPerRegion: 50}, quotas)
+
+	_, err = getLoadBalancerQuotas(mockedServiceQuotasClient{err: errors.New("access denied")})
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	cloudformation "github.com/mweagle/go-cloudformation"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxResourceTagKeyLength   = 128
+	maxResourceTagValueLength = 256
+	// stackNameResourceTag is set by the controller on the load balancers.
+	stackNameResourceTag = "StackName"
+	// maxTagResources is the maximum number of resources of a request of
+	// the ELBv2 tagging API.
+	maxTagResources = 20
+)
+
+// ResourceTags are tags set on the load balancers and target groups of a
+// stack, in addition to the tags of the stack. Unlike the tags of the stack,
+// they're visible in the cost explorer grouped by resource.
+type ResourceTags map[string]string
+
+// NewResourceTagsFromJSON parses and validates a JSON map of resource tags.
+func NewResourceTagsFromJSON(b []byte) (ResourceTags, error) {
+	tags := ResourceTags{}
+
+	err := json.Unmarshal(b, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tags.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Validate checks that the tags can be set on the resources.
+func (t ResourceTags) Validate() error {
+	for key, value := range t {
+		switch {
+		case key == "" || len(key) > maxResourceTagKeyLength:
+			return fmt.Errorf("invalid length of tag key %q", key)
+		case len(value) > maxResourceTagValueLength:
+			return fmt.Errorf("tag value of %q exceeds %d characters", key, maxResourceTagValueLength)
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			return fmt.Errorf("tag key %q uses the reserved prefix aws:", key)
+		case key == stackNameResourceTag:
+			return fmt.Errorf("tag key %q is set by the controller", key)
+		}
+	}
+	return nil
+}
+
+// Hash returns a stable hash of the tags. Empty tags result in an empty
+// hash.
+func (t ResourceTags) Hash() string {
+	if len(t) == 0 {
+		return ""
+	}
+
+	// maps are marshalled with sorted keys
+	buf, err := json.Marshal(t)
+	if err != nil {
+		log.Errorf("failed to marshal resource tags: %v", err)
+		return ""
+	}
+
+	hash := sha256.New()
+	hash.Write(buf)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// allResourceTags returns the tags of the resources of the stack. The tags
+// of the ingress override the ones rendered from the template.
+func (spec *stackSpec) allResourceTags() ResourceTags {
+	if len(spec.templateResourceTags) == 0 {
+		return spec.resourceTags
+	}
+	return mergeTags(spec.templateResourceTags, spec.resourceTags)
+}
+
+// templateTags returns the tags of a resource of the template: the given
+// ones followed by the resource tags sorted by key, or nil if there are
+// none.
+func (t ResourceTags) templateTags(tags ...cloudformation.Tag) *cloudformation.TagList {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		tags = append(tags, cloudformation.Tag{
+			Key:   cloudformation.String(key),
+			Value: cloudformation.String(t[key]),
+		})
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+	list := cloudformation.TagList(tags)
+	return &list
+}
+
+// UpdateResourceTags sets the resource tags of the load balancers and target
+// groups of the stack with the ELBv2 API, which takes seconds instead of the
+// minutes of a stack update. Tags of keys not rendered anymore are removed,
+// except for the ones set by AWS or propagated from the stack.
+func (a *Adapter) UpdateResourceTags(stack *Stack, opts *StackOptions) error {
+	spec, err := a.newStackSpec(stack.Name, opts)
+	if err != nil {
+		return err
+	}
+
+	keep := mergeTags(stack.tags)
+	for _, tag := range stackTags(spec) {
+		keep[aws.StringValue(tag.Key)] = ""
+	}
+	keep[stackNameResourceTag] = ""
+
+	return updateResourceTags(a.cloudformation, a.elbv2, stack.Name, spec.allResourceTags(), keep)
+}
+
+func updateResourceTags(cfSvc cloudformationiface.CloudFormationAPI, elbSvc elbv2iface.ELBV2API, stackName string, tags ResourceTags, keep map[string]string) error {
+	arns, err := stackLoadBalancingResources(cfSvc, stackName)
+	if err != nil {
+		return err
+	}
+
+	for len(arns) > 0 {
+		n := len(arns)
+		if n > maxTagResources {
+			n = maxTagResources
+		}
+		if err := tagResources(elbSvc, arns[:n], tags, keep); err != nil {
+			return err
+		}
+		arns = arns[n:]
+	}
+	return nil
+}
+
+func tagResources(svc elbv2iface.ELBV2API, arns []string, tags ResourceTags, keep map[string]string) error {
+	resp, err := svc.DescribeTags(&elbv2.DescribeTagsInput{
+		ResourceArns: aws.StringSlice(arns),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe tags of %v: %v", arns, err)
+	}
+
+	for _, description := range resp.TagDescriptions {
+		var obsolete []string
+		for _, tag := range description.Tags {
+			key := aws.StringValue(tag.Key)
+			if _, ok := tags[key]; ok {
+				continue
+			}
+			if _, ok := keep[key]; ok || strings.HasPrefix(key, "aws:") {
+				continue
+			}
+			obsolete = append(obsolete, key)
+		}
+		if len(obsolete) == 0 {
+			continue
+		}
+
+		sort.Strings(obsolete)
+		_, err := svc.RemoveTags(&elbv2.RemoveTagsInput{
+			ResourceArns: []*string{description.ResourceArn},
+			TagKeys:      aws.StringSlice(obsolete),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove tags %v of %s: %v", obsolete, aws.StringValue(description.ResourceArn), err)
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	elbTags := make([]*elbv2.Tag, 0, len(keys))
+	for _, key := range keys {
+		elbTags = append(elbTags, &elbv2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+
+	_, err = svc.AddTags(&elbv2.AddTagsInput{
+		ResourceArns: aws.StringSlice(arns),
+		Tags:         elbTags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add tags to %v: %v", arns, err)
+	}
+	return nil
+}
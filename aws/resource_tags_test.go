@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResourceTagsFromJSON(t *testing.T) {
+	for _, test := range []struct {
+		msg     string
+		json    string
+		want    ResourceTags
+		wantErr bool
+	}{
+		{
+			msg:  "valid tags",
+			json: `{"team":"foo","cost-center":""}`,
+			want: ResourceTags{"team": "foo", "cost-center": ""},
+		},
+		{
+			msg:     "invalid JSON",
+			json:    `["team"]`,
+			wantErr: true,
+		},
+		{
+			msg:     "empty key",
+			json:    `{"":"foo"}`,
+			wantErr: true,
+		},
+		{
+			msg:     "key too long",
+			json:    `{"` + strings.Repeat("k", 129) + `":"foo"}`,
+			wantErr: true,
+		},
+		{
+			msg:     "value too long",
+			json:    `{"team":"` + strings.Repeat("v", 257) + `"}`,
+			wantErr: true,
+		},
+		{
+			msg:     "reserved prefix",
+			json:    `{"AWS:team":"foo"}`,
+			wantErr: true,
+		},
+		{
+			msg:     "tag set by the controller",
+			json:    `{"StackName":"foo"}`,
+			wantErr: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			tags, err := NewResourceTagsFromJSON([]byte(test.json))
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, tags)
+		})
+	}
+}
+
+func TestResourceTagsHash(t *testing.T) {
+	assert.Equal(t, "", ResourceTags(nil).Hash())
+	assert.Equal(t, ResourceTags{"a": "1", "b": "2"}.Hash(), ResourceTags{"b": "2", "a": "1"}.Hash())
+	assert.NotEqual(t, ResourceTags{"a": "1"}.Hash(), ResourceTags{"a": "2"}.Hash())
+}
+
+func TestUpdateResourceTags(t *testing.T) {
+	lbARN := "arn:aws:elasticloadbalancing:eu-central-1:123456789012:loadbalancer/app/stack/1"
+	tgARN := "arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/stack/2"
+
+	cfSvc := &mockCloudFormationClient{outputs: cfMockOutputs{
+		describeStackResources: R(&cloudformation.DescribeStackResourcesOutput{
+			StackResources: []*cloudformation.StackResource{
+				{ResourceType: aws.String("AWS::ElasticLoadBalancingV2::LoadBalancer"), PhysicalResourceId: aws.String(lbARN)},
+				{ResourceType: aws.String("AWS::ElasticLoadBalancingV2::TargetGroup"), PhysicalResourceId: aws.String(tgARN)},
+				{ResourceType: aws.String("AWS::ElasticLoadBalancingV2::Listener"), PhysicalResourceId: aws.String("listener")},
+			},
+		}, nil),
+	}}
+	elbSvc := &mockElbv2Client{outputs: elbv2MockOutputs{
+		describeTags: R(&elbv2.DescribeTagsOutput{
+			TagDescriptions: []*elbv2.TagDescription{{
+				ResourceArn: aws.String(lbARN),
+				Tags: []*elbv2.Tag{
+					{Key: aws.String("team"), Value: aws.String("a")},
+					{Key: aws.String("old"), Value: aws.String("x")},
+					{Key: aws.String(stackNameResourceTag), Value: aws.String("stack")},
+					{Key: aws.String("aws:cloudformation:stack-name"), Value: aws.String("stack")},
+					{Key: aws.String("ingress:namespaces"), Value: aws.String("default")},
+				},
+			}, {
+				ResourceArn: aws.String(tgARN),
+				Tags: []*elbv2.Tag{
+					{Key: aws.String("team"), Value: aws.String("a")},
+				},
+			}},
+		}, nil),
+	}}
+
+	keep := map[string]string{stackNameResourceTag: "", "ingress:namespaces": ""}
+	err := updateResourceTags(cfSvc, elbSvc, "stack", ResourceTags{"team": "b", "cost": "c"}, keep)
+	require.NoError(t, err)
+
+	require.Len(t, elbSvc.removeTagsInputs, 1)
+	assert.Equal(t, []string{lbARN}, aws.StringValueSlice(elbSvc.removeTagsInputs[0].ResourceArns))
+	assert.Equal(t, []string{"old"}, aws.StringValueSlice(elbSvc.removeTagsInputs[0].TagKeys))
+
+	require.Len(t, elbSvc.addTagsInputs, 1)
+	assert.Equal(t, []string{lbARN, tgARN}, aws.StringValueSlice(elbSvc.addTagsInputs[0].ResourceArns))
+	assert.Equal(t, []*elbv2.Tag{
+		{Key: aws.String("cost"), Value: aws.String("c")},
+		{Key: aws.String("team"), Value: aws.String("b")},
+	}, elbSvc.addTagsInputs[0].Tags)
+
+	elbSvc.outputs.addTags = R(nil, errors.New("denied"))
+	assert.Error(t, updateResourceTags(cfSvc, elbSvc, "stack", ResourceTags{"team": "b"}, keep))
+
+	cfSvc.outputs.describeStackResources = R(nil, errors.New("failed"))
+	assert.Error(t, updateResourceTags(cfSvc, elbSvc, "stack", ResourceTags{"team": "b"}, keep))
+}
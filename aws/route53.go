@@ -0,0 +1,236 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	log "github.com/sirupsen/logrus"
+)
+
+// hostedZonesTTL is how long the listed hosted zones are used for the DNS
+// records of the stacks, so they're listed about once per reconciliation
+// instead of for every stack created or updated.
+const hostedZonesTTL = time.Minute
+
+type hostedZone struct {
+	id      string
+	name    string
+	private bool
+}
+
+type dnsRecord struct {
+	hostname     string
+	hostedZoneID string
+}
+
+func findHostedZones(svc route53iface.Route53API) ([]*hostedZone, error) {
+	zones := make([]*hostedZone, 0)
+	err := svc.ListHostedZonesPages(&route53.ListHostedZonesInput{},
+		func(page *route53.ListHostedZonesOutput, lastPage bool) bool {
+			for _, z := range page.HostedZones {
+				zone := &hostedZone{
+					id:   strings.TrimPrefix(aws.StringValue(z.Id), "/hostedzone/"),
+					name: strings.TrimSuffix(aws.StringValue(z.Name), "."),
+				}
+				if z.Config != nil {
+					zone.private = aws.BoolValue(z.Config.PrivateZone)
+				}
+				zones = append(zones, zone)
+			}
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("findHostedZones failed to list hosted zones: %v", err)
+	}
+	return zones, nil
+}
+
+// hostedZoneForHostname returns the hosted zone with the longest name
+// matching the hostname. Zones with the requested visibility are preferred
+// over the others.
+func hostedZoneForHostname(zones []*hostedZone, hostname string, private bool) *hostedZone {
+	var match *hostedZone
+	for _, zone := range zones {
+		if hostname != zone.name && !strings.HasSuffix(hostname, "."+zone.name) {
+			continue
+		}
+
+		if match == nil ||
+			len(zone.name) > len(match.name) ||
+			len(zone.name) == len(match.name) && zone.private == private && match.private != private {
+			match = zone
+		}
+	}
+	return match
+}
+
+// hostedZoneIDs maps the hostnames to the hosted zones the records should be
+// created in. Hostnames without a matching hosted zone are skipped.
+func (a *Adapter) hostedZoneIDs(hostnames []string, scheme string) (map[string]string, error) {
+	zoneIDs, err := a.matchHostedZones(hostnames, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hostname := range hostnames {
+		if _, ok := zoneIDs[hostname]; !ok {
+			log.Warnf("No hosted zone found for hostname %q, skipping DNS record", hostname)
+		}
+	}
+	return zoneIDs, nil
+}
+
+func (a *Adapter) matchHostedZones(hostnames []string, scheme string) (map[string]string, error) {
+	zones, err := a.cachedHostedZones()
+	if err != nil {
+		return nil, err
+	}
+
+	private := scheme == elbv2.LoadBalancerSchemeEnumInternal
+	zoneIDs := make(map[string]string, len(hostnames))
+	for _, hostname := range hostnames {
+		if zone := hostedZoneForHostname(zones, hostname, private); zone != nil {
+			zoneIDs[hostname] = zone.id
+		}
+	}
+	return zoneIDs, nil
+}
+
+// DNSRecordsHash returns the hash of the DNS records a stack of a load
+// balancer with the scheme manages for the hostnames, see HashDNSRecords.
+// It's compared with the hash of the stack to detect changes of the
+// hostnames and of their hosted zones.
+func (a *Adapter) DNSRecordsHash(hostnames []string, scheme string) (string, error) {
+	if len(hostnames) == 0 {
+		return "", nil
+	}
+
+	zoneIDs, err := a.matchHostedZones(hostnames, scheme)
+	if err != nil {
+		return "", err
+	}
+	return HashDNSRecords(zoneIDs), nil
+}
+
+// PlainDNSRecords returns the hostnames which have records without a set
+// identifier, e.g. managed by external-dns, in the hosted zones the records
+// of a load balancer with the scheme are created in. Route53 rejects
+// weighted records with the name and type of such records.
+func (a *Adapter) PlainDNSRecords(hostnames []string, scheme string) ([]string, error) {
+	zoneIDs, err := a.matchHostedZones(hostnames, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	var plain []string
+	for _, hostname := range hostnames {
+		zoneID, ok := zoneIDs[hostname]
+		if !ok {
+			continue
+		}
+		found, err := hasPlainDNSRecord(a.route53, zoneID, hostname)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			plain = append(plain, hostname)
+		}
+	}
+	return plain, nil
+}
+
+// hasPlainDNSRecord returns true if the hosted zone has a record for the
+// hostname without a set identifier, either of one of the types of the
+// weighted records or a CNAME record, which can't coexist with them.
+func hasPlainDNSRecord(svc route53iface.Route53API, zoneID, hostname string) (bool, error) {
+	resp, err := svc.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneID),
+		StartRecordName: aws.String(hostname),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list the records of %q in hosted zone %s: %v", hostname, zoneID, err)
+	}
+
+	// the records are sorted by name, starting with the requested one
+	for _, record := range resp.ResourceRecordSets {
+		name := strings.TrimSuffix(strings.Replace(aws.StringValue(record.Name), `\052`, "*", 1), ".")
+		if !strings.EqualFold(name, hostname) {
+			break
+		}
+		if record.SetIdentifier != nil {
+			continue
+		}
+		switch aws.StringValue(record.Type) {
+		case route53.RRTypeA, route53.RRTypeAaaa, route53.RRTypeTxt, route53.RRTypeCname:
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// cachedHostedZones returns the hosted zones of the account, which are only
+// listed again after hostedZonesTTL.
+func (a *Adapter) cachedHostedZones() ([]*hostedZone, error) {
+	if a.hostedZones != nil && a.clock.Now().Sub(a.hostedZonesListed) < hostedZonesTTL {
+		return a.hostedZones, nil
+	}
+
+	zones, err := findHostedZones(a.route53)
+	if err != nil {
+		return nil, err
+	}
+	a.hostedZones = zones
+	a.hostedZonesListed = a.clock.Now()
+	return zones, nil
+}
+
+// externalDNSOwnershipRecord returns the value of the TXT record external-dns
+// uses to track the owner of a DNS record. Writing it next to the managed
+// records lets both tools coexist and hand over records to each other by
+// changing the owner ID.
+func externalDNSOwnershipRecord(ownerID string) string {
+	return fmt.Sprintf(`"heritage=external-dns,external-dns/owner=%s"`, ownerID)
+}
+
+// HashDNSRecords returns a stable hash of the hostnames DNS records are
+// managed for and of the IDs of their hosted zones. It's used to detect
+// changes to the records of a stack. No records result in an empty hash.
+func HashDNSRecords(zoneIDs map[string]string) string {
+	records := make([]string, 0, len(zoneIDs))
+	for hostname, zoneID := range zoneIDs {
+		records = append(records, hostname+"="+zoneID)
+	}
+	return hashStrings(records)
+}
+
+// HashInternalDomains returns a stable hash of the internal domains denied
+// by a stack. It's used to detect changes to the domains read from a
+// ConfigMap. An empty list results in an empty hash.
+func HashInternalDomains(domains []string) string {
+	return hashStrings(domains)
+}
+
+func hashStrings(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	sorted := make([]string, len(values))
+	copy(sorted, values)
+	sort.Strings(sorted)
+
+	hash := sha256.New()
+	for _, value := range sorted {
+		hash.Write([]byte(value))
+		hash.Write([]byte{'\000'})
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRoute53Client struct {
+	route53iface.Route53API
+	zones   []*route53.HostedZone
+	records []*route53.ResourceRecordSet
+	err     error
+	calls   int
+}
+
+func (m *mockRoute53Client) ListHostedZonesPages(_ *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool) error {
+	m.calls++
+	if m.err != nil {
+		return m.err
+	}
+	fn(&route53.ListHostedZonesOutput{HostedZones: m.zones}, true)
+	return nil
+}
+
+func (m *mockRoute53Client) ListResourceRecordSets(in *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	resp := &route53.ListResourceRecordSetsOutput{}
+	for i, record := range m.records {
+		if aws.StringValue(record.Name) == aws.StringValue(in.StartRecordName)+"." {
+			resp.ResourceRecordSets = m.records[i:]
+			break
+		}
+	}
+	return resp, nil
+}
+
+func TestHostedZoneForHostname(t *testing.T) {
+	zones := []*hostedZone{
+		{id: "public", name: "example.org"},
+		{id: "private", name: "example.org", private: true},
+		{id: "sub", name: "team.example.org"},
+		{id: "other", name: "other-example.org"},
+	}
+
+	for _, test := range []struct {
+		name     string
+		hostname string
+		private  bool
+		zoneID   string
+	}{
+		{name: "apex", hostname: "example.org", zoneID: "public"},
+		{name: "public zone", hostname: "foo.example.org", zoneID: "public"},
+		{name: "private zone", hostname: "foo.example.org", private: true, zoneID: "private"},
+		{name: "longest match", hostname: "foo.team.example.org", zoneID: "sub"},
+		{name: "longest match over visibility", hostname: "foo.team.example.org", private: true, zoneID: "sub"},
+		{name: "no partial label match", hostname: "foo.le.org", zoneID: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			zone := hostedZoneForHostname(zones, test.hostname, test.private)
+			if test.zoneID == "" {
+				require.Nil(t, zone)
+				return
+			}
+			require.NotNil(t, zone)
+			require.Equal(t, test.zoneID, zone.id)
+		})
+	}
+}
+
+func TestHashDNSRecords(t *testing.T) {
+	require.Equal(t, "", HashDNSRecords(nil))
+	require.NotEqual(t, HashDNSRecords(map[string]string{"a.org": "Z1"}), HashDNSRecords(map[string]string{"a.org": "Z1", "b.org": "Z2"}))
+	// a changed hosted zone changes the hash
+	require.NotEqual(t, HashDNSRecords(map[string]string{"a.org": "Z1"}), HashDNSRecords(map[string]string{"a.org": "Z2"}))
+}
+
+func TestDNSRecordsHash(t *testing.T) {
+	svc := &mockRoute53Client{zones: []*route53.HostedZone{
+		{Id: aws.String("/hostedzone/Z1"), Name: aws.String("example.org.")},
+	}}
+	clock := &testClock{now: time.Now()}
+	a := newAdapter(DefaultControllerID, Clients{Route53: svc}).WithClock(clock)
+
+	hash, err := a.DNSRecordsHash(nil, "internet-facing")
+	require.NoError(t, err)
+	require.Equal(t, "", hash)
+	require.Equal(t, 0, svc.calls)
+
+	hash, err = a.DNSRecordsHash([]string{"foo.example.org", "foo.other.org"}, "internet-facing")
+	require.NoError(t, err)
+	require.Equal(t, HashDNSRecords(map[string]string{"foo.example.org": "Z1"}), hash)
+
+	// a more specific zone created later changes the hash
+	clock.now = clock.now.Add(hostedZonesTTL)
+	svc.zones = append(svc.zones, &route53.HostedZone{Id: aws.String("/hostedzone/Z2"), Name: aws.String("foo.example.org.")})
+	hash, err = a.DNSRecordsHash([]string{"foo.example.org", "foo.other.org"}, "internet-facing")
+	require.NoError(t, err)
+	require.Equal(t, HashDNSRecords(map[string]string{"foo.example.org": "Z2"}), hash)
+}
+
+func TestPlainDNSRecords(t *testing.T) {
+	record := func(name, recordType, setIdentifier string) *route53.ResourceRecordSet {
+		r := &route53.ResourceRecordSet{Name: aws.String(name), Type: aws.String(recordType)}
+		if setIdentifier != "" {
+			r.SetIdentifier = aws.String(setIdentifier)
+		}
+		return r
+	}
+	svc := &mockRoute53Client{
+		zones: []*route53.HostedZone{
+			{Id: aws.String("/hostedzone/Z1"), Name: aws.String("example.org.")},
+		},
+		records: []*route53.ResourceRecordSet{
+			record("bar.example.org.", "A", "stack-a"),
+			record("bar.example.org.", "A", "stack-b"),
+			record("baz.example.org.", "MX", ""),
+			record("foo.example.org.", "A", ""),
+			record("foo.example.org.", "TXT", ""),
+			record("qux.example.org.", "CNAME", ""),
+		},
+	}
+	a := newAdapter(DefaultControllerID, Clients{Route53: svc}).WithClock(&testClock{now: time.Now()})
+
+	plain, err := a.PlainDNSRecords([]string{"foo.example.org", "bar.example.org", "baz.example.org", "qux.example.org", "new.example.org", "foo.other.org"}, "internet-facing")
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo.example.org", "qux.example.org"}, plain)
+}
+
+func TestHostedZoneIDsCache(t *testing.T) {
+	svc := &mockRoute53Client{zones: []*route53.HostedZone{
+		{Id: aws.String("/hostedzone/Z1"), Name: aws.String("example.org.")},
+	}}
+	clock := &testClock{now: time.Now()}
+	a := newAdapter(DefaultControllerID, Clients{Route53: svc}).WithClock(clock)
+
+	for i := 0; i < 3; i++ {
+		zoneIDs, err := a.hostedZoneIDs([]string{"foo.example.org", "foo.other.org"}, "internet-facing")
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"foo.example.org": "Z1"}, zoneIDs)
+	}
+	require.Equal(t, 1, svc.calls)
+
+	// the zones are listed again once the cached ones expired
+	clock.now = clock.now.Add(hostedZonesTTL)
+	svc.zones = append(svc.zones, &route53.HostedZone{Id: aws.String("/hostedzone/Z2"), Name: aws.String("other.org.")})
+	zoneIDs, err := a.hostedZoneIDs([]string{"foo.other.org"}, "internet-facing")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"foo.other.org": "Z2"}, zoneIDs)
+	require.Equal(t, 2, svc.calls)
+
+	// failures aren't cached
+	clock.now = clock.now.Add(hostedZonesTTL)
+	svc.err = errors.New("throttled")
+	_, err = a.hostedZoneIDs([]string{"foo.other.org"}, "internet-facing")
+	require.Error(t, err)
+	svc.err = nil
+	_, err = a.hostedZoneIDs([]string{"foo.other.org"}, "internet-facing")
+	require.NoError(t, err)
+	require.Equal(t, 4, svc.calls)
+}
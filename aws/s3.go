@@ -0,0 +1,245 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	log "github.com/sirupsen/logrus"
+)
+
+// elbLogDeliveryPrincipal is the service principal writing the access logs
+// of load balancers in the regions without an ELB account.
+const elbLogDeliveryPrincipal = "logdelivery.elasticloadbalancing.amazonaws.com"
+
+// elbAccountIDs are the IDs of the AWS accounts of Elastic Load Balancing
+// writing the access logs in each region, see
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/enable-access-logging.html
+var elbAccountIDs = map[string]string{
+	"us-east-1":      "127311923021",
+	"us-east-2":      "033677994240",
+	"us-west-1":      "027434742980",
+	"us-west-2":      "797873946194",
+	"af-south-1":     "098369216593",
+	"ca-central-1":   "985666609251",
+	"eu-central-1":   "054676820928",
+	"eu-west-1":      "156460612806",
+	"eu-west-2":      "652711504416",
+	"eu-south-1":     "635631232127",
+	"eu-west-3":      "009996457667",
+	"eu-north-1":     "897822967062",
+	"ap-east-1":      "754344448648",
+	"ap-northeast-1": "582318560864",
+	"ap-northeast-2": "600734575887",
+	"ap-northeast-3": "383597477331",
+	"ap-southeast-1": "114774131450",
+	"ap-southeast-2": "783225319266",
+	"ap-south-1":     "718504428378",
+	"me-south-1":     "076674570225",
+	"sa-east-1":      "507241528517",
+	"us-gov-west-1":  "048591011584",
+	"us-gov-east-1":  "190560391635",
+	"cn-north-1":     "638102146993",
+	"cn-northwest-1": "037604701340",
+}
+
+// ValidateAccessLogsBucket checks that the S3 bucket of the access logs of
+// the load balancers is in their region, and so in their partition, and
+// that its policy allows Elastic Load Balancing to write the logs.
+// Otherwise the creation of the stacks fails with an opaque error. Nothing
+// is checked without access logs, and the checks which can't be done for
+// lack of permissions are skipped.
+func (a *Adapter) ValidateAccessLogsBucket() error {
+	if a.albLogsS3Bucket == "" || a.s3 == nil {
+		return nil
+	}
+
+	err := validateAccessLogsBucket(a.s3, a.albLogsS3Bucket, a.albLogsS3Prefix, a.Region())
+	if isAccessDeniedError(err) {
+		log.Warnf("Skipping validation of the access logs bucket %s: %v", a.albLogsS3Bucket, err)
+		return nil
+	}
+	return err
+}
+
+// Region returns the region of the load balancers, or an empty string if
+// the clients aren't the ones of the AWS SDK.
+func (a *Adapter) Region() string {
+	if c := sdkClient(a.elbv2); c != nil {
+		return aws.StringValue(c.Config.Region)
+	}
+	return ""
+}
+
+func validateAccessLogsBucket(svc s3iface.S3API, bucket, prefix, region string) error {
+	if region == "" {
+		return nil
+	}
+
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return fmt.Errorf("unknown region %s of the load balancers", region)
+	}
+
+	location, err := svc.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchBucket {
+		return fmt.Errorf("access logs bucket %s not found in partition %s of region %s, buckets of other partitions can't be used", bucket, partition.ID(), region)
+	}
+	if err != nil {
+		return err
+	}
+
+	bucketRegion := s3.NormalizeBucketLocation(aws.StringValue(location.LocationConstraint))
+	if bucketRegion != region {
+		return fmt.Errorf("access logs bucket %s is in region %s, but load balancers can only write access logs to buckets in their region %s", bucket, bucketRegion, region)
+	}
+
+	policy, err := svc.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: aws.String(bucket)})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchBucketPolicy" {
+		return fmt.Errorf("access logs bucket %s has no bucket policy allowing Elastic Load Balancing to write the logs", bucket)
+	}
+	if err != nil {
+		return err
+	}
+
+	return validateAccessLogsBucketPolicy(aws.StringValue(policy.Policy), bucket, prefix, region, partition.ID())
+}
+
+// bucketPolicy is the subset of an S3 bucket policy relevant for writing
+// access logs.
+type bucketPolicy struct {
+	Statement []struct {
+		Effect    string
+		Principal policyPrincipal
+		Action    stringOrList
+		Resource  stringOrList
+	}
+}
+
+type policyPrincipal struct {
+	Any     bool
+	AWS     stringOrList
+	Service stringOrList
+}
+
+func (p *policyPrincipal) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		p.Any = s == "*"
+		return nil
+	}
+
+	var principals struct {
+		AWS     stringOrList
+		Service stringOrList
+	}
+	if err := json.Unmarshal(b, &principals); err != nil {
+		return err
+	}
+	p.AWS = principals.AWS
+	p.Service = principals.Service
+	return nil
+}
+
+// stringOrList is a policy element which can be a single string or a list.
+type stringOrList []string
+
+func (l *stringOrList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*l = []string{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func validateAccessLogsBucketPolicy(policy, bucket, prefix, region, partition string) error {
+	var p bucketPolicy
+	if err := json.Unmarshal([]byte(policy), &p); err != nil {
+		return fmt.Errorf("failed to parse the policy of the access logs bucket %s: %v", bucket, err)
+	}
+
+	accountID := elbAccountIDs[region]
+	object := "arn:" + partition + ":s3:::" + bucket + "/"
+	if prefix != "" {
+		object += prefix + "/"
+	}
+	object += "AWSLogs/"
+
+	var otherPartitionResources []string
+	for _, statement := range p.Statement {
+		if statement.Effect != "Allow" || !allowsPutObject(statement.Action) || !isELBPrincipal(statement.Principal, accountID) {
+			continue
+		}
+
+		for _, resource := range statement.Resource {
+			if matchesResource(resource, object) {
+				return nil
+			}
+			if !strings.HasPrefix(resource, "arn:"+partition+":") {
+				otherPartitionResources = append(otherPartitionResources, resource)
+			}
+		}
+	}
+
+	if len(otherPartitionResources) > 0 {
+		return fmt.Errorf("policy of the access logs bucket %s grants Elastic Load Balancing access to %s, but the load balancers are in partition %s", bucket, strings.Join(otherPartitionResources, ", "), partition)
+	}
+
+	principal := elbLogDeliveryPrincipal
+	if accountID != "" {
+		principal = fmt.Sprintf("arn:%s:iam::%s:root", partition, accountID)
+	}
+	return fmt.Errorf("policy of the access logs bucket %s doesn't allow %s to put objects to %s*", bucket, principal, object)
+}
+
+func allowsPutObject(actions []string) bool {
+	for _, action := range actions {
+		switch action {
+		case "*", "s3:*", "s3:PutObject":
+			return true
+		}
+	}
+	return false
+}
+
+func isELBPrincipal(principal policyPrincipal, accountID string) bool {
+	if principal.Any {
+		return true
+	}
+	for _, service := range principal.Service {
+		if service == elbLogDeliveryPrincipal {
+			return true
+		}
+	}
+	if accountID == "" {
+		return false
+	}
+	for _, p := range principal.AWS {
+		if p == accountID || strings.HasSuffix(p, ":iam::"+accountID+":root") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesResource returns true if the resource of the policy, which may end
+// with a wildcard, covers the object ARNs starting with the prefix.
+func matchesResource(resource, prefix string) bool {
+	if strings.HasSuffix(resource, "*") {
+		r := strings.TrimSuffix(resource, "*")
+		return strings.HasPrefix(prefix, r) || strings.HasPrefix(r, prefix)
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockedS3Client struct {
+	s3iface.S3API
+	location    string
+	locationErr error
+	policy      string
+	policyErr   error
+}
+
+func (m mockedS3Client) GetBucketLocation(*s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	if m.locationErr != nil {
+		return nil, m.locationErr
+	}
+	return &s3.GetBucketLocationOutput{LocationConstraint: aws.String(m.location)}, nil
+}
+
+func (m mockedS3Client) GetBucketPolicy(*s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	if m.policyErr != nil {
+		return nil, m.policyErr
+	}
+	return &s3.GetBucketPolicyOutput{Policy: aws.String(m.policy)}, nil
+}
+
+func TestValidateAccessLogsBucket(t *testing.T) {
+	for _, test := range []struct {
+		msg       string
+		given     mockedS3Client
+		region    string
+		prefix    string
+		wantError string
+	}{
+		{
+			msg: "bucket policy granting the ELB account",
+			given: mockedS3Client{
+				location: "eu-central-1",
+				policy:   `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::054676820928:root"},"Action":"s3:PutObject","Resource":"arn:aws:s3:::logs/*"}]}`,
+			},
+			region: "eu-central-1",
+		},
+		{
+			msg: "bucket policy granting the log delivery service for the prefix",
+			given: mockedS3Client{
+				location: "eu-central-1",
+				policy:   `{"Statement":[{"Effect":"Allow","Principal":{"Service":["logdelivery.elasticloadbalancing.amazonaws.com"]},"Action":["s3:PutObject"],"Resource":["arn:aws:s3:::logs/alb/AWSLogs/123456789012/*"]}]}`,
+			},
+			region: "eu-central-1",
+			prefix: "alb",
+		},
+		{
+			msg: "bucket in us-east-1",
+			given: mockedS3Client{
+				policy: `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"127311923021"},"Action":"s3:*","Resource":"arn:aws:s3:::logs/*"}]}`,
+			},
+			region: "us-east-1",
+		},
+		{
+			msg: "bucket in China",
+			given: mockedS3Client{
+				location: "cn-north-1",
+				policy:   `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws-cn:iam::638102146993:root"},"Action":"s3:PutObject","Resource":"arn:aws-cn:s3:::logs/*"}]}`,
+			},
+			region: "cn-north-1",
+		},
+		{
+			msg:       "bucket in another partition",
+			given:     mockedS3Client{locationErr: awserr.New(s3.ErrCodeNoSuchBucket, "not found", nil)},
+			region:    "cn-north-1",
+			wantError: "access logs bucket logs not found in partition aws-cn of region cn-north-1, buckets of other partitions can't be used",
+		},
+		{
+			msg:       "bucket in another region",
+			given:     mockedS3Client{location: "EU"},
+			region:    "eu-central-1",
+			wantError: "access logs bucket logs is in region eu-west-1, but load balancers can only write access logs to buckets in their region eu-central-1",
+		},
+		{
+			msg: "bucket without policy",
+			given: mockedS3Client{
+				location:  "eu-central-1",
+				policyErr: awserr.New("NoSuchBucketPolicy", "no policy", nil),
+			},
+			region:    "eu-central-1",
+			wantError: "access logs bucket logs has no bucket policy allowing Elastic Load Balancing to write the logs",
+		},
+		{
+			msg: "bucket policy for another partition",
+			given: mockedS3Client{
+				location: "cn-north-1",
+				policy:   `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::638102146993:root"},"Action":"s3:PutObject","Resource":"arn:aws:s3:::logs/*"}]}`,
+			},
+			region:    "cn-north-1",
+			wantError: "policy of the access logs bucket logs grants Elastic Load Balancing access to arn:aws:s3:::logs/*, but the load balancers are in partition aws-cn",
+		},
+		{
+			msg: "bucket policy granting the ELB account of another region",
+			given: mockedS3Client{
+				location: "eu-central-1",
+				policy:   `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::156460612806:root"},"Action":"s3:PutObject","Resource":"arn:aws:s3:::logs/*"}]}`,
+			},
+			region:    "eu-central-1",
+			wantError: "policy of the access logs bucket logs doesn't allow arn:aws:iam::054676820928:root to put objects to arn:aws:s3:::logs/AWSLogs/*",
+		},
+		{
+			msg: "bucket policy for another prefix",
+			given: mockedS3Client{
+				location: "eu-central-1",
+				policy:   `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::054676820928:root"},"Action":"s3:PutObject","Resource":"arn:aws:s3:::logs/nlb/*"}]}`,
+			},
+			region:    "eu-central-1",
+			prefix:    "alb",
+			wantError: "policy of the access logs bucket logs doesn't allow arn:aws:iam::054676820928:root to put objects to arn:aws:s3:::logs/alb/AWSLogs/*",
+		},
+		{
+			msg:   "region of the load balancers unknown",
+			given: mockedS3Client{},
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			err := validateAccessLogsBucket(test.given, "logs", test.prefix, test.region)
+			if test.wantError == "" {
+				require.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.wantError)
+			}
+		})
+	}
+}
+
+func TestValidateAccessLogsBucketAccessDenied(t *testing.T) {
+	svc := mockedS3Client{locationErr: awserr.New("AccessDenied", "denied", nil)}
+	assert.True(t, isAccessDeniedError(validateAccessLogsBucket(svc, "logs", "", "eu-central-1")))
+}
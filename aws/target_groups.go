@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	cloudformation "github.com/mweagle/go-cloudformation"
+)
+
+// NewExternalTargetGroupARNs parses a comma separated list of ARNs of
+// target groups which aren't created by the controller. The ARNs are
+// returned sorted, without duplicates.
+func NewExternalTargetGroupARNs(value string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		a, err := arn.Parse(s)
+		if err != nil || a.Service != "elasticloadbalancing" || !strings.HasPrefix(a.Resource, "targetgroup/") {
+			return nil, fmt.Errorf("invalid target group ARN %q", s)
+		}
+		seen[s] = true
+	}
+
+	var arns []string
+	for s := range seen {
+		arns = append(arns, s)
+	}
+	sort.Strings(arns)
+	return arns, nil
+}
+
+// forwardAction returns the action of a listener forwarding to the target
+// group of the template. With external target groups, requests are split
+// evenly between them and the target group of the template.
+func forwardAction(targetGroup string, externalTargetGroupARNs []string) cloudformation.ElasticLoadBalancingV2ListenerAction {
+	if len(externalTargetGroupARNs) == 0 {
+		return cloudformation.ElasticLoadBalancingV2ListenerAction{
+			Type:           cloudformation.String("forward"),
+			TargetGroupArn: cloudformation.Ref(targetGroup).String(),
+		}
+	}
+
+	targetGroups := cloudformation.ElasticLoadBalancingV2ListenerTargetGroupTupleList{
+		{
+			TargetGroupArn: cloudformation.Ref(targetGroup).String(),
+			Weight:         cloudformation.Integer(1),
+		},
+	}
+	for _, targetGroupARN := range externalTargetGroupARNs {
+		targetGroups = append(targetGroups, cloudformation.ElasticLoadBalancingV2ListenerTargetGroupTuple{
+			TargetGroupArn: cloudformation.String(targetGroupARN),
+			Weight:         cloudformation.Integer(1),
+		})
+	}
+	return cloudformation.ElasticLoadBalancingV2ListenerAction{
+		Type: cloudformation.String("forward"),
+		ForwardConfig: &cloudformation.ElasticLoadBalancingV2ListenerForwardConfig{
+			TargetGroups: &targetGroups,
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExternalTargetGroupARNs(t *testing.T) {
+	tgA := "arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/a/1"
+	tgB := "arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/b/2"
+	for _, test := range []struct {
+		msg       string
+		given     string
+		want      []string
+		wantError bool
+	}{
+		{
+			msg:   "single target group",
+			given: tgA,
+			want:  []string{tgA},
+		},
+		{
+			msg:   "target groups are sorted without duplicates",
+			given: tgB + ", " + tgA + "," + tgB + ",",
+			want:  []string{tgA, tgB},
+		},
+		{
+			msg:   "empty list",
+			given: " , ",
+		},
+		{
+			msg:       "not an ARN",
+			given:     "tg-1",
+			wantError: true,
+		},
+		{
+			msg:       "ARN of a load balancer",
+			given:     "arn:aws:elasticloadbalancing:eu-central-1:123456789012:loadbalancer/app/lb/1",
+			wantError: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			got, err := NewExternalTargetGroupARNs(test.given)
+			if test.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestGenerateTemplateExternalTargetGroups(t *testing.T) {
+	tg := "arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/a/1"
+	actions := func(spec *stackSpec) map[string]interface{} {
+		generated, err := generateTemplate(spec)
+		require.NoError(t, err)
+
+		var template struct {
+			Parameters map[string]interface{}
+			Resources  map[string]struct {
+				Properties struct {
+					DefaultActions []map[string]interface{}
+				}
+			}
+		}
+		require.NoError(t, json.Unmarshal([]byte(generated), &template))
+		if len(spec.externalTargetGroupARNs) > 0 && spec.loadbalancerType == LoadBalancerTypeApplication {
+			assert.Contains(t, template.Parameters, parameterExternalTargetGroupARNsParameter)
+		}
+		assert.Equal(t, template.Resources["HTTPListener"].Properties.DefaultActions, template.Resources["HTTPSListener"].Properties.DefaultActions)
+		return template.Resources["HTTPSListener"].Properties.DefaultActions[0]
+	}
+
+	spec := &stackSpec{
+		loadbalancerType: LoadBalancerTypeApplication,
+		certificateARNs:  map[string]time.Time{"arn:cert": {}},
+	}
+	action := actions(spec)
+	assert.Equal(t, map[string]interface{}{"Ref": "TG"}, action["TargetGroupArn"])
+	assert.NotContains(t, action, "ForwardConfig")
+
+	spec.externalTargetGroupARNs = []string{tg}
+	action = actions(spec)
+	assert.NotContains(t, action, "TargetGroupArn")
+	assert.Equal(t, map[string]interface{}{
+		"TargetGroups": []interface{}{
+			map[string]interface{}{"TargetGroupArn": map[string]interface{}{"Ref": "TG"}, "Weight": float64(1)},
+			map[string]interface{}{"TargetGroupArn": tg, "Weight": float64(1)},
+		},
+	}, action["ForwardConfig"])
+
+	spec.loadbalancerType = LoadBalancerTypeNetwork
+	spec.nlbHTTPEnabled = true
+	action = actions(spec)
+	assert.NotContains(t, action, "ForwardConfig")
+}
@@ -0,0 +1,72 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// TargetHealth is the health of an instance in the target groups of the
+// stacks it's registered in.
+type TargetHealth struct {
+	InstanceID string
+	// Healthy is true if the instance is healthy in all target groups.
+	Healthy bool
+	// Reason is the reason of the first target group the instance isn't
+	// healthy in.
+	Reason string
+}
+
+// TargetHealthByIP returns the health of the instances registered in the
+// target groups of the stacks by their private IP addresses. Instances not
+// registered in any of the target groups are missing. It returns false if
+// the stacks have no target groups.
+func (a *Adapter) TargetHealthByIP(stacks []*Stack) (map[string]*TargetHealth, bool, error) {
+	targetGroupARNs := make([]string, 0, len(stacks))
+	for _, stack := range stacks {
+		if stack.TargetGroupARN != "" {
+			targetGroupARNs = append(targetGroupARNs, stack.TargetGroupARN)
+		}
+	}
+	if len(targetGroupARNs) == 0 {
+		return nil, false, nil
+	}
+
+	health := make(map[string]*TargetHealth)
+	for _, arn := range targetGroupARNs {
+		resp, err := a.elbv2.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(arn),
+		})
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to describe the health of the targets of target group %s: %v", arn, err)
+		}
+		for _, description := range resp.TargetHealthDescriptions {
+			id := aws.StringValue(description.Target.Id)
+			state := aws.StringValue(description.TargetHealth.State)
+			if state == elbv2.TargetHealthStateEnumUnused {
+				continue
+			}
+			h, ok := health[id]
+			if !ok {
+				h = &TargetHealth{InstanceID: id, Healthy: true}
+				health[id] = h
+			}
+			if h.Healthy && state != elbv2.TargetHealthStateEnumHealthy {
+				h.Healthy = false
+				h.Reason = fmt.Sprintf("%s in target group %s", state, arn)
+				if reason := aws.StringValue(description.TargetHealth.Reason); reason != "" {
+					h.Reason += ": " + reason
+				}
+			}
+		}
+	}
+
+	result := make(map[string]*TargetHealth, len(health))
+	for id, h := range health {
+		if details, ok := a.ec2Details[id]; ok && details.ip != "" {
+			result[details.ip] = h
+		}
+	}
+	return result, true, nil
+}
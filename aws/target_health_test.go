@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetHealthByIP(t *testing.T) {
+	elbv2Client := &mockElbv2Client{}
+	a := &Adapter{
+		elbv2: elbv2Client,
+		ec2Details: map[string]*instanceDetails{
+			"i-1": {id: "i-1", ip: "10.0.0.1"},
+			"i-2": {id: "i-2", ip: "10.0.0.2"},
+			"i-3": {id: "i-3", ip: "10.0.0.3"},
+		},
+	}
+
+	_, ok, err := a.TargetHealthByIP([]*Stack{{Name: "lambda"}})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	elbv2Client.outputs.describeTargetHealth = R(targetHealth(map[string]string{
+		"i-1": elbv2.TargetHealthStateEnumHealthy,
+		"i-2": elbv2.TargetHealthStateEnumInitial,
+		"i-3": elbv2.TargetHealthStateEnumUnused,
+		"i-4": elbv2.TargetHealthStateEnumHealthy,
+	}), nil)
+	health, ok, err := a.TargetHealthByIP([]*Stack{{TargetGroupARN: "arn:tg-1"}, {TargetGroupARN: "arn:tg-2"}})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, elbv2Client.dthinputs, 2)
+	require.Equal(t, map[string]*TargetHealth{
+		"10.0.0.1": {InstanceID: "i-1", Healthy: true},
+		"10.0.0.2": {InstanceID: "i-2", Reason: "initial in target group arn:tg-1"},
+	}, health)
+
+	elbv2Client.outputs.describeTargetHealth = R(nil, errors.New("failed"))
+	_, _, err = a.TargetHealthByIP([]*Stack{{TargetGroupARN: "arn:tg-1"}})
+	require.Error(t, err)
+}
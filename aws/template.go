@@ -0,0 +1,233 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TemplateSettings hold the settings of the stacks which aren't specific to
+// a load balancer. They are configured on the Adapter, e.g. by the flags of
+// the controller, and can be given directly to render the templates the
+// controller would create without access to AWS.
+type TemplateSettings struct {
+	ClusterID    string
+	VPCID        string
+	ControllerID string
+	// Subnets are the IDs of the subnets of the load balancer.
+	Subnets             []string
+	HealthCheckPath     string
+	HealthCheckPort     uint
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	TargetPort          uint
+	TargetHTTPS         bool
+	// TargetIPs registers the instances in the target group by their IP
+	// addresses instead of their IDs, e.g. in the account of a role.
+	TargetIPs                  bool
+	CreationTimeout            time.Duration
+	StackTerminationProtection bool
+	IdleConnectionTimeout      time.Duration
+	DeregistrationDelayTimeout time.Duration
+	ALBLogsS3Bucket            string
+	ALBLogsS3Prefix            string
+	HTTPRedirectToHTTPS        bool
+	NLBCrossZone               bool
+	NLBHTTPEnabled             bool
+	InternalDomains            []string
+	DenyInternalDomains        bool
+	// DenyInternalDomainsResponse, DenyInternalDomainsResponseContentType
+	// and DenyInternalDomainsResponseStatusCode are the response for
+	// requests to internal domains.
+	DenyInternalDomainsResponse            string
+	DenyInternalDomainsResponseContentType string
+	DenyInternalDomainsResponseStatusCode  int
+	// StackTags are additional tags of the stacks.
+	StackTags  map[string]string
+	DNSOwnerID string
+	// HostedZoneIDs maps the DNS hostnames of the load balancer to the IDs
+	// of their hosted zones. No DNS records are created for hostnames
+	// without a hosted zone.
+	HostedZoneIDs map[string]string
+}
+
+// DefaultTemplateSettings returns the settings of an Adapter without any
+// configuration for the cluster in the VPC.
+func DefaultTemplateSettings(clusterID, vpcID string) *TemplateSettings {
+	return &TemplateSettings{
+		ClusterID:           clusterID,
+		VPCID:               vpcID,
+		ControllerID:        DefaultControllerID,
+		HealthCheckPath:     DefaultHealthCheckPath,
+		HealthCheckPort:     DefaultHealthCheckPort,
+		HealthCheckInterval: DefaultHealthCheckInterval,
+		HealthCheckTimeout:  DefaultHealthCheckTimeout,
+		TargetPort:          DefaultTargetPort,
+		CreationTimeout:     DefaultCreationTimeout,
+		ALBLogsS3Bucket:     DefaultAlbS3LogsBucket,
+		ALBLogsS3Prefix:     DefaultAlbS3LogsPrefix,
+		NLBCrossZone:        DefaultNLBCrossZone,
+		NLBHTTPEnabled:      DefaultNLBHTTPEnabled,
+		DNSOwnerID:          DefaultControllerID,
+	}
+}
+
+// Template is a rendered stack: the body of its CloudFormation template and
+// the parameters and tags passed along with it.
+type Template struct {
+	Body       string
+	Parameters map[string]string
+	Tags       map[string]string
+}
+
+// RenderTemplate renders the stack the controller creates for the load
+// balancer with the given options. It's the same template the Adapter
+// creates and updates stacks with.
+func RenderTemplate(stackName string, settings *TemplateSettings, opts *StackOptions) (*Template, error) {
+	spec, err := newStackSpec(stackName, settings, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := generateTemplate(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &Template{
+		Body:       body,
+		Parameters: make(map[string]string),
+		Tags:       convertCloudFormationTags(stackTags(spec)),
+	}
+	for _, param := range stackParameters(spec) {
+		template.Parameters[aws.StringValue(param.ParameterKey)] = aws.StringValue(param.ParameterValue)
+	}
+	return template, nil
+}
+
+func newStackSpec(stackName string, settings *TemplateSettings, opts *StackOptions) (*stackSpec, error) {
+	if _, ok := SSLPolicies[opts.SSLPolicy]; !ok {
+		return nil, fmt.Errorf("invalid SSLPolicy '%s' defined", opts.SSLPolicy)
+	}
+
+	spec := &stackSpec{
+		name:            stackName,
+		scheme:          opts.Scheme,
+		ownerIngress:    opts.Owner,
+		certificateARNs: opts.CertificateARNs,
+		securityGroupID: opts.SecurityGroup,
+		subnets:         settings.Subnets,
+		vpcID:           settings.VPCID,
+		clusterID:       settings.ClusterID,
+		healthCheck: &healthCheck{
+			path:     settings.HealthCheckPath,
+			port:     settings.HealthCheckPort,
+			interval: settings.HealthCheckInterval,
+			timeout:  settings.HealthCheckTimeout,
+		},
+		targetPort:                        settings.TargetPort,
+		targetHTTPS:                       settings.TargetHTTPS,
+		targetIPs:                         settings.TargetIPs,
+		timeoutInMinutes:                  uint(settings.CreationTimeout.Minutes()),
+		stackTerminationProtection:        settings.StackTerminationProtection,
+		idleConnectionTimeoutSeconds:      uint(settings.IdleConnectionTimeout.Seconds()),
+		deregistrationDelayTimeoutSeconds: uint(settings.DeregistrationDelayTimeout.Seconds()),
+		controllerID:                      settings.ControllerID,
+		sslPolicy:                         opts.SSLPolicy,
+		ipAddressType:                     opts.IPAddressType,
+		loadbalancerType:                  opts.LoadBalancerType,
+		albLogsS3Bucket:                   settings.ALBLogsS3Bucket,
+		albLogsS3Prefix:                   settings.ALBLogsS3Prefix,
+		wafWebAclId:                       opts.WAFWebACLID,
+		wafRateLimit:                      opts.WAFRateLimit,
+		wafRateLimitKey:                   wafRateLimitKey(opts.WAFRateLimitKey),
+		wafManagedRuleGroups:              opts.WAFManagedRuleGroups,
+		cwAlarms:                          opts.CWAlarms,
+		templateFragments:                 opts.TemplateFragments,
+		httpRedirectToHTTPS:               settings.HTTPRedirectToHTTPS,
+		nlbCrossZone:                      settings.NLBCrossZone,
+		nlbHTTPEnabled:                    settings.NLBHTTPEnabled,
+		http2:                             opts.HTTP2,
+		preserveHostHeader:                opts.PreserveHostHeader,
+		httpDisabled:                      opts.HTTPDisabled,
+		frontingNLB:                       opts.FrontingNLB && opts.LoadBalancerType == LoadBalancerTypeApplication,
+		nlbSecurityGroup:                  opts.NLBSecurityGroup && opts.LoadBalancerType == LoadBalancerTypeNetwork,
+		slowStartDurationSeconds:          uint(opts.SlowStart.Seconds()),
+		clientKeepAliveSeconds:            uint(opts.ClientKeepAlive.Seconds()),
+		healthCheckMatcher:                opts.HealthCheckMatcher,
+		preserveClientIP:                  opts.PreserveClientIP,
+		proxyProtocolV2:                   opts.ProxyProtocolV2 && opts.LoadBalancerType == LoadBalancerTypeNetwork,
+		clientRoutingPolicy:               opts.ClientRoutingPolicy,
+		capacityUnits:                     opts.CapacityUnits,
+		defaultCertificateARN:             opts.DefaultCertificateARN,
+		externalTargetGroupARNs:           opts.ExternalTargetGroupARNs,
+		elasticIPs:                        opts.ElasticIPs,
+		ipv4Pool:                          opts.IPv4Pool,
+		endpointServicePrincipals:         opts.EndpointServicePrincipals,
+		healthyThresholdCount:             opts.HealthyThresholdCount,
+		unhealthyThresholdCount:           opts.UnhealthyThresholdCount,
+		listenerRules:                     opts.ListenerRules,
+		authentication:                    opts.Authentication,
+		resourceTags:                      opts.ResourceTags,
+		templateResourceTags:              opts.TemplateResourceTags,
+		loadBalancerAttributes:            opts.LoadBalancerAttributes,
+		targetGroupAttributes:             opts.TargetGroupAttributes,
+		tags:                              settings.StackTags,
+		internalDomains:                   settings.InternalDomains,
+		denyInternalDomains:               settings.DenyInternalDomains,
+		denyInternalDomainsOverride:       opts.DenyInternalDomains,
+		denyInternalDomainsResponse: denyResp{
+			body:        settings.DenyInternalDomainsResponse,
+			statusCode:  settings.DenyInternalDomainsResponseStatusCode,
+			contentType: settings.DenyInternalDomainsResponseContentType,
+		},
+		denyInternalDomainsResponseOverride: denyResp{
+			body:        opts.DenyInternalDomainsResponse,
+			statusCode:  opts.DenyInternalDomainsResponseStatusCode,
+			contentType: opts.DenyInternalDomainsResponseContentType,
+		},
+		dnsHostnamesHash: HashDNSRecords(settings.HostedZoneIDs),
+		dnsOwnerID:       settings.DNSOwnerID,
+		namespacesTag:    NamespacesTagValue(opts.Namespaces),
+	}
+
+	// only application load balancers can forward to Lambda functions
+	if opts.LoadBalancerType == LoadBalancerTypeApplication {
+		spec.lambdaTarget = opts.LambdaTarget
+	}
+
+	if len(opts.InternalDomains) > 0 {
+		spec.internalDomains = opts.InternalDomains
+		spec.internalDomainsHash = HashInternalDomains(opts.InternalDomains)
+	}
+
+	switch opts.DenyInternalDomains {
+	case "true":
+		spec.denyInternalDomains = true
+	case "false":
+		spec.denyInternalDomains = false
+	}
+
+	if opts.DenyInternalDomainsResponse != "" {
+		spec.denyInternalDomainsResponse.body = opts.DenyInternalDomainsResponse
+	}
+	if opts.DenyInternalDomainsResponseContentType != "" {
+		spec.denyInternalDomainsResponse.contentType = opts.DenyInternalDomainsResponseContentType
+	}
+	if opts.DenyInternalDomainsResponseStatusCode > 0 {
+		spec.denyInternalDomainsResponse.statusCode = opts.DenyInternalDomainsResponseStatusCode
+	}
+
+	for _, hostname := range opts.DNSHostnames {
+		if zoneID, ok := settings.HostedZoneIDs[hostname]; ok {
+			spec.dnsRecords = append(spec.dnsRecords, &dnsRecord{hostname: hostname, hostedZoneID: zoneID})
+		}
+	}
+	sort.Slice(spec.dnsRecords, func(i, j int) bool {
+		return spec.dnsRecords[i].hostname < spec.dnsRecords[j].hostname
+	})
+
+	return spec, nil
+}
@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+)
+
+// requiredOutputs are the outputs of the stacks read by the controller. They
+// can't be set by template fragments.
+var requiredOutputs = []string{outputLoadBalancerARN, outputTargetGroupARN, outputLoadBalancerDNSName}
+
+// shortFormFunction matches the short form of intrinsic functions, e.g.
+// !Ref, which is lost when the YAML is converted to JSON.
+var shortFormFunction = regexp.MustCompile(`(^|[\s\[{,:-])!(Ref|GetAtt|GetAZs|Base64|Cidr|FindInMap|ImportValue|Join|Select|Split|Sub|Transform|And|Equals|If|Not|Or|Condition)\b`)
+
+// TemplateFragment is a part of a CloudFormation template which is merged
+// into the templates of the stacks, e.g. to add custom resources. Its
+// entries replace the generated ones with the same name.
+type TemplateFragment struct {
+	Conditions map[string]json.RawMessage `json:"Conditions,omitempty"`
+	Mappings   map[string]json.RawMessage `json:"Mappings,omitempty"`
+	Resources  map[string]json.RawMessage `json:"Resources,omitempty"`
+	Outputs    map[string]json.RawMessage `json:"Outputs,omitempty"`
+}
+
+// TemplateFragments are the fragments merged into the templates of the
+// stacks, in order.
+type TemplateFragments []*TemplateFragment
+
+// NewTemplateFragmentFromYAML parses a template fragment from YAML or JSON.
+// Only the Conditions, Mappings, Resources and Outputs sections are
+// supported, resources must have a type and the outputs read by the
+// controller can't be set. Intrinsic functions must use the full form, e.g.
+// Ref or Fn::GetAtt.
+func NewTemplateFragmentFromYAML(b []byte) (*TemplateFragment, error) {
+	if m := shortFormFunction.FindSubmatch(b); m != nil {
+		return nil, fmt.Errorf("short form of intrinsic function !%s isn't supported", m[2])
+	}
+
+	data, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+
+	fragment := &TemplateFragment{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(fragment); err != nil {
+		return nil, err
+	}
+
+	if len(fragment.Conditions)+len(fragment.Mappings)+len(fragment.Resources)+len(fragment.Outputs) == 0 {
+		return nil, errors.New("empty template fragment")
+	}
+	for name, resource := range fragment.Resources {
+		var r struct {
+			Type string
+		}
+		if err := json.Unmarshal(resource, &r); err != nil || r.Type == "" {
+			return nil, fmt.Errorf("resource %s has no type", name)
+		}
+	}
+	for _, output := range requiredOutputs {
+		if _, ok := fragment.Outputs[output]; ok {
+			return nil, fmt.Errorf("output %s is required by the controller and can't be set", output)
+		}
+	}
+
+	return fragment, nil
+}
+
+// Hash returns the hash of the fragments to detect changes, or an empty
+// string if there are none.
+func (f TemplateFragments) Hash() string {
+	if len(f) == 0 {
+		return ""
+	}
+
+	buf, err := json.Marshal(f)
+	if err != nil {
+		log.Errorf("failed to marshal template fragments: %v", err)
+		return ""
+	}
+
+	hash := sha256.Sum256(buf)
+	return hex.EncodeToString(hash[:])
+}
+
+// mergeTemplateFragments merges the fragments into the template and checks
+// that the template still has the outputs required by the controller.
+func mergeTemplateFragments(template []byte, fragments TemplateFragments) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(template, &doc); err != nil {
+		return nil, err
+	}
+
+	sections := map[string]map[string]json.RawMessage{}
+	for _, fragment := range fragments {
+		for name, entries := range map[string]map[string]json.RawMessage{
+			"Conditions": fragment.Conditions,
+			"Mappings":   fragment.Mappings,
+			"Resources":  fragment.Resources,
+			"Outputs":    fragment.Outputs,
+		} {
+			if len(entries) == 0 {
+				continue
+			}
+			section, ok := sections[name]
+			if !ok {
+				section = map[string]json.RawMessage{}
+				if raw, ok := doc[name]; ok {
+					if err := json.Unmarshal(raw, &section); err != nil {
+						return nil, fmt.Errorf("failed to parse section %s of the template: %v", name, err)
+					}
+				}
+				sections[name] = section
+			}
+			for key, value := range entries {
+				section[key] = value
+			}
+		}
+	}
+
+	for name, section := range sections {
+		raw, err := json.Marshal(section)
+		if err != nil {
+			return nil, err
+		}
+		doc[name] = raw
+	}
+
+	var outputs map[string]json.RawMessage
+	if err := json.Unmarshal(doc["Outputs"], &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse the outputs of the template: %v", err)
+	}
+	for _, output := range requiredOutputs {
+		if _, ok := outputs[output]; !ok {
+			return nil, fmt.Errorf("the template has no output %s", output)
+		}
+	}
+
+	return json.MarshalIndent(doc, "", "    ")
+}
@@ -0,0 +1,145 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTemplateFragmentFromYAML(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "short form of intrinsic functions",
+			yaml: `
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+Outputs:
+  TopicARN:
+    Value: !Ref Topic
+`,
+			wantErr: true,
+		},
+		{
+			name: "resources and outputs with intrinsic functions in JSON syntax",
+			yaml: `
+Conditions:
+  Always:
+    Fn::Equals: ["a", "a"]
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+    Condition: Always
+Outputs:
+  TopicARN:
+    Value:
+      Ref: Topic
+`,
+		},
+		{
+			name:    "empty",
+			yaml:    "{}",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported section",
+			yaml:    "Parameters:\n  Foo:\n    Type: String\n",
+			wantErr: true,
+		},
+		{
+			name:    "resource without type",
+			yaml:    "Resources:\n  Topic:\n    Properties: {}\n",
+			wantErr: true,
+		},
+		{
+			name:    "required output",
+			yaml:    "Outputs:\n  LoadBalancerARN:\n    Value: foo\n",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fragment, err := NewTemplateFragmentFromYAML([]byte(test.yaml))
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.NotNil(t, fragment)
+			}
+		})
+	}
+}
+
+func TestTemplateFragmentsHash(t *testing.T) {
+	assert.Equal(t, "", TemplateFragments(nil).Hash())
+
+	topic, err := NewTemplateFragmentFromYAML([]byte("Resources:\n  Topic:\n    Type: AWS::SNS::Topic\n"))
+	require.NoError(t, err)
+	bucket, err := NewTemplateFragmentFromYAML([]byte("Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n"))
+	require.NoError(t, err)
+
+	hash := TemplateFragments{topic}.Hash()
+	assert.NotEmpty(t, hash)
+	assert.Equal(t, hash, TemplateFragments{topic}.Hash())
+	assert.NotEqual(t, hash, TemplateFragments{topic, bucket}.Hash())
+}
+
+func TestGenerateTemplateWithFragments(t *testing.T) {
+	topic, err := NewTemplateFragmentFromYAML([]byte(`
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+Outputs:
+  TopicARN:
+    Value:
+      Ref: Topic
+`))
+	require.NoError(t, err)
+	// later fragments replace the entries of earlier ones and of the
+	// generated template
+	patch, err := NewTemplateFragmentFromYAML([]byte(`
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: patched
+`))
+	require.NoError(t, err)
+
+	spec := &stackSpec{
+		loadbalancerType:  LoadBalancerTypeApplication,
+		templateFragments: TemplateFragments{topic, patch},
+	}
+	generated, err := generateTemplate(spec)
+	require.NoError(t, err)
+
+	var template struct {
+		Resources map[string]struct {
+			Type       string
+			Properties map[string]interface{}
+		}
+		Outputs map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal([]byte(generated), &template))
+
+	assert.Contains(t, template.Resources, "LB")
+	assert.Contains(t, template.Resources, "TG")
+	assert.Equal(t, "AWS::SNS::Topic", template.Resources["Topic"].Type)
+	assert.Equal(t, "patched", template.Resources["Topic"].Properties["TopicName"])
+	for _, output := range append(requiredOutputs, "TopicARN") {
+		assert.Contains(t, template.Outputs, output)
+	}
+}
+
+func TestMergeTemplateFragmentsRequiresOutputs(t *testing.T) {
+	topic, err := NewTemplateFragmentFromYAML([]byte("Resources:\n  Topic:\n    Type: AWS::SNS::Topic\n"))
+	require.NoError(t, err)
+
+	_, err = mergeTemplateFragments([]byte(`{"Resources": {}, "Outputs": {"LoadBalancerARN": {}}}`), TemplateFragments{topic})
+	assert.Error(t, err)
+}
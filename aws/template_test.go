@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	settings := DefaultTemplateSettings("cluster", "vpc")
+	settings.Subnets = []string{"subnet-1", "subnet-2"}
+	settings.HostedZoneIDs = map[string]string{"foo.example.org": "zone-1"}
+
+	opts := &StackOptions{
+		Scheme:           "internet-facing",
+		SecurityGroup:    "sg-1",
+		SSLPolicy:        DefaultSslPolicy,
+		IPAddressType:    DefaultIpAddressType,
+		LoadBalancerType: LoadBalancerTypeApplication,
+		HTTP2:            true,
+		CertificateARNs:  map[string]time.Time{"cert-arn": {}},
+		DNSHostnames:     []string{"foo.example.org", "bar.example.org"},
+	}
+
+	template, err := RenderTemplate("stack", settings, opts)
+	require.NoError(t, err)
+
+	var body struct {
+		Resources map[string]struct {
+			Type string
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(template.Body), &body))
+	assert.Equal(t, "AWS::ElasticLoadBalancingV2::LoadBalancer", body.Resources["LB"].Type)
+
+	records := 0
+	for _, resource := range body.Resources {
+		if resource.Type == "AWS::Route53::RecordSet" {
+			records++
+		}
+	}
+	assert.NotZero(t, records, "expected DNS records for the hostname with a hosted zone")
+
+	assert.Equal(t, "internet-facing", template.Parameters[parameterLoadBalancerSchemeParameter])
+	assert.Equal(t, "subnet-1,subnet-2", template.Parameters[parameterLoadBalancerSubnetsParameter])
+	assert.Equal(t, "vpc", template.Parameters[parameterTargetGroupVPCIDParameter])
+	assert.Equal(t, resourceLifecycleOwned, template.Tags[clusterIDTagPrefix+"cluster"])
+	assert.Equal(t, DefaultControllerID, template.Tags[kubernetesCreatorTag])
+	assert.Contains(t, template.Tags, certificateARNTagPrefix+"cert-arn")
+
+	opts.SSLPolicy = "invalid"
+	_, err = RenderTemplate("stack", settings, opts)
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxTemplateBodySize is the maximum size in bytes of a template passed to
+// CloudFormation in the request. Larger templates must be uploaded to S3.
+const maxTemplateBodySize = 51200
+
+// templateUploader uploads the template of a stack and returns its URL.
+type templateUploader func(stackName, template string) (string, error)
+
+// templateSource returns either the body or the URL of the template of the
+// stack, which is uploaded if it exceeds maxTemplateBodySize. Without an
+// uploader the body is returned anyway and CloudFormation rejects it.
+func templateSource(stackName, template string, upload templateUploader) (body *string, url *string, err error) {
+	if len(template) <= maxTemplateBodySize {
+		return aws.String(template), nil, nil
+	}
+	if upload == nil {
+		log.Warnf("The template of stack %s exceeds %d bytes, which requires --cloudformation-template-bucket", stackName, maxTemplateBodySize)
+		return aws.String(template), nil, nil
+	}
+
+	location, err := upload(stackName, template)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, aws.String(location), nil
+}
+
+// templateUploader returns the uploader of templates to the bucket of the
+// adapter, or nil if there's none.
+func (a *Adapter) templateUploader() templateUploader {
+	if a.templateBucket == "" || a.s3 == nil {
+		return nil
+	}
+	return func(stackName, template string) (string, error) {
+		return uploadTemplate(a.s3, a.templateBucket, stackName, template)
+	}
+}
+
+// uploadTemplate puts the template into the bucket under the name of the
+// stack and the hash of the template, so the uploads of the same template
+// replace each other, and returns the URL of the object.
+func uploadTemplate(svc s3iface.S3API, bucket, stackName, template string) (string, error) {
+	key := fmt.Sprintf("%s/%x.json", stackName, sha256.Sum256([]byte(template)))
+
+	_, err := svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        strings.NewReader(template),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload the template of stack %s to bucket %s: %v", stackName, bucket, err)
+	}
+
+	// the URL of the object for the endpoint and addressing style of the
+	// client
+	req, _ := svc.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err := req.Build(); err != nil {
+		return "", fmt.Errorf("failed to build the URL of the template of stack %s: %v", stackName, err)
+	}
+	return req.HTTPRequest.URL.String(), nil
+}
@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type templateS3Client struct {
+	s3iface.S3API
+	puts   []*s3.PutObjectInput
+	putErr error
+}
+
+func (m *templateS3Client) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	m.puts = append(m.puts, in)
+	return &s3.PutObjectOutput{}, m.putErr
+}
+
+func TestTemplateSource(t *testing.T) {
+	small := "{}"
+	large := strings.Repeat(" ", maxTemplateBodySize+1)
+
+	uploaded := []string{}
+	upload := func(stackName, template string) (string, error) {
+		uploaded = append(uploaded, stackName)
+		return "https://bucket.s3.eu-central-1.amazonaws.com/stack/hash.json", nil
+	}
+
+	body, url, err := templateSource("stack", small, upload)
+	require.NoError(t, err)
+	assert.Equal(t, small, aws.StringValue(body))
+	assert.Nil(t, url)
+	assert.Empty(t, uploaded)
+
+	body, url, err = templateSource("stack", large, upload)
+	require.NoError(t, err)
+	assert.Nil(t, body)
+	assert.Equal(t, "https://bucket.s3.eu-central-1.amazonaws.com/stack/hash.json", aws.StringValue(url))
+	assert.Equal(t, []string{"stack"}, uploaded)
+
+	// without a bucket CloudFormation rejects the template
+	body, url, err = templateSource("stack", large, nil)
+	require.NoError(t, err)
+	assert.Equal(t, large, aws.StringValue(body))
+	assert.Nil(t, url)
+
+	_, _, err = templateSource("stack", large, func(string, string) (string, error) {
+		return "", errors.New("access denied")
+	})
+	assert.Error(t, err)
+}
+
+func TestUploadTemplate(t *testing.T) {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("eu-central-1"),
+		Credentials: credentials.AnonymousCredentials,
+	}))
+	svc := &templateS3Client{S3API: s3.New(sess)}
+
+	url, err := uploadTemplate(svc, "templates", "stack", "{}")
+	require.NoError(t, err)
+	require.Len(t, svc.puts, 1)
+	key := aws.StringValue(svc.puts[0].Key)
+	assert.True(t, strings.HasPrefix(key, "stack/") && strings.HasSuffix(key, ".json"), key)
+	assert.Equal(t, "templates", aws.StringValue(svc.puts[0].Bucket))
+	assert.Equal(t, "https://templates.s3.eu-central-1.amazonaws.com/"+key, url)
+
+	svc.putErr = errors.New("access denied")
+	_, err = uploadTemplate(svc, "templates", "stack", "{}")
+	assert.Error(t, err)
+}
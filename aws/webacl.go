@@ -0,0 +1,186 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/aws/aws-sdk-go/service/wafv2/wafv2iface"
+	cloudformation "github.com/mweagle/go-cloudformation"
+)
+
+const (
+	// WAFRateLimitKeyIP and WAFRateLimitKeyForwardedIP are the keys the
+	// requests are counted by for the rate limit of a load balancer: the
+	// source IP of the requests, or the client IP of the X-Forwarded-For
+	// header when the load balancer is behind a proxy or CDN.
+	WAFRateLimitKeyIP          = "IP"
+	WAFRateLimitKeyForwardedIP = "FORWARDED_IP"
+
+	// MinWAFRateLimit and MaxWAFRateLimit define the range of the number
+	// of requests per 5 minutes of a rate-based rule.
+	MinWAFRateLimit = 100
+	MaxWAFRateLimit = 2000000000
+
+	webACLResource = "WebACL"
+
+	// managedRuleGroupVendor is the vendor of the AWS managed rule groups.
+	managedRuleGroupVendor = "AWS"
+)
+
+var managedRuleGroupNameRegex = regexp.MustCompile(`^[0-9A-Za-z_-]{1,128}$`)
+
+// IsValidWAFRateLimit returns true if the limit can be used for a
+// rate-based rule.
+func IsValidWAFRateLimit(limit int64) bool {
+	return limit >= MinWAFRateLimit && limit <= MaxWAFRateLimit
+}
+
+// NewWAFManagedRuleGroups parses a comma separated list of names of AWS
+// managed rule groups, e.g. AWSManagedRulesCommonRuleSet. The rule groups
+// are evaluated in the order of the list, duplicates are removed.
+func NewWAFManagedRuleGroups(value string) ([]string, error) {
+	var groups []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !managedRuleGroupNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid name of managed rule group %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// wafRateLimitKey returns the key of the rate limit, WAFRateLimitKeyIP by
+// default.
+func wafRateLimitKey(key string) string {
+	if key == "" {
+		return WAFRateLimitKeyIP
+	}
+	return key
+}
+
+// hasWebACL returns true if the controller creates a WebACL for the
+// application load balancer of the stack. Load balancers can only be
+// associated with one WebACL, so none is created if a WebACL is referenced.
+func (spec *stackSpec) hasWebACL() bool {
+	return spec.loadbalancerType == LoadBalancerTypeApplication &&
+		spec.wafWebAclId == "" &&
+		(spec.wafRateLimit > 0 || len(spec.wafManagedRuleGroups) > 0)
+}
+
+// addWebACL adds a WAFv2 WebACL with the rules of the stack, allowing all
+// requests not blocked by them, and associates it with the load balancer.
+// It's deleted with the stack.
+func addWebACL(template *cloudformation.Template, spec *stackSpec) {
+	rules := cloudformation.WAFv2WebACLRuleList{}
+	if spec.wafRateLimit > 0 {
+		rules = append(rules, rateLimitRule(spec.wafRateLimitKey, len(rules)))
+	}
+	for _, name := range spec.wafManagedRuleGroups {
+		rules = append(rules, managedRuleGroupRule(name, len(rules)))
+	}
+
+	template.AddResource(webACLResource, &cloudformation.WAFv2WebACL{
+		Scope: cloudformation.String("REGIONAL"),
+		DefaultAction: &cloudformation.WAFv2WebACLDefaultAction{
+			Allow: map[string]interface{}{},
+		},
+		Rules:            &rules,
+		VisibilityConfig: webACLVisibilityConfig(cloudformation.Ref("AWS::StackName").String()),
+	})
+
+	template.AddResource("WebACLAssociation", &cloudformation.WAFv2WebACLAssociation{
+		ResourceArn: cloudformation.Ref("LB").String(),
+		WebACLArn:   cloudformation.GetAtt(webACLResource, "Arn").String(),
+	})
+}
+
+// rateLimitRule returns a rule blocking the requests of clients exceeding
+// the rate limit parameter of the stack within 5 minutes.
+func rateLimitRule(key string, priority int) cloudformation.WAFv2WebACLRule {
+	statement := &cloudformation.WAFv2WebACLRateBasedStatementOne{
+		AggregateKeyType: cloudformation.Ref(parameterWAFRateLimitKeyParameter).String(),
+		Limit:            cloudformation.Ref(parameterWAFRateLimitParameter).Integer(),
+	}
+	if key == WAFRateLimitKeyForwardedIP {
+		// requests without a valid header are counted as one client
+		statement.ForwardedIPConfig = &cloudformation.WAFv2WebACLForwardedIPConfiguration{
+			HeaderName:       cloudformation.String("X-Forwarded-For"),
+			FallbackBehavior: cloudformation.String("MATCH"),
+		}
+	}
+
+	return cloudformation.WAFv2WebACLRule{
+		Name:     cloudformation.String("RateLimit"),
+		Priority: cloudformation.Integer(int64(priority)),
+		Action: &cloudformation.WAFv2WebACLRuleAction{
+			Block: map[string]interface{}{},
+		},
+		Statement: &cloudformation.WAFv2WebACLStatementOne{
+			RateBasedStatement: statement,
+		},
+		VisibilityConfig: webACLVisibilityConfig(cloudformation.String("RateLimit")),
+	}
+}
+
+// managedRuleGroupRule returns a rule applying the actions of the AWS
+// managed rule group.
+func managedRuleGroupRule(name string, priority int) cloudformation.WAFv2WebACLRule {
+	return cloudformation.WAFv2WebACLRule{
+		Name:     cloudformation.String(name),
+		Priority: cloudformation.Integer(int64(priority)),
+		OverrideAction: &cloudformation.WAFv2WebACLOverrideAction{
+			None: map[string]interface{}{},
+		},
+		Statement: &cloudformation.WAFv2WebACLStatementOne{
+			ManagedRuleGroupStatement: &cloudformation.WAFv2WebACLManagedRuleGroupStatement{
+				VendorName: cloudformation.String(managedRuleGroupVendor),
+				Name:       cloudformation.String(name),
+			},
+		},
+		VisibilityConfig: webACLVisibilityConfig(cloudformation.String(name)),
+	}
+}
+
+func webACLVisibilityConfig(metricName *cloudformation.StringExpr) *cloudformation.WAFv2WebACLVisibilityConfig {
+	return &cloudformation.WAFv2WebACLVisibilityConfig{
+		CloudWatchMetricsEnabled: cloudformation.Bool(true),
+		SampledRequestsEnabled:   cloudformation.Bool(true),
+		MetricName:               metricName,
+	}
+}
+
+// FirewallManagerWebACL returns the ARN of the WebACL associated with the
+// load balancer by AWS Firewall Manager, or an empty string if the load
+// balancer has no WebACL or one not managed by Firewall Manager.
+func (a *Adapter) FirewallManagerWebACL(loadBalancerARN string) (string, error) {
+	return firewallManagerWebACL(a.wafv2, loadBalancerARN)
+}
+
+func firewallManagerWebACL(svc wafv2iface.WAFV2API, loadBalancerARN string) (string, error) {
+	resp, err := svc.GetWebACLForResource(&wafv2.GetWebACLForResourceInput{
+		ResourceArn: aws.String(loadBalancerARN),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == wafv2.ErrCodeWAFNonexistentItemException {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if resp.WebACL == nil || !aws.BoolValue(resp.WebACL.ManagedByFirewallManager) {
+		return "", nil
+	}
+	return aws.StringValue(resp.WebACL.ARN), nil
+}
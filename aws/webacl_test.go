@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWAFManagedRuleGroups(t *testing.T) {
+	for _, test := range []struct {
+		msg       string
+		given     string
+		want      []string
+		wantError bool
+	}{
+		{
+			msg:   "single rule group",
+			given: "AWSManagedRulesCommonRuleSet",
+			want:  []string{"AWSManagedRulesCommonRuleSet"},
+		},
+		{
+			msg:   "rule groups keep their order",
+			given: "AWSManagedRulesKnownBadInputsRuleSet, AWSManagedRulesCommonRuleSet",
+			want:  []string{"AWSManagedRulesKnownBadInputsRuleSet", "AWSManagedRulesCommonRuleSet"},
+		},
+		{
+			msg:   "duplicates and empty names are removed",
+			given: "AWSManagedRulesCommonRuleSet,,AWSManagedRulesCommonRuleSet,",
+			want:  []string{"AWSManagedRulesCommonRuleSet"},
+		},
+		{
+			msg:   "empty list",
+			given: " , ",
+		},
+		{
+			msg:       "invalid name",
+			given:     "AWSManagedRulesCommonRuleSet,Core Rule Set",
+			wantError: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			got, err := NewWAFManagedRuleGroups(test.given)
+			if test.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestFirewallManagerWebACL(t *testing.T) {
+	fmsACL := "arn:aws:wafv2:eu-central-1:123456789012:regional/webacl/FMManagedWebACLV2-policy/id"
+	for _, test := range []struct {
+		msg       string
+		given     mockedWAFv2Client
+		want      string
+		wantError bool
+	}{
+		{
+			msg: "WebACL of Firewall Manager",
+			given: mockedWAFv2Client{webACL: &wafv2.WebACL{
+				ARN:                      aws.String(fmsACL),
+				ManagedByFirewallManager: aws.Bool(true),
+			}},
+			want: fmsACL,
+		},
+		{
+			msg: "WebACL of the controller",
+			given: mockedWAFv2Client{webACL: &wafv2.WebACL{
+				ARN:                      aws.String("arn:aws:wafv2:eu-central-1:123456789012:regional/webacl/WebACL-abc/id"),
+				ManagedByFirewallManager: aws.Bool(false),
+			}},
+		},
+		{
+			msg:   "no WebACL",
+			given: mockedWAFv2Client{},
+		},
+		{
+			msg:   "load balancer not found",
+			given: mockedWAFv2Client{err: awserr.New(wafv2.ErrCodeWAFNonexistentItemException, "not found", nil)},
+		},
+		{
+			msg:       "access denied",
+			given:     mockedWAFv2Client{err: awserr.New("AccessDeniedException", "denied", nil)},
+			wantError: true,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			got, err := firewallManagerWebACL(test.given, "lb-arn")
+			if test.wantError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	minStackRetryBackoff = time.Minute
+	maxStackRetryBackoff = 30 * time.Minute
+)
+
+// retryBackoff delays the retries of failing operations individually per
+// key. The delay doubles with every consecutive failure, plus a random
+// jitter of up to half of it, up to a cap, and is reset on success.
+type retryBackoff struct {
+	min   time.Duration
+	max   time.Duration
+	state map[string]backoffState
+}
+
+type backoffState struct {
+	failures int
+	next     time.Time
+}
+
+func newRetryBackoff(min, max time.Duration) *retryBackoff {
+	return &retryBackoff{
+		min:   min,
+		max:   max,
+		state: make(map[string]backoffState),
+	}
+}
+
+// allowed returns true if the operation of the key may be retried now.
+func (b *retryBackoff) allowed(key string, now time.Time) bool {
+	return !now.Before(b.state[key].next)
+}
+
+// failure records a failed operation and returns the delay until the next
+// retry.
+func (b *retryBackoff) failure(key string, now time.Time) time.Duration {
+	s := b.state[key]
+	s.failures++
+
+	delay := b.min
+	for i := 1; i < s.failures && delay < b.max; i++ {
+		delay *= 2
+	}
+	delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	if delay > b.max {
+		delay = b.max
+	}
+
+	s.next = now.Add(delay)
+	b.state[key] = s
+	return delay
+}
+
+// success resets the backoff of the key.
+func (b *retryBackoff) success(key string) {
+	if _, ok := b.state[key]; ok {
+		b.state[key] = backoffState{}
+	}
+}
+
+// prune forgets the keys which aren't in use anymore and the ones without
+// failures.
+func (b *retryBackoff) prune(keys map[string]bool) {
+	pruned := make(map[string]backoffState)
+	for key, s := range b.state {
+		if keys[key] && s.failures > 0 {
+			pruned[key] = s
+		}
+	}
+	b.state = pruned
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+// certificateEventsRetryDelay is the delay before receiving certificate
+// events again after a failure.
+const certificateEventsRetryDelay = 30 * time.Second
+
+var (
+	// certificateEvents counts the events of AWS Certificate Manager per
+	// type, and whether the certificate is attached to a load balancer.
+	certificateEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "certificate_events_total",
+		Help:      "Number of events of AWS Certificate Manager received, by type and whether the certificate is attached to a load balancer.",
+	}, []string{"type", "attached"})
+
+	attachedCertificates = &certificateStacks{}
+)
+
+func init() {
+	prometheus.MustRegister(certificateEvents)
+}
+
+// certificateStacks are the stacks whose load balancers use each
+// certificate, as of the latest reconciliation.
+type certificateStacks struct {
+	mu     sync.Mutex
+	stacks map[string][]string
+}
+
+// update records the certificates of the stacks of the load balancers.
+func (c *certificateStacks) update(loadBalancers []*loadBalancer) {
+	stacks := make(map[string][]string)
+	for _, lb := range loadBalancers {
+		if lb.stack == nil {
+			continue
+		}
+		for arn := range lb.stack.CertificateARNs {
+			stacks[arn] = append(stacks[arn], lb.stack.Name)
+		}
+	}
+
+	c.mu.Lock()
+	c.stacks = stacks
+	c.mu.Unlock()
+}
+
+// get returns the stacks using the certificate, sorted.
+func (c *certificateStacks) get(arn string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stacks := append([]string(nil), c.stacks[arn]...)
+	sort.Strings(stacks)
+	return stacks
+}
+
+// watchCertificateEvents receives the events of AWS Certificate Manager from
+// the queue and handles them until the context is cancelled.
+func watchCertificateEvents(ctx context.Context, receive func() ([]*aws.CertificateEvent, error), refresh func() error) {
+	for {
+		events, err := receive()
+		if err != nil {
+			log.Errorf("Failed to receive certificate events: %v", err)
+			select {
+			case <-clock.After(certificateEventsRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		handleCertificateEvents(events, attachedCertificates, refresh)
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+// handleCertificateEvents reloads the certificates and requests the
+// reconciliation of the stacks using the certificates of the events, so
+// renewed certificates replace the previous ones and expired ones are
+// replaced by others matching the hostnames, if any, without waiting for
+// the polling intervals. New certificates are picked up the same way.
+// Expiring certificates attached to load balancers are logged, ACM doesn't
+// renew them.
+func handleCertificateEvents(events []*aws.CertificateEvent, attached *certificateStacks, refresh func() error) {
+	if len(events) == 0 {
+		return
+	}
+
+	var stacks []string
+	reload := false
+	for _, event := range events {
+		reload = reload || !event.Expiring()
+		for _, arn := range event.CertificateARNs {
+			arnStacks := attached.get(arn)
+			certificateEvents.WithLabelValues(event.Type, strconv.FormatBool(len(arnStacks) > 0)).Inc()
+
+			switch {
+			case len(arnStacks) == 0:
+				log.Debugf("%s of certificate %s, it's not attached to any load balancer", event.Type, arn)
+			case event.Expiring():
+				log.Warnf("%s of certificate %s (%d days to expiry), it's attached to the load balancers of stacks %v", event.Type, arn, event.DaysToExpiry, arnStacks)
+			default:
+				log.Infof("%s of certificate %s, updating the load balancers of stacks %v", event.Type, arn, arnStacks)
+			}
+			stacks = append(stacks, arnStacks...)
+		}
+	}
+	if !reload && len(stacks) == 0 {
+		return
+	}
+
+	if err := refresh(); err != nil {
+		log.Errorf("Failed to reload the certificates after certificate events: %v", err)
+	}
+	syncRequests.trigger(stacks...)
+}
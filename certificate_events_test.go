@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+func TestHandleCertificateEvents(t *testing.T) {
+	attached := &certificateStacks{}
+	attached.update([]*loadBalancer{
+		{stack: &aws.Stack{Name: "stack-b", CertificateARNs: map[string]time.Time{"arn:cert-a": {}}}},
+		{stack: &aws.Stack{Name: "stack-a", CertificateARNs: map[string]time.Time{"arn:cert-a": {}, "arn:cert-b": {}}}},
+		{},
+	})
+	require.Equal(t, []string{"stack-a", "stack-b"}, attached.get("arn:cert-a"))
+
+	refreshed := 0
+	refresh := func() error {
+		refreshed++
+		return nil
+	}
+	syncRequests.takeStacks()
+
+	// expiring certificates which aren't attached are ignored
+	handleCertificateEvents([]*aws.CertificateEvent{
+		{Type: aws.CertificateEventApproachingExpiration, CertificateARNs: []string{"arn:cert-c"}},
+	}, attached, refresh)
+	require.Equal(t, 0, refreshed)
+	require.Empty(t, syncRequests.takeStacks())
+
+	// new certificates may match ingresses waiting for one
+	handleCertificateEvents([]*aws.CertificateEvent{
+		{Type: aws.CertificateEventAvailable, CertificateARNs: []string{"arn:cert-c"}},
+	}, attached, refresh)
+	require.Equal(t, 1, refreshed)
+	require.Empty(t, syncRequests.takeStacks())
+
+	handleCertificateEvents([]*aws.CertificateEvent{
+		{Type: aws.CertificateEventExpired, CertificateARNs: []string{"arn:cert-b"}},
+		{Type: aws.CertificateEventAvailable, CertificateARNs: []string{"arn:cert-a"}},
+	}, attached, refresh)
+	require.Equal(t, 2, refreshed)
+	require.ElementsMatch(t, []string{"stack-a", "stack-b"}, syncRequests.takeStacks())
+}
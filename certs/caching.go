@@ -13,6 +13,16 @@ type cachingProvider struct {
 	providers         []CertificatesProvider
 	certDetails       []*CertificateSummary
 	blacklistedArnMap map[string]bool
+	jitter            func(time.Duration) time.Duration
+}
+
+// RefreshableProvider is a CertificatesProvider which caches the
+// certificates and can reload them on demand.
+type RefreshableProvider interface {
+	CertificatesProvider
+	// Refresh reloads the certificates right away, e.g. after one was
+	// renewed.
+	Refresh() error
 }
 
 type certProviderWrapper struct {
@@ -25,11 +35,22 @@ type certProviderWrapper struct {
 // certificates it will continue to refresh the cache every
 // certUpdateInterval in the background. If the background refresh
 // fails the last known cached values are considered current.
-func NewCachingProvider(certUpdateInterval time.Duration, blacklistedArnMap map[string]bool, providers ...CertificatesProvider) (CertificatesProvider, error) {
+func NewCachingProvider(certUpdateInterval time.Duration, blacklistedArnMap map[string]bool, providers ...CertificatesProvider) (RefreshableProvider, error) {
+	return NewJitteredCachingProvider(certUpdateInterval, nil, blacklistedArnMap, providers...)
+}
+
+// NewJitteredCachingProvider is NewCachingProvider with every interval
+// between the background refreshes changed by the jitter function, so
+// the refreshes of many controllers don't happen at the same time.
+func NewJitteredCachingProvider(certUpdateInterval time.Duration, jitter func(time.Duration) time.Duration, blacklistedArnMap map[string]bool, providers ...CertificatesProvider) (RefreshableProvider, error) {
+	if jitter == nil {
+		jitter = func(d time.Duration) time.Duration { return d }
+	}
 	provider := &cachingProvider{
 		providers:         providers,
 		blacklistedArnMap: blacklistedArnMap,
 		certDetails:       make([]*CertificateSummary, 0),
+		jitter:            jitter,
 	}
 	if err := provider.updateCertCache(); err != nil {
 		return nil, fmt.Errorf("initial load of certificates failed: %v", err)
@@ -47,6 +68,12 @@ func (cc *cachingProvider) GetCertificates() ([]*CertificateSummary, error) {
 	return certCopy, nil
 }
 
+// Refresh updates the certificate cache right away. The cache is kept if
+// any provider fails.
+func (cc *cachingProvider) Refresh() error {
+	return cc.updateCertCache()
+}
+
 // updateCertCache will only update the current certificate cache if
 // all providers are successful.  In case it fails it will return the
 // original error.
@@ -90,7 +117,7 @@ func (cc *cachingProvider) updateCertCache() error {
 func (cc *cachingProvider) startBackgroundRefresh(certUpdateInterval time.Duration) {
 	go func() {
 		for {
-			time.Sleep(certUpdateInterval)
+			time.Sleep(cc.jitter(certUpdateInterval))
 			if err := cc.updateCertCache(); err != nil {
 				log.Errorf("certificate cache background update failed: %v", err)
 			}
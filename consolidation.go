@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// consolidationSettings are the settings of a load balancer which prevent
+// ingresses from sharing it. Load balancers with the same settings could be
+// merged if their ingresses were shared.
+type consolidationSettings struct {
+	Scheme                                 string `json:"scheme"`
+	LoadBalancerType                       string `json:"loadBalancerType"`
+	IPAddressType                          string `json:"ipAddressType"`
+	SecurityGroup                          string `json:"securityGroup,omitempty"`
+	SSLPolicy                              string `json:"sslPolicy,omitempty"`
+	HTTP2                                  bool   `json:"http2"`
+	PreserveHostHeader                     bool   `json:"preserveHostHeader,omitempty"`
+	HTTPDisabled                           bool   `json:"httpDisabled,omitempty"`
+	FrontingNLB                            bool   `json:"frontingNLB,omitempty"`
+	NLBSecurityGroup                       bool   `json:"nlbSecurityGroup,omitempty"`
+	LambdaTarget                           string `json:"lambdaTarget,omitempty"`
+	WAFWebACLID                            string `json:"wafWebACLID,omitempty"`
+	WAFRateLimit                           int64  `json:"wafRateLimit,omitempty"`
+	WAFRateLimitKey                        string `json:"wafRateLimitKey,omitempty"`
+	WAFManagedRuleGroups                   string `json:"wafManagedRuleGroups,omitempty"`
+	SlowStart                              string `json:"slowStart,omitempty"`
+	ClientKeepAlive                        string `json:"clientKeepAlive,omitempty"`
+	HealthCheckMatcher                     string `json:"healthCheckMatcher,omitempty"`
+	PreserveClientIP                       string `json:"preserveClientIP,omitempty"`
+	ProxyProtocolV2                        bool   `json:"proxyProtocolV2,omitempty"`
+	ClientRoutingPolicy                    string `json:"clientRoutingPolicy,omitempty"`
+	CapacityUnits                          int64  `json:"capacityUnits,omitempty"`
+	DefaultCertificateARN                  string `json:"defaultCertificateARN,omitempty"`
+	ExternalTargetGroupARNs                string `json:"externalTargetGroupARNs,omitempty"`
+	ElasticIPs                             string `json:"elasticIPs,omitempty"`
+	IPv4Pool                               string `json:"ipv4Pool,omitempty"`
+	EndpointServicePrincipals              string `json:"endpointServicePrincipals,omitempty"`
+	DenyInternalDomains                    string `json:"denyInternalDomains,omitempty"`
+	DenyInternalDomainsResponse            string `json:"denyInternalDomainsResponse,omitempty"`
+	DenyInternalDomainsResponseContentType string `json:"denyInternalDomainsResponseContentType,omitempty"`
+	DenyInternalDomainsResponseStatusCode  int    `json:"denyInternalDomainsResponseStatusCode,omitempty"`
+	HealthyThresholdCount                  uint   `json:"healthyThresholdCount,omitempty"`
+	UnhealthyThresholdCount                uint   `json:"unhealthyThresholdCount,omitempty"`
+	ListenerRulesHash                      string `json:"listenerRulesHash,omitempty"`
+	AuthenticationHash                     string `json:"authenticationHash,omitempty"`
+	ResourceTagsHash                       string `json:"resourceTagsHash,omitempty"`
+	AttributesHash                         string `json:"attributesHash,omitempty"`
+}
+
+// consolidationGroup lists load balancers with the same settings which could
+// be replaced by fewer ones.
+type consolidationGroup struct {
+	Settings      consolidationSettings `json:"settings"`
+	LoadBalancers []string              `json:"loadBalancers"`
+	// NotShared are the ingresses with shared=false, keeping a load
+	// balancer of their own.
+	NotShared []string `json:"notShared"`
+	// Required is the number of load balancers needed for the
+	// certificates of the group.
+	Required int `json:"required"`
+	// MonthlySavings is the estimated cost of the load balancers
+	// exceeding the required ones.
+	MonthlySavings float64 `json:"monthlySavings"`
+}
+
+// consolidationReport is the result of the consolidation advisor.
+type consolidationReport struct {
+	LoadBalancers  int                  `json:"loadBalancers"`
+	Removable      int                  `json:"removable"`
+	MonthlySavings float64              `json:"monthlySavings"`
+	Groups         []consolidationGroup `json:"groups"`
+}
+
+var (
+	consolidationMu     sync.Mutex
+	latestConsolidation = &consolidationReport{Groups: []consolidationGroup{}}
+
+	consolidationRemovable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "consolidation_removable_load_balancers",
+		Help:      "Number of load balancers which could be removed by sharing them between ingresses with the same settings.",
+	})
+	consolidationSavings = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "consolidation_monthly_savings",
+		Help:      "Estimated monthly cost of the load balancers which could be removed by sharing them.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(consolidationRemovable, consolidationSavings)
+}
+
+func (l *loadBalancer) consolidationSettings() consolidationSettings {
+	return consolidationSettings{
+		Scheme:                                 l.scheme,
+		LoadBalancerType:                       l.loadBalancerType,
+		IPAddressType:                          l.ipAddressType,
+		SecurityGroup:                          l.securityGroup,
+		SSLPolicy:                              l.sslPolicy,
+		HTTP2:                                  l.http2,
+		PreserveHostHeader:                     l.preserveHostHeader,
+		HTTPDisabled:                           l.httpDisabled,
+		FrontingNLB:                            l.frontingNLB,
+		NLBSecurityGroup:                       l.nlbSecurityGroup,
+		LambdaTarget:                           l.lambdaTarget,
+		WAFWebACLID:                            l.wafWebACLID,
+		WAFRateLimit:                           l.wafRateLimit,
+		WAFRateLimitKey:                        l.wafRateLimitKey,
+		WAFManagedRuleGroups:                   strings.Join(l.wafManagedRuleGroups, ","),
+		SlowStart:                              durationString(l.slowStart),
+		ClientKeepAlive:                        durationString(l.clientKeepAlive),
+		HealthCheckMatcher:                     l.healthCheckMatcher,
+		PreserveClientIP:                       l.preserveClientIP,
+		ProxyProtocolV2:                        l.proxyProtocolV2,
+		ClientRoutingPolicy:                    l.clientRoutingPolicy,
+		CapacityUnits:                          l.capacityUnits,
+		DefaultCertificateARN:                  l.defaultCertificateARN,
+		ExternalTargetGroupARNs:                strings.Join(l.externalTargetGroupARNs, ","),
+		ElasticIPs:                             strings.Join(l.elasticIPs, ","),
+		IPv4Pool:                               l.ipv4Pool,
+		EndpointServicePrincipals:              strings.Join(l.endpointServicePrincipals, ","),
+		DenyInternalDomains:                    l.denyInternalDomains,
+		DenyInternalDomainsResponse:            l.denyInternalDomainsResponse,
+		DenyInternalDomainsResponseContentType: l.denyInternalDomainsResponseContentType,
+		DenyInternalDomainsResponseStatusCode:  l.denyInternalDomainsResponseStatusCode,
+		HealthyThresholdCount:                  l.healthyThresholdCount,
+		UnhealthyThresholdCount:                l.unhealthyThresholdCount,
+		ListenerRulesHash:                      l.listenerRulesHash,
+		AuthenticationHash:                     l.authenticationHash,
+		ResourceTagsHash:                       l.resourceTagsHash,
+		AttributesHash:                         l.attributesHash,
+	}
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// buildConsolidationReport groups the load balancers serving ingresses by
+// their settings and estimates how many of them could be removed if all
+// ingresses of a group shared load balancers, given the certificates per
+// load balancer.
+func buildConsolidationReport(loadBalancers []*loadBalancer, certsPerALB int, monthlyCost float64) *consolidationReport {
+	groups := make(map[consolidationSettings]*consolidationGroup)
+	certificates := make(map[consolidationSettings]map[string]bool)
+	report := &consolidationReport{Groups: []consolidationGroup{}}
+
+	for _, lb := range loadBalancers {
+		if lb.clusterLocal || !lb.hasIngresses() {
+			continue
+		}
+		report.LoadBalancers++
+
+		settings := lb.consolidationSettings()
+		group, ok := groups[settings]
+		if !ok {
+			group = &consolidationGroup{Settings: settings, NotShared: []string{}}
+			groups[settings] = group
+			certificates[settings] = make(map[string]bool)
+		}
+
+		name := lb.stackName()
+		if name == "" {
+			name = "(new) " + lb.retryKey()
+		}
+		group.LoadBalancers = append(group.LoadBalancers, name)
+
+		for cert, ingresses := range lb.ingresses {
+			if len(ingresses) == 0 {
+				continue
+			}
+			certificates[settings][cert] = true
+			for _, ingress := range ingresses {
+				if !ingress.Shared {
+					group.NotShared = append(group.NotShared, ingress.ResourceType()+" "+ingress.String())
+				}
+			}
+		}
+	}
+
+	for settings, group := range groups {
+		group.Required = (len(certificates[settings]) + certsPerALB - 1) / certsPerALB
+		if group.Required < 1 {
+			group.Required = 1
+		}
+		removable := len(group.LoadBalancers) - group.Required
+		if removable <= 0 {
+			continue
+		}
+
+		sort.Strings(group.LoadBalancers)
+		sort.Strings(group.NotShared)
+		group.MonthlySavings = float64(removable) * monthlyCost
+		report.Removable += removable
+		report.MonthlySavings += group.MonthlySavings
+		report.Groups = append(report.Groups, *group)
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].MonthlySavings == report.Groups[j].MonthlySavings {
+			return report.Groups[i].LoadBalancers[0] < report.Groups[j].LoadBalancers[0]
+		}
+		return report.Groups[i].MonthlySavings > report.Groups[j].MonthlySavings
+	})
+
+	return report
+}
+
+// hasIngresses returns true if the load balancer serves any ingress.
+func (l *loadBalancer) hasIngresses() bool {
+	for _, ingresses := range l.ingresses {
+		if len(ingresses) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// updateConsolidationReport publishes the report of the load balancers of
+// the model. It's logged whenever it changes.
+func updateConsolidationReport(loadBalancers []*loadBalancer, certsPerALB int, monthlyCost float64) {
+	report := buildConsolidationReport(loadBalancers, certsPerALB, monthlyCost)
+
+	consolidationMu.Lock()
+	changed := !reflect.DeepEqual(report, latestConsolidation)
+	latestConsolidation = report
+	consolidationMu.Unlock()
+
+	consolidationRemovable.Set(float64(report.Removable))
+	consolidationSavings.Set(report.MonthlySavings)
+
+	if !changed {
+		return
+	}
+	for _, group := range report.Groups {
+		log.Infof("Load balancers %v could be consolidated into %d, saving about %.2f per month. Not shared: %v",
+			group.LoadBalancers, group.Required, group.MonthlySavings, group.NotShared)
+	}
+}
+
+// serveConsolidationReport writes the latest consolidation report as JSON.
+func serveConsolidationReport(w http.ResponseWriter, _ *http.Request) {
+	consolidationMu.Lock()
+	report := latestConsolidation
+	consolidationMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Errorf("Failed to write the consolidation report: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+func TestBuildConsolidationReport(t *testing.T) {
+	lb := func(stack, scheme, cert string, ingress *kubernetes.Ingress) *loadBalancer {
+		return &loadBalancer{
+			scheme:    scheme,
+			stack:     &aws.Stack{Name: stack},
+			ingresses: map[string][]*kubernetes.Ingress{cert: {ingress}},
+		}
+	}
+
+	loadBalancers := []*loadBalancer{
+		lb("stack-a", "internet-facing", "cert-a", &kubernetes.Ingress{Namespace: "team", Name: "a"}),
+		lb("stack-b", "internet-facing", "cert-b", &kubernetes.Ingress{Namespace: "team", Name: "b"}),
+		lb("stack-c", "internet-facing", "cert-c", &kubernetes.Ingress{Namespace: "team", Name: "c", Shared: true}),
+		lb("stack-d", "internal", "cert-d", &kubernetes.Ingress{Namespace: "team", Name: "d"}),
+		{stack: &aws.Stack{Name: "deleted"}, ingresses: map[string][]*kubernetes.Ingress{"cert-e": {}}},
+		{clusterLocal: true, ingresses: map[string][]*kubernetes.Ingress{kubernetes.DefaultClusterLocalDomain: {{Namespace: "team", Name: "local"}}}},
+	}
+
+	report := buildConsolidationReport(loadBalancers, 2, 10)
+
+	assert.Equal(t, 4, report.LoadBalancers)
+	assert.Equal(t, 1, report.Removable)
+	assert.Equal(t, 10.0, report.MonthlySavings)
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, []string{"stack-a", "stack-b", "stack-c"}, report.Groups[0].LoadBalancers)
+	assert.Equal(t, []string{"unknown team/a", "unknown team/b"}, report.Groups[0].NotShared)
+	assert.Equal(t, 2, report.Groups[0].Required)
+	assert.Equal(t, "internet-facing", report.Groups[0].Settings.Scheme)
+}
+
+func TestServeConsolidationReport(t *testing.T) {
+	updateConsolidationReport(nil, 25, 10)
+
+	w := httptest.NewRecorder()
+	serveConsolidationReport(w, httptest.NewRequest("GET", "/debug/consolidation", nil))
+
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"loadBalancers":0,"removable":0,"monthlySavings":0,"groups":[]}`, w.Body.String())
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path"
@@ -12,75 +14,147 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/certs"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes/fake"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
+	runCommand              = "run"
+	migrateOwnershipCommand = "migrate-ownership"
+
 	defaultDisableSNISupport      = "false"
 	defaultInstrumentedHttpClient = "false"
 	defaultHTTPRedirectToHTTPS    = "false"
 	defaultCertTTL                = "1h"
 	customTagFilterEnvVarName     = "CUSTOM_FILTERS"
+	minSSLPolicyModeUpgrade       = "upgrade"
+	minSSLPolicyModeReject        = "reject"
+	stuckStackRemediationNone     = "none"
+	stuckStackRemediationDelete   = "delete"
+	listenerDriftAlert            = "alert"
+	listenerDriftRevert           = "revert"
 )
 
 var (
-	buildstamp                    = "Not set"
-	githash                       = "Not set"
-	version                       = "Not set"
-	versionFlag                   bool
-	apiServerBaseURL              string
-	pollingInterval               time.Duration
-	creationTimeout               time.Duration
-	certPollingInterval           time.Duration
-	healthCheckPath               string
-	healthCheckPort               uint
-	healthCheckInterval           time.Duration
-	healthCheckTimeout            time.Duration
-	targetPort                    uint
-	targetHTTPS                   bool
-	metricsAddress                string
-	disableSNISupport             bool
-	disableInstrumentedHttpClient bool
-	certTTL                       time.Duration
-	stackTerminationProtection    bool
-	additionalStackTags           = make(map[string]string)
-	idleConnectionTimeout         time.Duration
-	deregistrationDelayTimeout    time.Duration
-	ingressClassFilters           string
-	controllerID                  string
-	clusterID                     string
-	vpcID                         string
-	clusterLocalDomain            string
-	maxCertsPerALB                int
-	sslPolicy                     string
-	blacklistCertARNs             []string
-	blacklistCertArnMap           map[string]bool
-	ipAddressType                 string
-	albLogsS3Bucket               string
-	albLogsS3Prefix               string
-	wafWebAclId                   string
-	httpRedirectToHTTPS           bool
-	debugFlag                     bool
-	quietFlag                     bool
-	firstRun                      bool = true
-	cwAlarmConfigMap              string
-	cwAlarmConfigMapLocation      *kubernetes.ResourceLocation
-	loadBalancerType              string
-	nlbCrossZone                  bool
-	nlbHTTPEnabled                bool
-	ingressAPIVersion             string
-	internalDomains               []string
-	denyInternalDomains           bool
-	denyInternalRespBody          string
-	denyInternalRespContentType   string
-	denyInternalRespStatusCode    int
-	defaultInternalDomains        = fmt.Sprintf("*%s", kubernetes.DefaultClusterLocalDomain)
+	buildstamp                         = "Not set"
+	githash                            = "Not set"
+	version                            = "Not set"
+	versionFlag                        bool
+	apiServerBaseURL                   string
+	kubeconfigPath                     string
+	kubeconfigContext                  string
+	pollingInterval                    time.Duration
+	maxPollingInterval                 time.Duration
+	creationTimeout                    time.Duration
+	certPollingInterval                time.Duration
+	pollingJitterFraction              float64
+	stackPollingInterval               time.Duration
+	healthCheckPath                    string
+	healthCheckPort                    uint
+	healthCheckInterval                time.Duration
+	healthCheckTimeout                 time.Duration
+	targetPort                         uint
+	targetHTTPS                        bool
+	metricsAddress                     string
+	webhookAddress                     string
+	syncAddress                        string
+	syncMinInterval                    time.Duration
+	webhookTLSCertFile                 string
+	webhookTLSKeyFile                  string
+	disableSNISupport                  bool
+	disableInstrumentedHttpClient      bool
+	awsEndpoint                        string
+	additionalRegionVPCs               = make(map[string]string)
+	assumeRoleARN                      string
+	managedRoleARNs                    []string
+	additionalRegionNames              []string
+	fakeKubernetesManifests            string
+	certTTL                            time.Duration
+	stackTerminationProtection         bool
+	additionalStackTags                = make(map[string]string)
+	additionalResourceTags             = make(map[string]string)
+	idleConnectionTimeout              time.Duration
+	deregistrationDelayTimeout         time.Duration
+	ingressClassFilters                string
+	controllerID                       string
+	clusterID                          string
+	vpcID                              string
+	clusterLocalDomain                 string
+	maxCertsPerALB                     int
+	sslPolicy                          string
+	blacklistCertARNs                  []string
+	defaultCertificateARN              string
+	certificateEventsQueueURL          string
+	blacklistCertArnMap                map[string]bool
+	ipAddressType                      string
+	ipv4Pool                           string
+	albLogsS3Bucket                    string
+	templateBucket                     string
+	stackUpdateChangeSets              string
+	albLogsS3Prefix                    string
+	wafWebAclId                        string
+	httpRedirectToHTTPS                bool
+	debugFlag                          bool
+	quietFlag                          bool
+	firstRun                           bool = true
+	cwAlarmConfigMap                   string
+	cwAlarmConfigMapLocation           *kubernetes.ResourceLocation
+	loadBalancerType                   string
+	nlbCrossZone                       bool
+	nlbHTTPEnabled                     bool
+	ingressAPIVersion                  string
+	internalDomains                    []string
+	internalDomainsConfigMap           string
+	internalDomainsConfigMapLocation   *kubernetes.ResourceLocation
+	templateFragmentsConfigMap         string
+	templateFragmentsConfigMapLocation *kubernetes.ResourceLocation
+	denyInternalDomains                bool
+	denyInternalRespBody               string
+	denyInternalRespContentType        string
+	denyInternalRespStatusCode         int
+	defaultInternalDomains             = fmt.Sprintf("*%s", kubernetes.DefaultClusterLocalDomain)
+	multiLBDNSRecords                  bool
+	dnsOwnerID                         string
+	allowedHostnameSuffixes            []string
+	allowedLoadBalancerAttributes      []string
+	allowedTargetGroupAttributes       []string
+	minSSLPolicy                       string
+	namespaceTags                      bool
+	checkFirewallManager               bool
+	loadBalancerMonthlyCost            float64
+	resourceTagsTemplateFlags          = make(map[string]string)
+	resourceTagsTemplate               kubernetes.ResourceTagsTemplate
+	serviceQuotas                      bool
+	maxListenerRules                   int
+	maxLoadBalancers                   int
+	loadBalancerQuotas                 *aws.LoadBalancerQuotas
+	namespaceDefaults                  bool
+	gatewayAPI                         bool
+	loadBalancerConfigurations         bool
+	watchResources                     bool
+	podReadinessGates                  bool
+	podReadinessGateSelector           string
+	recordEvents                       bool
+	minSSLPolicyMode                   string
+	stuckStackRemediation              string
+	listenerDriftCheckInterval         time.Duration
+	listenerDriftRemediation           string
+	statusUpdateInterval               time.Duration
+	statusUpdateBatchSize              int
+	statusPorts                        bool
+	statusIPs                          bool
+	ingressStateAnnotations            bool
+	faultInjection                     aws.FaultInjection
+	previousControllerID               string
+	previousClusterID                  string
+	command                            string
 )
 
 func loadSettings() error {
@@ -89,20 +163,42 @@ func loadSettings() error {
 	kingpin.Flag("quiet", "Enables quiet logging").Default("false").BoolVar(&quietFlag)
 	kingpin.Flag("api-server-base-url", "sets the kubernetes api server base url. If empty will try to use the configuration from the running cluster, else it will use InsecureConfig, that does not use encryption or authentication (use case to develop with kubectl proxy).").
 		Envar("API_SERVER_BASE_URL").StringVar(&apiServerBaseURL)
+	kingpin.Flag("kubeconfig", "path of a kubeconfig file to run the controller outside of the cluster. Users of the file can authenticate with a token or a client certificate. Only one file is supported.").
+		Envar("KUBECONFIG").StringVar(&kubeconfigPath)
+	kingpin.Flag("kubeconfig-context", "context of the --kubeconfig file to use instead of its current context.").
+		Envar("KUBECONFIG_CONTEXT").StringVar(&kubeconfigContext)
 	kingpin.Flag("polling-interval", "sets the polling interval for ingress resources. The flag accepts a value acceptable to time.ParseDuration").
 		Envar("POLLING_INTERVAL").Default("30s").DurationVar(&pollingInterval)
+	kingpin.Flag("max-polling-interval", "enables adaptive polling: the polling interval is doubled up to this interval after several reconciliations without changes, and reset to --polling-interval by a change or a failure. The flag accepts a value acceptable to time.ParseDuration").
+		Envar("MAX_POLLING_INTERVAL").Default("0s").DurationVar(&maxPollingInterval)
 	kingpin.Flag("creation-timeout", "sets the stack creation timeout. The flag accepts a value acceptable to time.ParseDuration. Should be >= 1min").
 		Envar("CREATION_TIMEOUT").Default(aws.DefaultCreationTimeout.String()).DurationVar(&creationTimeout)
 	kingpin.Flag("cert-polling-interval", "sets the polling interval for the certificates cache refresh. The flag accepts a value acceptable to time.ParseDuration").
 		Envar("CERT_POLLING_INTERVAL").Default(aws.DefaultCertificateUpdateInterval.String()).DurationVar(&certPollingInterval)
+	kingpin.Flag("polling-jitter", "changes every polling interval of ingresses, stacks and certificates randomly by up to this fraction of it, e.g. 0.1 for 10%, so controllers of many clusters in one AWS account don't call the AWS APIs at the same time. The random values are seeded with the cluster and controller IDs.").
+		Envar("POLLING_JITTER").Default("0").FloatVar(&pollingJitterFraction)
+	kingpin.Flag("stack-polling-interval", "sets the polling interval for the CloudFormation stacks, if longer than --polling-interval. Stacks are listed again right after the controller changed one of them. The flag accepts a value acceptable to time.ParseDuration").
+		Envar("STACK_POLLING_INTERVAL").Default("0s").DurationVar(&stackPollingInterval)
 	kingpin.Flag("disable-sni-support", "disables SNI support limiting the number of certificates per ALB to 1.").
 		Default(defaultDisableSNISupport).BoolVar(&disableSNISupport)
 	kingpin.Flag("disable-instrumented-http-client", "disables instrumented http client.").
 		Default(defaultInstrumentedHttpClient).BoolVar(&disableInstrumentedHttpClient)
+	kingpin.Flag("aws-endpoint", "sends the requests of all AWS services to this endpoint instead of the AWS ones, e.g. http://localhost:4566 for LocalStack. Requires --cluster-id and --vpc-id.").
+		StringVar(&awsEndpoint)
+	kingpin.Flag("additional-region", "provisions the load balancers of the ingresses also in this region, in the VPC of the cluster given as <region>=<vpc-id>. The flag can be repeated. The status of the ingresses lists the DNS names of the load balancers of all regions, the one of the region of the controller first.").
+		StringMapVar(&additionalRegionVPCs)
+	kingpin.Flag("assume-role-arn", "creates the stacks and load balancers of the ingresses in the account of this IAM role, which the controller assumes. Ingresses can assume another role of --managed-role-arn with the zalando.org/aws-load-balancer-assume-role-arn annotation.").
+		Envar("ASSUME_ROLE_ARN").StringVar(&assumeRoleARN)
+	kingpin.Flag("managed-role-arn", "manages the stacks in the account of this IAM role from the start and allows ingresses to assume it, so its load balancers are deleted once no ingress assumes the role anymore, also after a restart of the controller. Ingresses assuming other roles are ignored. The flag can be repeated, the role of --assume-role-arn is always managed.").
+		StringsVar(&managedRoleARNs)
+	kingpin.Flag("fake-kubernetes-manifests", "serves the ingresses, routegroups, namespaces and configmaps of this YAML file from an embedded fake Kubernetes API instead of using a cluster, e.g. to try the controller locally together with --aws-endpoint.").
+		StringVar(&fakeKubernetesManifests)
 	kingpin.Flag("stack-termination-protection", "enables stack termination protection for the stacks managed by the controller.").
 		Default("false").BoolVar(&stackTerminationProtection)
 	kingpin.Flag("additional-stack-tags", "set additional custom tags on the Cloudformation Stacks managed by the controller.").
 		StringMapVar(&additionalStackTags)
+	kingpin.Flag("additional-resource-tags", "set additional custom tags on the load balancers and target groups managed by the controller, but not on the Cloudformation Stacks. Tags of --resource-tags-template and of the resource tags annotation take precedence.").
+		StringMapVar(&additionalResourceTags)
 	kingpin.Flag("cert-ttl-timeout", "sets the timeout of how long a certificate is kept on an old ALB to be decommissioned.").
 		Default(defaultCertTTL).DurationVar(&certTTL)
 	kingpin.Flag("health-check-path", "sets the health check path for the created target groups").
@@ -122,12 +218,26 @@ func loadSettings() error {
 	kingpin.Flag("deregistration-delay-timeout", "sets the deregistration delay timeout of all target groups.  The flag accepts a value acceptable to time.ParseDuration that is between 1s and 3600s.").
 		Default(aws.DefaultDeregistrationTimeout.String()).DurationVar(&deregistrationDelayTimeout)
 	kingpin.Flag("metrics-address", "defines where to serve metrics").Default(":7979").StringVar(&metricsAddress)
-	kingpin.Flag("ingress-class-filter", "optional comma-seperated list of kubernetes.io/ingress.class annotation values to filter behaviour on.").
+	kingpin.Flag("sync-address", "Serve the /sync endpoint triggering an immediate reconciliation on this address, e.g. localhost:7980. Disabled if empty.").
+		Envar("SYNC_ADDRESS").StringVar(&syncAddress)
+	kingpin.Flag("sync-min-interval", "sets the minimum interval between reconciliations triggered via the /sync endpoint, requests made earlier are refused.").
+		Default("10s").DurationVar(&syncMinInterval)
+	kingpin.Flag("webhook-address", "Serve a validating admission webhook for the controller annotations on this address, e.g. :9443. Disabled if empty.").
+		Envar("WEBHOOK_ADDRESS").StringVar(&webhookAddress)
+	kingpin.Flag("webhook-tls-cert-file", "Certificate file of the admission webhook, reloaded when it changes.").
+		Envar("WEBHOOK_TLS_CERT_FILE").StringVar(&webhookTLSCertFile)
+	kingpin.Flag("webhook-tls-key-file", "Private key file of the admission webhook.").
+		Envar("WEBHOOK_TLS_KEY_FILE").StringVar(&webhookTLSKeyFile)
+	kingpin.Flag("ingress-class-filter", "optional comma-seperated list of kubernetes.io/ingress.class annotation values to filter behaviour on. Values can be glob patterns, e.g. skipper-*.").
 		StringVar(&ingressClassFilters)
 	kingpin.Flag("controller-id", "controller ID used to differentiate resources from multiple aws ingress controller instances").
 		Default(aws.DefaultControllerID).StringVar(&controllerID)
 	kingpin.Flag("cluster-id", "ID of the Kubernetes cluster used to lookup cluster related resources tagged with `kubernetes.io/cluster/<cluster-id>` tags. Auto discovered from the EC2 instance where the controller is running if not specified.").
 		StringVar(&clusterID)
+	kingpin.Flag("previous-controller-id", "controller ID which tagged the stacks before it was changed. Its stacks are managed too, and their tags are migrated to the current controller ID. Defaults to the current controller ID if only the previous cluster ID is set.").
+		Envar("PREVIOUS_CONTROLLER_ID").StringVar(&previousControllerID)
+	kingpin.Flag("previous-cluster-id", "cluster ID which tagged the stacks before it was changed. Its stacks are managed too, and their tags are migrated to the current cluster ID. Defaults to the current cluster ID if only the previous controller ID is set.").
+		Envar("PREVIOUS_CLUSTER_ID").StringVar(&previousClusterID)
 	kingpin.Flag("vpc-id", "VPC ID for where the cluster is running. Used to lookup relevant subnets. Auto discovered from the EC2 instance where the controller is running if not specified.").
 		StringVar(&vpcID)
 	kingpin.Flag("cluster-local-domain", "Cluster local domain is used to detect hostnames, that won't trigger a creation of an AWS load balancer, empty string will not change the default behavior. In Kubernetes you might want to pass cluster.local").
@@ -136,17 +246,55 @@ func loadSettings() error {
 		Default(strconv.Itoa(aws.DefaultMaxCertsPerALB)).IntVar(&maxCertsPerALB) // TODO: max
 	kingpin.Flag("ssl-policy", "Security policy that will define the protocols/ciphers accepts by the SSL listener").
 		Default(aws.DefaultSslPolicy).EnumVar(&sslPolicy, aws.SSLPoliciesList...)
+	kingpin.Flag("min-ssl-policy", "Minimum security policy of the SSL listeners. Ingresses selecting a weaker policy are upgraded or rejected depending on -min-ssl-policy-mode. Not enforced if empty.").
+		EnumVar(&minSSLPolicy, aws.SSLPoliciesList...)
+	kingpin.Flag("min-ssl-policy-mode", "Defines how ingresses selecting a security policy weaker than -min-ssl-policy are handled: upgrade uses the minimum policy instead, reject ignores the ingress.").
+		Default(minSSLPolicyModeUpgrade).EnumVar(&minSSLPolicyMode, minSSLPolicyModeUpgrade, minSSLPolicyModeReject)
+	kingpin.Flag("stuck-stack-remediation", "Defines how stacks stuck in DELETE_FAILED, or in REVIEW_IN_PROGRESS or CREATE_IN_PROGRESS for longer than -creation-timeout are handled: none only logs them, delete deletes them so they are created again under a new name.").
+		Default(stuckStackRemediationNone).EnumVar(&stuckStackRemediation, stuckStackRemediationNone, stuckStackRemediationDelete)
+	kingpin.Flag("listener-drift-check-interval", "Interval of the checks whether the HTTPS listeners, their certificates and the attributes of the load balancers in sync with their stacks were changed outside of CloudFormation, e.g. in the AWS console. Zero disables the checks.").
+		Default("0").DurationVar(&listenerDriftCheckInterval)
+	kingpin.Flag("listener-drift-remediation", "Defines how load balancers changed outside of CloudFormation are handled: alert only reports them, revert changes them back to the state of their stacks.").
+		Default(listenerDriftAlert).EnumVar(&listenerDriftRemediation, listenerDriftAlert, listenerDriftRevert)
+	kingpin.Flag("status-update-interval", "Interval of the batches of updates of the load balancer status of ingresses and route groups. Failed updates are retried with a backoff per ingress.").
+		Default("1s").DurationVar(&statusUpdateInterval)
+	kingpin.Flag("status-update-batch-size", "Maximum number of ingresses and route groups whose load balancer status is updated per --status-update-interval, limiting the writes to the API server when many ingresses move to another load balancer.").
+		Default("10").IntVar(&statusUpdateBatchSize)
+	kingpin.Flag("status-ports", "Publish the ports of the listeners of the load balancers in the status of ingresses, besides their hostnames.").
+		Envar("STATUS_PORTS").Default("false").BoolVar(&statusPorts)
+	kingpin.Flag("status-ips", "Publish the IP addresses of network load balancers in the status of ingresses, besides their hostnames. The addresses are resolved from the hostnames.").
+		Envar("STATUS_IPS").Default("false").BoolVar(&statusIPs)
+	kingpin.Flag("ingress-state-annotations", "records the stack, the load balancer ARN and the certificate ARNs serving an ingress or routegroup in annotations of the resource. They are updated whenever the load balancer or the certificates change.").
+		Default("false").BoolVar(&ingressStateAnnotations)
+	kingpin.Flag("fault-injection-error-rate", "Share of the AWS requests, between 0 and 1, failing with an injected internal error. For testing the resilience of the controller, never use in production.").
+		Default("0").Float64Var(&faultInjection.ErrorRate)
+	kingpin.Flag("fault-injection-throttle-rate", "Share of the AWS requests, between 0 and 1, failing with an injected throttling error. For testing the resilience of the controller, never use in production.").
+		Default("0").Float64Var(&faultInjection.ThrottleRate)
+	kingpin.Flag("fault-injection-latency", "Latency added to every AWS request. For testing the resilience of the controller, never use in production.").
+		Default("0s").DurationVar(&faultInjection.Latency)
 	kingpin.Flag("blacklist-certificate-arns", "Certificate ARNs to not consider by the controller.").StringsVar(&blacklistCertARNs)
+	kingpin.Flag("default-certificate-arn", "Certificate ARN used for the HTTPS listener of ingresses without hostnames or without a matching certificate. Such ingresses are skipped if not set.").
+		Envar("DEFAULT_CERTIFICATE_ARN").StringVar(&defaultCertificateARN)
+	kingpin.Flag("certificate-events-queue-url", "URL of an SQS queue receiving the events of AWS Certificate Manager from an EventBridge rule. Renewed and new certificates are then picked up right away, and expiring certificates attached to load balancers are logged.").
+		Envar("CERTIFICATE_EVENTS_QUEUE_URL").StringVar(&certificateEventsQueueURL)
 	kingpin.Flag("ip-addr-type", "IP Address type to use.").
 		Default(aws.DefaultIpAddressType).EnumVar(&ipAddressType, aws.IPAddressTypeIPV4, aws.IPAddressTypeDualstack)
+	kingpin.Flag("ipv4-pool", "ID of the IPAM pool the addresses of internet-facing Application Load Balancers are taken from, or of the public IPv4 pool (BYOIP) of the Elastic IPs auto-allocated for internet-facing Network Load Balancers. Ingresses can select another pool with an annotation. Not used if empty.").
+		Envar("IPV4_POOL").StringVar(&ipv4Pool)
 	kingpin.Flag("logs-s3-bucket", "S3 bucket to be used for ALB logging").
 		Default(aws.DefaultAlbS3LogsBucket).StringVar(&albLogsS3Bucket)
 	kingpin.Flag("logs-s3-prefix", "Prefix within S3 bucket to be used for ALB logging").
 		Default(aws.DefaultAlbS3LogsPrefix).StringVar(&albLogsS3Prefix)
+	kingpin.Flag("cloudformation-template-bucket", "S3 bucket in the region of the cluster the CloudFormation templates exceeding the size limit of 51200 bytes of requests are uploaded to.").
+		Envar("CLOUDFORMATION_TEMPLATE_BUCKET").StringVar(&templateBucket)
+	kingpin.Flag("stack-update-change-sets", "How stacks are updated: disabled updates them directly, non-destructive updates them with change sets which are only executed if no resource is replaced or removed, always updates them with change sets which are always executed. The planned changes of change sets are logged.").
+		Envar("STACK_UPDATE_CHANGE_SETS").Default(aws.ChangeSetsDisabled).EnumVar(&stackUpdateChangeSets, aws.ChangeSetsDisabled, aws.ChangeSetsNonDestructive, aws.ChangeSetsAlways)
 	kingpin.Flag("aws-waf-web-acl-id", "WAF web acl id to be associated with the ALB. For WAF v2 it is possible to specify the WebACL ARN arn:aws:wafv2:<region>:<account>:regional/webacl/<name>/<id>").
 		Default("").StringVar(&wafWebAclId)
 	kingpin.Flag("cloudwatch-alarms-config-map", "ConfigMap location of the form 'namespace/config-map-name' where to read CloudWatch Alarm configuration from. Ignored if empty.").
 		StringVar(&cwAlarmConfigMap)
+	kingpin.Flag("cloudformation-template-fragments-config-map", "ConfigMap location of the form 'namespace/config-map-name' where to read CloudFormation template fragments from, which are merged into the templates of the stacks, e.g. to add custom resources. Each key holds a fragment with Resources, Outputs, Conditions or Mappings in YAML or JSON. Ignored if empty.").
+		StringVar(&templateFragmentsConfigMap)
 	kingpin.Flag("redirect-http-to-https", "Configure HTTP listener to redirect to HTTPS").
 		Default(defaultHTTPRedirectToHTTPS).BoolVar(&httpRedirectToHTTPS)
 	kingpin.Flag("load-balancer-type", "Sets default Load Balancer type (application or network).").
@@ -155,29 +303,137 @@ func loadSettings() error {
 		Default("false").BoolVar(&nlbCrossZone)
 	kingpin.Flag("nlb-http-enabled", "Enable HTTP (port 80) for Network Load Balancers. By default this is disabled as NLB can't provide HTTP -> HTTPS redirect.").
 		Default("false").BoolVar(&nlbHTTPEnabled)
-	kingpin.Flag("ingress-api-version", "APIversion used for listing/updating ingresses.").
-		Default(kubernetes.IngressAPIVersionNetworking).EnumVar(&ingressAPIVersion, kubernetes.IngressAPIVersionNetworking, kubernetes.IngressAPIVersionExtensions)
+	kingpin.Flag("ingress-api-version", "APIversion used for listing/updating ingresses. With 'auto' the newest version served by the API server is used and detected again on errors.").
+		Default(kubernetes.IngressAPIVersionAuto).EnumVar(&ingressAPIVersion, kubernetes.IngressAPIVersionAuto, kubernetes.IngressAPIVersionNetworkingV1, kubernetes.IngressAPIVersionNetworking, kubernetes.IngressAPIVersionExtensions)
 	kingpin.Flag("deny-internal-domains", "Sets a rule on ALB's Listeners that denies requests with the Host header as a internal domain. Domains can be set with the -internal-domains flag.").
 		Default("false").BoolVar(&denyInternalDomains)
 	kingpin.Flag("internal-domains", "Define the internal domains to be blocked when -deny-internal-domains is set to true. Set it multiple times for multiple domains. The maximum size of each name is 128 characters. The following wildcard characters are supported: * (matches 0 or more characters) and ? (matches exactly 1 character).").
 		Default(defaultInternalDomains).StringsVar(&internalDomains)
+	kingpin.Flag("internal-domains-config-map", "ConfigMap location of the form 'namespace/config-map-name' where to read the internal domains from, one per line. It's read on every update and overrides -internal-domains unless empty. Ignored if empty.").
+		StringVar(&internalDomainsConfigMap)
 	kingpin.Flag("deny-internal-domains-response", "Defines the response body for a request identified as to an internal domain when -deny-internal-domains is set.").
 		Default("Unauthorized").StringVar(&denyInternalRespBody)
 	kingpin.Flag("deny-internal-domains-response-content-type", "Defines the response conten-type for a request identified as to an internal domain when -deny-internal-domains is set.").
 		Default("text/plain").StringVar(&denyInternalRespContentType)
 	kingpin.Flag("deny-internal-domains-response-status-code", "Defines the response status code for a request identified as to an internal domain when -deny-internal-domains is set.").
 		Default("401").IntVar(&denyInternalRespStatusCode)
-	kingpin.Parse()
+	kingpin.Flag("multi-lb-dns-records", "Manage weighted Route53 records for hostnames served by more than one load balancer. Route53 only returns the load balancers considered healthy.").
+		Default("false").BoolVar(&multiLBDNSRecords)
+	kingpin.Flag("dns-owner-id", "Owner ID written to the external-dns compatible TXT records of the DNS records managed by the controller. Defaults to the controller ID.").
+		StringVar(&dnsOwnerID)
+	kingpin.Flag("namespace-tags", "Tag the load balancers with the namespaces of the ingresses they serve, e.g. to split the cost of shared load balancers.").
+		Default("false").BoolVar(&namespaceTags)
+	kingpin.Flag("check-firewall-manager", "Check whether AWS Firewall Manager replaced the WAF web ACLs associated with the load balancers by the controller. The web ACLs of the affected stacks are then left as they are and the conflicts are reported.").
+		Default("false").BoolVar(&checkFirewallManager)
+	kingpin.Flag("max-load-balancers", "Maximum number of load balancers managed by the controller. No stacks are created beyond it and the affected ingresses are reported. Zero means unlimited.").
+		Default("0").IntVar(&maxLoadBalancers)
+	kingpin.Flag("load-balancer-monthly-cost", "Estimated monthly cost of a load balancer, used by the consolidation report of the load balancers which could be shared.").
+		Default("16.43").Float64Var(&loadBalancerMonthlyCost)
+	kingpin.Flag("resource-tags-template", "Tag the load balancers and target groups with values rendered from the metadata of their ingresses, e.g. team={{.Labels.team}}. The templates can use .Namespace, .Name, .Kind and .Labels. Set it multiple times for multiple tags.").
+		StringMapVar(&resourceTagsTemplateFlags)
+	kingpin.Flag("service-quotas", fmt.Sprintf("Read the Service Quotas of the account for certificates and rules of ALBs at startup. -max-certs-alb may then be higher than %d, up to the quota, and ingresses with more listener rules than allowed are ignored.", aws.DefaultMaxCertsPerALB)).
+		Default("false").BoolVar(&serviceQuotas)
+	kingpin.Flag("namespace-default-annotations", "Use the zalando.org/aws-* annotations set on namespaces as defaults for the ingresses and routegroups in them.").
+		Default("false").BoolVar(&namespaceDefaults)
+	kingpin.Flag("load-balancer-configurations", "Use the LoadBalancerConfiguration resources referenced by the zalando.org/aws-load-balancer-configuration annotation as defaults for the annotations of ingresses, routegroups and Gateways.").
+		Envar("LOAD_BALANCER_CONFIGURATIONS").Default("false").BoolVar(&loadBalancerConfigurations)
+	kingpin.Flag("gateway-api", "Provision load balancers for Gateways of the Kubernetes Gateway API, with the hostnames of their listeners and attached HTTPRoutes. The Gateway class is matched against --ingress-class-filter.").
+		Envar("GATEWAY_API").Default("false").BoolVar(&gatewayAPI)
+	kingpin.Flag("watch-resources", "Watch the ingresses, routegroups and the other resources the controller lists, instead of listing them in every reconciliation, and reconcile as soon as they change.").
+		Envar("WATCH_RESOURCES").Default("false").BoolVar(&watchResources)
+	kingpin.Flag("pod-readiness-gates", fmt.Sprintf("Set the %s readiness gate condition of pods once the instance they run on is healthy in the target groups of the load balancers.", kubernetes.TargetHealthReadinessGate)).
+		Envar("POD_READINESS_GATES").Default("false").BoolVar(&podReadinessGates)
+	kingpin.Flag("pod-readiness-gate-selector", "Label selector of the pods with readiness gates, e.g. application=skipper-ingress. All pods are listed if not set.").
+		Envar("POD_READINESS_GATE_SELECTOR").StringVar(&podReadinessGateSelector)
+	kingpin.Flag("ingress-events", "Record Kubernetes events on the ingresses and routegroups when their load balancers are created or updated, when their stacks fail and when no certificate is found for them.").
+		Envar("INGRESS_EVENTS").Default("false").BoolVar(&recordEvents)
+	kingpin.Flag("allowed-hostname-suffix", "Only consider ingress hostnames matching the DNS suffix. Set it multiple times for multiple suffixes. Hostnames not matching any suffix are ignored and ingresses without any allowed hostname are rejected. If not set, all hostnames are allowed.").
+		StringsVar(&allowedHostnameSuffixes)
+	kingpin.Flag("allowed-load-balancer-attribute", "Allow ingresses to set the load balancer attributes matching the pattern, e.g. routing.http.*, with the zalando.org/aws-load-balancer-attributes annotation. Set it multiple times for multiple patterns. If not set, no attributes are allowed.").
+		StringsVar(&allowedLoadBalancerAttributes)
+	kingpin.Flag("allowed-target-group-attribute", "Allow ingresses to set the target group attributes matching the pattern, e.g. load_balancing.*, with the zalando.org/aws-load-balancer-target-group-attributes annotation. Set it multiple times for multiple patterns. If not set, no attributes are allowed.").
+		StringsVar(&allowedTargetGroupAttributes)
+	kingpin.Command(runCommand, "run the controller").Default()
+	kingpin.Command(migrateOwnershipCommand, "migrate the tags of the stacks of the previous controller ID and cluster ID to the current ones, then exit")
+	command = kingpin.Parse()
 
 	blacklistCertArnMap = make(map[string]bool)
 	for _, s := range blacklistCertARNs {
 		blacklistCertArnMap[s] = true
 	}
 
+	if defaultCertificateARN != "" && !arn.IsARN(defaultCertificateARN) {
+		return fmt.Errorf("invalid default certificate ARN %q", defaultCertificateARN)
+	}
+
 	if creationTimeout < 1*time.Minute {
 		return fmt.Errorf("invalid creation timeout %d. please specify a value > 1min", creationTimeout)
 	}
 
+	if maxPollingInterval != 0 && maxPollingInterval < pollingInterval {
+		return fmt.Errorf("invalid max polling interval %s. please specify an interval >= --polling-interval", maxPollingInterval)
+	}
+
+	if pollingJitterFraction < 0 || pollingJitterFraction >= 1 {
+		return fmt.Errorf("invalid polling jitter %g. please specify a fraction >= 0 and < 1", pollingJitterFraction)
+	}
+
+	if awsEndpoint != "" {
+		if u, err := url.Parse(awsEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid AWS endpoint %q. please use a http or https URL", awsEndpoint)
+		}
+		if clusterID == "" || vpcID == "" {
+			return fmt.Errorf("--cluster-id and --vpc-id are required with --aws-endpoint")
+		}
+	}
+
+	if faultInjection.ErrorRate < 0 || faultInjection.ThrottleRate < 0 || faultInjection.ErrorRate+faultInjection.ThrottleRate > 1 {
+		return fmt.Errorf("invalid fault injection rates, the error and throttle rates must be positive and add up to at most 1")
+	}
+
+	var err error
+	if resourceTagsTemplate, err = kubernetes.NewResourceTagsTemplate(resourceTagsTemplateFlags); err != nil {
+		return fmt.Errorf("invalid --resource-tags-template: %v", err)
+	}
+
+	if additionalRegionNames, err = parseAdditionalRegions(additionalRegionVPCs); err != nil {
+		return fmt.Errorf("invalid --additional-region: %v", err)
+	}
+
+	if assumeRoleARN != "" && !aws.IsRoleARN(assumeRoleARN) {
+		return fmt.Errorf("invalid role ARN %q. please specify the ARN of an IAM role", assumeRoleARN)
+	}
+
+	for _, roleARN := range managedRoleARNs {
+		if !aws.IsRoleARN(roleARN) {
+			return fmt.Errorf("invalid --managed-role-arn %q. please specify the ARN of an IAM role", roleARN)
+		}
+	}
+
+	if err := aws.ResourceTags(additionalResourceTags).Validate(); err != nil {
+		return fmt.Errorf("invalid --additional-resource-tags: %v", err)
+	}
+
+	if fakeKubernetesManifests != "" && apiServerBaseURL != "" {
+		return fmt.Errorf("--fake-kubernetes-manifests and --api-server-base-url are mutually exclusive")
+	}
+
+	if kubeconfigPath != "" && (apiServerBaseURL != "" || fakeKubernetesManifests != "") {
+		return fmt.Errorf("--kubeconfig is mutually exclusive with --api-server-base-url and --fake-kubernetes-manifests")
+	}
+
+	if kubeconfigContext != "" && kubeconfigPath == "" {
+		return fmt.Errorf("--kubeconfig-context requires --kubeconfig")
+	}
+
+	if webhookAddress != "" && (webhookTLSCertFile == "" || webhookTLSKeyFile == "") {
+		return fmt.Errorf("--webhook-address requires --webhook-tls-cert-file and --webhook-tls-key-file")
+	}
+
+	if statusUpdateInterval <= 0 || statusUpdateBatchSize <= 0 {
+		return fmt.Errorf("--status-update-interval and --status-update-batch-size must be positive")
+	}
+
 	if healthCheckPort == 0 || healthCheckPort > 65535 {
 		return fmt.Errorf("invalid health check port: %d. please use a valid TCP port", healthCheckPort)
 	}
@@ -186,7 +442,19 @@ func loadSettings() error {
 		return fmt.Errorf("invalid target port: %d. please use a valid TCP port", targetPort)
 	}
 
-	if maxCertsPerALB > aws.DefaultMaxCertsPerALB {
+	if err := aws.ValidateAttributePatterns(allowedLoadBalancerAttributes); err != nil {
+		return err
+	}
+
+	if err := aws.ValidateAttributePatterns(allowedTargetGroupAttributes); err != nil {
+		return err
+	}
+
+	if maxLoadBalancers < 0 {
+		return fmt.Errorf("invalid max number of load balancers: %d. please use a positive number or 0 for unlimited", maxLoadBalancers)
+	}
+
+	if maxCertsPerALB > aws.DefaultMaxCertsPerALB && !serviceQuotas {
 		return fmt.Errorf("invalid max number of certificates per ALB: %d. AWS does not allow more than %d", maxCertsPerALB, aws.DefaultMaxCertsPerALB)
 	}
 
@@ -199,6 +467,40 @@ func loadSettings() error {
 		cwAlarmConfigMapLocation = loc
 	}
 
+	if internalDomainsConfigMap != "" {
+		loc, err := kubernetes.ParseResourceLocation(internalDomainsConfigMap)
+		if err != nil {
+			return fmt.Errorf("failed to parse internal domains config map location: %v", err)
+		}
+
+		internalDomainsConfigMapLocation = loc
+	}
+
+	if templateFragmentsConfigMap != "" {
+		loc, err := kubernetes.ParseResourceLocation(templateFragmentsConfigMap)
+		if err != nil {
+			return fmt.Errorf("failed to parse template fragments config map location: %v", err)
+		}
+
+		templateFragmentsConfigMapLocation = loc
+	}
+
+	if ipv4Pool != "" && !aws.IsValidIPv4Pool(ipv4Pool) {
+		return fmt.Errorf("invalid IPv4 pool: %s is neither an IPAM pool nor a public IPv4 pool", ipv4Pool)
+	}
+
+	if minSSLPolicy != "" && aws.IsWeakerSSLPolicy(sslPolicy, minSSLPolicy) {
+		return fmt.Errorf("invalid ssl policy: %s is weaker than the minimum ssl policy %s", sslPolicy, minSSLPolicy)
+	}
+
+	if command == migrateOwnershipCommand && previousControllerID == "" && previousClusterID == "" {
+		return fmt.Errorf("%s requires the previous controller ID or cluster ID", migrateOwnershipCommand)
+	}
+
+	if dnsOwnerID == "" {
+		dnsOwnerID = controllerID
+	}
+
 	if quietFlag && debugFlag {
 		log.Warn("--quiet and --debug flags are both set. Debug will be used as logging level.")
 	}
@@ -239,7 +541,7 @@ func main() {
 	}
 
 	log.Debug("aws.NewAdapter")
-	awsAdapter, err = aws.NewAdapter(clusterID, controllerID, vpcID, debugFlag, disableInstrumentedHttpClient)
+	awsAdapter, err = aws.NewAdapterWithEndpoint(clusterID, controllerID, vpcID, awsEndpoint, debugFlag, disableInstrumentedHttpClient)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -249,36 +551,29 @@ func main() {
 		customFilter = ""
 	}
 
-	awsAdapter = awsAdapter.
-		WithHealthCheckPath(healthCheckPath).
-		WithHealthCheckPort(healthCheckPort).
-		WithHealthCheckInterval(healthCheckInterval).
-		WithHealthCheckTimeout(healthCheckTimeout).
-		WithTargetPort(targetPort).
-		WithTargetHTTPS(targetHTTPS).
-		WithCreationTimeout(creationTimeout).
-		WithStackTerminationProtection(stackTerminationProtection).
-		WithIdleConnectionTimeout(idleConnectionTimeout).
-		WithDeregistrationDelayTimeout(deregistrationDelayTimeout).
-		WithControllerID(controllerID).
-		WithSslPolicy(sslPolicy).
-		WithIpAddressType(ipAddressType).
-		WithAlbLogsS3Bucket(albLogsS3Bucket).
-		WithAlbLogsS3Prefix(albLogsS3Prefix).
-		WithHTTPRedirectToHTTPS(httpRedirectToHTTPS).
-		WithNLBCrossZone(nlbCrossZone).
-		WithNLBHTTPEnabled(nlbHTTPEnabled).
-		WithCustomFilter(customFilter).
-		WithStackTags(additionalStackTags).
-		WithInternalDomains(internalDomains).
-		WithDenyInternalDomains(denyInternalDomains).
-		WithInternalDomainsDenyResponse(denyInternalRespBody).
-		WithInternalDomainsDenyResponseStatusCode(denyInternalRespStatusCode).
-		WithInternalDomainsDenyResponseContenType(denyInternalRespContentType)
+	awsAdapter = configureAWSAdapter(awsAdapter, customFilter)
 
-	log.Debug("certs.NewCachingProvider")
-	certificatesProvider, err := certs.NewCachingProvider(
+	if serviceQuotas {
+		applyServiceQuotas(awsAdapter)
+	}
+
+	if command == migrateOwnershipCommand {
+		if err = migrateOwnership(awsAdapter); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if err = awsAdapter.ValidateAccessLogsBucket(); err != nil {
+		log.Errorf("Access logs of the load balancers won't work: %v", err)
+	}
+
+	intervalJitter = newPollingJitter(pollingJitterFraction, awsAdapter.ClusterID(), controllerID)
+
+	log.Debug("certs.NewJitteredCachingProvider")
+	certificatesProvider, err := certs.NewJitteredCachingProvider(
 		certPollingInterval,
+		intervalJitter.apply,
 		blacklistCertArnMap,
 		awsAdapter.NewACMCertificateProvider(),
 		awsAdapter.NewIAMCertificateProvider(),
@@ -287,7 +582,32 @@ func main() {
 		log.Fatal(err)
 	}
 
-	if apiServerBaseURL == "" {
+	for _, name := range additionalRegionNames {
+		log.Debugf("aws.NewAdapterInRegion(%s)", name)
+		r, err := newAdditionalRegion(name, additionalRegionVPCs[name], awsAdapter.ClusterID(), customFilter)
+		if err != nil {
+			log.Fatalf("Failed to set up region %s: %v", name, err)
+		}
+		additionalRegions = append(additionalRegions, r)
+	}
+
+	if err := setUpAssumedRoles(awsAdapter, startupRoleARNs(assumeRoleARN, managedRoleARNs)); err != nil {
+		log.Fatal(err)
+	}
+
+	if fakeKubernetesManifests != "" {
+		log.Debug("fake.NewServer")
+		kubeConfig, err = newFakeKubernetesConfig(fakeKubernetesManifests)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if kubeconfigPath != "" {
+		log.Debug("kubernetes.KubeconfigConfig")
+		kubeConfig, err = kubernetes.KubeconfigConfig(kubeconfigPath, kubeconfigContext)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if apiServerBaseURL == "" {
 		log.Debug("kubernetes.InClusterConfig")
 		kubeConfig, err = kubernetes.InClusterConfig()
 		if err != nil {
@@ -308,6 +628,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	kubeAdapter = kubeAdapter.WithNamespaceDefaults(namespaceDefaults).WithLoadBalancerConfigurations(loadBalancerConfigurations).WithGatewayAPI(gatewayAPI).WithStatusPorts(statusPorts).WithStatusIPs(statusIPs).WithDefaultIPv4Pool(ipv4Pool).WithWatch(watchResources)
 
 	certificatesPerALB := maxCertsPerALB
 	if disableSNISupport {
@@ -315,7 +636,7 @@ func main() {
 	}
 
 	log.Info("controller manifest:")
-	log.Infof("Kubernetes API server: %s", apiServerBaseURL)
+	log.Infof("Kubernetes API server: %s", kubeConfig.BaseURL)
 	log.Infof("Cluster ID: %s", awsAdapter.ClusterID())
 	log.Infof("VPC ID: %s", awsAdapter.VpcID())
 	log.Infof("Instance ID: %s", awsAdapter.InstanceID())
@@ -323,17 +644,62 @@ func main() {
 	log.Infof("Internal subnet IDs: %s", awsAdapter.FindLBSubnets(elbv2.LoadBalancerSchemeEnumInternal))
 	log.Infof("Public subnet IDs: %s", awsAdapter.FindLBSubnets(elbv2.LoadBalancerSchemeEnumInternetFacing))
 	log.Infof("EC2 filters: %s", awsAdapter.FiltersString())
+	if assumeRoleARN != "" {
+		log.Infof("Assumed role: %s", assumeRoleARN)
+	}
+	if len(managedRoleARNs) > 0 {
+		log.Infof("Managed roles: %s", strings.Join(managedRoleARNs, ", "))
+	}
+	for _, r := range additionalRegions {
+		log.Infof("Additional region: %s (VPC ID: %s, security group ID: %s)", r.name(), r.awsAdapter.VpcID(), r.awsAdapter.SecurityGroupID())
+	}
 	log.Infof("Certificates per ALB: %d (SNI: %t)", certificatesPerALB, certificatesPerALB > 1)
 	log.Infof("Blacklisted Certificate ARNs (%d): %s", len(blacklistCertARNs), strings.Join(blacklistCertARNs, ","))
+	log.Infof("Default Certificate ARN: %s", defaultCertificateARN)
+	log.Infof("Certificate events queue: %s", certificateEventsQueueURL)
 	log.Infof("Ingress class filters: %s", kubeAdapter.IngressFiltersString())
 	log.Infof("ALB Logging S3 Bucket: %s", awsAdapter.S3Bucket())
 	log.Infof("ALB Logging S3 Prefix: %s", awsAdapter.S3Prefix())
 	log.Infof("CloudWatch Alarm ConfigMap: %s", cwAlarmConfigMapLocation)
+	log.Infof("Internal domains ConfigMap: %s", internalDomainsConfigMapLocation)
+	log.Infof("Template fragments ConfigMap: %s", templateFragmentsConfigMapLocation)
 	log.Infof("Default LoadBalancer type: %s", loadBalancerType)
+	log.Infof("Allowed hostname suffixes: %s", strings.Join(allowedHostnameSuffixes, ","))
+	log.Infof("Multi load balancer DNS records: %t (owner ID: %s)", multiLBDNSRecords, dnsOwnerID)
+	log.Infof("Minimum SSL policy: %s (mode: %s)", minSSLPolicy, minSSLPolicyMode)
+	log.Infof("Stuck stack remediation: %s", stuckStackRemediation)
+	if listenerDriftCheckInterval > 0 {
+		log.Infof("Listener drift check interval: %s (remediation: %s)", listenerDriftCheckInterval, listenerDriftRemediation)
+	}
+	if faultInjection.Enabled() {
+		log.Warnf("Injecting faults into AWS requests: error rate %g, throttle rate %g, latency %s", faultInjection.ErrorRate, faultInjection.ThrottleRate, faultInjection.Latency)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go handleTerminationSignals(cancel, syscall.SIGTERM, syscall.SIGQUIT)
 	go serveMetrics(metricsAddress)
+	if webhookAddress != "" {
+		go serveWebhook(webhookAddress, webhookTLSCertFile, webhookTLSKeyFile)
+	}
+	if syncAddress != "" {
+		syncRequests.minInterval = syncMinInterval
+		go serveSync(syncAddress)
+	}
+	ingressStatusUpdates = newStatusUpdateQueue(kubeAdapter.UpdateIngressLoadBalancer, statusUpdateBatchSize)
+	if ingressStateAnnotations {
+		ingressStatusUpdates = ingressStatusUpdates.withStateUpdates(kubeAdapter.UpdateIngressState)
+	}
+	go ingressStatusUpdates.run(ctx, statusUpdateInterval)
+	if recordEvents {
+		ingressEvents.record = kubeAdapter.RecordEvent
+	}
+	managedStacks.interval = stackPollingInterval
+	if certificateEventsQueueURL != "" {
+		receive := func() ([]*aws.CertificateEvent, error) {
+			return awsAdapter.ReceiveCertificateEvents(certificateEventsQueueURL)
+		}
+		go watchCertificateEvents(ctx, receive, certificatesProvider.Refresh)
+	}
 	startPolling(
 		ctx,
 		certificatesProvider,
@@ -342,6 +708,7 @@ func main() {
 		awsAdapter,
 		kubeAdapter,
 		pollingInterval,
+		maxPollingInterval,
 		wafWebAclId,
 	)
 
@@ -357,5 +724,109 @@ func handleTerminationSignals(cancelFunc func(), signals ...os.Signal) {
 
 func serveMetrics(address string) {
 	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/debug/consolidation", serveConsolidationReport)
 	log.Fatal(http.ListenAndServe(address, nil))
 }
+
+// serveSync serves the /sync endpoint on its own address, so it isn't
+// reachable by everyone scraping the metrics.
+func serveSync(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/sync", syncRequests)
+	mux.Handle("/sync/", syncRequests)
+	log.Fatal(http.ListenAndServe(address, mux))
+}
+
+// applyServiceQuotas limits the certificates per ALB and the listener rules
+// per ingress to the Service Quotas of the account. The defaults are kept
+// if the quotas can't be read.
+func applyServiceQuotas(awsAdapter *aws.Adapter) {
+	quotas, err := awsAdapter.LoadBalancerQuotas()
+	if err != nil {
+		log.Warnf("Failed to read service quotas, using defaults: %v", err)
+		if maxCertsPerALB > aws.DefaultMaxCertsPerALB {
+			maxCertsPerALB = aws.DefaultMaxCertsPerALB
+		}
+		return
+	}
+
+	log.Infof("Service quotas: %d certificates, %d listeners and %d rules per ALB, %d ALBs and %d NLBs", quotas.CertificatesPerALB, quotas.ListenersPerALB, quotas.RulesPerALB, quotas.ALBsPerRegion, quotas.NLBsPerRegion)
+	loadBalancerQuotas = quotas
+
+	if quotas.CertificatesPerALB > 0 && maxCertsPerALB > quotas.CertificatesPerALB {
+		log.Warnf("Limiting certificates per ALB to the service quota of %d", quotas.CertificatesPerALB)
+		maxCertsPerALB = quotas.CertificatesPerALB
+	}
+
+	maxListenerRules = maxListenerRulesForQuota(quotas.RulesPerALB)
+}
+
+// maxListenerRulesForQuota returns the number of listener rules an ingress
+// can define within the quota of rules per ALB. The rules are created on
+// both the HTTP and HTTPS listener, next to the rule denying internal
+// domains. Zero means unlimited.
+func maxListenerRulesForQuota(rulesPerALB int) int {
+	if rulesPerALB <= 0 {
+		return 0
+	}
+
+	max := rulesPerALB/2 - 1
+	if max < 1 {
+		max = 1
+	}
+	return max
+}
+
+// newFakeKubernetesConfig starts a fake Kubernetes API serving the objects
+// of the manifests file and returns the configuration to use it. The server
+// runs until the controller exits.
+func newFakeKubernetesConfig(manifestsFile string) (*kubernetes.Config, error) {
+	manifests, err := ioutil.ReadFile(manifestsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	server := fake.NewServer()
+	if err := server.Add(manifests); err != nil {
+		server.Close()
+		return nil, fmt.Errorf("failed to load %s: %v", manifestsFile, err)
+	}
+	log.Infof("Serving the fake Kubernetes API on %s", server.URL())
+	return server.Config(), nil
+}
+
+// configureAWSAdapter applies the settings of the flags to the adapter of a
+// region.
+func configureAWSAdapter(awsAdapter *aws.Adapter, customFilter string) *aws.Adapter {
+	return awsAdapter.
+		WithHealthCheckPath(healthCheckPath).
+		WithHealthCheckPort(healthCheckPort).
+		WithHealthCheckInterval(healthCheckInterval).
+		WithHealthCheckTimeout(healthCheckTimeout).
+		WithTargetPort(targetPort).
+		WithTargetHTTPS(targetHTTPS).
+		WithCreationTimeout(creationTimeout).
+		WithStackTerminationProtection(stackTerminationProtection).
+		WithIdleConnectionTimeout(idleConnectionTimeout).
+		WithDeregistrationDelayTimeout(deregistrationDelayTimeout).
+		WithControllerID(controllerID).
+		WithPreviousOwner(previousClusterID, previousControllerID).
+		WithSslPolicy(sslPolicy).
+		WithIpAddressType(ipAddressType).
+		WithAlbLogsS3Bucket(albLogsS3Bucket).
+		WithAlbLogsS3Prefix(albLogsS3Prefix).
+		WithTemplateBucket(templateBucket).
+		WithChangeSets(stackUpdateChangeSets).
+		WithHTTPRedirectToHTTPS(httpRedirectToHTTPS).
+		WithNLBCrossZone(nlbCrossZone).
+		WithNLBHTTPEnabled(nlbHTTPEnabled).
+		WithCustomFilter(customFilter).
+		WithStackTags(additionalStackTags).
+		WithInternalDomains(internalDomains).
+		WithDenyInternalDomains(denyInternalDomains).
+		WithInternalDomainsDenyResponse(denyInternalRespBody).
+		WithInternalDomainsDenyResponseStatusCode(denyInternalRespStatusCode).
+		WithInternalDomainsDenyResponseContenType(denyInternalRespContentType).
+		WithDNSOwnerID(dnsOwnerID).
+		WithFaultInjection(&faultInjection)
+}
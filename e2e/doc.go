@@ -0,0 +1,4 @@
+// Package e2e contains end-to-end tests of the AWS adapter running against
+// LocalStack. They are only built with the e2e build tag and need a running
+// LocalStack, see the e2e.localstack target of the Makefile.
+package e2e
@@ -0,0 +1,162 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+const (
+	clusterID    = "e2e-cluster"
+	controllerID = aws.DefaultControllerID
+	stackTimeout = 5 * time.Minute
+)
+
+// localStackEndpoint returns the endpoint of LocalStack and sets the
+// defaults of the region and credentials it accepts.
+func localStackEndpoint(t *testing.T) string {
+	endpoint := os.Getenv("LOCALSTACK_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("LOCALSTACK_ENDPOINT not set")
+	}
+	for key, value := range map[string]string{
+		"AWS_REGION":            "us-east-1",
+		"AWS_ACCESS_KEY_ID":     "test",
+		"AWS_SECRET_ACCESS_KEY": "test",
+	} {
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+		}
+	}
+	return endpoint
+}
+
+// setupCluster creates the VPC, subnets and security group the adapter
+// discovers on start and returns the ID of the VPC.
+func setupCluster(t *testing.T, endpoint string) string {
+	svc := ec2.New(session.Must(session.NewSession(awssdk.NewConfig().
+		WithEndpoint(endpoint).
+		WithCredentials(credentials.NewEnvCredentials()))))
+
+	vpc, err := svc.CreateVpc(&ec2.CreateVpcInput{CidrBlock: awssdk.String("10.0.0.0/16")})
+	require.NoError(t, err)
+	vpcID := awssdk.StringValue(vpc.Vpc.VpcId)
+
+	clusterTags := []*ec2.Tag{{Key: awssdk.String("kubernetes.io/cluster/" + clusterID), Value: awssdk.String("owned")}}
+
+	igw, err := svc.CreateInternetGateway(&ec2.CreateInternetGatewayInput{})
+	require.NoError(t, err)
+	_, err = svc.AttachInternetGateway(&ec2.AttachInternetGatewayInput{
+		InternetGatewayId: igw.InternetGateway.InternetGatewayId,
+		VpcId:             awssdk.String(vpcID),
+	})
+	require.NoError(t, err)
+
+	for i, az := range []string{"us-east-1a", "us-east-1b"} {
+		subnet, err := svc.CreateSubnet(&ec2.CreateSubnetInput{
+			VpcId:            awssdk.String(vpcID),
+			CidrBlock:        awssdk.String([]string{"10.0.1.0/24", "10.0.2.0/24"}[i]),
+			AvailabilityZone: awssdk.String(az),
+		})
+		require.NoError(t, err)
+		_, err = svc.CreateTags(&ec2.CreateTagsInput{Resources: []*string{subnet.Subnet.SubnetId}, Tags: clusterTags})
+		require.NoError(t, err)
+
+		table, err := svc.CreateRouteTable(&ec2.CreateRouteTableInput{VpcId: awssdk.String(vpcID)})
+		require.NoError(t, err)
+		_, err = svc.CreateRoute(&ec2.CreateRouteInput{
+			RouteTableId:         table.RouteTable.RouteTableId,
+			DestinationCidrBlock: awssdk.String("0.0.0.0/0"),
+			GatewayId:            igw.InternetGateway.InternetGatewayId,
+		})
+		require.NoError(t, err)
+		_, err = svc.AssociateRouteTable(&ec2.AssociateRouteTableInput{
+			RouteTableId: table.RouteTable.RouteTableId,
+			SubnetId:     subnet.Subnet.SubnetId,
+		})
+		require.NoError(t, err)
+	}
+
+	sg, err := svc.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{
+		GroupName:   awssdk.String(clusterID + "-lb"),
+		Description: awssdk.String("load balancers of " + clusterID),
+		VpcId:       awssdk.String(vpcID),
+	})
+	require.NoError(t, err)
+	_, err = svc.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{sg.GroupId},
+		Tags:      append(clusterTags, &ec2.Tag{Key: awssdk.String("kubernetes:application"), Value: awssdk.String(controllerID)}),
+	})
+	require.NoError(t, err)
+
+	return vpcID
+}
+
+// waitForStack waits until the stack is complete.
+func waitForStack(t *testing.T, adapter *aws.Adapter, name string) *aws.Stack {
+	deadline := time.Now().Add(stackTimeout)
+	for {
+		stack, err := adapter.GetStack(name)
+		if err == nil && stack.IsComplete() {
+			return stack
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stack %q not complete after %s: %v", name, stackTimeout, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func TestStackLifecycle(t *testing.T) {
+	endpoint := localStackEndpoint(t)
+	vpcID := setupCluster(t, endpoint)
+
+	adapter, err := aws.NewAdapterWithEndpoint(clusterID, controllerID, vpcID, endpoint, false, true)
+	require.NoError(t, err)
+
+	opts := &aws.StackOptions{
+		Scheme:           "internet-facing",
+		SecurityGroup:    adapter.SecurityGroupID(),
+		SSLPolicy:        aws.DefaultSslPolicy,
+		IPAddressType:    aws.DefaultIpAddressType,
+		LoadBalancerType: aws.LoadBalancerTypeApplication,
+		HTTP2:            true,
+		CertificateARNs: map[string]time.Time{
+			"arn:aws:acm:us-east-1:000000000000:certificate/e2e": {},
+		},
+	}
+
+	name, err := adapter.CreateStack(opts)
+	require.NoError(t, err)
+	stack := waitForStack(t, adapter, name)
+
+	stacks, err := adapter.FindManagedStacks()
+	require.NoError(t, err)
+	found := false
+	for _, s := range stacks {
+		found = found || s.Name == stack.Name
+	}
+	require.True(t, found, "stack %q not managed by the controller", stack.Name)
+
+	opts.CertificateARNs["arn:aws:acm:us-east-1:000000000000:certificate/old"] = time.Now().Add(time.Hour)
+	_, err = adapter.UpdateStackTags(stack.Name, opts)
+	require.NoError(t, err)
+	waitForStack(t, adapter, stack.Name)
+
+	opts.HTTP2 = false
+	_, err = adapter.UpdateStack(stack.Name, opts)
+	require.NoError(t, err)
+	waitForStack(t, adapter, stack.Name)
+
+	require.NoError(t, adapter.DeleteStack(stack))
+}
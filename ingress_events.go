@@ -0,0 +1,69 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+// Reasons of the Kubernetes events about the load balancers of ingresses.
+const (
+	eventReasonProvisioned  = "Provisioned"
+	eventReasonCertNotFound = "CertNotFound"
+	eventReasonStackFailed  = "StackFailed"
+
+	eventReasonQuotaExceeded              = "QuotaExceeded"
+	eventReasonLoadBalancerLimitExceeded  = "LoadBalancerLimitExceeded"
+	eventReasonListenerRulesLimitExceeded = "ListenerRulesLimitExceeded"
+	eventReasonWaitingForQuota            = "WaitingForQuota"
+	eventReasonInvalidResources           = "InvalidResources"
+	eventReasonFirewallManagerConflict    = "FirewallManagerConflict"
+	eventReasonDNSRecordConflict          = "DNSRecordConflict"
+
+	eventReasonInvalidAnnotation  = "InvalidAnnotation"
+	eventReasonHostnameNotAllowed = "HostnameNotAllowed"
+	eventReasonRoleNotAllowed     = "RoleNotAllowed"
+
+	eventReasonSSLPolicyNotAllowed = "SSLPolicyNotAllowed"
+	eventReasonSSLPolicyUpgraded   = "SSLPolicyUpgraded"
+)
+
+// eventRecorder records Kubernetes events on the resources of the ingresses.
+// Without a record function, e.g. if events are disabled, the events are
+// only logged by the callers.
+type eventRecorder struct {
+	record func(*kubernetes.Ingress, string, string, string, time.Time) error
+}
+
+// ingressEvents records the events of the controller, it's set up on start
+// with --ingress-events.
+var ingressEvents = &eventRecorder{}
+
+// event records the event on the resource of the ingress.
+func (r *eventRecorder) event(ing *kubernetes.Ingress, eventType, reason, message string) {
+	if r.record == nil {
+		return
+	}
+	err := r.record(ing, eventType, reason, message, clock.Now())
+	if err != nil && err != kubernetes.ErrUpdateNotNeeded {
+		log.Warnf("Failed to record event %s of %s %s: %v", reason, ing.ResourceType(), ing, err)
+	}
+}
+
+// loadBalancerEvent records the event on the resources of all ingresses of
+// the load balancer.
+func (r *eventRecorder) loadBalancerEvent(lb *loadBalancer, eventType, reason, message string) {
+	if r.record == nil {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, ingresses := range lb.ingresses {
+		for _, ing := range ingresses {
+			if key := statusUpdateKey(ing); !seen[key] {
+				seen[key] = true
+				r.event(ing, eventType, reason, message)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+func TestLoadBalancerEvent(t *testing.T) {
+	var recorded []string
+	r := &eventRecorder{}
+	a := &kubernetes.Ingress{Namespace: "ns", Name: "a"}
+	b := &kubernetes.Ingress{Namespace: "ns", Name: "b"}
+	lb := &loadBalancer{ingresses: map[string][]*kubernetes.Ingress{
+		"cert-1": {a, b},
+		"cert-2": {a},
+	}}
+
+	// events are disabled without a record function
+	r.loadBalancerEvent(lb, kubernetes.EventTypeNormal, eventReasonProvisioned, "created")
+
+	r.record = func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+		recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+		return nil
+	}
+	r.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonStackFailed, "failed")
+	require.ElementsMatch(t, []string{
+		"ns/a Warning StackFailed failed",
+		"ns/b Warning StackFailed failed",
+	}, recorded)
+}
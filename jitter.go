@@ -0,0 +1,50 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// pollingJitter randomizes the polling intervals, so controllers of many
+// clusters in one account don't call the AWS APIs at the same time. The
+// random source is seeded with the cluster and controller IDs, so the
+// intervals of different controllers diverge, even if they were started
+// together.
+type pollingJitter struct {
+	mu       sync.Mutex
+	rand     *rand.Rand
+	fraction float64
+}
+
+func newPollingJitter(fraction float64, ids ...string) *pollingJitter {
+	h := fnv.New64a()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+	return &pollingJitter{
+		rand:     rand.New(rand.NewSource(int64(h.Sum64()))),
+		fraction: fraction,
+	}
+}
+
+// apply returns the interval changed by a random amount of up to the
+// fraction of it, earlier or later. The interval is returned as is without
+// a fraction.
+func (j *pollingJitter) apply(d time.Duration) time.Duration {
+	if j == nil || j.fraction <= 0 || d <= 0 {
+		return d
+	}
+
+	j.mu.Lock()
+	r := j.rand.Float64()
+	j.mu.Unlock()
+
+	return d + time.Duration((2*r-1)*j.fraction*float64(d))
+}
+
+// intervalJitter randomizes the polling intervals of the controller, it's
+// set up on start.
+var intervalJitter *pollingJitter
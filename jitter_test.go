@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollingJitter(t *testing.T) {
+	var j *pollingJitter
+	require.Equal(t, 30*time.Second, j.apply(30*time.Second))
+	require.Equal(t, 30*time.Second, newPollingJitter(0, "cluster", "controller").apply(30*time.Second))
+
+	j = newPollingJitter(0.1, "cluster", "controller")
+	same := newPollingJitter(0.1, "cluster", "controller")
+	other := newPollingJitter(0.1, "other-cluster", "controller")
+
+	diverged := false
+	for i := 0; i < 100; i++ {
+		d := j.apply(30 * time.Second)
+		require.True(t, d >= 27*time.Second && d <= 33*time.Second, "interval %s out of bounds", d)
+		require.Equal(t, d, same.apply(30*time.Second))
+		if d != other.apply(30*time.Second) {
+			diverged = true
+		}
+	}
+	require.True(t, diverged)
+}
@@ -3,7 +3,13 @@ package kubernetes
 import (
 	"errors"
 	"fmt"
+	"net"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	log "github.com/sirupsen/logrus"
@@ -17,8 +23,17 @@ type Adapter struct {
 	ingressDefaultSecurityGroup    string
 	ingressDefaultSSLPolicy        string
 	ingressDefaultLoadBalancerType string
+	ingressDefaultIPv4Pool         string
 	clusterLocalDomain             string
 	routeGroupSupport              bool
+	namespaceDefaults              bool
+	gatewayAPI                     bool
+	loadBalancerConfigurations     bool
+	statusPorts                    bool
+	statusIPs                      bool
+	lookupHost                     func(string) ([]string, error)
+	changes                        <-chan struct{}
+	events                         *eventCache
 }
 
 type ingressType int
@@ -26,6 +41,7 @@ type ingressType int
 const (
 	ingressTypeIngress ingressType = iota + 1
 	ingressTypeRouteGroup
+	ingressTypeGateway
 )
 
 func (t ingressType) String() string {
@@ -34,6 +50,8 @@ func (t ingressType) String() string {
 		return "ingress"
 	case ingressTypeRouteGroup:
 		return "routegroup"
+	case ingressTypeGateway:
+		return "gateway"
 	default:
 		return "unknown"
 	}
@@ -43,6 +61,8 @@ const (
 	DefaultClusterLocalDomain = ".cluster.local"
 	loadBalancerTypeNLB       = "nlb"
 	loadBalancerTypeALB       = "alb"
+	wafRateLimitKeyIP         = "ip"
+	wafRateLimitKeyForwarded  = "forwarded-ip"
 )
 
 var (
@@ -73,26 +93,78 @@ var (
 		aws.LoadBalancerTypeApplication: loadBalancerTypeALB,
 		aws.LoadBalancerTypeNetwork:     loadBalancerTypeNLB,
 	}
+
+	wafRateLimitKeysIngressToAWS = map[string]string{
+		wafRateLimitKeyIP:        aws.WAFRateLimitKeyIP,
+		wafRateLimitKeyForwarded: aws.WAFRateLimitKeyForwardedIP,
+	}
 )
 
 // Ingress is the ingress-controller's business object. It is used to
 // store Kubernetes ingress and routegroup resources.
 type Ingress struct {
-	Shared           bool
-	HTTP2            bool
-	ClusterLocal     bool
-	CertificateARN   string
-	Namespace        string
-	Name             string
-	Hostname         string
-	Scheme           string
-	SecurityGroup    string
-	SSLPolicy        string
-	IPAddressType    string
-	LoadBalancerType string
-	WAFWebACLID      string
-	Hostnames        []string
-	resourceType     ingressType
+	Shared             bool
+	HTTP2              bool
+	PreserveHostHeader bool
+	HTTPDisabled       bool
+	FrontingNLB        bool
+	// NLBSecurityGroup is true if the security group is attached to a
+	// network load balancer, which is only the case if it's set by an
+	// annotation.
+	NLBSecurityGroup bool
+	LambdaTarget     string
+	// AssumeRoleARN is the role assumed to create the load balancer in
+	// the account of the role.
+	AssumeRoleARN  string
+	ClusterLocal   bool
+	CertificateARN string
+	Namespace      string
+	Name           string
+	Labels         map[string]string
+	Hostname       string
+	// LoadBalancerHostnames are all hostnames of the load balancers in
+	// the status, Hostname is the first one.
+	LoadBalancerHostnames                  []string
+	Scheme                                 string
+	SecurityGroup                          string
+	SSLPolicy                              string
+	IPAddressType                          string
+	LoadBalancerType                       string
+	WAFWebACLID                            string
+	WAFRateLimit                           int64
+	WAFRateLimitKey                        string
+	WAFManagedRuleGroups                   []string
+	SlowStart                              time.Duration
+	ClientKeepAlive                        time.Duration
+	HealthCheckMatcher                     string
+	PreserveClientIP                       string
+	ProxyProtocolV2                        bool
+	ClientRoutingPolicy                    string
+	CapacityUnits                          int64
+	DefaultCertificateARN                  string
+	ExternalTargetGroupARNs                []string
+	ElasticIPs                             []string
+	IPv4Pool                               string
+	EndpointServicePrincipals              []string
+	DenyInternalDomains                    string
+	DenyInternalDomainsResponse            string
+	DenyInternalDomainsResponseContentType string
+	DenyInternalDomainsResponseStatusCode  int
+	HealthyThresholdCount                  uint
+	UnhealthyThresholdCount                uint
+	ListenerRules                          aws.ListenerRuleList
+	Authentication                         *aws.ListenerAuthentication
+	ResourceTags                           aws.ResourceTags
+	LoadBalancerAttributes                 aws.Attributes
+	TargetGroupAttributes                  aws.Attributes
+	Paused                                 bool
+	Hostnames                              []string
+	AnnotationWarnings                     []string
+	resourceType                           ingressType
+	loadBalancerStatus                     []ingressLoadBalancer
+	statusOutdated                         bool
+	state                                  IngressState
+	uid                                    string
 }
 
 // String returns a string representation of the Ingress instance containing the namespace and the resource name.
@@ -100,6 +172,12 @@ func (i *Ingress) String() string {
 	return fmt.Sprintf("%s/%s", i.Namespace, i.Name)
 }
 
+// ResourceType returns the kind of Kubernetes resource the ingress was
+// created from.
+func (i *Ingress) ResourceType() string {
+	return i.resourceType.String()
+}
+
 // ConfigMap is the ingress-controller's representation of a Kubernetes
 // ConfigMap
 type ConfigMap struct {
@@ -118,31 +196,44 @@ func NewAdapter(config *Config, ingressAPIVersion string, ingressClassFilters []
 	if config == nil || config.BaseURL == "" {
 		return nil, ErrInvalidConfiguration
 	}
+	for _, filter := range ingressClassFilters {
+		if _, err := path.Match(filter, ""); err != nil {
+			return nil, fmt.Errorf("invalid ingress class filter %q: %v", filter, err)
+		}
+	}
 	c, err := newSimpleClient(config, disableInstrumentedHttpClient)
 	if err != nil {
 		return nil, err
 	}
+	ic := &ingressClient{apiVersion: ingressAPIVersion}
+	if ingressAPIVersion == IngressAPIVersionAuto {
+		ic = &ingressClient{autoDetect: true}
+	}
 	return &Adapter{
 		kubeClient:                     c,
-		ingressClient:                  &ingressClient{apiVersion: ingressAPIVersion},
+		ingressClient:                  ic,
 		ingressFilters:                 ingressClassFilters,
 		ingressDefaultSecurityGroup:    ingressDefaultSecurityGroup,
 		ingressDefaultSSLPolicy:        ingressDefaultSSLPolicy,
 		ingressDefaultLoadBalancerType: loadBalancerTypesAWSToIngress[ingressDefaultLoadBalancerType],
 		clusterLocalDomain:             clusterLocalDomain,
 		routeGroupSupport:              true,
+		events:                         &eventCache{},
+		lookupHost:                     net.LookupHost,
 	}, nil
 }
 
 func (a *Adapter) newIngressFromKube(kubeIngress *ingress) *Ingress {
 	var host string
-	var hostnames []string
+	var hostnames, lbHostnames []string
 	for _, ingressLoadBalancer := range kubeIngress.Status.LoadBalancer.Ingress {
 		if ingressLoadBalancer.Hostname != "" {
-			host = ingressLoadBalancer.Hostname
-			break
+			lbHostnames = append(lbHostnames, ingressLoadBalancer.Hostname)
 		}
 	}
+	if len(lbHostnames) > 0 {
+		host = lbHostnames[0]
+	}
 
 	for _, rule := range kubeIngress.Spec.Rules {
 		if rule.Host != "" && (a.clusterLocalDomain == "" || !strings.HasSuffix(rule.Host, a.clusterLocalDomain)) {
@@ -154,23 +245,32 @@ func (a *Adapter) newIngressFromKube(kubeIngress *ingress) *Ingress {
 
 	ingress.Namespace = kubeIngress.Metadata.Namespace
 	ingress.Name = kubeIngress.Metadata.Name
+	ingress.uid = kubeIngress.Metadata.UID
+	ingress.Labels = kubeIngress.Metadata.Labels
 	ingress.Hostname = host
+	ingress.LoadBalancerHostnames = lbHostnames
 	ingress.Hostnames = hostnames
 	ingress.resourceType = ingressTypeIngress
 	ingress.ClusterLocal = len(hostnames) < 1
+	ingress.state = newIngressState(kubeIngress.Metadata.Annotations)
+	ingress.loadBalancerStatus = kubeIngress.Status.LoadBalancer.Ingress
+	ingress.statusOutdated = !a.statusDetailsUpToDate(ingress)
+	logAnnotationWarnings(ingress)
 
 	return ingress
 }
 
 func (a *Adapter) newIngressFromRouteGroup(rg *routegroup) *Ingress {
 	var host string
-	var hostnames []string
+	var hostnames, lbHostnames []string
 	for _, lb := range rg.Status.LoadBalancer.Routegroup {
 		if lb.Hostname != "" {
-			host = lb.Hostname
-			break
+			lbHostnames = append(lbHostnames, lb.Hostname)
 		}
 	}
+	if len(lbHostnames) > 0 {
+		host = lbHostnames[0]
+	}
 
 	for _, host := range rg.Spec.Hosts {
 		if host != "" && (a.clusterLocalDomain == "" || !strings.HasSuffix(host, a.clusterLocalDomain)) {
@@ -182,17 +282,78 @@ func (a *Adapter) newIngressFromRouteGroup(rg *routegroup) *Ingress {
 
 	ingress.Namespace = rg.Metadata.Namespace
 	ingress.Name = rg.Metadata.Name
+	ingress.uid = rg.Metadata.UID
+	ingress.Labels = rg.Metadata.Labels
 	ingress.Hostname = host
+	ingress.LoadBalancerHostnames = lbHostnames
 	ingress.Hostnames = hostnames
 	ingress.resourceType = ingressTypeRouteGroup
 	ingress.ClusterLocal = len(hostnames) < 1
+	ingress.state = newIngressState(rg.Metadata.Annotations)
+	logAnnotationWarnings(ingress)
+
+	return ingress
+}
+
+// newIngressFromGateway maps a Gateway to the Ingress business object with
+// the hostnames of its listeners and of the HTTPRoutes attached to it. A
+// certificate ARN in the TLS options of a listener takes precedence over the
+// one of the annotation.
+func (a *Adapter) newIngressFromGateway(gw *gateway, routes []*httpRoute) *Ingress {
+	var host string
+	var hostnames, lbHostnames []string
+	for _, address := range gw.Status.Addresses {
+		if address.Type == gatewayAddressTypeHostname && address.Value != "" {
+			lbHostnames = append(lbHostnames, address.Value)
+		}
+	}
+	if len(lbHostnames) > 0 {
+		host = lbHostnames[0]
+	}
+
+	for _, host := range gatewayHostnames(gw, routes) {
+		if a.clusterLocalDomain == "" || !strings.HasSuffix(host, a.clusterLocalDomain) {
+			hostnames = append(hostnames, host)
+		}
+	}
+
+	ingress := a.parseAnnotations(gw.Metadata.Annotations)
+	if arn := gatewayCertificateARN(gw); arn != "" {
+		ingress.CertificateARN = arn
+	}
+
+	ingress.Namespace = gw.Metadata.Namespace
+	ingress.Name = gw.Metadata.Name
+	ingress.uid = gw.Metadata.UID
+	ingress.Labels = gw.Metadata.Labels
+	ingress.Hostname = host
+	ingress.LoadBalancerHostnames = lbHostnames
+	ingress.Hostnames = hostnames
+	ingress.resourceType = ingressTypeGateway
+	ingress.ClusterLocal = len(hostnames) < 1
+	ingress.state = newIngressState(gw.Metadata.Annotations)
+	logAnnotationWarnings(ingress)
 
 	return ingress
 }
 
+// logAnnotationWarnings logs the warnings about the annotations of the
+// ingress, once its resource is known.
+func logAnnotationWarnings(ingress *Ingress) {
+	for _, warning := range ingress.AnnotationWarnings {
+		log.Warnf("%s of %s %s", warning, ingress.ResourceType(), ingress)
+	}
+}
+
 // parseAnnotations parses the ingress configuration from the annotations of an
-// Ingress or ReouteGroup resource.
+// Ingress or ReouteGroup resource. Invalid or unsupported values are ignored
+// or adjusted, which is reported by the AnnotationWarnings of the ingress.
 func (a *Adapter) parseAnnotations(annotations map[string]string) *Ingress {
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
 	var scheme string
 	// Set schema to default if annotation value is not valid
 	switch getAnnotationsString(annotations, ingressSchemeAnnotation, "") {
@@ -208,8 +369,17 @@ func (a *Adapter) parseAnnotations(annotations map[string]string) *Ingress {
 	}
 
 	ipAddressType := aws.IPAddressTypeIPV4
-	if getAnnotationsString(annotations, ingressALBIPAddressType, "") == aws.IPAddressTypeDualstack {
+	switch v := getAnnotationsString(annotations, ingressALBIPAddressType, ""); v {
+	case aws.IPAddressTypeDualstack:
 		ipAddressType = aws.IPAddressTypeDualstack
+	case aws.IPAddressTypeDualstackWithoutPublicIPV4:
+		// internal load balancers have no public IPv4 addresses anyway
+		if scheme == elbv2.LoadBalancerSchemeEnumInternetFacing {
+			ipAddressType = aws.IPAddressTypeDualstackWithoutPublicIPV4
+		} else {
+			warn("Using IP address type %s instead of %s for an internal load balancer", aws.IPAddressTypeDualstack, v)
+			ipAddressType = aws.IPAddressTypeDualstack
+		}
 	}
 
 	sslPolicy := getAnnotationsString(annotations, ingressSSLPolicyAnnotation, a.ingressDefaultSSLPolicy)
@@ -225,27 +395,366 @@ func (a *Adapter) parseAnnotations(annotations map[string]string) *Ingress {
 	// convert to the internal naming e.g. nlb -> network
 	loadBalancerType = loadBalancerTypesIngressToAWS[loadBalancerType]
 
-	if loadBalancerType == aws.LoadBalancerTypeNetwork {
-		// ensure ipv4 for network load balancers
+	// network load balancers always have public IPv4 addresses when
+	// internet-facing
+	if loadBalancerType == aws.LoadBalancerTypeNetwork && ipAddressType == aws.IPAddressTypeDualstackWithoutPublicIPV4 {
+		warn("Using IP address type %s instead of %s for a network load balancer", aws.IPAddressTypeDualstack, ipAddressType)
+		ipAddressType = aws.IPAddressTypeDualstack
+	}
+
+	// a network load balancer can only front application load balancers,
+	// and only IPv4 is supported for their targets
+	frontingNLB := loadBalancerType == aws.LoadBalancerTypeApplication &&
+		getAnnotationsString(annotations, ingressFrontingNLBAnnotation, "") == "true"
+	if frontingNLB {
 		ipAddressType = aws.IPAddressTypeIPV4
 	}
 
+	// network load balancers only get a security group if the annotation
+	// sets one, since it can't be attached to the existing ones created
+	// without.
+	nlbSecurityGroup := loadBalancerType == aws.LoadBalancerTypeNetwork &&
+		getAnnotationsString(annotations, ingressSecurityGroupAnnotation, "") != ""
+
 	http2 := true
 	if getAnnotationsString(annotations, ingressHTTP2Annotation, "") == "false" {
 		http2 = false
 	}
 
+	// preserving the host header is only supported by application load
+	// balancers
+	preserveHostHeader := loadBalancerType == aws.LoadBalancerTypeApplication &&
+		getAnnotationsString(annotations, ingressPreserveHostHeaderAnnotation, "") == "true"
+
+	// slow start is only supported by target groups of application
+	// load balancers and ignored if outside of the allowed range.
+	var slowStart time.Duration
+	if loadBalancerType == aws.LoadBalancerTypeApplication {
+		d, err := time.ParseDuration(getAnnotationsString(annotations, ingressSlowStartAnnotation, "0s"))
+		if err == nil && d >= aws.MinSlowStartDuration && d <= aws.MaxSlowStartDuration {
+			slowStart = d.Truncate(time.Second)
+		}
+	}
+
+	// the client keep alive is only supported by application load
+	// balancers and ignored if outside of the allowed range.
+	var clientKeepAlive time.Duration
+	if loadBalancerType == aws.LoadBalancerTypeApplication {
+		d, err := time.ParseDuration(getAnnotationsString(annotations, ingressClientKeepAliveAnnotation, "0s"))
+		if err == nil && d >= aws.MinClientKeepAlive && d <= aws.MaxClientKeepAlive {
+			clientKeepAlive = d.Truncate(time.Second)
+		}
+	}
+
+	// the health check matcher is only supported by target groups of
+	// application load balancers and ignored if invalid.
+	var healthCheckMatcher string
+	if loadBalancerType == aws.LoadBalancerTypeApplication {
+		matcher := getAnnotationsString(annotations, ingressHealthCheckMatcherAnnotation, "")
+		if aws.IsValidHealthCheckMatcher(matcher) {
+			healthCheckMatcher = matcher
+		}
+	}
+
+	// client IP preservation is only configurable for target groups of
+	// network load balancers and ignored if invalid.
+	var preserveClientIP string
+	if loadBalancerType == aws.LoadBalancerTypeNetwork {
+		switch v := getAnnotationsString(annotations, ingressPreserveClientIPAnnotation, ""); v {
+		case "true", "false":
+			preserveClientIP = v
+		}
+	}
+
+	// static IPs are only supported by internet-facing network load
+	// balancers. Invalid values are ignored.
+	var elasticIPs []string
+	if v := getAnnotationsString(annotations, ingressElasticIPsAnnotation, ""); v != "" {
+		ids, err := aws.NewElasticIPs(v)
+		switch {
+		case err != nil:
+			warn("Ignoring Elastic IPs: %v", err)
+		case loadBalancerType != aws.LoadBalancerTypeNetwork || scheme != elbv2.LoadBalancerSchemeEnumInternetFacing:
+			warn("Ignoring Elastic IPs %v, they are only supported by internet-facing network load balancers", ids)
+		default:
+			elasticIPs = ids
+		}
+	}
+
+	// addresses are taken from an IPAM pool by internet-facing application
+	// load balancers and from a public IPv4 pool by the Elastic IPs
+	// allocated for internet-facing network load balancers. The default
+	// pool is silently skipped by the other load balancers, and invalid
+	// values are ignored.
+	var ipv4Pool string
+	if v := getAnnotationsString(annotations, ingressIPv4PoolAnnotation, a.ingressDefaultIPv4Pool); v != "" {
+		_, annotated := annotations[ingressIPv4PoolAnnotation]
+		internetFacing := scheme == elbv2.LoadBalancerSchemeEnumInternetFacing
+		autoAllocatedElasticIPs := len(elasticIPs) == 1 && elasticIPs[0] == aws.AutoAllocateElasticIPs
+		switch {
+		case !aws.IsValidIPv4Pool(v):
+			warn("Ignoring invalid IPv4 pool %q", v)
+		case internetFacing && loadBalancerType == aws.LoadBalancerTypeApplication && !frontingNLB && aws.IsIPAMPoolID(v):
+			ipv4Pool = v
+		case internetFacing && loadBalancerType == aws.LoadBalancerTypeNetwork && autoAllocatedElasticIPs && aws.IsPublicIPv4PoolID(v):
+			ipv4Pool = v
+		case annotated:
+			warn("Ignoring IPv4 pool %s, IPAM pools are only supported by internet-facing application load balancers and public IPv4 pools by auto-allocated Elastic IPs", v)
+		}
+	}
+
+	// VPC endpoint services are only supported by internal network load
+	// balancers. Invalid values are ignored.
+	var endpointServicePrincipals []string
+	if v := getAnnotationsString(annotations, ingressEndpointServicePrincipalsAnnotation, ""); v != "" {
+		principals, err := aws.NewEndpointServicePrincipals(v)
+		switch {
+		case err != nil:
+			warn("Ignoring endpoint service principals: %v", err)
+		case loadBalancerType != aws.LoadBalancerTypeNetwork || scheme != elbv2.LoadBalancerSchemeEnumInternal:
+			warn("Ignoring endpoint service principals %v, endpoint services are only supported by internal network load balancers", principals)
+		default:
+			endpointServicePrincipals = principals
+		}
+	}
+
+	// the PROXY protocol is only supported by target groups of network
+	// load balancers
+	proxyProtocolV2 := loadBalancerType == aws.LoadBalancerTypeNetwork &&
+		getAnnotationsString(annotations, ingressProxyProtocolV2Annotation, "") == "true"
+
+	// the client routing policy only applies to network load balancers
+	var clientRoutingPolicy string
+	if loadBalancerType == aws.LoadBalancerTypeNetwork {
+		if v, ok := annotations[ingressClientRoutingPolicyAnnotation]; ok {
+			if aws.IsValidClientRoutingPolicy(v) {
+				clientRoutingPolicy = v
+			} else {
+				warn("Ignoring invalid client routing policy %q", v)
+			}
+		}
+	}
+
+	// capacity can only be reserved for application load balancers
+	var capacityUnits int64
+	if v := getAnnotationsString(annotations, ingressCapacityUnitsAnnotation, ""); v != "" && loadBalancerType == aws.LoadBalancerTypeApplication {
+		units, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || !aws.IsValidCapacityUnits(units) {
+			warn("Ignoring invalid capacity units %q, at least %d are required", v, aws.MinCapacityUnits)
+		} else {
+			capacityUnits = units
+		}
+	}
+
+	// overrides the controller setting, ignored if invalid
+	var denyInternalDomains string
+	switch v := getAnnotationsString(annotations, ingressDenyInternalDomainsAnnotation, ""); v {
+	case "true", "false":
+		denyInternalDomains = v
+	}
+
+	// the response for requests to internal domains overrides the
+	// controller settings, invalid values are ignored
+	denyResponse := getAnnotationsString(annotations, ingressDenyInternalDomainsResponseAnnotation, "")
+	denyResponseContentType := getAnnotationsString(annotations, ingressDenyInternalDomainsResponseContentTypeAnnotation, "")
+	if denyResponseContentType != "" && !aws.IsValidDenyResponseContentType(denyResponseContentType) {
+		denyResponseContentType = ""
+	}
+	var denyResponseStatusCode int
+	if code, err := strconv.Atoi(getAnnotationsString(annotations, ingressDenyInternalDomainsResponseStatusCodeAnnotation, "")); err == nil && aws.IsValidDenyResponseStatusCode(code) {
+		denyResponseStatusCode = code
+	}
+
+	healthyThresholdCount := getThresholdCount(annotations, ingressHealthyThresholdAnnotation)
+	unhealthyThresholdCount := getThresholdCount(annotations, ingressUnhealthyThresholdAnnotation)
+
+	// only application load balancers can forward requests to Lambda
+	// functions, invalid ARNs are ignored
+	var lambdaTarget string
+	if arn := getAnnotationsString(annotations, ingressLambdaTargetAnnotation, ""); arn != "" && loadBalancerType == aws.LoadBalancerTypeApplication {
+		if aws.IsLambdaFunctionARN(arn) {
+			lambdaTarget = arn
+		} else {
+			warn("Ignoring invalid Lambda function ARN %q", arn)
+		}
+	}
+
+	// invalid role ARNs are ignored
+	var assumeRoleARN string
+	if arn := getAnnotationsString(annotations, ingressAssumeRoleARNAnnotation, ""); arn != "" {
+		if aws.IsRoleARN(arn) {
+			assumeRoleARN = arn
+		} else {
+			warn("Ignoring invalid role ARN %q", arn)
+		}
+	}
+
+	// listener rules are only supported by application load balancers and
+	// ignored if invalid.
+	var listenerRules aws.ListenerRuleList
+	if rules := getAnnotationsString(annotations, ingressListenerRulesAnnotation, ""); rules != "" && loadBalancerType == aws.LoadBalancerTypeApplication {
+		var err error
+		listenerRules, err = aws.NewListenerRuleListFromJSON([]byte(rules))
+		if err != nil {
+			warn("Ignoring listener rules: %v", err)
+		}
+	}
+
+	// authentication is only supported by application load balancers and
+	// ignored if invalid.
+	var authentication *aws.ListenerAuthentication
+	if auth := getAnnotationsString(annotations, ingressAuthenticationAnnotation, ""); auth != "" && loadBalancerType == aws.LoadBalancerTypeApplication {
+		var err error
+		authentication, err = aws.NewListenerAuthenticationFromJSON([]byte(auth))
+		if err != nil {
+			warn("Ignoring authentication: %v", err)
+		}
+	}
+
+	// invalid resource tags are ignored
+	var resourceTags aws.ResourceTags
+	if tags := getAnnotationsString(annotations, ingressResourceTagsAnnotation, ""); tags != "" {
+		var err error
+		resourceTags, err = aws.NewResourceTagsFromJSON([]byte(tags))
+		if err != nil {
+			warn("Ignoring resource tags: %v", err)
+		}
+	}
+
+	// the rate limit and the managed rule groups make the controller
+	// create a WebACL for application load balancers, which can't be
+	// combined with a referenced one. Invalid values are ignored.
+	wafWebACLID := getAnnotationsString(annotations, ingressWAFWebACLIDAnnotation, "")
+	var wafRateLimit int64
+	var wafRateLimitKey string
+	if v := getAnnotationsString(annotations, ingressWAFRateLimitAnnotation, ""); v != "" && loadBalancerType == aws.LoadBalancerTypeApplication {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		switch {
+		case err != nil || !aws.IsValidWAFRateLimit(limit):
+			warn("Ignoring invalid WAF rate limit %q", v)
+		case wafWebACLID != "":
+			warn("Ignoring WAF rate limit %d, the load balancer is associated with the WebACL %s", limit, wafWebACLID)
+		default:
+			wafRateLimit = limit
+			key := getAnnotationsString(annotations, ingressWAFRateLimitKeyAnnotation, wafRateLimitKeyIP)
+			if wafRateLimitKey = wafRateLimitKeysIngressToAWS[key]; wafRateLimitKey == "" {
+				warn("Ignoring invalid WAF rate limit key %q", key)
+				wafRateLimitKey = aws.WAFRateLimitKeyIP
+			}
+		}
+	}
+
+	var wafManagedRuleGroups []string
+	if v := getAnnotationsString(annotations, ingressWAFManagedRuleGroupsAnnotation, ""); v != "" && loadBalancerType == aws.LoadBalancerTypeApplication {
+		groups, err := aws.NewWAFManagedRuleGroups(v)
+		switch {
+		case err != nil:
+			warn("Ignoring WAF managed rule groups: %v", err)
+		case wafWebACLID != "":
+			warn("Ignoring WAF managed rule groups %v, the load balancer is associated with the WebACL %s", groups, wafWebACLID)
+		default:
+			wafManagedRuleGroups = groups
+		}
+	}
+
+	// only application load balancers can split requests between target
+	// groups, which isn't done for Lambda functions
+	var externalTargetGroupARNs []string
+	if v := getAnnotationsString(annotations, ingressExternalTargetGroupARNsAnnotation, ""); v != "" && loadBalancerType == aws.LoadBalancerTypeApplication {
+		arns, err := aws.NewExternalTargetGroupARNs(v)
+		switch {
+		case err != nil:
+			warn("Ignoring external target groups: %v", err)
+		case lambdaTarget != "":
+			warn("Ignoring external target groups %v, requests are forwarded to the Lambda function %s", arns, lambdaTarget)
+		default:
+			externalTargetGroupARNs = arns
+		}
+	}
+
+	// invalid attributes are ignored
+	loadBalancerAttributes, err := getAttributes(annotations, ingressAttributesAnnotation)
+	if err != nil {
+		warn("Ignoring attributes of %s: %v", ingressAttributesAnnotation, err)
+	}
+	targetGroupAttributes, err := getAttributes(annotations, ingressTargetGroupAttributesAnnotation)
+	if err != nil {
+		warn("Ignoring attributes of %s: %v", ingressTargetGroupAttributesAnnotation, err)
+	}
+
 	return &Ingress{
-		CertificateARN:   getAnnotationsString(annotations, ingressCertificateARNAnnotation, ""),
-		Scheme:           scheme,
-		Shared:           shared,
-		SecurityGroup:    getAnnotationsString(annotations, ingressSecurityGroupAnnotation, a.ingressDefaultSecurityGroup),
-		SSLPolicy:        sslPolicy,
-		IPAddressType:    ipAddressType,
-		LoadBalancerType: loadBalancerType,
-		WAFWebACLID:      getAnnotationsString(annotations, ingressWAFWebACLIDAnnotation, ""),
-		HTTP2:            http2,
+		CertificateARN:                         getAnnotationsString(annotations, ingressCertificateARNAnnotation, ""),
+		Scheme:                                 scheme,
+		Shared:                                 shared,
+		SecurityGroup:                          getAnnotationsString(annotations, ingressSecurityGroupAnnotation, a.ingressDefaultSecurityGroup),
+		SSLPolicy:                              sslPolicy,
+		IPAddressType:                          ipAddressType,
+		LoadBalancerType:                       loadBalancerType,
+		WAFWebACLID:                            wafWebACLID,
+		WAFRateLimit:                           wafRateLimit,
+		WAFRateLimitKey:                        wafRateLimitKey,
+		WAFManagedRuleGroups:                   wafManagedRuleGroups,
+		HTTP2:                                  http2,
+		PreserveHostHeader:                     preserveHostHeader,
+		HTTPDisabled:                           getAnnotationsString(annotations, ingressHTTPDisabledAnnotation, "") == "true",
+		FrontingNLB:                            frontingNLB,
+		NLBSecurityGroup:                       nlbSecurityGroup,
+		LambdaTarget:                           lambdaTarget,
+		AssumeRoleARN:                          assumeRoleARN,
+		SlowStart:                              slowStart,
+		ClientKeepAlive:                        clientKeepAlive,
+		HealthCheckMatcher:                     healthCheckMatcher,
+		PreserveClientIP:                       preserveClientIP,
+		ProxyProtocolV2:                        proxyProtocolV2,
+		ClientRoutingPolicy:                    clientRoutingPolicy,
+		CapacityUnits:                          capacityUnits,
+		DefaultCertificateARN:                  getAnnotationsString(annotations, ingressDefaultCertificateARNAnnotation, ""),
+		ExternalTargetGroupARNs:                externalTargetGroupARNs,
+		ElasticIPs:                             elasticIPs,
+		IPv4Pool:                               ipv4Pool,
+		EndpointServicePrincipals:              endpointServicePrincipals,
+		DenyInternalDomains:                    denyInternalDomains,
+		DenyInternalDomainsResponse:            denyResponse,
+		DenyInternalDomainsResponseContentType: denyResponseContentType,
+		DenyInternalDomainsResponseStatusCode:  denyResponseStatusCode,
+		HealthyThresholdCount:                  healthyThresholdCount,
+		UnhealthyThresholdCount:                unhealthyThresholdCount,
+		ListenerRules:                          listenerRules,
+		Authentication:                         authentication,
+		ResourceTags:                           resourceTags,
+		LoadBalancerAttributes:                 loadBalancerAttributes,
+		TargetGroupAttributes:                  targetGroupAttributes,
+		Paused:                                 getAnnotationsString(annotations, ingressPausedAnnotation, "") == "true",
+		AnnotationWarnings:                     warnings,
+	}
+}
+
+// getThresholdCount returns the health check threshold count of the
+// annotation or 0 if it's missing or outside of the allowed range.
+func getThresholdCount(annotations map[string]string, key string) uint {
+	count, err := strconv.ParseUint(getAnnotationsString(annotations, key, ""), 10, 32)
+	if err != nil || count < aws.MinHealthCheckThresholdCount || count > aws.MaxHealthCheckThresholdCount {
+		return 0
+	}
+	return uint(count)
+}
+
+// getAttributes returns the load balancer or target group attributes of the
+// annotation, or nil if it's missing or invalid.
+func getAttributes(annotations map[string]string, key string) (aws.Attributes, error) {
+	value := getAnnotationsString(annotations, key, "")
+	if value == "" {
+		return nil, nil
+	}
+
+	attributes, err := aws.NewAttributes(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(attributes) == 0 {
+		return nil, nil
 	}
+	return attributes, nil
 }
 
 func newMetadataForKube(i *Ingress) kubeItemMetadata {
@@ -277,31 +786,225 @@ func newMetadataForKube(i *Ingress) kubeItemMetadata {
 }
 
 func newIngressForKube(i *Ingress) *ingress {
+	status := make([]ingressLoadBalancer, 0, len(i.LoadBalancerHostnames))
+	for _, hostname := range i.statusHostnames() {
+		status = append(status, ingressLoadBalancer{Hostname: hostname})
+	}
 	return &ingress{
 		Metadata: newMetadataForKube(i),
 		Status: ingressStatus{
 			LoadBalancer: ingressLoadBalancerStatus{
-				Ingress: []ingressLoadBalancer{
-					{Hostname: i.Hostname},
-				},
+				Ingress: status,
 			},
 		},
 	}
 }
 
 func newRouteGroupForKube(i *Ingress) *routegroup {
+	status := make([]routegroupLoadBalancer, 0, len(i.LoadBalancerHostnames))
+	for _, hostname := range i.statusHostnames() {
+		status = append(status, routegroupLoadBalancer{Hostname: hostname})
+	}
 	return &routegroup{
 		Metadata: newMetadataForKube(i),
 		Status: routegroupStatus{
 			LoadBalancer: routegroupLoadBalancerStatus{
-				Routegroup: []routegroupLoadBalancer{
-					{Hostname: i.Hostname},
-				},
+				Routegroup: status,
 			},
 		},
 	}
 }
 
+func newGatewayForKube(i *Ingress) *gateway {
+	addresses := make([]gatewayAddress, 0, len(i.LoadBalancerHostnames))
+	for _, hostname := range i.statusHostnames() {
+		if hostname != "" {
+			addresses = append(addresses, gatewayAddress{Type: gatewayAddressTypeHostname, Value: hostname})
+		}
+	}
+	return &gateway{
+		Metadata: newMetadataForKube(i),
+		Status: gatewayStatus{
+			Addresses: addresses,
+		},
+	}
+}
+
+// statusHostnames returns the hostnames of the load balancers in the status
+// of the ingress.
+func (i *Ingress) statusHostnames() []string {
+	if len(i.LoadBalancerHostnames) > 0 {
+		return i.LoadBalancerHostnames
+	}
+	return []string{i.Hostname}
+}
+
+// statusUpToDate returns true if the status with the current hostnames
+// doesn't need to be updated to the new ones. A single hostname is up to
+// date if it's any of the current ones, multiple hostnames must all be the
+// current ones in the same order.
+func statusUpToDate(current, hostnames []string) bool {
+	if len(hostnames) == 1 {
+		for _, hostname := range current {
+			if hostname == hostnames[0] {
+				return true
+			}
+		}
+		return false
+	}
+	return reflect.DeepEqual(current, hostnames)
+}
+
+// StatusOutdated returns true if the status of the ingress lacks the ports
+// or IP addresses of its load balancers, or has them while they aren't
+// published, so it needs an update even with the current DNS names.
+func (i *Ingress) StatusOutdated() bool {
+	return i.statusOutdated
+}
+
+// statusDetailsUpToDate returns true if the load balancers in the status of
+// the ingress have the ports and IP addresses published by the adapter.
+func (a *Adapter) statusDetailsUpToDate(i *Ingress) bool {
+	var hasHostname, hasIP bool
+	for _, lb := range i.loadBalancerStatus {
+		if lb.Hostname == "" && lb.IP == "" {
+			continue
+		}
+		hasHostname = hasHostname || lb.Hostname != ""
+		hasIP = hasIP || lb.IP != ""
+		if !reflect.DeepEqual(lb.Ports, a.loadBalancerPorts(i)) {
+			return false
+		}
+	}
+	return hasIP == (hasHostname && a.publishesIPs(i))
+}
+
+// loadBalancerPorts returns the ports of the listeners of the load balancer
+// of the ingress, if they are published in its status.
+func (a *Adapter) loadBalancerPorts(i *Ingress) []ingressPortStatus {
+	if !a.statusPorts {
+		return nil
+	}
+	var ports []ingressPortStatus
+	if !i.HTTPDisabled {
+		ports = append(ports, ingressPortStatus{Port: 80, Protocol: "TCP"})
+	}
+	return append(ports, ingressPortStatus{Port: 443, Protocol: "TCP"})
+}
+
+// publishesIPs returns true if the IP addresses of the load balancer of the
+// ingress are published in its status. Only network load balancers have
+// static IP addresses, those of application load balancers change.
+func (a *Adapter) publishesIPs(i *Ingress) bool {
+	return a.statusIPs && (i.LoadBalancerType == aws.LoadBalancerTypeNetwork || i.FrontingNLB)
+}
+
+// loadBalancerStatus returns the status of the ingress with the load
+// balancer hostnames, followed by their IP addresses if published. A
+// hostname which doesn't resolve yet, e.g. of a new load balancer, is
+// published without IP addresses.
+func (a *Adapter) loadBalancerStatus(i *Ingress, hostnames []string) []ingressLoadBalancer {
+	ports := a.loadBalancerPorts(i)
+	status := make([]ingressLoadBalancer, 0, len(hostnames))
+	var ips []string
+	for _, hostname := range hostnames {
+		if hostname == "" {
+			status = append(status, ingressLoadBalancer{})
+			continue
+		}
+		status = append(status, ingressLoadBalancer{Hostname: hostname, Ports: ports})
+		if !a.publishesIPs(i) {
+			continue
+		}
+		addrs, err := a.lookupHost(hostname)
+		if err != nil {
+			log.Debugf("Failed to resolve the IP addresses of %s for %s %s: %v", hostname, i.ResourceType(), i, err)
+			continue
+		}
+		ips = append(ips, addrs...)
+	}
+	sort.Strings(ips)
+	for _, ip := range ips {
+		status = append(status, ingressLoadBalancer{IP: ip, Ports: ports})
+	}
+	return status
+}
+
+// WithNamespaceDefaults returns the receiver adapter after enabling the
+// controller annotations set on namespaces as defaults for their ingresses.
+func (a *Adapter) WithNamespaceDefaults(enabled bool) *Adapter {
+	a.namespaceDefaults = enabled
+	return a
+}
+
+// WithDefaultIPv4Pool returns the receiver adapter after setting the IPAM
+// pool or public IPv4 pool the addresses of internet-facing load balancers
+// are taken from, unless the ingresses select another one.
+func (a *Adapter) WithDefaultIPv4Pool(pool string) *Adapter {
+	a.ingressDefaultIPv4Pool = pool
+	return a
+}
+
+// WithGatewayAPI returns the receiver adapter after enabling the Gateway
+// API support, which provisions load balancers for Gateways besides
+// Ingresses and RouteGroups.
+func (a *Adapter) WithGatewayAPI(enabled bool) *Adapter {
+	a.gatewayAPI = enabled
+	return a
+}
+
+// WithLoadBalancerConfigurations returns the receiver adapter after
+// enabling the LoadBalancerConfiguration resources referenced by ingresses,
+// routegroups and Gateways as defaults for their annotations.
+func (a *Adapter) WithLoadBalancerConfigurations(enabled bool) *Adapter {
+	a.loadBalancerConfigurations = enabled
+	return a
+}
+
+// WithStatusPorts returns the receiver adapter after enabling the ports of
+// the load balancers in the status of ingresses.
+func (a *Adapter) WithStatusPorts(enabled bool) *Adapter {
+	a.statusPorts = enabled
+	return a
+}
+
+// WithStatusIPs returns the receiver adapter after enabling the IP
+// addresses of network load balancers in the status of ingresses, besides
+// their hostnames.
+func (a *Adapter) WithStatusIPs(enabled bool) *Adapter {
+	a.statusIPs = enabled
+	return a
+}
+
+// WithWatch returns the receiver adapter after enabling watches of the
+// listed resources, which are then served from a local cache instead of
+// listed in every reconciliation. Changes of the resources are signaled by
+// Changes.
+func (a *Adapter) WithWatch(enabled bool) *Adapter {
+	if w, ok := a.kubeClient.(watcher); ok && enabled {
+		c := newWatchingClient(a.kubeClient, w)
+		a.kubeClient = c
+		a.changes = c.changes
+	}
+	return a
+}
+
+// Changes returns a channel which receives when any watched resource is
+// added, deleted or changed other than in its status. It's nil without
+// watches.
+func (a *Adapter) Changes() <-chan struct{} {
+	return a.changes
+}
+
+// namespaceDefaultAnnotations returns the default annotations of the
+// namespaces if enabled.
+func (a *Adapter) namespaceDefaultAnnotations() (map[string]map[string]string, error) {
+	if !a.namespaceDefaults {
+		return nil, nil
+	}
+	return namespaceDefaultAnnotations(a.kubeClient)
+}
+
 // Get ingress class filters that are used to filter ingresses acted upon.
 func (a *Adapter) IngressFiltersString() string {
 	return strings.TrimSpace(strings.Join(a.ingressFilters, ","))
@@ -329,7 +1032,22 @@ func (a *Adapter) ListResources() ([]*Ingress, error) {
 		return nil, err
 	}
 	a.routeGroupSupport = true
-	return append(ings, rgs...), nil
+	ings = append(ings, rgs...)
+
+	if a.gatewayAPI {
+		gws, err := a.ListGateways()
+		if err != nil {
+			// the Gateway API CRDs do not exist or no permission to access
+			// the resources
+			if err == ErrResourceNotFound || err == ErrNoPermissionToAccessResource {
+				log.Warnf("Skipping Gateways because listing them failed: %v", err)
+				return ings, nil
+			}
+			return nil, err
+		}
+		ings = append(ings, gws...)
+	}
+	return ings, nil
 }
 
 // ListIngress can be used to obtain the list of ingress resources for
@@ -341,18 +1059,32 @@ func (a *Adapter) ListIngress() ([]*Ingress, error) {
 	if err != nil {
 		return nil, err
 	}
-	var ret []*Ingress
+	// ingresses with neither the class annotation nor an ingressClassName
+	// belong to the default IngressClass of the cluster, if any
+	var defaultClass string
 	if len(a.ingressFilters) > 0 {
-		for _, ingress := range il.Items {
-			ingressClass := getAnnotationsString(ingress.Metadata.Annotations, ingressClassAnnotation, "")
-			for _, v := range a.ingressFilters {
-				if v == ingressClass {
-					ret = append(ret, a.newIngressFromKube(ingress))
-				}
-			}
+		defaultClass, err = defaultIngressClass(a.kubeClient)
+		if err != nil && err != ErrResourceNotFound && err != ErrNoPermissionToAccessResource {
+			log.Warnf("Failed to find the default IngressClass: %v", err)
 		}
-	} else {
-		for _, ingress := range il.Items {
+	}
+
+	defaults, err := a.namespaceDefaultAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := a.loadBalancerConfigurationAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Ingress
+	for _, ingress := range il.Items {
+		ingressClass := getAnnotationsString(ingress.Metadata.Annotations, ingressClassAnnotation, ingress.Spec.IngressClassName)
+		if a.supportedIngressClass(ingressClass) ||
+			ingressClass == "" && defaultClass != "" && a.supportedIngressClass(defaultClass) {
+			ingress.Metadata.Annotations = resourceAnnotations(defaults[ingress.Metadata.Namespace], configs, ingress.Metadata.Namespace, ingress.Metadata.Annotations)
 			ret = append(ret, a.newIngressFromKube(ingress))
 		}
 	}
@@ -369,40 +1101,111 @@ func (a *Adapter) ListRoutegroups() ([]*Ingress, error) {
 		return nil, err
 	}
 
+	defaults, err := a.namespaceDefaultAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := a.loadBalancerConfigurationAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
 	var ret []*Ingress
-	if len(a.ingressFilters) > 0 {
-		for _, rg := range rgs.Items {
-			ingressClass := getAnnotationsString(rg.Metadata.Annotations, ingressClassAnnotation, "")
-			for _, v := range a.ingressFilters {
-				if v == ingressClass {
-					ret = append(ret, a.newIngressFromRouteGroup(rg))
-				}
-			}
-		}
-	} else {
-		for _, rg := range rgs.Items {
+	for _, rg := range rgs.Items {
+		ingressClass := getAnnotationsString(rg.Metadata.Annotations, ingressClassAnnotation, "")
+		if a.supportedIngressClass(ingressClass) {
+			rg.Metadata.Annotations = resourceAnnotations(defaults[rg.Metadata.Namespace], configs, rg.Metadata.Namespace, rg.Metadata.Annotations)
 			ret = append(ret, a.newIngressFromRouteGroup(rg))
 		}
 	}
 	return ret, nil
 }
 
+// ListGateways can be used to obtain the list of Gateway resources for all
+// namespaces filtered by their GatewayClass, with the hostnames of the
+// HTTPRoutes attached to them. It returns the Ingress business object.
+func (a *Adapter) ListGateways() ([]*Ingress, error) {
+	gws, err := listGateways(a.kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := listHTTPRoutes(a.kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := a.namespaceDefaultAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := a.loadBalancerConfigurationAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Ingress
+	for _, gw := range gws.Items {
+		if a.supportedIngressClass(gw.Spec.GatewayClassName) {
+			gw.Metadata.Annotations = resourceAnnotations(defaults[gw.Metadata.Namespace], configs, gw.Metadata.Namespace, gw.Metadata.Annotations)
+			ret = append(ret, a.newIngressFromGateway(gw, routes.Items))
+		}
+	}
+	return ret, nil
+}
+
+// supportedIngressClass returns true if the ingress class matches any of
+// the ingress class filters or no filters are set. Filters are glob
+// patterns, e.g. skipper-*, with the syntax of path.Match.
+func (a *Adapter) supportedIngressClass(ingressClass string) bool {
+	if len(a.ingressFilters) == 0 {
+		return true
+	}
+
+	for _, filter := range a.ingressFilters {
+		if matched, _ := path.Match(filter, ingressClass); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // UpdateIngressLoadBalancer can be used to update the loadBalancer object of an ingress resource. It will update
-// the hostname property with the provided load balancer DNS name.
-func (a *Adapter) UpdateIngressLoadBalancer(ingress *Ingress, loadBalancerDNSName string) error {
-	if ingress == nil || loadBalancerDNSName == "" {
+// the hostname property with the provided load balancer DNS name. With multiple DNS names, e.g. of the load
+// balancers in multiple regions, the status lists all of them in order.
+func (a *Adapter) UpdateIngressLoadBalancer(ingress *Ingress, loadBalancerDNSNames ...string) error {
+	if ingress == nil || len(loadBalancerDNSNames) == 0 {
 		return ErrInvalidIngressUpdateParams
 	}
 
-	if loadBalancerDNSName == DefaultClusterLocalDomain {
-		loadBalancerDNSName = ""
+	hostnames := make([]string, 0, len(loadBalancerDNSNames))
+	for _, dnsName := range loadBalancerDNSNames {
+		if dnsName == "" {
+			return ErrInvalidIngressUpdateParams
+		}
+		if dnsName == DefaultClusterLocalDomain {
+			dnsName = ""
+		}
+		hostnames = append(hostnames, dnsName)
 	}
 
 	switch ingress.resourceType {
 	case ingressTypeRouteGroup:
-		return updateRoutegroupLoadBalancer(a.kubeClient, newRouteGroupForKube(ingress), loadBalancerDNSName)
+		return updateRoutegroupLoadBalancer(a.kubeClient, newRouteGroupForKube(ingress), hostnames...)
+	case ingressTypeGateway:
+		return updateGatewayAddresses(a.kubeClient, newGatewayForKube(ingress), hostnames...)
 	case ingressTypeIngress:
-		return a.ingressClient.updateIngressLoadBalancer(a.kubeClient, newIngressForKube(ingress), loadBalancerDNSName)
+		// outdated details are also removed once they aren't published
+		if a.statusPorts || a.statusIPs || ingress.statusOutdated {
+			status := a.loadBalancerStatus(ingress, hostnames)
+			if reflect.DeepEqual(ingress.loadBalancerStatus, status) {
+				return ErrUpdateNotNeeded
+			}
+			return a.ingressClient.applyIngressLoadBalancer(a.kubeClient, newIngressForKube(ingress), status)
+		}
+		return a.ingressClient.updateIngressLoadBalancer(a.kubeClient, newIngressForKube(ingress), hostnames...)
 	}
 	return fmt.Errorf("Unknown resourceType '%s', failed to update Kubernetes resource", ingress.resourceType)
 }
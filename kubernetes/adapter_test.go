@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
 )
 
@@ -36,20 +40,21 @@ func TestMappingRoundtrip(tt *testing.T) {
 		{
 			msg: "test parsing a simple ingress object",
 			ingress: &Ingress{
-				Namespace:        "default",
-				Name:             "foo",
-				Hostname:         "bar",
-				Scheme:           "internal",
-				CertificateARN:   "zbr",
-				Shared:           true,
-				HTTP2:            true,
-				Hostnames:        []string{"domain.example.org"},
-				SecurityGroup:    testSecurityGroup,
-				SSLPolicy:        testSSLPolicy,
-				IPAddressType:    testIPAddressTypeDefault,
-				LoadBalancerType: testLoadBalancerTypeAWS,
-				resourceType:     ingressTypeIngress,
-				WAFWebACLID:      testWAFWebACLID,
+				Namespace:             "default",
+				Name:                  "foo",
+				Hostname:              "bar",
+				LoadBalancerHostnames: []string{"bar"},
+				Scheme:                "internal",
+				CertificateARN:        "zbr",
+				Shared:                true,
+				HTTP2:                 true,
+				Hostnames:             []string{"domain.example.org"},
+				SecurityGroup:         testSecurityGroup,
+				SSLPolicy:             testSSLPolicy,
+				IPAddressType:         testIPAddressTypeDefault,
+				LoadBalancerType:      testLoadBalancerTypeAWS,
+				resourceType:          ingressTypeIngress,
+				WAFWebACLID:           testWAFWebACLID,
 			},
 			kubeIngress: &ingress{
 				Metadata: kubeItemMetadata{
@@ -87,20 +92,21 @@ func TestMappingRoundtrip(tt *testing.T) {
 		{
 			msg: "test parsing an ingress object with cluster.local domain",
 			ingress: &Ingress{
-				Namespace:        "default",
-				Name:             "foo",
-				Hostname:         "bar",
-				Scheme:           "internal",
-				CertificateARN:   "zbr",
-				Shared:           true,
-				HTTP2:            true,
-				ClusterLocal:     true,
-				SecurityGroup:    testSecurityGroup,
-				SSLPolicy:        testSSLPolicy,
-				IPAddressType:    testIPAddressTypeDefault,
-				LoadBalancerType: testLoadBalancerTypeAWS,
-				resourceType:     ingressTypeIngress,
-				WAFWebACLID:      testWAFWebACLID,
+				Namespace:             "default",
+				Name:                  "foo",
+				Hostname:              "bar",
+				LoadBalancerHostnames: []string{"bar"},
+				Scheme:                "internal",
+				CertificateARN:        "zbr",
+				Shared:                true,
+				HTTP2:                 true,
+				ClusterLocal:          true,
+				SecurityGroup:         testSecurityGroup,
+				SSLPolicy:             testSSLPolicy,
+				IPAddressType:         testIPAddressTypeDefault,
+				LoadBalancerType:      testLoadBalancerTypeAWS,
+				resourceType:          ingressTypeIngress,
+				WAFWebACLID:           testWAFWebACLID,
 			},
 			kubeIngress: &ingress{
 				Metadata: kubeItemMetadata{
@@ -138,20 +144,21 @@ func TestMappingRoundtrip(tt *testing.T) {
 		{
 			msg: "test parsing an ingress object with shared=false,h2-enabled=false annotations",
 			ingress: &Ingress{
-				Namespace:        "default",
-				Name:             "foo",
-				Hostname:         "bar",
-				Scheme:           "internal",
-				CertificateARN:   "zbr",
-				Shared:           false,
-				HTTP2:            false,
-				ClusterLocal:     true,
-				SecurityGroup:    testSecurityGroup,
-				SSLPolicy:        testSSLPolicy,
-				IPAddressType:    testIPAddressTypeDefault,
-				LoadBalancerType: testLoadBalancerTypeAWS,
-				resourceType:     ingressTypeIngress,
-				WAFWebACLID:      testWAFWebACLID,
+				Namespace:             "default",
+				Name:                  "foo",
+				Hostname:              "bar",
+				LoadBalancerHostnames: []string{"bar"},
+				Scheme:                "internal",
+				CertificateARN:        "zbr",
+				Shared:                false,
+				HTTP2:                 false,
+				ClusterLocal:          true,
+				SecurityGroup:         testSecurityGroup,
+				SSLPolicy:             testSSLPolicy,
+				IPAddressType:         testIPAddressTypeDefault,
+				LoadBalancerType:      testLoadBalancerTypeAWS,
+				resourceType:          ingressTypeIngress,
+				WAFWebACLID:           testWAFWebACLID,
 			},
 			kubeIngress: &ingress{
 				Metadata: kubeItemMetadata{
@@ -182,20 +189,21 @@ func TestMappingRoundtrip(tt *testing.T) {
 		{
 			msg: "test parsing an ingress object with dualstack annotation",
 			ingress: &Ingress{
-				Namespace:        "default",
-				Name:             "foo",
-				Hostname:         "bar",
-				Scheme:           "internal",
-				CertificateARN:   "zbr",
-				Shared:           true,
-				HTTP2:            true,
-				ClusterLocal:     true,
-				SecurityGroup:    testSecurityGroup,
-				SSLPolicy:        testSSLPolicy,
-				IPAddressType:    testIPAddressTypeDualStack,
-				LoadBalancerType: testLoadBalancerTypeAWS,
-				resourceType:     ingressTypeIngress,
-				WAFWebACLID:      testWAFWebACLID,
+				Namespace:             "default",
+				Name:                  "foo",
+				Hostname:              "bar",
+				LoadBalancerHostnames: []string{"bar"},
+				Scheme:                "internal",
+				CertificateARN:        "zbr",
+				Shared:                true,
+				HTTP2:                 true,
+				ClusterLocal:          true,
+				SecurityGroup:         testSecurityGroup,
+				SSLPolicy:             testSSLPolicy,
+				IPAddressType:         testIPAddressTypeDualStack,
+				LoadBalancerType:      testLoadBalancerTypeAWS,
+				resourceType:          ingressTypeIngress,
+				WAFWebACLID:           testWAFWebACLID,
 			},
 			kubeIngress: &ingress{
 				Metadata: kubeItemMetadata{
@@ -230,6 +238,7 @@ func TestMappingRoundtrip(tt *testing.T) {
 				t.Fatalf("cannot create kubernetes adapter: %v", err)
 			}
 
+			tc.ingress.loadBalancerStatus = tc.kubeIngress.Status.LoadBalancer.Ingress
 			got := a.newIngressFromKube(tc.kubeIngress)
 			assert.Equal(t, tc.ingress, got, "mapping from kubernetes ingress to adapter failed")
 			assert.Equal(t, got.String(), fmt.Sprintf("%s/%s", tc.ingress.Namespace, tc.ingress.Name), "wrong value from String()")
@@ -242,6 +251,625 @@ func TestMappingRoundtrip(tt *testing.T) {
 	}
 }
 
+func TestParseAnnotations(t *testing.T) {
+	defaultIngress := func(f func(i *Ingress)) *Ingress {
+		i := &Ingress{
+			Scheme:           "internet-facing",
+			Shared:           true,
+			HTTP2:            true,
+			SecurityGroup:    testIngressDefaultSecurityGroup,
+			SSLPolicy:        testSSLPolicy,
+			IPAddressType:    testIPAddressTypeDefault,
+			LoadBalancerType: testLoadBalancerTypeAWS,
+		}
+		if f != nil {
+			f(i)
+		}
+		return i
+	}
+
+	for _, tc := range []struct {
+		msg         string
+		annotations map[string]string
+		expected    *Ingress
+		warned      bool
+	}{
+		{
+			msg:      "defaults",
+			expected: defaultIngress(nil),
+		},
+		{
+			msg:         "slow start duration",
+			annotations: map[string]string{ingressSlowStartAnnotation: "1m30s"},
+			expected:    defaultIngress(func(i *Ingress) { i.SlowStart = 90 * time.Second }),
+		},
+		{
+			msg:         "slow start duration out of range",
+			annotations: map[string]string{ingressSlowStartAnnotation: "10s"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "invalid slow start duration",
+			annotations: map[string]string{ingressSlowStartAnnotation: "foo"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "slow start duration is ignored for NLBs",
+			annotations: map[string]string{
+				ingressSlowStartAnnotation:        "1m",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg:         "health check matcher",
+			annotations: map[string]string{ingressHealthCheckMatcherAnnotation: "200-399"},
+			expected:    defaultIngress(func(i *Ingress) { i.HealthCheckMatcher = "200-399" }),
+		},
+		{
+			msg:         "invalid health check matcher",
+			annotations: map[string]string{ingressHealthCheckMatcherAnnotation: "200-600"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "health check matcher is ignored for NLBs",
+			annotations: map[string]string{
+				ingressHealthCheckMatcherAnnotation: "200-399",
+				ingressLoadBalancerTypeAnnotation:   loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg: "health check threshold counts",
+			annotations: map[string]string{
+				ingressHealthyThresholdAnnotation:   "3",
+				ingressUnhealthyThresholdAnnotation: "4",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.HealthyThresholdCount = 3
+				i.UnhealthyThresholdCount = 4
+			}),
+		},
+		{
+			msg: "health check threshold counts out of range",
+			annotations: map[string]string{
+				ingressHealthyThresholdAnnotation:   "1",
+				ingressUnhealthyThresholdAnnotation: "11",
+			},
+			expected: defaultIngress(nil),
+		},
+		{
+			msg: "NLBs use different threshold counts",
+			annotations: map[string]string{
+				ingressHealthyThresholdAnnotation:   "3",
+				ingressUnhealthyThresholdAnnotation: "4",
+				ingressLoadBalancerTypeAnnotation:   loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.HealthyThresholdCount = 3
+				i.UnhealthyThresholdCount = 4
+			}),
+		},
+		{
+			msg:         "listener rules",
+			annotations: map[string]string{ingressListenerRulesAnnotation: `[{"pathPatterns": ["/admin/*"], "action": "deny"}]`},
+			expected: defaultIngress(func(i *Ingress) {
+				i.ListenerRules = aws.ListenerRuleList{{PathPatterns: []string{"/admin/*"}, Action: aws.ListenerRuleActionDeny}}
+			}),
+		},
+		{
+			msg:         "invalid listener rules",
+			warned:      true,
+			annotations: map[string]string{ingressListenerRulesAnnotation: `[{"action": "deny"}]`},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "authentication",
+			annotations: map[string]string{ingressAuthenticationAnnotation: `{"type": "cognito", "userPoolARN": "arn:aws:cognito-idp:eu-central-1:123456789012:userpool/eu-central-1_abc", "userPoolClientID": "client", "userPoolDomain": "internal"}`},
+			expected: defaultIngress(func(i *Ingress) {
+				i.Authentication = &aws.ListenerAuthentication{
+					Type:             aws.ListenerAuthenticationCognito,
+					UserPoolARN:      "arn:aws:cognito-idp:eu-central-1:123456789012:userpool/eu-central-1_abc",
+					UserPoolClientID: "client",
+					UserPoolDomain:   "internal",
+				}
+			}),
+		},
+		{
+			msg:         "invalid authentication",
+			warned:      true,
+			annotations: map[string]string{ingressAuthenticationAnnotation: `{"type": "saml"}`},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "client keep alive",
+			annotations: map[string]string{ingressClientKeepAliveAnnotation: "2h"},
+			expected:    defaultIngress(func(i *Ingress) { i.ClientKeepAlive = 2 * time.Hour }),
+		},
+		{
+			msg:         "client keep alive out of range",
+			annotations: map[string]string{ingressClientKeepAliveAnnotation: "30s"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "preserve client IP",
+			annotations: map[string]string{
+				ingressPreserveClientIPAnnotation: "false",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.PreserveClientIP = "false"
+			}),
+		},
+		{
+			msg:         "preserve client IP is ignored for ALBs",
+			annotations: map[string]string{ingressPreserveClientIPAnnotation: "false"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "Elastic IPs",
+			annotations: map[string]string{
+				ingressElasticIPsAnnotation:       "eipalloc-2,eipalloc-1",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.ElasticIPs = []string{"eipalloc-1", "eipalloc-2"}
+			}),
+		},
+		{
+			msg:    "Elastic IPs are ignored for internal NLBs",
+			warned: true,
+			annotations: map[string]string{
+				ingressElasticIPsAnnotation:       aws.AutoAllocateElasticIPs,
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+				ingressSchemeAnnotation:           "internal",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.Scheme = "internal"
+			}),
+		},
+		{
+			msg:         "Elastic IPs are ignored for ALBs",
+			warned:      true,
+			annotations: map[string]string{ingressElasticIPsAnnotation: aws.AutoAllocateElasticIPs},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "IPAM pool",
+			annotations: map[string]string{ingressIPv4PoolAnnotation: "ipam-pool-0123456789abcdef0"},
+			expected: defaultIngress(func(i *Ingress) {
+				i.IPv4Pool = "ipam-pool-0123456789abcdef0"
+			}),
+		},
+		{
+			msg:    "IPAM pool is ignored for internal ALBs",
+			warned: true,
+			annotations: map[string]string{
+				ingressIPv4PoolAnnotation: "ipam-pool-0123456789abcdef0",
+				ingressSchemeAnnotation:   "internal",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.Scheme = "internal"
+			}),
+		},
+		{
+			msg: "public IPv4 pool of auto-allocated Elastic IPs",
+			annotations: map[string]string{
+				ingressIPv4PoolAnnotation:         "ipv4pool-ec2-0123456789abcdef0",
+				ingressElasticIPsAnnotation:       aws.AutoAllocateElasticIPs,
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.ElasticIPs = []string{aws.AutoAllocateElasticIPs}
+				i.IPv4Pool = "ipv4pool-ec2-0123456789abcdef0"
+			}),
+		},
+		{
+			msg:    "public IPv4 pool is ignored without auto-allocated Elastic IPs",
+			warned: true,
+			annotations: map[string]string{
+				ingressIPv4PoolAnnotation:         "ipv4pool-ec2-0123456789abcdef0",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+			}),
+		},
+		{
+			msg:         "invalid IPv4 pool is ignored",
+			warned:      true,
+			annotations: map[string]string{ingressIPv4PoolAnnotation: "pool-1"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "endpoint service principals",
+			annotations: map[string]string{
+				ingressEndpointServicePrincipalsAnnotation: "arn:aws:iam::123456789012:root",
+				ingressLoadBalancerTypeAnnotation:          loadBalancerTypeNLB,
+				ingressSchemeAnnotation:                    "internal",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.Scheme = "internal"
+				i.EndpointServicePrincipals = []string{"arn:aws:iam::123456789012:root"}
+			}),
+		},
+		{
+			msg:    "endpoint service principals are ignored for internet-facing NLBs",
+			warned: true,
+			annotations: map[string]string{
+				ingressEndpointServicePrincipalsAnnotation: "arn:aws:iam::123456789012:root",
+				ingressLoadBalancerTypeAnnotation:          loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg: "PROXY protocol v2",
+			annotations: map[string]string{
+				ingressProxyProtocolV2Annotation:  "true",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.ProxyProtocolV2 = true
+			}),
+		},
+		{
+			msg:         "PROXY protocol v2 is ignored for ALBs",
+			annotations: map[string]string{ingressProxyProtocolV2Annotation: "true"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "client routing policy",
+			annotations: map[string]string{
+				ingressClientRoutingPolicyAnnotation: aws.ClientRoutingPolicyAZAffinity,
+				ingressLoadBalancerTypeAnnotation:    loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.ClientRoutingPolicy = aws.ClientRoutingPolicyAZAffinity
+			}),
+		},
+		{
+			msg:    "invalid client routing policy",
+			warned: true,
+			annotations: map[string]string{
+				ingressClientRoutingPolicyAnnotation: "az-affinity",
+				ingressLoadBalancerTypeAnnotation:    loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg:         "capacity units",
+			annotations: map[string]string{ingressCapacityUnitsAnnotation: "500"},
+			expected:    defaultIngress(func(i *Ingress) { i.CapacityUnits = 500 }),
+		},
+		{
+			msg:         "capacity units below the minimum",
+			warned:      true,
+			annotations: map[string]string{ingressCapacityUnitsAnnotation: "50"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "default certificate",
+			annotations: map[string]string{ingressDefaultCertificateARNAnnotation: "arn:cert"},
+			expected:    defaultIngress(func(i *Ingress) { i.DefaultCertificateARN = "arn:cert" }),
+		},
+		{
+			msg: "capacity units are ignored for NLBs",
+			annotations: map[string]string{
+				ingressCapacityUnitsAnnotation:    "500",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg:         "client routing policy is ignored for ALBs",
+			annotations: map[string]string{ingressClientRoutingPolicyAnnotation: aws.ClientRoutingPolicyAZAffinity},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "preserve host header",
+			annotations: map[string]string{ingressPreserveHostHeaderAnnotation: "true"},
+			expected:    defaultIngress(func(i *Ingress) { i.PreserveHostHeader = true }),
+		},
+		{
+			msg: "preserve host header is ignored for NLBs",
+			annotations: map[string]string{
+				ingressPreserveHostHeaderAnnotation: "true",
+				ingressLoadBalancerTypeAnnotation:   loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg:         "HTTP disabled",
+			annotations: map[string]string{ingressHTTPDisabledAnnotation: "true"},
+			expected:    defaultIngress(func(i *Ingress) { i.HTTPDisabled = true }),
+		},
+		{
+			msg:         "dualstack without public IPv4",
+			annotations: map[string]string{ingressALBIPAddressType: aws.IPAddressTypeDualstackWithoutPublicIPV4},
+			expected:    defaultIngress(func(i *Ingress) { i.IPAddressType = aws.IPAddressTypeDualstackWithoutPublicIPV4 }),
+		},
+		{
+			msg:    "dualstack without public IPv4 is dualstack for internal load balancers",
+			warned: true,
+			annotations: map[string]string{
+				ingressALBIPAddressType: aws.IPAddressTypeDualstackWithoutPublicIPV4,
+				ingressSchemeAnnotation: "internal",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.IPAddressType = aws.IPAddressTypeDualstack
+				i.Scheme = "internal"
+			}),
+		},
+		{
+			msg: "dualstack NLB",
+			annotations: map[string]string{
+				ingressALBIPAddressType:           aws.IPAddressTypeDualstack,
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.IPAddressType = aws.IPAddressTypeDualstack
+			}),
+		},
+		{
+			msg:    "dualstack without public IPv4 is dualstack for NLBs",
+			warned: true,
+			annotations: map[string]string{
+				ingressALBIPAddressType:           aws.IPAddressTypeDualstackWithoutPublicIPV4,
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.IPAddressType = aws.IPAddressTypeDualstack
+			}),
+		},
+		{
+			msg: "NLB with security group",
+			annotations: map[string]string{
+				ingressSecurityGroupAnnotation:    "sg-123456",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerType = aws.LoadBalancerTypeNetwork
+				i.SecurityGroup = "sg-123456"
+				i.NLBSecurityGroup = true
+			}),
+		},
+		{
+			msg: "fronting NLB",
+			annotations: map[string]string{
+				ingressFrontingNLBAnnotation: "true",
+				ingressALBIPAddressType:      aws.IPAddressTypeDualstack,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.FrontingNLB = true }),
+		},
+		{
+			msg:         "Lambda target",
+			annotations: map[string]string{ingressLambdaTargetAnnotation: "arn:aws:lambda:eu-central-1:123456789012:function:maintenance"},
+			expected:    defaultIngress(func(i *Ingress) { i.LambdaTarget = "arn:aws:lambda:eu-central-1:123456789012:function:maintenance" }),
+		},
+		{
+			msg:         "invalid Lambda target is ignored",
+			warned:      true,
+			annotations: map[string]string{ingressLambdaTargetAnnotation: "maintenance"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "assumed role",
+			annotations: map[string]string{ingressAssumeRoleARNAnnotation: "arn:aws:iam::123456789012:role/load-balancers"},
+			expected:    defaultIngress(func(i *Ingress) { i.AssumeRoleARN = "arn:aws:iam::123456789012:role/load-balancers" }),
+		},
+		{
+			msg:         "invalid role ARN is ignored",
+			warned:      true,
+			annotations: map[string]string{ingressAssumeRoleARNAnnotation: "load-balancers"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "Lambda target is ignored for NLBs",
+			annotations: map[string]string{
+				ingressLambdaTargetAnnotation:     "arn:aws:lambda:eu-central-1:123456789012:function:maintenance",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg: "external target groups",
+			annotations: map[string]string{
+				ingressExternalTargetGroupARNsAnnotation: "arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/b/1, arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/a/2",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.ExternalTargetGroupARNs = []string{
+					"arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/a/2",
+					"arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/b/1",
+				}
+			}),
+		},
+		{
+			msg:         "invalid external target groups are ignored",
+			warned:      true,
+			annotations: map[string]string{ingressExternalTargetGroupARNsAnnotation: "tg-1"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:    "external target groups are ignored with a Lambda target",
+			warned: true,
+			annotations: map[string]string{
+				ingressExternalTargetGroupARNsAnnotation: "arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/a/2",
+				ingressLambdaTargetAnnotation:            "arn:aws:lambda:eu-central-1:123456789012:function:maintenance",
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LambdaTarget = "arn:aws:lambda:eu-central-1:123456789012:function:maintenance" }),
+		},
+		{
+			msg:         "WAF rate limit",
+			annotations: map[string]string{ingressWAFRateLimitAnnotation: "2000"},
+			expected: defaultIngress(func(i *Ingress) {
+				i.WAFRateLimit = 2000
+				i.WAFRateLimitKey = aws.WAFRateLimitKeyIP
+			}),
+		},
+		{
+			msg: "WAF rate limit by forwarded IP",
+			annotations: map[string]string{
+				ingressWAFRateLimitAnnotation:    "2000",
+				ingressWAFRateLimitKeyAnnotation: "forwarded-ip",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.WAFRateLimit = 2000
+				i.WAFRateLimitKey = aws.WAFRateLimitKeyForwardedIP
+			}),
+		},
+		{
+			msg:         "WAF managed rule groups",
+			annotations: map[string]string{ingressWAFManagedRuleGroupsAnnotation: "AWSManagedRulesCommonRuleSet,AWSManagedRulesKnownBadInputsRuleSet"},
+			expected: defaultIngress(func(i *Ingress) {
+				i.WAFManagedRuleGroups = []string{"AWSManagedRulesCommonRuleSet", "AWSManagedRulesKnownBadInputsRuleSet"}
+			}),
+		},
+		{
+			msg:         "invalid WAF managed rule groups are ignored",
+			warned:      true,
+			annotations: map[string]string{ingressWAFManagedRuleGroupsAnnotation: "core rule set"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:    "WAF managed rule groups are ignored with a WebACL",
+			warned: true,
+			annotations: map[string]string{
+				ingressWAFManagedRuleGroupsAnnotation: "AWSManagedRulesCommonRuleSet",
+				ingressWAFWebACLIDAnnotation:          "foo-bar-baz",
+			},
+			expected: defaultIngress(func(i *Ingress) { i.WAFWebACLID = "foo-bar-baz" }),
+		},
+		{
+			msg:         "WAF rate limit out of range is ignored",
+			warned:      true,
+			annotations: map[string]string{ingressWAFRateLimitAnnotation: "10"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:    "WAF rate limit is ignored with a WebACL",
+			warned: true,
+			annotations: map[string]string{
+				ingressWAFRateLimitAnnotation: "2000",
+				ingressWAFWebACLIDAnnotation:  "foo-bar-baz",
+			},
+			expected: defaultIngress(func(i *Ingress) { i.WAFWebACLID = "foo-bar-baz" }),
+		},
+		{
+			msg: "WAF rate limit is ignored for NLBs",
+			annotations: map[string]string{
+				ingressWAFRateLimitAnnotation:     "2000",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg: "attributes",
+			annotations: map[string]string{
+				ingressAttributesAnnotation:            "waf.fail_open.enabled=true",
+				ingressTargetGroupAttributesAnnotation: "load_balancing.algorithm.type=least_outstanding_requests",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.LoadBalancerAttributes = aws.Attributes{"waf.fail_open.enabled": "true"}
+				i.TargetGroupAttributes = aws.Attributes{"load_balancing.algorithm.type": "least_outstanding_requests"}
+			}),
+		},
+		{
+			msg:         "invalid attributes are ignored",
+			warned:      true,
+			annotations: map[string]string{ingressAttributesAnnotation: "waf.fail_open.enabled"},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg:         "paused",
+			annotations: map[string]string{ingressPausedAnnotation: "true"},
+			expected:    defaultIngress(func(i *Ingress) { i.Paused = true }),
+		},
+		{
+			msg:         "resource tags",
+			annotations: map[string]string{ingressResourceTagsAnnotation: `{"team":"foo","cost-center":"1234"}`},
+			expected:    defaultIngress(func(i *Ingress) { i.ResourceTags = aws.ResourceTags{"team": "foo", "cost-center": "1234"} }),
+		},
+		{
+			msg:         "invalid resource tags are ignored",
+			warned:      true,
+			annotations: map[string]string{ingressResourceTagsAnnotation: `{"aws:team":"foo"}`},
+			expected:    defaultIngress(nil),
+		},
+		{
+			msg: "fronting NLB is ignored for NLBs",
+			annotations: map[string]string{
+				ingressFrontingNLBAnnotation:      "true",
+				ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			},
+			expected: defaultIngress(func(i *Ingress) { i.LoadBalancerType = aws.LoadBalancerTypeNetwork }),
+		},
+		{
+			msg:         "deny internal domains override",
+			annotations: map[string]string{ingressDenyInternalDomainsAnnotation: "false"},
+			expected:    defaultIngress(func(i *Ingress) { i.DenyInternalDomains = "false" }),
+		},
+		{
+			msg: "deny internal domains response",
+			annotations: map[string]string{
+				ingressDenyInternalDomainsResponseAnnotation:            "<h1>Forbidden</h1>",
+				ingressDenyInternalDomainsResponseContentTypeAnnotation: "text/html",
+				ingressDenyInternalDomainsResponseStatusCodeAnnotation:  "403",
+			},
+			expected: defaultIngress(func(i *Ingress) {
+				i.DenyInternalDomainsResponse = "<h1>Forbidden</h1>"
+				i.DenyInternalDomainsResponseContentType = "text/html"
+				i.DenyInternalDomainsResponseStatusCode = 403
+			}),
+		},
+		{
+			msg: "invalid deny internal domains response is ignored",
+			annotations: map[string]string{
+				ingressDenyInternalDomainsResponseContentTypeAnnotation: "image/png",
+				ingressDenyInternalDomainsResponseStatusCodeAnnotation:  "302",
+			},
+			expected: defaultIngress(func(i *Ingress) {}),
+		},
+		{
+			msg:         "invalid deny internal domains override is ignored",
+			annotations: map[string]string{ingressDenyInternalDomainsAnnotation: "no"},
+			expected:    defaultIngress(func(i *Ingress) {}),
+		},
+	} {
+		t.Run(tc.msg, func(t *testing.T) {
+			a, err := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testIngressDefaultSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+			require.NoError(t, err)
+			got := a.parseAnnotations(tc.annotations)
+			assert.Equal(t, tc.warned, len(got.AnnotationWarnings) > 0, "unexpected warnings %v", got.AnnotationWarnings)
+			got.AnnotationWarnings = nil
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestParseAnnotationsDefaultIPv4Pool(t *testing.T) {
+	a, err := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testIngressDefaultSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	require.NoError(t, err)
+	a = a.WithDefaultIPv4Pool("ipam-pool-0123456789abcdef0")
+
+	assert.Equal(t, "ipam-pool-0123456789abcdef0", a.parseAnnotations(nil).IPv4Pool)
+	assert.Equal(t, "ipam-pool-fedcba9876543210f", a.parseAnnotations(map[string]string{
+		ingressIPv4PoolAnnotation: "ipam-pool-fedcba9876543210f",
+	}).IPv4Pool)
+	assert.Empty(t, a.parseAnnotations(map[string]string{
+		ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+	}).IPv4Pool)
+}
+
 func TestInsecureConfig(t *testing.T) {
 	cfg := InsecureConfig("http://domain.com:12345")
 	if cfg.BaseURL != "http://domain.com:12345" {
@@ -259,7 +887,9 @@ func TestInsecureConfig(t *testing.T) {
 }
 
 type mockClient struct {
-	broken bool
+	broken         bool
+	posted         []string
+	ingressFixture string
 }
 
 func (c *mockClient) get(res string) (io.ReadCloser, error) {
@@ -271,8 +901,23 @@ func (c *mockClient) get(res string) (io.ReadCloser, error) {
 	switch res {
 	case routegroupListResource:
 		fixture = "testdata/fixture01_rg.json"
+	case gatewayListResource:
+		fixture = "testdata/fixture01_gateway.json"
+	case httpRouteListResource:
+		fixture = "testdata/fixture01_httproute.json"
+	case fmt.Sprintf(ingressClassListResource, IngressAPIVersionNetworking):
+		fixture = "testdata/fixture01_ingressclass.json"
+	case namespaceListResource:
+		fixture = "testdata/fixture01_namespaces.json"
+	case loadBalancerConfigurationListResource:
+		fixture = "testdata/fixture01_loadbalancerconfigurations.json"
+	case apiGroupListResource:
+		fixture = "testdata/fixture01_apis.json"
 	case fmt.Sprintf(ingressListResource, IngressAPIVersionNetworking):
 		fixture = "testdata/fixture01.json"
+		if c.ingressFixture != "" {
+			fixture = c.ingressFixture
+		}
 	case fmt.Sprintf(configMapResource, "foo-ns", "foo-name"):
 		fixture = "testdata/fixture02.json"
 	default:
@@ -293,11 +938,25 @@ func (c *mockClient) patch(res string, payload []byte) (io.ReadCloser, error) {
 			return ioutil.NopCloser(strings.NewReader(":)")), nil
 		case "/apis/zalando.org/v1/namespaces/default/routegroups/foo/status":
 			return ioutil.NopCloser(strings.NewReader(":)")), nil
+		case "/apis/gateway.networking.k8s.io/v1/namespaces/default/gateways/foo/status":
+			return ioutil.NopCloser(strings.NewReader(":)")), nil
 		}
 	}
 	return nil, errors.New("mocked error")
 }
 
+func (c *mockClient) apply(res string, payload []byte) (io.ReadCloser, error) {
+	return c.patch(res, payload)
+}
+
+func (c *mockClient) post(res string, payload []byte) (io.ReadCloser, error) {
+	if c.broken {
+		return nil, errors.New("mocked error")
+	}
+	c.posted = append(c.posted, res+" "+string(payload))
+	return ioutil.NopCloser(strings.NewReader(":)")), nil
+}
+
 func TestListIngress(t *testing.T) {
 	a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testIngressDefaultSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
 	client := &mockClient{}
@@ -316,6 +975,62 @@ func TestListIngress(t *testing.T) {
 	}
 }
 
+func TestListIngressAutoDetectAPIVersion(t *testing.T) {
+	a, _ := NewAdapter(testConfig, IngressAPIVersionAuto, testIngressFilter, testIngressDefaultSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	client := &mockClient{}
+	a.kubeClient = client
+	ingresses, err := a.ListIngress()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(ingresses) != 1 {
+		t.Fatal("unexpected count of ingress resources")
+	}
+	if a.ingressClient.apiVersion != IngressAPIVersionNetworking {
+		t.Errorf("unexpected API version. wanted %q, got %q", IngressAPIVersionNetworking, a.ingressClient.apiVersion)
+	}
+
+	// a version no longer served is detected again
+	a.ingressClient.apiVersion = IngressAPIVersionExtensions
+	if _, err := a.ListIngress(); err != nil {
+		t.Error(err)
+	}
+	if a.ingressClient.apiVersion != IngressAPIVersionNetworking {
+		t.Errorf("unexpected API version. wanted %q, got %q", IngressAPIVersionNetworking, a.ingressClient.apiVersion)
+	}
+}
+
+func TestListIngressNamespaceDefaults(t *testing.T) {
+	for _, test := range []struct {
+		msg            string
+		enabled        bool
+		expectedScheme string
+	}{
+		{
+			msg:            "disabled",
+			expectedScheme: "internet-facing",
+		},
+		{
+			msg:            "enabled",
+			enabled:        true,
+			expectedScheme: "internal",
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			a, err := NewAdapter(testConfig, IngressAPIVersionNetworking, []string{"skipper"}, testIngressDefaultSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+			require.NoError(t, err)
+			a = a.WithNamespaceDefaults(test.enabled)
+			a.kubeClient = &mockClient{}
+
+			ingresses, err := a.ListIngress()
+			require.NoError(t, err)
+			// the ingress class can't be set on the namespace
+			require.Len(t, ingresses, 1)
+			assert.Equal(t, test.expectedScheme, ingresses[0].Scheme)
+		})
+	}
+}
+
 func TestUpdateIngressLoadBalancer(t *testing.T) {
 	a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
 	client := &mockClient{}
@@ -330,6 +1045,9 @@ func TestUpdateIngressLoadBalancer(t *testing.T) {
 	if err := a.UpdateIngressLoadBalancer(ing, "xpto"); err != nil {
 		t.Error(err)
 	}
+	if err := a.UpdateIngressLoadBalancer(ing, "xpto", "xpto-eu-west-1"); err != nil {
+		t.Error(err)
+	}
 	client.broken = true
 	if err := a.UpdateIngressLoadBalancer(ing, "xpto"); err == nil {
 		t.Error("expected an error")
@@ -337,11 +1055,72 @@ func TestUpdateIngressLoadBalancer(t *testing.T) {
 	if err := a.UpdateIngressLoadBalancer(ing, ""); err == nil {
 		t.Error("expected an error")
 	}
+	if err := a.UpdateIngressLoadBalancer(ing); err == nil {
+		t.Error("expected an error")
+	}
 	if err := a.UpdateIngressLoadBalancer(nil, "xpto"); err == nil {
 		t.Error("expected an error")
 	}
 }
 
+func TestUpdateIngressLoadBalancerStatusDetails(t *testing.T) {
+	var applied []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "PATCH", req.Method)
+		require.Equal(t, fmt.Sprintf(ingressPatchStatusResource, IngressAPIVersionNetworking, "default", "foo"), req.URL.Path)
+		b, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		applied = append(applied, string(b))
+	}))
+	defer testServer.Close()
+
+	a, err := NewAdapter(InsecureConfig(testServer.URL), IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+	a = a.WithStatusPorts(true).WithStatusIPs(true)
+	a.lookupHost = func(host string) ([]string, error) {
+		if host == "nlb.example.org" {
+			return []string{"10.0.0.2", "10.0.0.1"}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+
+	kubeIngress := &ingress{
+		Metadata: kubeItemMetadata{Namespace: "default", Name: "foo", Annotations: map[string]string{
+			ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+			ingressHTTPDisabledAnnotation:     "true",
+		}},
+		Spec: ingressSpec{Rules: []ingressItemRule{{Host: "foo.example.org"}}},
+		Status: ingressStatus{LoadBalancer: ingressLoadBalancerStatus{
+			Ingress: []ingressLoadBalancer{{Hostname: "nlb.example.org"}},
+		}},
+	}
+	ing := a.newIngressFromKube(kubeIngress)
+	require.True(t, ing.StatusOutdated())
+
+	require.NoError(t, a.UpdateIngressLoadBalancer(ing, "nlb.example.org"))
+	// the hostname doesn't resolve yet
+	require.NoError(t, a.UpdateIngressLoadBalancer(ing, "new-nlb.example.org"))
+	require.Equal(t, []string{
+		`{"apiVersion":"networking.k8s.io/v1beta1","kind":"Ingress","metadata":{"namespace":"default","name":"foo"},"status":{"loadBalancer":{"ingress":[{"hostname":"nlb.example.org","ports":[{"port":443,"protocol":"TCP"}]},{"ip":"10.0.0.1","ports":[{"port":443,"protocol":"TCP"}]},{"ip":"10.0.0.2","ports":[{"port":443,"protocol":"TCP"}]}]}}}`,
+		`{"apiVersion":"networking.k8s.io/v1beta1","kind":"Ingress","metadata":{"namespace":"default","name":"foo"},"status":{"loadBalancer":{"ingress":[{"hostname":"new-nlb.example.org","ports":[{"port":443,"protocol":"TCP"}]}]}}}`,
+	}, applied)
+
+	kubeIngress.Status.LoadBalancer.Ingress = a.loadBalancerStatus(ing, []string{"nlb.example.org"})
+	ing = a.newIngressFromKube(kubeIngress)
+	require.False(t, ing.StatusOutdated())
+	require.Equal(t, ErrUpdateNotNeeded, a.UpdateIngressLoadBalancer(ing, "nlb.example.org"))
+
+	// the published details are removed once disabled
+	a = a.WithStatusPorts(false).WithStatusIPs(false)
+	ing = a.newIngressFromKube(kubeIngress)
+	require.True(t, ing.StatusOutdated())
+	applied = nil
+	require.NoError(t, a.UpdateIngressLoadBalancer(ing, "nlb.example.org"))
+	require.Equal(t, []string{
+		`{"apiVersion":"networking.k8s.io/v1beta1","kind":"Ingress","metadata":{"namespace":"default","name":"foo"},"status":{"loadBalancer":{"ingress":[{"hostname":"nlb.example.org"}]}}}`,
+	}, applied)
+}
+
 func TestUpdateRouteGroupLoadBalancer(t *testing.T) {
 	a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
 	client := &mockClient{}
@@ -368,6 +1147,27 @@ func TestUpdateRouteGroupLoadBalancer(t *testing.T) {
 	}
 }
 
+func TestStatusUpToDate(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		current   []string
+		hostnames []string
+		want      bool
+	}{
+		{"single hostname", []string{"a"}, []string{"a"}, true},
+		{"single hostname in the status", []string{"a", "b"}, []string{"b"}, true},
+		{"other hostname", []string{"a"}, []string{"b"}, false},
+		{"empty status", nil, []string{"a"}, false},
+		{"multiple hostnames", []string{"a", "b"}, []string{"a", "b"}, true},
+		{"additional hostname", []string{"a"}, []string{"a", "b"}, false},
+		{"other order", []string{"b", "a"}, []string{"a", "b"}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, statusUpToDate(test.current, test.hostnames))
+		})
+	}
+}
+
 func TestBrokenConfig(t *testing.T) {
 	for _, test := range []struct {
 		name string
@@ -410,6 +1210,41 @@ func TestAdapter_GetConfigMap(t *testing.T) {
 	}
 }
 
+func TestInvalidIngressClassFilter(t *testing.T) {
+	_, err := NewAdapter(testConfig, IngressAPIVersionNetworking, []string{"skipper-["}, testIngressDefaultSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	assert.Error(t, err)
+}
+
+func TestListIngressClassName(t *testing.T) {
+	for name, test := range map[string]struct {
+		ingressClassFilters  []string
+		expectedIngressNames []string
+	}{
+		"defaultIngressClass": {
+			// the ingressClassName of another controller wins over the
+			// default IngressClass mapping to this one
+			ingressClassFilters:  []string{"default-class"},
+			expectedIngressNames: []string{"without-class", "annotation"},
+		},
+		"ingressClassName": {
+			ingressClassFilters:  []string{"other"},
+			expectedIngressNames: []string{"class-name"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, test.ingressClassFilters, testIngressDefaultSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+			a.kubeClient = &mockClient{ingressFixture: "testdata/fixture03_ingressclassname.json"}
+			ingresses, err := a.ListIngress()
+			require.NoError(t, err)
+			ingressNames := make([]string, len(ingresses))
+			for i, ing := range ingresses {
+				ingressNames[i] = ing.Name
+			}
+			assert.ElementsMatch(t, test.expectedIngressNames, ingressNames, "ingress names mismatch")
+		})
+	}
+}
+
 func TestListIngressFilterClass(t *testing.T) {
 	for name, test := range map[string]struct {
 		ingressClassFilters  []string
@@ -469,6 +1304,26 @@ func TestListIngressFilterClass(t *testing.T) {
 				"fixture-rg02",
 			},
 		},
+		"globIngressClass": {
+			ingressClassFilters: []string{"skip*"},
+			expectedIngressNames: []string{
+				"fixture02",
+				"fixture-rg02",
+			},
+		},
+		"overlappingIngressClassFilters": {
+			ingressClassFilters: []string{"skipper", "skip*", "?kipper"},
+			expectedIngressNames: []string{
+				"fixture02",
+				"fixture-rg02",
+			},
+		},
+		"defaultIngressClass": {
+			ingressClassFilters: []string{"default-class"},
+			expectedIngressNames: []string{
+				"fixture01",
+			},
+		},
 		"multipleIngressClassWithDefault2": {
 			ingressClassFilters: []string{"other", ""},
 			expectedIngressNames: []string{
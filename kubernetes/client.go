@@ -22,6 +22,8 @@ var ErrNoPermissionToAccessResource = errors.New("no permission to access resour
 type client interface {
 	get(string) (io.ReadCloser, error)
 	patch(string, []byte) (io.ReadCloser, error)
+	apply(string, []byte) (io.ReadCloser, error)
+	post(string, []byte) (io.ReadCloser, error)
 }
 
 type simpleClient struct {
@@ -29,7 +31,13 @@ type simpleClient struct {
 	httpClient *http.Client
 }
 
-const defaultControllerUserAgent = "kube-ingress-aws-controller"
+const (
+	defaultControllerUserAgent = "kube-ingress-aws-controller"
+
+	// fieldManager owns the fields set by the controller with server-side
+	// apply, e.g. the load balancer status of ingresses.
+	fieldManager = "kube-ingress-aws-controller"
+)
 
 func newSimpleClient(cfg *Config, disableInstrumentedHttpClient bool) (client, error) {
 	var (
@@ -37,21 +45,12 @@ func newSimpleClient(cfg *Config, disableInstrumentedHttpClient bool) (client, e
 		transport http.RoundTripper = http.DefaultTransport
 		c         *http.Client      = http.DefaultClient
 	)
-	if cfg.CAFile != "" {
-		fileData, err := ioutil.ReadFile(cfg.CAFile)
+	if cfg.hasTLSClientConfig() {
+		var err error
+		tlsConfig, err = newTLSConfig(cfg)
 		if err != nil {
 			return nil, err
 		}
-		certPool := x509.NewCertPool()
-		if !certPool.AppendCertsFromPEM(fileData) {
-			return nil, ErrInvalidCertificates
-		}
-
-		tlsConfig = &tls.Config{
-			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: cfg.Insecure,
-			RootCAs:            certPool,
-		}
 		transport = &http.Transport{
 			TLSHandshakeTimeout: 10 * time.Second,
 			TLSClientConfig:     tlsConfig,
@@ -81,12 +80,78 @@ func newSimpleClient(cfg *Config, disableInstrumentedHttpClient bool) (client, e
 	return &simpleClient{cfg: cfg, httpClient: c}, nil
 }
 
+func (cfg *Config) hasTLSClientConfig() bool {
+	return cfg.CAFile != "" || len(cfg.CAData) > 0 ||
+		cfg.CertFile != "" || len(cfg.CertData) > 0 ||
+		cfg.Insecure
+}
+
+func newTLSConfig(cfg *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.Insecure,
+	}
+
+	caData := cfg.CAData
+	if len(caData) == 0 && cfg.CAFile != "" {
+		fileData, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		caData = fileData
+	}
+	if len(caData) > 0 {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caData) {
+			return nil, ErrInvalidCertificates
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	certData, keyData := cfg.CertData, cfg.KeyData
+	if len(certData) == 0 && cfg.CertFile != "" {
+		fileData, err := ioutil.ReadFile(cfg.CertFile)
+		if err != nil {
+			return nil, err
+		}
+		certData = fileData
+	}
+	if len(keyData) == 0 && cfg.KeyFile != "" {
+		fileData, err := ioutil.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keyData = fileData
+	}
+	if len(certData) > 0 {
+		cert, err := tls.X509KeyPair(certData, keyData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 func (c *simpleClient) get(resource string) (io.ReadCloser, error) {
+	return c.doGet(c.httpClient, resource)
+}
+
+// watch streams the watch events of the resource. Watches last minutes, so
+// they don't time out like the other requests.
+func (c *simpleClient) watch(resource string) (io.ReadCloser, error) {
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	return c.doGet(&httpClient, resource)
+}
+
+func (c *simpleClient) doGet(httpClient *http.Client, resource string) (io.ReadCloser, error) {
 	req, err := c.createRequest("GET", resource, nil)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +167,9 @@ func (c *simpleClient) get(resource string) (io.ReadCloser, error) {
 	if resp.StatusCode == http.StatusForbidden {
 		return nil, ErrNoPermissionToAccessResource
 	}
+	if resp.StatusCode == http.StatusGone {
+		return nil, errResourceExpired
+	}
 	b, err := ioutil.ReadAll(resp.Body)
 	if err == nil {
 		err = fmt.Errorf("unexpected status code (%s) for GET %q: %s", http.StatusText(resp.StatusCode), resource, b)
@@ -110,11 +178,45 @@ func (c *simpleClient) get(resource string) (io.ReadCloser, error) {
 }
 
 func (c *simpleClient) patch(resource string, payload []byte) (io.ReadCloser, error) {
+	return c.doPatch(resource, "application/merge-patch+json", payload)
+}
+
+// apply changes the fields of the payload with server-side apply, owned by
+// the field manager of the controller. Conflicting fields, e.g. the ones set
+// by merge patches of earlier versions of the controller, are taken over,
+// fields not in the payload aren't changed.
+func (c *simpleClient) apply(resource string, payload []byte) (io.ReadCloser, error) {
+	return c.doPatch(resource+"?fieldManager="+fieldManager+"&force=true", "application/apply-patch+yaml", payload)
+}
+
+// post creates the resource of the payload in the collection.
+func (c *simpleClient) post(resource string, payload []byte) (io.ReadCloser, error) {
+	req, err := c.createRequest("POST", resource, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err == nil {
+			err = fmt.Errorf("unexpected status code (%s) for POST %q: %s", http.StatusText(resp.StatusCode), resource, b)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *simpleClient) doPatch(resource, contentType string, payload []byte) (io.ReadCloser, error) {
 	req, err := c.createRequest("PATCH", resource, bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("Content-Type", contentType)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -133,6 +134,34 @@ func TestClientPatch(t *testing.T) {
 	}
 }
 
+func TestClientApply(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" {
+			t.Errorf("unexpected HTTP method. wanted PATCH, got %q", r.Method)
+		}
+		if r.URL.Path != "/foo" {
+			t.Errorf("unexpected URL path. wanted %q, got %q", "/foo", r.URL.Path)
+		}
+		if got := r.URL.RawQuery; got != "fieldManager=kube-ingress-aws-controller&force=true" {
+			t.Errorf("unexpected query %q", got)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/apply-patch+yaml" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "ok")
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	c, _ := newSimpleClient(&Config{BaseURL: server.URL}, false)
+	r, err := c.apply("/foo", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+}
+
 func TestTLS(t *testing.T) {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		token := r.Header.Get("Authorization")
@@ -179,3 +208,53 @@ func TestTLS(t *testing.T) {
 		defer r.Close()
 	}
 }
+
+func TestTLSClientCertificate(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			t.Error("expected a client certificate")
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	cert, err := tls.LoadX509KeyPair("testdata/cert.pem", "testdata/key.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ioutil.ReadFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(ca)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := &Config{
+		BaseURL: server.URL,
+		TLSClientConfig: TLSClientConfig{
+			CAData:   ca,
+			CertFile: "testdata/cert.pem",
+			KeyFile:  "testdata/key.pem",
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	c, err := newSimpleClient(cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.get("/foo")
+	if err != nil {
+		t.Error(err)
+	} else {
+		r.Close()
+	}
+}
@@ -41,6 +41,17 @@ type Config struct {
 type TLSClientConfig struct {
 	// Trusted root certificates for server
 	CAFile string
+
+	// CAData holds PEM-encoded trusted root certificates. It takes
+	// precedence over CAFile.
+	CAData []byte
+
+	// Client certificate and key for TLS client authentication. The
+	// PEM-encoded data takes precedence over the files.
+	CertFile string
+	KeyFile  string
+	CertData []byte
+	KeyData  []byte
 }
 
 const (
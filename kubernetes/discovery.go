@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+type apiGroupList struct {
+	Groups []apiGroup `json:"groups"`
+}
+
+type apiGroup struct {
+	Name     string                     `json:"name"`
+	Versions []groupVersionForDiscovery `json:"versions"`
+}
+
+type groupVersionForDiscovery struct {
+	GroupVersion string `json:"groupVersion"`
+}
+
+const apiGroupListResource = "/apis"
+
+// ingressAPIVersions are the ingress API versions supported by the
+// controller, in order of preference.
+var ingressAPIVersions = []string{
+	IngressAPIVersionNetworkingV1,
+	IngressAPIVersionNetworking,
+	IngressAPIVersionExtensions,
+}
+
+func listAPIGroups(c client) (*apiGroupList, error) {
+	r, err := c.get(apiGroupListResource)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result apiGroupList
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// detectIngressAPIVersion returns the preferred ingress API version served
+// by the API server.
+func detectIngressAPIVersion(c client) (string, error) {
+	groups, err := listAPIGroups(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to list API groups: %v", err)
+	}
+
+	version := selectIngressAPIVersion(groups)
+	if version == "" {
+		return "", fmt.Errorf("no supported ingress API version found")
+	}
+	return version, nil
+}
+
+func selectIngressAPIVersion(groups *apiGroupList) string {
+	served := make(map[string]bool)
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			served[version.GroupVersion] = true
+		}
+	}
+
+	for _, version := range ingressAPIVersions {
+		if served[version] {
+			return version
+		}
+	}
+	return ""
+}
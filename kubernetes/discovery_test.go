@@ -0,0 +1,42 @@
+package kubernetes
+
+import (
+	"testing"
+)
+
+func TestSelectIngressAPIVersion(t *testing.T) {
+	for _, test := range []struct {
+		msg      string
+		versions []string
+		want     string
+	}{
+		{"prefers v1", []string{IngressAPIVersionExtensions, IngressAPIVersionNetworking, IngressAPIVersionNetworkingV1}, IngressAPIVersionNetworkingV1},
+		{"falls back to v1beta1", []string{IngressAPIVersionExtensions, IngressAPIVersionNetworking}, IngressAPIVersionNetworking},
+		{"falls back to extensions", []string{"apps/v1", IngressAPIVersionExtensions}, IngressAPIVersionExtensions},
+		{"no supported version", []string{"apps/v1"}, ""},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			groups := &apiGroupList{Groups: []apiGroup{{}}}
+			for _, version := range test.versions {
+				groups.Groups[0].Versions = append(groups.Groups[0].Versions, groupVersionForDiscovery{GroupVersion: version})
+			}
+			if got := selectIngressAPIVersion(groups); got != test.want {
+				t.Errorf("unexpected version. wanted %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectIngressAPIVersion(t *testing.T) {
+	version, err := detectIngressAPIVersion(&mockClient{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version != IngressAPIVersionNetworking {
+		t.Errorf("unexpected version. wanted %q, got %q", IngressAPIVersionNetworking, version)
+	}
+
+	if _, err := detectIngressAPIVersion(&mockClient{broken: true}); err == nil {
+		t.Error("expected an error")
+	}
+}
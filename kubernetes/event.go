@@ -0,0 +1,163 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// EventTypeNormal is the type of events about the progress of the load
+	// balancer of an ingress.
+	EventTypeNormal = "Normal"
+	// EventTypeWarning is the type of events about failures to provision the
+	// load balancer of an ingress.
+	EventTypeWarning = "Warning"
+
+	eventListResource = "/api/v1/namespaces/%s/events"
+	eventResource     = "/api/v1/namespaces/%s/events/%s"
+
+	// eventRepeatInterval is the shortest interval an event is repeated in,
+	// so reconciliations don't flood the API server with the same events.
+	eventRepeatInterval = 10 * time.Minute
+	// eventTTL is the time the API server keeps events by default.
+	eventTTL = time.Hour
+)
+
+type event struct {
+	APIVersion         string              `json:"apiVersion"`
+	Kind               string              `json:"kind"`
+	Metadata           applyMetadata       `json:"metadata"`
+	InvolvedObject     eventInvolvedObject `json:"involvedObject"`
+	Reason             string              `json:"reason"`
+	Message            string              `json:"message"`
+	Type               string              `json:"type"`
+	Source             eventSource         `json:"source"`
+	ReportingComponent string              `json:"reportingComponent"`
+	FirstTimestamp     string              `json:"firstTimestamp"`
+	LastTimestamp      string              `json:"lastTimestamp"`
+	Count              int                 `json:"count"`
+}
+
+type eventInvolvedObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+}
+
+type eventSource struct {
+	Component string `json:"component"`
+}
+
+// eventCache are the events recorded by the adapter, so repeated events
+// increase the count of the recorded one instead of creating new events.
+type eventCache struct {
+	mu     sync.Mutex
+	events map[string]*recordedEvent
+}
+
+type recordedEvent struct {
+	name  string
+	count int
+	last  time.Time
+}
+
+// RecordEvent records a Kubernetes event of the type about the resource of
+// the ingress, which users see with kubectl describe. The same event is
+// repeated at most every eventRepeatInterval, increasing its count.
+func (a *Adapter) RecordEvent(ingress *Ingress, eventType, reason, message string, now time.Time) error {
+	if ingress == nil {
+		return ErrInvalidIngressUpdateParams
+	}
+
+	involved := eventInvolvedObject{
+		Namespace: ingress.Namespace,
+		Name:      ingress.Name,
+		UID:       ingress.uid,
+	}
+	switch ingress.resourceType {
+	case ingressTypeRouteGroup:
+		involved.APIVersion, involved.Kind = "zalando.org/v1", "RouteGroup"
+	case ingressTypeGateway:
+		involved.APIVersion, involved.Kind = gatewayAPIVersion, "Gateway"
+	case ingressTypeIngress:
+		involved.APIVersion, involved.Kind = a.ingressClient.apiVersion, "Ingress"
+	default:
+		return fmt.Errorf("Unknown resourceType '%s', failed to record event", ingress.resourceType)
+	}
+
+	if a.events == nil {
+		a.events = &eventCache{}
+	}
+	a.events.mu.Lock()
+	defer a.events.mu.Unlock()
+
+	if a.events.events == nil {
+		a.events.events = make(map[string]*recordedEvent)
+	}
+	for key, e := range a.events.events {
+		if now.Sub(e.last) >= eventTTL {
+			delete(a.events.events, key)
+		}
+	}
+
+	key := fmt.Sprintf("%s %s %s %s %s", ingress.ResourceType(), ingress, eventType, reason, message)
+	recorded, ok := a.events.events[key]
+	if ok && now.Sub(recorded.last) < eventRepeatInterval {
+		return ErrUpdateNotNeeded
+	}
+
+	if ok {
+		payload, err := json.Marshal(map[string]interface{}{
+			"count":         recorded.count + 1,
+			"lastTimestamp": now.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+		r, err := a.kubeClient.patch(fmt.Sprintf(eventResource, ingress.Namespace, recorded.name), payload)
+		if err == nil {
+			r.Close()
+			recorded.count++
+			recorded.last = now
+			return nil
+		}
+		// the event expired, it's recorded again
+	}
+
+	recorded = &recordedEvent{
+		name:  fmt.Sprintf("%s.%x", ingress.Name, now.UnixNano()),
+		count: 1,
+		last:  now,
+	}
+	e := event{
+		APIVersion:         "v1",
+		Kind:               "Event",
+		Metadata:           applyMetadata{Namespace: ingress.Namespace, Name: recorded.name},
+		InvolvedObject:     involved,
+		Reason:             reason,
+		Message:            message,
+		Type:               eventType,
+		Source:             eventSource{Component: fieldManager},
+		ReportingComponent: fieldManager,
+		FirstTimestamp:     now.UTC().Format(time.RFC3339),
+		LastTimestamp:      now.UTC().Format(time.RFC3339),
+		Count:              1,
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	r, err := a.kubeClient.post(fmt.Sprintf(eventListResource, ingress.Namespace), payload)
+	if err != nil {
+		return fmt.Errorf("failed to record event %s of %s %s: %v", reason, ingress.ResourceType(), ingress, err)
+	}
+	defer r.Close()
+
+	a.events.events[key] = recorded
+	return nil
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordEvent(t *testing.T) {
+	var requests []string
+	expired := false
+	testServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		requests = append(requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, b))
+		switch {
+		case req.Method == "POST":
+			rw.WriteHeader(http.StatusCreated)
+		case expired:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	a, err := NewAdapter(InsecureConfig(testServer.URL), IngressAPIVersionNetworkingV1, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+	ing := &Ingress{Namespace: "default", Name: "foo", uid: "uid-1", resourceType: ingressTypeIngress}
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, a.RecordEvent(ing, EventTypeWarning, "CertNotFound", "no certificate", now))
+	require.Equal(t, ErrUpdateNotNeeded, a.RecordEvent(ing, EventTypeWarning, "CertNotFound", "no certificate", now.Add(time.Minute)))
+	// other events aren't held back
+	require.NoError(t, a.RecordEvent(ing, EventTypeNormal, "Provisioned", "created stack", now.Add(time.Minute)))
+
+	// repeated events increase the count
+	now = now.Add(eventRepeatInterval)
+	require.NoError(t, a.RecordEvent(ing, EventTypeWarning, "CertNotFound", "no certificate", now))
+
+	// expired events are recorded again
+	expired = true
+	now = now.Add(eventRepeatInterval)
+	require.NoError(t, a.RecordEvent(ing, EventTypeWarning, "CertNotFound", "no certificate", now))
+
+	name := fmt.Sprintf("foo.%x", time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC).UnixNano())
+	require.Len(t, requests, 5)
+	require.Equal(t, `POST /api/v1/namespaces/default/events {"apiVersion":"v1","kind":"Event","metadata":{"namespace":"default","name":"`+name+`"},"involvedObject":{"apiVersion":"networking.k8s.io/v1","kind":"Ingress","namespace":"default","name":"foo","uid":"uid-1"},"reason":"CertNotFound","message":"no certificate","type":"Warning","source":{"component":"kube-ingress-aws-controller"},"reportingComponent":"kube-ingress-aws-controller","firstTimestamp":"2021-07-01T12:00:00Z","lastTimestamp":"2021-07-01T12:00:00Z","count":1}`, requests[0])
+	require.Contains(t, requests[1], `"reason":"Provisioned"`)
+	require.Equal(t, `PATCH /api/v1/namespaces/default/events/`+name+` {"count":2,"lastTimestamp":"2021-07-01T12:10:00Z"}`, requests[2])
+	require.Contains(t, requests[3], "PATCH /api/v1/namespaces/default/events/"+name)
+	require.Contains(t, requests[4], `"firstTimestamp":"2021-07-01T12:20:00Z"`)
+
+	require.Error(t, a.RecordEvent(&Ingress{Namespace: "default", Name: "foo"}, EventTypeNormal, "Provisioned", "created stack", now))
+}
@@ -0,0 +1,479 @@
+// Package fake provides a fake of the subset of the Kubernetes API used by
+// the kubernetes.Adapter, for tests and for running the controller locally
+// without a cluster.
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+const (
+	routeGroupGroup = "zalando.org"
+	routeGroupAPI   = routeGroupGroup + "/v1"
+	defaultNS       = "default"
+)
+
+// resources maps the kinds of the served objects to their resources.
+var resources = map[string]string{
+	"Ingress":      "ingresses",
+	"IngressClass": "ingressclasses",
+	"RouteGroup":   "routegroups",
+	"Namespace":    "namespaces",
+	"ConfigMap":    "configmaps",
+}
+
+// clusterScoped are the resources which aren't namespaced.
+var clusterScoped = map[string]bool{
+	"ingressclasses": true,
+	"namespaces":     true,
+}
+
+// Server is a fake Kubernetes API server holding its objects in memory.
+// Ingresses are served with all the configured ingress API versions, and
+// the status of ingresses and routegroups can be patched like on a real
+// API server. Their annotations can be changed with server-side apply.
+type Server struct {
+	server *httptest.Server
+
+	mu                 sync.Mutex
+	ingressAPIVersions []string
+	// objects holds the objects by resource and namespace/name.
+	objects map[string]map[string]map[string]interface{}
+	// appliedAnnotations holds the keys of the annotations applied by
+	// resource, namespace/name and field manager.
+	appliedAnnotations map[string]map[string]bool
+}
+
+// NewServer starts a fake API server without objects, serving the
+// networking.k8s.io/v1 and networking.k8s.io/v1beta1 ingress APIs.
+func NewServer() *Server {
+	s := &Server{
+		ingressAPIVersions: []string{
+			kubernetes.IngressAPIVersionNetworkingV1,
+			kubernetes.IngressAPIVersionNetworking,
+		},
+		objects:            make(map[string]map[string]map[string]interface{}),
+		appliedAnnotations: make(map[string]map[string]bool),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Config returns the configuration of a kubernetes.Adapter using the server.
+func (s *Server) Config() *kubernetes.Config {
+	return kubernetes.InsecureConfig(s.server.URL)
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetIngressAPIVersions sets the ingress API versions served, e.g. to test
+// the detection of the version after an upgrade of the cluster.
+func (s *Server) SetIngressAPIVersions(versions ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ingressAPIVersions = versions
+}
+
+// Add adds the objects of the YAML or JSON manifest, which may contain
+// several documents separated by "---". Existing objects with the same
+// kind, namespace and name are replaced.
+func (s *Server) Add(manifest []byte) error {
+	for _, doc := range bytes.Split(manifest, []byte("\n---")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		j, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return err
+		}
+
+		var obj map[string]interface{}
+		if err := json.Unmarshal(j, &obj); err != nil {
+			return err
+		}
+		if obj == nil {
+			continue
+		}
+
+		if err := s.add(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) add(obj map[string]interface{}) error {
+	kind, _ := obj["kind"].(string)
+	resource, ok := resources[kind]
+	if !ok {
+		return fmt.Errorf("unsupported kind %q", kind)
+	}
+
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+		obj["metadata"] = metadata
+	}
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		return fmt.Errorf("%s without a name", kind)
+	}
+	namespace, _ := metadata["namespace"].(string)
+	if namespace == "" && !clusterScoped[resource] {
+		namespace = defaultNS
+		metadata["namespace"] = namespace
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.objects[resource] == nil {
+		s.objects[resource] = make(map[string]map[string]interface{})
+	}
+	s.objects[resource][namespace+"/"+name] = obj
+	return nil
+}
+
+// Object returns a copy of the object of the kind, or nil if it doesn't
+// exist. The namespace is ignored for cluster scoped kinds.
+func (s *Server) Object(kind, namespace, name string) map[string]interface{} {
+	resource := resources[kind]
+	if clusterScoped[resource] {
+		namespace = ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[resource][namespace+"/"+name]
+	if !ok {
+		return nil
+	}
+	return deepCopy(obj)
+}
+
+// LoadBalancerHostnames returns the load balancer hostnames in the status
+// of the ingress or routegroup.
+func (s *Server) LoadBalancerHostnames(kind, namespace, name string) []string {
+	obj := s.Object(kind, namespace, name)
+	status, _ := obj["status"].(map[string]interface{})
+	lb, _ := status["loadBalancer"].(map[string]interface{})
+	// routegroups list the load balancers under "routegroup"
+	key := "ingress"
+	if kind == "RouteGroup" {
+		key = "routegroup"
+	}
+	ingresses, _ := lb[key].([]interface{})
+
+	var hostnames []string
+	for _, i := range ingresses {
+		if m, ok := i.(map[string]interface{}); ok {
+			if hostname, ok := m["hostname"].(string); ok {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+	}
+	return hostnames
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.URL.Path == "/apis" && r.Method == http.MethodGet:
+		s.writeJSON(w, s.apiGroups())
+		return
+	case len(parts) >= 3 && parts[0] == "api" && parts[1] == "v1":
+		s.serveResource(w, r, "v1", parts[2:])
+		return
+	case len(parts) >= 4 && parts[0] == "apis":
+		s.serveResource(w, r, parts[1]+"/"+parts[2], parts[3:])
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// serveResource serves a list of objects, or a single object and its status
+// given the path after the group version.
+func (s *Server) serveResource(w http.ResponseWriter, r *http.Request, groupVersion string, parts []string) {
+	resource := parts[0]
+	if len(parts) > 2 && parts[0] == "namespaces" {
+		resource = parts[2]
+	}
+	if !s.served(groupVersion, resource) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.writeJSON(w, s.list(groupVersion, parts[0]))
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.writeObject(w, r, groupVersion, parts[0], "", parts[1])
+	case len(parts) == 4 && parts[0] == "namespaces" && r.Method == http.MethodGet:
+		s.writeObject(w, r, groupVersion, parts[2], parts[1], parts[3])
+	case len(parts) == 4 && parts[0] == "namespaces" && r.Method == http.MethodPatch:
+		s.applyAnnotations(w, r, groupVersion, parts[2], parts[1], parts[3])
+	case len(parts) == 5 && parts[0] == "namespaces" && parts[4] == "status" && r.Method == http.MethodPatch:
+		s.patchStatus(w, r, groupVersion, parts[2], parts[1], parts[3])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// served returns true if the resource is served with the group version.
+func (s *Server) served(groupVersion, resource string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch resource {
+	case "namespaces", "configmaps":
+		return groupVersion == "v1"
+	case "routegroups":
+		return groupVersion == routeGroupAPI
+	case "ingresses", "ingressclasses":
+		for _, v := range s.ingressAPIVersions {
+			if v == groupVersion {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) apiGroups() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make(map[string][]interface{})
+	names := []string{routeGroupGroup}
+	versions[routeGroupGroup] = []interface{}{map[string]interface{}{"groupVersion": routeGroupAPI}}
+	for _, v := range s.ingressAPIVersions {
+		group := strings.Split(v, "/")[0]
+		if _, ok := versions[group]; !ok {
+			names = append(names, group)
+		}
+		versions[group] = append(versions[group], map[string]interface{}{"groupVersion": v})
+	}
+
+	groups := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		groups = append(groups, map[string]interface{}{"name": name, "versions": versions[name]})
+	}
+	return map[string]interface{}{"kind": "APIGroupList", "groups": groups}
+}
+
+func (s *Server) list(groupVersion, resource string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.objects[resource]))
+	for key := range s.objects[resource] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		item := deepCopy(s.objects[resource][key])
+		item["apiVersion"] = groupVersion
+		items = append(items, item)
+	}
+	return map[string]interface{}{
+		"kind":       kindOf(resource) + "List",
+		"apiVersion": groupVersion,
+		"items":      items,
+	}
+}
+
+func (s *Server) writeObject(w http.ResponseWriter, r *http.Request, groupVersion, resource, namespace, name string) {
+	s.mu.Lock()
+	obj, ok := s.objects[resource][namespace+"/"+name]
+	if ok {
+		obj = deepCopy(obj)
+		obj["apiVersion"] = groupVersion
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeJSON(w, obj)
+}
+
+func (s *Server) patchStatus(w http.ResponseWriter, r *http.Request, groupVersion, resource, namespace, name string) {
+	if resource != "ingresses" && resource != "routegroups" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var patch map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// server-side apply requires a field manager and the type of the
+	// object. The fields of the status are owned by a single manager, so
+	// applying them is the same as merging them.
+	if r.Header.Get("Content-Type") == "application/apply-patch+yaml" {
+		if r.URL.Query().Get("fieldManager") == "" {
+			http.Error(w, "fieldManager is required for apply requests", http.StatusBadRequest)
+			return
+		}
+		if patch["apiVersion"] == nil || patch["kind"] == nil {
+			http.Error(w, "apiVersion and kind are required for apply requests", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	obj, ok := s.objects[resource][namespace+"/"+name]
+	if ok {
+		// only the status can be changed through the status subresource
+		if status, ok := patch["status"]; ok {
+			merged := mergePatch(map[string]interface{}{"status": obj["status"]}, map[string]interface{}{"status": status})
+			obj["status"] = merged["status"]
+		}
+		obj = deepCopy(obj)
+		obj["apiVersion"] = groupVersion
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeJSON(w, obj)
+}
+
+// applyAnnotations applies the annotations of the configuration with
+// server-side apply: annotations applied before by the same field manager
+// which are missing in the configuration are removed. Other fields can't be
+// applied.
+func (s *Server) applyAnnotations(w http.ResponseWriter, r *http.Request, groupVersion, resource, namespace, name string) {
+	manager := r.URL.Query().Get("fieldManager")
+	if r.Header.Get("Content-Type") != "application/apply-patch+yaml" || manager == "" {
+		http.Error(w, "only server-side apply with a field manager is supported", http.StatusUnsupportedMediaType)
+		return
+	}
+	if resource != "ingresses" && resource != "routegroups" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var config struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	key := namespace + "/" + name
+	obj, ok := s.objects[resource][key]
+	if ok {
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if annotations == nil {
+			annotations = make(map[string]interface{})
+		}
+
+		managerKey := resource + "/" + key + "/" + manager
+		for annotation := range s.appliedAnnotations[managerKey] {
+			if _, ok := config.Metadata.Annotations[annotation]; !ok {
+				delete(annotations, annotation)
+			}
+		}
+		applied := make(map[string]bool)
+		for annotation, value := range config.Metadata.Annotations {
+			annotations[annotation] = value
+			applied[annotation] = true
+		}
+		s.appliedAnnotations[managerKey] = applied
+
+		metadata["annotations"] = annotations
+		obj = deepCopy(obj)
+		obj["apiVersion"] = groupVersion
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeJSON(w, obj)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// mergePatch applies the JSON merge patch (RFC 7386) to the object.
+func mergePatch(obj, patch map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		obj = make(map[string]interface{})
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(obj, key)
+			continue
+		}
+		patchValue, isMap := value.(map[string]interface{})
+		if !isMap {
+			obj[key] = value
+			continue
+		}
+		objValue, _ := obj[key].(map[string]interface{})
+		obj[key] = mergePatch(objValue, patchValue)
+	}
+	return obj
+}
+
+func deepCopy(obj map[string]interface{}) map[string]interface{} {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	var c map[string]interface{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func kindOf(resource string) string {
+	for kind, r := range resources {
+		if r == resource {
+			return kind
+		}
+	}
+	return ""
+}
@@ -0,0 +1,187 @@
+package fake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+const manifest = `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: foo
+  namespace: team
+  annotations:
+    kubernetes.io/ingress.class: skipper
+spec:
+  rules:
+  - host: foo.example.org
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: other
+  annotations:
+    kubernetes.io/ingress.class: other
+spec:
+  rules:
+  - host: other.example.org
+---
+apiVersion: zalando.org/v1
+kind: RouteGroup
+metadata:
+  name: bar
+  namespace: team
+spec:
+  hosts:
+  - bar.example.org
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team
+  annotations:
+    zalando.org/aws-load-balancer-scheme: internal
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: kube-system
+data:
+  key: value
+`
+
+func TestAdapter(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	require.NoError(t, s.Add([]byte(manifest)))
+
+	a, err := kubernetes.NewAdapter(s.Config(), kubernetes.IngressAPIVersionAuto, []string{"skipper"}, "sg", "", "application", kubernetes.DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+	a.WithNamespaceDefaults(true)
+
+	resources, err := a.ListResources()
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	ingress := resources[0]
+	assert.Equal(t, "foo", ingress.Name)
+	assert.Equal(t, []string{"foo.example.org"}, ingress.Hostnames)
+	assert.Equal(t, "internal", ingress.Scheme)
+
+	require.NoError(t, a.UpdateIngressLoadBalancer(ingress, "lb.example.org"))
+	assert.Equal(t, []string{"lb.example.org"}, s.LoadBalancerHostnames("Ingress", "team", "foo"))
+
+	cm, err := a.GetConfigMap("kube-system", "config")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"key": "value"}, cm.Data)
+
+	_, err = a.GetConfigMap("kube-system", "missing")
+	assert.Error(t, err)
+}
+
+func TestRouteGroups(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	require.NoError(t, s.Add([]byte(manifest)))
+
+	a, err := kubernetes.NewAdapter(s.Config(), kubernetes.IngressAPIVersionNetworkingV1, nil, "sg", "", "application", kubernetes.DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+
+	rgs, err := a.ListRoutegroups()
+	require.NoError(t, err)
+	require.Len(t, rgs, 1)
+	assert.Equal(t, []string{"bar.example.org"}, rgs[0].Hostnames)
+
+	require.NoError(t, a.UpdateIngressLoadBalancer(rgs[0], "lb.example.org"))
+	assert.Equal(t, []string{"lb.example.org"}, s.LoadBalancerHostnames("RouteGroup", "team", "bar"))
+}
+
+func TestIngressAPIVersions(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	require.NoError(t, s.Add([]byte(manifest)))
+	s.SetIngressAPIVersions(kubernetes.IngressAPIVersionExtensions)
+
+	a, err := kubernetes.NewAdapter(s.Config(), kubernetes.IngressAPIVersionAuto, nil, "sg", "", "application", kubernetes.DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+
+	ingresses, err := a.ListIngress()
+	require.NoError(t, err)
+	assert.Len(t, ingresses, 2)
+
+	a, err = kubernetes.NewAdapter(s.Config(), kubernetes.IngressAPIVersionNetworkingV1, nil, "sg", "", "application", kubernetes.DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+	_, err = a.ListIngress()
+	assert.Error(t, err)
+}
+
+func TestAddInvalidManifest(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	assert.Error(t, s.Add([]byte("kind: Pod\nmetadata:\n  name: foo\n")))
+	assert.Error(t, s.Add([]byte("kind: Ingress\n")))
+	assert.Error(t, s.Add([]byte("kind: [")))
+}
+
+func TestIngressState(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	require.NoError(t, s.Add([]byte(manifest)))
+
+	a, err := kubernetes.NewAdapter(s.Config(), kubernetes.IngressAPIVersionNetworkingV1, nil, "sg", "", "application", kubernetes.DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+
+	resources, err := a.ListResources()
+	require.NoError(t, err)
+	require.Len(t, resources, 3)
+
+	state := kubernetes.IngressState{
+		StackName:        "stack",
+		LoadBalancerARN:  "arn:lb",
+		LoadBalancerType: "application",
+		CertificateARNs:  []string{"arn:cert-1", "arn:cert-2"},
+		TargetGroupARNs:  []string{"arn:tg-1", "arn:tg-2"},
+	}
+	reconciled := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, resource := range resources {
+		require.NoError(t, a.UpdateIngressState(resource, state, reconciled))
+	}
+
+	annotations := func(kind, name string) interface{} {
+		return s.Object(kind, "team", name)["metadata"].(map[string]interface{})["annotations"]
+	}
+	assert.Equal(t, map[string]interface{}{
+		"kubernetes.io/ingress.class":                     "skipper",
+		"zalando.org/aws-load-balancer-stack":             "stack",
+		"zalando.org/aws-load-balancer-arn":               "arn:lb",
+		"zalando.org/aws-load-balancer-kind":              "application",
+		"zalando.org/aws-load-balancer-certificate-arns":  "arn:cert-1,arn:cert-2",
+		"zalando.org/aws-load-balancer-target-group-arns": "arn:tg-1,arn:tg-2",
+		"zalando.org/aws-load-balancer-reconciled":        "2021-07-01T12:00:00Z",
+	}, annotations("Ingress", "foo"))
+	assert.Equal(t, "stack", annotations("RouteGroup", "bar").(map[string]interface{})["zalando.org/aws-load-balancer-stack"])
+
+	// the state is read from the annotations
+	resources, err = a.ListResources()
+	require.NoError(t, err)
+	for _, resource := range resources {
+		assert.True(t, resource.HasState(state))
+		assert.Equal(t, kubernetes.ErrUpdateNotNeeded, a.UpdateIngressState(resource, state, reconciled))
+	}
+
+	// an empty state removes the annotations
+	for _, resource := range resources {
+		if resource.Name == "foo" {
+			require.NoError(t, a.UpdateIngressState(resource, kubernetes.IngressState{}, reconciled))
+		}
+	}
+	assert.Equal(t, map[string]interface{}{
+		"kubernetes.io/ingress.class": "skipper",
+	}, annotations("Ingress", "foo"))
+}
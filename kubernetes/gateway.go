@@ -0,0 +1,255 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+type gatewayList struct {
+	Kind       string                 `json:"kind"`
+	APIVersion string                 `json:"apiVersion"`
+	Metadata   routegroupListMetadata `json:"metadata"`
+	Items      []*gateway             `json:"items"`
+}
+
+type gateway struct {
+	Metadata kubeItemMetadata `json:"metadata"`
+	Spec     gatewaySpec      `json:"spec"`
+	Status   gatewayStatus    `json:"status"`
+}
+
+type gatewaySpec struct {
+	GatewayClassName string            `json:"gatewayClassName"`
+	Listeners        []gatewayListener `json:"listeners"`
+}
+
+type gatewayListener struct {
+	Name     string            `json:"name"`
+	Hostname string            `json:"hostname,omitempty"`
+	Port     int               `json:"port"`
+	Protocol string            `json:"protocol"`
+	TLS      *gatewayTLSConfig `json:"tls,omitempty"`
+}
+
+type gatewayTLSConfig struct {
+	Mode    string            `json:"mode,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type gatewayStatus struct {
+	Addresses []gatewayAddress `json:"addresses"`
+}
+
+type gatewayAddress struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type httpRouteList struct {
+	Kind       string                 `json:"kind"`
+	APIVersion string                 `json:"apiVersion"`
+	Metadata   routegroupListMetadata `json:"metadata"`
+	Items      []*httpRoute           `json:"items"`
+}
+
+type httpRoute struct {
+	Metadata kubeItemMetadata `json:"metadata"`
+	Spec     httpRouteSpec    `json:"spec"`
+}
+
+type httpRouteSpec struct {
+	ParentRefs []gatewayParentReference `json:"parentRefs"`
+	Hostnames  []string                 `json:"hostnames"`
+}
+
+type gatewayParentReference struct {
+	Group       string `json:"group,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+const (
+	gatewayAPIVersion          = "gateway.networking.k8s.io/v1"
+	gatewayListResource        = "/apis/gateway.networking.k8s.io/v1/gateways"
+	gatewayNamespacedResource  = "/apis/gateway.networking.k8s.io/v1/namespaces/%s/gateways/%s"
+	gatewayPatchStatusResource = "/apis/gateway.networking.k8s.io/v1/namespaces/%s/gateways/%s/status"
+	httpRouteListResource      = "/apis/gateway.networking.k8s.io/v1/httproutes"
+	gatewayAddressTypeHostname = "Hostname"
+)
+
+func listGateways(c client) (*gatewayList, error) {
+	r, err := c.get(gatewayListResource)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result gatewayList
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func listHTTPRoutes(c client) (*httpRouteList, error) {
+	r, err := c.get(httpRouteListResource)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result httpRouteList
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// attaches returns true if the route attaches to the gateway, and the
+// listeners of the gateway it attaches to. All listeners are returned if the
+// route doesn't name one.
+func (r *httpRoute) attaches(gw *gateway) ([]gatewayListener, bool) {
+	var listeners []gatewayListener
+	attached := false
+	for _, ref := range r.Spec.ParentRefs {
+		if ref.Group != "" && ref.Group != "gateway.networking.k8s.io" || ref.Kind != "" && ref.Kind != "Gateway" {
+			continue
+		}
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = r.Metadata.Namespace
+		}
+		if ref.Name != gw.Metadata.Name || namespace != gw.Metadata.Namespace {
+			continue
+		}
+		for _, listener := range gw.Spec.Listeners {
+			if ref.SectionName == "" || ref.SectionName == listener.Name {
+				attached = true
+				listeners = append(listeners, listener)
+			}
+		}
+	}
+	return listeners, attached
+}
+
+// gatewayHostnames returns the sorted hostnames served by the gateway: the
+// hostnames of its listeners, and the ones of the routes attached to
+// listeners without a hostname.
+func gatewayHostnames(gw *gateway, routes []*httpRoute) []string {
+	hostnames := make(map[string]bool)
+	for _, listener := range gw.Spec.Listeners {
+		if listener.Hostname != "" {
+			hostnames[listener.Hostname] = true
+		}
+	}
+
+	for _, route := range routes {
+		listeners, ok := route.attaches(gw)
+		if !ok {
+			continue
+		}
+		for _, listener := range listeners {
+			if listener.Hostname != "" {
+				continue
+			}
+			for _, hostname := range route.Spec.Hostnames {
+				hostnames[hostname] = true
+			}
+			break
+		}
+	}
+
+	result := make([]string, 0, len(hostnames))
+	for hostname := range hostnames {
+		result = append(result, hostname)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// gatewayCertificateARN returns the certificate ARN of the TLS options of
+// the listeners of the gateway. The certificate references of the listeners
+// are Secrets, which can't be used by load balancers.
+func gatewayCertificateARN(gw *gateway) string {
+	for _, listener := range gw.Spec.Listeners {
+		if listener.TLS == nil {
+			continue
+		}
+		if arn := strings.TrimSpace(listener.TLS.Options[ingressCertificateARNAnnotation]); arn != "" {
+			return arn
+		}
+	}
+	return ""
+}
+
+// applyGatewayStatus is the configuration of the status of a gateway
+// applied with server-side apply.
+type applyGatewayStatus struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   applyMetadata `json:"metadata"`
+	Status     gatewayStatus `json:"status"`
+}
+
+func updateGatewayAddresses(c client, gw *gateway, newHostNames ...string) error {
+	ns, name := gw.Metadata.Namespace, gw.Metadata.Name
+	current := make([]string, 0, len(gw.Status.Addresses))
+	for _, address := range gw.Status.Addresses {
+		current = append(current, address.Value)
+	}
+
+	// gateways without hostnames have no address
+	hostnames := make([]string, 0, len(newHostNames))
+	for _, hostname := range newHostNames {
+		if hostname != "" {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	if statusUpToDate(current, hostnames) {
+		return ErrUpdateNotNeeded
+	}
+
+	addresses := make([]gatewayAddress, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		addresses = append(addresses, gatewayAddress{Type: gatewayAddressTypeHostname, Value: hostname})
+	}
+
+	applyStatus := applyGatewayStatus{
+		APIVersion: gatewayAPIVersion,
+		Kind:       "Gateway",
+		Metadata:   applyMetadata{Namespace: ns, Name: name},
+		Status:     gatewayStatus{Addresses: addresses},
+	}
+
+	resource := fmt.Sprintf(gatewayPatchStatusResource, ns, name)
+	payload, err := json.Marshal(applyStatus)
+	if err != nil {
+		return err
+	}
+
+	r, err := c.apply(resource, payload)
+	if err != nil {
+		return fmt.Errorf("failed to apply the status of gateway %s/%s = %q: %v", ns, name, hostnames, err)
+	}
+	defer r.Close()
+	return nil
+}
@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestListGateways(t *testing.T) {
+	a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	a.kubeClient = &mockClient{}
+
+	gateways, err := a.ListGateways()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gateways) != 1 {
+		t.Fatalf("unexpected count of gateways: %d", len(gateways))
+	}
+
+	gw := gateways[0]
+	if gw.String() != "default/fixture-gw01" || gw.ResourceType() != "gateway" {
+		t.Errorf("unexpected gateway %s %s", gw.ResourceType(), gw)
+	}
+	if want := []string{"gw.example.org", "route.example.org"}; !reflect.DeepEqual(gw.Hostnames, want) {
+		t.Errorf("unexpected hostnames, wanted %v, got %v", want, gw.Hostnames)
+	}
+	if want := []string{"lb.example.org"}; gw.Hostname != "lb.example.org" || !reflect.DeepEqual(gw.LoadBalancerHostnames, want) {
+		t.Errorf("unexpected load balancer hostnames, wanted %v, got %v", want, gw.LoadBalancerHostnames)
+	}
+	if gw.CertificateARN != "fixture-gw01" {
+		t.Errorf("unexpected certificate ARN %q", gw.CertificateARN)
+	}
+	if gw.Scheme != "internal" {
+		t.Errorf("unexpected scheme %q", gw.Scheme)
+	}
+}
+
+func TestListResourcesGatewayAPI(t *testing.T) {
+	a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	a.kubeClient = &mockClient{}
+
+	countGateways := func() int {
+		resources, err := a.ListResources()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, r := range resources {
+			if r.resourceType == ingressTypeGateway {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := countGateways(); n != 0 {
+		t.Errorf("unexpected gateways without the Gateway API: %d", n)
+	}
+	a = a.WithGatewayAPI(true)
+	if n := countGateways(); n != 1 {
+		t.Errorf("unexpected count of gateways: %d", n)
+	}
+}
+
+func TestGatewayHostnames(t *testing.T) {
+	gw := &gateway{
+		Metadata: kubeItemMetadata{Namespace: "default", Name: "gw"},
+		Spec: gatewaySpec{
+			Listeners: []gatewayListener{
+				{Name: "a", Hostname: "a.example.org"},
+				{Name: "b"},
+			},
+		},
+	}
+	route := func(namespace string, hostname string, refs ...gatewayParentReference) *httpRoute {
+		return &httpRoute{
+			Metadata: kubeItemMetadata{Namespace: namespace, Name: hostname},
+			Spec:     httpRouteSpec{ParentRefs: refs, Hostnames: []string{hostname}},
+		}
+	}
+
+	got := gatewayHostnames(gw, []*httpRoute{
+		route("default", "b.example.org", gatewayParentReference{Name: "gw"}),
+		route("other", "c.example.org", gatewayParentReference{Name: "gw", Namespace: "default", SectionName: "b"}),
+		// attached to the listener with a hostname of its own
+		route("default", "d.example.org", gatewayParentReference{Name: "gw", SectionName: "a"}),
+		route("other", "e.example.org", gatewayParentReference{Name: "gw"}),
+		route("default", "f.example.org", gatewayParentReference{Name: "other"}),
+		route("default", "g.example.org", gatewayParentReference{Name: "gw", Kind: "Service"}),
+		route("default", "a.example.org", gatewayParentReference{Name: "gw"}),
+	})
+	want := []string{"a.example.org", "b.example.org", "c.example.org"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected hostnames, wanted %v, got %v", want, got)
+	}
+}
+
+func TestUpdateGatewayAddresses(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/apis/gateway.networking.k8s.io/v1/namespaces/foo/gateways/bar/status" {
+			t.Error("unexpected URL path sent by the client", req.URL.Path)
+		}
+		if req.Method != "PATCH" {
+			t.Error("unexpected HTTP method. Wanted PATCH but got", req.Method)
+		}
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		got := string(b)
+		expected := `{"apiVersion":"gateway.networking.k8s.io/v1","kind":"Gateway","metadata":{"namespace":"foo","name":"bar"},"status":{"addresses":[{"type":"Hostname","value":"a.example.org"},{"type":"Hostname","value":"b.example.org"}]}}`
+		if got != expected {
+			t.Errorf("unexpected request body. Wanted %s but got %s", expected, got)
+		}
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	kubeClient, _ := newSimpleClient(&Config{BaseURL: testServer.URL}, false)
+	gw := &gateway{
+		Metadata: kubeItemMetadata{Namespace: "foo", Name: "bar"},
+		Status:   gatewayStatus{Addresses: []gatewayAddress{{Type: gatewayAddressTypeHostname, Value: "a.example.org"}}},
+	}
+
+	if err := updateGatewayAddresses(kubeClient, gw, "a.example.org"); err != ErrUpdateNotNeeded {
+		t.Errorf("unexpected result of an update to the current address: %v", err)
+	}
+	if err := updateGatewayAddresses(kubeClient, gw, "a.example.org", "b.example.org"); err != nil {
+		t.Error("unexpected result from update call:", err)
+	}
+}
+
+func TestUpdateGatewayLoadBalancer(t *testing.T) {
+	a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	client := &mockClient{}
+	a.kubeClient = client
+	ing := &Ingress{
+		Namespace:    "default",
+		Name:         "foo",
+		Hostname:     "bar",
+		resourceType: ingressTypeGateway,
+	}
+	if err := a.UpdateIngressLoadBalancer(ing, "xpto"); err != nil {
+		t.Error(err)
+	}
+	client.broken = true
+	if err := a.UpdateIngressLoadBalancer(ing, "xpto"); err == nil {
+		t.Error("expected an error")
+	}
+}
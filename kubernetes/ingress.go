@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type ingressList struct {
@@ -39,7 +41,8 @@ type kubeItemMetadata struct {
 }
 
 type ingressSpec struct {
-	Rules []ingressItemRule `json:"rules"`
+	IngressClassName string            `json:"ingressClassName"`
+	Rules            []ingressItemRule `json:"rules"`
 }
 
 type ingressItemRule struct {
@@ -55,25 +58,68 @@ type ingressLoadBalancerStatus struct {
 }
 
 type ingressLoadBalancer struct {
-	Hostname string `json:"hostname"`
+	Hostname string              `json:"hostname,omitempty"`
+	IP       string              `json:"ip,omitempty"`
+	Ports    []ingressPortStatus `json:"ports,omitempty"`
+}
+
+type ingressPortStatus struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
 }
 
 const (
 	// ingressALBIPAddressType is used in external-dns, https://github.com/kubernetes-incubator/external-dns/pull/1079
-	ingressALBIPAddressType           = "alb.ingress.kubernetes.io/ip-address-type"
-	IngressAPIVersionExtensions       = "extensions/v1beta1"
-	IngressAPIVersionNetworking       = "networking.k8s.io/v1beta1"
-	ingressListResource               = "/apis/%s/ingresses"
-	ingressPatchStatusResource        = "/apis/%s/namespaces/%s/ingresses/%s/status"
-	ingressCertificateARNAnnotation   = "zalando.org/aws-load-balancer-ssl-cert"
-	ingressSchemeAnnotation           = "zalando.org/aws-load-balancer-scheme"
-	ingressSharedAnnotation           = "zalando.org/aws-load-balancer-shared"
-	ingressSecurityGroupAnnotation    = "zalando.org/aws-load-balancer-security-group"
-	ingressSSLPolicyAnnotation        = "zalando.org/aws-load-balancer-ssl-policy"
-	ingressLoadBalancerTypeAnnotation = "zalando.org/aws-load-balancer-type"
-	ingressHTTP2Annotation            = "zalando.org/aws-load-balancer-http2"
-	ingressWAFWebACLIDAnnotation      = "zalando.org/aws-waf-web-acl-id"
-	ingressClassAnnotation            = "kubernetes.io/ingress.class"
+	ingressALBIPAddressType       = "alb.ingress.kubernetes.io/ip-address-type"
+	IngressAPIVersionExtensions   = "extensions/v1beta1"
+	IngressAPIVersionNetworking   = "networking.k8s.io/v1beta1"
+	IngressAPIVersionNetworkingV1 = "networking.k8s.io/v1"
+	// IngressAPIVersionAuto detects the ingress API version served by the
+	// API server.
+	IngressAPIVersionAuto                                   = "auto"
+	ingressListResource                                     = "/apis/%s/ingresses"
+	ingressPatchStatusResource                              = "/apis/%s/namespaces/%s/ingresses/%s/status"
+	ingressCertificateARNAnnotation                         = "zalando.org/aws-load-balancer-ssl-cert"
+	ingressSchemeAnnotation                                 = "zalando.org/aws-load-balancer-scheme"
+	ingressSharedAnnotation                                 = "zalando.org/aws-load-balancer-shared"
+	ingressSecurityGroupAnnotation                          = "zalando.org/aws-load-balancer-security-group"
+	ingressSSLPolicyAnnotation                              = "zalando.org/aws-load-balancer-ssl-policy"
+	ingressLoadBalancerTypeAnnotation                       = "zalando.org/aws-load-balancer-type"
+	ingressHTTP2Annotation                                  = "zalando.org/aws-load-balancer-http2"
+	ingressPreserveHostHeaderAnnotation                     = "zalando.org/aws-load-balancer-preserve-host-header"
+	ingressHTTPDisabledAnnotation                           = "zalando.org/aws-load-balancer-http-disabled"
+	ingressFrontingNLBAnnotation                            = "zalando.org/aws-load-balancer-fronting-nlb"
+	ingressLambdaTargetAnnotation                           = "zalando.org/aws-load-balancer-lambda-target"
+	ingressAssumeRoleARNAnnotation                          = "zalando.org/aws-load-balancer-assume-role-arn"
+	ingressDenyInternalDomainsAnnotation                    = "zalando.org/aws-load-balancer-deny-internal-domains"
+	ingressDenyInternalDomainsResponseAnnotation            = "zalando.org/aws-load-balancer-deny-internal-domains-response"
+	ingressDenyInternalDomainsResponseContentTypeAnnotation = "zalando.org/aws-load-balancer-deny-internal-domains-response-content-type"
+	ingressDenyInternalDomainsResponseStatusCodeAnnotation  = "zalando.org/aws-load-balancer-deny-internal-domains-response-status-code"
+	ingressWAFWebACLIDAnnotation                            = "zalando.org/aws-waf-web-acl-id"
+	ingressWAFRateLimitAnnotation                           = "zalando.org/aws-waf-rate-limit"
+	ingressWAFRateLimitKeyAnnotation                        = "zalando.org/aws-waf-rate-limit-key"
+	ingressWAFManagedRuleGroupsAnnotation                   = "zalando.org/aws-waf-managed-rule-groups"
+	ingressSlowStartAnnotation                              = "zalando.org/aws-load-balancer-slow-start-duration"
+	ingressClientKeepAliveAnnotation                        = "zalando.org/aws-load-balancer-client-keep-alive"
+	ingressHealthCheckMatcherAnnotation                     = "zalando.org/aws-load-balancer-health-check-success-codes"
+	ingressPreserveClientIPAnnotation                       = "zalando.org/aws-load-balancer-preserve-client-ip"
+	ingressProxyProtocolV2Annotation                        = "zalando.org/aws-load-balancer-proxy-protocol-v2"
+	ingressClientRoutingPolicyAnnotation                    = "zalando.org/aws-load-balancer-client-routing-policy"
+	ingressCapacityUnitsAnnotation                          = "zalando.org/aws-load-balancer-capacity-units"
+	ingressDefaultCertificateARNAnnotation                  = "zalando.org/aws-load-balancer-default-certificate-arn"
+	ingressExternalTargetGroupARNsAnnotation                = "zalando.org/aws-load-balancer-external-target-group-arns"
+	ingressElasticIPsAnnotation                             = "zalando.org/aws-load-balancer-elastic-ips"
+	ingressIPv4PoolAnnotation                               = "zalando.org/aws-load-balancer-ipv4-pool"
+	ingressEndpointServicePrincipalsAnnotation              = "zalando.org/aws-load-balancer-endpoint-service-principals"
+	ingressHealthyThresholdAnnotation                       = "zalando.org/aws-load-balancer-healthy-threshold-count"
+	ingressUnhealthyThresholdAnnotation                     = "zalando.org/aws-load-balancer-unhealthy-threshold-count"
+	ingressListenerRulesAnnotation                          = "zalando.org/aws-load-balancer-listener-rules"
+	ingressAuthenticationAnnotation                         = "zalando.org/aws-load-balancer-authentication"
+	ingressResourceTagsAnnotation                           = "zalando.org/aws-load-balancer-resource-tags"
+	ingressPausedAnnotation                                 = "zalando.org/aws-load-balancer-paused"
+	ingressAttributesAnnotation                             = "zalando.org/aws-load-balancer-attributes"
+	ingressTargetGroupAttributesAnnotation                  = "zalando.org/aws-load-balancer-target-group-attributes"
+	ingressClassAnnotation                                  = "kubernetes.io/ingress.class"
 )
 
 func getAnnotationsString(annotations map[string]string, key string, defaultValue string) string {
@@ -85,10 +131,40 @@ func getAnnotationsString(annotations map[string]string, key string, defaultValu
 
 type ingressClient struct {
 	apiVersion string
+	// autoDetect enables the detection of the API version, which is
+	// repeated whenever listing ingresses fails.
+	autoDetect bool
+}
+
+func (ic *ingressClient) detectAPIVersion(c client) error {
+	version, err := detectIngressAPIVersion(c)
+	if err != nil {
+		return err
+	}
+
+	if version != ic.apiVersion {
+		log.Infof("Using ingress API version %s", version)
+		ic.apiVersion = version
+	}
+	return nil
 }
 
 func (ic *ingressClient) listIngress(c client) (*ingressList, error) {
+	if ic.autoDetect && ic.apiVersion == "" {
+		if err := ic.detectAPIVersion(c); err != nil {
+			return nil, err
+		}
+	}
+
 	r, err := c.get(fmt.Sprintf(ingressListResource, ic.apiVersion))
+	if err != nil && ic.autoDetect {
+		// the served versions may have changed, e.g. after an upgrade
+		// of the cluster
+		previous := ic.apiVersion
+		if detectErr := ic.detectAPIVersion(c); detectErr == nil && ic.apiVersion != previous {
+			r, err = c.get(fmt.Sprintf(ingressListResource, ic.apiVersion))
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ingress list: %v", err)
 	}
@@ -108,35 +184,58 @@ func (ic *ingressClient) listIngress(c client) (*ingressList, error) {
 	return &result, nil
 }
 
-type patchIngressStatus struct {
-	Status ingressStatus `json:"status"`
+// applyIngressStatus is the configuration of the status of an ingress
+// applied with server-side apply.
+type applyIngressStatus struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   applyMetadata `json:"metadata"`
+	Status     ingressStatus `json:"status"`
 }
 
-func (ic *ingressClient) updateIngressLoadBalancer(c client, i *ingress, newHostName string) error {
-	ns, name := i.Metadata.Namespace, i.Metadata.Name
+type applyMetadata struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func (ic *ingressClient) updateIngressLoadBalancer(c client, i *ingress, newHostNames ...string) error {
+	current := make([]string, 0, len(i.Status.LoadBalancer.Ingress))
 	for _, ingressLb := range i.Status.LoadBalancer.Ingress {
-		if ingressLb.Hostname == newHostName {
-			return ErrUpdateNotNeeded
-		}
+		current = append(current, ingressLb.Hostname)
+	}
+	if statusUpToDate(current, newHostNames) {
+		return ErrUpdateNotNeeded
+	}
+
+	status := make([]ingressLoadBalancer, 0, len(newHostNames))
+	for _, hostname := range newHostNames {
+		status = append(status, ingressLoadBalancer{Hostname: hostname})
 	}
+	return ic.applyIngressLoadBalancer(c, i, status)
+}
 
-	patchStatus := patchIngressStatus{
+func (ic *ingressClient) applyIngressLoadBalancer(c client, i *ingress, status []ingressLoadBalancer) error {
+	ns, name := i.Metadata.Namespace, i.Metadata.Name
+	applyStatus := applyIngressStatus{
+		APIVersion: ic.apiVersion,
+		Kind:       "Ingress",
+		Metadata:   applyMetadata{Namespace: ns, Name: name},
 		Status: ingressStatus{
 			LoadBalancer: ingressLoadBalancerStatus{
-				Ingress: []ingressLoadBalancer{{Hostname: newHostName}},
+				Ingress: status,
 			},
 		},
 	}
 
 	resource := fmt.Sprintf(ingressPatchStatusResource, ic.apiVersion, ns, name)
-	payload, err := json.Marshal(patchStatus)
+	payload, err := json.Marshal(applyStatus)
 	if err != nil {
 		return err
 	}
 
-	r, err := c.patch(resource, payload)
+	r, err := c.apply(resource, payload)
 	if err != nil {
-		return fmt.Errorf("failed to patch ingress %s/%s = %q: %v", ns, name, newHostName, err)
+		return fmt.Errorf("failed to apply the status of ingress %s/%s: %v", ns, name, err)
 	}
 	defer r.Close()
 	return nil
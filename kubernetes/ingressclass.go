@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+type ingressClassList struct {
+	Kind       string          `json:"kind"`
+	APIVersion string          `json:"apiVersion"`
+	Items      []*ingressClass `json:"items"`
+}
+
+type ingressClass struct {
+	Metadata kubeItemMetadata `json:"metadata"`
+}
+
+const (
+	ingressClassListResource = "/apis/%s/ingressclasses"
+	// ingressClassIsDefaultAnnotation marks the IngressClass used for
+	// ingresses without a class.
+	ingressClassIsDefaultAnnotation = "ingressclass.kubernetes.io/is-default-class"
+)
+
+func listIngressClasses(c client) (*ingressClassList, error) {
+	r, err := c.get(fmt.Sprintf(ingressClassListResource, IngressAPIVersionNetworking))
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ingressClassList
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// defaultIngressClass returns the name of the IngressClass marked as the
+// default of the cluster or an empty string if there is none.
+func defaultIngressClass(c client) (string, error) {
+	classes, err := listIngressClasses(c)
+	if err != nil {
+		return "", err
+	}
+
+	for _, class := range classes.Items {
+		if getAnnotationsString(class.Metadata.Annotations, ingressClassIsDefaultAnnotation, "") == "true" {
+			return class.Metadata.Name, nil
+		}
+	}
+	return "", nil
+}
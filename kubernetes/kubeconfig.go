@@ -0,0 +1,160 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// kubeconfig is the subset of the kubeconfig file format of kubectl which
+// is supported by the controller.
+type kubeconfig struct {
+	CurrentContext string            `json:"current-context"`
+	Clusters       []kubeconfigEntry `json:"clusters"`
+	Contexts       []kubeconfigEntry `json:"contexts"`
+	Users          []kubeconfigEntry `json:"users"`
+}
+
+type kubeconfigEntry struct {
+	Name    string             `json:"name"`
+	Cluster *kubeconfigCluster `json:"cluster,omitempty"`
+	Context *kubeconfigContext `json:"context,omitempty"`
+	User    *kubeconfigUser    `json:"user,omitempty"`
+}
+
+type kubeconfigCluster struct {
+	Server                   string `json:"server"`
+	CertificateAuthority     string `json:"certificate-authority"`
+	CertificateAuthorityData []byte `json:"certificate-authority-data"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+}
+
+type kubeconfigContext struct {
+	Cluster string `json:"cluster"`
+	User    string `json:"user"`
+}
+
+type kubeconfigUser struct {
+	Token                 string      `json:"token"`
+	TokenFile             string      `json:"tokenFile"`
+	ClientCertificate     string      `json:"client-certificate"`
+	ClientCertificateData []byte      `json:"client-certificate-data"`
+	ClientKey             string      `json:"client-key"`
+	ClientKeyData         []byte      `json:"client-key-data"`
+	Exec                  interface{} `json:"exec"`
+	AuthProvider          interface{} `json:"auth-provider"`
+}
+
+// KubeconfigConfig creates a configuration for the Kubernetes Adapter from
+// a kubeconfig file, e.g. to run the controller outside of the cluster. The
+// context defaults to the current context of the file. Users can
+// authenticate with a bearer token or a client certificate. Relative paths
+// are resolved relative to the directory of the file, like kubectl does.
+func KubeconfigConfig(path, context string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %s: %v", path, err)
+	}
+
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	if context == "" {
+		return nil, fmt.Errorf("kubeconfig %s has no current context", path)
+	}
+
+	ctx := kc.context(context)
+	if ctx == nil {
+		return nil, fmt.Errorf("context %q not found in kubeconfig %s", context, path)
+	}
+	cluster := kc.cluster(ctx.Cluster)
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster %q of context %q not found in kubeconfig %s", ctx.Cluster, context, path)
+	}
+	if cluster.Server == "" {
+		return nil, fmt.Errorf("cluster %q of kubeconfig %s has no server", ctx.Cluster, path)
+	}
+
+	dir := filepath.Dir(path)
+	cfg := &Config{
+		BaseURL:   strings.TrimSuffix(cluster.Server, "/"),
+		UserAgent: defaultControllerUserAgent,
+		Insecure:  cluster.InsecureSkipTLSVerify,
+		Timeout:   10 * time.Second,
+		TLSClientConfig: TLSClientConfig{
+			CAFile: resolvePath(dir, cluster.CertificateAuthority),
+			CAData: cluster.CertificateAuthorityData,
+		},
+	}
+
+	// contexts without a user access the cluster anonymously
+	if ctx.User == "" {
+		return cfg, nil
+	}
+	user := kc.user(ctx.User)
+	if user == nil {
+		return nil, fmt.Errorf("user %q of context %q not found in kubeconfig %s", ctx.User, context, path)
+	}
+	if user.Exec != nil || user.AuthProvider != nil {
+		// e.g. the aws-iam-authenticator, use kubectl proxy instead
+		return nil, fmt.Errorf("user %q of kubeconfig %s uses an exec plugin or auth provider, which is not supported", ctx.User, path)
+	}
+
+	cfg.BearerToken = user.Token
+	if cfg.BearerToken == "" && user.TokenFile != "" {
+		token, err := ioutil.ReadFile(resolvePath(dir, user.TokenFile))
+		if err != nil {
+			return nil, err
+		}
+		cfg.BearerToken = strings.TrimSpace(string(token))
+	}
+	cfg.CertFile = resolvePath(dir, user.ClientCertificate)
+	cfg.CertData = user.ClientCertificateData
+	cfg.KeyFile = resolvePath(dir, user.ClientKey)
+	cfg.KeyData = user.ClientKeyData
+
+	return cfg, nil
+}
+
+func (kc *kubeconfig) context(name string) *kubeconfigContext {
+	for _, entry := range kc.Contexts {
+		if entry.Name == name && entry.Context != nil {
+			return entry.Context
+		}
+	}
+	return nil
+}
+
+func (kc *kubeconfig) cluster(name string) *kubeconfigCluster {
+	for _, entry := range kc.Clusters {
+		if entry.Name == name && entry.Cluster != nil {
+			return entry.Cluster
+		}
+	}
+	return nil
+}
+
+func (kc *kubeconfig) user(name string) *kubeconfigUser {
+	for _, entry := range kc.Users {
+		if entry.Name == name && entry.User != nil {
+			return entry.User
+		}
+	}
+	return nil
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
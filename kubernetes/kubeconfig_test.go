@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubeconfigConfig(t *testing.T) {
+	token, err := ioutil.ReadFile("testdata/token")
+	require.NoError(t, err)
+	ca, err := ioutil.ReadFile("testdata/ca.crt")
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		context string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			context: "",
+			want: &Config{
+				BaseURL:         "https://dev.example.org:6443",
+				UserAgent:       defaultControllerUserAgent,
+				BearerToken:     strings.TrimSpace(string(token)),
+				Timeout:         10 * time.Second,
+				TLSClientConfig: TLSClientConfig{CAFile: "testdata/ca.crt"},
+			},
+		},
+		{
+			context: "ci",
+			want: &Config{
+				BaseURL:   "https://ci.example.org",
+				UserAgent: defaultControllerUserAgent,
+				Timeout:   10 * time.Second,
+				TLSClientConfig: TLSClientConfig{
+					CAData:   ca,
+					CertFile: "testdata/cert.pem",
+					KeyFile:  "testdata/key.pem",
+				},
+			},
+		},
+		{
+			context: "anonymous",
+			want: &Config{
+				BaseURL:         "https://ci.example.org",
+				UserAgent:       defaultControllerUserAgent,
+				Timeout:         10 * time.Second,
+				TLSClientConfig: TLSClientConfig{CAData: ca},
+			},
+		},
+		{context: "eks", wantErr: true},
+		{context: "missing-cluster", wantErr: true},
+		{context: "missing", wantErr: true},
+	} {
+		t.Run(test.context, func(t *testing.T) {
+			cfg, err := KubeconfigConfig("testdata/kubeconfig/config", test.context)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, cfg)
+		})
+	}
+}
+
+func TestKubeconfigConfigMissingFile(t *testing.T) {
+	_, err := KubeconfigConfig("testdata/kubeconfig/missing", "")
+	assert.Error(t, err)
+}
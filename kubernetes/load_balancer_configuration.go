@@ -0,0 +1,174 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type loadBalancerConfigurationList struct {
+	Kind       string                       `json:"kind"`
+	APIVersion string                       `json:"apiVersion"`
+	Items      []*loadBalancerConfiguration `json:"items"`
+}
+
+// loadBalancerConfiguration configures the load balancers of the ingresses,
+// routegroups and Gateways referencing it in their namespace. Its fields
+// are the typed equivalents of the controller annotations.
+type loadBalancerConfiguration struct {
+	Metadata kubeItemMetadata              `json:"metadata"`
+	Spec     loadBalancerConfigurationSpec `json:"spec"`
+}
+
+type loadBalancerConfigurationSpec struct {
+	Scheme                string            `json:"scheme,omitempty"`
+	Type                  string            `json:"type,omitempty"`
+	Shared                *bool             `json:"shared,omitempty"`
+	HTTP2                 *bool             `json:"http2,omitempty"`
+	HTTPDisabled          *bool             `json:"httpDisabled,omitempty"`
+	CertificateARN        string            `json:"certificateARN,omitempty"`
+	SecurityGroup         string            `json:"securityGroup,omitempty"`
+	SSLPolicy             string            `json:"sslPolicy,omitempty"`
+	IPAddressType         string            `json:"ipAddressType,omitempty"`
+	IPv4Pool              string            `json:"ipv4Pool,omitempty"`
+	WAFWebACLID           string            `json:"wafWebACLID,omitempty"`
+	WAFRateLimit          *int64            `json:"wafRateLimit,omitempty"`
+	WAFManagedRuleGroups  []string          `json:"wafManagedRuleGroups,omitempty"`
+	ListenerRules         json.RawMessage   `json:"listenerRules,omitempty"`
+	Authentication        json.RawMessage   `json:"authentication,omitempty"`
+	ResourceTags          map[string]string `json:"resourceTags,omitempty"`
+	Attributes            map[string]string `json:"attributes,omitempty"`
+	TargetGroupAttributes map[string]string `json:"targetGroupAttributes,omitempty"`
+}
+
+const (
+	loadBalancerConfigurationListResource = "/apis/zalando.org/v1/loadbalancerconfigurations"
+
+	ingressLoadBalancerConfigurationAnnotation = "zalando.org/aws-load-balancer-configuration"
+)
+
+func listLoadBalancerConfigurations(c client) (*loadBalancerConfigurationList, error) {
+	r, err := c.get(loadBalancerConfigurationListResource)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result loadBalancerConfigurationList
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// loadBalancerConfigurationAnnotations returns the annotations equivalent to
+// the load balancer configurations, by namespace and name.
+func loadBalancerConfigurationAnnotations(c client) (map[string]map[string]string, error) {
+	configs, err := listLoadBalancerConfigurations(c)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make(map[string]map[string]string, len(configs.Items))
+	for _, config := range configs.Items {
+		key := fmt.Sprintf("%s/%s", config.Metadata.Namespace, config.Metadata.Name)
+		annotations[key] = config.Spec.annotations()
+	}
+	return annotations, nil
+}
+
+// annotations returns the controller annotations of the fields set in the
+// spec, which are validated when the annotations are parsed.
+func (s *loadBalancerConfigurationSpec) annotations() map[string]string {
+	annotations := make(map[string]string)
+	setString := func(key, value string) {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	setBool := func(key string, value *bool) {
+		if value != nil {
+			annotations[key] = strconv.FormatBool(*value)
+		}
+	}
+
+	setString(ingressSchemeAnnotation, s.Scheme)
+	setString(ingressLoadBalancerTypeAnnotation, s.Type)
+	setBool(ingressSharedAnnotation, s.Shared)
+	setBool(ingressHTTP2Annotation, s.HTTP2)
+	setBool(ingressHTTPDisabledAnnotation, s.HTTPDisabled)
+	setString(ingressCertificateARNAnnotation, s.CertificateARN)
+	setString(ingressSecurityGroupAnnotation, s.SecurityGroup)
+	setString(ingressSSLPolicyAnnotation, s.SSLPolicy)
+	setString(ingressALBIPAddressType, s.IPAddressType)
+	setString(ingressIPv4PoolAnnotation, s.IPv4Pool)
+	setString(ingressWAFWebACLIDAnnotation, s.WAFWebACLID)
+	if s.WAFRateLimit != nil {
+		annotations[ingressWAFRateLimitAnnotation] = strconv.FormatInt(*s.WAFRateLimit, 10)
+	}
+	setString(ingressWAFManagedRuleGroupsAnnotation, strings.Join(s.WAFManagedRuleGroups, ","))
+	setString(ingressListenerRulesAnnotation, string(s.ListenerRules))
+	setString(ingressAuthenticationAnnotation, string(s.Authentication))
+	if len(s.ResourceTags) > 0 {
+		// a map of strings is always marshaled
+		tags, _ := json.Marshal(s.ResourceTags)
+		annotations[ingressResourceTagsAnnotation] = string(tags)
+	}
+	setString(ingressAttributesAnnotation, formatAttributes(s.Attributes))
+	setString(ingressTargetGroupAttributesAnnotation, formatAttributes(s.TargetGroupAttributes))
+	return annotations
+}
+
+// formatAttributes returns the attributes in the key=value format of the
+// attribute annotations, sorted by key.
+func formatAttributes(attributes map[string]string) string {
+	pairs := make([]string, 0, len(attributes))
+	for key, value := range attributes {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// loadBalancerConfigurationAnnotations returns the load balancer
+// configurations if enabled. Without the CRD or the permission to list
+// them, there are none.
+func (a *Adapter) loadBalancerConfigurationAnnotations() (map[string]map[string]string, error) {
+	if !a.loadBalancerConfigurations {
+		return nil, nil
+	}
+	configs, err := loadBalancerConfigurationAnnotations(a.kubeClient)
+	if err == ErrResourceNotFound || err == ErrNoPermissionToAccessResource {
+		log.Warnf("Skipping load balancer configurations because listing them failed: %v", err)
+		return nil, nil
+	}
+	return configs, err
+}
+
+// resourceAnnotations returns the annotations of a resource in the
+// namespace, with the defaults of the load balancer configuration it
+// references, and then of the namespace, added for the keys not set. The
+// namespace can also reference a default load balancer configuration.
+func resourceAnnotations(namespaceDefaults map[string]string, configs map[string]map[string]string, namespace string, annotations map[string]string) map[string]string {
+	name := getAnnotationsString(annotations, ingressLoadBalancerConfigurationAnnotation, namespaceDefaults[ingressLoadBalancerConfigurationAnnotation])
+	if name != "" && configs != nil {
+		config, ok := configs[fmt.Sprintf("%s/%s", namespace, name)]
+		if !ok {
+			log.Warnf("Ignoring the load balancer configuration %s/%s, it doesn't exist", namespace, name)
+		}
+		annotations = mergeAnnotations(config, annotations)
+	}
+	return mergeAnnotations(namespaceDefaults, annotations)
+}
@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+func TestLoadBalancerConfigurationAnnotations(t *testing.T) {
+	a, err := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	require.NoError(t, err)
+	a.kubeClient = &mockClient{}
+
+	configs, err := a.loadBalancerConfigurationAnnotations()
+	require.NoError(t, err)
+	require.Nil(t, configs)
+
+	configs, err = a.WithLoadBalancerConfigurations(true).loadBalancerConfigurationAnnotations()
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]string{
+		"default/internal": {
+			ingressSchemeAnnotation:               "internal",
+			ingressHTTP2Annotation:                "false",
+			ingressSSLPolicyAnnotation:            "ELBSecurityPolicy-FS-2018-06",
+			ingressWAFRateLimitAnnotation:         "2000",
+			ingressWAFManagedRuleGroupsAnnotation: "AWSManagedRulesCommonRuleSet,AWSManagedRulesSQLiRuleSet",
+			ingressListenerRulesAnnotation:        `[{"sourceIPs": ["10.0.0.0/8"], "action": "deny"}]`,
+			ingressResourceTagsAnnotation:         `{"team":"foo"}`,
+			ingressAttributesAnnotation:           "idle_timeout.timeout_seconds=120,routing.http.drop_invalid_header_fields.enabled=true",
+		},
+	}, configs)
+
+	ingress := a.parseAnnotations(resourceAnnotations(nil, configs, "default", map[string]string{
+		ingressLoadBalancerConfigurationAnnotation: "internal",
+	}))
+	assert.Equal(t, "internal", ingress.Scheme)
+	assert.False(t, ingress.HTTP2)
+	assert.Equal(t, "ELBSecurityPolicy-FS-2018-06", ingress.SSLPolicy)
+	assert.Equal(t, int64(2000), ingress.WAFRateLimit)
+	assert.Equal(t, []string{"AWSManagedRulesCommonRuleSet", "AWSManagedRulesSQLiRuleSet"}, ingress.WAFManagedRuleGroups)
+	assert.Equal(t, aws.ListenerRuleList{{SourceIPs: []string{"10.0.0.0/8"}, Action: aws.ListenerRuleActionDeny}}, ingress.ListenerRules)
+	assert.Equal(t, aws.ResourceTags{"team": "foo"}, ingress.ResourceTags)
+	assert.Len(t, ingress.LoadBalancerAttributes, 2)
+}
+
+func TestResourceAnnotations(t *testing.T) {
+	configs := map[string]map[string]string{
+		"default/internal": {
+			ingressSchemeAnnotation:    "internal",
+			ingressSSLPolicyAnnotation: "ELBSecurityPolicy-FS-2018-06",
+		},
+	}
+	namespaceDefaults := map[string]string{
+		ingressSchemeAnnotation:           "internet-facing",
+		ingressLoadBalancerTypeAnnotation: loadBalancerTypeNLB,
+	}
+
+	for _, test := range []struct {
+		msg               string
+		namespace         string
+		namespaceDefaults map[string]string
+		annotations       map[string]string
+		expected          map[string]string
+	}{
+		{
+			msg:         "no reference",
+			namespace:   "default",
+			annotations: map[string]string{ingressSchemeAnnotation: "internet-facing"},
+			expected:    map[string]string{ingressSchemeAnnotation: "internet-facing"},
+		},
+		{
+			msg:       "configuration",
+			namespace: "default",
+			annotations: map[string]string{
+				ingressLoadBalancerConfigurationAnnotation: "internal",
+				ingressSSLPolicyAnnotation:                 testSSLPolicy,
+			},
+			expected: map[string]string{
+				ingressLoadBalancerConfigurationAnnotation: "internal",
+				ingressSchemeAnnotation:                    "internal",
+				ingressSSLPolicyAnnotation:                 testSSLPolicy,
+			},
+		},
+		{
+			msg:               "configuration before namespace defaults",
+			namespace:         "default",
+			namespaceDefaults: namespaceDefaults,
+			annotations:       map[string]string{ingressLoadBalancerConfigurationAnnotation: "internal"},
+			expected: map[string]string{
+				ingressLoadBalancerConfigurationAnnotation: "internal",
+				ingressSchemeAnnotation:                    "internal",
+				ingressSSLPolicyAnnotation:                 "ELBSecurityPolicy-FS-2018-06",
+				ingressLoadBalancerTypeAnnotation:          loadBalancerTypeNLB,
+			},
+		},
+		{
+			msg:               "configuration referenced by the namespace",
+			namespace:         "default",
+			namespaceDefaults: map[string]string{ingressLoadBalancerConfigurationAnnotation: "internal"},
+			expected: map[string]string{
+				ingressLoadBalancerConfigurationAnnotation: "internal",
+				ingressSchemeAnnotation:                    "internal",
+				ingressSSLPolicyAnnotation:                 "ELBSecurityPolicy-FS-2018-06",
+			},
+		},
+		{
+			msg:         "configuration in another namespace",
+			namespace:   "kube-system",
+			annotations: map[string]string{ingressLoadBalancerConfigurationAnnotation: "internal"},
+			expected:    map[string]string{ingressLoadBalancerConfigurationAnnotation: "internal"},
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			require.Equal(t, test.expected, resourceAnnotations(test.namespaceDefaults, configs, test.namespace, test.annotations))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+type namespaceList struct {
+	Kind       string       `json:"kind"`
+	APIVersion string       `json:"apiVersion"`
+	Items      []*namespace `json:"items"`
+}
+
+type namespace struct {
+	Metadata kubeItemMetadata `json:"metadata"`
+}
+
+const namespaceListResource = "/api/v1/namespaces"
+
+// namespaceDefaultAnnotationPrefixes are the prefixes of the annotations
+// which can be set on a namespace as defaults for its ingresses.
+var namespaceDefaultAnnotationPrefixes = []string{
+	"zalando.org/aws-",
+	ingressALBIPAddressType,
+}
+
+func listNamespaces(c client) (*namespaceList, error) {
+	r, err := c.get(namespaceListResource)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result namespaceList
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// namespaceDefaultAnnotations returns the controller annotations set on
+// the namespaces, by namespace name.
+func namespaceDefaultAnnotations(c client) (map[string]map[string]string, error) {
+	namespaces, err := listNamespaces(c)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := make(map[string]map[string]string)
+	for _, ns := range namespaces.Items {
+		for key, value := range ns.Metadata.Annotations {
+			if !isNamespaceDefaultAnnotation(key) {
+				continue
+			}
+			if defaults[ns.Metadata.Name] == nil {
+				defaults[ns.Metadata.Name] = make(map[string]string)
+			}
+			defaults[ns.Metadata.Name][key] = value
+		}
+	}
+	return defaults, nil
+}
+
+func isNamespaceDefaultAnnotation(key string) bool {
+	for _, prefix := range namespaceDefaultAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAnnotations returns the annotations with the defaults added for the
+// keys not set.
+func mergeAnnotations(defaults, annotations map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return annotations
+	}
+
+	merged := make(map[string]string, len(defaults)+len(annotations))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range annotations {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,189 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+)
+
+type podList struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Items      []*pod `json:"items"`
+}
+
+type pod struct {
+	Metadata kubeItemMetadata `json:"metadata"`
+	Spec     podSpec          `json:"spec"`
+	Status   podStatus        `json:"status"`
+}
+
+type podSpec struct {
+	ReadinessGates []podReadinessGate `json:"readinessGates"`
+}
+
+type podReadinessGate struct {
+	ConditionType string `json:"conditionType"`
+}
+
+type podStatus struct {
+	HostIP     string         `json:"hostIP,omitempty"`
+	Conditions []podCondition `json:"conditions"`
+}
+
+type podCondition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
+
+const (
+	podListResource   = "/api/v1/pods"
+	podStatusResource = "/api/v1/namespaces/%s/pods/%s/status"
+
+	// TargetHealthReadinessGate is the condition type of the readiness gate
+	// of pods which are only ready once the instance they run on is healthy
+	// in the target groups of the load balancers.
+	TargetHealthReadinessGate = "zalando.org/load-balancer-target-health"
+)
+
+// Pod is the ingress-controller's representation of a Kubernetes Pod with
+// the target health readiness gate.
+type Pod struct {
+	Namespace string
+	Name      string
+	HostIP    string
+	condition podCondition
+}
+
+// String returns a string representation of the Pod instance containing the
+// namespace and the resource name.
+func (p *Pod) String() string {
+	return fmt.Sprintf("%s/%s", p.Namespace, p.Name)
+}
+
+// TargetHealthy returns true if the condition of the target health readiness
+// gate of the pod is True.
+func (p *Pod) TargetHealthy() bool {
+	return p.condition.Status == "True"
+}
+
+func listPods(c client, selector string) (*podList, error) {
+	resource := podListResource
+	if selector != "" {
+		resource += "?labelSelector=" + url.QueryEscape(selector)
+	}
+	r, err := c.get(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result podList
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ListReadinessGatedPods lists the pods matching the label selector which
+// have the target health readiness gate and run on a node.
+func (a *Adapter) ListReadinessGatedPods(selector string) ([]*Pod, error) {
+	pods, err := listPods(a.kubeClient, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Pod
+	for _, p := range pods.Items {
+		if !p.hasReadinessGate(TargetHealthReadinessGate) || p.Status.HostIP == "" {
+			continue
+		}
+		gated := &Pod{
+			Namespace: p.Metadata.Namespace,
+			Name:      p.Metadata.Name,
+			HostIP:    p.Status.HostIP,
+		}
+		for _, condition := range p.Status.Conditions {
+			if condition.Type == TargetHealthReadinessGate {
+				gated.condition = condition
+			}
+		}
+		ret = append(ret, gated)
+	}
+	return ret, nil
+}
+
+func (p *pod) hasReadinessGate(conditionType string) bool {
+	for _, gate := range p.Spec.ReadinessGates {
+		if gate.ConditionType == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPodStatus is the configuration of the conditions of a pod applied
+// with server-side apply.
+type applyPodStatus struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   applyMetadata `json:"metadata"`
+	Status     podStatus     `json:"status"`
+}
+
+// UpdatePodReadiness sets the condition of the target health readiness gate
+// of the pod. The time of the transition is only changed with the status.
+func (a *Adapter) UpdatePodReadiness(p *Pod, ready bool, reason, message string, now time.Time) error {
+	if p == nil {
+		return ErrInvalidIngressUpdateParams
+	}
+
+	condition := podCondition{
+		Type:               TargetHealthReadinessGate,
+		Status:             "False",
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: p.condition.LastTransitionTime,
+	}
+	if ready {
+		condition.Status = "True"
+	}
+	if condition == p.condition {
+		return ErrUpdateNotNeeded
+	}
+	if condition.Status != p.condition.Status || condition.LastTransitionTime == "" {
+		condition.LastTransitionTime = now.UTC().Format(time.RFC3339)
+	}
+
+	apply := applyPodStatus{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Metadata:   applyMetadata{Namespace: p.Namespace, Name: p.Name},
+		Status:     podStatus{Conditions: []podCondition{condition}},
+	}
+	payload, err := json.Marshal(apply)
+	if err != nil {
+		return err
+	}
+
+	r, err := a.kubeClient.apply(fmt.Sprintf(podStatusResource, p.Namespace, p.Name), payload)
+	if err != nil {
+		return fmt.Errorf("failed to apply the readiness of pod %s: %v", p, err)
+	}
+	defer r.Close()
+
+	p.condition = condition
+	return nil
+}
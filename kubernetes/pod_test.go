@@ -0,0 +1,81 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const podFixture = `{
+  "kind": "PodList",
+  "apiVersion": "v1",
+  "items": [
+    {
+      "metadata": {"namespace": "kube-system", "name": "skipper-1"},
+      "spec": {"readinessGates": [{"conditionType": "zalando.org/load-balancer-target-health"}]},
+      "status": {
+        "hostIP": "10.0.0.1",
+        "conditions": [
+          {"type": "Ready", "status": "False"},
+          {"type": "zalando.org/load-balancer-target-health", "status": "False", "reason": "Unhealthy", "message": "initial", "lastTransitionTime": "2021-07-01T12:00:00Z"}
+        ]
+      }
+    },
+    {
+      "metadata": {"namespace": "kube-system", "name": "skipper-2"},
+      "spec": {"readinessGates": [{"conditionType": "zalando.org/load-balancer-target-health"}]},
+      "status": {}
+    },
+    {
+      "metadata": {"namespace": "kube-system", "name": "other"},
+      "status": {"hostIP": "10.0.0.1"}
+    }
+  ]
+}`
+
+func TestPodReadiness(t *testing.T) {
+	var patched []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "GET":
+			require.Equal(t, podListResource, req.URL.Path)
+			require.Equal(t, "application=skipper", req.URL.Query().Get("labelSelector"))
+			fmt.Fprint(rw, podFixture)
+		case "PATCH":
+			require.Equal(t, fmt.Sprintf(podStatusResource, "kube-system", "skipper-1"), req.URL.Path)
+			b, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			patched = append(patched, string(b))
+		}
+	}))
+	defer testServer.Close()
+
+	a, err := NewAdapter(InsecureConfig(testServer.URL), IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, true)
+	require.NoError(t, err)
+
+	pods, err := a.ListReadinessGatedPods("application=skipper")
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	p := pods[0]
+	require.Equal(t, "kube-system/skipper-1", p.String())
+	require.Equal(t, "10.0.0.1", p.HostIP)
+	require.False(t, p.TargetHealthy())
+
+	now := time.Date(2021, 7, 1, 12, 5, 0, 0, time.UTC)
+	require.Equal(t, ErrUpdateNotNeeded, a.UpdatePodReadiness(p, false, "Unhealthy", "initial", now))
+	require.Empty(t, patched)
+
+	// the transition time is kept while the status doesn't change
+	require.NoError(t, a.UpdatePodReadiness(p, false, "Unhealthy", "unhealthy", now))
+	require.NoError(t, a.UpdatePodReadiness(p, true, "Healthy", "", now))
+	require.True(t, p.TargetHealthy())
+	require.Equal(t, []string{
+		`{"apiVersion":"v1","kind":"Pod","metadata":{"namespace":"kube-system","name":"skipper-1"},"status":{"conditions":[{"type":"zalando.org/load-balancer-target-health","status":"False","reason":"Unhealthy","message":"unhealthy","lastTransitionTime":"2021-07-01T12:00:00Z"}]}}`,
+		`{"apiVersion":"v1","kind":"Pod","metadata":{"namespace":"kube-system","name":"skipper-1"},"status":{"conditions":[{"type":"zalando.org/load-balancer-target-health","status":"True","reason":"Healthy","lastTransitionTime":"2021-07-01T12:05:00Z"}]}}`,
+	}, patched)
+}
@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+// ResourceTagsTemplate renders the tags of the AWS resources from the
+// metadata of the ingresses and routegroups they're created for. The values
+// are Go templates of the fields Namespace, Name, Kind and Labels, e.g.
+// `{{.Labels.team}}`. Missing labels render as empty strings.
+type ResourceTagsTemplate map[string]*template.Template
+
+type resourceTagsTemplateData struct {
+	Namespace string
+	Name      string
+	Kind      string
+	Labels    map[string]string
+}
+
+// NewResourceTagsTemplate parses the templates of the values of the tags.
+func NewResourceTagsTemplate(tags map[string]string) (ResourceTagsTemplate, error) {
+	keys := make(aws.ResourceTags, len(tags))
+	t := make(ResourceTagsTemplate, len(tags))
+	for key, value := range tags {
+		keys[key] = ""
+		tmpl, err := template.New(key).Option("missingkey=zero").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of tag %q: %v", key, err)
+		}
+		t[key] = tmpl
+	}
+
+	if err := keys.Validate(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Render returns the tags of the resources of the ingress. Tags rendering
+// to an empty value are omitted.
+func (t ResourceTagsTemplate) Render(ingress *Ingress) (map[string]string, error) {
+	labels := ingress.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	data := resourceTagsTemplateData{
+		Namespace: ingress.Namespace,
+		Name:      ingress.Name,
+		Kind:      ingress.ResourceType(),
+		Labels:    labels,
+	}
+
+	tags := make(map[string]string, len(t))
+	for key, tmpl := range t {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render tag %q: %v", key, err)
+		}
+		if buf.Len() > 0 {
+			tags[key] = buf.String()
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,43 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceTagsTemplate(t *testing.T) {
+	tmpl, err := NewResourceTagsTemplate(map[string]string{
+		"namespace": "{{.Namespace}}",
+		"owner":     "{{.Kind}}/{{.Name}}",
+		"team":      "{{.Labels.team}}",
+		"cost":      "{{.Labels.cost}}",
+	})
+	require.NoError(t, err)
+
+	tags, err := tmpl.Render(&Ingress{
+		Namespace:    "default",
+		Name:         "foo",
+		Labels:       map[string]string{"team": "bar"},
+		resourceType: ingressTypeRouteGroup,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"namespace": "default",
+		"owner":     "routegroup/foo",
+		"team":      "bar",
+	}, tags)
+
+	tags, err = tmpl.Render(&Ingress{Namespace: "default", Name: "foo", resourceType: ingressTypeIngress})
+	require.NoError(t, err)
+	assert.NotContains(t, tags, "team")
+}
+
+func TestInvalidResourceTagsTemplate(t *testing.T) {
+	_, err := NewResourceTagsTemplate(map[string]string{"team": "{{.Labels.team"})
+	assert.Error(t, err)
+
+	_, err = NewResourceTagsTemplate(map[string]string{"aws:team": "{{.Namespace}}"})
+	assert.Error(t, err)
+}
@@ -67,35 +67,50 @@ func listRoutegroups(c client) (*routegroupList, error) {
 	return &result, nil
 }
 
-type patchRoutegroupStatus struct {
-	Status routegroupStatus `json:"status"`
+// applyRoutegroupStatus is the configuration of the status of a routegroup
+// applied with server-side apply.
+type applyRoutegroupStatus struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Metadata   applyMetadata    `json:"metadata"`
+	Status     routegroupStatus `json:"status"`
 }
 
-func updateRoutegroupLoadBalancer(c client, rg *routegroup, newHostName string) error {
+func updateRoutegroupLoadBalancer(c client, rg *routegroup, newHostNames ...string) error {
 	ns, name := rg.Metadata.Namespace, rg.Metadata.Name
+	current := make([]string, 0, len(rg.Status.LoadBalancer.Routegroup))
 	for _, routegroupLb := range rg.Status.LoadBalancer.Routegroup {
-		if routegroupLb.Hostname == newHostName {
-			return ErrUpdateNotNeeded
-		}
+		current = append(current, routegroupLb.Hostname)
+	}
+	if statusUpToDate(current, newHostNames) {
+		return ErrUpdateNotNeeded
+	}
+
+	status := make([]routegroupLoadBalancer, 0, len(newHostNames))
+	for _, hostname := range newHostNames {
+		status = append(status, routegroupLoadBalancer{Hostname: hostname})
 	}
 
-	patchStatus := patchRoutegroupStatus{
+	applyStatus := applyRoutegroupStatus{
+		APIVersion: "zalando.org/v1",
+		Kind:       "RouteGroup",
+		Metadata:   applyMetadata{Namespace: ns, Name: name},
 		Status: routegroupStatus{
 			LoadBalancer: routegroupLoadBalancerStatus{
-				Routegroup: []routegroupLoadBalancer{{Hostname: newHostName}},
+				Routegroup: status,
 			},
 		},
 	}
 
 	resource := fmt.Sprintf(routegroupPatchStatusResource, ns, name)
-	payload, err := json.Marshal(patchStatus)
+	payload, err := json.Marshal(applyStatus)
 	if err != nil {
 		return err
 	}
 
-	r, err := c.patch(resource, payload)
+	r, err := c.apply(resource, payload)
 	if err != nil {
-		return fmt.Errorf("failed to patch routegroup %s/%s = %q: %v", ns, name, newHostName, err)
+		return fmt.Errorf("failed to apply the status of routegroup %s/%s = %q: %v", ns, name, newHostNames, err)
 	}
 	defer r.Close()
 	return nil
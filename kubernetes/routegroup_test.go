@@ -62,11 +62,6 @@ func TestListRoutegroupFailureScenarios(t *testing.T) {
 }
 
 func TestUpdateRoutegroupLoaBalancer(t *testing.T) {
-	expectedContentType := map[string]bool{
-		"application/json-patch+json":            true,
-		"application/merge-patch+json":           true,
-		"application/strategic-merge-patch+json": true,
-	}
 	testServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		if req.URL.Path != fmt.Sprintf(routegroupPatchStatusResource, "foo", "bar") {
 			t.Error("unexpected URL path sent by the client", req.URL.Path)
@@ -75,15 +70,21 @@ func TestUpdateRoutegroupLoaBalancer(t *testing.T) {
 			t.Error("unexpected HTTP method. Wanted PATCH but got", req.Method)
 		}
 		ct := req.Header.Get("Content-Type")
-		if !expectedContentType[ct] {
+		if ct != "application/apply-patch+yaml" {
 			t.Error("unexpected content type", ct)
 		}
+		if fm := req.URL.Query().Get("fieldManager"); fm != fieldManager {
+			t.Error("unexpected field manager", fm)
+		}
+		if req.URL.Query().Get("force") != "true" {
+			t.Error("expected a forced apply")
+		}
 		b, err := ioutil.ReadAll(req.Body)
 		if err != nil {
 			t.Error(err)
 		}
 		got := string(b)
-		expected := `{"status":{"loadBalancer":{"routegroup":[{"hostname":"example.org"}]}}}`
+		expected := `{"apiVersion":"zalando.org/v1","kind":"RouteGroup","metadata":{"namespace":"foo","name":"bar"},"status":{"loadBalancer":{"routegroup":[{"hostname":"example.org"}]}}}`
 		if got != expected {
 			t.Errorf("unexpected request body. Wanted %s but got %s", expected, got)
 		}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	ingressResource                   = "/apis/%s/namespaces/%s/ingresses/%s"
+	ingressStackAnnotation            = "zalando.org/aws-load-balancer-stack"
+	ingressLoadBalancerARNAnnotation  = "zalando.org/aws-load-balancer-arn"
+	ingressCertificateARNsAnnotation  = "zalando.org/aws-load-balancer-certificate-arns"
+	ingressLoadBalancerKindAnnotation = "zalando.org/aws-load-balancer-kind"
+	ingressTargetGroupARNsAnnotation  = "zalando.org/aws-load-balancer-target-group-arns"
+	ingressReconciledAnnotation       = "zalando.org/aws-load-balancer-reconciled"
+	ingressEndpointServiceAnnotation  = "zalando.org/aws-load-balancer-endpoint-service-name"
+)
+
+// IngressState is the load balancer serving an ingress or routegroup, which
+// the controller records in annotations of the resource.
+type IngressState struct {
+	StackName       string
+	LoadBalancerARN string
+	// LoadBalancerType is the type of the load balancer, application or
+	// network.
+	LoadBalancerType string
+	// CertificateARNs are the certificates of the load balancer used for
+	// the hostnames of the ingress, sorted.
+	CertificateARNs []string
+	// TargetGroupARNs are the target groups the listeners of the load
+	// balancer forward requests to.
+	TargetGroupARNs []string
+	// EndpointServiceName is the name of the VPC endpoint service of the
+	// load balancer, if any.
+	EndpointServiceName string
+}
+
+func newIngressState(annotations map[string]string) IngressState {
+	state := IngressState{
+		StackName:           annotations[ingressStackAnnotation],
+		LoadBalancerARN:     annotations[ingressLoadBalancerARNAnnotation],
+		LoadBalancerType:    annotations[ingressLoadBalancerKindAnnotation],
+		EndpointServiceName: annotations[ingressEndpointServiceAnnotation],
+	}
+	if arns := annotations[ingressCertificateARNsAnnotation]; arns != "" {
+		state.CertificateARNs = strings.Split(arns, ",")
+	}
+	if arns := annotations[ingressTargetGroupARNsAnnotation]; arns != "" {
+		state.TargetGroupARNs = strings.Split(arns, ",")
+	}
+	return state
+}
+
+// HasState returns true if the annotations of the ingress record the state.
+func (i *Ingress) HasState(state IngressState) bool {
+	return reflect.DeepEqual(i.state, state)
+}
+
+type applyMetadataAnnotations struct {
+	APIVersion string                   `json:"apiVersion"`
+	Kind       string                   `json:"kind"`
+	Metadata   applyAnnotationsMetadata `json:"metadata"`
+}
+
+type applyAnnotationsMetadata struct {
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// UpdateIngressState records the state in annotations of the ingress
+// resource, with the time of the update. The annotations are owned by the
+// field manager of the controller, so an empty state removes them.
+func (a *Adapter) UpdateIngressState(ingress *Ingress, state IngressState, reconciled time.Time) error {
+	if ingress == nil {
+		return ErrInvalidIngressUpdateParams
+	}
+	if ingress.HasState(state) {
+		return ErrUpdateNotNeeded
+	}
+
+	var annotations map[string]string
+	if state.StackName != "" {
+		annotations = map[string]string{
+			ingressStackAnnotation:           state.StackName,
+			ingressLoadBalancerARNAnnotation: state.LoadBalancerARN,
+			ingressCertificateARNsAnnotation: strings.Join(state.CertificateARNs, ","),
+			ingressReconciledAnnotation:      reconciled.UTC().Format(time.RFC3339),
+		}
+		if state.LoadBalancerType != "" {
+			annotations[ingressLoadBalancerKindAnnotation] = state.LoadBalancerType
+		}
+		if len(state.TargetGroupARNs) > 0 {
+			annotations[ingressTargetGroupARNsAnnotation] = strings.Join(state.TargetGroupARNs, ",")
+		}
+		if state.EndpointServiceName != "" {
+			annotations[ingressEndpointServiceAnnotation] = state.EndpointServiceName
+		}
+	}
+
+	apply := applyMetadataAnnotations{
+		Metadata: applyAnnotationsMetadata{
+			Namespace:   ingress.Namespace,
+			Name:        ingress.Name,
+			Annotations: annotations,
+		},
+	}
+	var resource string
+	switch ingress.resourceType {
+	case ingressTypeRouteGroup:
+		apply.APIVersion, apply.Kind = "zalando.org/v1", "RouteGroup"
+		resource = fmt.Sprintf(routegroupNamespacedResource, ingress.Namespace, ingress.Name)
+	case ingressTypeGateway:
+		apply.APIVersion, apply.Kind = gatewayAPIVersion, "Gateway"
+		resource = fmt.Sprintf(gatewayNamespacedResource, ingress.Namespace, ingress.Name)
+	case ingressTypeIngress:
+		apply.APIVersion, apply.Kind = a.ingressClient.apiVersion, "Ingress"
+		resource = fmt.Sprintf(ingressResource, a.ingressClient.apiVersion, ingress.Namespace, ingress.Name)
+	default:
+		return fmt.Errorf("Unknown resourceType '%s', failed to update Kubernetes resource", ingress.resourceType)
+	}
+
+	payload, err := json.Marshal(apply)
+	if err != nil {
+		return err
+	}
+
+	r, err := a.kubeClient.apply(resource, payload)
+	if err != nil {
+		return fmt.Errorf("failed to apply the state annotations of %s %s: %v", ingress.ResourceType(), ingress, err)
+	}
+	defer r.Close()
+
+	ingress.state = state
+	return nil
+}
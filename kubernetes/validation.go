@@ -0,0 +1,207 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+// annotationValidators check the values of the controller annotations, which
+// are otherwise ignored or replaced by defaults when invalid.
+var annotationValidators = map[string]func(string) error{
+	ingressSchemeAnnotation:                oneOf(elbv2.LoadBalancerSchemeEnumInternal, elbv2.LoadBalancerSchemeEnumInternetFacing),
+	ingressLoadBalancerTypeAnnotation:      oneOf(loadBalancerTypeALB, loadBalancerTypeNLB),
+	ingressALBIPAddressType:                oneOf(aws.IPAddressTypeIPV4, aws.IPAddressTypeDualstack, aws.IPAddressTypeDualstackWithoutPublicIPV4),
+	ingressSharedAnnotation:                isBool,
+	ingressHTTP2Annotation:                 isBool,
+	ingressHTTPDisabledAnnotation:          isBool,
+	ingressFrontingNLBAnnotation:           isBool,
+	ingressPreserveHostHeaderAnnotation:    isBool,
+	ingressPreserveClientIPAnnotation:      isBool,
+	ingressProxyProtocolV2Annotation:       isBool,
+	ingressDenyInternalDomainsAnnotation:   isBool,
+	ingressPausedAnnotation:                isBool,
+	ingressCertificateARNAnnotation:        isCertificateARN,
+	ingressDefaultCertificateARNAnnotation: isCertificateARN,
+	ingressSSLPolicyAnnotation: func(v string) error {
+		if _, ok := aws.SSLPolicies[v]; !ok {
+			return fmt.Errorf("unknown SSL policy")
+		}
+		return nil
+	},
+	ingressWAFWebACLIDAnnotation: func(v string) error {
+		if !aws.IsValidWAFWebACLID(v) {
+			return fmt.Errorf("must be the ARN of a regional WAFv2 web ACL or the ID of a WAF Classic web ACL")
+		}
+		return nil
+	},
+	ingressWAFRateLimitAnnotation: func(v string) error {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || !aws.IsValidWAFRateLimit(limit) {
+			return fmt.Errorf("must be a number between %d and %d", aws.MinWAFRateLimit, aws.MaxWAFRateLimit)
+		}
+		return nil
+	},
+	ingressWAFRateLimitKeyAnnotation: oneOf(wafRateLimitKeyIP, wafRateLimitKeyForwarded),
+	ingressWAFManagedRuleGroupsAnnotation: func(v string) error {
+		_, err := aws.NewWAFManagedRuleGroups(v)
+		return err
+	},
+	ingressSlowStartAnnotation:       durationBetween(aws.MinSlowStartDuration, aws.MaxSlowStartDuration),
+	ingressClientKeepAliveAnnotation: durationBetween(aws.MinClientKeepAlive, aws.MaxClientKeepAlive),
+	ingressHealthCheckMatcherAnnotation: func(v string) error {
+		if !aws.IsValidHealthCheckMatcher(v) {
+			return fmt.Errorf("must be HTTP codes between 200 and 499, e.g. 200,302 or 200-399")
+		}
+		return nil
+	},
+	ingressHealthyThresholdAnnotation:   thresholdCount,
+	ingressUnhealthyThresholdAnnotation: thresholdCount,
+	ingressClientRoutingPolicyAnnotation: func(v string) error {
+		if !aws.IsValidClientRoutingPolicy(v) {
+			return fmt.Errorf("unknown client routing policy")
+		}
+		return nil
+	},
+	ingressCapacityUnitsAnnotation: func(v string) error {
+		units, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || !aws.IsValidCapacityUnits(units) {
+			return fmt.Errorf("must be a number of at least %d", aws.MinCapacityUnits)
+		}
+		return nil
+	},
+	ingressLambdaTargetAnnotation: func(v string) error {
+		if !aws.IsLambdaFunctionARN(v) {
+			return fmt.Errorf("must be the ARN of a Lambda function")
+		}
+		return nil
+	},
+	ingressAssumeRoleARNAnnotation: func(v string) error {
+		if !aws.IsRoleARN(v) {
+			return fmt.Errorf("must be the ARN of an IAM role")
+		}
+		return nil
+	},
+	ingressDenyInternalDomainsResponseContentTypeAnnotation: func(v string) error {
+		if !aws.IsValidDenyResponseContentType(v) {
+			return fmt.Errorf("unsupported content type")
+		}
+		return nil
+	},
+	ingressDenyInternalDomainsResponseStatusCodeAnnotation: func(v string) error {
+		code, err := strconv.Atoi(v)
+		if err != nil || !aws.IsValidDenyResponseStatusCode(code) {
+			return fmt.Errorf("must be a 2XX, 4XX or 5XX status code")
+		}
+		return nil
+	},
+	ingressExternalTargetGroupARNsAnnotation: func(v string) error {
+		_, err := aws.NewExternalTargetGroupARNs(v)
+		return err
+	},
+	ingressElasticIPsAnnotation: func(v string) error {
+		_, err := aws.NewElasticIPs(v)
+		return err
+	},
+	ingressIPv4PoolAnnotation: func(v string) error {
+		if !aws.IsValidIPv4Pool(v) {
+			return fmt.Errorf("must be the ID of an IPAM pool or of a public IPv4 pool")
+		}
+		return nil
+	},
+	ingressEndpointServicePrincipalsAnnotation: func(v string) error {
+		_, err := aws.NewEndpointServicePrincipals(v)
+		return err
+	},
+	ingressListenerRulesAnnotation: func(v string) error {
+		_, err := aws.NewListenerRuleListFromJSON([]byte(v))
+		return err
+	},
+	ingressAuthenticationAnnotation: func(v string) error {
+		_, err := aws.NewListenerAuthenticationFromJSON([]byte(v))
+		return err
+	},
+	ingressResourceTagsAnnotation: func(v string) error {
+		_, err := aws.NewResourceTagsFromJSON([]byte(v))
+		return err
+	},
+	ingressAttributesAnnotation:            isAttributes,
+	ingressTargetGroupAttributesAnnotation: isAttributes,
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, value := range values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func isBool(v string) error {
+	return oneOf("true", "false")(v)
+}
+
+func isCertificateARN(v string) error {
+	a, err := arn.Parse(v)
+	if err != nil || (a.Service != "acm" && a.Service != "iam") {
+		return fmt.Errorf("must be the ARN of an ACM or IAM certificate")
+	}
+	return nil
+}
+
+func durationBetween(min, max time.Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < min || d > max {
+			return fmt.Errorf("must be a duration between %s and %s", min, max)
+		}
+		return nil
+	}
+}
+
+func thresholdCount(v string) error {
+	count, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || count < aws.MinHealthCheckThresholdCount || count > aws.MaxHealthCheckThresholdCount {
+		return fmt.Errorf("must be a number between %d and %d", aws.MinHealthCheckThresholdCount, aws.MaxHealthCheckThresholdCount)
+	}
+	return nil
+}
+
+func isAttributes(v string) error {
+	_, err := aws.NewAttributes(v)
+	return err
+}
+
+// ValidateAnnotations returns an error listing the controller annotations
+// with invalid values, which the controller would ignore. Annotations with
+// the same value in the previous annotations, e.g. of the ingress before an
+// update, aren't validated, so they don't block unrelated changes.
+func ValidateAnnotations(annotations, previous map[string]string) error {
+	var invalid []string
+	for key, value := range annotations {
+		validate, ok := annotationValidators[key]
+		if !ok {
+			continue
+		}
+		if old, ok := previous[key]; ok && old == value {
+			continue
+		}
+		if err := validate(value); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s=%q: %v", key, value, err))
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	sort.Strings(invalid)
+	return fmt.Errorf("invalid annotations: %s", strings.Join(invalid, "; "))
+}
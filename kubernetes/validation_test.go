@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	for _, test := range []struct {
+		msg         string
+		annotations map[string]string
+		previous    map[string]string
+		expected    string
+	}{
+		{
+			msg: "valid annotations",
+			annotations: map[string]string{
+				ingressSchemeAnnotation:         "internal",
+				ingressSSLPolicyAnnotation:      "ELBSecurityPolicy-TLS-1-2-2017-01",
+				ingressCertificateARNAnnotation: "arn:aws:acm:eu-central-1:123456789012:certificate/f4bd7ed6-bf23-11e6-8db1-ef7ba1500c61",
+				ingressWAFWebACLIDAnnotation:    "arn:aws:wafv2:eu-central-1:123456789012:regional/webacl/foo/a1b2c3d4",
+				ingressListenerRulesAnnotation:  `[{"sourceIPs": ["10.0.0.0/8"], "action": "deny"}]`,
+				ingressClassAnnotation:          "skipper",
+				"zalando.org/other":             "x",
+			},
+		},
+		{
+			msg: "invalid annotations",
+			annotations: map[string]string{
+				ingressSchemeAnnotation:        "private",
+				ingressSSLPolicyAnnotation:     "ELBSecurityPolicy-Unknown",
+				ingressHTTP2Annotation:         "yes",
+				ingressWAFWebACLIDAnnotation:   "arn:aws:wafv2:eu-central-1:123456789012:global/webacl/foo/a1b2c3d4",
+				ingressListenerRulesAnnotation: `[{"action": "deny"}]`,
+			},
+			expected: `invalid annotations: ` +
+				`zalando.org/aws-load-balancer-http2="yes": must be one of true, false; ` +
+				`zalando.org/aws-load-balancer-listener-rules="[{\"action\": \"deny\"}]": invalid listener rule 0: at least one source IP or path pattern is required; ` +
+				`zalando.org/aws-load-balancer-scheme="private": must be one of internal, internet-facing; ` +
+				`zalando.org/aws-load-balancer-ssl-policy="ELBSecurityPolicy-Unknown": unknown SSL policy; ` +
+				`zalando.org/aws-waf-web-acl-id="arn:aws:wafv2:eu-central-1:123456789012:global/webacl/foo/a1b2c3d4": must be the ARN of a regional WAFv2 web ACL or the ID of a WAF Classic web ACL`,
+		},
+		{
+			msg:         "unchanged invalid annotation",
+			annotations: map[string]string{ingressSchemeAnnotation: "private", ingressSlowStartAnnotation: "1s"},
+			previous:    map[string]string{ingressSchemeAnnotation: "private"},
+			expected:    `invalid annotations: zalando.org/aws-load-balancer-slow-start-duration="1s": must be a duration between 30s and 15m0s`,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			err := ValidateAnnotations(test.annotations, test.previous)
+			if test.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
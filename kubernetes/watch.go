@@ -0,0 +1,390 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errResourceExpired is returned when the resource version of a watch is
+// too old, so the resource has to be listed again.
+var errResourceExpired = errors.New("resource version expired")
+
+const (
+	watchRetryInterval    = time.Second
+	maxWatchRetryInterval = time.Minute
+)
+
+// watcher is implemented by the clients which can watch resources.
+type watcher interface {
+	watch(string) (io.ReadCloser, error)
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// watchStatus is the object of ERROR watch events.
+type watchStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// watchObject are the parts of a watched object which identify it and which
+// the controller reacts to. Changes of the status alone are ignored.
+type watchObject struct {
+	Metadata struct {
+		Namespace       string            `json:"namespace"`
+		Name            string            `json:"name"`
+		ResourceVersion string            `json:"resourceVersion"`
+		Annotations     map[string]string `json:"annotations"`
+		Labels          map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec json.RawMessage `json:"spec"`
+}
+
+type watchList struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// watchedResource returns true if the resource is a list of resources
+// watched instead of listed in every reconciliation.
+func watchedResource(resource string) bool {
+	switch resource {
+	case routegroupListResource, gatewayListResource, httpRouteListResource, namespaceListResource, loadBalancerConfigurationListResource:
+		return true
+	}
+	for _, version := range []string{IngressAPIVersionExtensions, IngressAPIVersionNetworking, IngressAPIVersionNetworkingV1} {
+		if resource == fmt.Sprintf(ingressListResource, version) || resource == fmt.Sprintf(ingressClassListResource, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchingClient serves the lists of the watched resources from a local
+// cache kept up to date by watches, and passes all other requests to the
+// client. A resource is listed once on its first request and watched from
+// then on. While a watch can't be resumed, the resource is listed again.
+type watchingClient struct {
+	client
+	watcher watcher
+
+	mu      sync.Mutex
+	watches map[string]*resourceWatch
+	changes chan struct{}
+	stop    chan struct{}
+}
+
+func newWatchingClient(c client, w watcher) *watchingClient {
+	return &watchingClient{
+		client:  c,
+		watcher: w,
+		watches: make(map[string]*resourceWatch),
+		changes: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+func (c *watchingClient) get(resource string) (io.ReadCloser, error) {
+	if !watchedResource(resource) {
+		return c.client.get(resource)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if w, ok := c.watches[resource]; ok {
+		if b, ok := w.list(); ok {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+		// listing the resource again failed
+		return c.client.get(resource)
+	}
+
+	w := &resourceWatch{resource: resource}
+	b, _, err := w.relist(c.client)
+	if err != nil {
+		return nil, err
+	}
+	c.watches[resource] = w
+	go c.run(w)
+
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// notify signals a change of the watched resources. Changes are merged
+// until they're received.
+func (c *watchingClient) notify() {
+	select {
+	case c.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (c *watchingClient) remove(w *resourceWatch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.watches, w.resource)
+}
+
+// run watches the resource until the client is stopped, or the resource
+// can't be accessed anymore, e.g. after its CRD was removed. In that case the
+// next request lists and watches it again.
+func (c *watchingClient) run(w *resourceWatch) {
+	retry := watchRetryInterval
+	relist := false
+	for {
+		select {
+		case <-c.stop:
+			return
+		default:
+		}
+
+		var err error
+		if relist {
+			var changed bool
+			_, changed, err = w.relist(c.client)
+			if changed {
+				c.notify()
+			}
+		}
+		if err == nil {
+			relist = false
+			err = w.watch(c.watcher, c.notify, c.stop)
+			if err == nil {
+				// the API server ends watches after a while
+				retry = watchRetryInterval
+				continue
+			}
+		}
+
+		if err == ErrResourceNotFound || err == ErrNoPermissionToAccessResource {
+			log.Warnf("Stopping the watch of %s: %v", w.resource, err)
+			c.remove(w)
+			return
+		}
+		relist = true
+		if err == errResourceExpired {
+			continue
+		}
+
+		log.Warnf("Watch of %s failed, listing it again in %s: %v", w.resource, retry, err)
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(retry):
+		}
+		retry *= 2
+		if retry > maxWatchRetryInterval {
+			retry = maxWatchRetryInterval
+		}
+	}
+}
+
+// resourceWatch is the cache of the objects of a watched resource.
+type resourceWatch struct {
+	resource string
+
+	mu              sync.Mutex
+	synced          bool
+	resourceVersion string
+	kind            string
+	items           map[string]json.RawMessage
+	fingerprints    map[string]string
+}
+
+// list returns the cached list of the resource, or false if it isn't synced.
+func (w *resourceWatch) list() ([]byte, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.synced {
+		return nil, false
+	}
+
+	keys := make([]string, 0, len(w.items))
+	for key := range w.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := watchList{Kind: w.kind, Items: make([]json.RawMessage, 0, len(keys))}
+	list.Metadata.ResourceVersion = w.resourceVersion
+	for _, key := range keys {
+		list.Items = append(list.Items, w.items[key])
+	}
+
+	b, err := json.Marshal(list)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// relist lists the resource and replaces the cached objects. It returns the
+// list, and true if the objects changed since the last list.
+func (w *resourceWatch) relist(c client) ([]byte, bool, error) {
+	b, err := w.get(c)
+	if err != nil {
+		w.mu.Lock()
+		w.synced = false
+		w.mu.Unlock()
+		return nil, false, err
+	}
+
+	var list watchList
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, false, err
+	}
+
+	items := make(map[string]json.RawMessage, len(list.Items))
+	fingerprints := make(map[string]string, len(list.Items))
+	for _, item := range list.Items {
+		obj, fingerprint, err := parseWatchObject(item)
+		if err != nil {
+			return nil, false, err
+		}
+		key := obj.Metadata.Namespace + "/" + obj.Metadata.Name
+		items[key] = item
+		fingerprints[key] = fingerprint
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed := w.fingerprints != nil && !reflect.DeepEqual(w.fingerprints, fingerprints)
+	w.synced = true
+	w.resourceVersion = list.Metadata.ResourceVersion
+	w.kind = list.Kind
+	w.items = items
+	w.fingerprints = fingerprints
+	return b, changed, nil
+}
+
+func (w *resourceWatch) get(c client) ([]byte, error) {
+	r, err := c.get(w.resource)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// watch applies the watch events of the resource to the cache from its last
+// resource version, until the API server ends the watch or the client is
+// stopped. Changes of the objects are notified.
+func (w *resourceWatch) watch(c watcher, notify func(), stop <-chan struct{}) error {
+	w.mu.Lock()
+	resourceVersion := w.resourceVersion
+	w.mu.Unlock()
+
+	r, err := c.watch(w.resource + "?watch=true&allowWatchBookmarks=true&resourceVersion=" + url.QueryEscape(resourceVersion))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			r.Close()
+		case <-done:
+		}
+	}()
+
+	d := json.NewDecoder(r)
+	for {
+		var event watchEvent
+		if err := d.Decode(&event); err == io.EOF {
+			return nil
+		} else if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		changed, err := w.apply(event)
+		if err != nil {
+			return err
+		}
+		if changed {
+			notify()
+		}
+	}
+}
+
+// apply applies a watch event to the cache. It returns true if an object
+// was added, deleted or changed other than in its status.
+func (w *resourceWatch) apply(event watchEvent) (bool, error) {
+	if event.Type == "ERROR" {
+		var status watchStatus
+		if err := json.Unmarshal(event.Object, &status); err != nil {
+			return false, err
+		}
+		if status.Code == 410 {
+			return false, errResourceExpired
+		}
+		return false, fmt.Errorf("watch error %d: %s", status.Code, status.Message)
+	}
+
+	obj, fingerprint, err := parseWatchObject(event.Object)
+	if err != nil {
+		return false, err
+	}
+	key := obj.Metadata.Namespace + "/" + obj.Metadata.Name
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed := false
+	switch event.Type {
+	case "ADDED", "MODIFIED":
+		changed = w.fingerprints[key] != fingerprint
+		w.items[key] = event.Object
+		w.fingerprints[key] = fingerprint
+	case "DELETED":
+		_, changed = w.items[key]
+		delete(w.items, key)
+		delete(w.fingerprints, key)
+	case "BOOKMARK":
+	default:
+		return false, fmt.Errorf("unexpected watch event type %q", event.Type)
+	}
+	w.resourceVersion = obj.Metadata.ResourceVersion
+	return changed, nil
+}
+
+// parseWatchObject returns the metadata of the object, and a fingerprint of
+// its annotations, labels and spec.
+func parseWatchObject(b json.RawMessage) (*watchObject, string, error) {
+	var obj watchObject
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, "", err
+	}
+	fingerprint, err := json.Marshal([]interface{}{obj.Metadata.Annotations, obj.Metadata.Labels, obj.Spec})
+	if err != nil {
+		return nil, "", err
+	}
+	return &obj, string(fingerprint), nil
+}
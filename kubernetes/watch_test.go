@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWatchClient struct {
+	lists   chan string
+	watches chan string
+	events  *io.PipeWriter
+}
+
+func newFakeWatchClient() *fakeWatchClient {
+	return &fakeWatchClient{
+		lists:   make(chan string, 2),
+		watches: make(chan string, 2),
+	}
+}
+
+func (c *fakeWatchClient) get(res string) (io.ReadCloser, error) {
+	if !watchedResource(res) {
+		return ioutil.NopCloser(strings.NewReader(res)), nil
+	}
+	select {
+	case list := <-c.lists:
+		return ioutil.NopCloser(strings.NewReader(list)), nil
+	default:
+		return nil, fmt.Errorf("unexpected list of %s", res)
+	}
+}
+
+func (c *fakeWatchClient) patch(res string, payload []byte) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("unexpected patch of %s", res)
+}
+
+func (c *fakeWatchClient) apply(res string, payload []byte) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("unexpected apply of %s", res)
+}
+
+func (c *fakeWatchClient) post(res string, payload []byte) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("unexpected post to %s", res)
+}
+
+func (c *fakeWatchClient) watch(res string) (io.ReadCloser, error) {
+	r, w := io.Pipe()
+	c.events = w
+	c.watches <- res
+	return r, nil
+}
+
+func (c *fakeWatchClient) send(t *testing.T, eventType, object string) {
+	_, err := fmt.Fprintf(c.events, `{"type":%q,"object":%s}`+"\n", eventType, object)
+	require.NoError(t, err)
+}
+
+func watchTestObject(name, resourceVersion, annotation, status string) string {
+	return fmt.Sprintf(`{"metadata":{"namespace":"default","name":%q,"resourceVersion":%q,"annotations":{"a":%q}},"spec":{"rules":[]},"status":{"loadBalancer":{"ingress":[{"hostname":%q}]}}}`,
+		name, resourceVersion, annotation, status)
+}
+
+func requireWatchList(t *testing.T, c client, resource string, names ...string) {
+	r, err := c.get(resource)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var list ingressList
+	require.NoError(t, json.NewDecoder(r).Decode(&list))
+	got := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		got = append(got, item.Metadata.Name)
+	}
+	require.Equal(t, names, got)
+}
+
+func requireWatchChange(t *testing.T, c *watchingClient) {
+	select {
+	case <-c.changes:
+	case <-time.After(time.Second):
+		t.Fatal("expected a change")
+	}
+}
+
+func TestWatchingClient(t *testing.T) {
+	resource := fmt.Sprintf(ingressListResource, IngressAPIVersionNetworkingV1)
+	fake := newFakeWatchClient()
+	c := newWatchingClient(fake, fake)
+	defer close(c.stop)
+
+	fake.lists <- fmt.Sprintf(`{"kind":"IngressList","metadata":{"resourceVersion":"1"},"items":[%s]}`, watchTestObject("a", "1", "x", ""))
+	requireWatchList(t, c, resource, "a")
+	require.Equal(t, resource+"?watch=true&allowWatchBookmarks=true&resourceVersion=1", <-fake.watches)
+
+	// status updates aren't changes
+	fake.send(t, "MODIFIED", watchTestObject("a", "2", "x", "lb.example.org"))
+	fake.send(t, "ADDED", watchTestObject("b", "3", "x", ""))
+	requireWatchChange(t, c)
+	select {
+	case <-c.changes:
+		t.Fatal("unexpected change")
+	default:
+	}
+	requireWatchList(t, c, resource, "a", "b")
+
+	fake.send(t, "MODIFIED", watchTestObject("b", "4", "y", ""))
+	requireWatchChange(t, c)
+	fake.send(t, "DELETED", watchTestObject("a", "5", "x", ""))
+	requireWatchChange(t, c)
+	requireWatchList(t, c, resource, "b")
+
+	// an expired resource version lists the resource again
+	fake.lists <- fmt.Sprintf(`{"kind":"IngressList","metadata":{"resourceVersion":"10"},"items":[%s]}`, watchTestObject("c", "10", "x", ""))
+	fake.send(t, "ERROR", `{"kind":"Status","code":410,"message":"too old resource version"}`)
+	requireWatchChange(t, c)
+	require.Equal(t, resource+"?watch=true&allowWatchBookmarks=true&resourceVersion=10", <-fake.watches)
+	requireWatchList(t, c, resource, "c")
+
+	// other resources aren't watched
+	r, err := c.get(namespaceListResource + "/default")
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, namespaceListResource+"/default", string(b))
+}
+
+func TestWatchingClientListError(t *testing.T) {
+	fake := newFakeWatchClient()
+	c := newWatchingClient(fake, fake)
+	defer close(c.stop)
+
+	_, err := c.get(routegroupListResource)
+	require.Error(t, err)
+	require.Empty(t, c.watches)
+}
+
+func TestWithWatch(t *testing.T) {
+	a, _ := NewAdapter(testConfig, IngressAPIVersionNetworking, testIngressFilter, testSecurityGroup, testSSLPolicy, testLoadBalancerTypeAWS, DefaultClusterLocalDomain, false)
+	require.Nil(t, a.WithWatch(false).Changes())
+	require.NotNil(t, a.WithWatch(true).Changes())
+
+	// clients which can't watch list the resources every time
+	a.kubeClient = &mockClient{}
+	a.changes = nil
+	require.Nil(t, a.WithWatch(true).Changes())
+}
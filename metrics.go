@@ -0,0 +1,187 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+const metricsNamespace = "kube_ingress_aws_controller"
+
+var (
+	// stackErrors counts the failed operations per stack, so a failing
+	// stack can be told apart from the ones reconciled successfully.
+	stackErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stack_errors_total",
+		Help:      "Number of failed operations on CloudFormation stacks.",
+	}, []string{"stack", "operation"})
+
+	// ingressErrors counts the failed status updates per ingress.
+	ingressErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "ingress_errors_total",
+		Help:      "Number of failed updates of ingresses and route groups.",
+	}, []string{"namespace", "name"})
+
+	// ingressAnnotationWarnings is the number of warnings about the
+	// annotations of each ingress, e.g. invalid values which are ignored.
+	ingressAnnotationWarnings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "ingress_annotation_warnings",
+		Help:      "Number of annotations of ingresses, route groups and Gateways which are ignored or adjusted.",
+	}, []string{"namespace", "name"})
+
+	// admissionReviews counts the admission reviews of the webhook by
+	// whether the object was allowed.
+	admissionReviews = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "admission_reviews_total",
+		Help:      "Number of objects validated by the admission webhook.",
+	}, []string{"allowed"})
+
+	// loadBalancerQuotaExceeded counts the load balancers which couldn't
+	// be created because the quota of the account is exhausted.
+	loadBalancerQuotaExceeded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "load_balancer_quota_exceeded_total",
+		Help:      "Number of load balancers not created because the quota of the account is exhausted.",
+	})
+
+	// loadBalancerLimitExceeded counts the load balancers which weren't
+	// created because of --max-load-balancers.
+	loadBalancerLimitExceeded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "load_balancer_limit_exceeded_total",
+		Help:      "Number of load balancers not created because the maximum number of load balancers is reached.",
+	})
+
+	// firewallManagerConflicts is the number of load balancers whose
+	// WebACL was replaced by AWS Firewall Manager.
+	firewallManagerConflicts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "firewall_manager_conflicts",
+		Help:      "Number of load balancers whose WebACL set by the controller was replaced by a WebACL of AWS Firewall Manager.",
+	})
+
+	// listenerDrift is the number of load balancers whose listeners were
+	// changed outside of CloudFormation.
+	listenerDrift = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "listener_drift",
+		Help:      "Number of load balancers whose HTTPS listener, certificates or attributes differ from their stacks.",
+	})
+
+	// loadBalancersWaitingForQuota is the number of load balancers whose
+	// creation is deferred because the quota of the account is reached.
+	loadBalancersWaitingForQuota = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "load_balancers_waiting_for_quota",
+		Help:      "Number of load balancers waiting to be created because the quota of the account is reached.",
+	})
+
+	// statusUpdatesPending is the number of ingresses waiting for the
+	// update of their status.
+	statusUpdatesPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "ingress_status_updates_pending",
+		Help:      "Number of ingresses and route groups whose status update to the DNS name of their load balancer is queued.",
+	})
+
+	// managedLoadBalancers is the number of stacks of load balancers
+	// managed by the controller.
+	managedLoadBalancers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "managed_load_balancers",
+		Help:      "Number of load balancers managed by the controller.",
+	})
+
+	// drainingInstances is the number of instances deregistered from the
+	// target groups whose connections are still draining.
+	drainingInstances = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "draining_instances",
+		Help:      "Number of instances deregistered from the target groups which are still draining.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(stackErrors, ingressErrors, ingressAnnotationWarnings, admissionReviews, loadBalancerQuotaExceeded, loadBalancerLimitExceeded, loadBalancersWaitingForQuota, managedLoadBalancers, firewallManagerConflicts, listenerDrift, statusUpdatesPending, drainingInstances)
+}
+
+// seriesLabels remembers the label values of the series of a metric vector
+// with two labels, so the series of resources which don't exist anymore can
+// be deleted.
+type seriesLabels struct {
+	mu     sync.Mutex
+	labels map[[2]string]bool
+}
+
+func newSeriesLabels() *seriesLabels {
+	return &seriesLabels{labels: make(map[[2]string]bool)}
+}
+
+// add remembers the label values of a series.
+func (s *seriesLabels) add(first, second string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.labels[[2]string{first, second}] = true
+}
+
+// prune deletes the series of the vector whose label values aren't kept.
+func (s *seriesLabels) prune(vec interface{ DeleteLabelValues(...string) bool }, keep func(first, second string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := make(map[[2]string]bool)
+	for labels := range s.labels {
+		if keep(labels[0], labels[1]) {
+			pruned[labels] = true
+		} else {
+			vec.DeleteLabelValues(labels[0], labels[1])
+		}
+	}
+	s.labels = pruned
+}
+
+var (
+	stackErrorLabels        = newSeriesLabels()
+	ingressErrorLabels      = newSeriesLabels()
+	annotationWarningLabels = newSeriesLabels()
+)
+
+// countStackError counts a failed operation on the stack.
+func countStackError(stack, operation string) {
+	stackErrorLabels.add(stack, operation)
+	stackErrors.WithLabelValues(stack, operation).Inc()
+}
+
+// countIngressError counts a failed update of the ingress.
+func countIngressError(ing *kubernetes.Ingress) {
+	ingressErrorLabels.add(ing.Namespace, ing.Name)
+	ingressErrors.WithLabelValues(ing.Namespace, ing.Name).Inc()
+}
+
+// pruneErrorMetrics deletes the series of the error counters of the stacks
+// and ingresses which don't exist anymore. Failures creating stacks aren't
+// counted for a stack name and are kept.
+func pruneErrorMetrics(stacks []*aws.Stack, ingresses []*kubernetes.Ingress) {
+	stackNames := make(map[string]bool, len(stacks))
+	for _, stack := range stacks {
+		stackNames[stack.Name] = true
+	}
+	stackErrorLabels.prune(stackErrors, func(stack, _ string) bool {
+		return stack == "" || stackNames[stack]
+	})
+
+	names := make(map[[2]string]bool, len(ingresses))
+	for _, ing := range ingresses {
+		names[[2]string{ing.Namespace, ing.Name}] = true
+	}
+	ingressErrorLabels.prune(ingressErrors, func(namespace, name string) bool {
+		return names[[2]string{namespace, name}]
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+// migrateOwnership migrates the tags of the stacks of the previous
+// controller ID and cluster ID to the current ones, without recreating the
+// load balancers. The controller of the previous IDs must be stopped first,
+// otherwise it creates new load balancers for the ingresses of the migrated
+// stacks.
+func migrateOwnership(awsAdapter *aws.Adapter) error {
+	stacks, err := awsAdapter.FindManagedStacks()
+	if err != nil {
+		return err
+	}
+
+	var migrated, failed int
+	for _, stack := range stacks {
+		if !stack.PreviousOwner {
+			continue
+		}
+		stackID, err := awsAdapter.MigrateStackOwnership(stack)
+		if err != nil {
+			log.Errorf("failed to migrate the ownership of stack %q: %v", stack.Name, err)
+			failed++
+			continue
+		}
+		log.Infof("migrated the ownership of stack %q", stackID)
+		migrated++
+	}
+
+	log.Infof("migrated the ownership of %d stack(s)", migrated)
+	if failed > 0 {
+		return fmt.Errorf("failed to migrate the ownership of %d stack(s)", failed)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+// updatePodReadiness sets the condition of the target health readiness gate
+// of the pods matching the selector, once the instance they run on is
+// healthy in the target groups of the stacks. Pods stay ready when their
+// instance gets unhealthy later, e.g. in the target group of a new stack,
+// so that the pods of all instances don't become unready together.
+func updatePodReadiness(awsAdapter *aws.Adapter, kubeAdapter *kubernetes.Adapter, stacks []*aws.Stack, selector string) {
+	pods, err := kubeAdapter.ListReadinessGatedPods(selector)
+	if err != nil {
+		log.Errorf("Failed to list the pods with readiness gates: %v", err)
+		return
+	}
+
+	waiting := make([]*kubernetes.Pod, 0, len(pods))
+	for _, p := range pods {
+		if !p.TargetHealthy() {
+			waiting = append(waiting, p)
+		}
+	}
+	if len(waiting) == 0 {
+		return
+	}
+
+	health, hasTargetGroups, err := awsAdapter.TargetHealthByIP(withoutDrainingStacks(stacks))
+	if err != nil {
+		log.Errorf("Failed to check the readiness of %d pod(s): %v", len(waiting), err)
+		return
+	}
+
+	now := clock.Now()
+	for _, p := range waiting {
+		ready, reason, message := podTargetHealth(p.HostIP, health, hasTargetGroups)
+		err := kubeAdapter.UpdatePodReadiness(p, ready, reason, message, now)
+		switch {
+		case err == kubernetes.ErrUpdateNotNeeded:
+		case err != nil:
+			log.Errorf("Failed to update the readiness of pod %s: %v", p, err)
+		case ready:
+			log.Infof("Pod %s is ready: %s", p, message)
+		default:
+			log.Debugf("Pod %s isn't ready: %s", p, message)
+		}
+	}
+}
+
+// podTargetHealth returns the readiness of a pod on the instance with the
+// host IP, and the reason and message of the condition of its readiness gate.
+func podTargetHealth(hostIP string, health map[string]*aws.TargetHealth, hasTargetGroups bool) (bool, string, string) {
+	if !hasTargetGroups {
+		return true, "NoTargetGroups", "there are no target groups"
+	}
+	h, ok := health[hostIP]
+	if !ok {
+		return false, "NotRegistered", fmt.Sprintf("the instance with IP %s isn't registered in any target group", hostIP)
+	}
+	if !h.Healthy {
+		return false, "Unhealthy", fmt.Sprintf("instance %s is %s", h.InstanceID, h.Reason)
+	}
+	return true, "Healthy", fmt.Sprintf("instance %s is healthy in all target groups", h.InstanceID)
+}
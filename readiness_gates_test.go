@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+func TestPodTargetHealth(t *testing.T) {
+	health := map[string]*aws.TargetHealth{
+		"10.0.0.1": {InstanceID: "i-1", Healthy: true},
+		"10.0.0.2": {InstanceID: "i-2", Reason: "initial in target group arn:tg"},
+	}
+
+	for _, test := range []struct {
+		hostIP          string
+		hasTargetGroups bool
+		ready           bool
+		reason          string
+	}{
+		{hostIP: "10.0.0.1", hasTargetGroups: true, ready: true, reason: "Healthy"},
+		{hostIP: "10.0.0.2", hasTargetGroups: true, reason: "Unhealthy"},
+		{hostIP: "10.0.0.3", hasTargetGroups: true, reason: "NotRegistered"},
+		{hostIP: "10.0.0.3", ready: true, reason: "NoTargetGroups"},
+	} {
+		ready, reason, message := podTargetHealth(test.hostIP, health, test.hasTargetGroups)
+		require.Equal(t, test.ready, ready, test.hostIP)
+		require.Equal(t, test.reason, reason, test.hostIP)
+		require.NotEmpty(t, message)
+	}
+}
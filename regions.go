@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/certs"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+// region is a region the controller provisions the load balancers of the
+// ingresses in, with its own clients, certificates and managed stacks. The
+// account of an assumed role is a region of its own.
+type region struct {
+	awsAdapter    *aws.Adapter
+	certsProvider certs.CertificatesProvider
+	stacks        *stackCache
+	roleARN       string
+}
+
+// additionalRegions are the regions from --additional-region, besides the
+// one the controller runs in. They're set up on start.
+var additionalRegions []*region
+
+// assumedRoles are the accounts of the roles assumed for ingresses by role
+// ARN. The ones of --assume-role-arn and --managed-role-arn, the only roles
+// ingresses may assume, are set up on start and kept, so their load
+// balancers are deleted once no ingress uses the role anymore.
+var assumedRoles = make(map[string]*region)
+
+// newAdditionalRegion sets up the adapter of the cluster in the VPC of the
+// region with the settings of the flags, and the cache of its certificates.
+func newAdditionalRegion(name, vpcID, clusterID, customFilter string) (*region, error) {
+	awsAdapter, err := aws.NewAdapterInRegion(clusterID, controllerID, vpcID, name, awsEndpoint, debugFlag, disableInstrumentedHttpClient)
+	if err != nil {
+		return nil, err
+	}
+	awsAdapter = configureAWSAdapter(awsAdapter, customFilter)
+	if serviceQuotas {
+		applyServiceQuotas(awsAdapter)
+	}
+	if err := awsAdapter.ValidateAccessLogsBucket(); err != nil {
+		log.Errorf("Access logs of the load balancers in region %s won't work: %v", name, err)
+	}
+
+	certsProvider, err := certs.NewJitteredCachingProvider(
+		certPollingInterval,
+		intervalJitter.apply,
+		blacklistCertArnMap,
+		awsAdapter.NewACMCertificateProvider(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &region{
+		awsAdapter:    awsAdapter,
+		certsProvider: certsProvider,
+		stacks:        &stackCache{interval: stackPollingInterval, stale: true},
+	}, nil
+}
+
+// newAssumedRoleRegion sets up the adapter of the account of the role in
+// the region of the controller, and the cache of its certificates.
+func newAssumedRoleRegion(awsAdapter *aws.Adapter, roleARN string) (*region, error) {
+	roleAdapter, err := awsAdapter.AssumeRole(roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	certsProvider, err := certs.NewJitteredCachingProvider(
+		certPollingInterval,
+		intervalJitter.apply,
+		blacklistCertArnMap,
+		roleAdapter.NewACMCertificateProvider(),
+		roleAdapter.NewIAMCertificateProvider(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &region{
+		awsAdapter:    roleAdapter,
+		certsProvider: certsProvider,
+		stacks:        &stackCache{interval: stackPollingInterval, stale: true},
+		roleARN:       roleARN,
+	}, nil
+}
+
+// startupRoleARNs returns the sorted ARNs of the roles whose accounts are
+// managed from the start, the role of --assume-role-arn and the ones of
+// --managed-role-arn.
+func startupRoleARNs(defaultRoleARN string, managed []string) []string {
+	seen := make(map[string]bool)
+	roleARNs := make([]string, 0, len(managed)+1)
+	for _, roleARN := range append([]string{defaultRoleARN}, managed...) {
+		if roleARN == "" || seen[roleARN] {
+			continue
+		}
+		seen[roleARN] = true
+		roleARNs = append(roleARNs, roleARN)
+	}
+	sort.Strings(roleARNs)
+	return roleARNs
+}
+
+// setUpAssumedRoles sets up the accounts of the roles on start, so the
+// stacks in them are found without any ingress assuming the roles.
+func setUpAssumedRoles(awsAdapter *aws.Adapter, roleARNs []string) error {
+	for _, roleARN := range roleARNs {
+		r, err := newAssumedRoleRegion(awsAdapter, roleARN)
+		if err != nil {
+			return fmt.Errorf("failed to assume role %s: %v", roleARN, err)
+		}
+		assumedRoles[roleARN] = r
+	}
+	return nil
+}
+
+// ingressesByRole splits the ingresses into the ones served by load
+// balancers of the account of the controller and the ones of the accounts
+// of the assumed roles, by role ARN. The role of --assume-role-arn is
+// assumed for ingresses without a role of their own. Ingresses assuming a
+// role which isn't allowed are left out, so they can't make the controller
+// create load balancers in any account trusting it.
+func ingressesByRole(ingresses []*kubernetes.Ingress, defaultRoleARN string, allowedRoleARNs []string) ([]*kubernetes.Ingress, map[string][]*kubernetes.Ingress) {
+	allowed := make(map[string]bool, len(allowedRoleARNs))
+	for _, roleARN := range allowedRoleARNs {
+		allowed[roleARN] = true
+	}
+
+	own := make([]*kubernetes.Ingress, 0, len(ingresses))
+	byRole := make(map[string][]*kubernetes.Ingress)
+	for _, ing := range ingresses {
+		roleARN := ing.AssumeRoleARN
+		if roleARN == "" {
+			roleARN = defaultRoleARN
+		} else if !allowed[roleARN] {
+			log.Errorf("Ignoring %s %s: role %s is not allowed to be assumed", ing.ResourceType(), ing, roleARN)
+			ingressEvents.event(ing, kubernetes.EventTypeWarning, eventReasonRoleNotAllowed,
+				fmt.Sprintf("Ignored, role %s is neither the one of --assume-role-arn nor one of --managed-role-arn", roleARN))
+			continue
+		}
+		if roleARN == "" {
+			own = append(own, ing)
+			continue
+		}
+		byRole[roleARN] = append(byRole[roleARN], ing)
+	}
+	return own, byRole
+}
+
+// assumedRoleRegions returns the accounts of all roles assumed so far, and
+// sets up the ones of new roles of the ingresses, sorted by role ARN. It
+// returns false if any of them can't be set up.
+func assumedRoleRegions(awsAdapter *aws.Adapter, byRole map[string][]*kubernetes.Ingress) ([]*region, bool) {
+	complete := true
+	for roleARN := range byRole {
+		if _, ok := assumedRoles[roleARN]; ok {
+			continue
+		}
+		r, err := newAssumedRoleRegion(awsAdapter, roleARN)
+		if err != nil {
+			log.Errorf("Failed to assume role %s: %v", roleARN, err)
+			complete = false
+			continue
+		}
+		assumedRoles[roleARN] = r
+	}
+
+	roleARNs := make([]string, 0, len(assumedRoles))
+	for roleARN := range assumedRoles {
+		roleARNs = append(roleARNs, roleARN)
+	}
+	sort.Strings(roleARNs)
+
+	regions := make([]*region, 0, len(roleARNs))
+	for _, roleARN := range roleARNs {
+		regions = append(regions, assumedRoles[roleARN])
+	}
+	return regions, complete
+}
+
+// regionalModel is the model of the load balancers of a region built by a
+// reconciliation.
+type regionalModel struct {
+	region        *region
+	primary       bool
+	stacks        []*aws.Stack
+	loadBalancers []*loadBalancer
+}
+
+// name returns the name of the region for logging.
+func (r *region) name() string {
+	name := r.awsAdapter.Region()
+	if name == "" {
+		name = "default"
+	}
+	if r.roleARN != "" {
+		name += " (role " + r.roleARN + ")"
+	}
+	return name
+}
+
+// buildModel lists the stacks, the instances and the certificates of the
+// region and builds the model of its load balancers for the ingresses.
+func (r *region) buildModel(
+	ingresses []*kubernetes.Ingress,
+	cwAlarms aws.CloudWatchAlarmList,
+	internalDomains []string,
+	templateFragments aws.TemplateFragments,
+	certsPerALB int,
+	certTTL time.Duration,
+	globalWAFACL string,
+	primary bool,
+) (*regionalModel, error) {
+	awsAdapter := r.awsAdapter
+
+	stacks, err := r.stacks.get(clock.Now(), awsAdapter.FindManagedStacks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed stacks: %v", err)
+	}
+	log.Infof("Found %d stack(s) in region %s", len(stacks), r.name())
+
+	err = awsAdapter.UpdateAutoScalingGroupsAndInstances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instances from EC2: %v", err)
+	}
+
+	certificateSummaries, err := r.certsProvider.GetCertificates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificates: %v", err)
+	}
+
+	awsAdapter.UpdateTargetGroupsAndAutoScalingGroups(withoutDrainingStacks(stacks))
+	log.Infof("Found %d owned auto scaling group(s)", len(awsAdapter.OwnedAutoScalingGroups))
+	log.Infof("Found %d targeted auto scaling group(s)", len(awsAdapter.TargetedAutoScalingGroups))
+	log.Infof("Found %d single instance(s)", len(awsAdapter.SingleInstances()))
+	draining := awsAdapter.DrainingInstances()
+	log.Infof("Found %d draining instance(s)", len(draining))
+	if primary {
+		drainingInstances.Set(float64(len(draining)))
+	}
+	log.Infof("Found %d EC2 instance(s)", awsAdapter.CachedInstances())
+	log.Infof("Found %d certificate(s)", len(certificateSummaries))
+
+	certs := &Certificates{certificateSummaries: certificateSummaries}
+	model := buildManagedModel(certs, certsPerALB, certTTL, ingresses, stacks, cwAlarms, globalWAFACL)
+	if namespaceTags {
+		attachNamespaces(model)
+	}
+	if len(resourceTagsTemplate) > 0 || len(additionalResourceTags) > 0 {
+		attachTemplateResourceTags(model, resourceTagsTemplate, additionalResourceTags)
+	}
+	if checkFirewallManager {
+		checkFirewallManagerConflicts(awsAdapter, model)
+	}
+	if primary {
+		updateConsolidationReport(model, certsPerALB, loadBalancerMonthlyCost)
+		attachedCertificates.update(model)
+	}
+	model = enforceMaxLoadBalancers(model, maxLoadBalancers)
+	model = deferCreationsOverQuota(awsAdapter, model, loadBalancerQuotas)
+	if len(internalDomains) > 0 {
+		attachInternalDomains(model, internalDomains)
+	}
+	if len(templateFragments) > 0 {
+		attachTemplateFragments(model, templateFragments)
+	}
+
+	return &regionalModel{
+		region:        r,
+		primary:       primary,
+		stacks:        stacks,
+		loadBalancers: model,
+	}, nil
+}
+
+// ingressDNSNames collects the DNS names of the load balancers of the
+// ingresses in all regions during a reconciliation, so that the status of
+// an ingress lists all of them, the one of the primary region first.
+type ingressDNSNames struct {
+	ingresses map[string]*kubernetes.Ingress
+	dnsNames  map[string]map[int]string
+	keys      []string
+	regions   int
+}
+
+func newIngressDNSNames(regions int) *ingressDNSNames {
+	return &ingressDNSNames{
+		ingresses: make(map[string]*kubernetes.Ingress),
+		dnsNames:  make(map[string]map[int]string),
+		regions:   regions,
+	}
+}
+
+// set records the DNS name of the load balancer of the ingress in the
+// region with the index, replacing a previous one of the region.
+func (n *ingressDNSNames) set(region int, ing *kubernetes.Ingress, dnsName string) {
+	key := statusUpdateKey(ing)
+	if _, ok := n.dnsNames[key]; !ok {
+		n.keys = append(n.keys, key)
+		n.dnsNames[key] = make(map[int]string)
+	}
+	n.ingresses[key] = ing
+	n.dnsNames[key][region] = dnsName
+}
+
+// forRegion returns the function recording the DNS names of the region
+// with the index.
+func (n *ingressDNSNames) forRegion(region int) func(*kubernetes.Ingress, string) {
+	return func(ing *kubernetes.Ingress, dnsName string) {
+		n.set(region, ing, dnsName)
+	}
+}
+
+// get returns the DNS names of the ingress in the order of the regions,
+// without duplicates, e.g. of cluster local ingresses.
+func (n *ingressDNSNames) get(key string) []string {
+	var dnsNames []string
+	seen := make(map[string]bool)
+	for i := 0; i < n.regions; i++ {
+		dnsName, ok := n.dnsNames[key][i]
+		if !ok || seen[dnsName] {
+			continue
+		}
+		seen[dnsName] = true
+		dnsNames = append(dnsNames, dnsName)
+	}
+	return dnsNames
+}
+
+// queue queues the status updates of the ingresses to their DNS names.
+func (n *ingressDNSNames) queue(q *statusUpdateQueue) {
+	for _, key := range n.keys {
+		q.add(n.ingresses[key], n.get(key)...)
+	}
+}
+
+// parseAdditionalRegions parses the VPCs of the additional regions from
+// --additional-region. The regions are sorted, so the DNS names of the
+// load balancers are always listed in the same order.
+func parseAdditionalRegions(regions map[string]string) ([]string, error) {
+	names := make([]string, 0, len(regions))
+	for name, vpcID := range regions {
+		if name == "" || vpcID == "" || strings.ContainsAny(name, " /") {
+			return nil, fmt.Errorf("invalid additional region %q with VPC %q", name, vpcID)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
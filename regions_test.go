@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+func TestIngressDNSNames(t *testing.T) {
+	f := &fakeStatusUpdates{}
+	q := newStatusUpdateQueue(f.update, 10)
+
+	a := &kubernetes.Ingress{Namespace: "ns", Name: "a"}
+	b := &kubernetes.Ingress{Namespace: "ns", Name: "b"}
+	c := &kubernetes.Ingress{Namespace: "ns", Name: "c"}
+	local := &kubernetes.Ingress{Namespace: "ns", Name: "local", Hostname: "lb.example.org"}
+
+	n := newIngressDNSNames(2)
+	primary, additional := n.forRegion(0), n.forRegion(1)
+	// the DNS name of the primary region comes first regardless of the
+	// order of the reconciliation
+	additional(a, "lb.eu-west-1.example.org")
+	primary(a, "lb.example.org")
+	primary(b, "lb.example.org")
+	additional(c, "lb.eu-west-1.example.org")
+	primary(local, kubernetes.DefaultClusterLocalDomain)
+	additional(local, kubernetes.DefaultClusterLocalDomain)
+
+	n.queue(q)
+	require.Equal(t, 4, q.flush(clock.Now()))
+	require.Equal(t, []string{
+		"a=lb.example.org,lb.eu-west-1.example.org",
+		"b=lb.example.org",
+		"c=lb.eu-west-1.example.org",
+		"local=" + kubernetes.DefaultClusterLocalDomain,
+	}, f.updated)
+}
+
+func TestParseAdditionalRegions(t *testing.T) {
+	names, err := parseAdditionalRegions(map[string]string{
+		"us-east-1": "vpc-2",
+		"eu-west-1": "vpc-1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"eu-west-1", "us-east-1"}, names)
+
+	names, err = parseAdditionalRegions(nil)
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	for _, regions := range []map[string]string{
+		{"eu-west-1": ""},
+		{"": "vpc-1"},
+		{"eu west 1": "vpc-1"},
+	} {
+		_, err := parseAdditionalRegions(regions)
+		require.Error(t, err, "%v", regions)
+	}
+}
+
+func TestIngressesByRole(t *testing.T) {
+	const role, defaultRole = "arn:aws:iam::123456789012:role/a", "arn:aws:iam::123456789012:role/default"
+	a := &kubernetes.Ingress{Name: "a", AssumeRoleARN: role}
+	b := &kubernetes.Ingress{Name: "b"}
+
+	own, byRole := ingressesByRole([]*kubernetes.Ingress{a, b}, "", []string{role})
+	require.Equal(t, []*kubernetes.Ingress{b}, own)
+	require.Equal(t, map[string][]*kubernetes.Ingress{role: {a}}, byRole)
+
+	own, byRole = ingressesByRole([]*kubernetes.Ingress{a, b}, defaultRole, []string{defaultRole, role})
+	require.Empty(t, own)
+	require.Equal(t, map[string][]*kubernetes.Ingress{role: {a}, defaultRole: {b}}, byRole)
+}
+
+func TestIngressesByRoleNotAllowed(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, _ string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s", ing, eventType, reason))
+			return nil
+		},
+	}
+
+	const role, other = "arn:aws:iam::123456789012:role/a", "arn:aws:iam::210987654321:role/other"
+	a := &kubernetes.Ingress{Namespace: "ns", Name: "a", AssumeRoleARN: role}
+	b := &kubernetes.Ingress{Namespace: "ns", Name: "b", AssumeRoleARN: other}
+	c := &kubernetes.Ingress{Namespace: "ns", Name: "c"}
+
+	// the ingress assuming a role outside of the allowed ones is neither
+	// served in the account of the controller nor in the one of the role
+	own, byRole := ingressesByRole([]*kubernetes.Ingress{a, b, c}, "", []string{role})
+	require.Equal(t, []*kubernetes.Ingress{c}, own)
+	require.Equal(t, map[string][]*kubernetes.Ingress{role: {a}}, byRole)
+	require.Equal(t, []string{"ns/b Warning RoleNotAllowed"}, recorded)
+
+	defer func(roles map[string]*region) { assumedRoles = roles }(assumedRoles)
+	assumedRoles = map[string]*region{role: {roleARN: role}}
+	regions, complete := assumedRoleRegions(nil, byRole)
+	require.True(t, complete)
+	require.Len(t, regions, 1)
+	require.Equal(t, role, regions[0].roleARN)
+}
+
+func TestStartupRoleARNs(t *testing.T) {
+	const a, b = "arn:aws:iam::123456789012:role/a", "arn:aws:iam::123456789012:role/b"
+	require.Empty(t, startupRoleARNs("", nil))
+	require.Equal(t, []string{a}, startupRoleARNs(a, nil))
+	require.Equal(t, []string{a, b}, startupRoleARNs(b, []string{a, b}))
+}
+
+func TestAssumedRoleRegionsWithoutIngresses(t *testing.T) {
+	defer func(roles map[string]*region) { assumedRoles = roles }(assumedRoles)
+
+	// the accounts of the roles set up on start are reconciled without
+	// any ingress assuming the roles, so their stacks are deleted
+	const a, b = "arn:aws:iam::123456789012:role/a", "arn:aws:iam::123456789012:role/b"
+	assumedRoles = map[string]*region{
+		b: {roleARN: b},
+		a: {roleARN: a},
+	}
+	regions, complete := assumedRoleRegions(nil, nil)
+	require.True(t, complete)
+	require.Len(t, regions, 2)
+	require.Equal(t, a, regions[0].roleARN)
+	require.Equal(t, b, regions[1].roleARN)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"time"
+
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+// stackCache keeps the managed stacks between reconciliations, so the
+// stacks are listed every --stack-polling-interval instead of every
+// --polling-interval. The stacks are listed again in the next
+// reconciliation once the controller changed or waits for any of them.
+type stackCache struct {
+	interval time.Duration
+	stacks   []*aws.Stack
+	listed   time.Time
+	stale    bool
+}
+
+// managedStacks are the stacks found by the worker loop.
+var managedStacks = &stackCache{stale: true}
+
+// get returns the cached stacks, or the stacks listed by the function if
+// the cache is stale or older than the interval.
+func (c *stackCache) get(now time.Time, list func() ([]*aws.Stack, error)) ([]*aws.Stack, error) {
+	if c.interval > 0 && !c.stale && now.Sub(c.listed) < c.interval {
+		return c.stacks, nil
+	}
+
+	stacks, err := list()
+	if err != nil {
+		return nil, err
+	}
+	c.stacks = stacks
+	c.listed = now
+	c.stale = false
+	return stacks, nil
+}
+
+// invalidate makes the next reconciliation list the stacks.
+func (c *stackCache) invalidate() {
+	c.stale = true
+}
+
+// settled returns true if the load balancer needs no operation on its
+// stack, so the cached stack is still accurate after the reconciliation.
+func (l *loadBalancer) settled() bool {
+	switch l.Status() {
+	case ready, paused:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+)
+
+func TestStackCache(t *testing.T) {
+	listed := 0
+	var listErr error
+	list := func() ([]*aws.Stack, error) {
+		listed++
+		return []*aws.Stack{{Name: "stack"}}, listErr
+	}
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	// without an interval the stacks are listed every time
+	c := &stackCache{stale: true}
+	for i := 0; i < 2; i++ {
+		_, err := c.get(now, list)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, listed)
+
+	listed = 0
+	c = &stackCache{interval: 5 * time.Minute, stale: true}
+	stacks, err := c.get(now, list)
+	require.NoError(t, err)
+	require.Len(t, stacks, 1)
+	require.Equal(t, 1, listed)
+
+	cached, err := c.get(now.Add(time.Minute), list)
+	require.NoError(t, err)
+	require.Equal(t, stacks, cached)
+	require.Equal(t, 1, listed)
+
+	c.invalidate()
+	_, err = c.get(now.Add(2*time.Minute), list)
+	require.NoError(t, err)
+	require.Equal(t, 2, listed)
+
+	_, err = c.get(now.Add(7*time.Minute), list)
+	require.NoError(t, err)
+	require.Equal(t, 3, listed)
+
+	// failures are retried in the next reconciliation
+	listErr = errors.New("throttled")
+	c.invalidate()
+	_, err = c.get(now.Add(8*time.Minute), list)
+	require.Error(t, err)
+	listErr = nil
+	_, err = c.get(now.Add(8*time.Minute), list)
+	require.NoError(t, err)
+	require.Equal(t, 5, listed)
+}
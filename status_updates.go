@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+const (
+	minStatusUpdateRetryBackoff = 10 * time.Second
+	maxStatusUpdateRetryBackoff = 5 * time.Minute
+)
+
+// statusUpdateQueue collects the load balancer DNS names to set in the
+// status of ingresses and route groups, and the states to record in their
+// annotations, and applies them in batches of a limited size per interval,
+// so that moving many ingresses to another load balancer doesn't hit the API
+// server with a burst of writes. Only the latest update of an ingress is
+// kept, and failed updates are retried with a backoff per ingress.
+type statusUpdateQueue struct {
+	mu          sync.Mutex
+	update      func(*kubernetes.Ingress, ...string) error
+	updateState func(*kubernetes.Ingress, kubernetes.IngressState, time.Time) error
+	batchSize   int
+	pending     map[string]*statusUpdate
+	order       []string
+	retries     *retryBackoff
+}
+
+// statusUpdate is either the update of the DNS names or of the state of an
+// ingress.
+type statusUpdate struct {
+	ingress  *kubernetes.Ingress
+	dnsNames []string
+	state    *kubernetes.IngressState
+}
+
+func newStatusUpdateQueue(update func(*kubernetes.Ingress, ...string) error, batchSize int) *statusUpdateQueue {
+	return &statusUpdateQueue{
+		update:    update,
+		batchSize: batchSize,
+		pending:   make(map[string]*statusUpdate),
+		retries:   newRetryBackoff(minStatusUpdateRetryBackoff, maxStatusUpdateRetryBackoff),
+	}
+}
+
+// withStateUpdates enables recording the states of ingresses with the
+// update function.
+func (q *statusUpdateQueue) withStateUpdates(updateState func(*kubernetes.Ingress, kubernetes.IngressState, time.Time) error) *statusUpdateQueue {
+	q.updateState = updateState
+	return q
+}
+
+// ingressStatusUpdates is the queue of the status updates of the
+// controller, it's set up on start.
+var ingressStatusUpdates *statusUpdateQueue
+
+func statusUpdateKey(ing *kubernetes.Ingress) string {
+	return ing.ResourceType() + " " + ing.String()
+}
+
+// add queues the update of the ingress to the DNS names, e.g. of the load
+// balancers in multiple regions, replacing a pending update of the ingress.
+// Ingresses which already have the DNS names are skipped.
+func (q *statusUpdateQueue) add(ing *kubernetes.Ingress, dnsNames ...string) {
+	key := statusUpdateKey(ing)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if hasDNSNames(ing, dnsNames) {
+		// drop an obsolete update, e.g. after moving back to the previous
+		// load balancer
+		if _, ok := q.pending[key]; ok {
+			q.remove(map[string]bool{key: true})
+		}
+		return
+	}
+
+	if _, ok := q.pending[key]; !ok {
+		q.order = append(q.order, key)
+	}
+	q.pending[key] = &statusUpdate{ingress: ing, dnsNames: dnsNames}
+	statusUpdatesPending.Set(float64(len(q.pending)))
+}
+
+// hasDNSNames returns true if the status of the ingress has the DNS names,
+// and the ports and IP addresses of the load balancers if published.
+func hasDNSNames(ing *kubernetes.Ingress, dnsNames []string) bool {
+	if ing.StatusOutdated() {
+		return false
+	}
+	if len(dnsNames) == 1 {
+		dnsName := dnsNames[0]
+		return ing.Hostname == dnsName || (dnsName == kubernetes.DefaultClusterLocalDomain && ing.Hostname == "")
+	}
+	return reflect.DeepEqual(ing.LoadBalancerHostnames, dnsNames)
+}
+
+func stateUpdateKey(ing *kubernetes.Ingress) string {
+	return statusUpdateKey(ing) + " state"
+}
+
+// addState queues recording the state in the annotations of the ingress,
+// replacing a pending update of its state. It does nothing unless state
+// updates are enabled or if the ingress already records the state.
+func (q *statusUpdateQueue) addState(ing *kubernetes.Ingress, state kubernetes.IngressState) {
+	if q.updateState == nil {
+		return
+	}
+	key := stateUpdateKey(ing)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if ing.HasState(state) {
+		if _, ok := q.pending[key]; ok {
+			q.remove(map[string]bool{key: true})
+		}
+		return
+	}
+
+	if _, ok := q.pending[key]; !ok {
+		q.order = append(q.order, key)
+	}
+	q.pending[key] = &statusUpdate{ingress: ing, state: &state}
+	statusUpdatesPending.Set(float64(len(q.pending)))
+}
+
+// retain drops the pending updates and the backoff of the ingresses which
+// aren't in the list of keys anymore, e.g. because they were deleted.
+func (q *statusUpdateQueue) retain(keys map[string]bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	obsolete := make(map[string]bool)
+	retained := make(map[string]bool)
+	for key, u := range q.pending {
+		if keys[statusUpdateKey(u.ingress)] {
+			retained[key] = true
+		} else {
+			obsolete[key] = true
+		}
+	}
+	q.remove(obsolete)
+	q.retries.prune(retained)
+}
+
+// remove drops the pending updates of the keys. The lock must be held.
+func (q *statusUpdateQueue) remove(keys map[string]bool) {
+	if len(keys) == 0 {
+		return
+	}
+
+	order := make([]string, 0, len(q.order))
+	pending := make(map[string]*statusUpdate, len(q.pending))
+	for _, key := range q.order {
+		if !keys[key] {
+			order = append(order, key)
+			pending[key] = q.pending[key]
+		}
+	}
+	q.order = order
+	q.pending = pending
+	statusUpdatesPending.Set(float64(len(q.pending)))
+}
+
+// flush applies up to a batch of the pending updates which aren't backing
+// off from previous failures, in the order they were queued. It returns the
+// number of updates attempted.
+func (q *statusUpdateQueue) flush(now time.Time) int {
+	q.mu.Lock()
+	var batch []string
+	updates := make(map[string]*statusUpdate)
+	for _, key := range q.order {
+		if len(batch) == q.batchSize {
+			break
+		}
+		if !q.retries.allowed(key, now) {
+			continue
+		}
+		batch = append(batch, key)
+		updates[key] = q.pending[key]
+	}
+	q.mu.Unlock()
+
+	// the API server is called without holding the lock, so updates can be
+	// queued meanwhile
+	errs := make(map[string]error, len(batch))
+	for _, key := range batch {
+		errs[key] = q.apply(updates[key], now)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	done := make(map[string]bool, len(batch))
+	for _, key := range batch {
+		if errs[key] != nil {
+			delay := q.retries.failure(key, now)
+			log.Infof("retrying the status update of %s in %s", key, delay)
+			continue
+		}
+		q.retries.success(key)
+		// keep other updates queued while the API server was called
+		if p, ok := q.pending[key]; ok && p.equal(updates[key]) {
+			done[key] = true
+		}
+	}
+	q.remove(done)
+
+	return len(batch)
+}
+
+func (u *statusUpdate) equal(other *statusUpdate) bool {
+	return reflect.DeepEqual(u.dnsNames, other.dnsNames) && reflect.DeepEqual(u.state, other.state)
+}
+
+func (q *statusUpdateQueue) apply(u *statusUpdate, now time.Time) error {
+	ing := u.ingress
+	if u.state != nil {
+		err := q.updateState(ing, *u.state, now)
+		switch {
+		case err == kubernetes.ErrUpdateNotNeeded:
+		case err != nil:
+			log.Errorf("Failed to record the state of ingress: %v", err)
+			countIngressError(ing)
+			return err
+		default:
+			log.Debugf("recorded the state of ingress %v: stack %q", ing, u.state.StackName)
+		}
+		return nil
+	}
+
+	err := q.update(ing, u.dnsNames...)
+	switch {
+	case err == kubernetes.ErrUpdateNotNeeded:
+		log.Debugf("Ingress update not needed %v with DNS name %q", ing, u.dnsNames)
+	case err != nil:
+		log.Errorf("Failed to update ingress: %v", err)
+		countIngressError(ing)
+		return err
+	default:
+		log.Infof("updated ingress %v with DNS name %q", ing, u.dnsNames)
+	}
+	return nil
+}
+
+// run flushes a batch of updates every interval until the context is
+// cancelled.
+func (q *statusUpdateQueue) run(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-clock.After(interval):
+			q.flush(clock.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+type fakeStatusUpdates struct {
+	updated []string
+	failing map[string]bool
+}
+
+func (f *fakeStatusUpdates) update(ing *kubernetes.Ingress, dnsNames ...string) error {
+	if f.failing[ing.Name] {
+		return errors.New("failed")
+	}
+	f.updated = append(f.updated, ing.Name+"="+strings.Join(dnsNames, ","))
+	return nil
+}
+
+func TestStatusUpdateQueueBatches(t *testing.T) {
+	f := &fakeStatusUpdates{}
+	q := newStatusUpdateQueue(f.update, 2)
+	now := time.Now()
+
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "a"}, "old.example.org")
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "b"}, "lb.example.org")
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "c"}, "lb.example.org")
+	// only the latest DNS name of an ingress is set
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "a"}, "lb.example.org")
+	// ingresses with the DNS name are skipped
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "d", Hostname: "lb.example.org"}, "lb.example.org")
+
+	require.Equal(t, 2, q.flush(now))
+	require.Equal(t, []string{"a=lb.example.org", "b=lb.example.org"}, f.updated)
+
+	require.Equal(t, 1, q.flush(now))
+	require.Equal(t, []string{"a=lb.example.org", "b=lb.example.org", "c=lb.example.org"}, f.updated)
+
+	require.Equal(t, 0, q.flush(now))
+}
+
+func TestStatusUpdateQueueMultipleDNSNames(t *testing.T) {
+	f := &fakeStatusUpdates{}
+	q := newStatusUpdateQueue(f.update, 10)
+
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "a", Hostname: "lb.example.org", LoadBalancerHostnames: []string{"lb.example.org"}}, "lb.example.org", "lb.eu-west-1.example.org")
+	// ingresses with the DNS names are skipped
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "b", Hostname: "lb.example.org", LoadBalancerHostnames: []string{"lb.example.org", "lb.eu-west-1.example.org"}}, "lb.example.org", "lb.eu-west-1.example.org")
+
+	require.Equal(t, 1, q.flush(time.Now()))
+	require.Equal(t, []string{"a=lb.example.org,lb.eu-west-1.example.org"}, f.updated)
+}
+
+func TestStatusUpdateQueueRetries(t *testing.T) {
+	f := &fakeStatusUpdates{failing: map[string]bool{"a": true}}
+	q := newStatusUpdateQueue(f.update, 10)
+	now := time.Now()
+
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "a"}, "lb.example.org")
+	q.add(&kubernetes.Ingress{Namespace: "ns", Name: "b"}, "lb.example.org")
+
+	require.Equal(t, 2, q.flush(now))
+	require.Equal(t, []string{"b=lb.example.org"}, f.updated)
+
+	// the failed update backs off
+	require.Equal(t, 0, q.flush(now))
+
+	f.failing = nil
+	require.Equal(t, 1, q.flush(now.Add(2*maxStatusUpdateRetryBackoff)))
+	require.Equal(t, []string{"b=lb.example.org", "a=lb.example.org"}, f.updated)
+}
+
+func TestStatusUpdateQueueDropsObsoleteUpdates(t *testing.T) {
+	f := &fakeStatusUpdates{}
+	q := newStatusUpdateQueue(f.update, 10)
+
+	a := &kubernetes.Ingress{Namespace: "ns", Name: "a"}
+	b := &kubernetes.Ingress{Namespace: "ns", Name: "b", Hostname: "old.example.org"}
+	q.add(a, "lb.example.org")
+	q.add(b, "new.example.org")
+
+	// a was deleted, b moved back to its load balancer
+	q.retain(map[string]bool{statusUpdateKey(b): true})
+	q.add(b, "old.example.org")
+
+	require.Equal(t, 0, q.flush(time.Now()))
+	require.Empty(t, f.updated)
+}
+
+func TestStatusUpdateQueueStates(t *testing.T) {
+	f := &fakeStatusUpdates{}
+	var states []string
+	updateState := func(ing *kubernetes.Ingress, state kubernetes.IngressState, _ time.Time) error {
+		states = append(states, ing.Name+"="+state.StackName)
+		return nil
+	}
+	ing := &kubernetes.Ingress{Namespace: "ns", Name: "a"}
+	state := kubernetes.IngressState{StackName: "stack", CertificateARNs: []string{"arn:cert"}}
+
+	// states aren't recorded unless enabled
+	q := newStatusUpdateQueue(f.update, 10)
+	q.addState(ing, state)
+	require.Equal(t, 0, q.flush(time.Now()))
+
+	q = newStatusUpdateQueue(f.update, 10).withStateUpdates(updateState)
+	q.add(ing, "lb.example.org")
+	q.addState(ing, state)
+	require.Equal(t, 2, q.flush(time.Now()))
+	require.Equal(t, []string{"a=lb.example.org"}, f.updated)
+	require.Equal(t, []string{"a=stack"}, states)
+}
+
+func TestIngressCertificates(t *testing.T) {
+	a := &kubernetes.Ingress{Name: "a"}
+	b := &kubernetes.Ingress{Name: "b"}
+	lb := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"arn:cert-2": {a},
+			"arn:cert-1": {a, b},
+		},
+	}
+	require.Equal(t, map[*kubernetes.Ingress][]string{
+		a: {"arn:cert-1", "arn:cert-2"},
+		b: {"arn:cert-1"},
+	}, lb.ingressCertificates())
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// syncTrigger wakes the worker loop out of its polling sleep on request,
+// e.g. after fixing an annotation during an incident. Requests via HTTP
+// are refused within the minimum interval since the last accepted one, so
+// they can't exhaust the limits of the AWS APIs.
+type syncTrigger struct {
+	mu          sync.Mutex
+	wake        chan struct{}
+	stacks      map[string]bool
+	minInterval time.Duration
+	lastRequest time.Time
+}
+
+// syncRequests are the reconciliations requested via the /sync endpoint
+// of --sync-address and by certificate events.
+var syncRequests = newSyncTrigger()
+
+func newSyncTrigger() *syncTrigger {
+	return &syncTrigger{
+		wake:   make(chan struct{}, 1),
+		stacks: make(map[string]bool),
+	}
+}
+
+// trigger requests a reconciliation. Requests made before it starts are
+// merged into one. The retry backoff of the given stacks is reset, so
+// their failed operations are retried right away.
+func (t *syncTrigger) trigger(stacks ...string) {
+	t.mu.Lock()
+	for _, stack := range stacks {
+		t.stacks[stack] = true
+	}
+	t.mu.Unlock()
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// takeStacks returns the stacks whose backoff should be reset and forgets
+// them. Only the backoff of managed stacks is reset by the worker loop.
+func (t *syncTrigger) takeStacks() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stacks := make([]string, 0, len(t.stacks))
+	for stack := range t.stacks {
+		stacks = append(stacks, stack)
+	}
+	t.stacks = make(map[string]bool)
+	return stacks
+}
+
+// ServeHTTP handles POST /sync and POST /sync/{stack}.
+func (t *syncTrigger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stack := strings.Trim(strings.TrimPrefix(r.URL.Path, "/sync"), "/")
+	if strings.Contains(stack, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if wait := t.accept(clock.Now()); wait > 0 {
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", math.Ceil(wait.Seconds())))
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	if stack == "" {
+		log.Info("Reconciliation requested")
+		t.trigger()
+	} else {
+		log.Infof("Reconciliation of stack %s requested", stack)
+		t.trigger(stack)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// accept records a request at the given time and returns zero, or returns
+// how long to wait if it's made within the minimum interval since the last
+// accepted one.
+func (t *syncTrigger) accept(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if wait := t.lastRequest.Add(t.minInterval).Sub(now); !t.lastRequest.IsZero() && wait > 0 {
+		return wait
+	}
+	t.lastRequest = now
+	return 0
+}
+
+// managedStackNames returns the names of the stacks of all regions found by
+// the worker loop.
+func managedStackNames() map[string]bool {
+	caches := []*stackCache{managedStacks}
+	for _, r := range additionalRegions {
+		caches = append(caches, r.stacks)
+	}
+	for _, r := range assumedRoles {
+		caches = append(caches, r.stacks)
+	}
+
+	names := make(map[string]bool)
+	for _, c := range caches {
+		for _, stack := range c.stacks {
+			names[stack.Name] = true
+		}
+	}
+	return names
+}
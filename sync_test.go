@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws/fake"
+)
+
+func TestSyncTrigger(t *testing.T) {
+	for _, test := range []struct {
+		msg        string
+		method     string
+		path       string
+		wantStatus int
+		wantStacks []string
+		wantWake   bool
+	}{
+		{
+			msg:        "reconcile",
+			method:     http.MethodPost,
+			path:       "/sync",
+			wantStatus: http.StatusAccepted,
+			wantStacks: []string{},
+			wantWake:   true,
+		},
+		{
+			msg:        "reconcile a stack",
+			method:     http.MethodPost,
+			path:       "/sync/kube-ingress-aws-controller-abc",
+			wantStatus: http.StatusAccepted,
+			wantStacks: []string{"kube-ingress-aws-controller-abc"},
+			wantWake:   true,
+		},
+		{
+			msg:        "GET is not allowed",
+			method:     http.MethodGet,
+			path:       "/sync",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantStacks: []string{},
+		},
+		{
+			msg:        "invalid stack name",
+			method:     http.MethodPost,
+			path:       "/sync/foo/bar",
+			wantStatus: http.StatusNotFound,
+			wantStacks: []string{},
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			trigger := newSyncTrigger()
+			rec := httptest.NewRecorder()
+			trigger.ServeHTTP(rec, httptest.NewRequest(test.method, test.path, nil))
+
+			assert.Equal(t, test.wantStatus, rec.Code)
+			assert.Equal(t, test.wantStacks, trigger.takeStacks())
+			select {
+			case <-trigger.wake:
+				assert.True(t, test.wantWake, "unexpected wake up")
+			default:
+				assert.False(t, test.wantWake, "missing wake up")
+			}
+		})
+	}
+}
+
+func TestSyncTriggerMergesRequests(t *testing.T) {
+	trigger := newSyncTrigger()
+	trigger.trigger("a")
+	trigger.trigger("b")
+	trigger.trigger("a")
+
+	<-trigger.wake
+	select {
+	case <-trigger.wake:
+		t.Error("requests weren't merged")
+	default:
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, trigger.takeStacks())
+	assert.Empty(t, trigger.takeStacks())
+}
+
+func TestSyncTriggerMinInterval(t *testing.T) {
+	defer func(c aws.Clock) { clock = c }(clock)
+	fakeClock := fake.NewClock(time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC))
+	clock = fakeClock
+
+	trigger := newSyncTrigger()
+	trigger.minInterval = 10 * time.Second
+	request := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		trigger.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sync", nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusAccepted, request().Code)
+	<-trigger.wake
+
+	fakeClock.Advance(4 * time.Second)
+	rec := request()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "6", rec.Header().Get("Retry-After"))
+	select {
+	case <-trigger.wake:
+		t.Error("refused request woke the worker loop")
+	default:
+	}
+
+	fakeClock.Advance(6 * time.Second)
+	assert.Equal(t, http.StatusAccepted, request().Code)
+}
+
+func TestManagedStackNames(t *testing.T) {
+	defer func(stacks *stackCache, regions []*region, roles map[string]*region) {
+		managedStacks, additionalRegions, assumedRoles = stacks, regions, roles
+	}(managedStacks, additionalRegions, assumedRoles)
+
+	managedStacks = &stackCache{stacks: []*aws.Stack{{Name: "a"}}}
+	additionalRegions = []*region{{stacks: &stackCache{stacks: []*aws.Stack{{Name: "b"}}}}}
+	assumedRoles = map[string]*region{"role": {stacks: &stackCache{stacks: []*aws.Stack{{Name: "c"}}}}}
+
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, managedStackNames())
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
+)
+
+// admissionReview is the subset of an admission.k8s.io/v1 AdmissionReview
+// used to validate the annotations of ingresses, routegroups and Gateways.
+type admissionReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *admissionRequest  `json:"request,omitempty"`
+	Response   *admissionResponse `json:"response,omitempty"`
+}
+
+type admissionRequest struct {
+	UID       string          `json:"uid"`
+	Operation string          `json:"operation"`
+	Object    admissionObject `json:"object"`
+	OldObject admissionObject `json:"oldObject"`
+}
+
+type admissionObject struct {
+	Metadata struct {
+		Namespace   string            `json:"namespace"`
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+type admissionResponse struct {
+	UID     string           `json:"uid"`
+	Allowed bool             `json:"allowed"`
+	Status  *admissionStatus `json:"status,omitempty"`
+}
+
+type admissionStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// serveAdmissionReview handles POST /validate, rejecting objects with
+// invalid controller annotations, which would otherwise be ignored during
+// the reconciliation.
+func serveAdmissionReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review admissionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	req := review.Request
+	response := &admissionResponse{UID: req.UID, Allowed: true}
+	if req.Operation == "CREATE" || req.Operation == "UPDATE" {
+		if err := kubernetes.ValidateAnnotations(req.Object.Metadata.Annotations, req.OldObject.Metadata.Annotations); err != nil {
+			log.Infof("Rejecting %s of %s/%s: %v", req.Operation, req.Object.Metadata.Namespace, req.Object.Metadata.Name, err)
+			response.Allowed = false
+			response.Status = &admissionStatus{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+		}
+	}
+	admissionReviews.WithLabelValues(boolLabel(response.Allowed)).Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admissionReview{
+		APIVersion: review.APIVersion,
+		Kind:       review.Kind,
+		Response:   response,
+	})
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// keyPairLoader serves the TLS certificate of the webhook, which is loaded
+// again when its file changes, e.g. when it's renewed by cert-manager.
+type keyPairLoader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (l *keyPairLoader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.certFile)
+	if err != nil {
+		if l.cert != nil {
+			return l.cert, nil
+		}
+		return nil, err
+	}
+	if l.cert != nil && info.ModTime().Equal(l.modTime) {
+		return l.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		if l.cert != nil {
+			log.Errorf("Failed to reload the webhook certificate, keeping the previous one: %v", err)
+			return l.cert, nil
+		}
+		return nil, err
+	}
+	l.cert, l.modTime = &cert, info.ModTime()
+	return l.cert, nil
+}
+
+func serveWebhook(address, certFile, keyFile string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", serveAdmissionReview)
+	loader := &keyPairLoader{certFile: certFile, keyFile: keyFile}
+	if _, err := loader.getCertificate(nil); err != nil {
+		log.Fatalf("Failed to load the webhook certificate: %v", err)
+	}
+	server := &http.Server{
+		Addr:      address,
+		Handler:   mux,
+		TLSConfig: &tls.Config{GetCertificate: loader.getCertificate},
+	}
+	log.Fatal(server.ListenAndServeTLS("", ""))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeAdmissionReview(t *testing.T) {
+	for _, test := range []struct {
+		msg      string
+		method   string
+		body     string
+		code     int
+		allowed  bool
+		rejected string
+	}{
+		{
+			msg:     "valid annotations",
+			method:  http.MethodPost,
+			body:    `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"1","operation":"CREATE","object":{"metadata":{"annotations":{"zalando.org/aws-load-balancer-scheme":"internal"}}}}}`,
+			code:    http.StatusOK,
+			allowed: true,
+		},
+		{
+			msg:      "invalid annotations",
+			method:   http.MethodPost,
+			body:     `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"1","operation":"UPDATE","object":{"metadata":{"annotations":{"zalando.org/aws-load-balancer-scheme":"private"}}},"oldObject":{"metadata":{}}}}`,
+			code:     http.StatusOK,
+			rejected: `invalid annotations: zalando.org/aws-load-balancer-scheme="private": must be one of internal, internet-facing`,
+		},
+		{
+			msg:     "unchanged invalid annotations",
+			method:  http.MethodPost,
+			body:    `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"1","operation":"UPDATE","object":{"metadata":{"annotations":{"zalando.org/aws-load-balancer-scheme":"private"}}},"oldObject":{"metadata":{"annotations":{"zalando.org/aws-load-balancer-scheme":"private"}}}}}`,
+			code:    http.StatusOK,
+			allowed: true,
+		},
+		{
+			msg:     "deletion",
+			method:  http.MethodPost,
+			body:    `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"1","operation":"DELETE","oldObject":{"metadata":{"annotations":{"zalando.org/aws-load-balancer-scheme":"private"}}}}}`,
+			code:    http.StatusOK,
+			allowed: true,
+		},
+		{
+			msg:    "no request",
+			method: http.MethodPost,
+			body:   `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`,
+			code:   http.StatusBadRequest,
+		},
+		{
+			msg:    "wrong method",
+			method: http.MethodGet,
+			code:   http.StatusMethodNotAllowed,
+		},
+	} {
+		t.Run(test.msg, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			serveAdmissionReview(rec, httptest.NewRequest(test.method, "/validate", strings.NewReader(test.body)))
+			require.Equal(t, test.code, rec.Code)
+			if test.code != http.StatusOK {
+				return
+			}
+
+			var review admissionReview
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&review))
+			require.Equal(t, "AdmissionReview", review.Kind)
+			require.Equal(t, "1", review.Response.UID)
+			require.Equal(t, test.allowed, review.Response.Allowed)
+			if test.rejected != "" {
+				require.Equal(t, test.rejected, review.Response.Status.Message)
+			}
+		})
+	}
+}
@@ -22,19 +22,60 @@ import (
 )
 
 type loadBalancer struct {
-	ingresses        map[string][]*kubernetes.Ingress
-	scheme           string
-	stack            *aws.Stack
-	shared           bool
-	http2            bool
-	clusterLocal     bool
-	securityGroup    string
-	sslPolicy        string
-	ipAddressType    string
-	wafWebACLID      string
-	certTTL          time.Duration
-	cwAlarms         aws.CloudWatchAlarmList
-	loadBalancerType string
+	ingresses                              map[string][]*kubernetes.Ingress
+	scheme                                 string
+	stack                                  *aws.Stack
+	shared                                 bool
+	http2                                  bool
+	preserveHostHeader                     bool
+	httpDisabled                           bool
+	frontingNLB                            bool
+	nlbSecurityGroup                       bool
+	lambdaTarget                           string
+	clusterLocal                           bool
+	securityGroup                          string
+	sslPolicy                              string
+	ipAddressType                          string
+	wafWebACLID                            string
+	wafRateLimit                           int64
+	wafRateLimitKey                        string
+	wafManagedRuleGroups                   []string
+	certTTL                                time.Duration
+	cwAlarms                               aws.CloudWatchAlarmList
+	templateFragments                      aws.TemplateFragments
+	loadBalancerType                       string
+	dnsHostnames                           []string
+	dnsRecordsHash                         string
+	namespaces                             []string
+	internalDomains                        []string
+	slowStart                              time.Duration
+	clientKeepAlive                        time.Duration
+	healthCheckMatcher                     string
+	preserveClientIP                       string
+	proxyProtocolV2                        bool
+	clientRoutingPolicy                    string
+	capacityUnits                          int64
+	defaultCertificateARN                  string
+	externalTargetGroupARNs                []string
+	elasticIPs                             []string
+	ipv4Pool                               string
+	endpointServicePrincipals              []string
+	denyInternalDomains                    string
+	denyInternalDomainsResponse            string
+	denyInternalDomainsResponseContentType string
+	denyInternalDomainsResponseStatusCode  int
+	healthyThresholdCount                  uint
+	unhealthyThresholdCount                uint
+	listenerRules                          aws.ListenerRuleList
+	listenerRulesHash                      string
+	authentication                         *aws.ListenerAuthentication
+	authenticationHash                     string
+	resourceTags                           aws.ResourceTags
+	resourceTagsHash                       string
+	templateResourceTags                   aws.ResourceTags
+	loadBalancerAttributes                 aws.Attributes
+	targetGroupAttributes                  aws.Attributes
+	attributesHash                         string
 }
 
 const (
@@ -42,8 +83,55 @@ const (
 	update
 	missing
 	delete
+	stuck
+	pending
+	quotaExceeded
+	updateTags
+	paused
 )
 
+const (
+	minQuotaBackoff = time.Minute
+	maxQuotaBackoff = time.Hour
+)
+
+var (
+	// quotaBackoff delays the creation of stacks after the load balancer
+	// quota of the account was found exhausted. It's doubled on every
+	// failure and reset once a stack created while backing off completes.
+	quotaBackoff      time.Duration
+	quotaBackoffUntil time.Time
+
+	// quotaExceededStacks holds the names of the stacks whose creation
+	// failed because of the exhausted quota, so each failure is reported
+	// once and not on every reconciliation until the stack is deleted.
+	quotaExceededStacks = make(map[string]bool)
+
+	// quotaBackoffStacks holds the names of the stacks created while
+	// backing off, whose completion resets the backoff. They're kept until
+	// then, because the stacks just created aren't listed yet.
+	quotaBackoffStacks = make(map[string]bool)
+)
+
+// clock provides the current time to the decisions of the worker, e.g. the
+// expiry of certificate TTLs, and the timer of the polling.
+var clock aws.Clock = aws.SystemClock
+
+// pendingStackUpdates holds the names of the stacks whose update was
+// deferred because they were being changed. The desired state is computed
+// on every update, so only the newest one is applied once the stack
+// settles.
+var pendingStackUpdates = make(map[string]bool)
+
+// drainingStacks holds the names of the stacks to delete whose targets are
+// being drained, with the time the draining started.
+var drainingStacks = make(map[string]time.Time)
+
+// maxStackDraining is the longest time the targets of a stack are drained
+// before it's deleted anyway, the maximum deregistration delay of target
+// groups.
+const maxStackDraining = time.Hour
+
 const (
 	maxTargetGroupSupported = 1000
 )
@@ -52,25 +140,96 @@ func (l *loadBalancer) Status() int {
 	if l.clusterLocal {
 		return ready
 	}
-	if l.stack.ShouldDelete() {
+	if l.paused() {
+		return paused
+	}
+	if l.stack.IsStuck(creationTimeout, clock.Now()) {
+		return stuck
+	}
+	if l.stack.IsQuotaExceeded() {
+		return quotaExceeded
+	}
+	if l.stack.ShouldDelete(clock.Now()) {
 		return delete
 	}
 	if len(l.ingresses) != 0 && l.stack == nil {
 		return missing
 	}
-	if firstRun || !l.inSync() && l.stack.IsComplete() {
+	if l.stack != nil && (firstRun || !l.inSync() || pendingStackUpdates[l.stack.Name]) {
+		if !l.stack.IsComplete() {
+			return pending
+		}
+		if !firstRun && !pendingStackUpdates[l.stack.Name] && l.templateInSync() {
+			return updateTags
+		}
 		return update
 	}
 	return ready
 }
 
+// paused returns true if the stack of the load balancer is tagged as paused
+// or one of its ingresses has the paused annotation.
+func (l *loadBalancer) paused() bool {
+	if l.stack == nil {
+		return false
+	}
+	if l.stack.Paused {
+		return true
+	}
+	for _, ingresses := range l.ingresses {
+		for _, ingress := range ingresses {
+			if ingress.Paused {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // inSync checks if the loadBalancer is in sync with the backing CF stack. It's
-// considered in sync when certs found for the ingresses match those already
-// defined on the stack and the cloudwatch alarm config is up-to-date.
+// considered in sync when its template, its resource tags and its tags are in
+// sync.
 func (l *loadBalancer) inSync() bool {
-	return reflect.DeepEqual(l.CertificateARNs(), l.stack.CertificateARNs) &&
-		l.stack.CWAlarmConfigHash == l.cwAlarms.Hash() &&
-		l.wafWebACLID == l.stack.WAFWebACLID
+	return l.templateInSync() && l.resourceTagsInSync() && l.tagsInSync()
+}
+
+// templateInSync checks if the template of the backing CF stack is up to
+// date: the certs found for the ingresses are the ones defined on the stack,
+// the cloudwatch alarm config is up-to-date and the managed DNS records cover
+// the same hostnames in the same hosted zones.
+func (l *loadBalancer) templateInSync() bool {
+	certificates := l.CertificateARNs()
+	if len(certificates) != len(l.stack.CertificateARNs) {
+		return false
+	}
+	for arn := range certificates {
+		if _, ok := l.stack.CertificateARNs[arn]; !ok {
+			return false
+		}
+	}
+
+	return l.stack.CWAlarmConfigHash == l.cwAlarms.Hash() &&
+		l.stack.TemplateFragmentsHash == l.templateFragments.Hash() &&
+		l.wafWebACLID == l.stack.WAFWebACLID &&
+		l.stack.DNSHostnamesHash == l.dnsRecordsHash &&
+		l.stack.InternalDomainsHash == aws.HashInternalDomains(l.internalDomains)
+}
+
+// resourceTagsInSync checks if the tags rendered from the resource tags
+// template were applied to the resources of the stack. They're set with the
+// ELBv2 API instead of a stack update.
+func (l *loadBalancer) resourceTagsInSync() bool {
+	return l.stack.TemplateResourceTagsHash == l.templateResourceTags.Hash()
+}
+
+// tagsInSync checks if the tags of the backing CF stack are up to date: the
+// stack is tagged by the current owner, the TTLs of the certs match and the
+// namespaces tag lists the namespaces of its ingresses. These can be updated
+// without touching the template.
+func (l *loadBalancer) tagsInSync() bool {
+	return !l.stack.PreviousOwner &&
+		reflect.DeepEqual(l.CertificateARNs(), l.stack.CertificateARNs) &&
+		l.stack.NamespacesTag == aws.NamespacesTagValue(l.namespaces)
 }
 
 // addIngress adds an ingress object to the load balancer.
@@ -93,7 +252,37 @@ func (l *loadBalancer) addIngress(certificateARNs []string, ingress *kubernetes.
 		l.sslPolicy != ingress.SSLPolicy ||
 		l.loadBalancerType != ingress.LoadBalancerType ||
 		l.http2 != ingress.HTTP2 ||
-		l.wafWebACLID != ingress.WAFWebACLID {
+		l.preserveHostHeader != ingress.PreserveHostHeader ||
+		l.httpDisabled != ingress.HTTPDisabled ||
+		l.frontingNLB != ingress.FrontingNLB ||
+		l.nlbSecurityGroup != ingress.NLBSecurityGroup ||
+		l.lambdaTarget != ingress.LambdaTarget ||
+		l.wafWebACLID != ingress.WAFWebACLID ||
+		l.wafRateLimit != ingress.WAFRateLimit ||
+		l.wafRateLimitKey != ingress.WAFRateLimitKey ||
+		strings.Join(l.wafManagedRuleGroups, ",") != strings.Join(ingress.WAFManagedRuleGroups, ",") ||
+		l.slowStart != ingress.SlowStart ||
+		l.clientKeepAlive != ingress.ClientKeepAlive ||
+		l.healthCheckMatcher != ingress.HealthCheckMatcher ||
+		l.preserveClientIP != ingress.PreserveClientIP ||
+		l.proxyProtocolV2 != ingress.ProxyProtocolV2 ||
+		l.clientRoutingPolicy != ingress.ClientRoutingPolicy ||
+		l.capacityUnits != ingress.CapacityUnits ||
+		l.defaultCertificateARN != ingress.DefaultCertificateARN ||
+		strings.Join(l.externalTargetGroupARNs, ",") != strings.Join(ingress.ExternalTargetGroupARNs, ",") ||
+		strings.Join(l.elasticIPs, ",") != strings.Join(ingress.ElasticIPs, ",") ||
+		l.ipv4Pool != ingress.IPv4Pool ||
+		strings.Join(l.endpointServicePrincipals, ",") != strings.Join(ingress.EndpointServicePrincipals, ",") ||
+		l.denyInternalDomains != ingress.DenyInternalDomains ||
+		l.denyInternalDomainsResponse != ingress.DenyInternalDomainsResponse ||
+		l.denyInternalDomainsResponseContentType != ingress.DenyInternalDomainsResponseContentType ||
+		l.denyInternalDomainsResponseStatusCode != ingress.DenyInternalDomainsResponseStatusCode ||
+		l.healthyThresholdCount != ingress.HealthyThresholdCount ||
+		l.unhealthyThresholdCount != ingress.UnhealthyThresholdCount ||
+		l.listenerRulesHash != ingress.ListenerRules.Hash() ||
+		l.authenticationHash != ingress.Authentication.Hash() ||
+		l.resourceTagsHash != ingress.ResourceTags.Hash() ||
+		l.attributesHash != aws.HashAttributes(ingress.LoadBalancerAttributes, ingress.TargetGroupAttributes) {
 		return false
 	}
 
@@ -132,6 +321,14 @@ func (l *loadBalancer) addIngress(certificateARNs []string, ingress *kubernetes.
 		l.ingresses[certificateARN] = append(l.ingresses[certificateARN], ingress)
 	}
 
+	// the rules, authentication, resource tags and attributes of existing
+	// load balancers are only known by their hash, all ingresses sharing
+	// the load balancer have the same.
+	l.listenerRules = ingress.ListenerRules
+	l.authentication = ingress.Authentication
+	l.resourceTags = ingress.ResourceTags
+	l.loadBalancerAttributes = ingress.LoadBalancerAttributes
+	l.targetGroupAttributes = ingress.TargetGroupAttributes
 	l.shared = ingress.Shared
 	return true
 }
@@ -146,11 +343,12 @@ func (l *loadBalancer) CertificateARNs() map[string]time.Time {
 		}
 	}
 
+	now := clock.Now().UTC()
 	for arn, ttl := range l.stack.CertificateARNs {
 		if _, ok := certificates[arn]; !ok {
 			if ttl.IsZero() {
-				certificates[arn] = time.Now().UTC().Add(l.certTTL)
-			} else if ttl.After(time.Now().UTC()) {
+				certificates[arn] = now.Add(l.certTTL)
+			} else if ttl.After(now) {
 				certificates[arn] = ttl
 			}
 		}
@@ -159,6 +357,25 @@ func (l *loadBalancer) CertificateARNs() map[string]time.Time {
 	return certificates
 }
 
+// Hostnames returns the sorted list of hostnames of all the ingresses
+// attached to the load balancer.
+func (l *loadBalancer) Hostnames() []string {
+	seen := make(map[string]bool)
+	hostnames := make([]string, 0)
+	for _, ingresses := range l.ingresses {
+		for _, ingress := range ingresses {
+			for _, hostname := range ingress.Hostnames {
+				if !seen[hostname] {
+					seen[hostname] = true
+					hostnames = append(hostnames, hostname)
+				}
+			}
+		}
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
 // Owner returns the ingress resource owning the load balancer. If there are no
 // owners it will return an empty string meaning the load balancer is shared
 // between multiple ingresses.
@@ -223,23 +440,49 @@ func startPolling(
 	awsAdapter *aws.Adapter,
 	kubeAdapter *kubernetes.Adapter,
 	pollingInterval time.Duration,
+	maxPollingInterval time.Duration,
 	globalWAFACL string,
 ) {
+	polling := newAdaptivePolling(pollingInterval, maxPollingInterval)
 	for {
-		if err := doWork(certsProvider, certsPerALB, certTTL, awsAdapter, kubeAdapter, globalWAFACL); err != nil {
+		if stacks := syncRequests.takeStacks(); len(stacks) > 0 {
+			managed := managedStackNames()
+			for _, stack := range stacks {
+				if !managed[stack] {
+					log.Warnf("Not resetting the backoff of stack %s: not managed by the controller", stack)
+					continue
+				}
+				stackRetries.success(stack)
+			}
+		}
+		changed, err := doWork(certsProvider, certsPerALB, certTTL, awsAdapter, kubeAdapter, globalWAFACL)
+		if err != nil {
 			log.Error(err)
 		}
 		firstRun = false
 
-		log.Debugf("Start polling sleep %s", pollingInterval)
+		interval := intervalJitter.apply(polling.next(changed, err))
+		log.Debugf("Start polling sleep %s", interval)
 		select {
-		case <-time.After(pollingInterval):
+		case <-clock.After(interval):
+		case <-syncRequests.wake:
+			managedStacks.invalidate()
+			for _, r := range additionalRegions {
+				r.stacks.invalidate()
+			}
+			for _, r := range assumedRoles {
+				r.stacks.invalidate()
+			}
+		case <-kubeAdapter.Changes():
+			log.Debug("Watched resources changed")
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// doWork runs a reconciliation of all load balancers. It returns true if any
+// of them needed a change.
 func doWork(
 	certsProvider certs.CertificatesProvider,
 	certsPerALB int,
@@ -247,7 +490,7 @@ func doWork(
 	awsAdapter *aws.Adapter,
 	kubeAdapter *kubernetes.Adapter,
 	globalWAFACL string,
-) error {
+) (bool, error) {
 	defer func() error {
 		if r := recover(); r != nil {
 			log.Errorln("shit has hit the fan:", errors.Wrap(r.(error), "panic caused by"))
@@ -259,58 +502,208 @@ func doWork(
 
 	ingresses, err := kubeAdapter.ListResources()
 	if err != nil {
-		return fmt.Errorf("doWork failed to list ingress resources: %v", err)
+		return false, fmt.Errorf("doWork failed to list ingress resources: %v", err)
 	}
 	log.Infof("Found %d ingress(es)", len(ingresses))
+	listedIngresses := ingresses
+	recordAnnotationWarnings(ingresses)
 
-	stacks, err := awsAdapter.FindManagedStacks()
-	if err != nil {
-		return fmt.Errorf("doWork failed to list managed stacks: %v", err)
-	}
-	log.Infof("Found %d stack(s)", len(stacks))
+	ingresses = filterAllowedHostnames(ingresses, allowedHostnameSuffixes)
+	ingresses = enforceMinSSLPolicy(ingresses, minSSLPolicy, minSSLPolicyMode)
+	enforceMaxListenerRules(ingresses, maxListenerRules)
+	filterAllowedAttributes(ingresses, allowedLoadBalancerAttributes, allowedTargetGroupAttributes)
 
-	err = awsAdapter.UpdateAutoScalingGroupsAndInstances()
+	cwAlarms, err := getCloudWatchAlarms(kubeAdapter, cwAlarmConfigMapLocation)
 	if err != nil {
-		return fmt.Errorf("doWork failed to get instances from EC2: %v", err)
+		return false, fmt.Errorf("doWork failed to retrieve cloudwatch alarm configuration: %v", err)
 	}
 
-	certificateSummaries, err := certsProvider.GetCertificates()
+	internalDomains, err := getInternalDomains(kubeAdapter, internalDomainsConfigMapLocation)
 	if err != nil {
-		return fmt.Errorf("doWork failed to get certificates: %v", err)
+		return false, fmt.Errorf("doWork failed to retrieve internal domains: %v", err)
 	}
 
-	cwAlarms, err := getCloudWatchAlarms(kubeAdapter, cwAlarmConfigMapLocation)
+	templateFragments, err := getTemplateFragments(kubeAdapter, templateFragmentsConfigMapLocation)
 	if err != nil {
-		return fmt.Errorf("doWork failed to retrieve cloudwatch alarm configuration: %v", err)
+		return false, fmt.Errorf("doWork failed to retrieve template fragments: %v", err)
 	}
 
-	awsAdapter.UpdateTargetGroupsAndAutoScalingGroups(stacks)
-	log.Infof("Found %d owned auto scaling group(s)", len(awsAdapter.OwnedAutoScalingGroups))
-	log.Infof("Found %d targeted auto scaling group(s)", len(awsAdapter.TargetedAutoScalingGroups))
-	log.Infof("Found %d single instance(s)", len(awsAdapter.SingleInstances()))
-	log.Infof("Found %d EC2 instance(s)", awsAdapter.CachedInstances())
-	log.Infof("Found %d certificate(s)", len(certificateSummaries))
 	log.Infof("Found %d cloudwatch alarm configuration(s)", len(cwAlarms))
+	log.Infof("Found %d template fragment(s)", len(templateFragments))
+
+	// ingresses of assumed roles are only served in the accounts of the
+	// roles in the region of the controller
+	ingresses, roleIngresses := ingressesByRole(ingresses, assumeRoleARN, startupRoleARNs(assumeRoleARN, managedRoleARNs))
+	roleRegions, complete := assumedRoleRegions(awsAdapter, roleIngresses)
+
+	regions := append([]*region{{
+		awsAdapter:    awsAdapter,
+		certsProvider: certsProvider,
+		stacks:        managedStacks,
+	}}, additionalRegions...)
+	regions = append(regions, roleRegions...)
+
+	models := make([]*regionalModel, 0, len(regions))
+	for i, r := range regions {
+		regionIngresses := ingresses
+		if r.roleARN != "" {
+			regionIngresses = roleIngresses[r.roleARN]
+		}
+		model, err := r.buildModel(regionIngresses, cwAlarms, internalDomains, templateFragments, certsPerALB, certTTL, globalWAFACL, i == 0)
+		if err != nil {
+			if i == 0 {
+				return false, fmt.Errorf("doWork failed in region %s: %v", r.name(), err)
+			}
+			// the other regions are reconciled, but neither the state
+			// kept for the stacks of the region is pruned nor are the
+			// DNS names of its load balancers dropped from the status
+			log.Errorf("Failed to reconcile the load balancers in region %s: %v", r.name(), err)
+			complete = false
+			continue
+		}
+		models = append(models, model)
+	}
 
-	certs := &Certificates{certificateSummaries: certificateSummaries}
-	model := buildManagedModel(certs, certsPerALB, certTTL, ingresses, stacks, cwAlarms, globalWAFACL)
-	log.Debugf("Have %d model(s)", len(model))
-	for _, loadBalancer := range model {
-		switch loadBalancer.Status() {
-		case delete:
-			deleteStack(awsAdapter, loadBalancer)
-		case missing:
-			createStack(awsAdapter, loadBalancer)
-			updateIngress(kubeAdapter, loadBalancer)
-		case ready:
-			updateIngress(kubeAdapter, loadBalancer)
-		case update:
-			updateStack(awsAdapter, loadBalancer)
-			updateIngress(kubeAdapter, loadBalancer)
+	if listenerDriftCheckInterval > 0 {
+		checkListenerDrift(models)
+	}
+	if multiLBDNSRecords {
+		attachDNSRecords(models)
+	}
+
+	dnsNames := newIngressDNSNames(len(regions))
+	var stacks []*aws.Stack
+	var loadBalancers []*loadBalancer
+	retryKeys := make(map[string]bool)
+	statusUpdateKeys := make(map[string]bool, len(ingresses))
+	changed := false
+	for i, model := range models {
+		log.Debugf("Have %d model(s) in region %s", len(model.loadBalancers), model.region.name())
+		status := &ingressStatus{setDNSName: dnsNames.forRegion(i), recordState: model.primary || model.region.roleARN != ""}
+		for _, loadBalancer := range model.loadBalancers {
+			if !loadBalancer.settled() {
+				model.region.stacks.invalidate()
+				changed = true
+			}
+			reconcileLoadBalancer(model.region.awsAdapter, loadBalancer, status)
+			retryKeys[loadBalancer.retryKey()] = true
+			for _, ingresses := range loadBalancer.ingresses {
+				for _, ing := range ingresses {
+					statusUpdateKeys[statusUpdateKey(ing)] = true
+				}
+			}
 		}
+		stacks = append(stacks, model.stacks...)
+		loadBalancers = append(loadBalancers, model.loadBalancers...)
+	}
+	if podReadinessGates {
+		updatePodReadiness(awsAdapter, kubeAdapter, models[0].stacks, podReadinessGateSelector)
 	}
 
-	return nil
+	if complete {
+		dnsNames.queue(ingressStatusUpdates)
+		prunePendingStackUpdates(stacks)
+		pruneQuotaExceededStacks(stacks)
+		pruneDrainingStacks(loadBalancers)
+		stackRetries.prune(retryKeys)
+		pruneErrorMetrics(stacks, listedIngresses)
+		ingressStatusUpdates.retain(statusUpdateKeys)
+	}
+
+	return changed, nil
+}
+
+// reconcileLoadBalancer brings the stack and the ingresses of a load
+// balancer in line with the model. Failures are logged and counted per
+// stack and ingress, and a panic is recovered, so they never block the
+// reconciliation of the other load balancers.
+func reconcileLoadBalancer(awsAdapter *aws.Adapter, lb *loadBalancer, status *ingressStatus) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("failed to reconcile load balancer of stack %q: %v", lb.stackName(), r)
+			countStackError(lb.stackName(), "reconcile")
+		}
+	}()
+
+	switch lb.Status() {
+	case delete:
+		retryStackOperation(lb, func() error { return deleteStack(awsAdapter, lb) })
+	case stuck:
+		retryStackOperation(lb, func() error { return remediateStuckStack(awsAdapter, lb, stuckStackRemediation) })
+	case quotaExceeded:
+		handleQuotaExceeded(lb, lb.stack.Name)
+		retryStackOperation(lb, func() error { return deleteStack(awsAdapter, lb) })
+	case missing:
+		if validateResources(awsAdapter, lb) {
+			retryStackOperation(lb, func() error { return createStack(awsAdapter, lb) })
+		}
+		updateIngress(lb, status)
+	case ready:
+		resetQuotaBackoff(lb)
+		updateIngress(lb, status)
+	case update:
+		if validateResources(awsAdapter, lb) {
+			retryStackOperation(lb, func() error { return updateStack(awsAdapter, lb) })
+		}
+		updateIngress(lb, status)
+	case updateTags:
+		retryStackOperation(lb, func() error { return updateStackTags(awsAdapter, lb) })
+		updateIngress(lb, status)
+	case paused:
+		log.Infof("stack %q is paused, not changing it", lb.stack.Name)
+		updateIngress(lb, status)
+	case pending:
+		log.Debugf("deferring update of stack %q until it settles", lb.stack.Name)
+		pendingStackUpdates[lb.stack.Name] = true
+		updateIngress(lb, status)
+	}
+}
+
+// stackRetries backs off the retries of failing stack operations per load
+// balancer.
+var stackRetries = newRetryBackoff(minStackRetryBackoff, maxStackRetryBackoff)
+
+// retryStackOperation runs the operation on the stack of the load balancer
+// unless it's backing off from previous failures.
+func retryStackOperation(lb *loadBalancer, op func() error) {
+	key := lb.retryKey()
+	now := clock.Now()
+	if !stackRetries.allowed(key, now) {
+		log.Debugf("backing off operation on stack %q after failures", key)
+		return
+	}
+
+	if err := op(); err != nil {
+		delay := stackRetries.failure(key, now)
+		log.Infof("retrying operation on stack %q in %s", key, delay)
+		return
+	}
+	stackRetries.success(key)
+}
+
+// retryKey identifies the load balancer for the backoff of failing stack
+// operations: the name of its stack or, if it has none yet, its scheme and
+// certificates.
+func (l *loadBalancer) retryKey() string {
+	if l.stack != nil {
+		return l.stack.Name
+	}
+
+	certificates := make([]string, 0, len(l.ingresses))
+	for cert := range l.ingresses {
+		certificates = append(certificates, cert)
+	}
+	sort.Strings(certificates)
+	return l.scheme + "/" + strings.Join(certificates, ",")
+}
+
+// stackName returns the name of the stack of the load balancer or an empty
+// string if it has none yet.
+func (l *loadBalancer) stackName() string {
+	if l.stack == nil {
+		return ""
+	}
+	return l.stack.Name
 }
 
 func sortStacks(stacks []*aws.Stack) {
@@ -327,17 +720,47 @@ func getAllLoadBalancers(certTTL time.Duration, stacks []*aws.Stack) []*loadBala
 
 	for _, stack := range stacks {
 		lb := &loadBalancer{
-			stack:            stack,
-			ingresses:        make(map[string][]*kubernetes.Ingress),
-			scheme:           stack.Scheme,
-			shared:           stack.OwnerIngress == "",
-			securityGroup:    stack.SecurityGroup,
-			sslPolicy:        stack.SSLPolicy,
-			ipAddressType:    stack.IpAddressType,
-			loadBalancerType: stack.LoadBalancerType,
-			http2:            stack.HTTP2,
-			wafWebACLID:      stack.WAFWebACLID,
-			certTTL:          certTTL,
+			stack:                                  stack,
+			ingresses:                              make(map[string][]*kubernetes.Ingress),
+			scheme:                                 stack.Scheme,
+			shared:                                 stack.OwnerIngress == "",
+			securityGroup:                          stack.SecurityGroup,
+			sslPolicy:                              stack.SSLPolicy,
+			ipAddressType:                          stack.IpAddressType,
+			loadBalancerType:                       stack.LoadBalancerType,
+			http2:                                  stack.HTTP2,
+			preserveHostHeader:                     stack.PreserveHostHeader,
+			httpDisabled:                           stack.HTTPDisabled,
+			frontingNLB:                            stack.FrontingNLB,
+			nlbSecurityGroup:                       stack.NLBSecurityGroup,
+			lambdaTarget:                           stack.LambdaTarget,
+			wafWebACLID:                            stack.WAFWebACLID,
+			wafRateLimit:                           stack.WAFRateLimit,
+			wafRateLimitKey:                        stack.WAFRateLimitKey,
+			wafManagedRuleGroups:                   stack.WAFManagedRuleGroups,
+			slowStart:                              stack.SlowStart,
+			clientKeepAlive:                        stack.ClientKeepAlive,
+			healthCheckMatcher:                     stack.HealthCheckMatcher,
+			preserveClientIP:                       stack.PreserveClientIP,
+			proxyProtocolV2:                        stack.ProxyProtocolV2,
+			clientRoutingPolicy:                    stack.ClientRoutingPolicy,
+			capacityUnits:                          stack.CapacityUnits,
+			defaultCertificateARN:                  stack.DefaultCertificateARN,
+			externalTargetGroupARNs:                stack.ExternalTargetGroupARNs,
+			elasticIPs:                             stack.ElasticIPs,
+			ipv4Pool:                               stack.IPv4Pool,
+			endpointServicePrincipals:              stack.EndpointServicePrincipals,
+			denyInternalDomains:                    stack.DenyInternalDomains,
+			denyInternalDomainsResponse:            stack.DenyInternalDomainsResponse,
+			denyInternalDomainsResponseContentType: stack.DenyInternalDomainsResponseContentType,
+			denyInternalDomainsResponseStatusCode:  stack.DenyInternalDomainsResponseStatusCode,
+			healthyThresholdCount:                  stack.HealthyThresholdCount,
+			unhealthyThresholdCount:                stack.UnhealthyThresholdCount,
+			listenerRulesHash:                      stack.ListenerRulesHash,
+			authenticationHash:                     stack.AuthenticationHash,
+			resourceTagsHash:                       stack.ResourceTagsHash,
+			attributesHash:                         stack.AttributesHash,
+			certTTL:                                certTTL,
 		}
 		// initialize ingresses map with existing certificates from the
 		// stack.
@@ -378,14 +801,39 @@ func matchIngressesToLoadBalancers(
 					ingress.Namespace,
 					ingress.Name,
 				)
+				ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonCertNotFound,
+					fmt.Sprintf("Certificate %s not found", ingress.CertificateARN))
 				continue
 			}
 			certificateARNs = []string{ingress.CertificateARN}
 		} else {
 			certificateARNs = certs.FindMatchingCertificateIDs(ingress.Hostnames)
 			if len(certificateARNs) == 0 {
-				log.Errorf("No certificates found for %v", ingress.Hostnames)
-				continue
+				if defaultCertificateARN == "" || !certs.CertificateExists(defaultCertificateARN) {
+					log.Errorf("No certificates found for %v", ingress.Hostnames)
+					ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonCertNotFound,
+						fmt.Sprintf("No certificate found for the hostnames %s", strings.Join(ingress.Hostnames, ", ")))
+					continue
+				}
+				log.Debugf("Using the default certificate for ingress '%s/%s' with hostnames %v", ingress.Namespace, ingress.Name, ingress.Hostnames)
+				certificateARNs = []string{defaultCertificateARN}
+			}
+		}
+
+		// the default certificate is served to clients without SNI, so
+		// it's added to the certificates of the ingress
+		if arn := ingress.DefaultCertificateARN; arn != "" {
+			if !certs.CertificateExists(arn) {
+				log.Warnf("Ignoring unknown default certificate '%s' of ingress '%s/%s'", arn, ingress.Namespace, ingress.Name)
+				ingress.DefaultCertificateARN = ""
+			} else {
+				found := false
+				for _, certificateARN := range certificateARNs {
+					found = found || certificateARN == arn
+				}
+				if !found {
+					certificateARNs = append(certificateARNs, arn)
+				}
 			}
 		}
 
@@ -420,15 +868,50 @@ func matchIngressesToLoadBalancers(
 			loadBalancers = append(
 				loadBalancers,
 				&loadBalancer{
-					ingresses:        i,
-					scheme:           ingress.Scheme,
-					shared:           ingress.Shared,
-					securityGroup:    ingress.SecurityGroup,
-					sslPolicy:        ingress.SSLPolicy,
-					ipAddressType:    ingress.IPAddressType,
-					loadBalancerType: ingress.LoadBalancerType,
-					http2:            ingress.HTTP2,
-					wafWebACLID:      ingress.WAFWebACLID,
+					ingresses:                              i,
+					scheme:                                 ingress.Scheme,
+					shared:                                 ingress.Shared,
+					securityGroup:                          ingress.SecurityGroup,
+					sslPolicy:                              ingress.SSLPolicy,
+					ipAddressType:                          ingress.IPAddressType,
+					loadBalancerType:                       ingress.LoadBalancerType,
+					http2:                                  ingress.HTTP2,
+					preserveHostHeader:                     ingress.PreserveHostHeader,
+					httpDisabled:                           ingress.HTTPDisabled,
+					frontingNLB:                            ingress.FrontingNLB,
+					nlbSecurityGroup:                       ingress.NLBSecurityGroup,
+					lambdaTarget:                           ingress.LambdaTarget,
+					wafWebACLID:                            ingress.WAFWebACLID,
+					wafRateLimit:                           ingress.WAFRateLimit,
+					wafRateLimitKey:                        ingress.WAFRateLimitKey,
+					wafManagedRuleGroups:                   ingress.WAFManagedRuleGroups,
+					slowStart:                              ingress.SlowStart,
+					clientKeepAlive:                        ingress.ClientKeepAlive,
+					healthCheckMatcher:                     ingress.HealthCheckMatcher,
+					preserveClientIP:                       ingress.PreserveClientIP,
+					proxyProtocolV2:                        ingress.ProxyProtocolV2,
+					clientRoutingPolicy:                    ingress.ClientRoutingPolicy,
+					capacityUnits:                          ingress.CapacityUnits,
+					defaultCertificateARN:                  ingress.DefaultCertificateARN,
+					externalTargetGroupARNs:                ingress.ExternalTargetGroupARNs,
+					elasticIPs:                             ingress.ElasticIPs,
+					ipv4Pool:                               ingress.IPv4Pool,
+					endpointServicePrincipals:              ingress.EndpointServicePrincipals,
+					denyInternalDomains:                    ingress.DenyInternalDomains,
+					denyInternalDomainsResponse:            ingress.DenyInternalDomainsResponse,
+					denyInternalDomainsResponseContentType: ingress.DenyInternalDomainsResponseContentType,
+					denyInternalDomainsResponseStatusCode:  ingress.DenyInternalDomainsResponseStatusCode,
+					healthyThresholdCount:                  ingress.HealthyThresholdCount,
+					unhealthyThresholdCount:                ingress.UnhealthyThresholdCount,
+					listenerRules:                          ingress.ListenerRules,
+					listenerRulesHash:                      ingress.ListenerRules.Hash(),
+					authentication:                         ingress.Authentication,
+					authenticationHash:                     ingress.Authentication.Hash(),
+					resourceTags:                           ingress.ResourceTags,
+					resourceTagsHash:                       ingress.ResourceTags.Hash(),
+					loadBalancerAttributes:                 ingress.LoadBalancerAttributes,
+					targetGroupAttributes:                  ingress.TargetGroupAttributes,
+					attributesHash:                         aws.HashAttributes(ingress.LoadBalancerAttributes, ingress.TargetGroupAttributes),
 				},
 			)
 		}
@@ -450,9 +933,634 @@ func attachCloudWatchAlarms(loadBalancers []*loadBalancer, cwAlarms aws.CloudWat
 	}
 }
 
+// filterAllowedHostnames removes the hostnames not matching any of the
+// allowed suffixes from the ingresses. Ingresses left without any hostname
+// are rejected and not considered for load balancers. If no suffixes are
+// configured all hostnames are allowed.
+func filterAllowedHostnames(ingresses []*kubernetes.Ingress, allowedSuffixes []string) []*kubernetes.Ingress {
+	if len(allowedSuffixes) == 0 {
+		return ingresses
+	}
+
+	result := make([]*kubernetes.Ingress, 0, len(ingresses))
+	for _, ingress := range ingresses {
+		if ingress.ClusterLocal {
+			result = append(result, ingress)
+			continue
+		}
+
+		hostnames := make([]string, 0, len(ingress.Hostnames))
+		var rejected []string
+		for _, hostname := range ingress.Hostnames {
+			if hasAllowedSuffix(hostname, allowedSuffixes) {
+				hostnames = append(hostnames, hostname)
+			} else {
+				log.Errorf("Rejecting hostname %q of %s %s: not matching any of the allowed suffixes %q", hostname, ingress.ResourceType(), ingress, allowedSuffixes)
+				rejected = append(rejected, hostname)
+			}
+		}
+
+		if len(rejected) > 0 && len(hostnames) == 0 {
+			log.Errorf("Ignoring %s %s: none of its hostnames is allowed", ingress.ResourceType(), ingress)
+			ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonHostnameNotAllowed,
+				fmt.Sprintf("Ignored, none of the hostnames %s is allowed", strings.Join(rejected, ", ")))
+			continue
+		}
+		if len(rejected) > 0 {
+			ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonHostnameNotAllowed,
+				fmt.Sprintf("Hostnames %s not allowed, they don't match any of the allowed suffixes", strings.Join(rejected, ", ")))
+			ingress.Hostnames = hostnames
+		}
+
+		result = append(result, ingress)
+	}
+
+	return result
+}
+
+// recordAnnotationWarnings records an event for each warning about the
+// annotations of the ingresses, e.g. an invalid value which is ignored, and
+// sets the number of warnings of each ingress as metric.
+func recordAnnotationWarnings(ingresses []*kubernetes.Ingress) {
+	warned := make(map[[2]string]bool)
+	for _, ingress := range ingresses {
+		if len(ingress.AnnotationWarnings) == 0 {
+			continue
+		}
+		for _, warning := range ingress.AnnotationWarnings {
+			ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonInvalidAnnotation, warning)
+		}
+		warned[[2]string{ingress.Namespace, ingress.Name}] = true
+		annotationWarningLabels.add(ingress.Namespace, ingress.Name)
+		ingressAnnotationWarnings.WithLabelValues(ingress.Namespace, ingress.Name).Set(float64(len(ingress.AnnotationWarnings)))
+	}
+	annotationWarningLabels.prune(ingressAnnotationWarnings, func(namespace, name string) bool {
+		return warned[[2]string{namespace, name}]
+	})
+}
+
+// enforceMinSSLPolicy upgrades the SSL policy of the ingresses selecting a
+// policy weaker than the minimum one, or removes them from the list in
+// reject mode, and records an event on them.
+func enforceMinSSLPolicy(ingresses []*kubernetes.Ingress, minPolicy, mode string) []*kubernetes.Ingress {
+	if minPolicy == "" {
+		return ingresses
+	}
+
+	result := make([]*kubernetes.Ingress, 0, len(ingresses))
+	for _, ingress := range ingresses {
+		if ingress.ClusterLocal || !aws.IsWeakerSSLPolicy(ingress.SSLPolicy, minPolicy) {
+			result = append(result, ingress)
+			continue
+		}
+
+		if mode == minSSLPolicyModeReject {
+			log.Errorf("Ignoring %s %s: SSL policy %s is weaker than the minimum SSL policy %s", ingress.ResourceType(), ingress, ingress.SSLPolicy, minPolicy)
+			ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonSSLPolicyNotAllowed,
+				fmt.Sprintf("Ignored, SSL policy %s is weaker than the minimum SSL policy %s", ingress.SSLPolicy, minPolicy))
+			continue
+		}
+
+		log.Warnf("Upgrading SSL policy of %s %s from %s to the minimum SSL policy %s", ingress.ResourceType(), ingress, ingress.SSLPolicy, minPolicy)
+		ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonSSLPolicyUpgraded,
+			fmt.Sprintf("SSL policy %s upgraded to the minimum SSL policy %s", ingress.SSLPolicy, minPolicy))
+		ingress.SSLPolicy = minPolicy
+		result = append(result, ingress)
+	}
+
+	return result
+}
+
+// enforceMaxListenerRules ignores the listener rules of the ingresses
+// defining more of them than the quota allows, like invalid rules, and
+// records an event on the ingresses. Zero means unlimited.
+func enforceMaxListenerRules(ingresses []*kubernetes.Ingress, max int) {
+	if max <= 0 {
+		return
+	}
+
+	for _, ingress := range ingresses {
+		if len(ingress.ListenerRules) > max {
+			msg := fmt.Sprintf("Ignoring listener rules: %d listener rules exceed the quota of %d", len(ingress.ListenerRules), max)
+			log.Warnf("%s of %s %s", msg, ingress.ResourceType(), ingress)
+			ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonListenerRulesLimitExceeded, msg)
+			ingress.ListenerRules = nil
+		}
+	}
+}
+
+// filterAllowedAttributes removes the load balancer and target group
+// attributes of the ingresses which aren't allowed by the patterns, and
+// reports them.
+func filterAllowedAttributes(ingresses []*kubernetes.Ingress, allowedLoadBalancer, allowedTargetGroup []string) {
+	for _, ingress := range ingresses {
+		var denied []string
+		if len(ingress.LoadBalancerAttributes) > 0 {
+			ingress.LoadBalancerAttributes, denied = ingress.LoadBalancerAttributes.Filter(allowedLoadBalancer)
+			if len(denied) > 0 {
+				log.Warnf("Ignoring load balancer attributes %v of %s %s: not allowed", denied, ingress.ResourceType(), ingress)
+			}
+		}
+		if len(ingress.TargetGroupAttributes) > 0 {
+			ingress.TargetGroupAttributes, denied = ingress.TargetGroupAttributes.Filter(allowedTargetGroup)
+			if len(denied) > 0 {
+				log.Warnf("Ignoring target group attributes %v of %s %s: not allowed", denied, ingress.ResourceType(), ingress)
+			}
+		}
+	}
+}
+
+// enforceMaxLoadBalancers removes the load balancers without a stack from
+// the model once the number of load balancers would exceed the maximum, so
+// their stacks aren't created, and records an event on their ingresses.
+// Existing stacks are kept. The load balancers to create are chosen in a
+// stable order. Zero means unlimited.
+func enforceMaxLoadBalancers(loadBalancers []*loadBalancer, max int) []*loadBalancer {
+	existing := 0
+	var missing []*loadBalancer
+	for _, lb := range loadBalancers {
+		switch {
+		case lb.clusterLocal:
+		case lb.stack != nil:
+			existing++
+		case len(lb.ingresses) > 0:
+			missing = append(missing, lb)
+		}
+	}
+	managedLoadBalancers.Set(float64(existing))
+
+	if max <= 0 || existing+len(missing) <= max {
+		return loadBalancers
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].retryKey() < missing[j].retryKey()
+	})
+	refused := make(map[*loadBalancer]bool)
+	for i, lb := range missing {
+		if existing+i < max {
+			continue
+		}
+		refused[lb] = true
+		loadBalancerLimitExceeded.Inc()
+		for _, ingresses := range lb.ingresses {
+			for _, ing := range ingresses {
+				log.Errorf("Not creating a load balancer for %s %s: the maximum of %d load balancers is reached", ing.ResourceType(), ing, max)
+			}
+		}
+		ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonLoadBalancerLimitExceeded,
+			fmt.Sprintf("Not creating the load balancer: the maximum of %d load balancers is reached", max))
+	}
+
+	result := make([]*loadBalancer, 0, len(loadBalancers)-len(refused))
+	for _, lb := range loadBalancers {
+		if !refused[lb] {
+			result = append(result, lb)
+		}
+	}
+	return result
+}
+
+// deferCreationsOverQuota removes the load balancers without a stack from the
+// model which would exceed the quotas of load balancers per region of the
+// account, so they wait for capacity instead of failing during the creation
+// of their stacks. Nothing is deferred if the quotas or the load balancers
+// of the account can't be read.
+func deferCreationsOverQuota(awsAdapter *aws.Adapter, loadBalancers []*loadBalancer, quotas *aws.LoadBalancerQuotas) []*loadBalancer {
+	if quotas == nil || (quotas.ALBsPerRegion <= 0 && quotas.NLBsPerRegion <= 0) {
+		return loadBalancers
+	}
+
+	missing := false
+	for _, lb := range loadBalancers {
+		if !lb.clusterLocal && lb.stack == nil && len(lb.ingresses) > 0 {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		loadBalancersWaitingForQuota.Set(0)
+		return loadBalancers
+	}
+
+	inUse, err := awsAdapter.CountLoadBalancers()
+	if err != nil {
+		log.Warnf("Not checking the load balancer quotas: %v", err)
+		return loadBalancers
+	}
+
+	return limitCreationsToQuota(loadBalancers, inUse, map[string]int{
+		aws.LoadBalancerTypeApplication: quotas.ALBsPerRegion,
+		aws.LoadBalancerTypeNetwork:     quotas.NLBsPerRegion,
+	})
+}
+
+// limitCreationsToQuota removes the load balancers without a stack from the
+// model once the load balancers in use and the ones created before them
+// reach the quota of their type, and records an event on their ingresses
+// explaining the wait. An application load balancer with a network load
+// balancer in front of it needs both. The load balancers to create are
+// chosen in a stable order, quotas of zero are unlimited.
+func limitCreationsToQuota(loadBalancers []*loadBalancer, inUse, quotas map[string]int) []*loadBalancer {
+	var missing []*loadBalancer
+	for _, lb := range loadBalancers {
+		if !lb.clusterLocal && lb.stack == nil && len(lb.ingresses) > 0 {
+			missing = append(missing, lb)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].retryKey() < missing[j].retryKey()
+	})
+
+	used := make(map[string]int, len(inUse))
+	for lbType, n := range inUse {
+		used[lbType] = n
+	}
+
+	deferred := make(map[*loadBalancer]bool)
+	for _, lb := range missing {
+		needed := map[string]int{lb.loadBalancerType: 1}
+		if lb.frontingNLB {
+			needed[aws.LoadBalancerTypeNetwork]++
+		}
+
+		var exhausted string
+		for lbType, n := range needed {
+			if quotas[lbType] > 0 && used[lbType]+n > quotas[lbType] {
+				exhausted = lbType
+			}
+		}
+		if exhausted == "" {
+			for lbType, n := range needed {
+				used[lbType] += n
+			}
+			continue
+		}
+
+		deferred[lb] = true
+		for _, ingresses := range lb.ingresses {
+			for _, ing := range ingresses {
+				log.Warnf("Waiting to create a load balancer for %s %s: %d of %d %s load balancers of the account in use", ing.ResourceType(), ing, used[exhausted], quotas[exhausted], exhausted)
+			}
+		}
+		ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonWaitingForQuota,
+			fmt.Sprintf("Waiting to create the load balancer: %d of %d %s load balancers of the account in use", used[exhausted], quotas[exhausted], exhausted))
+	}
+	loadBalancersWaitingForQuota.Set(float64(len(deferred)))
+
+	if len(deferred) == 0 {
+		return loadBalancers
+	}
+	result := make([]*loadBalancer, 0, len(loadBalancers)-len(deferred))
+	for _, lb := range loadBalancers {
+		if !deferred[lb] {
+			result = append(result, lb)
+		}
+	}
+	return result
+}
+
+// checkFirewallManagerConflicts detects the load balancers whose WebACL was
+// replaced by AWS Firewall Manager.
+func checkFirewallManagerConflicts(awsAdapter *aws.Adapter, loadBalancers []*loadBalancer) {
+	keepFirewallManagerWebACLs(loadBalancers, awsAdapter.FirewallManagerWebACL)
+}
+
+// keepFirewallManagerWebACLs keeps the WebACL of the stacks of the load
+// balancers associated with a WebACL of Firewall Manager, instead of
+// fighting over the association with every update of the stack, and
+// reports the conflict as an event on each ingress. Only load balancers
+// with a WebACL set by the controller are checked.
+func keepFirewallManagerWebACLs(loadBalancers []*loadBalancer, firewallManagerWebACL func(loadBalancerARN string) (string, error)) {
+	conflicts := 0
+	for _, lb := range loadBalancers {
+		if lb.stack == nil || lb.stack.LoadBalancerARN == "" || !lb.hasWebACL() {
+			continue
+		}
+
+		webACL, err := firewallManagerWebACL(lb.stack.LoadBalancerARN)
+		if err != nil {
+			log.Warnf("Failed to check the WebACL of the load balancer of stack %s: %v", lb.stack.Name, err)
+			continue
+		}
+		if webACL == "" {
+			continue
+		}
+
+		conflicts++
+		lb.wafWebACLID = lb.stack.WAFWebACLID
+		for _, ingresses := range lb.ingresses {
+			for _, ing := range ingresses {
+				log.Errorf("WebACL of %s %s conflicts with the WebACL %s of Firewall Manager associated with the load balancer of stack %s, not updating it", ing.ResourceType(), ing, webACL, lb.stack.Name)
+			}
+		}
+		ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonFirewallManagerConflict,
+			fmt.Sprintf("WebACL overridden by the WebACL %s of Firewall Manager associated with the load balancer, not updating it", webACL))
+	}
+	firewallManagerConflicts.Set(float64(conflicts))
+}
+
+// lastListenerDriftCheck is the time of the last check of the listeners of
+// the load balancers.
+var lastListenerDriftCheck time.Time
+
+// checkListenerDrift checks the listeners of the load balancers of all
+// regions for changes made outside of CloudFormation once per
+// --listener-drift-check-interval.
+func checkListenerDrift(models []*regionalModel) {
+	now := clock.Now()
+	if now.Before(lastListenerDriftCheck.Add(listenerDriftCheckInterval)) {
+		return
+	}
+	lastListenerDriftCheck = now
+
+	adapters := make(map[*aws.Stack]*aws.Adapter)
+	var loadBalancers []*loadBalancer
+	for _, model := range models {
+		for _, stack := range model.stacks {
+			adapters[stack] = model.region.awsAdapter
+		}
+		loadBalancers = append(loadBalancers, model.loadBalancers...)
+	}
+	detect := func(stack *aws.Stack) (*aws.ListenerDrift, error) {
+		return adapters[stack].DetectListenerDrift(stack)
+	}
+	revert := func(stack *aws.Stack, drift *aws.ListenerDrift) error {
+		return adapters[stack].RevertListenerDrift(stack, drift)
+	}
+	handleListenerDrift(loadBalancers, detect, revert, listenerDriftRemediation)
+}
+
+// handleListenerDrift compares the HTTPS listeners of the load balancers in
+// sync with their stacks with the stacks, and reports or reverts the
+// differences. Load balancers about to be updated are skipped, the update
+// doesn't necessarily revert the differences, but they're checked again
+// next time.
+func handleListenerDrift(loadBalancers []*loadBalancer, detect func(*aws.Stack) (*aws.ListenerDrift, error), revert func(*aws.Stack, *aws.ListenerDrift) error, remediation string) {
+	drifted := 0
+	for _, lb := range loadBalancers {
+		if lb.stack == nil || lb.stack.LoadBalancerARN == "" || len(lb.stack.CertificateARNs) == 0 || lb.Status() != ready {
+			continue
+		}
+
+		drift, err := detect(lb.stack)
+		if err != nil {
+			log.Warnf("Failed to check the listeners of the load balancer of stack %s: %v", lb.stack.Name, err)
+			continue
+		}
+		if drift.Empty() {
+			continue
+		}
+
+		if remediation != listenerDriftRevert {
+			drifted++
+			log.Warnf("Load balancer of stack %s was changed outside of CloudFormation: %s", lb.stack.Name, drift)
+			continue
+		}
+
+		if err := revert(lb.stack, drift); err != nil {
+			drifted++
+			log.Errorf("Failed to revert the changes of the load balancer of stack %s made outside of CloudFormation (%s): %v", lb.stack.Name, drift, err)
+			countStackError(lb.stack.Name, "revert-listener-drift")
+			continue
+		}
+		log.Infof("Reverted the changes of the load balancer of stack %s made outside of CloudFormation: %s", lb.stack.Name, drift)
+	}
+	listenerDrift.Set(float64(drifted))
+}
+
+// hasWebACL returns true if the controller associates the load balancer
+// with a WebACL, or did so before.
+func (l *loadBalancer) hasWebACL() bool {
+	return l.wafWebACLID != "" ||
+		l.wafRateLimit > 0 ||
+		len(l.wafManagedRuleGroups) > 0 ||
+		(l.stack != nil && l.stack.WAFWebACLID != "")
+}
+
+func hasAllowedSuffix(hostname string, allowedSuffixes []string) bool {
+	hostname = strings.ToLower(hostname)
+	for _, suffix := range allowedSuffixes {
+		suffix = strings.ToLower(strings.TrimPrefix(suffix, "."))
+		if hostname == suffix || strings.HasSuffix(hostname, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachSharedDNSHostnames sets the hostnames served by more than one load
+// balancer on each of the load balancers serving them, so that DNS records
+// spreading the traffic across all of them are managed. The hostnames whose
+// records are managed by the stack of a load balancer already are kept as
+// long as it serves them, so the hostname keeps resolving once the other
+// load balancers are gone.
+func attachSharedDNSHostnames(loadBalancers []*loadBalancer) {
+	lbsByHostname := make(map[string][]*loadBalancer)
+	for _, lb := range loadBalancers {
+		if lb.clusterLocal || lb.stack.ShouldDelete(clock.Now()) {
+			continue
+		}
+		for _, hostname := range lb.Hostnames() {
+			lbsByHostname[hostname] = append(lbsByHostname[hostname], lb)
+		}
+	}
+
+	for _, lb := range loadBalancers {
+		lb.dnsHostnames = nil
+		if lb.clusterLocal {
+			continue
+		}
+		managed := make(map[string]bool)
+		if lb.stack != nil {
+			for _, hostname := range lb.stack.DNSHostnames {
+				managed[hostname] = true
+			}
+		}
+		for _, hostname := range lb.Hostnames() {
+			if len(lbsByHostname[hostname]) > 1 || managed[hostname] {
+				lb.dnsHostnames = append(lb.dnsHostnames, hostname)
+			}
+		}
+	}
+}
+
+// attachDNSRecords sets the shared hostnames and the hashes of the DNS
+// records of the load balancers of all regions, so a hostname served in
+// more than one region gets a weighted record for each of them.
+func attachDNSRecords(models []*regionalModel) {
+	var loadBalancers []*loadBalancer
+	for _, model := range models {
+		loadBalancers = append(loadBalancers, model.loadBalancers...)
+	}
+	attachSharedDNSHostnames(loadBalancers)
+	for _, model := range models {
+		skipConflictingDNSRecords(model.region.awsAdapter, model.loadBalancers)
+		attachDNSRecordsHashes(model.region.awsAdapter, model.loadBalancers)
+	}
+}
+
+// skipConflictingDNSRecords drops the hostnames with plain DNS records, e.g.
+// managed by external-dns, from the DNS records of the load balancers and
+// records an event on their ingresses, as Route53 rejects the weighted
+// records and the update of the stack would fail. Only the hostnames whose
+// records aren't managed by the stack yet are checked.
+func skipConflictingDNSRecords(awsAdapter *aws.Adapter, loadBalancers []*loadBalancer) {
+	for _, lb := range loadBalancers {
+		managed := make(map[string]bool)
+		if lb.stack != nil {
+			for _, hostname := range lb.stack.DNSHostnames {
+				managed[hostname] = true
+			}
+		}
+		var added []string
+		for _, hostname := range lb.dnsHostnames {
+			if !managed[hostname] {
+				added = append(added, hostname)
+			}
+		}
+		if len(added) == 0 {
+			continue
+		}
+
+		plain, err := awsAdapter.PlainDNSRecords(added, lb.scheme)
+		if err != nil {
+			log.Warnf("Failed to check the DNS records of stack %q: %v", lb.stackName(), err)
+			continue
+		}
+		if len(plain) == 0 {
+			continue
+		}
+
+		conflicting := make(map[string]bool, len(plain))
+		for _, hostname := range plain {
+			conflicting[hostname] = true
+		}
+		hostnames := make([]string, 0, len(lb.dnsHostnames))
+		for _, hostname := range lb.dnsHostnames {
+			if !conflicting[hostname] {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+		lb.dnsHostnames = hostnames
+
+		for _, ingresses := range lb.ingresses {
+			for _, ingress := range ingresses {
+				for _, hostname := range ingress.Hostnames {
+					if conflicting[hostname] {
+						msg := fmt.Sprintf("Skipping the weighted DNS records of hostname %s: a record without set identifier exists, e.g. managed by external-dns", hostname)
+						log.Warnf("%s of %s %s", msg, ingress.ResourceType(), ingress)
+						ingressEvents.event(ingress, kubernetes.EventTypeWarning, eventReasonDNSRecordConflict, msg)
+					}
+				}
+			}
+		}
+	}
+}
+
+// attachDNSRecordsHashes sets the hash of the DNS records of each load
+// balancer, which covers the hosted zones of the hostnames, so the stack is
+// updated once a hostname moves to another zone. Load balancers whose hash
+// can't be computed are considered in sync.
+func attachDNSRecordsHashes(awsAdapter *aws.Adapter, loadBalancers []*loadBalancer) {
+	for _, lb := range loadBalancers {
+		hash, err := awsAdapter.DNSRecordsHash(lb.dnsHostnames, lb.scheme)
+		if err != nil {
+			log.Warnf("Failed to find the hosted zones of the DNS records of stack %q: %v", lb.stackName(), err)
+			if lb.stack != nil {
+				hash = lb.stack.DNSHostnamesHash
+			}
+		}
+		lb.dnsRecordsHash = hash
+	}
+}
+
+// attachNamespaces sets the namespaces of the ingresses served by each load
+// balancer, so they can be tagged for cost allocation.
+func attachNamespaces(loadBalancers []*loadBalancer) {
+	for _, lb := range loadBalancers {
+		seen := make(map[string]bool)
+		lb.namespaces = nil
+		for _, ingresses := range lb.ingresses {
+			for _, ingress := range ingresses {
+				if !ingress.ClusterLocal && !seen[ingress.Namespace] {
+					seen[ingress.Namespace] = true
+					lb.namespaces = append(lb.namespaces, ingress.Namespace)
+				}
+			}
+		}
+		sort.Strings(lb.namespaces)
+	}
+}
+
+// attachTemplateResourceTags sets the tags rendered from the template for
+// the ingresses of each load balancer, on top of the additional tags of the
+// controller. The values of the ingresses sharing a load balancer are
+// joined. Ingresses failing to render are skipped.
+func attachTemplateResourceTags(loadBalancers []*loadBalancer, tmpl kubernetes.ResourceTagsTemplate, additional aws.ResourceTags) {
+	for _, lb := range loadBalancers {
+		lb.templateResourceTags = nil
+		if len(additional) > 0 && lb.hasIngresses() && !lb.clusterLocal {
+			lb.templateResourceTags = make(aws.ResourceTags, len(additional))
+			for key, value := range additional {
+				lb.templateResourceTags[key] = value
+			}
+		}
+
+		values := make(map[string]map[string]bool)
+		for _, ingresses := range lb.ingresses {
+			for _, ingress := range ingresses {
+				if ingress.ClusterLocal {
+					continue
+				}
+				tags, err := tmpl.Render(ingress)
+				if err != nil {
+					log.Errorf("Failed to render the resource tags of %s %s: %v", ingress.ResourceType(), ingress, err)
+					continue
+				}
+				for key, value := range tags {
+					if values[key] == nil {
+						values[key] = make(map[string]bool)
+					}
+					values[key][value] = true
+				}
+			}
+		}
+
+		for key, set := range values {
+			list := make([]string, 0, len(set))
+			for value := range set {
+				list = append(list, value)
+			}
+			if lb.templateResourceTags == nil {
+				lb.templateResourceTags = make(aws.ResourceTags, len(values))
+			}
+			lb.templateResourceTags[key] = aws.JoinTagValues(list)
+		}
+	}
+}
+
+// attachInternalDomains sets the internal domains read from the ConfigMap,
+// overriding the ones of the controller flags.
+func attachInternalDomains(loadBalancers []*loadBalancer, internalDomains []string) {
+	for _, lb := range loadBalancers {
+		lb.internalDomains = internalDomains
+	}
+}
+
+// attachTemplateFragments sets the template fragments read from the
+// ConfigMap on all load balancers.
+func attachTemplateFragments(loadBalancers []*loadBalancer, fragments aws.TemplateFragments) {
+	for _, lb := range loadBalancers {
+		lb.templateFragments = fragments
+	}
+}
+
 func attachGlobalWAFACL(ings []*kubernetes.Ingress, globalWAFACL string) {
 	for _, ing := range ings {
-		if ing.WAFWebACLID != "" {
+		// ingresses with a rate limit or managed rule groups get a
+		// WebACL created by the controller
+		if ing.WAFWebACLID != "" || ing.WAFRateLimit > 0 || len(ing.WAFManagedRuleGroups) > 0 {
 			continue
 		}
 
@@ -478,41 +1586,180 @@ func buildManagedModel(
 	return model
 }
 
-func createStack(awsAdapter *aws.Adapter, lb *loadBalancer) {
+// stackOptions returns the options used to create or update the stack
+// backing the load balancer.
+func (l *loadBalancer) stackOptions(certificateARNs map[string]time.Time) *aws.StackOptions {
+	return &aws.StackOptions{
+		CertificateARNs:                        certificateARNs,
+		Scheme:                                 l.scheme,
+		SecurityGroup:                          l.securityGroup,
+		Owner:                                  l.Owner(),
+		SSLPolicy:                              l.sslPolicy,
+		IPAddressType:                          l.ipAddressType,
+		WAFWebACLID:                            l.wafWebACLID,
+		WAFRateLimit:                           l.wafRateLimit,
+		WAFRateLimitKey:                        l.wafRateLimitKey,
+		WAFManagedRuleGroups:                   l.wafManagedRuleGroups,
+		CWAlarms:                               l.cwAlarms,
+		TemplateFragments:                      l.templateFragments,
+		LoadBalancerType:                       l.loadBalancerType,
+		HTTP2:                                  l.http2,
+		PreserveHostHeader:                     l.preserveHostHeader,
+		HTTPDisabled:                           l.httpDisabled,
+		FrontingNLB:                            l.frontingNLB,
+		NLBSecurityGroup:                       l.nlbSecurityGroup,
+		LambdaTarget:                           l.lambdaTarget,
+		DNSHostnames:                           l.dnsHostnames,
+		InternalDomains:                        l.internalDomains,
+		Namespaces:                             l.namespaces,
+		SlowStart:                              l.slowStart,
+		ClientKeepAlive:                        l.clientKeepAlive,
+		HealthCheckMatcher:                     l.healthCheckMatcher,
+		PreserveClientIP:                       l.preserveClientIP,
+		ProxyProtocolV2:                        l.proxyProtocolV2,
+		ClientRoutingPolicy:                    l.clientRoutingPolicy,
+		CapacityUnits:                          l.capacityUnits,
+		DefaultCertificateARN:                  l.defaultCertificateARN,
+		ExternalTargetGroupARNs:                l.externalTargetGroupARNs,
+		ElasticIPs:                             l.elasticIPs,
+		IPv4Pool:                               l.ipv4Pool,
+		EndpointServicePrincipals:              l.endpointServicePrincipals,
+		DenyInternalDomains:                    l.denyInternalDomains,
+		DenyInternalDomainsResponse:            l.denyInternalDomainsResponse,
+		DenyInternalDomainsResponseContentType: l.denyInternalDomainsResponseContentType,
+		DenyInternalDomainsResponseStatusCode:  l.denyInternalDomainsResponseStatusCode,
+		HealthyThresholdCount:                  l.healthyThresholdCount,
+		UnhealthyThresholdCount:                l.unhealthyThresholdCount,
+		ListenerRules:                          l.listenerRules,
+		Authentication:                         l.authentication,
+		ResourceTags:                           l.resourceTags,
+		TemplateResourceTags:                   l.templateResourceTags,
+		LoadBalancerAttributes:                 l.loadBalancerAttributes,
+		TargetGroupAttributes:                  l.targetGroupAttributes,
+	}
+}
+
+func createStack(awsAdapter *aws.Adapter, lb *loadBalancer) error {
 	certificates := make([]string, 0, len(lb.ingresses))
+	certificateARNs := make(map[string]time.Time, len(lb.ingresses))
 	for cert := range lb.ingresses {
 		certificates = append(certificates, cert)
+		certificateARNs[cert] = time.Time{}
+	}
+
+	if clock.Now().Before(quotaBackoffUntil) {
+		log.Warnf("deferring creation of stack for certificates %q until %s: load balancer quota exhausted", certificates, quotaBackoffUntil.Format(time.RFC3339))
+		return nil
 	}
 
 	log.Infof("creating stack for certificates %q / ingress %q", certificates, lb.ingresses)
 
-	stackId, err := awsAdapter.CreateStack(certificates, lb.scheme, lb.securityGroup, lb.Owner(), lb.sslPolicy, lb.ipAddressType, lb.wafWebACLID, lb.cwAlarms, lb.loadBalancerType, lb.http2)
+	stackId, err := awsAdapter.CreateStack(lb.stackOptions(certificateARNs))
+	if aws.IsQuotaExceededError(err) {
+		handleQuotaExceeded(lb, stackId)
+		return nil
+	}
 	if err != nil {
 		if isAlreadyExistsError(err) {
 			lb.stack, err = awsAdapter.GetStack(stackId)
 			if err == nil {
-				return
+				return nil
 			}
 		}
 		log.Errorf("createStack(%q) failed: %v", certificates, err)
-	} else {
-		log.Infof("stack %q for certificates %q created", stackId, certificates)
+		countStackError("", "create")
+		ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonStackFailed,
+			fmt.Sprintf("Failed to create the stack of the load balancer: %v", err))
+		return err
+	}
+
+	log.Infof("stack %q for certificates %q created", stackId, certificates)
+	ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeNormal, eventReasonProvisioned,
+		fmt.Sprintf("Creating the load balancer in stack %s", stackId))
+	if quotaBackoff > 0 {
+		quotaBackoffStacks[stackId] = true
 	}
+	return nil
 }
 
-func updateStack(awsAdapter *aws.Adapter, lb *loadBalancer) {
+func updateStack(awsAdapter *aws.Adapter, lb *loadBalancer) error {
 	certificates := lb.CertificateARNs()
 
 	log.Infof("updating %q stack for %d certificates / %d ingresses", lb.scheme, len(certificates), len(lb.ingresses))
 
-	stackId, err := awsAdapter.UpdateStack(lb.stack.Name, certificates, lb.scheme, lb.securityGroup, lb.Owner(), lb.sslPolicy, lb.ipAddressType, lb.wafWebACLID, lb.cwAlarms, lb.loadBalancerType, lb.http2)
+	stackId, err := awsAdapter.UpdateStack(lb.stack.Name, lb.stackOptions(certificates))
+	if err == nil || isNoUpdatesToBePerformedError(err) {
+		pendingStackUpdates[lb.stack.Name] = false
+	}
+	var destructive *aws.DestructiveChangeSetError
+	var changeSetPending *aws.ChangeSetPendingError
 	if isNoUpdatesToBePerformedError(err) {
 		log.Debugf("stack(%q) is already up to date", certificates)
+	} else if errors.As(err, &changeSetPending) {
+		// the update continues with the change set in the next cycle
+		log.Infof("updateStack(%q) waiting: %v", certificates, err)
+		pendingStackUpdates[lb.stack.Name] = true
+	} else if errors.As(err, &destructive) {
+		log.Warnf("updateStack(%q) held back: %v", certificates, err)
+		countStackError(lb.stack.Name, "destructive-change-set")
+		ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonStackFailed,
+			fmt.Sprintf("Update of stack %s held back: %v", lb.stack.Name, err))
+		return err
 	} else if err != nil {
 		log.Errorf("updateStack(%q) failed: %v", certificates, err)
+		countStackError(lb.stack.Name, "update")
+		ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonStackFailed,
+			fmt.Sprintf("Failed to update stack %s: %v", lb.stack.Name, err))
+		return err
 	} else {
 		log.Infof("stack %q for certificate %q updated", stackId, certificates)
+		ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeNormal, eventReasonProvisioned,
+			fmt.Sprintf("Updating the load balancer in stack %s", stackId))
+	}
+	return nil
+}
+
+// updateStackTags updates the tags of the stack of the load balancer when
+// its template is up to date, e.g. to refresh the TTLs of its certificates.
+// Changed resource tags are set on the resources first and recorded by the
+// hash tag of the stack. If that fails, the stack is updated instead.
+func updateStackTags(awsAdapter *aws.Adapter, lb *loadBalancer) error {
+	certificates := lb.CertificateARNs()
+
+	if !lb.resourceTagsInSync() {
+		log.Infof("updating resource tags of stack %q", lb.stack.Name)
+		if err := awsAdapter.UpdateResourceTags(lb.stack, lb.stackOptions(certificates)); err != nil {
+			log.Warnf("failed to update the resource tags of stack %q, updating the stack instead: %v", lb.stack.Name, err)
+			countStackError(lb.stack.Name, "update-resource-tags")
+			return updateStack(awsAdapter, lb)
+		}
+	}
+
+	log.Infof("updating tags of %q stack %q", lb.scheme, lb.stack.Name)
+
+	_, err := awsAdapter.UpdateStackTags(lb.stack.Name, lb.stackOptions(certificates))
+	if isNoUpdatesToBePerformedError(err) {
+		log.Debugf("tags of stack %q are already up to date", lb.stack.Name)
+	} else if err != nil {
+		log.Errorf("updateStackTags(%q) failed: %v", lb.stack.Name, err)
+		countStackError(lb.stack.Name, "update-tags")
+		return err
+	} else {
+		log.Infof("tags of stack %q updated", lb.stack.Name)
 	}
+	return nil
+}
+
+// prunePendingStackUpdates forgets the applied updates and the deferred
+// updates of stacks which don't exist anymore.
+func prunePendingStackUpdates(stacks []*aws.Stack) {
+	pruned := make(map[string]bool)
+	for _, stack := range stacks {
+		if pendingStackUpdates[stack.Name] {
+			pruned[stack.Name] = true
+		}
+	}
+	pendingStackUpdates = pruned
 }
 
 func isAlreadyExistsError(err error) bool {
@@ -532,7 +1779,21 @@ func isNoUpdatesToBePerformedError(err error) bool {
 	return false
 }
 
-func updateIngress(kubeAdapter *kubernetes.Adapter, lb *loadBalancer) {
+// ingressStatus records the status of the ingresses of the load balancers
+// of a region.
+type ingressStatus struct {
+	// setDNSName records the DNS name of the load balancer of the ingress
+	// in the region.
+	setDNSName func(*kubernetes.Ingress, string)
+	// recordState is true if the states of the ingresses are recorded,
+	// which are the ones of the load balancers in the primary region.
+	recordState bool
+}
+
+// updateIngress records the DNS name of the load balancer for the status
+// updates of its ingresses, and queues the updates of their state
+// annotations.
+func updateIngress(lb *loadBalancer, status *ingressStatus) {
 	var dnsName string
 	if lb.clusterLocal {
 		dnsName = kubernetes.DefaultClusterLocalDomain
@@ -545,26 +1806,211 @@ func updateIngress(kubeAdapter *kubernetes.Adapter, lb *loadBalancer) {
 	}
 	for _, ingresses := range lb.ingresses {
 		for _, ing := range ingresses {
-			if err := kubeAdapter.UpdateIngressLoadBalancer(ing, dnsName); err != nil {
-				if err == kubernetes.ErrUpdateNotNeeded {
-					log.Debugf("Ingress update not needed %v with DNS name %q", ing, dnsName)
-				} else {
-					log.Errorf("Failed to update ingress: %v", err)
-				}
-			} else {
-				log.Infof("updated ingress %v with DNS name %q", ing, dnsName)
+			status.setDNSName(ing, dnsName)
+		}
+	}
+
+	if lb.stack == nil || !status.recordState {
+		return
+	}
+	for ing, certificateARNs := range lb.ingressCertificates() {
+		ingressStatusUpdates.addState(ing, kubernetes.IngressState{
+			StackName:           lb.stack.Name,
+			LoadBalancerARN:     lb.stack.LoadBalancerARN,
+			LoadBalancerType:    lb.stack.LoadBalancerType,
+			CertificateARNs:     certificateARNs,
+			TargetGroupARNs:     lb.stack.ListenerTargetGroupARNs(),
+			EndpointServiceName: lb.stack.EndpointServiceName,
+		})
+	}
+}
+
+// ingressCertificates returns the sorted ARNs of the certificates used for
+// each ingress of the load balancer.
+func (l *loadBalancer) ingressCertificates() map[*kubernetes.Ingress][]string {
+	certificates := make(map[*kubernetes.Ingress][]string)
+	for arn, ingresses := range l.ingresses {
+		for _, ing := range ingresses {
+			if arn == "" {
+				// keep ingresses without certificates
+				certificates[ing] = certificates[ing]
+				continue
 			}
+			certificates[ing] = append(certificates[ing], arn)
 		}
 	}
+	for _, arns := range certificates {
+		sort.Strings(arns)
+	}
+	return certificates
 }
 
-func deleteStack(awsAdapter *aws.Adapter, lb *loadBalancer) {
+func deleteStack(awsAdapter *aws.Adapter, lb *loadBalancer) error {
 	stackName := lb.stack.Name
+	drained, err := drainStack(lb.stack, awsAdapter.DetachStack, awsAdapter.StackTargetsDrained)
+	if err != nil {
+		log.Errorf("deleteStack failed to drain stack %q: %v", stackName, err)
+		countStackError(stackName, "drain")
+		return err
+	}
+	if !drained {
+		return nil
+	}
+
 	if err := awsAdapter.DeleteStack(lb.stack); err != nil {
 		log.Errorf("deleteStack failed to delete stack %q: %v", stackName, err)
-	} else {
-		log.Infof("deleted orphaned stack %q", stackName)
+		countStackError(stackName, "delete")
+		return err
 	}
+
+	log.Infof("deleted orphaned stack %q", stackName)
+	return nil
+}
+
+// drainStack detaches the targets from the target group of a stack about to
+// be deleted, so the load balancer completes the requests in flight within
+// the deregistration delay, instead of failing them when the stack is
+// deleted. It returns true once the targets are drained, or after
+// maxStackDraining.
+func drainStack(stack *aws.Stack, detach func(*aws.Stack) error, drained func(*aws.Stack) (bool, error)) (bool, error) {
+	since, ok := drainingStacks[stack.Name]
+	if !ok {
+		if err := detach(stack); err != nil {
+			return false, err
+		}
+		drainingStacks[stack.Name] = clock.Now()
+		log.Infof("draining the targets of stack %q before deleting it", stack.Name)
+		return false, nil
+	}
+
+	if clock.Now().Sub(since) >= maxStackDraining {
+		log.Warnf("targets of stack %q not drained after %s, deleting it anyway", stack.Name, maxStackDraining)
+		return true, nil
+	}
+	return drained(stack)
+}
+
+// withoutDrainingStacks returns the stacks whose targets aren't drained, so
+// the target groups of the draining ones aren't attached again.
+func withoutDrainingStacks(stacks []*aws.Stack) []*aws.Stack {
+	result := make([]*aws.Stack, 0, len(stacks))
+	for _, stack := range stacks {
+		if _, ok := drainingStacks[stack.Name]; !ok {
+			result = append(result, stack)
+		}
+	}
+	return result
+}
+
+// pruneDrainingStacks forgets the draining stacks which were deleted or
+// aren't to be deleted anymore, e.g. because an ingress uses them again.
+// Their target groups are attached again by the next reconciliation. Stacks
+// rolled back because of an exhausted quota are deleted like unused ones.
+func pruneDrainingStacks(loadBalancers []*loadBalancer) {
+	pruned := make(map[string]time.Time)
+	for _, lb := range loadBalancers {
+		if lb.stack == nil {
+			continue
+		}
+		if status := lb.Status(); status != delete && status != quotaExceeded {
+			continue
+		}
+		if since, ok := drainingStacks[lb.stack.Name]; ok {
+			pruned[lb.stack.Name] = since
+		}
+	}
+	drainingStacks = pruned
+}
+
+// validateResources checks the AWS resources referenced by the ingresses of
+// a load balancer before its stack is created or updated. Failures are
+// reported for each ingress and recorded as events on them.
+func validateResources(awsAdapter *aws.Adapter, lb *loadBalancer) bool {
+	err := awsAdapter.ValidateResources(lb.securityGroup, lb.wafWebACLID)
+	if err == nil {
+		return true
+	}
+
+	countStackError(lb.stackName(), "validate")
+	for _, ingresses := range lb.ingresses {
+		for _, ing := range ingresses {
+			log.Errorf("Invalid AWS resources referenced by %s %s: %v", ing.ResourceType(), ing, err)
+		}
+	}
+	ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonInvalidResources,
+		fmt.Sprintf("Invalid AWS resources referenced by the load balancer: %v", err))
+	return false
+}
+
+// handleQuotaExceeded reports the ingresses of a load balancer whose stack
+// couldn't be created because the quota of the account is exhausted and
+// backs off the creation of stacks, once per stack.
+func handleQuotaExceeded(lb *loadBalancer, stackName string) {
+	if quotaExceededStacks[stackName] {
+		return
+	}
+	quotaExceededStacks[stackName] = true
+	loadBalancerQuotaExceeded.Inc()
+
+	quotaBackoff *= 2
+	if quotaBackoff < minQuotaBackoff {
+		quotaBackoff = minQuotaBackoff
+	}
+	if quotaBackoff > maxQuotaBackoff {
+		quotaBackoff = maxQuotaBackoff
+	}
+	quotaBackoffUntil = clock.Now().Add(quotaBackoff)
+
+	log.Errorf("Failed to create stack %q: load balancer quota exhausted, retrying after %s", stackName, quotaBackoff)
+	ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonQuotaExceeded,
+		fmt.Sprintf("Failed to create the load balancer: load balancer quota of the account exhausted, retrying after %s", quotaBackoff))
+}
+
+// resetQuotaBackoff resets the backoff of the creation of stacks once the
+// creation of a stack created while backing off completes, because the
+// quota has capacity again.
+func resetQuotaBackoff(lb *loadBalancer) {
+	if lb.stack == nil || !quotaBackoffStacks[lb.stack.Name] || !lb.stack.IsComplete() {
+		return
+	}
+	quotaBackoffStacks = make(map[string]bool)
+	quotaBackoff = 0
+}
+
+// pruneQuotaExceededStacks forgets the failed stacks which don't exist
+// anymore.
+func pruneQuotaExceededStacks(stacks []*aws.Stack) {
+	pruned := make(map[string]bool)
+	for _, stack := range stacks {
+		if quotaExceededStacks[stack.Name] {
+			pruned[stack.Name] = true
+		}
+	}
+	quotaExceededStacks = pruned
+}
+
+// remediateStuckStack reports a stack stuck in a transient or failed state
+// and deletes it if the remediation asks for it. The ingresses of a deleted
+// stack get a new stack on one of the next updates.
+func remediateStuckStack(awsAdapter *aws.Adapter, lb *loadBalancer, remediation string) error {
+	log.Errorf("stack %q is stuck: %v", lb.stack.Name, lb.stack.Err())
+	countStackError(lb.stack.Name, "stuck")
+	ingressEvents.loadBalancerEvent(lb, kubernetes.EventTypeWarning, eventReasonStackFailed,
+		fmt.Sprintf("Stack %s is stuck: %v", lb.stack.Name, lb.stack.Err()))
+
+	if remediation != stuckStackRemediationDelete {
+		return nil
+	}
+
+	stackName := lb.stack.Name
+	if err := awsAdapter.DeleteStack(lb.stack); err != nil {
+		log.Errorf("remediateStuckStack failed to delete stack %q: %v", stackName, err)
+		countStackError(stackName, "delete")
+		return err
+	}
+
+	log.Infof("deleted stuck stack %q", stackName)
+	return nil
 }
 
 // getCloudWatchAlarms retrieves CloudWatch Alarm configuration from a
@@ -613,3 +2059,78 @@ func getCloudWatchAlarmsFromConfigMap(configMap *kubernetes.ConfigMap) aws.Cloud
 
 	return configList
 }
+
+// getTemplateFragments retrieves the template fragments from the ConfigMap
+// described by configMapLoc, or none if configMapLoc is nil.
+func getTemplateFragments(kubeAdapter *kubernetes.Adapter, configMapLoc *kubernetes.ResourceLocation) (aws.TemplateFragments, error) {
+	if configMapLoc == nil {
+		return nil, nil
+	}
+
+	configMap, err := kubeAdapter.GetConfigMap(configMapLoc.Namespace, configMapLoc.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return getTemplateFragmentsFromConfigMap(configMap), nil
+}
+
+// getTemplateFragmentsFromConfigMap parses the template fragments of all
+// ConfigMap data keys, sorted by key. Invalid fragments are logged and
+// ignored.
+func getTemplateFragmentsFromConfigMap(configMap *kubernetes.ConfigMap) aws.TemplateFragments {
+	keys := make([]string, 0, len(configMap.Data))
+	for k := range configMap.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var fragments aws.TemplateFragments
+	for _, key := range keys {
+		fragment, err := aws.NewTemplateFragmentFromYAML([]byte(configMap.Data[key]))
+		if err != nil {
+			log.Warnf("ignoring template fragment from config map key %q due to error: %v", key, err)
+			continue
+		}
+		fragments = append(fragments, fragment)
+	}
+
+	return fragments
+}
+
+func getInternalDomains(kubeAdapter *kubernetes.Adapter, configMapLoc *kubernetes.ResourceLocation) ([]string, error) {
+	if configMapLoc == nil {
+		return nil, nil
+	}
+
+	configMap, err := kubeAdapter.GetConfigMap(configMapLoc.Namespace, configMapLoc.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return getInternalDomainsFromConfigMap(configMap), nil
+}
+
+// getInternalDomainsFromConfigMap extracts the internal domains from all
+// ConfigMap data keys. Each line holds one domain, empty lines and lines
+// starting with '#' are ignored. The result is sorted and free of
+// duplicates.
+func getInternalDomainsFromConfigMap(configMap *kubernetes.ConfigMap) []string {
+	seen := make(map[string]bool)
+	var domains []string
+
+	for _, data := range configMap.Data {
+		for _, line := range strings.Split(data, "\n") {
+			domain := strings.TrimSpace(line)
+			if domain == "" || strings.HasPrefix(domain, "#") || seen[domain] {
+				continue
+			}
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+
+	sort.Strings(domains)
+
+	return domains
+}
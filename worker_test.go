@@ -2,13 +2,19 @@ package main
 
 import (
 	"crypto/x509"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	awscloudformation "github.com/aws/aws-sdk-go/service/cloudformation"
 	cloudformation "github.com/mweagle/go-cloudformation"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/aws"
+	"github.com/zalando-incubator/kube-ingress-aws-controller/aws/fake"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/certs"
 	"github.com/zalando-incubator/kube-ingress-aws-controller/kubernetes"
 )
@@ -142,6 +148,46 @@ func TestAddIngress(tt *testing.T) {
 			},
 			added: true,
 		},
+		{
+			name: "with WAF rate limit",
+			loadBalancer: &loadBalancer{
+				ingresses:       make(map[string][]*kubernetes.Ingress),
+				wafRateLimit:    2000,
+				wafRateLimitKey: aws.WAFRateLimitKeyIP,
+			},
+			ingress: &kubernetes.Ingress{
+				WAFRateLimit:    2000,
+				WAFRateLimitKey: aws.WAFRateLimitKeyIP,
+				Shared:          true,
+			},
+			added: true,
+		},
+		{
+			name: "with WAF rate limit, to not matching LB",
+			loadBalancer: &loadBalancer{
+				ingresses:       make(map[string][]*kubernetes.Ingress),
+				wafRateLimit:    2000,
+				wafRateLimitKey: aws.WAFRateLimitKeyIP,
+			},
+			ingress: &kubernetes.Ingress{
+				WAFRateLimit:    2000,
+				WAFRateLimitKey: aws.WAFRateLimitKeyForwardedIP,
+				Shared:          true,
+			},
+			added: false,
+		},
+		{
+			name: "with WAF managed rule groups, to not matching LB",
+			loadBalancer: &loadBalancer{
+				ingresses:            make(map[string][]*kubernetes.Ingress),
+				wafManagedRuleGroups: []string{"AWSManagedRulesCommonRuleSet"},
+			},
+			ingress: &kubernetes.Ingress{
+				WAFManagedRuleGroups: []string{"AWSManagedRulesCommonRuleSet", "AWSManagedRulesKnownBadInputsRuleSet"},
+				Shared:               true,
+			},
+			added: false,
+		},
 		{
 			name: "with WAF ACL id, to not matching LB",
 			loadBalancer: &loadBalancer{
@@ -165,6 +211,161 @@ func TestAddIngress(tt *testing.T) {
 			},
 			added: false,
 		},
+		{
+			name: "slow start not matching",
+			loadBalancer: &loadBalancer{
+				ingresses: make(map[string][]*kubernetes.Ingress),
+				slowStart: time.Minute,
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:    true,
+				SlowStart: 2 * time.Minute,
+			},
+			added: false,
+		},
+		{
+			name: "health check matcher not matching",
+			loadBalancer: &loadBalancer{
+				ingresses:          make(map[string][]*kubernetes.Ingress),
+				healthCheckMatcher: "200",
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:             true,
+				HealthCheckMatcher: "200-399",
+			},
+			added: false,
+		},
+		{
+			name: "healthy threshold count not matching",
+			loadBalancer: &loadBalancer{
+				ingresses:             make(map[string][]*kubernetes.Ingress),
+				healthyThresholdCount: 3,
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:                true,
+				HealthyThresholdCount: 5,
+			},
+			added: false,
+		},
+		{
+			name: "listener rules not matching",
+			loadBalancer: &loadBalancer{
+				ingresses: make(map[string][]*kubernetes.Ingress),
+			},
+			ingress: &kubernetes.Ingress{
+				Shared: true,
+				ListenerRules: aws.ListenerRuleList{
+					{PathPatterns: []string{"/admin/*"}, Action: aws.ListenerRuleActionDeny},
+				},
+			},
+			added: false,
+		},
+		{
+			name: "client keep alive not matching",
+			loadBalancer: &loadBalancer{
+				ingresses:       make(map[string][]*kubernetes.Ingress),
+				clientKeepAlive: time.Hour,
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:          true,
+				ClientKeepAlive: 2 * time.Hour,
+			},
+			added: false,
+		},
+		{
+			name: "preserve client IP not matching",
+			loadBalancer: &loadBalancer{
+				ingresses:        make(map[string][]*kubernetes.Ingress),
+				loadBalancerType: aws.LoadBalancerTypeNetwork,
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:           true,
+				LoadBalancerType: aws.LoadBalancerTypeNetwork,
+				PreserveClientIP: "false",
+			},
+			added: false,
+		},
+		{
+			name: "PROXY protocol v2 not matching",
+			loadBalancer: &loadBalancer{
+				ingresses:        make(map[string][]*kubernetes.Ingress),
+				loadBalancerType: aws.LoadBalancerTypeNetwork,
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:           true,
+				LoadBalancerType: aws.LoadBalancerTypeNetwork,
+				ProxyProtocolV2:  true,
+			},
+			added: false,
+		},
+		{
+			name: "capacity units not matching",
+			loadBalancer: &loadBalancer{
+				ingresses: make(map[string][]*kubernetes.Ingress),
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:        true,
+				CapacityUnits: 200,
+			},
+			added: false,
+		},
+		{
+			name: "client routing policy not matching",
+			loadBalancer: &loadBalancer{
+				ingresses:        make(map[string][]*kubernetes.Ingress),
+				loadBalancerType: aws.LoadBalancerTypeNetwork,
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:              true,
+				LoadBalancerType:    aws.LoadBalancerTypeNetwork,
+				ClientRoutingPolicy: aws.ClientRoutingPolicyAZAffinity,
+			},
+			added: false,
+		},
+		{
+			name: "preserve host header not matching",
+			loadBalancer: &loadBalancer{
+				ingresses: make(map[string][]*kubernetes.Ingress),
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:             true,
+				PreserveHostHeader: true,
+			},
+			added: false,
+		},
+		{
+			name: "HTTP disabled not matching",
+			loadBalancer: &loadBalancer{
+				ingresses: make(map[string][]*kubernetes.Ingress),
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:       true,
+				HTTPDisabled: true,
+			},
+			added: false,
+		},
+		{
+			name: "deny internal domains override not matching",
+			loadBalancer: &loadBalancer{
+				ingresses: make(map[string][]*kubernetes.Ingress),
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:              true,
+				DenyInternalDomains: "false",
+			},
+			added: false,
+		},
+		{
+			name: "deny internal domains response not matching",
+			loadBalancer: &loadBalancer{
+				ingresses: make(map[string][]*kubernetes.Ingress),
+			},
+			ingress: &kubernetes.Ingress{
+				Shared:                                true,
+				DenyInternalDomainsResponseStatusCode: 403,
+			},
+			added: false,
+		},
 	} {
 		tt.Run(test.name, func(t *testing.T) {
 			assert.Equal(
@@ -399,41 +600,584 @@ func TestGetCloudWatchAlarmConfigFromConfigMap(t *testing.T) {
 				{AlarmName: cloudformation.String("baz")},
 			},
 		},
-	} {
-		t.Run(test.name, func(t *testing.T) {
-			result := getCloudWatchAlarmsFromConfigMap(test.cm)
-
-			assert.Equal(t, test.expected, result)
-		})
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result := getCloudWatchAlarmsFromConfigMap(test.cm)
+
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestGetInternalDomainsFromConfigMap(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		cm       *kubernetes.ConfigMap
+		expected []string
+	}{
+		{
+			name:     "empty config map",
+			cm:       &kubernetes.ConfigMap{},
+			expected: nil,
+		},
+		{
+			name: "domains of all keys",
+			cm: &kubernetes.ConfigMap{
+				Data: map[string]string{
+					"some-key":       "*.internal\n# comment\n\n  *.cluster.local  \n",
+					"some-other-key": "*.corp\n*.internal",
+				},
+			},
+			expected: []string{"*.cluster.local", "*.corp", "*.internal"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, getInternalDomainsFromConfigMap(test.cm))
+		})
+	}
+}
+
+func TestGetTemplateFragmentsFromConfigMap(t *testing.T) {
+	cm := &kubernetes.ConfigMap{
+		Data: map[string]string{
+			"b-bucket": "Resources:\n  Bucket:\n    Type: AWS::S3::Bucket\n",
+			"a-topic":  `{"Resources": {"Topic": {"Type": "AWS::SNS::Topic"}}}`,
+			"invalid":  "Parameters:\n  Foo:\n    Type: String\n",
+		},
+	}
+
+	fragments := getTemplateFragmentsFromConfigMap(cm)
+	require.Len(t, fragments, 2)
+	assert.Contains(t, fragments[0].Resources, "Topic")
+	assert.Contains(t, fragments[1].Resources, "Bucket")
+}
+
+func TestAttachCloudWatchAlarmsCopy(t *testing.T) {
+	lbOne := &loadBalancer{scheme: "foo"}
+	lbTwo := &loadBalancer{scheme: "bar"}
+
+	lbs := []*loadBalancer{
+		lbOne,
+		lbTwo,
+	}
+
+	alarms := aws.CloudWatchAlarmList{
+		{AlarmName: cloudformation.String("baz")},
+	}
+
+	attachCloudWatchAlarms(lbs, alarms)
+
+	expected := []*loadBalancer{
+		{scheme: "foo", cwAlarms: alarms},
+		{scheme: "bar", cwAlarms: alarms},
+	}
+
+	require.Equal(t, expected, lbs)
+
+	// This should not modify the alarms of lbTwo.
+	lbOne.cwAlarms[0].AlarmName = cloudformation.String("qux")
+
+	assert.Equal(t, cloudformation.String("baz"), lbTwo.cwAlarms[0].AlarmName)
+}
+
+func TestFilterAllowedHostnames(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		suffixes []string
+		input    []*kubernetes.Ingress
+		expected []*kubernetes.Ingress
+	}{
+		{
+			name:     "no suffixes allow everything",
+			input:    []*kubernetes.Ingress{{Hostnames: []string{"foo.example.org", "foo.typo.org"}}},
+			expected: []*kubernetes.Ingress{{Hostnames: []string{"foo.example.org", "foo.typo.org"}}},
+		},
+		{
+			name:     "hostnames outside of the suffixes are removed",
+			suffixes: []string{"example.org", ".example.com"},
+			input:    []*kubernetes.Ingress{{Hostnames: []string{"foo.example.org", "foo.typo.org", "*.example.com", "example.org"}}},
+			expected: []*kubernetes.Ingress{{Hostnames: []string{"foo.example.org", "*.example.com", "example.org"}}},
+		},
+		{
+			name:     "suffix must match full labels",
+			suffixes: []string{"example.org"},
+			input:    []*kubernetes.Ingress{{Hostnames: []string{"fooexample.org"}}, {Hostnames: []string{"FOO.EXAMPLE.ORG"}}},
+			expected: []*kubernetes.Ingress{{Hostnames: []string{"FOO.EXAMPLE.ORG"}}},
+		},
+		{
+			name:     "cluster local ingresses are kept",
+			suffixes: []string{"example.org"},
+			input:    []*kubernetes.Ingress{{ClusterLocal: true}},
+			expected: []*kubernetes.Ingress{{ClusterLocal: true}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, filterAllowedHostnames(test.input, test.suffixes))
+		})
+	}
+}
+
+func TestFilterAllowedHostnamesEvents(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+			return nil
+		},
+	}
+
+	input := []*kubernetes.Ingress{
+		{Namespace: "ns", Name: "partly", Hostnames: []string{"foo.example.org", "foo.typo.org"}},
+		{Namespace: "ns", Name: "rejected", Hostnames: []string{"bar.typo.org"}},
+		{Namespace: "ns", Name: "allowed", Hostnames: []string{"bar.example.org"}},
+		{Namespace: "ns", Name: "hostless", CertificateARN: "arn:aws:acm:eu-central-1:123456789012:certificate/foo"},
+	}
+	expected := []*kubernetes.Ingress{
+		{Namespace: "ns", Name: "partly", Hostnames: []string{"foo.example.org"}},
+		{Namespace: "ns", Name: "allowed", Hostnames: []string{"bar.example.org"}},
+		{Namespace: "ns", Name: "hostless", CertificateARN: "arn:aws:acm:eu-central-1:123456789012:certificate/foo"},
+	}
+	require.Equal(t, expected, filterAllowedHostnames(input, []string{"example.org"}))
+	require.Equal(t, []string{
+		"ns/partly Warning HostnameNotAllowed Hostnames foo.typo.org not allowed, they don't match any of the allowed suffixes",
+		"ns/rejected Warning HostnameNotAllowed Ignored, none of the hostnames bar.typo.org is allowed",
+	}, recorded)
+}
+
+func TestEnforceMinSSLPolicy(t *testing.T) {
+	const (
+		weak   = "ELBSecurityPolicy-TLS-1-0-2015-04"
+		strong = "ELBSecurityPolicy-TLS13-1-2-2021-06"
+	)
+
+	for _, test := range []struct {
+		name      string
+		minPolicy string
+		mode      string
+		input     []*kubernetes.Ingress
+		expected  []*kubernetes.Ingress
+		events    []string
+	}{
+		{
+			name:     "no minimum policy allows everything",
+			mode:     minSSLPolicyModeReject,
+			input:    []*kubernetes.Ingress{{SSLPolicy: weak}},
+			expected: []*kubernetes.Ingress{{SSLPolicy: weak}},
+		},
+		{
+			name:      "weaker policies are upgraded",
+			minPolicy: strong,
+			mode:      minSSLPolicyModeUpgrade,
+			input:     []*kubernetes.Ingress{{Namespace: "ns", Name: "weak", SSLPolicy: weak}, {SSLPolicy: "ELBSecurityPolicy-TLS13-1-3-2021-06"}},
+			expected:  []*kubernetes.Ingress{{Namespace: "ns", Name: "weak", SSLPolicy: strong}, {SSLPolicy: "ELBSecurityPolicy-TLS13-1-3-2021-06"}},
+			events:    []string{"ns/weak Warning SSLPolicyUpgraded SSL policy " + weak + " upgraded to the minimum SSL policy " + strong},
+		},
+		{
+			name:      "weaker policies are rejected",
+			minPolicy: strong,
+			mode:      minSSLPolicyModeReject,
+			input:     []*kubernetes.Ingress{{Namespace: "ns", Name: "weak", SSLPolicy: weak}, {SSLPolicy: strong}},
+			expected:  []*kubernetes.Ingress{{SSLPolicy: strong}},
+			events:    []string{"ns/weak Warning SSLPolicyNotAllowed Ignored, SSL policy " + weak + " is weaker than the minimum SSL policy " + strong},
+		},
+		{
+			name:      "cluster local ingresses are kept",
+			minPolicy: strong,
+			mode:      minSSLPolicyModeReject,
+			input:     []*kubernetes.Ingress{{ClusterLocal: true, SSLPolicy: weak}},
+			expected:  []*kubernetes.Ingress{{ClusterLocal: true, SSLPolicy: weak}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var recorded []string
+			defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+			ingressEvents = &eventRecorder{
+				record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+					recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+					return nil
+				},
+			}
+
+			require.Equal(t, test.expected, enforceMinSSLPolicy(test.input, test.minPolicy, test.mode))
+			require.Equal(t, test.events, recorded)
+		})
+	}
+}
+
+func TestAttachNamespaces(t *testing.T) {
+	lb := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert-a":                             {{Namespace: "team-b"}, {Namespace: "team-a"}},
+			"cert-b":                             {{Namespace: "team-b"}},
+			kubernetes.DefaultClusterLocalDomain: {{Namespace: "team-c", ClusterLocal: true}},
+		},
+		namespaces: []string{"stale"},
+	}
+
+	attachNamespaces([]*loadBalancer{lb})
+
+	require.Equal(t, []string{"team-a", "team-b"}, lb.namespaces)
+}
+
+func TestEnforceMaxLoadBalancers(t *testing.T) {
+	existing := &loadBalancer{stack: &aws.Stack{Name: "existing"}, ingresses: map[string][]*kubernetes.Ingress{}}
+	local := &loadBalancer{clusterLocal: true, ingresses: map[string][]*kubernetes.Ingress{}}
+	newLB := func(cert string) *loadBalancer {
+		return &loadBalancer{ingresses: map[string][]*kubernetes.Ingress{cert: {{Namespace: "team", Name: cert}}}}
+	}
+	b, a, c := newLB("cert-b"), newLB("cert-a"), newLB("cert-c")
+	model := []*loadBalancer{existing, b, local, a, c}
+
+	require.Equal(t, model, enforceMaxLoadBalancers(model, 0))
+	require.Equal(t, model, enforceMaxLoadBalancers(model, 4))
+	require.Equal(t, []*loadBalancer{existing, b, local, a}, enforceMaxLoadBalancers(model, 3))
+	require.Equal(t, []*loadBalancer{existing, local}, enforceMaxLoadBalancers(model, 1))
+}
+
+func TestEnforceMaxLoadBalancersEvents(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+			return nil
+		},
+	}
+
+	existing := &loadBalancer{stack: &aws.Stack{Name: "existing"}, ingresses: map[string][]*kubernetes.Ingress{}}
+	a := &loadBalancer{ingresses: map[string][]*kubernetes.Ingress{"cert-a": {{Namespace: "team", Name: "a"}}}}
+	b := &loadBalancer{ingresses: map[string][]*kubernetes.Ingress{
+		"cert-b": {{Namespace: "team", Name: "b"}},
+		"cert-c": {{Namespace: "team", Name: "c"}},
+	}}
+
+	require.Equal(t, []*loadBalancer{existing, a}, enforceMaxLoadBalancers([]*loadBalancer{existing, b, a}, 2))
+	require.ElementsMatch(t, []string{
+		"team/b Warning LoadBalancerLimitExceeded Not creating the load balancer: the maximum of 2 load balancers is reached",
+		"team/c Warning LoadBalancerLimitExceeded Not creating the load balancer: the maximum of 2 load balancers is reached",
+	}, recorded)
+}
+
+func TestLimitCreationsToQuota(t *testing.T) {
+	existing := &loadBalancer{stack: &aws.Stack{Name: "existing"}, loadBalancerType: aws.LoadBalancerTypeApplication}
+	newLB := func(cert, lbType string, frontingNLB bool) *loadBalancer {
+		return &loadBalancer{
+			loadBalancerType: lbType,
+			frontingNLB:      frontingNLB,
+			ingresses:        map[string][]*kubernetes.Ingress{cert: {{Namespace: "team", Name: cert}}},
+		}
+	}
+	albA := newLB("cert-a", aws.LoadBalancerTypeApplication, false)
+	albB := newLB("cert-b", aws.LoadBalancerTypeApplication, false)
+	fronted := newLB("cert-c", aws.LoadBalancerTypeApplication, true)
+	nlb := newLB("cert-d", aws.LoadBalancerTypeNetwork, false)
+	model := []*loadBalancer{existing, albB, albA, fronted, nlb}
+
+	quotas := map[string]int{aws.LoadBalancerTypeApplication: 50, aws.LoadBalancerTypeNetwork: 50}
+	require.Equal(t, model, limitCreationsToQuota(model, map[string]int{aws.LoadBalancerTypeApplication: 10}, quotas))
+
+	require.Equal(t, []*loadBalancer{existing, albB, albA, fronted},
+		limitCreationsToQuota(model, map[string]int{aws.LoadBalancerTypeApplication: 47, aws.LoadBalancerTypeNetwork: 49}, quotas))
+
+	require.Equal(t, []*loadBalancer{existing, albA, nlb},
+		limitCreationsToQuota(model, map[string]int{aws.LoadBalancerTypeApplication: 49, aws.LoadBalancerTypeNetwork: 48}, quotas))
+
+	require.Equal(t, []*loadBalancer{existing, albB, albA},
+		limitCreationsToQuota(model, map[string]int{aws.LoadBalancerTypeApplication: 10, aws.LoadBalancerTypeNetwork: 50}, quotas))
+
+	require.Equal(t, model, limitCreationsToQuota(model, map[string]int{aws.LoadBalancerTypeNetwork: 100}, map[string]int{aws.LoadBalancerTypeApplication: 50}))
+}
+
+func TestLimitCreationsToQuotaEvents(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+			return nil
+		},
+	}
+
+	a := &loadBalancer{
+		loadBalancerType: aws.LoadBalancerTypeApplication,
+		ingresses:        map[string][]*kubernetes.Ingress{"cert-a": {{Namespace: "team", Name: "a"}}},
+	}
+	b := &loadBalancer{
+		loadBalancerType: aws.LoadBalancerTypeApplication,
+		ingresses:        map[string][]*kubernetes.Ingress{"cert-b": {{Namespace: "team", Name: "b"}}},
+	}
+
+	require.Equal(t, []*loadBalancer{a}, limitCreationsToQuota([]*loadBalancer{b, a},
+		map[string]int{aws.LoadBalancerTypeApplication: 49}, map[string]int{aws.LoadBalancerTypeApplication: 50}))
+	require.Equal(t, []string{
+		"team/b Warning WaitingForQuota Waiting to create the load balancer: 50 of 50 application load balancers of the account in use",
+	}, recorded)
+}
+
+func TestFilterAllowedAttributes(t *testing.T) {
+	ingress := &kubernetes.Ingress{
+		LoadBalancerAttributes: aws.Attributes{
+			"routing.http.drop_invalid_header_fields.enabled": "true",
+			"deletion_protection.enabled":                     "true",
+		},
+		TargetGroupAttributes: aws.Attributes{"load_balancing.algorithm.type": "least_outstanding_requests"},
+	}
+	filterAllowedAttributes([]*kubernetes.Ingress{ingress}, []string{"routing.http.*"}, nil)
+
+	assert.Equal(t, aws.Attributes{"routing.http.drop_invalid_header_fields.enabled": "true"}, ingress.LoadBalancerAttributes)
+	assert.Empty(t, ingress.TargetGroupAttributes)
+	assert.Equal(t, aws.HashAttributes(ingress.LoadBalancerAttributes, nil),
+		aws.HashAttributes(ingress.LoadBalancerAttributes, ingress.TargetGroupAttributes))
+}
+
+func TestHandleListenerDrift(t *testing.T) {
+	defer func(v bool) { firstRun = v }(firstRun)
+	firstRun = false
+
+	lb := func(name string) *loadBalancer {
+		return &loadBalancer{
+			stack: &aws.Stack{
+				Name:            name,
+				LoadBalancerARN: name + "-arn",
+				CertificateARNs: map[string]time.Time{"cert": {}},
+			},
+			ingresses: map[string][]*kubernetes.Ingress{"cert": {{}}},
+		}
+	}
+	inSync := lb("in-sync")
+	drifted := lb("drifted")
+	failing := lb("failing")
+	outOfSync := lb("out-of-sync")
+	outOfSync.ingresses["other"] = []*kubernetes.Ingress{{}}
+	withoutARN := lb("without-arn")
+	withoutARN.stack.LoadBalancerARN = ""
+	loadBalancers := []*loadBalancer{inSync, drifted, failing, outOfSync, withoutARN}
+
+	var checked []string
+	detect := func(stack *aws.Stack) (*aws.ListenerDrift, error) {
+		checked = append(checked, stack.Name)
+		switch stack.Name {
+		case "drifted":
+			return &aws.ListenerDrift{SSLPolicy: "ELBSecurityPolicy-2016-08"}, nil
+		case "failing":
+			return nil, errors.New("failed")
+		}
+		return &aws.ListenerDrift{}, nil
+	}
+	var reverted []string
+	revert := func(stack *aws.Stack, _ *aws.ListenerDrift) error {
+		reverted = append(reverted, stack.Name)
+		return nil
+	}
+
+	handleListenerDrift(loadBalancers, detect, revert, listenerDriftAlert)
+	assert.Equal(t, []string{"in-sync", "drifted", "failing"}, checked)
+	assert.Empty(t, reverted)
+	assert.Equal(t, 1.0, testutil.ToFloat64(listenerDrift))
+
+	handleListenerDrift(loadBalancers, detect, revert, listenerDriftRevert)
+	assert.Equal(t, []string{"drifted"}, reverted)
+	assert.Equal(t, 0.0, testutil.ToFloat64(listenerDrift))
+}
+
+func TestKeepFirewallManagerWebACLs(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+			return nil
+		},
+	}
+
+	stack := func(name, webACL string) *aws.Stack {
+		return &aws.Stack{Name: name, LoadBalancerARN: name + "-arn", WAFWebACLID: webACL}
+	}
+	ingresses := map[string][]*kubernetes.Ingress{"cert": {{Namespace: "team", Name: "app"}}}
+	conflicting := &loadBalancer{stack: stack("conflicting", "old-acl"), wafWebACLID: "new-acl", ingresses: ingresses}
+	rateLimited := &loadBalancer{stack: stack("rate-limited", ""), wafRateLimit: 2000, ingresses: ingresses}
+	withoutWebACL := &loadBalancer{stack: stack("without-webacl", ""), ingresses: ingresses}
+	withoutARN := &loadBalancer{stack: &aws.Stack{Name: "without-arn"}, wafWebACLID: "new-acl", ingresses: ingresses}
+	failing := &loadBalancer{stack: stack("failing", "old-acl"), wafWebACLID: "new-acl", ingresses: ingresses}
+
+	var checked []string
+	keepFirewallManagerWebACLs(
+		[]*loadBalancer{conflicting, rateLimited, withoutWebACL, withoutARN, failing},
+		func(arn string) (string, error) {
+			checked = append(checked, arn)
+			switch arn {
+			case "failing-arn":
+				return "", errors.New("access denied")
+			case "rate-limited-arn":
+				return "", nil
+			}
+			return "fms-acl", nil
+		},
+	)
+
+	assert.Equal(t, []string{"conflicting-arn", "rate-limited-arn", "failing-arn"}, checked)
+	assert.Equal(t, "old-acl", conflicting.wafWebACLID)
+	assert.Equal(t, "new-acl", failing.wafWebACLID)
+	assert.Equal(t, int64(2000), rateLimited.wafRateLimit)
+	assert.Equal(t, []string{
+		"team/app Warning FirewallManagerConflict WebACL overridden by the WebACL fms-acl of Firewall Manager associated with the load balancer, not updating it",
+	}, recorded)
+}
+
+func TestAttachTemplateResourceTags(t *testing.T) {
+	tmpl, err := kubernetes.NewResourceTagsTemplate(map[string]string{
+		"namespace": "{{.Namespace}}",
+		"team":      "{{.Labels.team}}",
+	})
+	require.NoError(t, err)
+
+	lb := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert-a":                             {{Namespace: "ns-b", Labels: map[string]string{"team": "foo"}}, {Namespace: "ns-a"}},
+			"cert-b":                             {{Namespace: "ns-b", Labels: map[string]string{"team": "bar"}}},
+			kubernetes.DefaultClusterLocalDomain: {{Namespace: "ns-c", ClusterLocal: true}},
+		},
+		templateResourceTags: aws.ResourceTags{"stale": "true"},
+	}
+	empty := &loadBalancer{ingresses: map[string][]*kubernetes.Ingress{}}
+
+	attachTemplateResourceTags([]*loadBalancer{lb, empty}, tmpl, nil)
+
+	require.Equal(t, aws.ResourceTags{"namespace": "ns-a ns-b", "team": "bar foo"}, lb.templateResourceTags)
+	require.Nil(t, empty.templateResourceTags)
+
+	// the rendered tags take precedence over the additional ones
+	attachTemplateResourceTags([]*loadBalancer{lb, empty}, tmpl, aws.ResourceTags{"team": "platform", "compliance": "pci"})
+
+	require.Equal(t, aws.ResourceTags{"namespace": "ns-a ns-b", "team": "bar foo", "compliance": "pci"}, lb.templateResourceTags)
+	require.Nil(t, empty.templateResourceTags)
+
+	attachTemplateResourceTags([]*loadBalancer{lb}, nil, aws.ResourceTags{"compliance": "pci"})
+	require.Equal(t, aws.ResourceTags{"compliance": "pci"}, lb.templateResourceTags)
+}
+
+func TestAttachSharedDNSHostnames(t *testing.T) {
+	ingress := func(hostnames ...string) []*kubernetes.Ingress {
+		return []*kubernetes.Ingress{{Hostnames: hostnames}}
+	}
+
+	lbOne := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert-a": ingress("foo.example.org", "bar.example.org"),
+		},
+	}
+	lbTwo := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert-b": ingress("foo.example.org"),
+			"cert-c": ingress("baz.example.org"),
+		},
+	}
+	deleted := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert-d": ingress("bar.example.org"),
+		},
+		stack: &aws.Stack{
+			CertificateARNs: map[string]time.Time{
+				"cert-d": time.Now().Add(-time.Minute),
+			},
+		},
+	}
+	clusterLocal := &loadBalancer{
+		clusterLocal: true,
+		ingresses: map[string][]*kubernetes.Ingress{
+			kubernetes.DefaultClusterLocalDomain: ingress("baz.example.org"),
+		},
 	}
-}
 
-func TestAttachCloudWatchAlarmsCopy(t *testing.T) {
-	lbOne := &loadBalancer{scheme: "foo"}
-	lbTwo := &loadBalancer{scheme: "bar"}
+	attachSharedDNSHostnames([]*loadBalancer{lbOne, lbTwo, deleted, clusterLocal})
 
-	lbs := []*loadBalancer{
-		lbOne,
-		lbTwo,
+	require.Equal(t, []string{"foo.example.org"}, lbOne.dnsHostnames)
+	require.Equal(t, []string{"foo.example.org"}, lbTwo.dnsHostnames)
+	require.Nil(t, clusterLocal.dnsHostnames)
+
+	// the records of a hostname which was shared are kept on the remaining
+	// load balancer
+	lbOne.stack = &aws.Stack{DNSHostnames: []string{"foo.example.org"}}
+	attachSharedDNSHostnames([]*loadBalancer{lbOne})
+	require.Equal(t, []string{"foo.example.org"}, lbOne.dnsHostnames)
+}
+
+func TestSkipConflictingDNSRecords(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, _ string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s", ing, eventType, reason))
+			return nil
+		},
 	}
 
-	alarms := aws.CloudWatchAlarmList{
-		{AlarmName: cloudformation.String("baz")},
+	f := fake.New()
+	f.AddCluster("cluster", "controller", "vpc-1")
+	f.Route53.AddHostedZone("zone-1", "example.org", false)
+	f.Route53.AddRecord("zone-1", "foo.example.org", "A", "")
+	f.Route53.AddRecord("zone-1", "bar.example.org", "A", "stack")
+	f.Route53.AddRecord("zone-1", "baz.example.org", "A", "")
+	awsAdapter, err := f.NewAdapter("cluster", "controller", "vpc-1")
+	require.NoError(t, err)
+
+	lb := &loadBalancer{
+		scheme: "internet-facing",
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert": {
+				{Namespace: "default", Name: "foo", Hostnames: []string{"foo.example.org"}},
+				{Namespace: "default", Name: "bar", Hostnames: []string{"bar.example.org", "baz.example.org"}},
+			},
+		},
+		// the records of baz.example.org are managed by the stack
+		// already, so they aren't checked again
+		stack:        &aws.Stack{Name: "stack", DNSHostnames: []string{"baz.example.org"}},
+		dnsHostnames: []string{"bar.example.org", "baz.example.org", "foo.example.org"},
 	}
+	skipConflictingDNSRecords(awsAdapter, []*loadBalancer{lb})
 
-	attachCloudWatchAlarms(lbs, alarms)
+	require.Equal(t, []string{"bar.example.org", "baz.example.org"}, lb.dnsHostnames)
+	require.Equal(t, []string{"default/foo Warning DNSRecordConflict"}, recorded)
+}
 
-	expected := []*loadBalancer{
-		{scheme: "foo", cwAlarms: alarms},
-		{scheme: "bar", cwAlarms: alarms},
+func TestAttachDNSRecords(t *testing.T) {
+	newRegion := func() *region {
+		f := fake.New()
+		f.AddCluster("cluster", "controller", "vpc-1")
+		f.Route53.AddHostedZone("zone-1", "example.org", false)
+		awsAdapter, err := f.NewAdapter("cluster", "controller", "vpc-1")
+		require.NoError(t, err)
+		return &region{awsAdapter: awsAdapter}
+	}
+	ingress := func(hostnames ...string) []*kubernetes.Ingress {
+		return []*kubernetes.Ingress{{Hostnames: hostnames}}
 	}
 
-	require.Equal(t, expected, lbs)
+	primary := &loadBalancer{
+		scheme: "internet-facing",
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert-a": ingress("foo.example.org", "bar.example.org"),
+		},
+	}
+	additional := &loadBalancer{
+		scheme: "internet-facing",
+		ingresses: map[string][]*kubernetes.Ingress{
+			"cert-b": ingress("foo.example.org"),
+		},
+	}
 
-	// This should not modify the alarms of lbTwo.
-	lbOne.cwAlarms[0].AlarmName = cloudformation.String("qux")
+	attachDNSRecords([]*regionalModel{
+		{region: newRegion(), primary: true, loadBalancers: []*loadBalancer{primary}},
+		{region: newRegion(), loadBalancers: []*loadBalancer{additional}},
+	})
 
-	assert.Equal(t, cloudformation.String("baz"), lbTwo.cwAlarms[0].AlarmName)
+	// the hostname served in both regions gets a weighted record in each
+	hash := aws.HashDNSRecords(map[string]string{"foo.example.org": "zone-1"})
+	require.Equal(t, []string{"foo.example.org"}, primary.dnsHostnames)
+	require.Equal(t, hash, primary.dnsRecordsHash)
+	require.Equal(t, []string{"foo.example.org"}, additional.dnsHostnames)
+	require.Equal(t, hash, additional.dnsRecordsHash)
 }
 
 func TestIsLBInSync(t *testing.T) {
@@ -521,6 +1265,49 @@ func TestIsLBInSync(t *testing.T) {
 			wafWebACLID: "foo-bar-baz",
 		},
 		expect: true,
+	}, {
+		title: "not matching DNS hostnames",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": []*kubernetes.Ingress{{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": time.Time{},
+				},
+				CWAlarmConfigHash: aws.CloudWatchAlarmList{{}}.Hash(),
+			},
+			cwAlarms:       aws.CloudWatchAlarmList{{}},
+			dnsRecordsHash: aws.HashDNSRecords(map[string]string{"foo.example.org": "Z1"}),
+		},
+	}, {
+		title: "not matching namespaces",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": []*kubernetes.Ingress{{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": time.Time{},
+				},
+				NamespacesTag: "default",
+			},
+			namespaces: []string{"default", "team-a"},
+		},
+	}, {
+		title: "not matching internal domains",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": []*kubernetes.Ingress{{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": time.Time{},
+				},
+				InternalDomainsHash: aws.HashInternalDomains([]string{"*.cluster.local"}),
+			},
+			internalDomains: []string{"*.cluster.local", "*.internal"},
+		},
 	}} {
 		t.Run(test.title, func(t *testing.T) {
 			require.Equal(t, test.expect, test.lb.inSync())
@@ -528,6 +1315,299 @@ func TestIsLBInSync(t *testing.T) {
 	}
 }
 
+func TestIsLBTemplateInSync(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).UTC()
+	for _, test := range []struct {
+		title          string
+		lb             *loadBalancer
+		templateInSync bool
+		tagsInSync     bool
+	}{{
+		title: "in sync",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": {{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": {},
+				},
+			},
+		},
+		templateInSync: true,
+		tagsInSync:     true,
+	}, {
+		title: "certificate TTL not tagged yet",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": {{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": {},
+					"bar": {},
+				},
+			},
+			certTTL: time.Hour,
+		},
+		templateInSync: true,
+		tagsInSync:     false,
+	}, {
+		title: "certificate TTL tagged",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": {{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": {},
+					"bar": expiry,
+				},
+			},
+		},
+		templateInSync: true,
+		tagsInSync:     true,
+	}, {
+		title: "certificate expired",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": {{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": {},
+					"bar": time.Now().Add(-time.Minute),
+				},
+			},
+		},
+		templateInSync: false,
+		tagsInSync:     false,
+	}, {
+		title: "namespaces changed",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": {{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": {},
+				},
+				NamespacesTag: aws.NamespacesTagValue([]string{"default"}),
+			},
+			namespaces: []string{"default", "kube-system"},
+		},
+		templateInSync: true,
+		tagsInSync:     false,
+	}, {
+		title: "new certificate",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": {{}},
+				"bar": {{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": {},
+				},
+			},
+		},
+		templateInSync: false,
+		tagsInSync:     false,
+	}, {
+		title: "tagged by the previous owner",
+		lb: &loadBalancer{
+			ingresses: map[string][]*kubernetes.Ingress{
+				"foo": {{}},
+			},
+			stack: &aws.Stack{
+				CertificateARNs: map[string]time.Time{
+					"foo": {},
+				},
+				PreviousOwner: true,
+			},
+		},
+		templateInSync: true,
+		tagsInSync:     false,
+	}} {
+		t.Run(test.title, func(t *testing.T) {
+			require.Equal(t, test.templateInSync, test.lb.templateInSync())
+			require.Equal(t, test.tagsInSync, test.lb.tagsInSync())
+		})
+	}
+}
+
+func TestResourceTagsInSync(t *testing.T) {
+	tags := aws.ResourceTags{"team": "foo"}
+	lb := &loadBalancer{
+		ingresses:            map[string][]*kubernetes.Ingress{"foo": {{}}},
+		stack:                &aws.Stack{CertificateARNs: map[string]time.Time{"foo": {}}},
+		templateResourceTags: tags,
+	}
+	require.True(t, lb.templateInSync())
+	require.False(t, lb.resourceTagsInSync())
+	require.False(t, lb.inSync())
+
+	lb.stack.TemplateResourceTagsHash = tags.Hash()
+	require.True(t, lb.resourceTagsInSync())
+	require.True(t, lb.inSync())
+}
+
+func TestLoadBalancerStatusPending(t *testing.T) {
+	lb := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"foo": {{}},
+		},
+		stack: &aws.Stack{
+			Name: "stack",
+			CertificateARNs: map[string]time.Time{
+				"bar": time.Time{},
+			},
+		},
+	}
+	require.Equal(t, pending, lb.Status())
+
+	pendingStackUpdates["stack"] = true
+	prunePendingStackUpdates([]*aws.Stack{{Name: "other"}})
+	require.False(t, pendingStackUpdates["stack"])
+}
+
+func TestLoadBalancerStatusPaused(t *testing.T) {
+	stack := &aws.Stack{
+		Name:            "stack",
+		CertificateARNs: map[string]time.Time{"bar": time.Time{}},
+	}
+	lb := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{"foo": {{}, {Paused: true}}},
+		stack:     stack,
+	}
+	require.Equal(t, paused, lb.Status())
+
+	lb.ingresses["foo"][1].Paused = false
+	require.Equal(t, pending, lb.Status())
+
+	stack.Paused = true
+	stack.CertificateARNs = map[string]time.Time{"bar": time.Now().Add(-time.Hour)}
+	lb.ingresses = map[string][]*kubernetes.Ingress{}
+	require.Equal(t, paused, lb.Status())
+
+	lb.stack = nil
+	lb.ingresses = map[string][]*kubernetes.Ingress{"foo": {{Paused: true}}}
+	require.Equal(t, missing, lb.Status())
+}
+
+func TestLoadBalancerStatusDeleteAfterCertificateTTL(t *testing.T) {
+	fakeClock := fake.NewClock(time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC))
+	defer func(c aws.Clock) { clock = c }(clock)
+	clock = fakeClock
+
+	lb := &loadBalancer{
+		stack: &aws.Stack{
+			Name: "stack",
+			CertificateARNs: map[string]time.Time{
+				"foo": fakeClock.Now().Add(time.Hour),
+			},
+		},
+	}
+	require.NotEqual(t, delete, lb.Status())
+
+	fakeClock.Advance(time.Hour)
+	require.Equal(t, delete, lb.Status())
+}
+
+func TestDrainStack(t *testing.T) {
+	fakeClock := fake.NewClock(time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC))
+	defer func(c aws.Clock) { clock = c }(clock)
+	clock = fakeClock
+	defer func(stacks map[string]time.Time) { drainingStacks = stacks }(drainingStacks)
+	drainingStacks = make(map[string]time.Time)
+
+	stack := &aws.Stack{Name: "stack"}
+	other := &aws.Stack{Name: "other"}
+	detached := 0
+	detach := func(*aws.Stack) error {
+		detached++
+		return nil
+	}
+	targets := 1
+	drained := func(*aws.Stack) (bool, error) {
+		return targets == 0, nil
+	}
+
+	done, err := drainStack(stack, detach, drained)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, 1, detached)
+	require.Equal(t, []*aws.Stack{other}, withoutDrainingStacks([]*aws.Stack{stack, other}))
+
+	// the targets are detached once
+	done, err = drainStack(stack, detach, drained)
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, 1, detached)
+
+	targets = 0
+	done, err = drainStack(stack, detach, drained)
+	require.NoError(t, err)
+	require.True(t, done)
+
+	// stacks are deleted after the maximum deregistration delay
+	targets = 1
+	fakeClock.Advance(maxStackDraining)
+	done, err = drainStack(stack, detach, drained)
+	require.NoError(t, err)
+	require.True(t, done)
+
+	// the stack isn't deleted anymore
+	stack.CertificateARNs = map[string]time.Time{"foo": {}}
+	pruneDrainingStacks([]*loadBalancer{{stack: stack, ingresses: map[string][]*kubernetes.Ingress{}}})
+	require.Empty(t, drainingStacks)
+}
+
+func TestReconcileLoadBalancerDeletesQuotaExceededStack(t *testing.T) {
+	fakeClock := fake.NewClock(time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC))
+	defer func(c aws.Clock) { clock = c }(clock)
+	clock = fakeClock
+	defer func(stacks map[string]time.Time) { drainingStacks = stacks }(drainingStacks)
+	drainingStacks = make(map[string]time.Time)
+	defer func() {
+		quotaBackoff, quotaBackoffUntil = 0, time.Time{}
+		quotaExceededStacks = make(map[string]bool)
+	}()
+
+	f := fake.New()
+	f.AddCluster("cluster", "controller", "vpc-1")
+	f.CloudFormation.AddStack(&awscloudformation.Stack{
+		StackName:   awssdk.String("rolled-back"),
+		StackStatus: awssdk.String(awscloudformation.StackStatusRollbackComplete),
+		Tags: []*awscloudformation.Tag{
+			{Key: awssdk.String("kubernetes:application"), Value: awssdk.String("controller")},
+			{Key: awssdk.String("kubernetes.io/cluster/cluster"), Value: awssdk.String("owned")},
+		},
+		Outputs: []*awscloudformation.Output{
+			{OutputKey: awssdk.String("TargetGroupARN"), OutputValue: awssdk.String("arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/rolled-back")},
+		},
+	})
+	f.CloudFormation.AddStackEvent("rolled-back", &awscloudformation.StackEvent{
+		ResourceStatus:       awssdk.String(awscloudformation.ResourceStatusCreateFailed),
+		ResourceStatusReason: awssdk.String("You've reached the limit on the number of load balancers (Error Code: TooManyLoadBalancers)"),
+	})
+	awsAdapter, err := f.NewAdapter("cluster", "controller", "vpc-1")
+	require.NoError(t, err)
+
+	for cycle := 0; cycle < 3 && len(f.CloudFormation.Stacks()) > 0; cycle++ {
+		stacks, err := awsAdapter.FindManagedStacks()
+		require.NoError(t, err)
+		require.Len(t, stacks, 1)
+
+		lb := &loadBalancer{stack: stacks[0], ingresses: map[string][]*kubernetes.Ingress{}}
+		require.Equal(t, quotaExceeded, lb.Status())
+		reconcileLoadBalancer(awsAdapter, lb, &ingressStatus{})
+		pruneDrainingStacks([]*loadBalancer{lb})
+		fakeClock.Advance(time.Minute)
+	}
+	require.Empty(t, f.CloudFormation.Stacks())
+}
+
 func TestMatchIngressesToLoadbalancers(t *testing.T) {
 	defaultMaxCertsPerLB := 3
 	defaultCerts := &certmock{
@@ -748,6 +1828,29 @@ func TestMatchIngressesToLoadbalancers(t *testing.T) {
 	}
 }
 
+func TestMatchIngressesToLoadBalancersFallbackCertificate(t *testing.T) {
+	finder := &certmock{
+		summaries: []*certs.CertificateSummary{
+			certs.NewCertificate("foo", &x509.Certificate{DNSNames: []string{"foo.org"}}, nil),
+			certs.NewCertificate("fallback", &x509.Certificate{DNSNames: []string{"fallback.org"}}, nil),
+		},
+	}
+	ingresses := []*kubernetes.Ingress{
+		{Name: "no-hostnames", LoadBalancerType: aws.LoadBalancerTypeApplication, Shared: true},
+		{Name: "no-certificate", LoadBalancerType: aws.LoadBalancerTypeApplication, Shared: true, Hostnames: []string{"bar.org"}},
+	}
+
+	lbs := matchIngressesToLoadBalancers(nil, finder, 3, ingresses)
+	require.Len(t, lbs, 1)
+
+	defer func(arn string) { defaultCertificateARN = arn }(defaultCertificateARN)
+	defaultCertificateARN = "fallback"
+
+	lbs = matchIngressesToLoadBalancers(nil, finder, 3, ingresses)
+	require.Len(t, lbs, 2)
+	require.Len(t, lbs[1].ingresses["fallback"], 2)
+}
+
 func TestBuildModel(t *testing.T) {
 	defaultMaxCertsPerLB := 3
 	defaultCerts := &certmock{
@@ -932,3 +2035,216 @@ func TestBuildModel(t *testing.T) {
 		})
 	}
 }
+
+func TestEnforceMaxListenerRules(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+			return nil
+		},
+	}
+
+	ingresses := []*kubernetes.Ingress{
+		{Namespace: "default", Name: "few", ListenerRules: make(aws.ListenerRuleList, 2)},
+		{Namespace: "default", Name: "many", ListenerRules: make(aws.ListenerRuleList, 5)},
+	}
+
+	enforceMaxListenerRules(ingresses, 0)
+	require.Len(t, ingresses[1].ListenerRules, 5)
+	require.Empty(t, recorded)
+
+	// the ingress is kept without its listener rules
+	enforceMaxListenerRules(ingresses, 4)
+	require.Len(t, ingresses[0].ListenerRules, 2)
+	require.Empty(t, ingresses[1].ListenerRules)
+	require.Equal(t, []string{
+		"default/many Warning ListenerRulesLimitExceeded Ignoring listener rules: 5 listener rules exceed the quota of 4",
+	}, recorded)
+}
+
+func TestMaxListenerRulesForQuota(t *testing.T) {
+	require.Equal(t, 0, maxListenerRulesForQuota(0))
+	require.Equal(t, 49, maxListenerRulesForQuota(100))
+	require.Equal(t, 1, maxListenerRulesForQuota(2))
+}
+
+func TestValidateResourcesEvents(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+			return nil
+		},
+	}
+
+	f := fake.New()
+	f.AddCluster("cluster", "controller", "vpc-1")
+	f.EC2.AddSecurityGroup("sg-other", "other", "vpc-2", nil)
+	awsAdapter, err := f.NewAdapter("cluster", "controller", "vpc-1")
+	require.NoError(t, err)
+
+	lb := &loadBalancer{
+		securityGroup: "sg-cluster",
+		ingresses:     map[string][]*kubernetes.Ingress{"cert": {{Namespace: "ns", Name: "a"}}},
+	}
+	require.True(t, validateResources(awsAdapter, lb))
+	require.Empty(t, recorded)
+
+	lb.securityGroup = "sg-missing"
+	require.False(t, validateResources(awsAdapter, lb))
+	lb.securityGroup = "sg-other"
+	require.False(t, validateResources(awsAdapter, lb))
+	require.Equal(t, []string{
+		"ns/a Warning InvalidResources Invalid AWS resources referenced by the load balancer: security group sg-missing not found",
+		"ns/a Warning InvalidResources Invalid AWS resources referenced by the load balancer: security group sg-other belongs to VPC vpc-2 instead of vpc-1",
+	}, recorded)
+}
+
+func TestHandleQuotaExceeded(t *testing.T) {
+	defer func() {
+		quotaBackoff, quotaBackoffUntil = 0, time.Time{}
+		quotaExceededStacks = make(map[string]bool)
+	}()
+
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, _ string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s", ing, eventType, reason))
+			return nil
+		},
+	}
+
+	lb := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"foo": {{Namespace: "default", Name: "foo"}},
+		},
+	}
+
+	handleQuotaExceeded(lb, "a")
+	require.Equal(t, minQuotaBackoff, quotaBackoff)
+	require.True(t, quotaBackoffUntil.After(time.Now()))
+	require.Equal(t, []string{"default/foo Warning QuotaExceeded"}, recorded)
+
+	// the failed stack is reported once, not on every reconciliation
+	handleQuotaExceeded(lb, "a")
+	require.Equal(t, minQuotaBackoff, quotaBackoff)
+	require.Len(t, recorded, 1)
+
+	handleQuotaExceeded(lb, "b")
+	require.Equal(t, 2*minQuotaBackoff, quotaBackoff)
+	require.Len(t, recorded, 2)
+
+	quotaBackoff = maxQuotaBackoff
+	handleQuotaExceeded(lb, "c")
+	require.Equal(t, maxQuotaBackoff, quotaBackoff)
+
+	pruneQuotaExceededStacks([]*aws.Stack{{Name: "c"}})
+	require.Equal(t, map[string]bool{"c": true}, quotaExceededStacks)
+}
+
+func TestReconcileLoadBalancerRecovers(t *testing.T) {
+	lb := &loadBalancer{
+		ingresses: map[string][]*kubernetes.Ingress{
+			"foo": {{}},
+		},
+	}
+
+	// creating the stack without an adapter panics
+	require.NotPanics(t, func() { reconcileLoadBalancer(nil, lb, &ingressStatus{}) })
+	require.Equal(t, float64(1), testutil.ToFloat64(stackErrors.WithLabelValues("", "reconcile")))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	now := time.Now()
+	b := newRetryBackoff(time.Minute, 4*time.Minute)
+
+	require.True(t, b.allowed("foo", now))
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		delay := b.failure("foo", now)
+		assert.True(t, delay >= want && delay <= want+want/2, "delay %s not within [%s, %s]", delay, want, want+want/2)
+		assert.False(t, b.allowed("foo", now))
+		assert.True(t, b.allowed("foo", now.Add(delay)))
+		assert.True(t, b.allowed("bar", now))
+	}
+
+	// the jitter doesn't exceed the cap once saturated
+	for i := 0; i < 10; i++ {
+		delay := b.failure("foo", now)
+		assert.True(t, delay <= 4*time.Minute, "delay %s exceeds the maximum", delay)
+	}
+
+	b.success("foo")
+	assert.True(t, b.allowed("foo", now))
+	delay := b.failure("foo", now)
+	assert.True(t, delay <= time.Minute+time.Minute/2)
+
+	b.failure("bar", now)
+	b.prune(map[string]bool{"foo": true})
+	assert.False(t, b.allowed("foo", now))
+	assert.True(t, b.allowed("bar", now))
+	assert.Len(t, b.state, 1)
+}
+
+func TestRetryKey(t *testing.T) {
+	lb := &loadBalancer{
+		scheme: "internet-facing",
+		ingresses: map[string][]*kubernetes.Ingress{
+			"b": nil,
+			"a": nil,
+		},
+	}
+	assert.Equal(t, "internet-facing/a,b", lb.retryKey())
+
+	lb.stack = &aws.Stack{Name: "foo"}
+	assert.Equal(t, "foo", lb.retryKey())
+}
+
+func TestRecordAnnotationWarnings(t *testing.T) {
+	var recorded []string
+	defer func(r *eventRecorder) { ingressEvents = r }(ingressEvents)
+	ingressEvents = &eventRecorder{
+		record: func(ing *kubernetes.Ingress, eventType, reason, message string, _ time.Time) error {
+			recorded = append(recorded, fmt.Sprintf("%s %s %s %s", ing, eventType, reason, message))
+			return nil
+		},
+	}
+
+	recordAnnotationWarnings([]*kubernetes.Ingress{
+		{Namespace: "default", Name: "valid"},
+		{Namespace: "default", Name: "invalid", AnnotationWarnings: []string{`Ignoring invalid role ARN "foo"`}},
+	})
+	require.Equal(t, []string{`default/invalid Warning InvalidAnnotation Ignoring invalid role ARN "foo"`}, recorded)
+	require.Equal(t, 1, testutil.CollectAndCount(ingressAnnotationWarnings))
+	require.Equal(t, float64(1), testutil.ToFloat64(ingressAnnotationWarnings.WithLabelValues("default", "invalid")))
+
+	// the series is deleted once the annotation is fixed
+	recordAnnotationWarnings([]*kubernetes.Ingress{{Namespace: "default", Name: "invalid"}})
+	require.Equal(t, 0, testutil.CollectAndCount(ingressAnnotationWarnings))
+}
+
+func TestPruneErrorMetrics(t *testing.T) {
+	stackErrors.Reset()
+	ingressErrors.Reset()
+	defer stackErrors.Reset()
+	defer ingressErrors.Reset()
+
+	countStackError("", "create")
+	countStackError("a", "update")
+	countStackError("b", "update")
+	countStackError("b", "delete")
+	countIngressError(&kubernetes.Ingress{Namespace: "default", Name: "foo"})
+	countIngressError(&kubernetes.Ingress{Namespace: "default", Name: "bar"})
+
+	pruneErrorMetrics([]*aws.Stack{{Name: "a"}}, []*kubernetes.Ingress{{Namespace: "default", Name: "foo"}})
+
+	// failures to create stacks don't belong to a stack and are kept
+	require.Equal(t, 2, testutil.CollectAndCount(stackErrors))
+	require.Equal(t, float64(1), testutil.ToFloat64(stackErrors.WithLabelValues("a", "update")))
+	require.Equal(t, 1, testutil.CollectAndCount(ingressErrors))
+	require.Equal(t, float64(1), testutil.ToFloat64(ingressErrors.WithLabelValues("default", "foo")))
+}